@@ -0,0 +1,66 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"time"
+)
+
+// ClusterDeviceStatus describes how far a device of a subcluster is in
+// applying the current cluster assertion.
+type ClusterDeviceStatus struct {
+	ID     int    `json:"id"`
+	Device string `json:"device"`
+	// Status is one of "converged", "lagging", "failed" or "unknown".
+	Status string `json:"status"`
+	// Sequence is the sequence of the last cluster assertion applied by the
+	// device.
+	Sequence int `json:"sequence,omitempty"`
+	// Reason explains why the device is lagging or failed.
+	Reason string `json:"reason,omitempty"`
+	// Local is set for the device that answered the request.
+	Local bool `json:"local,omitempty"`
+	// Received is when the status of a peer device was last received.
+	Received time.Time `json:"received,omitzero"`
+}
+
+// ClusterSubclusterStatus holds the status of the devices of a subcluster.
+type ClusterSubclusterStatus struct {
+	Name    string                `json:"name"`
+	Devices []ClusterDeviceStatus `json:"devices"`
+}
+
+// ClusterStatus is the status of the devices of the cluster that this device
+// is part of.
+type ClusterStatus struct {
+	ClusterID   string                    `json:"cluster-id"`
+	Sequence    int                       `json:"sequence"`
+	Subclusters []ClusterSubclusterStatus `json:"subclusters"`
+}
+
+// ClusterStatus returns the status of the devices of each subcluster of the
+// cluster that this device is part of.
+func (client *Client) ClusterStatus() (*ClusterStatus, error) {
+	var status ClusterStatus
+	if _, err := client.doSync("GET", "/v2/cluster/status", nil, nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
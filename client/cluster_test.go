@@ -0,0 +1,75 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientClusterStatus(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"cluster-id": "cluster-id",
+			"sequence": 2,
+			"subclusters": [{
+				"name": "default",
+				"devices": [
+					{"id": 1, "device": "serial-1.model.brand", "status": "converged", "sequence": 2, "local": true},
+					{"id": 2, "device": "serial-2.model.brand", "status": "lagging", "sequence": 1, "reason": "cluster sequence 2 not applied yet", "received": "2026-01-01T00:00:00Z"},
+					{"id": 3, "device": "serial-3.model.brand", "status": "unknown"}
+				]
+			}]
+		}
+	}`
+
+	status, err := cs.cli.ClusterStatus()
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/cluster/status")
+	c.Check(status, DeepEquals, &client.ClusterStatus{
+		ClusterID: "cluster-id",
+		Sequence:  2,
+		Subclusters: []client.ClusterSubclusterStatus{{
+			Name: "default",
+			Devices: []client.ClusterDeviceStatus{
+				{ID: 1, Device: "serial-1.model.brand", Status: "converged", Sequence: 2, Local: true},
+				{ID: 2, Device: "serial-2.model.brand", Status: "lagging", Sequence: 1, Reason: "cluster sequence 2 not applied yet", Received: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+				{ID: 3, Device: "serial-3.model.brand", Status: "unknown"},
+			},
+		}},
+	})
+}
+
+func (cs *clientSuite) TestClientClusterStatusNotFound(c *C) {
+	cs.status = 404
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 404,
+		"result": {"message": "this device is not part of a cluster", "kind": ""}
+	}`
+
+	_, err := cs.cli.ClusterStatus()
+	c.Assert(err, ErrorMatches, "this device is not part of a cluster")
+}
@@ -0,0 +1,173 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// DeviceManagementMessage describes a pending device management request
+// message.
+type DeviceManagementMessage struct {
+	ID          string    `json:"id"`
+	SeqNum      int       `json:"seq-num,omitempty"`
+	AccountID   string    `json:"account-id"`
+	Kind        string    `json:"kind"`
+	Source      string    `json:"source,omitempty"`
+	ReceiveTime time.Time `json:"receive-time"`
+	ValidUntil  time.Time `json:"valid-until"`
+
+	// Stage is where the message is in its processing, one of "queued",
+	// "blocked", "dispatched", "applying" or "responding".
+	Stage         string `json:"stage"`
+	ChangeID      string `json:"change-id,omitempty"`
+	ApplyChangeID string `json:"apply-change-id,omitempty"`
+	// Status and Reason hold the outcome of the message, if known.
+	Status string `json:"status,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// DeviceManagementSequence holds the pending messages sharing a base ID.
+type DeviceManagementSequence struct {
+	BaseID   string                    `json:"base-id"`
+	Applied  int                       `json:"applied,omitempty"`
+	Blocked  bool                      `json:"blocked,omitempty"`
+	Messages []DeviceManagementMessage `json:"messages"`
+}
+
+// DeviceManagementResponse describes a queued device management
+// response message.
+type DeviceManagementResponse struct {
+	MessageID string `json:"message-id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+	// Source is "local" for responses waiting to be exported, empty for the
+	// ones to send to the store.
+	Source string `json:"source,omitempty"`
+}
+
+// DeviceManagementQueue is the state of the device management message queue.
+type DeviceManagementQueue struct {
+	Sequences        []DeviceManagementSequence `json:"sequences"`
+	Responses        []DeviceManagementResponse `json:"responses"`
+	LastExchangeTime time.Time                  `json:"last-exchange-time,omitzero"`
+}
+
+// DeviceManagementMessages returns the pending device management request
+// messages, by sequence, and the queued responses.
+func (client *Client) DeviceManagementMessages() (*DeviceManagementQueue, error) {
+	var queue DeviceManagementQueue
+	if _, err := client.doSync("GET", "/v2/device-management/messages", nil, nil, nil, &queue); err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
+
+// DropDeviceManagementSequence drops the pending request messages with the
+// given base ID, rejecting the earliest one.
+func (client *Client) DropDeviceManagementSequence(baseID string) (changeID string, err error) {
+	data, err := json.Marshal(map[string]any{
+		"action":  "drop-sequence",
+		"base-id": baseID,
+	})
+	if err != nil {
+		return "", err
+	}
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	return client.doAsync("POST", "/v2/device-management/messages", nil, headers, bytes.NewReader(data))
+}
+
+// ImportDeviceManagementMessages imports a stream of request-message
+// assertions, along with the assertions needed to verify them, for processing
+// by the device management subsystem without going through the store.
+func (client *Client) ImportDeviceManagementMessages(r io.Reader) (changeID string, err error) {
+	headers := map[string]string{
+		"Content-Type": asserts.MediaType,
+	}
+	return client.doAsync("POST", "/v2/device-management/messages", nil, headers, r)
+}
+
+// DeviceManagementResponses returns the response-message assertions to the
+// imported request messages which are pending export.
+func (client *Client) DeviceManagementResponses() ([]*asserts.ResponseMessage, error) {
+	response, cancel, err := client.rawWithTimeout(context.Background(), "GET", "/v2/device-management/responses", nil, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get device management responses: %w", err)
+	}
+	defer cancel()
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return nil, parseError(response)
+	}
+
+	count, err := strconv.Atoi(response.Header.Get("X-Ubuntu-Assertions-Count"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid assertions count")
+	}
+
+	dec := asserts.NewDecoder(response.Body)
+	responses := make([]*asserts.ResponseMessage, 0, count)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode device management responses: %v", err)
+		}
+		res, ok := a.(*asserts.ResponseMessage)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %q assertion in device management responses", a.Type().Name)
+		}
+		responses = append(responses, res)
+	}
+	if len(responses) != count {
+		return nil, fmt.Errorf("response did not have the expected number of assertions")
+	}
+
+	return responses, nil
+}
+
+// AcknowledgeDeviceManagementResponses acknowledges the exported responses to
+// the given imported request messages so that they are not exported again.
+func (client *Client) AcknowledgeDeviceManagementResponses(messageIDs []string) error {
+	data, err := json.Marshal(map[string]any{
+		"action":      "acknowledge",
+		"message-ids": messageIDs,
+	})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	_, err = client.doSync("POST", "/v2/device-management/responses", nil, headers, bytes.NewReader(data), nil)
+	return err
+}
@@ -0,0 +1,185 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientImportDeviceManagementMessages(c *C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"result": {},
+		"change": "42"
+	}`
+	chgID, err := cs.cli.ImportDeviceManagementMessages(strings.NewReader("assertions"))
+	c.Assert(err, IsNil)
+	c.Check(chgID, Equals, "42")
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/device-management/messages")
+	c.Check(cs.req.Header.Get("Content-Type"), Equals, asserts.MediaType)
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	c.Check(string(body), Equals, "assertions")
+}
+
+const responseMessageAssertion = `type: response-message
+account-id: my-brand
+message-id: mesg-1
+device: serial-1.my-model.my-brand
+status: success
+timestamp: 2026-01-01T00:00:00Z
+body-length: 0
+sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
+
+openpgp ...
+`
+
+func (cs *clientSuite) TestClientDeviceManagementResponses(c *C) {
+	cs.header = http.Header{}
+	cs.header.Add("X-Ubuntu-Assertions-Count", "1")
+	cs.rsp = responseMessageAssertion
+
+	responses, err := cs.cli.DeviceManagementResponses()
+	c.Assert(err, IsNil)
+	c.Assert(responses, HasLen, 1)
+	c.Check(responses[0].HeaderString("message-id"), Equals, "mesg-1")
+	c.Check(responses[0].Status(), Equals, asserts.MessageStatusSuccess)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/device-management/responses")
+}
+
+func (cs *clientSuite) TestClientDeviceManagementResponsesCountMismatch(c *C) {
+	cs.header = http.Header{}
+	cs.header.Add("X-Ubuntu-Assertions-Count", "2")
+	cs.rsp = responseMessageAssertion
+
+	_, err := cs.cli.DeviceManagementResponses()
+	c.Assert(err, ErrorMatches, "response did not have the expected number of assertions")
+}
+
+func (cs *clientSuite) TestClientDeviceManagementResponsesError(c *C) {
+	cs.status = 500
+	cs.header = http.Header{}
+	cs.header.Add("Content-Type", "application/json")
+	cs.rsp = `{"type": "error", "result": {"message": "boom"}}`
+
+	_, err := cs.cli.DeviceManagementResponses()
+	c.Assert(err, ErrorMatches, "boom")
+}
+
+func (cs *clientSuite) TestClientAcknowledgeDeviceManagementResponses(c *C) {
+	cs.rsp = `{"type": "sync", "result": null}`
+
+	err := cs.cli.AcknowledgeDeviceManagementResponses([]string{"mesg-1", "other"})
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/device-management/responses")
+
+	var data map[string]any
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&data), IsNil)
+	c.Check(data, DeepEquals, map[string]any{
+		"action":      "acknowledge",
+		"message-ids": []any{"mesg-1", "other"},
+	})
+}
+
+func (cs *clientSuite) TestClientDeviceManagementMessages(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"sequences": [{
+				"base-id": "stuck",
+				"applied": 1,
+				"blocked": true,
+				"messages": [{
+					"id": "stuck-3",
+					"seq-num": 3,
+					"account-id": "my-brand",
+					"kind": "snap-install",
+					"receive-time": "2026-01-01T00:00:00Z",
+					"valid-until": "2026-01-02T00:00:00Z",
+					"stage": "blocked"
+				}]
+			}],
+			"responses": [{"message-id": "mesg-1", "status": "rejected", "reason": "unauthorized", "source": "local"}],
+			"last-exchange-time": "2026-01-01T01:00:00Z"
+		}
+	}`
+
+	queue, err := cs.cli.DeviceManagementMessages()
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/device-management/messages")
+	c.Check(queue, DeepEquals, &client.DeviceManagementQueue{
+		Sequences: []client.DeviceManagementSequence{{
+			BaseID:  "stuck",
+			Applied: 1,
+			Blocked: true,
+			Messages: []client.DeviceManagementMessage{{
+				ID:          "stuck-3",
+				SeqNum:      3,
+				AccountID:   "my-brand",
+				Kind:        "snap-install",
+				ReceiveTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				ValidUntil:  time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+				Stage:       "blocked",
+			}},
+		}},
+		Responses: []client.DeviceManagementResponse{
+			{MessageID: "mesg-1", Status: "rejected", Reason: "unauthorized", Source: "local"},
+		},
+		LastExchangeTime: time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC),
+	})
+}
+
+func (cs *clientSuite) TestClientDropDeviceManagementSequence(c *C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"result": {},
+		"change": "42"
+	}`
+	chgID, err := cs.cli.DropDeviceManagementSequence("stuck")
+	c.Assert(err, IsNil)
+	c.Check(chgID, Equals, "42")
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/device-management/messages")
+	c.Check(cs.req.Header.Get("Content-Type"), Equals, "application/json")
+
+	var data map[string]any
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&data), IsNil)
+	c.Check(data, DeepEquals, map[string]any{
+		"action":  "drop-sequence",
+		"base-id": "stuck",
+	})
+}
@@ -171,6 +171,12 @@ const (
 	// ErrorKindInterfacesRequestsRuleConflict: a rule with conflicting path pattern and permissions already exists.
 	ErrorKindInterfacesRequestsRuleConflict ErrorKind = "interfaces-requests-rule-conflict"
 
+	// ErrorKindInterfacesRequestsRuleIsPolicy: the rule is a policy rule set by the administrator, which cannot be modified or removed.
+	ErrorKindInterfacesRequestsRuleIsPolicy ErrorKind = "interfaces-requests-rule-is-policy"
+
+	// ErrorKindInterfacesRequestsRuleShadowedByPolicy: policy rules set by the administrator take precedence over the rule for every path and permission.
+	ErrorKindInterfacesRequestsRuleShadowedByPolicy ErrorKind = "interfaces-requests-rule-shadowed-by-policy"
+
 	// ErrorKindMissingSnapResourcePair: cannot find a snap-resource-pair when attempting to sideload a component.
 	ErrorKindMissingSnapResourcePair ErrorKind = "missing-snap-resource-pair"
 
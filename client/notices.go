@@ -61,4 +61,9 @@ type NoticeType string
 const (
 	// SnapRunInhibitNotice is recorded when "snap run" is inhibited due refresh.
 	SnapRunInhibitNotice NoticeType = "snap-run-inhibit"
+
+	// QuotaSoftLimitNotice is recorded when a quota group has been over its
+	// soft limits for the configured window and the soft limits action was
+	// taken. The key is the quota group name.
+	QuotaSoftLimitNotice NoticeType = "quota-soft-limit"
 )
@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PromptingRule is a prompting rule in the portable form in which it is
+// exported, and from which it can be imported on another machine.
+//
+// The constraints are kept as they are, since their contents depend on the
+// interface of the rule.
+type PromptingRule struct {
+	Snap        string                     `json:"snap"`
+	Interface   string                     `json:"interface"`
+	Constraints map[string]json.RawMessage `json:"constraints"`
+}
+
+type promptingRulesSelector struct {
+	Snap      string `json:"snap,omitempty"`
+	Interface string `json:"interface,omitempty"`
+}
+
+type promptingRulesAction struct {
+	Action   string                  `json:"action"`
+	Selector *promptingRulesSelector `json:"selector,omitempty"`
+	Rules    []PromptingRule         `json:"rules,omitempty"`
+}
+
+// ExportPromptingRules returns the prompting rules of the current user, in
+// portable form. If snap and/or iface are not empty, only the rules for the
+// given snap and/or interface are exported.
+func (client *Client) ExportPromptingRules(snap, iface string) ([]PromptingRule, error) {
+	action := promptingRulesAction{Action: "export"}
+	if snap != "" || iface != "" {
+		action.Selector = &promptingRulesSelector{
+			Snap:      snap,
+			Interface: iface,
+		}
+	}
+	data, err := json.Marshal(&action)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal request: %v", err)
+	}
+
+	var rules []PromptingRule
+	if _, err := client.doSync("POST", "/v2/interfaces/requests/rules", nil, nil, bytes.NewReader(data), &rules); err != nil {
+		return nil, fmt.Errorf("cannot export prompting rules: %w", err)
+	}
+	return rules, nil
+}
+
+// ImportPromptingRules adds the given prompting rules to those of the current
+// user. Either all the rules are imported, or none is. Returns the IDs of the
+// resulting rules, which may be existing rules into which imported rules were
+// merged.
+func (client *Client) ImportPromptingRules(rules []PromptingRule) ([]string, error) {
+	data, err := json.Marshal(&promptingRulesAction{Action: "import", Rules: rules})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal request: %v", err)
+	}
+
+	var imported []struct {
+		ID string `json:"id"`
+	}
+	if _, err := client.doSync("POST", "/v2/interfaces/requests/rules", nil, nil, bytes.NewReader(data), &imported); err != nil {
+		return nil, fmt.Errorf("cannot import prompting rules: %w", err)
+	}
+	ids := make([]string, 0, len(imported))
+	for _, rule := range imported {
+		ids = append(ids, rule.ID)
+	}
+	return ids, nil
+}
+
+// PromptingCheck explains what the prompting rules of the current user, along
+// with any policy rules, would decide about a request.
+type PromptingCheck struct {
+	// Decision is one of "allow", "deny", or "prompt".
+	Decision    string                     `json:"decision"`
+	Permissions []PromptingPermissionCheck `json:"permissions"`
+}
+
+// PromptingPermissionCheck explains what the prompting rules would decide
+// about one of the permissions of a request, and why.
+type PromptingPermissionCheck struct {
+	Permission string `json:"permission"`
+	Decision   string `json:"decision"`
+	// Source is one of "policy-snap", "policy", or "user", or empty if no
+	// rule applies.
+	Source  string                  `json:"source,omitempty"`
+	Variant string                  `json:"variant,omitempty"`
+	RuleIDs []string                `json:"rule-ids,omitempty"`
+	Matches []PromptingVariantMatch `json:"matches,omitempty"`
+	Reason  string                  `json:"reason"`
+}
+
+// PromptingVariantMatch is a path pattern variant of a prompting rule which
+// matches the path of a request.
+type PromptingVariantMatch struct {
+	Source  string   `json:"source"`
+	Variant string   `json:"variant"`
+	Outcome string   `json:"outcome"`
+	RuleIDs []string `json:"rule-ids"`
+}
+
+// CheckPromptingRequest reports what the prompting rules of the current user
+// would decide about a request from the given snap for the given permissions
+// to the given path through the given interface, and which rules lead to that
+// decision. The request is only simulated, no prompt is created.
+func (client *Client) CheckPromptingRequest(snap, iface, path string, permissions []string) (*PromptingCheck, error) {
+	query := url.Values{}
+	query.Set("snap", snap)
+	query.Set("interface", iface)
+	query.Set("path", path)
+	query.Set("permissions", strings.Join(permissions, ","))
+
+	var check PromptingCheck
+	if _, err := client.doSync("GET", "/v2/interfaces/requests/check", query, nil, nil, &check); err != nil {
+		return nil, fmt.Errorf("cannot check prompting request: %w", err)
+	}
+	return &check, nil
+}
@@ -0,0 +1,193 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientExportPromptingRules(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{"snap": "firefox", "interface": "home", "constraints": {"path-pattern": "/home/test/**", "permissions": {"read": {"outcome": "allow", "lifespan": "forever"}}}}
+		]
+	}`
+
+	rules, err := cs.cli.ExportPromptingRules("firefox", "")
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/interfaces/requests/rules")
+	c.Assert(rules, HasLen, 1)
+	c.Check(rules[0].Snap, Equals, "firefox")
+	c.Check(rules[0].Interface, Equals, "home")
+	c.Check(string(rules[0].Constraints["path-pattern"]), Equals, `"/home/test/**"`)
+
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	var jsonBody map[string]any
+	c.Assert(json.Unmarshal(body, &jsonBody), IsNil)
+	c.Check(jsonBody, DeepEquals, map[string]any{
+		"action":   "export",
+		"selector": map[string]any{"snap": "firefox"},
+	})
+}
+
+func (cs *clientSuite) TestClientExportPromptingRulesNoSelector(c *C) {
+	cs.rsp = `{"type": "sync", "result": []}`
+
+	rules, err := cs.cli.ExportPromptingRules("", "")
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 0)
+
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	var jsonBody map[string]any
+	c.Assert(json.Unmarshal(body, &jsonBody), IsNil)
+	c.Check(jsonBody, DeepEquals, map[string]any{
+		"action": "export",
+	})
+}
+
+func (cs *clientSuite) TestClientImportPromptingRules(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{"id": "0000000000000002", "snap": "firefox", "interface": "home"},
+			{"id": "0000000000000003", "snap": "firefox", "interface": "camera"}
+		]
+	}`
+
+	ids, err := cs.cli.ImportPromptingRules([]client.PromptingRule{
+		{
+			Snap:      "firefox",
+			Interface: "camera",
+			Constraints: map[string]json.RawMessage{
+				"permissions": json.RawMessage(`{"access":{"outcome":"allow","lifespan":"forever"}}`),
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(ids, DeepEquals, []string{"0000000000000002", "0000000000000003"})
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/interfaces/requests/rules")
+
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	var jsonBody map[string]any
+	c.Assert(json.Unmarshal(body, &jsonBody), IsNil)
+	c.Check(jsonBody, DeepEquals, map[string]any{
+		"action": "import",
+		"rules": []any{
+			map[string]any{
+				"snap":      "firefox",
+				"interface": "camera",
+				"constraints": map[string]any{
+					"permissions": map[string]any{
+						"access": map[string]any{"outcome": "allow", "lifespan": "forever"},
+					},
+				},
+			},
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientImportPromptingRulesError(c *C) {
+	cs.status = 409
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 409,
+		"result": {"message": "cannot import rule 0: a rule conflicts with the given rule", "kind": "interfaces-requests-rule-conflict"}
+	}`
+
+	_, err := cs.cli.ImportPromptingRules([]client.PromptingRule{{Snap: "firefox", Interface: "camera"}})
+	c.Assert(err, ErrorMatches, "cannot import prompting rules: cannot import rule 0: a rule conflicts with the given rule")
+}
+
+func (cs *clientSuite) TestClientCheckPromptingRequest(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"decision": "deny",
+			"permissions": [
+				{"permission": "read", "decision": "prompt", "reason": "no rule matches the path, so the user would be prompted"},
+				{
+					"permission": "write",
+					"decision": "deny",
+					"source": "policy",
+					"variant": "/home/*/.ssh/**",
+					"rule-ids": ["0000000000000012"],
+					"matches": [
+						{"source": "policy", "variant": "/home/*/.ssh/**", "outcome": "deny", "rule-ids": ["0000000000000012"]},
+						{"source": "user", "variant": "/home/test/**", "outcome": "allow", "rule-ids": ["0000000000000003"]}
+					],
+					"reason": "/home/*/.ssh/** is the only matching variant of the policy rules for all snaps, which take precedence over matching user rules"
+				}
+			]
+		}
+	}`
+
+	check, err := cs.cli.CheckPromptingRequest("firefox", "home", "/home/test/.ssh/config", []string{"read", "write"})
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/interfaces/requests/check")
+	c.Check(cs.req.URL.Query(), DeepEquals, url.Values{
+		"snap":        []string{"firefox"},
+		"interface":   []string{"home"},
+		"path":        []string{"/home/test/.ssh/config"},
+		"permissions": []string{"read,write"},
+	})
+	c.Check(check, DeepEquals, &client.PromptingCheck{
+		Decision: "deny",
+		Permissions: []client.PromptingPermissionCheck{
+			{Permission: "read", Decision: "prompt", Reason: "no rule matches the path, so the user would be prompted"},
+			{
+				Permission: "write",
+				Decision:   "deny",
+				Source:     "policy",
+				Variant:    "/home/*/.ssh/**",
+				RuleIDs:    []string{"0000000000000012"},
+				Matches: []client.PromptingVariantMatch{
+					{Source: "policy", Variant: "/home/*/.ssh/**", Outcome: "deny", RuleIDs: []string{"0000000000000012"}},
+					{Source: "user", Variant: "/home/test/**", Outcome: "allow", RuleIDs: []string{"0000000000000003"}},
+				},
+				Reason: "/home/*/.ssh/** is the only matching variant of the policy rules for all snaps, which take precedence over matching user rules",
+			},
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientCheckPromptingRequestError(c *C) {
+	cs.status = 400
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 400,
+		"result": {"message": "invalid permissions for home interface: \"access\"", "kind": "interfaces-requests-invalid-fields"}
+	}`
+
+	_, err := cs.cli.CheckPromptingRequest("firefox", "home", "/home/test/foo", []string{"access"})
+	c.Assert(err, ErrorMatches, `cannot check prompting request: invalid permissions for home interface: "access"`)
+}
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/snapcore/snapd/gadget/quantity"
@@ -66,12 +67,51 @@ type QuotaJournalValues struct {
 	*QuotaJournalRate
 }
 
+// QuotaIOValues are the block IO limits of a quota group. The bandwidths
+// are in bytes per second.
+type QuotaIOValues struct {
+	ReadBandwidth  quantity.Size `json:"read-bandwidth,omitempty"`
+	WriteBandwidth quantity.Size `json:"write-bandwidth,omitempty"`
+	Weight         int           `json:"weight,omitempty"`
+}
+
+// QuotaNetworkValues are the network limits of a quota group, in the
+// constraints, and the network usage of the group, in the current values. The
+// bandwidth is in bytes per second.
+type QuotaNetworkValues struct {
+	EgressBandwidth quantity.Size `json:"egress-bandwidth,omitempty"`
+	EgressBytes     quantity.Size `json:"egress-bytes,omitempty"`
+}
+
+// QuotaSoftLimitsValues are the soft limits of a quota group. The action is
+// taken once the group has been over any of the thresholds for the window.
+type QuotaSoftLimitsValues struct {
+	Memory        quantity.Size `json:"memory,omitempty"`
+	CPUPercentage int           `json:"cpu-percentage,omitempty"`
+	Threads       int           `json:"threads,omitempty"`
+	Window        time.Duration `json:"window,omitempty"`
+	Action        string        `json:"action,omitempty"`
+}
+
 type QuotaValues struct {
-	Memory  quantity.Size       `json:"memory,omitempty"`
-	CPU     *QuotaCPUValues     `json:"cpu,omitempty"`
-	CPUSet  *QuotaCPUSetValues  `json:"cpu-set,omitempty"`
-	Threads int                 `json:"threads,omitempty"`
-	Journal *QuotaJournalValues `json:"journal,omitempty"`
+	Memory     quantity.Size          `json:"memory,omitempty"`
+	CPU        *QuotaCPUValues        `json:"cpu,omitempty"`
+	CPUSet     *QuotaCPUSetValues     `json:"cpu-set,omitempty"`
+	Threads    int                    `json:"threads,omitempty"`
+	Journal    *QuotaJournalValues    `json:"journal,omitempty"`
+	IO         *QuotaIOValues         `json:"io,omitempty"`
+	Network    *QuotaNetworkValues    `json:"network,omitempty"`
+	SoftLimits *QuotaSoftLimitsValues `json:"soft-limits,omitempty"`
+}
+
+// QuotaUsageSample is the resource usage of a quota group at a given time.
+// The CPU usage is the CPU time used since the previous sample, as a
+// percentage of a single CPU.
+type QuotaUsageSample struct {
+	Time    time.Time     `json:"time"`
+	Memory  quantity.Size `json:"memory"`
+	CPU     int           `json:"cpu"`
+	Threads int           `json:"threads"`
 }
 
 type EnsureQuotaOptions struct {
@@ -133,6 +173,28 @@ func (client *Client) GetQuotaGroup(groupName string) (*QuotaGroupResult, error)
 	return res, nil
 }
 
+// GetQuotaGroupUsage returns the samples of the resource usage of the given
+// quota group, oldest first. If since is not zero, only the samples taken
+// since then are returned.
+func (client *Client) GetQuotaGroupUsage(groupName string, since time.Time) ([]QuotaUsageSample, error) {
+	if groupName == "" {
+		return nil, fmt.Errorf("cannot get quota group usage without a name")
+	}
+
+	query := url.Values{}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+
+	var res []QuotaUsageSample
+	path := fmt.Sprintf("/v2/quotas/%s/usage", groupName)
+	if _, err := client.doSync("GET", path, query, nil, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 func (client *Client) RemoveQuotaGroup(groupName string) (changeID string, err error) {
 	if groupName == "" {
 		return "", fmt.Errorf("cannot remove quota group without a name")
@@ -154,6 +216,29 @@ func (client *Client) RemoveQuotaGroup(groupName string) (changeID string, err e
 	return chgID, nil
 }
 
+// ThawQuotaGroup thaws the given quota group after it was frozen by its soft
+// limits action.
+func (client *Client) ThawQuotaGroup(groupName string) (changeID string, err error) {
+	if groupName == "" {
+		return "", fmt.Errorf("cannot thaw quota group without a name")
+	}
+	data := &postQuotaData{
+		Action:    "thaw",
+		GroupName: groupName,
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return "", err
+	}
+	chgID, err := client.doAsync("POST", "/v2/quotas", nil, nil, &body)
+	if err != nil {
+		return "", fmt.Errorf("cannot thaw quota group: %w", err)
+	}
+
+	return chgID, nil
+}
+
 func (client *Client) Quotas() ([]*QuotaGroupResult, error) {
 	var res []*QuotaGroupResult
 	if _, err := client.doSync("GET", "/v2/quotas", nil, nil, nil, &res); err != nil {
@@ -162,6 +162,36 @@ func (cs *clientSuite) TestGetQuotaGroupError(c *check.C) {
 	c.Check(err, check.ErrorMatches, `server error: "Internal Server Error"`)
 }
 
+func (cs *clientSuite) TestGetQuotaGroupUsageInvalidName(c *check.C) {
+	_, err := cs.cli.GetQuotaGroupUsage("", time.Time{})
+	c.Assert(err, check.ErrorMatches, `cannot get quota group usage without a name`)
+}
+
+func (cs *clientSuite) TestGetQuotaGroupUsage(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [
+			{"time": "2026-01-01T10:05:00Z", "memory": 1024, "cpu": 50, "threads": 4},
+			{"time": "2026-01-01T10:10:00Z", "memory": 2048, "cpu": 120, "threads": 5}
+		]
+	}`
+
+	samples, err := cs.cli.GetQuotaGroupUsage("foo", time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas/foo/usage")
+	c.Check(cs.req.URL.RawQuery, check.Equals, "")
+	c.Check(samples, check.DeepEquals, []client.QuotaUsageSample{
+		{Time: time.Date(2026, 1, 1, 10, 5, 0, 0, time.UTC), Memory: quantity.SizeKiB, CPU: 50, Threads: 4},
+		{Time: time.Date(2026, 1, 1, 10, 10, 0, 0, time.UTC), Memory: 2 * quantity.SizeKiB, CPU: 120, Threads: 5},
+	})
+
+	_, err = cs.cli.GetQuotaGroupUsage("foo", time.Date(2026, 1, 1, 10, 7, 0, 0, time.UTC))
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.Query().Get("since"), check.Equals, "2026-01-01T10:07:00Z")
+}
+
 func (cs *clientSuite) TestRemoveQuotaGroup(c *check.C) {
 	cs.status = 202
 	cs.rsp = `{
@@ -192,3 +222,34 @@ func (cs *clientSuite) TestRemoveQuotaGroupError(c *check.C) {
 	_, err := cs.cli.RemoveQuotaGroup("foo")
 	c.Check(err, check.ErrorMatches, `cannot remove quota group: server error: "Internal Server Error"`)
 }
+
+func (cs *clientSuite) TestThawQuotaGroup(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+
+	chgID, err := cs.cli.ThawQuotaGroup("foo")
+	c.Assert(err, check.IsNil)
+	c.Assert(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas")
+	body, err := io.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]any
+	err = json.Unmarshal(body, &req)
+	c.Assert(err, check.IsNil)
+	c.Assert(req, check.DeepEquals, map[string]any{
+		"action":     "thaw",
+		"group-name": "foo",
+	})
+}
+
+func (cs *clientSuite) TestThawQuotaGroupError(c *check.C) {
+	cs.status = 500
+	cs.rsp = `{"type": "error"}`
+	_, err := cs.cli.ThawQuotaGroup("foo")
+	c.Check(err, check.ErrorMatches, `cannot thaw quota group: server error: "Internal Server Error"`)
+}
@@ -64,6 +64,9 @@ type SnapOptions struct {
 	Time             string          `json:"time,omitempty"`
 	HoldLevel        string          `json:"hold-level,omitempty"`
 	Users            []string        `json:"users,omitempty"`
+	// SnapshotPassphrase is the passphrase to encrypt snapshots with,
+	// when they are configured to be encrypted with one.
+	SnapshotPassphrase string `json:"snapshot-passphrase,omitempty"`
 }
 
 func writeFieldBool(mw *multipart.Writer, key string, val bool) error {
@@ -136,6 +139,8 @@ type multiActionData struct {
 	Time           string              `json:"time,omitempty"`
 	HoldLevel      string              `json:"hold-level,omitempty"`
 	Components     map[string][]string `json:"components,omitempty"`
+
+	SnapshotPassphrase string `json:"snapshot-passphrase,omitempty"`
 }
 
 // Install adds the snap with the given name from the given channel (or
@@ -205,8 +210,11 @@ func (client *Client) Switch(name string, options *SnapOptions) (changeID string
 }
 
 // SnapshotMany snapshots many snaps (all, if names empty) for many users (all, if users is empty).
-func (client *Client) SnapshotMany(names []string, users []string) (setID uint64, changeID string, err error) {
-	result, changeID, err := client.doMultiSnapActionFull("snapshot", names, nil, &SnapOptions{Users: users})
+//
+// The passphrase is only needed when snapshots are configured to be
+// encrypted with one.
+func (client *Client) SnapshotMany(names []string, users []string, passphrase string) (setID uint64, changeID string, err error) {
+	result, changeID, err := client.doMultiSnapActionFull("snapshot", names, nil, &SnapOptions{Users: users, SnapshotPassphrase: passphrase})
 	if err != nil {
 		return 0, "", err
 	}
@@ -269,6 +277,7 @@ func (client *Client) doMultiSnapActionFull(actionName string, snaps []string, c
 		action.ValidationSets = options.ValidationSets
 		action.Time = options.Time
 		action.HoldLevel = options.HoldLevel
+		action.SnapshotPassphrase = options.SnapshotPassphrase
 	}
 
 	data, err := json.Marshal(&action)
@@ -108,7 +108,7 @@ func (cs *clientSuite) TestClientMultiOpSnapServerError(c *check.C) {
 		_, err := s.op(cs.cli, nil, nil)
 		c.Check(err, check.ErrorMatches, `.*fail`, check.Commentf(s.action))
 	}
-	_, _, err := cs.cli.SnapshotMany(nil, nil)
+	_, _, err := cs.cli.SnapshotMany(nil, nil, "")
 	c.Check(err, check.ErrorMatches, `.*fail`)
 }
 
@@ -128,7 +128,7 @@ func (cs *clientSuite) TestClientMultiOpSnapResponseError(c *check.C) {
 		_, err := s.op(cs.cli, nil, nil)
 		c.Check(err, check.ErrorMatches, `.*server error: "Internal Server Error"`, check.Commentf(s.action))
 	}
-	_, _, err := cs.cli.SnapshotMany(nil, nil)
+	_, _, err := cs.cli.SnapshotMany(nil, nil, "")
 	c.Check(err, check.ErrorMatches, `.*server error: "Internal Server Error"`)
 }
 
@@ -292,7 +292,7 @@ func (cs *clientSuite) TestClientMultiSnapshot(c *check.C) {
 		"status-code": 202,
 		"type": "async"
 	}`
-	setID, changeID, err := cs.cli.SnapshotMany([]string{pkgName}, nil)
+	setID, changeID, err := cs.cli.SnapshotMany([]string{pkgName}, nil, "")
 	c.Assert(err, check.IsNil)
 	c.Check(cs.req.Header.Get("Content-Type"), check.Equals, "application/json")
 
@@ -309,6 +309,27 @@ func (cs *clientSuite) TestClientMultiSnapshot(c *check.C) {
 	c.Check(changeID, check.Equals, "d728")
 }
 
+func (cs *clientSuite) TestClientMultiSnapshotPassphrase(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"result": {"set-id": 42},
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	_, _, err := cs.cli.SnapshotMany([]string{pkgName}, []string{"a-user"}, "sekrit")
+	c.Assert(err, check.IsNil)
+
+	var jsonBody map[string]any
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]any{
+		"action":              "snapshot",
+		"snaps":               []any{pkgName},
+		"users":               []any{"a-user"},
+		"snapshot-passphrase": "sekrit",
+	})
+}
+
 func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	cs.status = 202
 	cs.rsp = `{
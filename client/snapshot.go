@@ -39,6 +39,10 @@ import (
 // SnapshotExportMediaType is the media type used to identify snapshot exports in the API.
 const SnapshotExportMediaType = "application/x.snapd.snapshot"
 
+// SnapshotPassphraseHeader is the header carrying the passphrase needed to
+// verify imported snapshots encrypted with one.
+const SnapshotPassphraseHeader = "X-Snapd-Snapshot-Passphrase"
+
 var (
 	ErrSnapshotSetNotFound   = errors.New("no snapshot set with the given ID")
 	ErrSnapshotSnapsNotFound = errors.New("no snapshot for the requested snaps found in the set with the given ID")
@@ -46,10 +50,11 @@ var (
 
 // A snapshotAction is used to request an operation on a snapshot.
 type snapshotAction struct {
-	SetID  uint64   `json:"set"`
-	Action string   `json:"action"`
-	Snaps  []string `json:"snaps,omitempty"`
-	Users  []string `json:"users,omitempty"`
+	SetID      uint64   `json:"set"`
+	Action     string   `json:"action"`
+	Snaps      []string `json:"snaps,omitempty"`
+	Users      []string `json:"users,omitempty"`
+	Passphrase string   `json:"passphrase,omitempty"`
 }
 
 // A Snapshot is a collection of archives with a simple metadata json file
@@ -68,7 +73,8 @@ type Snapshot struct {
 	Summary  string        `json:"summary"`
 	Version  string        `json:"version"`
 
-	// the snap's configuration at snapshot time
+	// the snap's configuration at snapshot time; for encrypted
+	// snapshots this is only available once the snapshot is unlocked
 	Conf map[string]any `json:"conf,omitempty"`
 
 	// the hash of the archives' data, keyed by archive path
@@ -81,6 +87,9 @@ type Snapshot struct {
 	// dynamic snapshot options
 	Options *snap.SnapshotOptions `json:"options,omitempty"`
 
+	// set if the snapshot data is encrypted
+	Encryption *SnapshotEncryption `json:"encryption,omitempty"`
+
 	// if the snapshot failed to open this will be the reason why
 	Broken string `json:"broken,omitempty"`
 
@@ -89,6 +98,29 @@ type Snapshot struct {
 	// newer snapd just updates this flag on the fly for snapshots
 	// returned by List().
 	Auto bool `json:"auto,omitempty"`
+
+	// Origin tells how the snapshot was taken: SnapshotOriginAutomatic on
+	// snap removal, SnapshotOriginScheduled as per a snapshot schedule, or
+	// empty if it was requested. Like Auto, it is set on the fly for
+	// snapshots returned by List().
+	Origin string `json:"origin,omitempty"`
+}
+
+const (
+	SnapshotOriginAutomatic = "automatic"
+	SnapshotOriginScheduled = "scheduled"
+)
+
+// SnapshotEncryption describes how the data of an encrypted snapshot is
+// protected.
+type SnapshotEncryption struct {
+	// KeySource is the kind of source the key is taken from, one of
+	// "key-file", "tpm" or "passphrase".
+	KeySource string `json:"key-source"`
+	// Salt is mixed with the secret from the key source to derive the key.
+	Salt []byte `json:"salt"`
+	// KeyCheck allows telling a wrong key apart from corrupted data.
+	KeyCheck string `json:"key-check"`
 }
 
 // IsValid checks whether the snapshot is missing information that
@@ -104,6 +136,7 @@ func (sh *Snapshot) ContentHash() ([]byte, error) {
 	sh2.SetID = 0
 	sh2.Time = time.Time{}
 	sh2.Auto = false
+	sh2.Origin = ""
 	sh2.Options = nil
 	h := sha256.New()
 	enc := json.NewEncoder(h)
@@ -195,26 +228,39 @@ func (client *Client) ForgetSnapshots(setID uint64, snaps []string) (changeID st
 // CheckSnapshots verifies the archive checksums in the given snapshot set.
 //
 // If snaps or users are non-empty, limit to checking only those
-// archives of the snapshot.
-func (client *Client) CheckSnapshots(setID uint64, snaps []string, users []string) (changeID string, err error) {
+// archives of the snapshot. The passphrase is only needed for snapshots
+// encrypted with one.
+func (client *Client) CheckSnapshots(setID uint64, snaps []string, users []string, passphrase string) (changeID string, err error) {
 	return client.snapshotAction(&snapshotAction{
-		SetID:  setID,
-		Action: "check",
-		Snaps:  snaps,
-		Users:  users,
+		SetID:      setID,
+		Action:     "check",
+		Snaps:      snaps,
+		Users:      users,
+		Passphrase: passphrase,
 	})
 }
 
 // RestoreSnapshots extracts the given snapshot set.
 //
 // If snaps or users are non-empty, limit to checking only those
-// archives of the snapshot.
-func (client *Client) RestoreSnapshots(setID uint64, snaps []string, users []string) (changeID string, err error) {
+// archives of the snapshot. The passphrase is only needed for snapshots
+// encrypted with one.
+func (client *Client) RestoreSnapshots(setID uint64, snaps []string, users []string, passphrase string) (changeID string, err error) {
+	return client.snapshotAction(&snapshotAction{
+		SetID:      setID,
+		Action:     "restore",
+		Snaps:      snaps,
+		Users:      users,
+		Passphrase: passphrase,
+	})
+}
+
+// PushSnapshots pushes the given snapshot set to the configured remote
+// target.
+func (client *Client) PushSnapshots(setID uint64) (changeID string, err error) {
 	return client.snapshotAction(&snapshotAction{
 		SetID:  setID,
-		Action: "restore",
-		Snaps:  snaps,
-		Users:  users,
+		Action: "push",
 	})
 }
 
@@ -267,11 +313,14 @@ type SnapshotImportSet struct {
 }
 
 // SnapshotImport imports an exported snapshot set.
-func (client *Client) SnapshotImport(exportStream io.Reader, size int64) (SnapshotImportSet, error) {
+func (client *Client) SnapshotImport(exportStream io.Reader, size int64, passphrase string) (SnapshotImportSet, error) {
 	headers := map[string]string{
 		"Content-Type":   SnapshotExportMediaType,
 		"Content-Length": strconv.FormatInt(size, 10),
 	}
+	if passphrase != "" {
+		headers[SnapshotPassphraseHeader] = passphrase
+	}
 
 	var importSet SnapshotImportSet
 	if _, err := client.doSync("POST", "/v2/snapshots", nil, headers, exportStream, &importSet); err != nil {
@@ -280,3 +329,27 @@ func (client *Client) SnapshotImport(exportStream io.Reader, size int64) (Snapsh
 
 	return importSet, nil
 }
+
+// PullSnapshot imports the snapshot set pushed to the configured remote
+// target with the given ID. The returned set has the ID it is known by
+// locally. The passphrase is only needed for snapshots encrypted with one.
+func (client *Client) PullSnapshot(setID uint64, passphrase string) (SnapshotImportSet, error) {
+	data, err := json.Marshal(&snapshotAction{
+		SetID:      setID,
+		Action:     "pull",
+		Passphrase: passphrase,
+	})
+	if err != nil {
+		return SnapshotImportSet{}, fmt.Errorf("cannot marshal snapshot action: %v", err)
+	}
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	var importSet SnapshotImportSet
+	if _, err := client.doSync("POST", "/v2/snapshots", nil, headers, bytes.NewBuffer(data), &importSet); err != nil {
+		return importSet, err
+	}
+
+	return importSet, nil
+}
@@ -98,7 +98,7 @@ func (cs *clientSuite) TestClientSnapshotSets(c *check.C) {
 	})
 }
 
-func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, users []string, f func() (string, error)) {
+func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, users []string, passphrase string, f func() (string, error)) {
 	cs.status = 202
 	cs.rsp = `{
 		"status-code": 202,
@@ -117,6 +117,7 @@ func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, u
 	c.Check(act.Action, check.Equals, action)
 	c.Check(act.Snaps, check.DeepEquals, []string{"asnap", "bsnap"})
 	c.Check(act.Users, check.DeepEquals, users)
+	c.Check(act.Passphrase, check.Equals, passphrase)
 
 	c.Check(cs.req.Method, check.Equals, "POST")
 	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
@@ -124,15 +125,17 @@ func (cs *clientSuite) testClientSnapshotActionFull(c *check.C, action string, u
 }
 
 func (cs *clientSuite) TestClientForgetSnapshot(c *check.C) {
-	cs.testClientSnapshotActionFull(c, "forget", nil, func() (string, error) {
+	cs.testClientSnapshotActionFull(c, "forget", nil, "", func() (string, error) {
 		return cs.cli.ForgetSnapshots(42, []string{"asnap", "bsnap"})
 	})
 }
 
-func (cs *clientSuite) testClientSnapshotAction(c *check.C, action string, f func(uint64, []string, []string) (string, error)) {
-	cs.testClientSnapshotActionFull(c, action, []string{"auser", "buser"}, func() (string, error) {
-		return f(42, []string{"asnap", "bsnap"}, []string{"auser", "buser"})
-	})
+func (cs *clientSuite) testClientSnapshotAction(c *check.C, action string, f func(uint64, []string, []string, string) (string, error)) {
+	for _, passphrase := range []string{"", "sekrit"} {
+		cs.testClientSnapshotActionFull(c, action, []string{"auser", "buser"}, passphrase, func() (string, error) {
+			return f(42, []string{"asnap", "bsnap"}, []string{"auser", "buser"}, passphrase)
+		})
+	}
 }
 
 func (cs *clientSuite) TestClientCheckSnapshots(c *check.C) {
@@ -143,6 +146,42 @@ func (cs *clientSuite) TestClientRestoreSnapshots(c *check.C) {
 	cs.testClientSnapshotAction(c, "restore", cs.cli.RestoreSnapshots)
 }
 
+func (cs *clientSuite) TestClientPushSnapshots(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"status-code": 202,
+		"type": "async",
+		"change": "1too3"
+	}`
+	id, err := cs.cli.PushSnapshots(42)
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "1too3")
+
+	act, err := client.UnmarshalSnapshotAction(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(act.SetID, check.Equals, uint64(42))
+	c.Check(act.Action, check.Equals, "push")
+	c.Check(act.Snaps, check.HasLen, 0)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
+}
+
+func (cs *clientSuite) TestClientPullSnapshot(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {"set-id": 7, "snaps": ["foo"]}}`
+	importSet, err := cs.cli.PullSnapshot(42, "sekrit")
+	c.Assert(err, check.IsNil)
+	c.Check(importSet, check.DeepEquals, client.SnapshotImportSet{ID: 7, Snaps: []string{"foo"}})
+
+	c.Assert(cs.req.Header.Get("Content-Type"), check.Equals, "application/json")
+	act, err := client.UnmarshalSnapshotAction(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(act.SetID, check.Equals, uint64(42))
+	c.Check(act.Action, check.Equals, "pull")
+	c.Check(act.Passphrase, check.Equals, "sekrit")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
+}
+
 func (cs *clientSuite) TestClientExportSnapshotSpecificErr(c *check.C) {
 	content := `{"type":"error","status-code":400,"result":{"message":"boom","kind":"err-kind","value":"err-value"}}`
 	cs.contentLength = int64(len(content))
@@ -212,7 +251,7 @@ func (cs *clientSuite) TestClientSnapshotImport(c *check.C) {
 
 		fakeSnapshotData := "fake"
 		r := strings.NewReader(fakeSnapshotData)
-		importSet, err := cs.cli.SnapshotImport(r, int64(len(fakeSnapshotData)))
+		importSet, err := cs.cli.SnapshotImport(r, int64(len(fakeSnapshotData)), "")
 		if t.error != "" {
 			c.Assert(err, check.NotNil, comm)
 			c.Check(err.Error(), check.Equals, t.error, comm)
@@ -229,6 +268,15 @@ func (cs *clientSuite) TestClientSnapshotImport(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientSnapshotImportPassphrase(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {"set-id": 42, "snaps": ["foo"]}}`
+	cs.status = 200
+
+	_, err := cs.cli.SnapshotImport(strings.NewReader("fake"), 4, "sekrit")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Header.Get("X-Snapd-Snapshot-Passphrase"), check.Equals, "sekrit")
+}
+
 func (cs *clientSuite) TestClientSnapshotContentHash(c *check.C) {
 	now := time.Now()
 	revno := snap.R(1)
@@ -152,6 +152,10 @@ type AssembleConfig struct {
 	// Signer is a function that signs the given data with the private key that
 	// matches the public key embedded in the serial assertion.
 	Signer func([]byte) ([]byte, error)
+	// Transport selects the [Transport] used by [AssembleState.Run] when none
+	// is given to it. If unset, defaults to [TransportHTTPS]. All the devices
+	// of an assembly session must use the same transport.
+	Transport TransportKind
 }
 
 const AssembleSessionLength = time.Hour
@@ -171,6 +175,9 @@ func NewAssembleState(
 	if config.Signer == nil {
 		return nil, errors.New("signer function is required")
 	}
+	if _, err := NewTransport(config.Transport); err != nil {
+		return nil, err
+	}
 
 	// default clock to time.Now if not provided
 	if config.Clock == nil {
@@ -532,7 +539,8 @@ type RunOptions struct {
 
 // Run starts the assembly process, managing both the server and periodic client
 // operations. It returns when the context is cancelled, returning the
-// discovered device identities and routes.
+// discovered device identities and routes. If transport is nil, the transport
+// selected by [AssembleConfig.Transport] is used.
 func (as *AssembleState) Run(
 	ctx context.Context,
 	ln net.Listener,
@@ -548,6 +556,14 @@ func (as *AssembleState) Run(
 		return nil, Routes{}, errors.New("cannot resume an assembly session that began more than an hour ago")
 	}
 
+	if transport == nil {
+		var err error
+		transport, err = NewTransport(as.config.Transport)
+		if err != nil {
+			return nil, Routes{}, err
+		}
+	}
+
 	addr := ln.Addr().String()
 	client := transport.NewClient(as.cert)
 
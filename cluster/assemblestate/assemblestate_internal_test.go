@@ -1944,6 +1944,28 @@ func (s *clusterSuite) TestNewAssembleStateInvalidSigner(c *check.C) {
 	c.Assert(err, check.ErrorMatches, ".*serial proof verification failed for device local-device.*invalid signature.*")
 }
 
+func (s *clusterSuite) TestNewAssembleStateUnknownTransport(c *check.C) {
+	db, signing := mockAssertDB(c)
+	serial, key := createTestSerial(c, signing)
+
+	certPEM, keyPEM := createTestCertAndKey(c)
+	cfg := AssembleConfig{
+		Secret:    "secret",
+		RDT:       DeviceToken("local-device"),
+		TLSCert:   certPEM,
+		TLSKey:    keyPEM,
+		Serial:    serial,
+		Signer:    privateKeySigner(key),
+		Transport: "carrier-pigeon",
+	}
+
+	commit := func(AssembleSession) {}
+
+	_, err := NewAssembleState(cfg, AssembleSession{}, func(DeviceToken, Identifier) (RouteSelector, error) {
+		return statelessSelector(), nil
+	}, commit, db)
+	c.Assert(err, check.ErrorMatches, `unknown transport "carrier-pigeon"`)
+}
 func (s *clusterSuite) TestRunTimeout(c *check.C) {
 	db, signing := mockAssertDB(c)
 	cfg := createTestAssembleConfig(c, signing, "secret", "rdt")
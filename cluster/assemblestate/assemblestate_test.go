@@ -73,6 +73,16 @@ func createTestCertAndKey(c *check.C) (certPEM []byte, keyPEM []byte) {
 }
 
 func (s *assembleSuite) TestRun(c *check.C) {
+	s.testRun(c, "")
+}
+
+func (s *assembleSuite) TestRunStreamTransport(c *check.C) {
+	s.testRun(c, assemblestate.TransportStream)
+}
+
+// testRun assembles a cluster over the given transport, which is picked by
+// Run from the configuration.
+func (s *assembleSuite) testRun(c *check.C, transport assemblestate.TransportKind) {
 	db, signing := mockAssertDB(c)
 
 	const count = 16
@@ -107,6 +117,8 @@ func (s *assembleSuite) TestRun(c *check.C) {
 			TLSKey:  key,
 			Serial:  serial,
 			Signer:  privateKeySigner(pk),
+
+			Transport: transport,
 		}, assemblestate.AssembleSession{},
 			func(self assemblestate.DeviceToken, identified func(assemblestate.DeviceToken) bool) (assemblestate.RouteSelector, error) {
 				return assemblestate.NewPrioritySelector(self, nil, identified), nil
@@ -125,7 +137,7 @@ func (s *assembleSuite) TestRun(c *check.C) {
 			_, _, err := as.Run(
 				ctx,
 				listeners[rdt],
-				assemblestate.NewHTTPSTransport(),
+				nil,
 				disco,
 				assemblestate.RunOptions{Period: time.Millisecond * 100},
 			)
@@ -144,6 +156,8 @@ func (s *assembleSuite) TestRun(c *check.C) {
 		Serial:  serial,
 		Signer:  privateKeySigner(pk),
 
+		Transport: transport,
+
 		// this session has an expected size, so it will terminate on its own
 		ExpectedSize: count,
 	}, assemblestate.AssembleSession{},
@@ -161,7 +175,7 @@ func (s *assembleSuite) TestRun(c *check.C) {
 	ids, routes, err := as.Run(
 		ctx,
 		listeners[rdt],
-		assemblestate.NewHTTPSTransport(),
+		nil,
 		disco,
 		assemblestate.RunOptions{Period: time.Millisecond * 100},
 	)
@@ -38,6 +38,34 @@ func AssertionDevices(ids []Identity, routes Routes) ([]any, error) {
 		return nil, err
 	}
 
+	serials, err := identitySerials(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// sort identities based on brand, model, then serial so that numeric id
+	// assignment is consistent, even across multiple assemble sessions.
+	sortIdentities(ids, serials)
+
+	devices := make([]any, 0, len(ids))
+	for i, identity := range ids {
+		addrs := addresses[identity.RDT]
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no addresses available for device %q", identity.RDT)
+		}
+
+		serial := serials[identity.RDT]
+		devices = append(devices, map[string]any{
+			"id":        strconv.Itoa(i + 1),
+			"device":    serial.DeviceID().String(),
+			"addresses": addrs,
+		})
+	}
+
+	return devices, nil
+}
+
+func identitySerials(ids []Identity) (map[DeviceToken]*asserts.Serial, error) {
 	serials := make(map[DeviceToken]*asserts.Serial, len(ids))
 	for _, identity := range ids {
 		serial, err := serialFromBundle(identity.SerialBundle)
@@ -51,9 +79,10 @@ func AssertionDevices(ids []Identity, routes Routes) ([]any, error) {
 
 		serials[identity.RDT] = serial
 	}
+	return serials, nil
+}
 
-	// sort identities based on brand, model, then serial so that numeric id
-	// assignment is consistent, even across multiple assemble sessions.
+func sortIdentities(ids []Identity, serials map[DeviceToken]*asserts.Serial) {
 	sort.Slice(ids, func(i, j int) bool {
 		left := serials[ids[i].RDT]
 		right := serials[ids[j].RDT]
@@ -67,23 +96,6 @@ func AssertionDevices(ids []Identity, routes Routes) ([]any, error) {
 
 		return left.Serial() < right.Serial()
 	})
-
-	devices := make([]any, 0, len(ids))
-	for i, identity := range ids {
-		addrs := addresses[identity.RDT]
-		if len(addrs) == 0 {
-			return nil, fmt.Errorf("no addresses available for device %q", identity.RDT)
-		}
-
-		serial := serials[identity.RDT]
-		devices = append(devices, map[string]any{
-			"id":        strconv.Itoa(i + 1),
-			"device":    serial.DeviceID().String(),
-			"addresses": addrs,
-		})
-	}
-
-	return devices, nil
 }
 
 func serialFromBundle(bundle string) (*asserts.Serial, error) {
@@ -0,0 +1,502 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assemblestate
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+// MDNSService is the DNS-SD service type under which devices taking part in an
+// assembly session advertise themselves on the local link.
+const MDNSService = "_snapd-assemble._tcp.local."
+
+const (
+	dnsTypeA    = 1
+	dnsTypePTR  = 12
+	dnsTypeTXT  = 16
+	dnsTypeAAAA = 28
+	dnsTypeSRV  = 33
+	dnsTypeANY  = 255
+
+	dnsClassIN = 1
+	// mdnsCacheFlush marks records which are unique to their owner, see RFC
+	// 6762, section 10.2.
+	mdnsCacheFlush = 0x8000
+
+	mdnsTTL = 120
+)
+
+var (
+	mdnsGroupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+	mdnsListen = func(iface *net.Interface) (net.PacketConn, error) {
+		return net.ListenMulticastUDP("udp4", iface, mdnsGroupAddr)
+	}
+)
+
+// MDNSOptions carries the options of an [MDNSDiscovery].
+type MDNSOptions struct {
+	// Instance is the DNS-SD instance name under which this device is
+	// advertised, usually its RDT. It must be unique on the local link.
+	Instance string
+	// Interface is the network interface to use. If nil, the system default
+	// is used.
+	Interface *net.Interface
+	// Interval is how often peers are queried for. Defaults to 5 seconds.
+	Interval time.Duration
+}
+
+// MDNSDiscovery finds the peers taking part in an assembly session on the
+// local link using multicast DNS service discovery (mDNS/DNS-SD), for devices
+// that don't know the addresses of their peers beforehand. Each device
+// advertises the address of its assembly server as an instance of the
+// [MDNSService] service and browses for the instances of the others.
+type MDNSDiscovery struct {
+	opts MDNSOptions
+}
+
+// NewMDNSDiscovery creates a new [MDNSDiscovery] with the given options.
+func NewMDNSDiscovery(opts MDNSOptions) (*MDNSDiscovery, error) {
+	if opts.Instance == "" {
+		return nil, errors.New("mDNS instance name is required")
+	}
+	// the instance name is a single DNS label
+	if len(opts.Instance) > 63 || strings.ContainsAny(opts.Instance, ".") {
+		return nil, fmt.Errorf("invalid mDNS instance name %q", opts.Instance)
+	}
+	if opts.Interval == 0 {
+		opts.Interval = 5 * time.Second
+	}
+
+	return &MDNSDiscovery{opts: opts}, nil
+}
+
+func (d *MDNSDiscovery) instanceName() string {
+	return d.opts.Instance + "." + MDNSService
+}
+
+// Discover advertises this device as reachable at the given address, usually
+// the one of the listener given to [AssembleState.Run], and sends the
+// addresses of the peers found on the local link to discoveries until the
+// context is cancelled. Each peer address is only reported once.
+//
+// If the host part of addr is unspecified, e.g. when listening on all
+// interfaces, peers reach this device at the source address of its mDNS
+// messages.
+func (d *MDNSDiscovery) Discover(ctx context.Context, addr string, discoveries chan<- []string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address to advertise: %v", err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port to advertise: %q", portStr)
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsUnspecified() {
+		ip = nil
+	}
+
+	conn, err := mdnsListen(d.opts.Interface)
+	if err != nil {
+		return fmt.Errorf("cannot listen for mDNS messages: %v", err)
+	}
+
+	announcement := d.announcement(uint16(port), ip)
+	query := encodeDNSMessage(&dnsMessage{
+		questions: []dnsQuestion{{name: MDNSService, qtype: dnsTypePTR}},
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.receive(ctx, conn, announcement, discoveries)
+	}()
+
+	// announce ourselves right away, so that peers already browsing find us
+	// without waiting for their next query
+	send := func(msg []byte) {
+		if _, err := conn.WriteTo(msg, mdnsGroupAddr); err != nil {
+			logger.Debugf("cannot send mDNS message: %v", err)
+		}
+	}
+	send(announcement)
+	send(query)
+
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			send(query)
+		case <-ctx.Done():
+			conn.Close()
+			wg.Wait()
+			return nil
+		}
+	}
+}
+
+// announcement returns the mDNS response advertising this device.
+func (d *MDNSDiscovery) announcement(port uint16, ip net.IP) []byte {
+	target := d.opts.Instance + ".local."
+	msg := &dnsMessage{
+		response: true,
+		answers: []dnsRecord{
+			{name: MDNSService, rtype: dnsTypePTR, target: d.instanceName()},
+			{name: d.instanceName(), rtype: dnsTypeSRV, target: target, port: port},
+			// DNS-SD requires a TXT record, even if empty
+			{name: d.instanceName(), rtype: dnsTypeTXT},
+		},
+	}
+	if ip != nil {
+		rtype := uint16(dnsTypeA)
+		if ip.To4() == nil {
+			rtype = dnsTypeAAAA
+		}
+		msg.answers = append(msg.answers, dnsRecord{name: target, rtype: rtype, ip: ip})
+	}
+	return encodeDNSMessage(msg)
+}
+
+func (d *MDNSDiscovery) receive(ctx context.Context, conn net.PacketConn, announcement []byte, discoveries chan<- []string) {
+	seen := make(map[string]bool)
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Debugf("cannot receive mDNS messages: %v", err)
+			}
+			return
+		}
+
+		msg, err := decodeDNSMessage(buf[:n])
+		if err != nil {
+			// not for us to fix
+			continue
+		}
+
+		if !msg.response {
+			if d.isQueried(msg) {
+				if _, err := conn.WriteTo(announcement, mdnsGroupAddr); err != nil {
+					logger.Debugf("cannot answer mDNS query: %v", err)
+				}
+			}
+			continue
+		}
+
+		var found []string
+		for _, addr := range d.peerAddresses(msg, src) {
+			if !seen[addr] {
+				seen[addr] = true
+				found = append(found, addr)
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		logger.Debugf("discovered %d peer(s) over mDNS", len(found))
+		select {
+		case discoveries <- found:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// isQueried returns whether the given query asks for the instances of the
+// assembly service.
+func (d *MDNSDiscovery) isQueried(msg *dnsMessage) bool {
+	for _, q := range msg.questions {
+		if strings.EqualFold(q.name, MDNSService) && (q.qtype == dnsTypePTR || q.qtype == dnsTypeANY) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerAddresses returns the addresses of the peers advertised in the given
+// response, other than this device.
+func (d *MDNSDiscovery) peerAddresses(msg *dnsMessage, src net.Addr) []string {
+	hosts := make(map[string]net.IP)
+	for _, rr := range msg.answers {
+		if rr.rtype == dnsTypeA || rr.rtype == dnsTypeAAAA {
+			hosts[strings.ToLower(rr.name)] = rr.ip
+		}
+	}
+
+	var addrs []string
+	for _, rr := range msg.answers {
+		if rr.rtype != dnsTypeSRV {
+			continue
+		}
+		instance, ok := cutSuffixFold(rr.name, "."+MDNSService)
+		if !ok || strings.EqualFold(instance, d.opts.Instance) {
+			continue
+		}
+
+		ip := hosts[strings.ToLower(rr.target)]
+		if ip == nil {
+			udpAddr, ok := src.(*net.UDPAddr)
+			if !ok {
+				continue
+			}
+			ip = udpAddr.IP
+		}
+		addrs = append(addrs, net.JoinHostPort(ip.String(), strconv.Itoa(int(rr.port))))
+	}
+	return addrs
+}
+
+func cutSuffixFold(s, suffix string) (string, bool) {
+	if len(s) < len(suffix) || !strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+		return s, false
+	}
+	return s[:len(s)-len(suffix)], true
+}
+
+// dnsMessage is the subset of a DNS message used by mDNS service discovery.
+type dnsMessage struct {
+	response  bool
+	questions []dnsQuestion
+	// answers holds the records of all the sections of the message.
+	answers []dnsRecord
+}
+
+type dnsQuestion struct {
+	name  string
+	qtype uint16
+}
+
+type dnsRecord struct {
+	name  string
+	rtype uint16
+
+	// target is set for PTR and SRV records.
+	target string
+	// port is set for SRV records.
+	port uint16
+	// ip is set for A and AAAA records.
+	ip net.IP
+}
+
+func appendDNSName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func encodeDNSMessage(msg *dnsMessage) []byte {
+	b := make([]byte, 0, 512)
+
+	// mDNS messages have a zero ID
+	b = appendUint16(b, 0)
+	var flags uint16
+	if msg.response {
+		// response, authoritative answer
+		flags = 0x8400
+	}
+	b = appendUint16(b, flags)
+	b = appendUint16(b, uint16(len(msg.questions)))
+	b = appendUint16(b, uint16(len(msg.answers)))
+	b = appendUint16(b, 0)
+	b = appendUint16(b, 0)
+
+	for _, q := range msg.questions {
+		b = appendDNSName(b, q.name)
+		b = appendUint16(b, q.qtype)
+		b = appendUint16(b, dnsClassIN)
+	}
+
+	for _, rr := range msg.answers {
+		var data []byte
+		class := uint16(dnsClassIN | mdnsCacheFlush)
+		switch rr.rtype {
+		case dnsTypePTR:
+			// shared record, there are many instances of a service
+			class = dnsClassIN
+			data = appendDNSName(nil, rr.target)
+		case dnsTypeSRV:
+			// priority and weight
+			data = []byte{0, 0, 0, 0}
+			data = appendUint16(data, rr.port)
+			data = appendDNSName(data, rr.target)
+		case dnsTypeTXT:
+			// a single empty string
+			data = []byte{0}
+		case dnsTypeA:
+			data = rr.ip.To4()
+		case dnsTypeAAAA:
+			data = rr.ip.To16()
+		}
+
+		b = appendDNSName(b, rr.name)
+		b = appendUint16(b, rr.rtype)
+		b = appendUint16(b, class)
+		b = append(b, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], mdnsTTL)
+		b = appendUint16(b, uint16(len(data)))
+		b = append(b, data...)
+	}
+
+	return b
+}
+
+var errDNSTruncated = errors.New("truncated DNS message")
+
+// readDNSName reads the possibly compressed name at the given offset of msg,
+// returning it along with the offset following it.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	// bound the number of compression pointers followed to avoid loops
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errDNSTruncated
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errDNSTruncated
+			}
+			if jumps++; jumps > 16 {
+				return "", 0, errors.New("too many compression pointers in DNS name")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		case n&0xc0 != 0:
+			return "", 0, fmt.Errorf("invalid DNS label length %d", n)
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errDNSTruncated
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+func decodeDNSMessage(b []byte) (*dnsMessage, error) {
+	if len(b) < 12 {
+		return nil, errDNSTruncated
+	}
+
+	msg := &dnsMessage{
+		response: b[2]&0x80 != 0,
+	}
+	qdcount := int(binary.BigEndian.Uint16(b[4:]))
+	rrcount := int(binary.BigEndian.Uint16(b[6:])) + int(binary.BigEndian.Uint16(b[8:])) + int(binary.BigEndian.Uint16(b[10:]))
+
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		name, next, err := readDNSName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(b) {
+			return nil, errDNSTruncated
+		}
+		msg.questions = append(msg.questions, dnsQuestion{
+			name:  name,
+			qtype: binary.BigEndian.Uint16(b[next:]),
+		})
+		off = next + 4
+	}
+
+	for i := 0; i < rrcount; i++ {
+		name, next, err := readDNSName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(b) {
+			return nil, errDNSTruncated
+		}
+		rtype := binary.BigEndian.Uint16(b[next:])
+		length := int(binary.BigEndian.Uint16(b[next+8:]))
+		start := next + 10
+		if start+length > len(b) {
+			return nil, errDNSTruncated
+		}
+		data := b[start : start+length]
+		off = start + length
+
+		rr := dnsRecord{name: name, rtype: rtype}
+		switch rtype {
+		case dnsTypePTR:
+			if rr.target, _, err = readDNSName(b, start); err != nil {
+				return nil, err
+			}
+		case dnsTypeSRV:
+			if length < 7 {
+				return nil, errDNSTruncated
+			}
+			rr.port = binary.BigEndian.Uint16(data[4:])
+			if rr.target, _, err = readDNSName(b, start+6); err != nil {
+				return nil, err
+			}
+		case dnsTypeA:
+			if length != net.IPv4len {
+				return nil, errors.New("invalid A record")
+			}
+			rr.ip = net.IP(append([]byte(nil), data...))
+		case dnsTypeAAAA:
+			if length != net.IPv6len {
+				return nil, errors.New("invalid AAAA record")
+			}
+			rr.ip = net.IP(append([]byte(nil), data...))
+		default:
+			// not relevant to service discovery
+			continue
+		}
+		msg.answers = append(msg.answers, rr)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,300 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assemblestate
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/testutil"
+)
+
+type mdnsSuite struct{}
+
+var _ = check.Suite(&mdnsSuite{})
+
+type mdnsPacket struct {
+	data []byte
+	src  net.Addr
+}
+
+// mdnsBus connects fake multicast sockets, delivering what one sends to all
+// the others.
+type mdnsBus struct {
+	lock  sync.Mutex
+	conns []*fakeMDNSConn
+}
+
+func (b *mdnsBus) listen(ip net.IP) *fakeMDNSConn {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	conn := &fakeMDNSConn{
+		bus:    b,
+		addr:   &net.UDPAddr{IP: ip, Port: 5353},
+		in:     make(chan mdnsPacket, 64),
+		closed: make(chan struct{}),
+	}
+	b.conns = append(b.conns, conn)
+	return conn
+}
+
+type fakeMDNSConn struct {
+	bus    *mdnsBus
+	addr   *net.UDPAddr
+	in     chan mdnsPacket
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (c *fakeMDNSConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case pkt := <-c.in:
+		return copy(p, pkt.data), pkt.src, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *fakeMDNSConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.bus.lock.Lock()
+	defer c.bus.lock.Unlock()
+
+	for _, other := range c.bus.conns {
+		if other == c {
+			continue
+		}
+		select {
+		case other.in <- mdnsPacket{data: append([]byte(nil), p...), src: c.addr}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (c *fakeMDNSConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *fakeMDNSConn) LocalAddr() net.Addr                { return c.addr }
+func (c *fakeMDNSConn) SetDeadline(t time.Time) error      { return nil }
+func (c *fakeMDNSConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *fakeMDNSConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (s *mdnsSuite) TestNewMDNSDiscoveryErrors(c *check.C) {
+	_, err := NewMDNSDiscovery(MDNSOptions{})
+	c.Assert(err, check.ErrorMatches, "mDNS instance name is required")
+
+	_, err = NewMDNSDiscovery(MDNSOptions{Instance: "a.b"})
+	c.Assert(err, check.ErrorMatches, `invalid mDNS instance name "a.b"`)
+}
+
+func (s *mdnsSuite) TestDiscover(c *check.C) {
+	bus := &mdnsBus{}
+	ips := []net.IP{net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), net.IPv4(10, 0, 0, 3)}
+	listening := make(chan struct{})
+	next := 0
+	restore := testutil.Mock(&mdnsListen, func(iface *net.Interface) (net.PacketConn, error) {
+		conn := bus.listen(ips[next])
+		next++
+		listening <- struct{}{}
+		return conn, nil
+	})
+	defer restore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the first two listen on all interfaces and are reached at the source
+	// of their messages, the last one advertises an explicit address
+	advertised := []string{"0.0.0.0:8001", "[::]:8002", "192.168.1.3:8003"}
+	expected := []string{"10.0.0.1:8001", "10.0.0.2:8002", "192.168.1.3:8003"}
+
+	var wg sync.WaitGroup
+	results := make([]chan []string, len(advertised))
+	for i, addr := range advertised {
+		d, err := NewMDNSDiscovery(MDNSOptions{
+			Instance: "rdt-" + string(rune('a'+i)),
+			Interval: 10 * time.Millisecond,
+		})
+		c.Assert(err, check.IsNil)
+
+		results[i] = make(chan []string, 16)
+		wg.Add(1)
+		go func(addr string, out chan<- []string) {
+			defer wg.Done()
+			c.Check(d.Discover(ctx, addr, out), check.IsNil)
+		}(addr, results[i])
+
+		// start the discoverers one at a time so that they get the expected
+		// addresses
+		<-listening
+	}
+
+	for i := range advertised {
+		var found []string
+		timeout := time.After(5 * time.Second)
+		for len(found) < len(advertised)-1 {
+			select {
+			case addrs := <-results[i]:
+				found = append(found, addrs...)
+			case <-timeout:
+				c.Fatalf("discovered only %v", found)
+			}
+		}
+		sort.Strings(found)
+
+		var others []string
+		for j, addr := range expected {
+			if j != i {
+				others = append(others, addr)
+			}
+		}
+		c.Check(found, check.DeepEquals, others)
+	}
+
+	cancel()
+	wg.Wait()
+
+	// addresses are only reported once
+	for i := range results {
+		select {
+		case addrs := <-results[i]:
+			c.Errorf("unexpected rediscovery of %v", addrs)
+		default:
+		}
+	}
+}
+
+func (s *mdnsSuite) TestDiscoverErrors(c *check.C) {
+	d, err := NewMDNSDiscovery(MDNSOptions{Instance: "rdt"})
+	c.Assert(err, check.IsNil)
+
+	err = d.Discover(context.Background(), "garbage", nil)
+	c.Assert(err, check.ErrorMatches, "invalid address to advertise: .*")
+
+	err = d.Discover(context.Background(), "127.0.0.1:http", nil)
+	c.Assert(err, check.ErrorMatches, `invalid port to advertise: "http"`)
+
+	restore := testutil.Mock(&mdnsListen, func(iface *net.Interface) (net.PacketConn, error) {
+		return nil, net.ErrClosed
+	})
+	defer restore()
+
+	err = d.Discover(context.Background(), "127.0.0.1:8001", nil)
+	c.Assert(err, check.ErrorMatches, "cannot listen for mDNS messages: .*")
+}
+
+func (s *mdnsSuite) TestDNSMessageRoundTrip(c *check.C) {
+	msg := &dnsMessage{
+		response: true,
+		answers: []dnsRecord{
+			{name: MDNSService, rtype: dnsTypePTR, target: "rdt." + MDNSService},
+			{name: "rdt." + MDNSService, rtype: dnsTypeSRV, target: "rdt.local.", port: 8001},
+			{name: "rdt." + MDNSService, rtype: dnsTypeTXT},
+			{name: "rdt.local.", rtype: dnsTypeA, ip: net.IPv4(10, 0, 0, 1).To4()},
+			{name: "rdt.local.", rtype: dnsTypeAAAA, ip: net.ParseIP("fe80::1")},
+		},
+	}
+
+	decoded, err := decodeDNSMessage(encodeDNSMessage(msg))
+	c.Assert(err, check.IsNil)
+	c.Check(decoded.response, check.Equals, true)
+	// TXT records are not kept
+	c.Check(decoded.answers, check.DeepEquals, append(msg.answers[:2:2], msg.answers[3:]...))
+
+	query := &dnsMessage{questions: []dnsQuestion{{name: MDNSService, qtype: dnsTypePTR}}}
+	decoded, err = decodeDNSMessage(encodeDNSMessage(query))
+	c.Assert(err, check.IsNil)
+	c.Check(decoded, check.DeepEquals, query)
+}
+
+func (s *mdnsSuite) TestDecodeDNSMessageCompressedNames(c *check.C) {
+	b := []byte{
+		0, 0, 0x84, 0, // id, flags
+		0, 0, 0, 1, 0, 0, 0, 0, // one answer
+	}
+	// the service name, at offset 12
+	b = appendDNSName(b, MDNSService)
+	b = appendUint16(b, dnsTypeSRV)
+	b = appendUint16(b, dnsClassIN)
+	b = append(b, 0, 0, 0, 120)
+	// priority, weight, port and target "peer" + pointer to ".local." in the
+	// service name
+	data := []byte{0, 0, 0, 0, 0x1f, 0x41, 4, 'p', 'e', 'e', 'r', 0xc0, byte(12 + 1 + len("_snapd-assemble") + 1 + len("_tcp"))}
+	b = appendUint16(b, uint16(len(data)))
+	b = append(b, data...)
+
+	msg, err := decodeDNSMessage(b)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.answers, check.HasLen, 1)
+	c.Check(msg.answers[0].name, check.Equals, MDNSService)
+	c.Check(msg.answers[0].port, check.Equals, uint16(8001))
+	c.Check(msg.answers[0].target, check.Equals, "peer.local.")
+}
+
+func (s *mdnsSuite) TestDecodeDNSMessageErrors(c *check.C) {
+	valid := encodeDNSMessage(&dnsMessage{
+		response: true,
+		answers: []dnsRecord{
+			{name: "rdt." + MDNSService, rtype: dnsTypeSRV, target: "rdt.local.", port: 8001},
+		},
+	})
+
+	// every truncation of a valid message is rejected
+	for i := 0; i < len(valid); i++ {
+		_, err := decodeDNSMessage(valid[:i])
+		c.Check(err, check.NotNil, check.Commentf("length %d", i))
+	}
+
+	// compression loop
+	loop := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xc0, 12, 0, 12, 0, 1}
+	_, err := decodeDNSMessage(loop)
+	c.Check(err, check.ErrorMatches, "too many compression pointers in DNS name")
+
+	badLabel := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0x40, 0, 0, 12, 0, 1}
+	_, err = decodeDNSMessage(badLabel)
+	c.Check(err, check.ErrorMatches, "invalid DNS label length 64")
+}
+
+func (s *mdnsSuite) TestPeerAddressesIgnoresOthers(c *check.C) {
+	d, err := NewMDNSDiscovery(MDNSOptions{Instance: "self"})
+	c.Assert(err, check.IsNil)
+
+	msg := &dnsMessage{
+		response: true,
+		answers: []dnsRecord{
+			// ourselves
+			{name: "self." + MDNSService, rtype: dnsTypeSRV, target: "self.local.", port: 8001},
+			// another service
+			{name: "printer._ipp._tcp.local.", rtype: dnsTypeSRV, target: "printer.local.", port: 631},
+			// a peer
+			{name: "PEER." + MDNSService, rtype: dnsTypeSRV, target: "peer.local.", port: 8002},
+		},
+	}
+	src := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 5353}
+	c.Check(d.peerAddresses(msg, src), check.DeepEquals, []string{"10.0.0.2:8002"})
+}
@@ -0,0 +1,267 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assemblestate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// MembershipChanges describes how an assembly session run against an existing
+// cluster changes the devices that are part of it.
+type MembershipChanges struct {
+	// Replace maps the ids of devices of the cluster that failed to the device
+	// ids ("<serial>.<model>.<brand-id>") of the devices that take their place.
+	// A replacement joins the subclusters of the device that it replaces.
+	Replace map[int]string `json:"replace,omitempty"`
+	// Remove lists the ids of devices that leave the cluster without being
+	// replaced.
+	Remove []int `json:"remove,omitempty"`
+	// Add is the number of devices that join the cluster in addition to the
+	// replacements. They are not part of any subcluster.
+	Add int `json:"add,omitempty"`
+}
+
+// leaving returns the ids of the devices of the cluster that are replaced or
+// removed, after checking that the changes apply to the cluster.
+func (mc *MembershipChanges) leaving(cluster *asserts.Cluster) (map[int]bool, error) {
+	if mc.Add < 0 {
+		return nil, fmt.Errorf("invalid number of devices to add: %d", mc.Add)
+	}
+
+	known := make(map[int]bool, len(cluster.Devices()))
+	members := make(map[string]bool, len(cluster.Devices()))
+	for _, dev := range cluster.Devices() {
+		known[dev.ID] = true
+		members[dev.DeviceID.String()] = true
+	}
+
+	leaving := make(map[int]bool, len(mc.Replace)+len(mc.Remove))
+	replacements := make(map[string]bool, len(mc.Replace))
+	for id, replacement := range mc.Replace {
+		if !known[id] {
+			return nil, fmt.Errorf("cannot replace unknown device id %d", id)
+		}
+		if members[replacement] {
+			return nil, fmt.Errorf("cannot replace device id %d with %q: device is already part of the cluster", id, replacement)
+		}
+		if replacements[replacement] {
+			return nil, fmt.Errorf("cannot replace more than one device with %q", replacement)
+		}
+		replacements[replacement] = true
+		leaving[id] = true
+	}
+
+	for _, id := range mc.Remove {
+		if !known[id] {
+			return nil, fmt.Errorf("cannot remove unknown device id %d", id)
+		}
+		if leaving[id] {
+			return nil, fmt.Errorf("cannot both remove and replace device id %d", id)
+		}
+		leaving[id] = true
+	}
+
+	return leaving, nil
+}
+
+// MembershipSeed returns what an assembly session changing the membership of
+// the given cluster starts from: the addresses of the devices that stay in the
+// cluster, to be used as discovered addresses, and the expected size of the
+// session.
+func MembershipSeed(cluster *asserts.Cluster, changes MembershipChanges) (addresses []string, expectedSize int, err error) {
+	leaving, err := changes.leaving(cluster)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, dev := range cluster.Devices() {
+		if leaving[dev.ID] {
+			continue
+		}
+		addresses = append(addresses, dev.Addresses...)
+		expectedSize++
+	}
+
+	return addresses, expectedSize + len(changes.Replace) + changes.Add, nil
+}
+
+// IdentityFingerprints returns the fingerprints of the certificates that the
+// devices of an assembly session used, by device id ("<serial>.<model>.<brand-id>").
+// Once the session is over, devices keep using these certificates to
+// communicate with each other.
+func IdentityFingerprints(ids []Identity) (map[string]Fingerprint, error) {
+	serials, err := identitySerials(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	fps := make(map[string]Fingerprint, len(ids))
+	for _, identity := range ids {
+		fps[serials[identity.RDT].DeviceID().String()] = identity.FP
+	}
+	return fps, nil
+}
+
+// UpdatedClusterHeaders returns the headers of the cluster assertion that
+// follows the given one in its sequence, given the data returned by an
+// assembly session seeded with [MembershipSeed]. The returned headers are
+// meant to be signed by the authority of the cluster.
+//
+// Devices that stay in the cluster keep their ids and their subclusters, and
+// their addresses are refreshed if they took part in the session. Devices new
+// to the cluster get ids following the highest id in use.
+func UpdatedClusterHeaders(cluster *asserts.Cluster, ids []Identity, routes Routes, changes MembershipChanges) (map[string]any, error) {
+	leaving, err := changes.leaving(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := addressesFromRoutes(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	serials, err := identitySerials(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// sorted so that id assignment is consistent across sessions, like with
+	// AssertionDevices
+	sortIdentities(ids, serials)
+
+	current := make(map[string]asserts.ClusterDevice, len(cluster.Devices()))
+	nextID := 1
+	for _, dev := range cluster.Devices() {
+		current[dev.DeviceID.String()] = dev
+		if dev.ID >= nextID {
+			nextID = dev.ID + 1
+		}
+	}
+
+	// addresses of the devices that took part in the session, and ids of the
+	// new ones
+	seen := make(map[int][]any, len(ids))
+	joined := make(map[string]int)
+	var newDevices []any
+	for _, identity := range ids {
+		addrs := addresses[identity.RDT]
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no addresses available for device %q", identity.RDT)
+		}
+
+		deviceID := serials[identity.RDT].DeviceID().String()
+		if dev, ok := current[deviceID]; ok {
+			if leaving[dev.ID] {
+				return nil, fmt.Errorf("device id %d is leaving the cluster but took part in the assembly session", dev.ID)
+			}
+			seen[dev.ID] = addrs
+			continue
+		}
+
+		joined[deviceID] = nextID
+		newDevices = append(newDevices, map[string]any{
+			"id":        strconv.Itoa(nextID),
+			"device":    deviceID,
+			"addresses": addrs,
+		})
+		nextID++
+	}
+
+	for id, replacement := range changes.Replace {
+		if _, ok := joined[replacement]; !ok {
+			return nil, fmt.Errorf("replacement %q for device id %d did not take part in the assembly session", replacement, id)
+		}
+	}
+	if added := len(joined) - len(changes.Replace); added != changes.Add {
+		return nil, fmt.Errorf("expected %d devices to join the cluster in addition to the replacements, got %d", changes.Add, added)
+	}
+
+	devices := make([]any, 0, len(cluster.Devices())+len(newDevices))
+	for _, dev := range cluster.Devices() {
+		if leaving[dev.ID] {
+			continue
+		}
+
+		addrs, ok := seen[dev.ID]
+		if !ok {
+			addrs = make([]any, 0, len(dev.Addresses))
+			for _, addr := range dev.Addresses {
+				addrs = append(addrs, addr)
+			}
+		}
+
+		devices = append(devices, map[string]any{
+			"id":        strconv.Itoa(dev.ID),
+			"device":    dev.DeviceID.String(),
+			"addresses": addrs,
+		})
+	}
+	devices = append(devices, newDevices...)
+
+	subclusters := make([]any, 0, len(cluster.Subclusters()))
+	for _, sub := range cluster.Subclusters() {
+		var members []int
+		for _, id := range sub.Devices {
+			switch {
+			case changes.Replace[id] != "":
+				members = append(members, joined[changes.Replace[id]])
+			case !leaving[id]:
+				members = append(members, id)
+			}
+		}
+		sort.Ints(members)
+
+		memberIDs := make([]any, 0, len(members))
+		for _, id := range members {
+			memberIDs = append(memberIDs, strconv.Itoa(id))
+		}
+
+		snaps := make([]any, 0, len(sub.Snaps))
+		for _, sn := range sub.Snaps {
+			snaps = append(snaps, map[string]any{
+				"state":    string(sn.State),
+				"instance": sn.Instance,
+				"channel":  sn.Channel,
+			})
+		}
+
+		subclusters = append(subclusters, map[string]any{
+			"name":    sub.Name,
+			"devices": memberIDs,
+			"snaps":   snaps,
+		})
+	}
+
+	return map[string]any{
+		"type":         asserts.ClusterType.Name,
+		"authority-id": cluster.AuthorityID(),
+		"cluster-id":   cluster.ClusterID(),
+		"sequence":     strconv.Itoa(cluster.Sequence() + 1),
+		"devices":      devices,
+		"subclusters":  subclusters,
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
@@ -0,0 +1,302 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assemblestate_test
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/cluster/assemblestate"
+)
+
+type membershipFixture struct {
+	signing *assertstest.SigningDB
+	cluster *asserts.Cluster
+
+	// identities of devices taking part in assembly sessions, by device id
+	identities map[string]assemblestate.Identity
+}
+
+// newMembershipFixture creates a cluster of three devices along with the
+// identities of two of them, and of two devices new to the cluster.
+func newMembershipFixture(c *check.C) *membershipFixture {
+	key, _ := assertstest.GenerateKey(752)
+	f := &membershipFixture{
+		signing:    assertstest.NewSigningDB("cluster-brand", key),
+		identities: make(map[string]assemblestate.Identity),
+	}
+
+	for i, serial := range []string{"serial-1", "serial-3", "serial-4", "serial-5"} {
+		s, bundle, _ := makeBundleWithID(c, "brand", "model", serial)
+		rdt := assemblestate.DeviceToken("rdt-" + serial)
+		f.identities[s.DeviceID().String()] = assemblestate.Identity{
+			RDT:          rdt,
+			FP:           assemblestate.CalculateFP([]byte{byte(i)}),
+			SerialBundle: bundle,
+		}
+	}
+
+	headers := map[string]any{
+		"type":       "cluster",
+		"cluster-id": "cluster-id",
+		"sequence":   "3",
+		"devices": []any{
+			map[string]any{"id": "1", "device": "serial-1.model.brand", "addresses": []any{"10.0.0.1:8001"}},
+			map[string]any{"id": "2", "device": "serial-2.model.brand", "addresses": []any{"10.0.0.2:8001"}},
+			map[string]any{"id": "3", "device": "serial-3.model.brand", "addresses": []any{"10.0.0.3:8001"}},
+		},
+		"subclusters": []any{
+			map[string]any{
+				"name":    "default",
+				"devices": []any{"1", "2", "3"},
+				"snaps": []any{
+					map[string]any{"state": "clustered", "instance": "microceph", "channel": "squid/stable"},
+				},
+			},
+			map[string]any{
+				"name":    "db",
+				"devices": []any{"2"},
+				"snaps":   []any{},
+			},
+		},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+
+	a, err := f.signing.Sign(asserts.ClusterType, headers, nil, "")
+	c.Assert(err, check.IsNil)
+	f.cluster = a.(*asserts.Cluster)
+
+	return f
+}
+
+// session returns the results of an assembly session between the given
+// devices, each reachable at the given address.
+func (f *membershipFixture) session(addrs map[string]string) ([]assemblestate.Identity, assemblestate.Routes) {
+	var ids []assemblestate.Identity
+	var routes assemblestate.Routes
+	for device, addr := range addrs {
+		ids = append(ids, f.identities[device])
+		routes.Devices = append(routes.Devices, f.identities[device].RDT)
+		routes.Addresses = append(routes.Addresses, addr)
+	}
+
+	for i := range routes.Devices {
+		for j := range routes.Devices {
+			if i != j {
+				routes.Routes = append(routes.Routes, i, j, j)
+			}
+		}
+	}
+	return ids, routes
+}
+
+func (s *assembleSuite) TestMembershipSeed(c *check.C) {
+	f := newMembershipFixture(c)
+
+	addrs, size, err := assemblestate.MembershipSeed(f.cluster, assemblestate.MembershipChanges{
+		Replace: map[int]string{2: "serial-4.model.brand"},
+		Add:     1,
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(addrs, check.DeepEquals, []string{"10.0.0.1:8001", "10.0.0.3:8001"})
+	c.Check(size, check.Equals, 4)
+
+	addrs, size, err = assemblestate.MembershipSeed(f.cluster, assemblestate.MembershipChanges{
+		Remove: []int{1},
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(addrs, check.DeepEquals, []string{"10.0.0.2:8001", "10.0.0.3:8001"})
+	c.Check(size, check.Equals, 2)
+}
+
+func (s *assembleSuite) TestUpdatedClusterHeadersReplaceAndAdd(c *check.C) {
+	f := newMembershipFixture(c)
+
+	ids, routes := f.session(map[string]string{
+		"serial-1.model.brand": "10.0.1.1:8001",
+		"serial-3.model.brand": "10.0.1.3:8001",
+		"serial-4.model.brand": "10.0.1.4:8001",
+		"serial-5.model.brand": "10.0.1.5:8001",
+	})
+
+	headers, err := assemblestate.UpdatedClusterHeaders(f.cluster, ids, routes, assemblestate.MembershipChanges{
+		Replace: map[int]string{2: "serial-5.model.brand"},
+		Add:     1,
+	})
+	c.Assert(err, check.IsNil)
+
+	c.Check(headers["type"], check.Equals, "cluster")
+	c.Check(headers["authority-id"], check.Equals, "cluster-brand")
+	c.Check(headers["cluster-id"], check.Equals, "cluster-id")
+	c.Check(headers["sequence"], check.Equals, "4")
+	c.Check(headers["timestamp"], check.Not(check.Equals), "")
+
+	c.Check(headers["devices"], check.DeepEquals, []any{
+		map[string]any{"id": "1", "device": "serial-1.model.brand", "addresses": []any{"10.0.1.1:8001"}},
+		map[string]any{"id": "3", "device": "serial-3.model.brand", "addresses": []any{"10.0.1.3:8001"}},
+		// new devices are numbered in order of their serial
+		map[string]any{"id": "4", "device": "serial-4.model.brand", "addresses": []any{"10.0.1.4:8001"}},
+		map[string]any{"id": "5", "device": "serial-5.model.brand", "addresses": []any{"10.0.1.5:8001"}},
+	})
+	c.Check(headers["subclusters"], check.DeepEquals, []any{
+		map[string]any{
+			"name":    "default",
+			"devices": []any{"1", "3", "5"},
+			"snaps": []any{
+				map[string]any{"state": "clustered", "instance": "microceph", "channel": "squid/stable"},
+			},
+		},
+		map[string]any{
+			"name":    "db",
+			"devices": []any{"5"},
+			"snaps":   []any{},
+		},
+	})
+
+	// the headers form the next assertion of the sequence
+	a, err := f.signing.Sign(asserts.ClusterType, headers, nil, "")
+	c.Assert(err, check.IsNil)
+	cluster := a.(*asserts.Cluster)
+	c.Check(cluster.Sequence(), check.Equals, 4)
+	c.Check(cluster.Devices(), check.HasLen, 4)
+}
+
+func (s *assembleSuite) TestUpdatedClusterHeadersRemove(c *check.C) {
+	f := newMembershipFixture(c)
+
+	// device 3 could not take part in the session, it keeps its address
+	ids, routes := f.session(map[string]string{
+		"serial-1.model.brand": "10.0.1.1:8001",
+		"serial-4.model.brand": "10.0.1.4:8001",
+	})
+
+	headers, err := assemblestate.UpdatedClusterHeaders(f.cluster, ids, routes, assemblestate.MembershipChanges{
+		Remove: []int{2},
+		Add:    1,
+	})
+	c.Assert(err, check.IsNil)
+
+	c.Check(headers["devices"], check.DeepEquals, []any{
+		map[string]any{"id": "1", "device": "serial-1.model.brand", "addresses": []any{"10.0.1.1:8001"}},
+		map[string]any{"id": "3", "device": "serial-3.model.brand", "addresses": []any{"10.0.0.3:8001"}},
+		map[string]any{"id": "4", "device": "serial-4.model.brand", "addresses": []any{"10.0.1.4:8001"}},
+	})
+	// added devices are not part of any subcluster
+	c.Check(headers["subclusters"], check.DeepEquals, []any{
+		map[string]any{
+			"name":    "default",
+			"devices": []any{"1", "3"},
+			"snaps": []any{
+				map[string]any{"state": "clustered", "instance": "microceph", "channel": "squid/stable"},
+			},
+		},
+		map[string]any{
+			"name":    "db",
+			"devices": []any{},
+			"snaps":   []any{},
+		},
+	})
+
+	_, err = f.signing.Sign(asserts.ClusterType, headers, nil, "")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *assembleSuite) TestUpdatedClusterHeadersErrors(c *check.C) {
+	f := newMembershipFixture(c)
+
+	all := map[string]string{
+		"serial-1.model.brand": "10.0.1.1:8001",
+		"serial-3.model.brand": "10.0.1.3:8001",
+		"serial-4.model.brand": "10.0.1.4:8001",
+	}
+
+	cases := []struct {
+		changes assemblestate.MembershipChanges
+		addrs   map[string]string
+		err     string
+	}{{
+		changes: assemblestate.MembershipChanges{Replace: map[int]string{7: "serial-4.model.brand"}},
+		err:     `cannot replace unknown device id 7`,
+	}, {
+		changes: assemblestate.MembershipChanges{Replace: map[int]string{2: "serial-3.model.brand"}},
+		err:     `cannot replace device id 2 with "serial-3.model.brand": device is already part of the cluster`,
+	}, {
+		changes: assemblestate.MembershipChanges{Replace: map[int]string{1: "serial-4.model.brand", 2: "serial-4.model.brand"}},
+		err:     `cannot replace more than one device with "serial-4.model.brand"`,
+	}, {
+		changes: assemblestate.MembershipChanges{Remove: []int{7}},
+		err:     `cannot remove unknown device id 7`,
+	}, {
+		changes: assemblestate.MembershipChanges{Replace: map[int]string{2: "serial-4.model.brand"}, Remove: []int{2}},
+		err:     `cannot both remove and replace device id 2`,
+	}, {
+		changes: assemblestate.MembershipChanges{Add: -1},
+		err:     `invalid number of devices to add: -1`,
+	}, {
+		changes: assemblestate.MembershipChanges{Replace: map[int]string{2: "serial-5.model.brand"}},
+		err:     `replacement "serial-5.model.brand" for device id 2 did not take part in the assembly session`,
+	}, {
+		changes: assemblestate.MembershipChanges{Remove: []int{3}, Add: 1},
+		err:     `device id 3 is leaving the cluster but took part in the assembly session`,
+	}, {
+		changes: assemblestate.MembershipChanges{},
+		err:     `expected 0 devices to join the cluster in addition to the replacements, got 1`,
+	}, {
+		changes: assemblestate.MembershipChanges{Replace: map[int]string{2: "serial-4.model.brand"}, Add: 1},
+		err:     `expected 1 devices to join the cluster in addition to the replacements, got 0`,
+	}}
+
+	for _, tc := range cases {
+		addrs := tc.addrs
+		if addrs == nil {
+			addrs = all
+		}
+		ids, routes := f.session(addrs)
+
+		_, err := assemblestate.UpdatedClusterHeaders(f.cluster, ids, routes, tc.changes)
+		c.Check(err, check.ErrorMatches, tc.err, check.Commentf("%+v", tc.changes))
+	}
+
+	// the seed rejects invalid changes too
+	_, _, err := assemblestate.MembershipSeed(f.cluster, assemblestate.MembershipChanges{Remove: []int{7}})
+	c.Check(err, check.ErrorMatches, `cannot remove unknown device id 7`)
+}
+
+func (s *assembleSuite) TestIdentityFingerprints(c *check.C) {
+	f := newMembershipFixture(c)
+
+	ids, _ := f.session(map[string]string{
+		"serial-1.model.brand": "10.0.1.1:8001",
+		"serial-4.model.brand": "10.0.1.4:8001",
+	})
+
+	fps, err := assemblestate.IdentityFingerprints(ids)
+	c.Assert(err, check.IsNil)
+	c.Check(fps, check.DeepEquals, map[string]assemblestate.Fingerprint{
+		"serial-1.model.brand": f.identities["serial-1.model.brand"].FP,
+		"serial-4.model.brand": f.identities["serial-4.model.brand"].FP,
+	})
+
+	_, err = assemblestate.IdentityFingerprints([]assemblestate.Identity{{RDT: "rdt", SerialBundle: "garbage"}})
+	c.Check(err, check.ErrorMatches, `cannot parse serial bundle for device "rdt": .*`)
+}
@@ -110,3 +110,37 @@ type Routes struct {
 	//   - Route 2: Devices[2] can reach Devices[1] via Addresses[1]
 	Routes []int `json:"routes"`
 }
+
+// Status is a top-level message used by the devices of an assembled cluster to
+// report to their peers how far they got in applying the cluster assertion.
+// It is only accepted by peers implementing [StatusCommitter].
+type Status struct {
+	// ClusterID is the id of the cluster that the sender is part of.
+	ClusterID string `json:"cluster-id"`
+
+	// Device is the device id ("<serial>.<model>.<brand-id>") of the sender.
+	Device string `json:"device"`
+
+	// Sequence is the sequence of the last cluster assertion that the sender
+	// applied, zero if none.
+	Sequence int `json:"sequence"`
+
+	// Error explains why the sender failed to apply a later cluster assertion,
+	// if it did.
+	Error string `json:"error,omitempty"`
+
+	// Snaps contains the state of the snaps of the subclusters that the sender
+	// is part of.
+	Snaps []SnapStatus `json:"snaps"`
+}
+
+// SnapStatus contains the state of a snap on a device of a cluster.
+type SnapStatus struct {
+	// Instance is the snap's instance name.
+	Instance string `json:"instance"`
+	// Revision is the installed revision of the snap, empty if the snap is not
+	// installed.
+	Revision string `json:"revision,omitempty"`
+	// Channel is the channel that the snap tracks.
+	Channel string `json:"channel,omitempty"`
+}
@@ -0,0 +1,587 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assemblestate
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"golang.org/x/time/rate"
+)
+
+// Frames exchanged by the [StreamTransport]. A request is made of:
+//   - 1 byte: length n of the message kind
+//   - n bytes: message kind, e.g. "routes"
+//   - 4 bytes: big-endian length m of the payload
+//   - m bytes: JSON payload
+//
+// and is answered with a single status byte.
+const (
+	streamStatusOK byte = iota
+	streamStatusBadRequest
+	streamStatusForbidden
+	streamStatusUnknownKind
+)
+
+var (
+	// streamMaxPayload bounds the payload of messages from trusted peers.
+	streamMaxPayload = 16 * 1024 * 1024
+	// streamMaxAuthPayload bounds the payload of auth messages, which come
+	// from untrusted peers.
+	streamMaxAuthPayload = 4 * 1024
+
+	streamIdleTimeout    = 2 * time.Minute
+	streamRequestTimeout = time.Minute
+)
+
+func streamStatusError(kind string, status byte) error {
+	switch status {
+	case streamStatusOK:
+		return nil
+	case streamStatusBadRequest:
+		return fmt.Errorf("peer rejected '%s' message as invalid", kind)
+	case streamStatusForbidden:
+		return fmt.Errorf("peer refused '%s' message", kind)
+	case streamStatusUnknownKind:
+		return fmt.Errorf("peer does not support '%s' messages", kind)
+	default:
+		return fmt.Errorf("unexpected status %d in response to '%s' message", status, kind)
+	}
+}
+
+// StreamTransport implements the Transport interface with length-prefixed
+// messages over long-lived TLS 1.3 connections with mutual authentication.
+// Unlike [HTTPSTransport], which performs a TLS handshake for every message, a
+// connection to a peer is established once and reused for all the messages
+// sent to it, which keeps the handshake overhead low in large clusters.
+type StreamTransport struct {
+	stats TransportStats
+
+	lock    sync.Mutex
+	clients []*StreamClient
+}
+
+// NewStreamTransport creates a new [StreamTransport] instance.
+func NewStreamTransport() *StreamTransport {
+	return &StreamTransport{}
+}
+
+// Serve implements the Transport interface. It accepts TLS connections on the
+// given [net.Listener] and routes the messages received over them to the
+// given [PeerAuthenticator]. Messages other than auth messages are only
+// accepted from peers using an authenticated certificate.
+//
+// The server runs until the context is cancelled, at which point all the
+// connections are closed, including the ones opened by the clients created
+// by this transport.
+func (t *StreamTransport) Serve(ctx context.Context, ln net.Listener, cert tls.Certificate, pa PeerAuthenticator) error {
+	listener := tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	})
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	conns := make(map[net.Conn]bool)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				// the listener is closed on shutdown
+				return
+			}
+
+			lock.Lock()
+			conns[conn] = true
+			lock.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				t.serveConn(conn.(*tls.Conn), pa)
+
+				lock.Lock()
+				delete(conns, conn)
+				lock.Unlock()
+			}()
+		}
+	}()
+
+	<-ctx.Done()
+
+	listener.Close()
+	lock.Lock()
+	for conn := range conns {
+		conn.Close()
+	}
+	lock.Unlock()
+	wg.Wait()
+
+	t.closeClients()
+
+	return nil
+}
+
+func (t *StreamTransport) serveConn(conn *tls.Conn, pa PeerAuthenticator) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(streamRequestTimeout))
+	if err := conn.Handshake(); err != nil {
+		logger.Debugf("cannot complete handshake with peer: %v", err)
+		return
+	}
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) != 1 {
+		return
+	}
+	fp := CalculateFP(certs[0].Raw)
+
+	r := bufio.NewReader(conn)
+	for {
+		// peers keep their connection open while they have messages for us
+		conn.SetDeadline(time.Now().Add(streamIdleTimeout))
+
+		status, err := t.serveRequest(r, fp, pa)
+		if err != nil && !errors.Is(err, errReplyAndClose) {
+			if !errors.Is(err, io.EOF) {
+				logger.Debugf("dropping connection to peer: %v", err)
+			}
+			return
+		}
+
+		if _, err := conn.Write([]byte{status}); err != nil {
+			return
+		}
+
+		if err != nil {
+			// the rest of the request was not read
+			return
+		}
+	}
+}
+
+// errReplyAndClose is returned by serveRequest when the request must be
+// answered but the connection cannot be used anymore.
+var errReplyAndClose = errors.New("reply and close connection")
+
+// serveRequest reads a single request and returns the status to answer it
+// with. An error is returned if the connection cannot be used anymore.
+func (t *StreamTransport) serveRequest(r io.Reader, fp Fingerprint, pa PeerAuthenticator) (status byte, err error) {
+	kind, size, err := readStreamHeader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var peer VerifiedPeer
+	limit := streamMaxPayload
+	if kind == "auth" {
+		limit = streamMaxAuthPayload
+	} else {
+		peer, err = pa.VerifyPeer(fp)
+		if err != nil {
+			// don't read anything else from an untrusted peer
+			logger.Debug("dropping message from untrusted peer")
+			return streamStatusForbidden, errReplyAndClose
+		}
+	}
+	if size > limit {
+		logger.Debugf("dropping '%s' message of %d bytes", kind, size)
+		return streamStatusBadRequest, errReplyAndClose
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, err
+	}
+	t.stats.recv(int64(size))
+
+	if kind == "auth" {
+		return handleStreamAuth(payload, fp, pa), nil
+	}
+	return handleStreamTrusted(kind, payload, peer), nil
+}
+
+func handleStreamAuth(payload []byte, fp Fingerprint, pa PeerAuthenticator) byte {
+	var auth Auth
+	if err := json.Unmarshal(payload, &auth); err != nil {
+		return streamStatusBadRequest
+	}
+
+	if err := pa.AuthenticateAndCommit(auth, fp); err != nil {
+		logger.Debugf("cannot authenticate peer: %v", err)
+		return streamStatusForbidden
+	}
+	return streamStatusOK
+}
+
+func handleStreamTrusted(kind string, payload []byte, peer VerifiedPeer) byte {
+	var err error
+	switch kind {
+	case "routes":
+		var routes Routes
+		if err := json.Unmarshal(payload, &routes); err != nil {
+			return streamStatusBadRequest
+		}
+		err = peer.CommitRoutes(routes)
+	case "unknown":
+		var unknown UnknownDevices
+		if err := json.Unmarshal(payload, &unknown); err != nil {
+			return streamStatusBadRequest
+		}
+		err = peer.CommitDeviceQueries(unknown)
+	case "devices":
+		var devices Devices
+		if err := json.Unmarshal(payload, &devices); err != nil {
+			return streamStatusBadRequest
+		}
+		err = peer.CommitDevices(devices)
+	case "status":
+		committer, ok := peer.(StatusCommitter)
+		if !ok {
+			return streamStatusUnknownKind
+		}
+		var status Status
+		if err := json.Unmarshal(payload, &status); err != nil {
+			return streamStatusBadRequest
+		}
+		err = committer.CommitStatus(status)
+	default:
+		return streamStatusUnknownKind
+	}
+
+	if err != nil {
+		logger.Debugf("cannot commit '%s' message: %v", kind, err)
+		return streamStatusBadRequest
+	}
+	return streamStatusOK
+}
+
+func readStreamHeader(r io.Reader) (kind string, size int, err error) {
+	var kindLen [1]byte
+	if _, err := io.ReadFull(r, kindLen[:]); err != nil {
+		return "", 0, err
+	}
+	if kindLen[0] == 0 {
+		return "", 0, errors.New("empty message kind")
+	}
+
+	buf := make([]byte, int(kindLen[0])+4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", 0, err
+	}
+
+	kind = string(buf[:kindLen[0]])
+	size = int(binary.BigEndian.Uint32(buf[kindLen[0]:]))
+	return kind, size, nil
+}
+
+func writeStreamRequest(w io.Writer, kind string, payload []byte) error {
+	if len(kind) == 0 || len(kind) > 255 {
+		return fmt.Errorf("invalid message kind %q", kind)
+	}
+
+	buf := make([]byte, 0, 1+len(kind)+4+len(payload))
+	buf = append(buf, byte(len(kind)))
+	buf = append(buf, kind...)
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(payload)))
+	buf = append(buf, size[:]...)
+	buf = append(buf, payload...)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// NewClient creates a Client compatible with this [StreamTransport] for
+// sending outbound assembly protocol messages. The client will use the
+// provided TLS certificate for mutual authentication. Its connections are
+// closed once [StreamTransport.Serve] returns.
+func (t *StreamTransport) NewClient(cert tls.Certificate) Client {
+	client := NewStreamClient(cert, &t.stats, rate.NewLimiter(rate.Limit(1_000_000), 5_000_000))
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.clients = append(t.clients, client)
+
+	return client
+}
+
+func (t *StreamTransport) closeClients() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, client := range t.clients {
+		client.Close()
+	}
+	t.clients = nil
+}
+
+// Stats returns the cumulative statistics for messages sent and received by
+// this [Transport].
+func (t *StreamTransport) Stats() TransportStats {
+	return t.stats.clone()
+}
+
+// streamConn is a connection to a peer, used for one message at a time.
+type streamConn struct {
+	lock sync.Mutex
+	conn *tls.Conn
+	fp   Fingerprint
+}
+
+// StreamClient implements the Client interface for sending outbound assembly
+// protocol messages to peers served by a [StreamTransport]. It keeps one
+// connection per peer address, reused for all the messages sent to the peer.
+type StreamClient struct {
+	// cert is the TLS certificate that we should use when sending messages.
+	cert tls.Certificate
+	// stats is provided by the parent [Transport] to keep track of messages
+	// sent.
+	stats *TransportStats
+	// limiter helps us rate limit our output of bytes/second.
+	limiter *rate.Limiter
+
+	lock  sync.Mutex
+	conns map[string]*streamConn
+}
+
+// NewStreamClient creates a new [StreamClient] with custom rate limiting. Pass
+// nil for the limiter to disable rate limiting entirely.
+func NewStreamClient(cert tls.Certificate, stats *TransportStats, limiter *rate.Limiter) *StreamClient {
+	return &StreamClient{
+		cert:    cert,
+		stats:   stats,
+		limiter: limiter,
+		conns:   make(map[string]*streamConn),
+	}
+}
+
+// Close closes all the connections of the client.
+func (c *StreamClient) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for addr, sc := range c.conns {
+		sc.conn.Close()
+		delete(c.conns, addr)
+	}
+}
+
+func (c *StreamClient) dial(ctx context.Context, addr string) (*streamConn, error) {
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			// peers use self-signed certificates, they are checked against
+			// the fingerprints of authenticated peers instead
+			InsecureSkipVerify: true,
+			Certificates:       []tls.Certificate{c.cert},
+			MinVersion:         tls.VersionTLS13,
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := conn.(*tls.Conn)
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("exactly one peer certificate expected, got %d", len(certs))
+	}
+
+	return &streamConn{
+		conn: tlsConn,
+		fp:   CalculateFP(certs[0].Raw),
+	}, nil
+}
+
+// conn returns the connection to the given address, establishing it if
+// needed. The returned connection is locked, and reused reports whether it
+// was established for an earlier message.
+func (c *StreamClient) conn(ctx context.Context, addr string) (sc *streamConn, reused bool, err error) {
+	c.lock.Lock()
+	sc, ok := c.conns[addr]
+	c.lock.Unlock()
+
+	if !ok {
+		sc, err = c.dial(ctx, addr)
+		if err != nil {
+			return nil, false, err
+		}
+
+		c.lock.Lock()
+		if existing, ok := c.conns[addr]; ok {
+			// lost a race with another message to the same peer
+			sc.conn.Close()
+			sc = existing
+		} else {
+			c.conns[addr] = sc
+		}
+		c.lock.Unlock()
+	}
+
+	sc.lock.Lock()
+	return sc, ok, nil
+}
+
+// drop forgets about a connection that cannot be used anymore.
+func (c *StreamClient) drop(addr string, sc *streamConn) {
+	sc.conn.Close()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.conns[addr] == sc {
+		delete(c.conns, addr)
+	}
+}
+
+// send sends a message to the peer at the given address. If check is not nil,
+// it is called with the fingerprint of the certificate used by the peer before
+// sending anything.
+func (c *StreamClient) send(ctx context.Context, addr string, kind string, data any, check func(Fingerprint) error) (Fingerprint, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	// rate limit based on the number of bytes/second that we're sending. this
+	// will block until we have enough bytes in our budget to send the payload.
+	if c.limiter != nil {
+		if err := c.limiter.WaitN(ctx, len(payload)); err != nil {
+			return Fingerprint{}, err
+		}
+	}
+
+	for {
+		sc, reused, err := c.conn(ctx, addr)
+		if err != nil {
+			return Fingerprint{}, err
+		}
+
+		fp := sc.fp
+		if check != nil {
+			if err := check(fp); err != nil {
+				sc.lock.Unlock()
+				return Fingerprint{}, err
+			}
+		}
+
+		err = c.exchange(ctx, sc, kind, payload)
+		sc.lock.Unlock()
+
+		var statusErr *streamStatus
+		switch {
+		case err == nil:
+		case errors.As(err, &statusErr):
+			// the peer answered, the connection is still usable
+			return Fingerprint{}, statusErr.err
+		case reused && ctx.Err() == nil:
+			// the peer might have closed an idle connection, try again
+			// with a new one
+			c.drop(addr, sc)
+			continue
+		default:
+			c.drop(addr, sc)
+			return Fingerprint{}, err
+		}
+
+		if c.stats != nil {
+			c.stats.sent(int64(len(payload)))
+		}
+
+		return fp, nil
+	}
+}
+
+// streamStatus carries an error reported by a peer.
+type streamStatus struct {
+	err error
+}
+
+func (s *streamStatus) Error() string {
+	return s.err.Error()
+}
+
+func (c *StreamClient) exchange(ctx context.Context, sc *streamConn, kind string, payload []byte) error {
+	deadline := time.Now().Add(streamRequestTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	sc.conn.SetDeadline(deadline)
+
+	// unblock the exchange if the context is cancelled
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			sc.conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	if err := writeStreamRequest(sc.conn, kind, payload); err != nil {
+		return err
+	}
+
+	var status [1]byte
+	if _, err := io.ReadFull(sc.conn, status[:]); err != nil {
+		return err
+	}
+
+	if err := streamStatusError(kind, status[0]); err != nil {
+		return &streamStatus{err: err}
+	}
+	return nil
+}
+
+// Trusted sends a message to a trusted peer, verifying that the peer uses the
+// certificate with the given fingerprint.
+func (c *StreamClient) Trusted(ctx context.Context, addr string, fp Fingerprint, kind string, data any) error {
+	_, err := c.send(ctx, addr, kind, data, func(peerFP Fingerprint) error {
+		if peerFP != fp {
+			return errors.New("refusing to communicate with unexpected peer certificate")
+		}
+		return nil
+	})
+	return err
+}
+
+// Untrusted sends a message to a peer that we do not trust yet and returns the
+// fingerprint of the certificate that the peer used.
+func (c *StreamClient) Untrusted(ctx context.Context, addr string, kind string, data any) (Fingerprint, error) {
+	return c.send(ctx, addr, kind, data, nil)
+}
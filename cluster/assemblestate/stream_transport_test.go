@@ -0,0 +1,333 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assemblestate_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/cluster/assemblestate"
+)
+
+type streamTransportSuite struct{}
+
+var _ = check.Suite(&streamTransportSuite{})
+
+// countingListener counts the connections accepted by the wrapped listener.
+type countingListener struct {
+	net.Listener
+	accepted int64
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt64(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+// serveStream runs a StreamTransport with the given authenticator until the
+// returned function is called.
+func serveStream(c *check.C, transport *assemblestate.StreamTransport, ln net.Listener, pa assemblestate.PeerAuthenticator) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Check(transport.Serve(ctx, ln, testServerCert, pa), check.IsNil)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func (s *streamTransportSuite) TestNewTransport(c *check.C) {
+	t, err := assemblestate.NewTransport("")
+	c.Assert(err, check.IsNil)
+	c.Check(t, check.FitsTypeOf, &assemblestate.HTTPSTransport{})
+
+	t, err = assemblestate.NewTransport(assemblestate.TransportHTTPS)
+	c.Assert(err, check.IsNil)
+	c.Check(t, check.FitsTypeOf, &assemblestate.HTTPSTransport{})
+
+	t, err = assemblestate.NewTransport(assemblestate.TransportStream)
+	c.Assert(err, check.IsNil)
+	c.Check(t, check.FitsTypeOf, &assemblestate.StreamTransport{})
+
+	_, err = assemblestate.NewTransport("quic")
+	c.Assert(err, check.ErrorMatches, `unknown transport "quic"`)
+}
+
+func (s *streamTransportSuite) TestMessagesReuseConnection(c *check.C) {
+	var auths []assemblestate.Auth
+	var routes []assemblestate.Routes
+	var unknown []assemblestate.UnknownDevices
+	var devices []assemblestate.Devices
+	peer := &testVerifiedPeer{
+		CommitRoutesFunc: func(r assemblestate.Routes) error {
+			routes = append(routes, r)
+			return nil
+		},
+		CommitDeviceQueriesFunc: func(u assemblestate.UnknownDevices) error {
+			unknown = append(unknown, u)
+			return nil
+		},
+		CommitDevicesFunc: func(d assemblestate.Devices) error {
+			devices = append(devices, d)
+			return nil
+		},
+	}
+	pa := &testPeerAuthenticator{
+		AuthenticateAndCommitFunc: func(auth assemblestate.Auth, fp assemblestate.Fingerprint) error {
+			c.Check(fp, check.Equals, testClientCertFP)
+			auths = append(auths, auth)
+			return nil
+		},
+		VerifyPeerFunc: func(fp assemblestate.Fingerprint) (assemblestate.VerifiedPeer, error) {
+			c.Check(fp, check.Equals, testClientCertFP)
+			return peer, nil
+		},
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	cl := &countingListener{Listener: ln}
+	addr := ln.Addr().String()
+
+	transport := assemblestate.NewStreamTransport()
+	stop := serveStream(c, transport, cl, pa)
+	defer stop()
+
+	ctx := context.Background()
+	client := transport.NewClient(testClientCert)
+
+	auth := assemblestate.Auth{HMAC: []byte("hmac"), RDT: "rdt"}
+	fp, err := client.Untrusted(ctx, addr, "auth", auth)
+	c.Assert(err, check.IsNil)
+	c.Check(fp, check.Equals, testServerCertFP)
+
+	r := assemblestate.Routes{
+		Devices:   []assemblestate.DeviceToken{"a", "b"},
+		Addresses: []string{"127.0.0.1:8001"},
+		Routes:    []int{0, 1, 0},
+	}
+	c.Assert(client.Trusted(ctx, addr, testServerCertFP, "routes", r), check.IsNil)
+
+	u := assemblestate.UnknownDevices{Devices: []assemblestate.DeviceToken{"c"}}
+	c.Assert(client.Trusted(ctx, addr, testServerCertFP, "unknown", u), check.IsNil)
+
+	d := assemblestate.Devices{Devices: []assemblestate.Identity{{RDT: "c", SerialBundle: "serial"}}}
+	c.Assert(client.Trusted(ctx, addr, testServerCertFP, "devices", d), check.IsNil)
+
+	c.Check(auths, check.DeepEquals, []assemblestate.Auth{auth})
+	c.Check(routes, check.DeepEquals, []assemblestate.Routes{r})
+	c.Check(unknown, check.DeepEquals, []assemblestate.UnknownDevices{u})
+	c.Check(devices, check.DeepEquals, []assemblestate.Devices{d})
+
+	// all the messages went through a single connection
+	c.Check(atomic.LoadInt64(&cl.accepted), check.Equals, int64(1))
+
+	stats := transport.Stats()
+	c.Check(stats.Sent, check.Equals, int64(4))
+	c.Check(stats.Received, check.Equals, int64(4))
+	c.Check(stats.Tx, check.Equals, stats.Rx)
+}
+
+func (s *streamTransportSuite) TestTrustedCertificateMismatch(c *check.C) {
+	pa := &testPeerAuthenticator{}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+
+	transport := assemblestate.NewStreamTransport()
+	stop := serveStream(c, transport, ln, pa)
+	defer stop()
+
+	client := transport.NewClient(testClientCert)
+	err = client.Trusted(context.Background(), ln.Addr().String(), testClientCertFP, "routes", assemblestate.Routes{})
+	c.Assert(err, check.ErrorMatches, "refusing to communicate with unexpected peer certificate")
+
+	c.Check(transport.Stats().Sent, check.Equals, int64(0))
+}
+
+func (s *streamTransportSuite) TestPeerErrors(c *check.C) {
+	verified := 0
+	pa := &testPeerAuthenticator{
+		AuthenticateAndCommitFunc: func(auth assemblestate.Auth, fp assemblestate.Fingerprint) error {
+			return errors.New("invalid hmac")
+		},
+		VerifyPeerFunc: func(fp assemblestate.Fingerprint) (assemblestate.VerifiedPeer, error) {
+			verified++
+			if verified == 1 {
+				return nil, errors.New("unknown peer")
+			}
+			return &testVerifiedPeer{
+				CommitRoutesFunc: func(r assemblestate.Routes) error {
+					return errors.New("invalid routes")
+				},
+			}, nil
+		},
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	cl := &countingListener{Listener: ln}
+	addr := ln.Addr().String()
+
+	transport := assemblestate.NewStreamTransport()
+	stop := serveStream(c, transport, cl, pa)
+	defer stop()
+
+	ctx := context.Background()
+	client := transport.NewClient(testClientCert)
+
+	_, err = client.Untrusted(ctx, addr, "auth", assemblestate.Auth{})
+	c.Check(err, check.ErrorMatches, "peer refused 'auth' message")
+
+	// the peer is not trusted yet, the connection gets closed after the
+	// reply
+	err = client.Trusted(ctx, addr, testServerCertFP, "routes", assemblestate.Routes{})
+	c.Check(err, check.ErrorMatches, "peer refused 'routes' message")
+
+	err = client.Trusted(ctx, addr, testServerCertFP, "routes", assemblestate.Routes{})
+	c.Check(err, check.ErrorMatches, "peer rejected 'routes' message as invalid")
+
+	err = client.Trusted(ctx, addr, testServerCertFP, "commit", nil)
+	c.Check(err, check.ErrorMatches, "peer does not support 'commit' messages")
+
+	c.Check(atomic.LoadInt64(&cl.accepted), check.Equals, int64(2))
+	c.Check(transport.Stats().Sent, check.Equals, int64(0))
+}
+
+func (s *streamTransportSuite) TestOversizedAuth(c *check.C) {
+	pa := &testPeerAuthenticator{}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+
+	transport := assemblestate.NewStreamTransport()
+	stop := serveStream(c, transport, ln, pa)
+	defer stop()
+
+	client := transport.NewClient(testClientCert)
+	auth := assemblestate.Auth{HMAC: []byte(strings.Repeat("x", 8*1024))}
+	_, err = client.Untrusted(context.Background(), ln.Addr().String(), "auth", auth)
+	c.Check(err, check.ErrorMatches, "peer rejected 'auth' message as invalid")
+}
+
+func (s *streamTransportSuite) TestReconnect(c *check.C) {
+	var received []assemblestate.Routes
+	pa := &testPeerAuthenticator{
+		VerifyPeerFunc: func(fp assemblestate.Fingerprint) (assemblestate.VerifiedPeer, error) {
+			return &testVerifiedPeer{
+				CommitRoutesFunc: func(r assemblestate.Routes) error {
+					received = append(received, r)
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	addr := ln.Addr().String()
+
+	// the client outlives the server
+	var stats assemblestate.TransportStats
+	client := assemblestate.NewStreamClient(testClientCert, &stats, nil)
+	defer client.Close()
+
+	stop := serveStream(c, assemblestate.NewStreamTransport(), ln, pa)
+	err = client.Trusted(context.Background(), addr, testServerCertFP, "routes", assemblestate.Routes{})
+	c.Assert(err, check.IsNil)
+	stop()
+
+	// the connection was closed by the server, a new one is established
+	ln, err = net.Listen("tcp", addr)
+	c.Assert(err, check.IsNil)
+	stop = serveStream(c, assemblestate.NewStreamTransport(), ln, pa)
+	defer stop()
+
+	err = client.Trusted(context.Background(), addr, testServerCertFP, "routes", assemblestate.Routes{})
+	c.Assert(err, check.IsNil)
+
+	c.Check(received, check.HasLen, 2)
+	c.Check(stats.Sent, check.Equals, int64(2))
+}
+
+func (s *streamTransportSuite) TestUntrustedNoServer(c *check.C) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	client := assemblestate.NewStreamClient(testClientCert, nil, nil)
+	defer client.Close()
+
+	_, err = client.Untrusted(context.Background(), addr, "auth", assemblestate.Auth{})
+	c.Check(err, check.ErrorMatches, ".*connection refused")
+}
+
+func (s *streamTransportSuite) TestStatus(c *check.C) {
+	var statuses []assemblestate.Status
+	committer := true
+	pa := &testPeerAuthenticator{
+		VerifyPeerFunc: func(fp assemblestate.Fingerprint) (assemblestate.VerifiedPeer, error) {
+			if !committer {
+				return &testVerifiedPeer{}, nil
+			}
+			return &testStatusPeer{
+				CommitStatusFunc: func(status assemblestate.Status) error {
+					statuses = append(statuses, status)
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	addr := ln.Addr().String()
+
+	transport := assemblestate.NewStreamTransport()
+	stop := serveStream(c, transport, ln, pa)
+	defer stop()
+
+	msg := assemblestate.Status{ClusterID: "cluster-id", Device: "serial.model.brand", Sequence: 1}
+
+	client := transport.NewClient(testClientCert)
+	err = client.Trusted(context.Background(), addr, testServerCertFP, "status", msg)
+	c.Assert(err, check.IsNil)
+	c.Check(statuses, check.DeepEquals, []assemblestate.Status{msg})
+
+	committer = false
+	err = client.Trusted(context.Background(), addr, testServerCertFP, "status", msg)
+	c.Check(err, check.ErrorMatches, "peer does not support 'status' messages")
+}
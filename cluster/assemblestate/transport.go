@@ -56,6 +56,29 @@ type Transport interface {
 	Stats() TransportStats
 }
 
+// TransportKind identifies a [Transport] implementation.
+type TransportKind string
+
+const (
+	// TransportHTTPS selects the [HTTPSTransport].
+	TransportHTTPS TransportKind = "https"
+	// TransportStream selects the [StreamTransport].
+	TransportStream TransportKind = "stream"
+)
+
+// NewTransport creates a new [Transport] of the given kind, which defaults to
+// [TransportHTTPS] if empty.
+func NewTransport(kind TransportKind) (Transport, error) {
+	switch kind {
+	case "", TransportHTTPS:
+		return NewHTTPSTransport(), nil
+	case TransportStream:
+		return NewStreamTransport(), nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", kind)
+	}
+}
+
 // Client is used to communicate with our peers.
 type Client interface {
 	// Trusted sends a message to a trusted peer. Implementations must verify
@@ -92,6 +115,14 @@ type VerifiedPeer interface {
 	CommitRoutes(routes Routes) error
 }
 
+// StatusCommitter is implemented by the [VerifiedPeer]s of a cluster that is
+// already assembled, which exchange [Status] messages. The transports reject
+// status messages from peers that don't implement it.
+type StatusCommitter interface {
+	// CommitStatus records the given status of the peer.
+	CommitStatus(status Status) error
+}
+
 // TransportStats carries the statistics for a [Transport].
 type TransportStats struct {
 	// Sent is the number of messages sent.
@@ -147,6 +178,7 @@ func NewHTTPSTransport() *HTTPSTransport {
 //   - /assemble/routes: Route information from trusted peers
 //   - /assemble/unknown: Device queries from trusted peers
 //   - /assemble/devices: Device identity responses from trusted peers
+//   - /assemble/status: Status of trusted peers of an assembled cluster
 //
 // The server runs until the context is cancelled.
 func (t *HTTPSTransport) Serve(ctx context.Context, ln net.Listener, cert tls.Certificate, pa PeerAuthenticator) error {
@@ -157,6 +189,7 @@ func (t *HTTPSTransport) Serve(ctx context.Context, ln net.Listener, cert tls.Ce
 	mux.Handle("/assemble/routes", t.statsHandler(t.trustedHandler(t.handleRoutes, pa)))
 	mux.Handle("/assemble/unknown", t.statsHandler(t.trustedHandler(t.handleUnknown, pa)))
 	mux.Handle("/assemble/devices", t.statsHandler(t.trustedHandler(t.handleDevices, pa)))
+	mux.Handle("/assemble/status", t.statsHandler(t.trustedHandler(t.handleStatus, pa)))
 
 	server := &http.Server{
 		Handler: mux,
@@ -316,6 +349,26 @@ func (t *HTTPSTransport) handleDevices(w http.ResponseWriter, r *http.Request, p
 	}
 }
 
+func (t *HTTPSTransport) handleStatus(w http.ResponseWriter, r *http.Request, peer VerifiedPeer) {
+	committer, ok := peer.(StatusCommitter)
+	if !ok {
+		w.WriteHeader(404)
+		return
+	}
+
+	var status Status
+	if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+		w.WriteHeader(400)
+		return
+	}
+
+	if err := committer.CommitStatus(status); err != nil {
+		w.WriteHeader(400)
+		logger.Debugf("cannot commit peer status: %v", err)
+		return
+	}
+}
+
 // NewClient creates a Client compatible with this [HTTPSTransport] for sending
 // outbound assembly protocol messages. The client will use the provided TLS
 // certificate for mutual authentication.
@@ -574,6 +574,78 @@ func (s *transportSuite) TestHTTPSTransportServeDevicesRoute(c *check.C) {
 	wg.Wait()
 }
 
+type testStatusPeer struct {
+	testVerifiedPeer
+	CommitStatusFunc func(status assemblestate.Status) error
+}
+
+func (m *testStatusPeer) CommitStatus(status assemblestate.Status) error {
+	return m.CommitStatusFunc(status)
+}
+
+func (s *transportSuite) TestHTTPSTransportServeStatusRoute(c *check.C) {
+	var statuses []assemblestate.Status
+	committer := true
+	pa := &testPeerAuthenticator{
+		VerifyPeerFunc: func(fp assemblestate.Fingerprint) (assemblestate.VerifiedPeer, error) {
+			if !committer {
+				return &testVerifiedPeer{}, nil
+			}
+			return &testStatusPeer{
+				CommitStatusFunc: func(status assemblestate.Status) error {
+					if status.Device == "" {
+						return errors.New("missing device")
+					}
+					statuses = append(statuses, status)
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	transport := assemblestate.NewHTTPSTransport()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer ln.Close()
+
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = transport.Serve(ctx, ln, testServerCert, pa)
+	}()
+
+	msg := assemblestate.Status{
+		ClusterID: "cluster-id",
+		Device:    "serial.model.brand",
+		Sequence:  2,
+		Snaps:     []assemblestate.SnapStatus{{Instance: "microceph", Revision: "12", Channel: "squid/stable"}},
+	}
+
+	client := transport.NewClient(testClientCert)
+	err = client.Trusted(ctx, addr, testServerCertFP, "status", msg)
+	c.Assert(err, check.IsNil)
+	c.Assert(statuses, check.DeepEquals, []assemblestate.Status{msg})
+
+	err = client.Trusted(ctx, addr, testServerCertFP, "status", assemblestate.Status{})
+	c.Assert(err, check.ErrorMatches, "response to 'status' message contains status code 400")
+
+	// peers taking part in an assembly session don't take statuses
+	committer = false
+	err = client.Trusted(ctx, addr, testServerCertFP, "status", msg)
+	c.Assert(err, check.ErrorMatches, "response to 'status' message contains status code 404")
+	c.Assert(statuses, check.HasLen, 1)
+
+	cancel()
+	wg.Wait()
+}
+
 func (s *transportSuite) TestHTTPSTransportServeRoutesRoute(c *check.C) {
 	var routes []assemblestate.Routes
 	var peerFPs []assemblestate.Fingerprint
@@ -56,6 +56,7 @@ import (
 
 	"github.com/snapcore/snapd/overlord/install"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/snap"
@@ -759,13 +760,29 @@ func copyCoreUbuntuAuthData(srcUbuntuData, destUbuntuData string) error {
 	}
 
 	// ensure the user state is transferred as well
-	srcState := filepath.Join(srcUbuntuData, "system-data/var/lib/snapd/state.json")
+	srcStateDir := filepath.Join(srcUbuntuData, "system-data/var/lib/snapd")
 	dstState := filepath.Join(destUbuntuData, "system-data/var/lib/snapd/state.json")
-	err := state.CopyState(srcState, dstState, []string{"auth.users", "auth.macaroon-key", "auth.last-id"})
+	return copyUserState(srcStateDir, dstState)
+}
+
+// copyUserState copies the user state from the snapd state in srcStateDir,
+// be it in the state database or in a state file yet to be migrated to it,
+// to the dstState state file.
+func copyUserState(srcStateDir, dstState string) error {
+	userState := []string{"auth.users", "auth.macaroon-key", "auth.last-id"}
+	var err error
+	if srcDB := filepath.Join(srcStateDir, "state.db"); osutil.FileExists(srcDB) {
+		var srcState *state.State
+		srcState, err = statedb.ReadState(nil, srcDB)
+		if err == nil {
+			err = state.CopyStateFrom(srcState, dstState, userState)
+		}
+	} else {
+		err = state.CopyState(filepath.Join(srcStateDir, "state.json"), dstState, userState)
+	}
 	if err != nil && !errors.Is(err, state.ErrNoState) {
 		return fmt.Errorf("cannot copy user state: %v", err)
 	}
-
 	return nil
 }
 
@@ -805,14 +822,9 @@ func copyHybridUbuntuDataAuth(srcUbuntuData, destUbuntuData string) error {
 	}
 
 	// ensure the user state is transferred as well
-	srcState := filepath.Join(srcUbuntuData, "var/lib/snapd/state.json")
+	srcStateDir := filepath.Join(srcUbuntuData, "var/lib/snapd")
 	dstState := filepath.Join(destUbuntuData, "system-data/var/lib/snapd/state.json")
-	err := state.CopyState(srcState, dstState, []string{"auth.users", "auth.macaroon-key", "auth.last-id"})
-	if err != nil && !errors.Is(err, state.ErrNoState) {
-		return fmt.Errorf("cannot copy user state: %v", err)
-	}
-
-	return nil
+	return copyUserState(srcStateDir, dstState)
 }
 
 // drop a marker file that disables console-conf
@@ -2511,8 +2523,9 @@ func setupSeedSnapdSnap(rootfsDir string, snapdSeedSnap *seed.Snap) error {
 
 var tryRecoverySystemHealthCheck = func(model gadget.Model) error {
 	// check that writable is accessible by checking whether the
-	// state file exists
-	if !osutil.FileExists(dirs.SnapStateFileUnder(boot.InitramfsHostWritableDir(model))) {
+	// state database, or a state file yet to be migrated to it, exists
+	hostWritable := boot.InitramfsHostWritableDir(model)
+	if !osutil.FileExists(dirs.SnapStateDBFileUnder(hostWritable)) && !osutil.FileExists(dirs.SnapStateFileUnder(hostWritable)) {
 		return fmt.Errorf("host state file is not accessible")
 	}
 	return nil
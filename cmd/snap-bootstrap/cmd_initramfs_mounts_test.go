@@ -46,6 +46,8 @@ import (
 	"github.com/snapcore/snapd/osutil/disks"
 	"github.com/snapcore/snapd/osutil/kcmdline"
 	"github.com/snapcore/snapd/osutil/squashfs"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedtest"
@@ -946,6 +948,39 @@ type timeTestCase struct {
 	comment      string
 }
 
+func (s *initramfsMountsSuite) TestCopyUserStateFromStateDB(c *C) {
+	srcStateDir := c.MkDir()
+	err := statedb.Write(filepath.Join(srcStateDir, "state.db"), &state.Delta{
+		Full: true,
+		Entries: map[string]map[string][]byte{
+			state.EntriesData: {
+				"auth": []byte(`{"users":[{"id":1,"name":"mvo"}],"macaroon-key":"not-a-cookie","last-id":1}`),
+				"some": []byte(`{"other":"stuff"}`),
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+	// the state database takes precedence over a left over state file
+	err = os.WriteFile(filepath.Join(srcStateDir, "state.json"), []byte(`{"data":{}}`), 0600)
+	c.Assert(err, IsNil)
+
+	dstState := filepath.Join(c.MkDir(), "state.json")
+	err = main.CopyUserState(srcStateDir, dstState)
+	c.Assert(err, IsNil)
+
+	st, err := os.Open(dstState)
+	c.Assert(err, IsNil)
+	defer st.Close()
+	copied, err := state.ReadState(nil, st)
+	c.Assert(err, IsNil)
+	copied.Lock()
+	defer copied.Unlock()
+	var auth map[string]any
+	c.Assert(copied.Get("auth", &auth), IsNil)
+	c.Check(auth["macaroon-key"], Equals, "not-a-cookie")
+	c.Check(copied.Get("some", &auth), testutil.ErrorIs, state.ErrNoState)
+}
+
 func (s *initramfsMountsSuite) timeTestCases() []timeTestCase {
 	// epoch time
 	epoch := time.Time{}
@@ -49,6 +49,8 @@ var (
 	ParseImageManifest = parseImageManifest
 
 	CreateOverlayDirs = createOverlayDirs
+
+	CopyUserState = copyUserState
 )
 
 type OverlayFsOptions = overlayFsOptions
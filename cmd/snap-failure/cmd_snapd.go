@@ -31,6 +31,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snapdtool"
 )
@@ -173,6 +174,14 @@ func (c *cmdSnapd) Execute(args []string) error {
 		return osutil.OutputErrCombine(stdout, stderr, err)
 	}
 
+	// the previous snapd may predate the state database, the state file
+	// written on stop is missing what changed since if snapd failed
+	if osutil.FileExists(dirs.SnapStateDBFile) {
+		if err := statedb.WriteStateFile(dirs.SnapStateDBFile, dirs.SnapStateFile); err != nil {
+			logger.Noticef("cannot write the state file for the previous snapd: %v", err)
+		}
+	}
+
 	logger.Noticef("restoring invoking snapd from: %v", snapdPath)
 	if prevRev != "0" {
 		// if prevRev was "0" it means we did *not* find a
@@ -31,6 +31,8 @@ import (
 	failure "github.com/snapcore/snapd/cmd/snap-failure"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
@@ -104,6 +106,44 @@ set -eu
 	})
 }
 
+func (r *failureSuite) TestCallPrevSnapdWritesStateFile(c *C) {
+	origArgs := os.Args
+	defer func() { os.Args = origArgs }()
+
+	writeSeqFile(c, "snapd", snap.R(123), []*snap.SideInfo{
+		{Revision: snap.R(100)},
+		{Revision: snap.R(123)},
+	})
+	c.Assert(os.MkdirAll(filepath.Join(dirs.SnapMountDir, "snapd"), 0755), IsNil)
+
+	// the state database of the failed snapd
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapStateDBFile), 0755), IsNil)
+	err := statedb.Write(dirs.SnapStateDBFile, &state.Delta{
+		Full: true,
+		Entries: map[string]map[string][]byte{
+			state.EntriesData: {"mark": []byte("1")},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	// the previous snapd finds the current state in the state file
+	mockScript := `
+set -eu
+grep -q '"mark":1' '%s'
+`
+	systemdRunCmd := testutil.MockCommand(c, "systemd-run", fmt.Sprintf(mockScript, dirs.SnapStateFile))
+	defer systemdRunCmd.Restore()
+
+	os.Args = []string{"snap-failure", "snapd"}
+	err = failure.Run()
+	c.Check(err, IsNil)
+	c.Check(systemdRunCmd.Calls(), HasLen, 1)
+
+	changed, err := statedb.StateFileChanged(dirs.SnapStateDBFile, dirs.SnapStateFile)
+	c.Assert(err, IsNil)
+	c.Check(changed, Equals, false)
+}
+
 func (r *failureSuite) TestCallPrevSnapdFromSnapRestartSnapdFallback(c *C) {
 	defer failure.MockWaitTimes(1*time.Millisecond, 1*time.Millisecond)()
 
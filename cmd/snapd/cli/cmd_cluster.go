@@ -0,0 +1,124 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var (
+	shortClusterHelp = i18n.G("Inspect the cluster this device is part of")
+	longClusterHelp  = i18n.G(`
+The cluster command contains sub-commands to inspect the cluster of devices
+that this device is part of.
+`)
+
+	shortClusterStatusHelp = i18n.G("Show the status of the devices of the cluster")
+	longClusterStatusHelp  = i18n.G(`
+The cluster status command shows, for each subcluster, whether its devices
+converged to the snaps defined by the current cluster assertion. A device is
+lagging while it has yet to apply the assertion, and failed if applying it
+did not succeed. The status of peer devices is the one they last published to
+this device, it is unknown if they did not publish one yet.
+`)
+)
+
+type cmdCluster struct {
+	clientMixin
+	Status cmdClusterStatus `command:"status"`
+}
+
+type cmdClusterStatus struct {
+	clientMixin
+}
+
+func init() {
+	cmd := addCommand("cluster", shortClusterHelp, longClusterHelp,
+		func() flags.Commander { return &cmdCluster{} }, nil, nil)
+	cmd.extra = func(c *flags.Command) {
+		status := c.Find("status")
+		status.ShortDescription = shortClusterStatusHelp
+		status.LongDescription = longClusterStatusHelp
+	}
+}
+
+// setClient passes the client on to the sub-commands, which are not
+// registered on their own.
+func (x *cmdCluster) setClient(cli *client.Client) {
+	x.clientMixin.setClient(cli)
+	x.Status.setClient(cli)
+}
+
+func (x *cmdCluster) Execute(args []string) error {
+	return flag.ErrHelp
+}
+
+func (x *cmdClusterStatus) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	status, err := x.client.ClusterStatus()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Cluster %s at sequence %d\n"), status.ClusterID, status.Sequence)
+	if len(status.Subclusters) == 0 {
+		return nil
+	}
+
+	w := tabWriter()
+	fmt.Fprint(w, i18n.G("Subcluster\tID\tDevice\tStatus\tSequence\tNotes\n"))
+	for _, sub := range status.Subclusters {
+		for _, dev := range sub.Devices {
+			seq := "-"
+			if dev.Sequence > 0 {
+				seq = fmt.Sprint(dev.Sequence)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", sub.Name, dev.ID, dev.Device, dev.Status, seq, clusterDeviceNotes(dev))
+		}
+	}
+	w.Flush()
+
+	return nil
+}
+
+func clusterDeviceNotes(dev client.ClusterDeviceStatus) string {
+	var notes []string
+	if dev.Local {
+		// TRANSLATORS: note on the device that the cluster status is shown on
+		notes = append(notes, i18n.G("this device"))
+	}
+	if dev.Reason != "" {
+		notes = append(notes, dev.Reason)
+	}
+	if len(notes) == 0 {
+		return "-"
+	}
+	return strings.Join(notes, "; ")
+}
@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snapd/cli"
+)
+
+func (s *SnapSuite) TestClusterStatus(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/cluster/status")
+		fmt.Fprintln(w, `{"type": "sync", "result": {
+			"cluster-id": "cluster-id",
+			"sequence": 2,
+			"subclusters": [{
+				"name": "default",
+				"devices": [
+					{"id": 1, "device": "serial-1.model.brand", "status": "converged", "sequence": 2, "local": true},
+					{"id": 2, "device": "serial-2.model.brand", "status": "lagging", "sequence": 1, "reason": "cluster sequence 2 not applied yet", "received": "2026-01-01T00:00:00Z"},
+					{"id": 3, "device": "serial-3.model.brand", "status": "unknown"}
+				]
+			}, {
+				"name": "db",
+				"devices": [
+					{"id": 2, "device": "serial-2.model.brand", "status": "failed", "sequence": 1, "reason": "cannot install \"db\"", "received": "2026-01-01T00:00:00Z"}
+				]
+			}]
+		}}`)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"cluster", "status"})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(n, Equals, 1)
+	c.Check(s.Stdout(), Equals, `
+Cluster cluster-id at sequence 2
+Subcluster  ID   Device                Status     Sequence  Notes
+default     1    serial-1.model.brand  converged  2         this device
+default     2    serial-2.model.brand  lagging    1         cluster sequence 2 not applied yet
+default     3    serial-3.model.brand  unknown    -         -
+db          2    serial-2.model.brand  failed     1         cannot install "db"
+`[1:])
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestClusterStatusNotInCluster(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "this device is not part of a cluster"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"cluster", "status"})
+	c.Assert(err, ErrorMatches, "this device is not part of a cluster")
+}
+
+func (s *SnapSuite) TestClusterStatusExtraArgs(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"cluster", "status", "extra"})
+	c.Assert(err, Equals, snap.ErrExtraArgs)
+}
+
+func (s *SnapSuite) TestClusterNoSubcommand(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"cluster"})
+	c.Assert(err, ErrorMatches, "Please specify the status command")
+}
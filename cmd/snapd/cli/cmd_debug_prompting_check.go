@@ -0,0 +1,98 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdDebugPromptingCheck struct {
+	clientMixin
+	Interface string `long:"interface" default:"home"`
+
+	Positional struct {
+		Snap        string   `positional-arg-name:"<snap>" required:"yes"`
+		Path        string   `positional-arg-name:"<path>" required:"yes"`
+		Permissions []string `positional-arg-name:"<permission>" required:"1"`
+	} `positional-args:"yes"`
+}
+
+var cmdDebugPromptingCheckShortHelp = i18n.G("Check how prompting rules would handle a request")
+var cmdDebugPromptingCheckLongHelp = i18n.G(`The prompting-check command reports whether a request from the given snap
+for the given permissions to the given path would be allowed, denied, or
+prompted for by the prompting rules of the current user and the policy
+rules, were it received now.
+
+For each permission, the rules and path pattern variants which match the
+path are listed, along with the reason why the deciding variant takes
+precedence over the others. No prompt is created by the check.`)
+
+func init() {
+	addDebugCommand("prompting-check", cmdDebugPromptingCheckShortHelp, cmdDebugPromptingCheckLongHelp, func() flags.Commander {
+		return &cmdDebugPromptingCheck{}
+	}, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"interface": i18n.G("Interface through which the request is made"),
+	}, []argDesc{
+		// TRANSLATORS: This needs to begin with < and end with >
+		{name: "<snap>", desc: i18n.G("Snap making the request")},
+		// TRANSLATORS: This needs to begin with < and end with >
+		{name: "<path>", desc: i18n.G("Path to which access is requested")},
+		// TRANSLATORS: This needs to begin with < and end with >
+		{name: "<permission>", desc: i18n.G("Requested permission, such as read, write, or execute")},
+	})
+}
+
+func (x *cmdDebugPromptingCheck) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	check, err := x.client.CheckPromptingRequest(x.Positional.Snap, x.Interface, x.Positional.Path, x.Positional.Permissions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Decision: %s\n"), check.Decision)
+	for _, perm := range check.Permissions {
+		fmt.Fprintf(Stdout, "\n%s: %s\n", perm.Permission, perm.Decision)
+		if perm.Source != "" {
+			fmt.Fprintf(Stdout, i18n.G("  source: %s\n"), perm.Source)
+			fmt.Fprintf(Stdout, i18n.G("  variant: %s\n"), perm.Variant)
+			fmt.Fprintf(Stdout, i18n.G("  rules: %s\n"), strings.Join(perm.RuleIDs, ", "))
+		}
+		fmt.Fprintf(Stdout, i18n.G("  reason: %s\n"), perm.Reason)
+		if len(perm.Matches) == 0 {
+			continue
+		}
+		fmt.Fprintf(Stdout, i18n.G("  matching variants:\n"))
+		w := tabWriter()
+		for _, match := range perm.Matches {
+			fmt.Fprintf(w, "    %s\t%s\t%s\t%s\n", match.Source, match.Variant, match.Outcome, strings.Join(match.RuleIDs, ","))
+		}
+		w.Flush()
+	}
+	return nil
+}
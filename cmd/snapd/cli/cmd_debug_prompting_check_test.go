@@ -0,0 +1,125 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snapd/cli"
+)
+
+func (s *SnapSuite) TestDebugPromptingCheck(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/interfaces/requests/check")
+			c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+				"snap":        []string{"firefox"},
+				"interface":   []string{"home"},
+				"path":        []string{"/home/test/.ssh/config"},
+				"permissions": []string{"read,write"},
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+				"decision": "deny",
+				"permissions": [
+					{"permission": "read", "decision": "prompt", "reason": "no rule matches the path, so the user would be prompted"},
+					{
+						"permission": "write",
+						"decision": "deny",
+						"source": "policy",
+						"variant": "/home/*/.ssh/**",
+						"rule-ids": ["0000000000000012"],
+						"matches": [
+							{"source": "policy", "variant": "/home/*/.ssh/**", "outcome": "deny", "rule-ids": ["0000000000000012"]},
+							{"source": "user", "variant": "/home/test/**", "outcome": "allow", "rule-ids": ["0000000000000003", "0000000000000004"]}
+						],
+						"reason": "/home/*/.ssh/** is the only matching variant of the policy rules for all snaps, which take precedence over matching user rules"
+					}
+				]
+			}}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "prompting-check", "firefox", "/home/test/.ssh/config", "read", "write"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, `Decision: deny
+
+read: prompt
+  reason: no rule matches the path, so the user would be prompted
+
+write: deny
+  source: policy
+  variant: /home/*/.ssh/**
+  rules: 0000000000000012
+  reason: /home/*/.ssh/** is the only matching variant of the policy rules for all snaps, which take precedence over matching user rules
+  matching variants:
+    policy  /home/*/.ssh/**  deny   0000000000000012
+    user    /home/test/**    allow  0000000000000003,0000000000000004
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugPromptingCheckInterface(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query().Get("interface"), check.Equals, "camera")
+		c.Check(r.URL.Query().Get("permissions"), check.Equals, "access")
+		fmt.Fprintln(w, `{"type": "sync", "result": {
+			"decision": "prompt",
+			"permissions": [
+				{"permission": "access", "decision": "prompt", "reason": "no rule matches the path, so the user would be prompted"}
+			]
+		}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "prompting-check", "--interface=camera", "firefox", "/dev/video0", "access"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `Decision: prompt
+
+access: prompt
+  reason: no rule matches the path, so the user would be prompted
+`)
+}
+
+func (s *SnapSuite) TestDebugPromptingCheckError(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 400, "result": {"message": "invalid permissions for home interface: \"access\"", "kind": "interfaces-requests-invalid-fields"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "prompting-check", "firefox", "/home/test/foo", "access"})
+	c.Assert(err, check.ErrorMatches, `cannot check prompting request: invalid permissions for home interface: "access"`)
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugPromptingCheckMissingPermission(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "prompting-check", "firefox", "/home/test/foo"})
+	c.Assert(err, check.ErrorMatches, `the required argument .*<permission>.* was not provided`)
+}
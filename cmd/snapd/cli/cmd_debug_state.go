@@ -20,6 +20,7 @@
 package cli
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -27,6 +28,7 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
@@ -34,9 +36,11 @@ import (
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/dot"
 	"github.com/snapcore/snapd/overlord/ifacestate/schema"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/strutil"
 )
 
@@ -53,6 +57,10 @@ type cmdDebugState struct {
 
 	IsSeeded bool `long:"is-seeded"`
 
+	JSON bool `long:"json"`
+
+	At string `long:"at"`
+
 	// flags for --change=N output
 	DotOutput bool `long:"dot"` // XXX: mildly useful (too crowded in many cases), but let's have it just in case
 	// When inspecting errors/undone tasks, those in Hold state are usually irrelevant, make it possible to ignore them
@@ -64,7 +72,17 @@ type cmdDebugState struct {
 }
 
 var cmdDebugStateShortHelp = i18n.G("Inspect a snapd state file.")
-var cmdDebugStateLongHelp = i18n.G("Inspect a snapd state file, bypassing snapd API.")
+var cmdDebugStateLongHelp = i18n.G(`Inspect a snapd state file, bypassing snapd API.
+
+The state file can be either a state.json file or a state.db state database.
+A state.json file that was migrated to the state database next to it, or
+written from it for an older snapd, is read from there.
+
+With --json, the whole state is output in the format of a state.json file.
+
+With --at, the state database is inspected as it was at the given time, as
+far back as its history of checkpoints goes, which covers the last day by
+the minute.`)
 
 type byChangeSpawnTime []*state.Change
 
@@ -72,10 +90,57 @@ func (c byChangeSpawnTime) Len() int           { return len(c) }
 func (c byChangeSpawnTime) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
 func (c byChangeSpawnTime) Less(i, j int) bool { return c[i].SpawnTime().Before(c[j].SpawnTime()) }
 
-func loadState(path string) (*state.State, error) {
+// resolveStatePath returns the state file or database to read given the
+// path of either.
+func resolveStatePath(path string) string {
 	if path == "" {
 		path = "state.json"
 	}
+	if !strings.HasSuffix(path, ".json") {
+		return path
+	}
+	// the state file might have been migrated to the state database next
+	// to it, and then only be kept up to date for an older snapd when
+	// snapd stops
+	dbPath := strings.TrimSuffix(path, ".json") + ".db"
+	if !osutil.FileExists(dbPath) {
+		return path
+	}
+	// failing to tell, e.g. as snapd keeps the state database open,
+	// reading the state database says why rather than reading a state
+	// file that might be stale
+	if changed, err := statedb.StateFileChanged(dbPath, path); err != nil || !changed {
+		return dbPath
+	}
+	return path
+}
+
+func isStateDB(path string) bool {
+	return strings.HasSuffix(path, ".db")
+}
+
+// parseStateTime parses the time given with --at.
+func parseStateTime(at string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse time %q: expected RFC3339 format, e.g. 2006-01-02T15:04:05Z", at)
+	}
+	return t, nil
+}
+
+// loadState reads the state at path, as it was at the given time unless
+// that is zero.
+func loadState(path string, at time.Time) (*state.State, error) {
+	path = resolveStatePath(path)
+	if isStateDB(path) {
+		if !at.IsZero() {
+			return statedb.ReadStateAt(nil, path, at)
+		}
+		return statedb.ReadState(nil, path)
+	}
+	if !at.IsZero() {
+		return nil, fmt.Errorf("cannot inspect the state at a given time: %s has no history", path)
+	}
 	r, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read the state file: %s", err)
@@ -98,7 +163,9 @@ func init() {
 		"connections": i18n.G("List all connections"),
 		"connection":  i18n.G("Show details of the matching connections (snap or snap:plug,snap:slot or snap:plug-or-slot"),
 		"is-seeded":   i18n.G("Output seeding status (true or false)"),
+		"json":        i18n.G("Output the whole state as JSON"),
 		"check":       i18n.G("Check change consistency"),
+		"at":          i18n.G("Inspect the state as it was at the given time (RFC3339)"),
 	}), nil)
 }
 
@@ -293,6 +360,19 @@ func (c *cmdDebugState) showIsSeeded(st *state.State) error {
 	return nil
 }
 
+func (c *cmdDebugState) showJSON(st *state.State) error {
+	st.Lock()
+	defer st.Unlock()
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, "%s\n", data)
+
+	return nil
+}
+
 type connectionInfo struct {
 	PlugSnap string
 	PlugName string
@@ -489,7 +569,14 @@ func (c *cmdDebugState) showTask(st *state.State, taskID string) error {
 }
 
 func (c *cmdDebugState) Execute(args []string) error {
-	st, err := loadState(c.Positional.StateFilePath)
+	var at time.Time
+	if c.At != "" {
+		var err error
+		if at, err = parseStateTime(c.At); err != nil {
+			return err
+		}
+	}
+	st, err := loadState(c.Positional.StateFilePath, at)
 	if err != nil {
 		return err
 	}
@@ -511,6 +598,9 @@ func (c *cmdDebugState) Execute(args []string) error {
 	if c.Connections {
 		cmds = append(cmds, "--connections")
 	}
+	if c.JSON {
+		cmds = append(cmds, "--json")
+	}
 	if len(cmds) > 1 {
 		return fmt.Errorf("cannot use %s and %s together", cmds[0], cmds[1])
 	}
@@ -519,6 +609,10 @@ func (c *cmdDebugState) Execute(args []string) error {
 		return c.showIsSeeded(st)
 	}
 
+	if c.JSON {
+		return c.showJSON(st)
+	}
+
 	if c.DotOutput && c.ChangeID == "" {
 		return fmt.Errorf("--dot can only be used with --change=")
 	}
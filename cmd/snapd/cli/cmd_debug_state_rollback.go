@@ -0,0 +1,117 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state/statedb"
+)
+
+type cmdDebugStateRollback struct {
+	timeMixin
+
+	At string `long:"at" required:"yes"`
+
+	Positional struct {
+		StateFilePath string `positional-args:"yes" positional-arg-name:"<state-file>"`
+	} `positional-args:"yes"`
+}
+
+var cmdDebugStateRollbackShortHelp = i18n.G("Roll back the snapd state to an earlier time.")
+var cmdDebugStateRollbackLongHelp = i18n.G(`Roll back the snapd state database to what it was at the given time,
+as far back as its history of checkpoints goes, which covers the last day
+by the minute. snapd must be stopped.
+
+The rollback is itself kept in the history, so it can be undone by rolling
+back to a time before it. The changes in the restored state are listed
+afterwards, as with snap debug state --changes.
+
+The state database defaults to the one of the system.`)
+
+func init() {
+	addDebugCommand("state-rollback", cmdDebugStateRollbackShortHelp, cmdDebugStateRollbackLongHelp, func() flags.Commander {
+		return &cmdDebugStateRollback{}
+	}, timeDescs.also(map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"at": i18n.G("Time to roll back the state to (RFC3339)"),
+	}), nil)
+}
+
+// checkSnapdStopped checks that no snapd holds the state lock next to the
+// state database at path.
+func checkSnapdStopped(path string) error {
+	lockPath := filepath.Join(filepath.Dir(path), filepath.Base(dirs.SnapStateLockFile))
+	if !osutil.FileExists(lockPath) {
+		return nil
+	}
+	flock, err := osutil.NewFileLockWithMode(lockPath, 0644)
+	if err != nil {
+		return err
+	}
+	defer flock.Close()
+	if err := flock.TryLock(); err != nil {
+		if err == osutil.ErrAlreadyLocked {
+			return fmt.Errorf("cannot roll back the state while snapd is running")
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *cmdDebugStateRollback) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	at, err := parseStateTime(c.At)
+	if err != nil {
+		return err
+	}
+	path := c.Positional.StateFilePath
+	if path == "" {
+		path = dirs.SnapStateDBFile
+	}
+	path = resolveStatePath(path)
+	if !isStateDB(path) {
+		return fmt.Errorf("cannot roll back the state: %s has no history", path)
+	}
+
+	if err := checkSnapdStopped(path); err != nil {
+		return err
+	}
+	if err := statedb.Rollback(path, at); err != nil {
+		return err
+	}
+
+	st, err := statedb.ReadState(nil, path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, i18n.G("Rolled back the state in %s to %s.\n"), path, at.Format(time.RFC3339))
+	render := &cmdDebugState{timeMixin: c.timeMixin}
+	return render.showChanges(st)
+}
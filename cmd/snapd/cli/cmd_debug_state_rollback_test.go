@@ -0,0 +1,81 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	main "github.com/snapcore/snapd/cmd/snapd/cli"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state/statedb"
+)
+
+func (s *SnapSuite) TestDebugStateRollback(c *C) {
+	stateDB, seededAt := makeStateDBWithHistory(c)
+	at := seededAt.Format(time.RFC3339Nano)
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state-rollback", "--abs-time", "--at", at, stateDB})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals,
+		"Rolled back the state in "+stateDB+" to "+seededAt.Format(time.RFC3339)+".\n"+
+			"ID   Status  Spawn                 Ready                 Label         Summary\n"+
+			"9    Do      2009-11-10T23:00:00Z  0001-01-01T00:00:00Z  install-snap  install a snap\n"+
+			"10   Done    2009-11-10T23:00:10Z  2009-11-10T23:00:30Z  revert-snap   revert c snap\n")
+	c.Check(s.Stderr(), Equals, "")
+
+	st, err := statedb.ReadState(nil, stateDB)
+	c.Assert(err, IsNil)
+	st.Lock()
+	defer st.Unlock()
+	var seeded bool
+	c.Check(st.Get("seeded", &seeded), IsNil)
+	c.Check(seeded, Equals, true)
+}
+
+func (s *SnapSuite) TestDebugStateRollbackSnapdRunning(c *C) {
+	stateDB, seededAt := makeStateDBWithHistory(c)
+	flock, err := osutil.NewFileLockWithMode(filepath.Join(filepath.Dir(stateDB), "state.lock"), 0644)
+	c.Assert(err, IsNil)
+	defer flock.Close()
+	c.Assert(flock.Lock(), IsNil)
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state-rollback", "--at", seededAt.Format(time.RFC3339Nano), stateDB})
+	c.Check(err, ErrorMatches, "cannot roll back the state while snapd is running")
+}
+
+func (s *SnapSuite) TestDebugStateRollbackErrors(c *C) {
+	dir := c.MkDir()
+	stateFile := filepath.Join(dir, "test-state.json")
+	c.Assert(os.WriteFile(stateFile, stateJSON, 0644), IsNil)
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state-rollback", stateFile})
+	c.Check(err, ErrorMatches, "the required flag `--at' was not specified")
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state-rollback", "--at", "2009-11-10T23:00:00Z", stateFile})
+	c.Check(err, ErrorMatches, `cannot roll back the state: .*/test-state.json has no history`)
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state-rollback", "--at", "2009-11-10T23:00:00Z", filepath.Join(dir, "missing.db")})
+	c.Check(err, ErrorMatches, `cannot open state database: .* no such file or directory`)
+}
@@ -20,14 +20,18 @@
 package cli_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	. "gopkg.in/check.v1"
 
 	main "github.com/snapcore/snapd/cmd/snapd/cli"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 )
 
 var stateJSON = []byte(`
@@ -319,6 +323,120 @@ func (s *SnapSuite) TestDebugChanges(c *C) {
 	c.Check(s.Stderr(), Equals, "")
 }
 
+type stateDBBackend struct {
+	path string
+}
+
+func (b *stateDBBackend) Checkpoint(data []byte) error {
+	panic("Checkpoint should not be called with a DeltaBackend")
+}
+
+func (b *stateDBBackend) CheckpointDelta(delta *state.Delta) error {
+	return statedb.Write(b.path, delta)
+}
+
+func (b *stateDBBackend) EnsureBefore(d time.Duration) {}
+
+func (s *SnapSuite) TestDebugChangesStateDB(c *C) {
+	stateDB := filepath.Join(c.MkDir(), "state.db")
+	st, err := state.ReadState(&stateDBBackend{path: stateDB}, bytes.NewReader(stateJSON))
+	c.Assert(err, IsNil)
+	// checkpoint it all into the state database
+	st.Lock()
+	st.Set("mark", 1)
+	st.Unlock()
+
+	// the state database is also found from the state file it replaced
+	for _, path := range []string{stateDB, strings.TrimSuffix(stateDB, ".db") + ".json"} {
+		s.ResetStdStreams()
+		rest, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--abs-time", "--changes", path})
+		c.Assert(err, IsNil)
+		c.Assert(rest, DeepEquals, []string{})
+		c.Check(s.Stdout(), Matches,
+			"ID   Status  Spawn                 Ready                 Label         Summary\n"+
+				"9    Do      2009-11-10T23:00:00Z  0001-01-01T00:00:00Z  install-snap  install a snap\n"+
+				"10   Done    2009-11-10T23:00:10Z  2009-11-10T23:00:30Z  revert-snap   revert c snap\n")
+		c.Check(s.Stderr(), Equals, "")
+	}
+}
+
+func (s *SnapSuite) TestDebugStateDBWithStateFileForOlderSnapd(c *C) {
+	stateDB, _ := makeStateDBWithHistory(c)
+	stateFile := strings.TrimSuffix(stateDB, ".db") + ".json"
+	// the state file is written from the unseeded state for an older
+	// snapd, then the state database moves on
+	c.Assert(statedb.WriteStateFile(stateDB, stateFile), IsNil)
+	c.Assert(statedb.Write(stateDB, &state.Delta{Entries: map[string]map[string][]byte{
+		"data": {"seeded": []byte("true")},
+	}}), IsNil)
+
+	// so the state database is read instead of the state file
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--is-seeded", stateFile})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "true\n")
+
+	// unless an older snapd changed the state file since
+	data, err := os.ReadFile(stateFile)
+	c.Assert(err, IsNil)
+	c.Assert(os.WriteFile(stateFile, append(data, '\n'), 0600), IsNil)
+	s.ResetStdStreams()
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--is-seeded", stateFile})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "false\n")
+}
+
+// makeStateDBWithHistory writes the test state into a state database and
+// then marks it unseeded, returning the path of the database and a time
+// between the two checkpoints.
+func makeStateDBWithHistory(c *C) (path string, seededAt time.Time) {
+	path = filepath.Join(c.MkDir(), "state.db")
+	st, err := state.ReadState(&stateDBBackend{path: path}, bytes.NewReader(stateJSON))
+	c.Assert(err, IsNil)
+	st.Lock()
+	st.Set("mark", 1)
+	st.Unlock()
+
+	seededAt = time.Now()
+	// make sure the next checkpoint is strictly later
+	time.Sleep(time.Millisecond)
+
+	st.Lock()
+	st.Set("seeded", false)
+	st.Unlock()
+	return path, seededAt
+}
+
+func (s *SnapSuite) TestDebugStateAt(c *C) {
+	stateDB, seededAt := makeStateDBWithHistory(c)
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--is-seeded", stateDB})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "false\n")
+
+	s.ResetStdStreams()
+	rest, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--is-seeded", "--at", seededAt.Format(time.RFC3339Nano), stateDB})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "true\n")
+	c.Check(s.Stderr(), Equals, "")
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--is-seeded", "--at", "2009-11-10T23:00:00Z", stateDB})
+	c.Check(err, ErrorMatches, `cannot read state database at 2009-11-10T23:00:00Z: no state history before .*`)
+}
+
+func (s *SnapSuite) TestDebugStateAtErrors(c *C) {
+	stateFile := filepath.Join(c.MkDir(), "test-state.json")
+	c.Assert(os.WriteFile(stateFile, stateJSON, 0644), IsNil)
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--changes", "--at", "2009-11-10T23:00:00Z", stateFile})
+	c.Check(err, ErrorMatches, `cannot inspect the state at a given time: .*/test-state.json has no history`)
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--changes", "--at", "yesterday", stateFile})
+	c.Check(err, ErrorMatches, `cannot parse time "yesterday": expected RFC3339 format, e.g. 2006-01-02T15:04:05Z`)
+}
+
 func (s *SnapSuite) TestDebugChangesMissingState(c *C) {
 	_, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--changes", "/missing-state.json"})
 	c.Check(err, ErrorMatches, "cannot read the state file: open /missing-state.json: no such file or directory")
@@ -480,6 +598,26 @@ func (s *SnapSuite) TestDebugIsSeededNo(c *C) {
 	c.Check(s.Stderr(), Equals, "")
 }
 
+func (s *SnapSuite) TestDebugStateJSON(c *C) {
+	stateDB, _ := makeStateDBWithHistory(c)
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--json", stateDB})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	st, err := state.ReadState(nil, strings.NewReader(s.Stdout()))
+	c.Assert(err, IsNil)
+	st.Lock()
+	defer st.Unlock()
+	var seeded bool
+	c.Assert(st.Get("seeded", &seeded), IsNil)
+	c.Check(seeded, Equals, false)
+	c.Check(st.Changes(), HasLen, 2)
+	c.Check(s.Stderr(), Equals, "")
+
+	_, err = main.Parser(main.Client()).ParseArgs([]string{"debug", "state", "--json", "--changes", stateDB})
+	c.Check(err, ErrorMatches, "cannot use --changes and --json together")
+}
+
 func (s *SnapSuite) TestDebugConnections(c *C) {
 	dir := c.MkDir()
 	stateFile := filepath.Join(dir, "test-state.json")
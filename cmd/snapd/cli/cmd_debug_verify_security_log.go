@@ -0,0 +1,135 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/seclog"
+)
+
+type cmdDebugVerifySecurityLog struct {
+	clientMixin
+
+	Positional struct {
+		LogPath flags.Filename `positional-arg-name:"<log-file>"`
+	} `positional-args:"yes"`
+}
+
+var cmdDebugVerifySecurityLogShortHelp = i18n.G("Verify the integrity of the security log.")
+var cmdDebugVerifySecurityLogLongHelp = i18n.G(`Verify that the records of the snapd security log have neither been
+modified nor removed, by checking the hash chain linking them and the
+signatures of their checkpoints, made with the device key.
+
+Records following the last checkpoint are reported, as they could have
+been altered unnoticed. The log file defaults to the audit log, whose last
+records are also checked against the head of the chain kept by snapd, to
+detect records removed at its end.`)
+
+func init() {
+	addDebugCommand("verify-security-log", cmdDebugVerifySecurityLogShortHelp, cmdDebugVerifySecurityLogLongHelp, func() flags.Commander {
+		return &cmdDebugVerifySecurityLog{}
+	}, nil, nil)
+}
+
+// deviceKeys returns the device keys found in the serial assertions known
+// to the system, by ID.
+func (x *cmdDebugVerifySecurityLog) deviceKeys() (map[string]asserts.PublicKey, error) {
+	serials, err := x.client.Known("serial", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the device keys: %v", err)
+	}
+	keys := make(map[string]asserts.PublicKey, len(serials))
+	for _, a := range serials {
+		serial, ok := a.(*asserts.Serial)
+		if !ok {
+			continue
+		}
+		keys[serial.DeviceKey().ID()] = serial.DeviceKey()
+	}
+	return keys, nil
+}
+
+func (x *cmdDebugVerifySecurityLog) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	path := string(x.Positional.LogPath)
+	var head *seclog.HashChainHead
+	if path == "" {
+		path = filepath.Join(dirs.GlobalRootDir, seclog.AuditLogFile)
+		// the head needs to be read before the log, which can only have
+		// more records by then
+		if err := x.client.DebugGet("security-log-head", &head, nil); err != nil {
+			return fmt.Errorf("cannot get the head of the security log: %v", err)
+		}
+	}
+
+	keys, err := x.deviceKeys()
+	if err != nil {
+		return err
+	}
+	verify := func(data, signature []byte, keyID string) error {
+		key, ok := keys[keyID]
+		if !ok {
+			return fmt.Errorf("unknown device key %s", keyID)
+		}
+		return asserts.RawVerifyWithKey(data, signature, key)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	report, err := seclog.VerifyHashChain(f, head, verify)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %v", path, err)
+	}
+	if report.Records == 0 {
+		return fmt.Errorf(i18n.G("no security log records found in %s"), path)
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Checked %d records, from %d to %d, with %d checkpoints.\n"),
+		report.Records, report.FirstSeq, report.LastSeq, report.Checkpoints)
+	switch {
+	case report.Checkpoints == 0:
+		fmt.Fprintf(Stdout, i18n.G("No record is covered by a checkpoint.\n"))
+	case report.LastCheckpointSeq != report.LastSeq:
+		fmt.Fprintf(Stdout, i18n.G("Records after %d are not covered by a checkpoint.\n"), report.LastCheckpointSeq)
+	}
+	if len(report.Problems) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Problems found:\n"))
+	for _, problem := range report.Problems {
+		fmt.Fprintf(Stdout, "  %s\n", problem)
+	}
+	return errors.New(i18n.G("the security log has been tampered with"))
+}
@@ -0,0 +1,222 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	snap "github.com/snapcore/snapd/cmd/snapd/cli"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/seclog"
+)
+
+type verifySecurityLogSuite struct {
+	BaseSnapSuite
+
+	storeSigning *assertstest.StoreStack
+	devKey       asserts.PrivateKey
+	logPath      string
+	statePath    string
+}
+
+var _ = Suite(&verifySecurityLogSuite{})
+
+func (s *verifySecurityLogSuite) SetUpTest(c *C) {
+	s.BaseSnapSuite.SetUpTest(c)
+	s.storeSigning = assertstest.NewStoreStack("my-brand", nil)
+	s.devKey, _ = assertstest.GenerateKey(752)
+	s.logPath = filepath.Join(c.MkDir(), "audit.log")
+	s.statePath = filepath.Join(c.MkDir(), seclog.HashChainFile)
+
+	serial := s.serial(c)
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/assertions/serial":
+			w.Header().Set("X-Ubuntu-Assertions-Count", "1")
+			w.Write(asserts.Encode(serial))
+		case "/v2/debug":
+			c.Check(r.URL.Query().Get("aspect"), Equals, "security-log-head")
+			head, err := seclog.ReadHashChainHead(s.statePath)
+			if errors.Is(err, os.ErrNotExist) {
+				head = nil
+			} else {
+				c.Assert(err, IsNil)
+			}
+			c.Assert(json.NewEncoder(w).Encode(map[string]any{"type": "sync", "result": head}), IsNil)
+		default:
+			c.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+	s.AddCleanup(func() { seclog.SetupCheckpointSigner(nil) })
+}
+
+func (s *verifySecurityLogSuite) serial(c *C) asserts.Assertion {
+	encDevKey, err := asserts.EncodePublicKey(s.devKey.PublicKey())
+	c.Assert(err, IsNil)
+	serial, err := s.storeSigning.Sign(asserts.SerialType, map[string]any{
+		"authority-id":        "my-brand",
+		"brand-id":            "my-brand",
+		"model":               "my-model",
+		"serial":              "serial-1",
+		"device-key":          string(encDevKey),
+		"device-key-sha3-384": s.devKey.PublicKey().ID(),
+		"timestamp":           time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	return serial
+}
+
+// writeLog writes n chained records to the log, embedded in audit log lines;
+// the first one is a signed checkpoint.
+func (s *verifySecurityLogSuite) writeLog(c *C, n int) {
+	seclog.SetupCheckpointSigner(func(data []byte) ([]byte, string, error) {
+		sig, err := asserts.RawSignWithKey(data, s.devKey)
+		return sig, s.devKey.PublicKey().ID(), err
+	})
+	f, err := os.Create(s.logPath)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	auditWriter := writerFunc(func(p []byte) (int, error) {
+		return fmt.Fprintf(f, "type=USER msg=audit(1.0:1): %s", p)
+	})
+	hw := seclog.NewHashChainWriter(auditWriter, s.statePath, "")
+	for i := 1; i <= n; i++ {
+		_, err := fmt.Fprintf(hw, `{"event":"event-%d"}`+"\n", i)
+		c.Assert(err, IsNil)
+	}
+	// saves the head of the chain
+	c.Assert(hw.Close(), IsNil)
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func (s *verifySecurityLogSuite) TestVerify(c *C) {
+	s.writeLog(c, 3)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log", s.logPath})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(s.Stdout(), Equals, `Checked 3 records, from 1 to 3, with 1 checkpoints.
+Records after 1 are not covered by a checkpoint.
+`)
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *verifySecurityLogSuite) TestVerifyDefaultPath(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("/")
+	s.logPath = filepath.Join(dirs.GlobalRootDir, "/var/log/audit/audit.log")
+	c.Assert(os.MkdirAll(filepath.Dir(s.logPath), 0755), IsNil)
+	s.writeLog(c, 1)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "Checked 1 records, from 1 to 1, with 1 checkpoints.\n")
+}
+
+func (s *verifySecurityLogSuite) TestVerifyDefaultPathTruncated(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("/")
+	s.logPath = filepath.Join(dirs.GlobalRootDir, "/var/log/audit/audit.log")
+	c.Assert(os.MkdirAll(filepath.Dir(s.logPath), 0755), IsNil)
+	s.writeLog(c, 3)
+	data, err := os.ReadFile(s.logPath)
+	c.Assert(err, IsNil)
+	lines := strings.SplitAfter(string(data), "\n")
+	c.Assert(os.WriteFile(s.logPath, []byte(strings.Join(lines[:2], "")), 0644), IsNil)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log"})
+	c.Assert(err, ErrorMatches, "the security log has been tampered with")
+	c.Check(s.Stdout(), Equals, `Checked 2 records, from 1 to 2, with 1 checkpoints.
+Records after 1 are not covered by a checkpoint.
+Problems found:
+  record 3 is missing at the end of the log
+`)
+
+	// the head is only checked for the default log
+	s.ResetStdStreams()
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log", s.logPath})
+	c.Assert(err, IsNil)
+}
+
+func (s *verifySecurityLogSuite) TestVerifyTampered(c *C) {
+	s.writeLog(c, 4)
+	data, err := os.ReadFile(s.logPath)
+	c.Assert(err, IsNil)
+	lines := strings.SplitAfter(string(data), "\n")
+	// modify the first record and drop the third one
+	lines[0] = strings.Replace(lines[0], "event-1", "event-X", 1)
+	lines = append(lines[:2], lines[3:]...)
+	c.Assert(os.WriteFile(s.logPath, []byte(strings.Join(lines, "")), 0644), IsNil)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log", s.logPath})
+	c.Assert(err, ErrorMatches, "the security log has been tampered with")
+	c.Check(s.Stdout(), Equals, `Checked 3 records, from 1 to 4, with 1 checkpoints.
+Records after 1 are not covered by a checkpoint.
+Problems found:
+  line 1: record 1 has been modified
+  line 3: record 3 is missing
+`)
+}
+
+func (s *verifySecurityLogSuite) TestVerifyUnknownKey(c *C) {
+	s.devKey, _ = assertstest.GenerateKey(752)
+	s.writeLog(c, 1)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log", s.logPath})
+	c.Assert(err, ErrorMatches, "the security log has been tampered with")
+	c.Check(s.Stdout(), Equals, fmt.Sprintf(`Checked 1 records, from 1 to 1, with 0 checkpoints.
+No record is covered by a checkpoint.
+Problems found:
+  line 1: invalid signature of record 1: unknown device key %s
+`, s.devKey.PublicKey().ID()))
+}
+
+func (s *verifySecurityLogSuite) TestVerifyNoRecords(c *C) {
+	c.Assert(os.WriteFile(s.logPath, []byte("type=USER msg=audit(1.0:1): something else\n"), 0644), IsNil)
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log", s.logPath})
+	c.Assert(err, ErrorMatches, "no security log records found in .*/audit.log")
+}
+
+func (s *verifySecurityLogSuite) TestVerifyMissingFile(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log", s.logPath})
+	c.Assert(err, ErrorMatches, "open .*/audit.log: no such file or directory")
+}
+
+func (s *verifySecurityLogSuite) TestVerifyExtraArgs(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "verify-security-log", s.logPath, "extra"})
+	c.Assert(err, ErrorMatches, "too many arguments for command")
+}
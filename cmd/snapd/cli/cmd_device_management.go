@@ -0,0 +1,156 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var (
+	shortDeviceManagementHelp = i18n.G("Show the device management message queue")
+	longDeviceManagementHelp  = i18n.G(`
+The device-management command lists the pending request messages for remote
+device management, by sequence, showing where each one is in its processing,
+the changes acting on it and why it was rejected, if it was. It also lists the
+responses waiting to be sent to the store or exported.
+
+A sequence stuck at missing messages can be dropped with --drop-sequence: the
+earliest pending message of the sequence is rejected and the others are
+discarded.
+`)
+)
+
+type cmdDeviceManagement struct {
+	waitMixin
+	timeMixin
+	DropSequence string `long:"drop-sequence" value-name:"<base-id>"`
+}
+
+func init() {
+	addCommand("device-management", shortDeviceManagementHelp, longDeviceManagementHelp,
+		func() flags.Commander { return &cmdDeviceManagement{} },
+		waitDescs.also(timeDescs).also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"drop-sequence": i18n.G("Drop the pending messages of the given sequence"),
+		}), nil)
+}
+
+func (x *cmdDeviceManagement) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	if x.DropSequence != "" {
+		return x.dropSequence()
+	}
+
+	queue, err := x.client.DeviceManagementMessages()
+	if err != nil {
+		return err
+	}
+	if len(queue.Sequences) == 0 && len(queue.Responses) == 0 {
+		fmt.Fprintln(Stdout, i18n.G("No pending device management messages."))
+		return nil
+	}
+
+	w := tabWriter()
+	if len(queue.Sequences) > 0 {
+		fmt.Fprint(w, i18n.G("Sequence\tMessage\tKind\tReceived\tStage\tChange\tNotes\n"))
+		for _, seq := range queue.Sequences {
+			for _, msg := range seq.Messages {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", seq.BaseID, msg.ID, msg.Kind,
+					x.fmtTime(msg.ReceiveTime), msg.Stage, mgmtMessageChange(msg), mgmtMessageNotes(msg))
+			}
+		}
+	}
+	if len(queue.Responses) > 0 {
+		if len(queue.Sequences) > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprint(w, i18n.G("Response\tStatus\tDestination\tReason\n"))
+		for _, res := range queue.Responses {
+			// TRANSLATORS: where a device management response is sent to
+			dest := i18n.G("store")
+			if res.Source != "" {
+				dest = res.Source
+			}
+			reason := res.Reason
+			if reason == "" {
+				reason = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", res.MessageID, res.Status, dest, reason)
+		}
+	}
+	w.Flush()
+
+	return nil
+}
+
+// mgmtMessageChange returns the most relevant change acting on the message.
+func mgmtMessageChange(msg client.DeviceManagementMessage) string {
+	switch {
+	case msg.ApplyChangeID != "":
+		return msg.ApplyChangeID
+	case msg.ChangeID != "":
+		return msg.ChangeID
+	default:
+		return "-"
+	}
+}
+
+func mgmtMessageNotes(msg client.DeviceManagementMessage) string {
+	var notes []string
+	if msg.Source != "" {
+		notes = append(notes, msg.Source)
+	}
+	if msg.Stage == "blocked" {
+		notes = append(notes, i18n.G("waiting for missing messages"))
+	}
+	if msg.Reason != "" {
+		notes = append(notes, fmt.Sprintf("%s: %s", msg.Status, msg.Reason))
+	}
+	if len(notes) == 0 {
+		return "-"
+	}
+	return strings.Join(notes, "; ")
+}
+
+func (x *cmdDeviceManagement) dropSequence() error {
+	changeID, err := x.client.DropDeviceManagementSequence(x.DropSequence)
+	if err != nil {
+		return err
+	}
+
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Dropped sequence %q\n"), x.DropSequence)
+	return nil
+}
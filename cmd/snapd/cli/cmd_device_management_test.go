@@ -0,0 +1,139 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snapd/cli"
+)
+
+const deviceManagementQueueJSON = `{"type": "sync", "result": {
+	"sequences": [{
+		"base-id": "seq",
+		"messages": [
+			{"id": "seq-1", "seq-num": 1, "kind": "snap-install", "receive-time": "2026-01-01T00:00:00Z", "stage": "applying", "change-id": "1", "apply-change-id": "2"},
+			{"id": "seq-2", "seq-num": 2, "kind": "snap-remove", "receive-time": "2026-01-01T00:00:00Z", "stage": "queued", "source": "local"}
+		]
+	}, {
+		"base-id": "single",
+		"messages": [
+			{"id": "single", "kind": "snap-refresh", "receive-time": "2026-01-01T00:00:00Z", "stage": "responding", "change-id": "3", "status": "rejected", "reason": "unauthorized"}
+		]
+	}, {
+		"base-id": "stuck",
+		"applied": 1,
+		"blocked": true,
+		"messages": [
+			{"id": "stuck-3", "seq-num": 3, "kind": "snap-install", "receive-time": "2026-01-01T00:00:00Z", "stage": "blocked"}
+		]
+	}],
+	"responses": [
+		{"message-id": "mesg-1", "status": "success"},
+		{"message-id": "other", "status": "error", "reason": "cannot install", "source": "local"}
+	]
+}}`
+
+func (s *SnapSuite) TestDeviceManagementList(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/device-management/messages")
+		fmt.Fprintln(w, deviceManagementQueueJSON)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"device-management", "--abs-time"})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(n, Equals, 1)
+	c.Check(s.Stdout(), Equals, `
+Sequence  Message  Kind          Received              Stage       Change  Notes
+seq       seq-1    snap-install  2026-01-01T00:00:00Z  applying    2       -
+seq       seq-2    snap-remove   2026-01-01T00:00:00Z  queued      -       local
+single    single   snap-refresh  2026-01-01T00:00:00Z  responding  3       rejected: unauthorized
+stuck     stuck-3  snap-install  2026-01-01T00:00:00Z  blocked     -       waiting for missing messages
+
+Response  Status   Destination  Reason
+mesg-1    success  store        -
+other     error    local        cannot install
+`[1:])
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestDeviceManagementListEmpty(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": {"sequences": [], "responses": []}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"device-management"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "No pending device management messages.\n")
+}
+
+func (s *SnapSuite) TestDeviceManagementDropSequence(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		case 1:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/device-management/messages")
+			c.Check(r.Header.Get("Content-Type"), Equals, "application/json")
+			var data map[string]any
+			c.Assert(json.NewDecoder(r.Body).Decode(&data), IsNil)
+			c.Check(data, DeepEquals, map[string]any{
+				"action":  "drop-sequence",
+				"base-id": "stuck",
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "status-code": 202, "change": "42"}`)
+		case 2:
+			c.Check(r.URL.Path, Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected request %d", n)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"device-management", "--drop-sequence", "stuck"})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+	c.Check(s.Stdout(), Equals, "Dropped sequence \"stuck\"\n")
+}
+
+func (s *SnapSuite) TestDeviceManagementDropSequenceError(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "cannot drop sequence \"unknown\": no pending messages for sequence"}}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"device-management", "--drop-sequence", "unknown"})
+	c.Assert(err, ErrorMatches, `cannot drop sequence "unknown": no pending messages for sequence`)
+}
+
+func (s *SnapSuite) TestDeviceManagementExtraArgs(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"device-management", "extra"})
+	c.Assert(err, Equals, snap.ErrExtraArgs)
+}
@@ -221,6 +221,9 @@ var helpCategories = []helpCategory{
 		Label:       i18n.G("Permissions"),
 		Description: i18n.G("manage permissions"),
 		Commands:    []string{"connections", "interface", "connect", "disconnect"},
+		// TODO: promote to Commands once AppArmor prompting is no longer
+		// behind a feature flag
+		AllOnlyCommands: []string{"prompting-rules"},
 	}, {
 		Label:       i18n.G("Configuration"),
 		Description: i18n.G("system administration and configuration"),
@@ -246,6 +249,9 @@ var helpCategories = []helpCategory{
 		Label:       i18n.G("Device"),
 		Description: i18n.G("manage device"),
 		Commands:    []string{"model", "remodel", "reboot", "recovery"},
+		// TODO: promote to Commands once remote device management and
+		// clustering are no longer behind feature flags
+		AllOnlyCommands: []string{"device-management", "import-mgmt-messages", "export-mgmt-responses", "cluster"},
 	}, {
 		Label:       i18n.G("Warnings"),
 		Other:       true,
@@ -270,7 +276,7 @@ var helpCategories = []helpCategory{
 	}, {
 		Label:       i18n.G("Quota Groups"),
 		Description: i18n.G("Manage quota groups for snaps"),
-		Commands:    []string{"set-quota", "remove-quota", "quotas", "quota"},
+		Commands:    []string{"set-quota", "remove-quota", "thaw-quota", "quotas", "quota"},
 	}, {
 		Label:       i18n.G("Validation Sets"),
 		Description: i18n.G("Manage validation sets"),
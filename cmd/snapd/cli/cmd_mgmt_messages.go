@@ -0,0 +1,225 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/osutil"
+)
+
+var (
+	shortImportMgmtMessagesHelp = i18n.G("Import device management messages")
+	longImportMgmtMessagesHelp  = i18n.G(`
+The import-mgmt-messages command imports signed request-message assertions
+for remote device management from a file or from the *.assert files of a
+directory, e.g. on removable media, for devices that cannot exchange messages
+with the store.
+
+The assertions needed to verify the messages, like account-key assertions,
+can be provided alongside them. Response-message assertions found in the
+directory are ignored. The responses to the imported messages are kept until
+exported with export-mgmt-responses.
+`)
+
+	shortExportMgmtResponsesHelp = i18n.G("Export device management responses")
+	longExportMgmtResponsesHelp  = i18n.G(`
+The export-mgmt-responses command writes the response-message assertions to
+the imported request messages processed so far into the given directory, one
+file per message. Exported responses are not exported again.
+`)
+)
+
+type cmdImportMgmtMessages struct {
+	waitMixin
+	Positional struct {
+		Path flags.Filename
+	} `positional-args:"true" required:"true"`
+}
+
+type cmdExportMgmtResponses struct {
+	clientMixin
+	Positional struct {
+		Dir flags.Filename
+	} `positional-args:"true" required:"true"`
+}
+
+func init() {
+	addCommand("import-mgmt-messages", shortImportMgmtMessagesHelp, longImportMgmtMessagesHelp,
+		func() flags.Commander { return &cmdImportMgmtMessages{} },
+		waitDescs, []argDesc{{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<file or directory>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Assertion file or directory with assertion files"),
+		}})
+	addCommand("export-mgmt-responses", shortExportMgmtResponsesHelp, longExportMgmtResponsesHelp,
+		func() flags.Commander { return &cmdExportMgmtResponses{} },
+		nil, []argDesc{{
+			// TRANSLATORS: This needs to begin with < and end with >
+			name: i18n.G("<directory>"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			desc: i18n.G("Directory to write the responses to"),
+		}})
+}
+
+// mgmtMessageFiles returns the assertion files to import from path, either
+// path itself or the *.assert files in it if it is a directory.
+func mgmtMessageFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.assert"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readMgmtMessages reads the assertions to import from files into a single
+// stream, returning it along with the number of request messages in it.
+func readMgmtMessages(files []string) (stream *bytes.Buffer, count int, err error) {
+	stream = &bytes.Buffer{}
+	enc := asserts.NewEncoder(stream)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, 0, err
+		}
+		dec := asserts.NewDecoder(f)
+		for {
+			a, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, 0, fmt.Errorf("cannot decode assertions in %q: %v", file, err)
+			}
+			switch a.Type() {
+			case asserts.ResponseMessageType:
+				// likely exported earlier into the same directory
+				continue
+			case asserts.RequestMessageType:
+				count++
+			}
+			if err := enc.Encode(a); err != nil {
+				f.Close()
+				return nil, 0, err
+			}
+		}
+		f.Close()
+	}
+	return stream, count, nil
+}
+
+func (x *cmdImportMgmtMessages) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	path := string(x.Positional.Path)
+	files, err := mgmtMessageFiles(path)
+	if err != nil {
+		return err
+	}
+	stream, count, err := readMgmtMessages(files)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(i18n.G("cannot find request messages to import in %q"), path)
+	}
+
+	changeID, err := x.client.ImportDeviceManagementMessages(stream)
+	if err != nil {
+		return err
+	}
+
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.NG("Imported %d request message\n", "Imported %d request messages\n", count), count)
+	return nil
+}
+
+// mgmtResponseFilename returns the name of the file holding the response to
+// the given request message.
+func mgmtResponseFilename(messageID string) string {
+	return fmt.Sprintf("response-%s.assert", strings.ReplaceAll(messageID, "/", "_"))
+}
+
+func (x *cmdExportMgmtResponses) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	dir := string(x.Positional.Dir)
+	if !osutil.IsDirectory(dir) {
+		return fmt.Errorf(i18n.G("cannot export responses: %q is not a directory"), dir)
+	}
+
+	responses, err := x.client.DeviceManagementResponses()
+	if err != nil {
+		return err
+	}
+	if len(responses) == 0 {
+		fmt.Fprintln(Stdout, i18n.G("No responses to export."))
+		return nil
+	}
+
+	messageIDs := make([]string, 0, len(responses))
+	for _, res := range responses {
+		messageID := res.HeaderString("message-id")
+		target := filepath.Join(dir, mgmtResponseFilename(messageID))
+		if err := osutil.AtomicWriteFile(target, asserts.Encode(res), 0644, 0); err != nil {
+			return fmt.Errorf(i18n.G("cannot export response to message %q: %v"), messageID, err)
+		}
+		messageIDs = append(messageIDs, messageID)
+	}
+
+	// only forget about the responses once they are safely written
+	if err := x.client.AcknowledgeDeviceManagementResponses(messageIDs); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.NG("Exported %d response to %s\n", "Exported %d responses to %s\n", len(responses)), len(responses), dir)
+	return nil
+}
@@ -0,0 +1,238 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	snap "github.com/snapcore/snapd/cmd/snapd/cli"
+)
+
+type mgmtMessagesSuite struct {
+	BaseSnapSuite
+
+	storeSigning *assertstest.StoreStack
+	devKey       asserts.PrivateKey
+}
+
+var _ = Suite(&mgmtMessagesSuite{})
+
+func (s *mgmtMessagesSuite) SetUpTest(c *C) {
+	s.BaseSnapSuite.SetUpTest(c)
+	s.storeSigning = assertstest.NewStoreStack("my-brand", nil)
+	s.devKey, _ = assertstest.GenerateKey(752)
+}
+
+func (s *mgmtMessagesSuite) requestMessage(c *C, messageID string) asserts.Assertion {
+	now := time.Now()
+	a, err := s.storeSigning.Sign(asserts.RequestMessageType, map[string]any{
+		"authority-id": "my-brand",
+		"account-id":   "my-brand",
+		"message-id":   messageID,
+		"message-kind": "snap-install",
+		"devices":      []any{"serial-1.my-model.my-brand"},
+		"valid-since":  now.UTC().Format(time.RFC3339),
+		"valid-until":  now.Add(time.Hour).UTC().Format(time.RFC3339),
+		"timestamp":    now.UTC().Format(time.RFC3339),
+	}, []byte(`{"snap": "foo"}`), "")
+	c.Assert(err, IsNil)
+	return a
+}
+
+func (s *mgmtMessagesSuite) responseMessage(c *C, messageID string) asserts.Assertion {
+	a, err := asserts.SignWithoutAuthority(asserts.ResponseMessageType, map[string]any{
+		"account-id": "my-brand",
+		"message-id": messageID,
+		"device":     "serial-1.my-model.my-brand",
+		"status":     "success",
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	}, nil, s.devKey)
+	c.Assert(err, IsNil)
+	return a
+}
+
+func (s *mgmtMessagesSuite) writeAssertions(c *C, path string, as ...asserts.Assertion) {
+	f, err := os.Create(path)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	enc := asserts.NewEncoder(f)
+	for _, a := range as {
+		c.Assert(enc.Encode(a), IsNil)
+	}
+}
+
+func (s *mgmtMessagesSuite) TestImportFromDirectory(c *C) {
+	dir := c.MkDir()
+	s.writeAssertions(c, filepath.Join(dir, "01.assert"), s.requestMessage(c, "mesg-1"), s.storeSigning.StoreAccountKey(""))
+	s.writeAssertions(c, filepath.Join(dir, "02.assert"), s.requestMessage(c, "mesg-2"))
+	// exported earlier, skipped
+	s.writeAssertions(c, filepath.Join(dir, "response-other.assert"), s.responseMessage(c, "other"))
+	// not an assertion file
+	c.Assert(os.WriteFile(filepath.Join(dir, "README"), []byte("hello"), 0644), IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		case 1:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/device-management/messages")
+			c.Check(r.Header.Get("Content-Type"), Equals, asserts.MediaType)
+
+			var types []string
+			dec := asserts.NewDecoder(r.Body)
+			for {
+				a, err := dec.Decode()
+				if err == io.EOF {
+					break
+				}
+				c.Assert(err, IsNil)
+				types = append(types, a.Type().Name)
+			}
+			c.Check(types, DeepEquals, []string{"request-message", "account-key", "request-message"})
+
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "status-code": 202, "change": "42"}`)
+		case 2:
+			c.Check(r.URL.Path, Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected request %d", n)
+		}
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"import-mgmt-messages", dir})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(n, Equals, 2)
+	c.Check(s.Stdout(), Equals, "Imported 2 request messages\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *mgmtMessagesSuite) TestImportFromFileNoWait(c *C) {
+	path := filepath.Join(c.MkDir(), "messages")
+	s.writeAssertions(c, path, s.requestMessage(c, "mesg-1"))
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.URL.Path, Equals, "/v2/device-management/messages")
+		w.WriteHeader(202)
+		fmt.Fprintln(w, `{"type": "async", "status-code": 202, "change": "42"}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"import-mgmt-messages", "--no-wait", path})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 1)
+	c.Check(s.Stdout(), Equals, "42\n")
+}
+
+func (s *mgmtMessagesSuite) TestImportErrors(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	dir := c.MkDir()
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"import-mgmt-messages", dir})
+	c.Check(err, ErrorMatches, fmt.Sprintf(`cannot find request messages to import in %q`, dir))
+
+	bad := filepath.Join(dir, "bad.assert")
+	c.Assert(os.WriteFile(bad, []byte("garbage"), 0644), IsNil)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"import-mgmt-messages", dir})
+	c.Check(err, ErrorMatches, fmt.Sprintf(`cannot decode assertions in %q: .*`, bad))
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"import-mgmt-messages", filepath.Join(dir, "missing")})
+	c.Check(err, ErrorMatches, `stat .*/missing: no such file or directory`)
+}
+
+func (s *mgmtMessagesSuite) TestExport(c *C) {
+	dir := c.MkDir()
+	responses := []asserts.Assertion{s.responseMessage(c, "mesg-1"), s.responseMessage(c, "other")}
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		case 1:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/device-management/responses")
+			w.Header().Set("Content-Type", asserts.MediaType)
+			w.Header().Set("X-Ubuntu-Assertions-Count", "2")
+			enc := asserts.NewEncoder(w)
+			for _, a := range responses {
+				c.Assert(enc.Encode(a), IsNil)
+			}
+		case 2:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/device-management/responses")
+			var data map[string]any
+			c.Assert(json.NewDecoder(r.Body).Decode(&data), IsNil)
+			c.Check(data, DeepEquals, map[string]any{
+				"action":      "acknowledge",
+				"message-ids": []any{"mesg-1", "other"},
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+		default:
+			c.Fatalf("unexpected request %d", n)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"export-mgmt-responses", dir})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+	c.Check(s.Stdout(), Equals, fmt.Sprintf("Exported 2 responses to %s\n", dir))
+
+	for _, a := range responses {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("response-%s.assert", a.HeaderString("message-id"))))
+		c.Assert(err, IsNil)
+		c.Check(string(data), Equals, string(asserts.Encode(a)))
+	}
+}
+
+func (s *mgmtMessagesSuite) TestExportNothing(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		w.Header().Set("X-Ubuntu-Assertions-Count", "0")
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"export-mgmt-responses", c.MkDir()})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "No responses to export.\n")
+}
+
+func (s *mgmtMessagesSuite) TestExportNotADirectory(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	path := filepath.Join(c.MkDir(), "missing")
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"export-mgmt-responses", path})
+	c.Check(err, ErrorMatches, fmt.Sprintf(`cannot export responses: %q is not a directory`, path))
+}
@@ -0,0 +1,191 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var (
+	shortPromptingRulesHelp = i18n.G("Export or import prompting rules")
+	longPromptingRulesHelp  = i18n.G(`
+The prompting-rules command contains sub-commands to move the prompting rules
+of the current user from one machine to another.
+`)
+
+	shortPromptingRulesExportHelp = i18n.G("Export the prompting rules of the current user")
+	longPromptingRulesExportHelp  = i18n.G(`
+The prompting-rules export command writes the prompting rules of the current
+user to the given file, or to standard output, in a format which can be
+imported on another machine.
+
+Permissions which only last for the current session are not exported, and
+permissions which expire after a timespan are exported with the time they
+have left. Rules set by the administrator are not exported. Path patterns
+within the home directory of the user are exported relative to $HOME.
+`)
+
+	shortPromptingRulesImportHelp = i18n.G("Import prompting rules for the current user")
+	longPromptingRulesImportHelp  = i18n.G(`
+The prompting-rules import command adds the prompting rules from the given
+file, or from standard input, to the prompting rules of the current user.
+$HOME in the path patterns of the rules stands for the home directory of the
+current user.
+
+Imported rules with the same path pattern as an existing rule are merged into
+it. If any imported rule is invalid or conflicts with an existing rule or with
+another imported rule, no rule is imported.
+`)
+)
+
+type cmdPromptingRules struct {
+	clientMixin
+	Export cmdPromptingRulesExport `command:"export"`
+	Import cmdPromptingRulesImport `command:"import"`
+}
+
+type cmdPromptingRulesExport struct {
+	clientMixin
+	Snap       string `long:"snap"`
+	Interface  string `long:"interface"`
+	Positional struct {
+		File flags.Filename `positional-arg-name:"<file>"`
+	} `positional-args:"yes"`
+}
+
+type cmdPromptingRulesImport struct {
+	clientMixin
+	Positional struct {
+		File flags.Filename `positional-arg-name:"<file>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// promptingRulesFile is the format of the files to which prompting rules are
+// exported, and from which they are imported.
+type promptingRulesFile struct {
+	Rules []client.PromptingRule `json:"rules"`
+}
+
+func init() {
+	cmd := addCommand("prompting-rules", shortPromptingRulesHelp, longPromptingRulesHelp,
+		func() flags.Commander { return &cmdPromptingRules{} }, nil, nil)
+	cmd.extra = func(c *flags.Command) {
+		export := c.Find("export")
+		export.ShortDescription = shortPromptingRulesExportHelp
+		export.LongDescription = longPromptingRulesExportHelp
+		// TRANSLATORS: This should not start with a lowercase letter.
+		export.FindOptionByLongName("snap").Description = i18n.G("Only export the rules for the given snap")
+		// TRANSLATORS: This should not start with a lowercase letter.
+		export.FindOptionByLongName("interface").Description = i18n.G("Only export the rules for the given interface")
+		// TRANSLATORS: This should not start with a lowercase letter.
+		export.Args()[0].Description = i18n.G("File to write the rules to (defaults to stdout)")
+
+		imp := c.Find("import")
+		imp.ShortDescription = shortPromptingRulesImportHelp
+		imp.LongDescription = longPromptingRulesImportHelp
+		// TRANSLATORS: This should not start with a lowercase letter.
+		imp.Args()[0].Description = i18n.G("File to read the rules from, or - for stdin")
+	}
+}
+
+// setClient passes the client on to the sub-commands, which are not
+// registered on their own.
+func (x *cmdPromptingRules) setClient(cli *client.Client) {
+	x.clientMixin.setClient(cli)
+	x.Export.setClient(cli)
+	x.Import.setClient(cli)
+}
+
+func (x *cmdPromptingRules) Execute(args []string) error {
+	return flag.ErrHelp
+}
+
+func (x *cmdPromptingRulesExport) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	rules, err := x.client.ExportPromptingRules(x.Snap, x.Interface)
+	if err != nil {
+		return err
+	}
+	if rules == nil {
+		rules = []client.PromptingRule{}
+	}
+	data, err := json.MarshalIndent(promptingRulesFile{Rules: rules}, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if x.Positional.File == "" || x.Positional.File == "-" {
+		_, err = Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(string(x.Positional.File), data, 0600); err != nil {
+		return fmt.Errorf(i18n.G("cannot write prompting rules: %v"), err)
+	}
+	fmt.Fprintf(Stdout, i18n.NG("Exported %d rule to %s\n", "Exported %d rules to %s\n", len(rules)), len(rules), x.Positional.File)
+	return nil
+}
+
+func (x *cmdPromptingRulesImport) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if x.Positional.File == "-" {
+		data, err = io.ReadAll(Stdin)
+	} else {
+		data, err = os.ReadFile(string(x.Positional.File))
+	}
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot read prompting rules: %v"), err)
+	}
+
+	var file promptingRulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf(i18n.G("cannot decode prompting rules: %v"), err)
+	}
+	if len(file.Rules) == 0 {
+		return errors.New(i18n.G("no prompting rules to import"))
+	}
+
+	ids, err := x.client.ImportPromptingRules(file.Rules)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(Stdout, i18n.NG("Imported %d rule\n", "Imported %d rules\n", len(ids)), len(ids))
+	return nil
+}
@@ -0,0 +1,163 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cli_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snapd/cli"
+)
+
+const promptingRulesExportResponse = `{"type": "sync", "result": [
+	{"snap": "firefox", "interface": "home", "constraints": {"path-pattern": "/home/test/Downloads/**", "permissions": {"read": {"outcome": "allow", "lifespan": "forever"}}}}
+]}`
+
+const promptingRulesFile = `{
+  "rules": [
+    {
+      "snap": "firefox",
+      "interface": "home",
+      "constraints": {
+        "path-pattern": "/home/test/Downloads/**",
+        "permissions": {
+          "read": {
+            "outcome": "allow",
+            "lifespan": "forever"
+          }
+        }
+      }
+    }
+  ]
+}
+`
+
+func (s *SnapSuite) TestPromptingRulesExport(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v2/interfaces/requests/rules")
+		var body map[string]any
+		c.Assert(json.NewDecoder(r.Body).Decode(&body), IsNil)
+		c.Check(body, DeepEquals, map[string]any{
+			"action":   "export",
+			"selector": map[string]any{"snap": "firefox", "interface": "home"},
+		})
+		fmt.Fprintln(w, promptingRulesExportResponse)
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "export", "--snap", "firefox", "--interface", "home"})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(n, Equals, 1)
+	c.Check(s.Stdout(), Equals, promptingRulesFile)
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestPromptingRulesExportToFile(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		c.Assert(json.NewDecoder(r.Body).Decode(&body), IsNil)
+		c.Check(body, DeepEquals, map[string]any{"action": "export"})
+		fmt.Fprintln(w, promptingRulesExportResponse)
+	})
+
+	path := filepath.Join(c.MkDir(), "rules.json")
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "export", path})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, fmt.Sprintf("Exported 1 rule to %s\n", path))
+	data, err := os.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, promptingRulesFile)
+}
+
+func (s *SnapSuite) TestPromptingRulesExportNone(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "export"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "{\n  \"rules\": []\n}\n")
+}
+
+func (s *SnapSuite) TestPromptingRulesImport(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v2/interfaces/requests/rules")
+		data, err := io.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		var body map[string]any
+		c.Assert(json.Unmarshal(data, &body), IsNil)
+		c.Check(body["action"], Equals, "import")
+		var expected map[string]any
+		c.Assert(json.Unmarshal([]byte(promptingRulesFile), &expected), IsNil)
+		c.Check(body["rules"], DeepEquals, expected["rules"])
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"id": "0000000000000002", "snap": "firefox", "interface": "home"}]}`)
+	})
+
+	path := filepath.Join(c.MkDir(), "rules.json")
+	c.Assert(os.WriteFile(path, []byte(promptingRulesFile), 0644), IsNil)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "import", path})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 1)
+	c.Check(s.Stdout(), Equals, "Imported 1 rule\n")
+
+	// Rules can be read from stdin too
+	s.ResetStdStreams()
+	s.stdin.WriteString(promptingRulesFile)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "import", "-"})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+	c.Check(s.Stdout(), Equals, "Imported 1 rule\n")
+}
+
+func (s *SnapSuite) TestPromptingRulesImportErrors(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(409)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 409, "result": {"message": "cannot import rule 0: a rule conflicts with the given rule", "kind": "interfaces-requests-rule-conflict"}}`)
+	})
+
+	dir := c.MkDir()
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "import", filepath.Join(dir, "missing.json")})
+	c.Check(err, ErrorMatches, "cannot read prompting rules: .*no such file or directory")
+
+	path := filepath.Join(dir, "rules.json")
+	c.Assert(os.WriteFile(path, []byte("not json"), 0644), IsNil)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "import", path})
+	c.Check(err, ErrorMatches, "cannot decode prompting rules: .*")
+
+	c.Assert(os.WriteFile(path, []byte(`{"rules":[]}`), 0644), IsNil)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "import", path})
+	c.Check(err, ErrorMatches, "no prompting rules to import")
+
+	c.Assert(os.WriteFile(path, []byte(promptingRulesFile), 0644), IsNil)
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"prompting-rules", "import", path})
+	c.Check(err, ErrorMatches, "cannot import prompting rules: cannot import rule 0: a rule conflicts with the given rule")
+}
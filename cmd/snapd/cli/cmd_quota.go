@@ -40,6 +40,11 @@ var longQuotaHelp = i18n.G(`
 The quota command shows information about a quota group, including the set of 
 snaps and any sub-groups it contains, as well as its resource constraints and 
 the current usage of those constrained resources.
+
+With --history, the quota command instead shows the memory, CPU and thread 
+usage of the quota group as sampled periodically by snapd. The CPU usage is the 
+CPU time used since the previous sample, as a percentage of a single CPU. The 
+history is kept for a day and does not survive a reboot.
 `)
 
 var shortQuotasHelp = i18n.G("Show quota groups")
@@ -56,6 +61,14 @@ remove a quota group with sub-groups, the sub-groups must first be removed until
 there are no sub-groups for the group, then the group itself can be removed.
 `)
 
+var shortThawQuotaHelp = i18n.G("Thaw quota group")
+var longThawQuotaHelp = i18n.G(`
+The thaw-quota command resumes the processes of the given quota group after
+the group was frozen by its "freeze" soft limits action. The group is otherwise
+left unchanged, and is frozen again if it stays over its soft limits for the
+soft limits window.
+`)
+
 var shortSetQuotaHelp = i18n.G(`Create or update a quota group.`)
 var longSetQuotaHelp = i18n.G(`
 The set-quota command updates or creates a quota group with the specified set of
@@ -103,6 +116,27 @@ The journal limits can be increased and decreased after being set on a group.
 Setting a journal limit will cause the snaps in the group to be put into the same
 journal namespace. This will affect the behaviour of the log command.
 
+The IO read and write bandwidth limits apply to the block device backing the
+snap data directories, and can be increased and decreased after being set on
+a group. The IO weight is the share of the IO bandwidth the group gets when
+competing with other groups, from 1 to 10000 with a default of 100. IO quotas
+require cgroup v2.
+
+The network egress bandwidth limit caps the rate at which the services in a
+group can send data, packets exceeding it are dropped. It can be increased and
+decreased after being set on a group. Network quotas require cgroup v2 and are
+experimental.
+
+Soft limits are thresholds on the memory, CPU and threads used by a group which,
+unlike the other quotas, are not enforced by the kernel. When the group has
+been over any of its soft limits for the soft limits window, which is at least
+5 minutes, snapd takes the soft limits action: "notify" only records a notice,
+"restart" restarts the services in the group and "freeze" freezes all the
+processes in the group. A frozen group stays frozen until it is thawed with
+thaw-quota, updated with set-quota or removed. The soft CPU limit is a percentage of a single CPU,
+sampled every 5 minutes. Soft limits must be below the respective quotas, the
+freeze action requires cgroup v2, and soft limits are experimental.
+
 New quotas can be set on existing quota groups, but existing quotas cannot be removed
 from a quota group, without removing and recreating the entire group.
 
@@ -116,30 +150,53 @@ func init() {
 	addCommand("set-quota", shortSetQuotaHelp, longSetQuotaHelp,
 		func() flags.Commander { return &cmdSetQuota{} },
 		waitDescs.also(map[string]string{
-			"memory":             i18n.G("Memory quota as <number><unit> (e.g. 64MB, 1GB)"),
-			"cpu":                i18n.G("CPU quota as <percentage>% or <count>x<percentage>% (e.g. 50%, 2x100%)"),
-			"cpu-set":            i18n.G("CPU set quota as comma-separated list of CPU core indices (e.g. 0,1,3)"),
-			"threads":            i18n.G("Threads quota as a positive integer (e.g. 512)"),
-			"journal-size":       i18n.G("Journal size quota as <number><unit> (e.g. 16MB)"),
-			"journal-rate-limit": i18n.G("Journal rate limit as <message count>/<message period> (e.g. 100/1s, 1000/1m)"),
-			"parent":             i18n.G("Parent quota group"),
+			"memory":                   i18n.G("Memory quota as <number><unit> (e.g. 64MB, 1GB)"),
+			"cpu":                      i18n.G("CPU quota as <percentage>% or <count>x<percentage>% (e.g. 50%, 2x100%)"),
+			"cpu-set":                  i18n.G("CPU set quota as comma-separated list of CPU core indices (e.g. 0,1,3)"),
+			"threads":                  i18n.G("Threads quota as a positive integer (e.g. 512)"),
+			"journal-size":             i18n.G("Journal size quota as <number><unit> (e.g. 16MB)"),
+			"journal-rate-limit":       i18n.G("Journal rate limit as <message count>/<message period> (e.g. 100/1s, 1000/1m)"),
+			"io-read-bandwidth":        i18n.G("IO read bandwidth quota as <number><unit>/s (e.g. 10MB/s)"),
+			"io-write-bandwidth":       i18n.G("IO write bandwidth quota as <number><unit>/s (e.g. 10MB/s)"),
+			"io-weight":                i18n.G("IO weight as an integer between 1 and 10000 (e.g. 200)"),
+			"network-egress-bandwidth": i18n.G("Network egress bandwidth quota as <number><unit>/s (e.g. 1MB/s)"),
+			"soft-memory":              i18n.G("Soft memory limit as <number><unit> (e.g. 48MB)"),
+			"soft-cpu":                 i18n.G("Soft CPU limit as <percentage>% of a single CPU (e.g. 80%, 150%)"),
+			"soft-threads":             i18n.G("Soft threads limit as a positive integer (e.g. 256)"),
+			"soft-window":              i18n.G("How long the group must be over its soft limits before acting, as a duration (e.g. 15m)"),
+			"soft-action":              i18n.G("Action taken when the group is over its soft limits for the window: notify, restart or freeze (default: notify)"),
+			"parent":                   i18n.G("Parent quota group"),
+		}), nil)
+	addCommand("quota", shortQuotaHelp, longQuotaHelp, func() flags.Commander { return &cmdQuota{} },
+		timeDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"history": i18n.G("Show the history of the resource usage of the quota group"),
 		}), nil)
-	addCommand("quota", shortQuotaHelp, longQuotaHelp, func() flags.Commander { return &cmdQuota{} }, nil, nil)
 	addCommand("quotas", shortQuotasHelp, longQuotasHelp, func() flags.Commander { return &cmdQuotas{} }, nil, nil)
 	addCommand("remove-quota", shortRemoveQuotaHelp, longRemoveQuotaHelp, func() flags.Commander { return &cmdRemoveQuota{} }, nil, nil)
+	addCommand("thaw-quota", shortThawQuotaHelp, longThawQuotaHelp, func() flags.Commander { return &cmdThawQuota{} }, nil, nil)
 }
 
 type cmdSetQuota struct {
 	waitMixin
 
-	MemoryMax        string `long:"memory" optional:"true"`
-	CPUMax           string `long:"cpu" optional:"true"`
-	CPUSet           string `long:"cpu-set" optional:"true"`
-	ThreadsMax       string `long:"threads" optional:"true"`
-	JournalSizeMax   string `long:"journal-size" optional:"true"`
-	JournalRateLimit string `long:"journal-rate-limit" optional:"true"`
-	Parent           string `long:"parent" optional:"true"`
-	Positional       struct {
+	MemoryMax              string `long:"memory" optional:"true"`
+	CPUMax                 string `long:"cpu" optional:"true"`
+	CPUSet                 string `long:"cpu-set" optional:"true"`
+	ThreadsMax             string `long:"threads" optional:"true"`
+	JournalSizeMax         string `long:"journal-size" optional:"true"`
+	JournalRateLimit       string `long:"journal-rate-limit" optional:"true"`
+	IOReadBandwidth        string `long:"io-read-bandwidth" optional:"true"`
+	IOWriteBandwidth       string `long:"io-write-bandwidth" optional:"true"`
+	IOWeight               string `long:"io-weight" optional:"true"`
+	NetworkEgressBandwidth string `long:"network-egress-bandwidth" optional:"true"`
+	SoftMemory             string `long:"soft-memory" optional:"true"`
+	SoftCPU                string `long:"soft-cpu" optional:"true"`
+	SoftThreads            string `long:"soft-threads" optional:"true"`
+	SoftWindow             string `long:"soft-window" optional:"true"`
+	SoftAction             string `long:"soft-action" optional:"true"`
+	Parent                 string `long:"parent" optional:"true"`
+	Positional             struct {
 		GroupName string        `positional-arg-name:"<group-name>" required:"true"`
 		Snaps     []serviceName `positional-arg-name:"<snap-or-service>" optional:"true"`
 	} `positional-args:"yes"`
@@ -194,6 +251,19 @@ func parseJournalRateQuota(journalRateLimit string) (count int, period time.Dura
 	return count, period, nil
 }
 
+// parseBandwidthQuota parses a bandwidth in bytes per second given as a
+// size, optionally followed by /s.
+func parseBandwidthQuota(bandwidth string) (quantity.Size, error) {
+	value, err := strutil.ParseByteSize(strings.TrimSuffix(bandwidth, "/s"))
+	if err != nil {
+		return 0, err
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("bandwidth must be positive")
+	}
+	return quantity.Size(value), nil
+}
+
 func (x *cmdSetQuota) parseQuotas() (*client.QuotaValues, error) {
 	var quotaValues client.QuotaValues
 
@@ -266,12 +336,101 @@ func (x *cmdSetQuota) parseQuotas() (*client.QuotaValues, error) {
 		}
 	}
 
+	if x.IOReadBandwidth != "" || x.IOWriteBandwidth != "" || x.IOWeight != "" {
+		quotaValues.IO = &client.QuotaIOValues{}
+		if x.IOReadBandwidth != "" {
+			value, err := parseBandwidthQuota(x.IOReadBandwidth)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse io read bandwidth %q: %v", x.IOReadBandwidth, err)
+			}
+			quotaValues.IO.ReadBandwidth = value
+		}
+		if x.IOWriteBandwidth != "" {
+			value, err := parseBandwidthQuota(x.IOWriteBandwidth)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse io write bandwidth %q: %v", x.IOWriteBandwidth, err)
+			}
+			quotaValues.IO.WriteBandwidth = value
+		}
+		if x.IOWeight != "" {
+			value, err := strconv.ParseUint(x.IOWeight, 10, 32)
+			if err != nil || value == 0 {
+				return nil, fmt.Errorf("cannot use io weight value %q", x.IOWeight)
+			}
+			quotaValues.IO.Weight = int(value)
+		}
+	}
+
+	if x.NetworkEgressBandwidth != "" {
+		value, err := parseBandwidthQuota(x.NetworkEgressBandwidth)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse network egress bandwidth %q: %v", x.NetworkEgressBandwidth, err)
+		}
+		quotaValues.Network = &client.QuotaNetworkValues{
+			EgressBandwidth: value,
+		}
+	}
+
+	if x.hasSoftLimitsSet() {
+		softLimits, err := x.parseSoftLimits()
+		if err != nil {
+			return nil, err
+		}
+		quotaValues.SoftLimits = softLimits
+	}
+
 	return &quotaValues, nil
 }
 
+func (x *cmdSetQuota) parseSoftLimits() (*client.QuotaSoftLimitsValues, error) {
+	var softLimits client.QuotaSoftLimitsValues
+
+	if x.SoftMemory != "" {
+		value, err := strutil.ParseByteSize(x.SoftMemory)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse soft memory limit %q: %v", x.SoftMemory, err)
+		}
+		softLimits.Memory = quantity.Size(value)
+	}
+
+	if x.SoftCPU != "" {
+		value, err := strconv.ParseUint(strings.TrimSuffix(x.SoftCPU, "%"), 10, 32)
+		if err != nil || value == 0 {
+			return nil, fmt.Errorf("cannot use soft cpu limit value %q", x.SoftCPU)
+		}
+		softLimits.CPUPercentage = int(value)
+	}
+
+	if x.SoftThreads != "" {
+		value, err := strconv.ParseUint(x.SoftThreads, 10, 32)
+		if err != nil || value == 0 {
+			return nil, fmt.Errorf("cannot use soft threads limit value %q", x.SoftThreads)
+		}
+		softLimits.Threads = int(value)
+	}
+
+	if x.SoftWindow != "" {
+		value, err := time.ParseDuration(x.SoftWindow)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse soft limits window %q: %v", x.SoftWindow, err)
+		}
+		softLimits.Window = value
+	}
+
+	softLimits.Action = x.SoftAction
+	return &softLimits, nil
+}
+
+func (x *cmdSetQuota) hasSoftLimitsSet() bool {
+	return x.SoftMemory != "" || x.SoftCPU != "" || x.SoftThreads != "" ||
+		x.SoftWindow != "" || x.SoftAction != ""
+}
+
 func (x *cmdSetQuota) hasQuotaSet() bool {
 	return x.MemoryMax != "" || x.CPUMax != "" || x.CPUSet != "" ||
-		x.ThreadsMax != "" || x.JournalSizeMax != "" || x.JournalRateLimit != ""
+		x.ThreadsMax != "" || x.JournalSizeMax != "" || x.JournalRateLimit != "" ||
+		x.IOReadBandwidth != "" || x.IOWriteBandwidth != "" || x.IOWeight != "" ||
+		x.NetworkEgressBandwidth != "" || x.hasSoftLimitsSet()
 }
 
 func (x *cmdSetQuota) splitSnapsAndServices() (snaps []string, services []string) {
@@ -380,7 +539,9 @@ func (x *cmdSetQuota) Execute(args []string) (err error) {
 
 type cmdQuota struct {
 	clientMixin
+	timeMixin
 
+	History    bool `long:"history"`
 	Positional struct {
 		GroupName string `positional-arg-name:"<group-name>" required:"true"`
 	} `positional-args:"yes"`
@@ -391,6 +552,10 @@ func (x *cmdQuota) Execute(args []string) (err error) {
 		return fmt.Errorf("too many arguments provided")
 	}
 
+	if x.History {
+		return x.showHistory()
+	}
+
 	group, err := x.client.GetQuotaGroup(x.Positional.GroupName)
 	if err != nil {
 		return err
@@ -438,12 +603,43 @@ func (x *cmdQuota) Execute(args []string) (err error) {
 				group.Constraints.Journal.RatePeriod)
 		}
 	}
+	if group.Constraints.IO != nil {
+		if group.Constraints.IO.ReadBandwidth != 0 {
+			fmt.Fprintf(w, "  io-read-bandwidth:\t%s\n", fmtBandwidth(group.Constraints.IO.ReadBandwidth))
+		}
+		if group.Constraints.IO.WriteBandwidth != 0 {
+			fmt.Fprintf(w, "  io-write-bandwidth:\t%s\n", fmtBandwidth(group.Constraints.IO.WriteBandwidth))
+		}
+		if group.Constraints.IO.Weight != 0 {
+			fmt.Fprintf(w, "  io-weight:\t%d\n", group.Constraints.IO.Weight)
+		}
+	}
+	if group.Constraints.Network != nil {
+		fmt.Fprintf(w, "  network-egress-bandwidth:\t%s\n", fmtBandwidth(group.Constraints.Network.EgressBandwidth))
+	}
+	if soft := group.Constraints.SoftLimits; soft != nil {
+		if soft.Memory != 0 {
+			fmt.Fprintf(w, "  soft-memory:\t%s\n", strings.TrimSpace(fmtSize(int64(soft.Memory))))
+		}
+		if soft.CPUPercentage != 0 {
+			fmt.Fprintf(w, "  soft-cpu:\t%d%%\n", soft.CPUPercentage)
+		}
+		if soft.Threads != 0 {
+			fmt.Fprintf(w, "  soft-threads:\t%d\n", soft.Threads)
+		}
+		fmt.Fprintf(w, "  soft-window:\t%s\n", soft.Window)
+		fmt.Fprintf(w, "  soft-action:\t%s\n", soft.Action)
+	}
 
 	memoryUsage := "0B"
 	currentThreads := 0
+	networkEgressUsage := "0B"
 	if group.Current != nil {
 		memoryUsage = strings.TrimSpace(fmtSize(int64(group.Current.Memory)))
 		currentThreads = group.Current.Threads
+		if group.Current.Network != nil {
+			networkEgressUsage = strings.TrimSpace(fmtSize(int64(group.Current.Network.EgressBytes)))
+		}
 	}
 
 	fmt.Fprintf(w, "current:\n")
@@ -453,6 +649,9 @@ func (x *cmdQuota) Execute(args []string) (err error) {
 	if group.Constraints.Threads != 0 {
 		fmt.Fprintf(w, "  threads:\t%d\n", currentThreads)
 	}
+	if group.Constraints.Network != nil {
+		fmt.Fprintf(w, "  network-egress-bytes:\t%s\n", networkEgressUsage)
+	}
 
 	if len(group.Subgroups) > 0 {
 		fmt.Fprint(w, "subgroups:\n")
@@ -476,6 +675,31 @@ func (x *cmdQuota) Execute(args []string) (err error) {
 	return nil
 }
 
+func (x *cmdQuota) showHistory() error {
+	samples, err := x.client.GetQuotaGroupUsage(x.Positional.GroupName, time.Time{})
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		fmt.Fprintf(Stderr, i18n.G("No usage history for quota group %q yet.\n"), x.Positional.GroupName)
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Time\tMemory\tCPU\tThreads"))
+	for _, sample := range samples {
+		fmt.Fprintf(w, "%s\t%s\t%d%%\t%d\n", x.fmtTime(sample.Time),
+			fmtSize(int64(sample.Memory)), sample.CPU, sample.Threads)
+	}
+	return nil
+}
+
+func fmtBandwidth(bandwidth quantity.Size) string {
+	return fmtSize(int64(bandwidth)) + "/s"
+}
+
 type cmdRemoveQuota struct {
 	waitMixin
 
@@ -500,6 +724,30 @@ func (x *cmdRemoveQuota) Execute(args []string) (err error) {
 	return nil
 }
 
+type cmdThawQuota struct {
+	waitMixin
+
+	Positional struct {
+		GroupName string `positional-arg-name:"<group-name>" required:"true"`
+	} `positional-args:"yes"`
+}
+
+func (x *cmdThawQuota) Execute(args []string) (err error) {
+	chgID, err := x.client.ThawQuotaGroup(x.Positional.GroupName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := x.wait(chgID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
 type cmdQuotas struct {
 	clientMixin
 }
@@ -560,7 +808,39 @@ func (x *cmdQuotas) Execute(args []string) (err error) {
 			}
 		}
 
-		// format current resource values as memory=N,threads=N
+		// format io constraint as io-read-bandwidth=xMB/s,io-write-bandwidth=xMB/s,io-weight=N
+		if q.Constraints.IO != nil {
+			if q.Constraints.IO.ReadBandwidth != 0 {
+				grpConstraints = append(grpConstraints, "io-read-bandwidth="+fmtBandwidth(q.Constraints.IO.ReadBandwidth))
+			}
+			if q.Constraints.IO.WriteBandwidth != 0 {
+				grpConstraints = append(grpConstraints, "io-write-bandwidth="+fmtBandwidth(q.Constraints.IO.WriteBandwidth))
+			}
+			if q.Constraints.IO.Weight != 0 {
+				grpConstraints = append(grpConstraints, "io-weight="+strconv.Itoa(q.Constraints.IO.Weight))
+			}
+		}
+
+		// format network constraint as network-egress-bandwidth=xMB/s
+		if q.Constraints.Network != nil {
+			grpConstraints = append(grpConstraints, "network-egress-bandwidth="+fmtBandwidth(q.Constraints.Network.EgressBandwidth))
+		}
+
+		// format soft limits as soft-memory=xMB,soft-cpu=N%,soft-threads=N,soft-action=action
+		if soft := q.Constraints.SoftLimits; soft != nil {
+			if soft.Memory != 0 {
+				grpConstraints = append(grpConstraints, "soft-memory="+strings.TrimSpace(fmtSize(int64(soft.Memory))))
+			}
+			if soft.CPUPercentage != 0 {
+				grpConstraints = append(grpConstraints, fmt.Sprintf("soft-cpu=%d%%", soft.CPUPercentage))
+			}
+			if soft.Threads != 0 {
+				grpConstraints = append(grpConstraints, "soft-threads="+strconv.Itoa(soft.Threads))
+			}
+			grpConstraints = append(grpConstraints, "soft-action="+soft.Action)
+		}
+
+		// format current resource values as memory=N,threads=N,network-egress-bytes=N
 		var grpCurrent []string
 		if q.Current != nil {
 			if q.Constraints.Memory != 0 && q.Current.Memory != 0 {
@@ -569,6 +849,9 @@ func (x *cmdQuotas) Execute(args []string) (err error) {
 			if q.Constraints.Threads != 0 && q.Current.Threads != 0 {
 				grpCurrent = append(grpCurrent, "threads="+fmt.Sprintf("%d", q.Current.Threads))
 			}
+			if q.Constraints.Network != nil && q.Current.Network != nil && q.Current.Network.EgressBytes != 0 {
+				grpCurrent = append(grpCurrent, "network-egress-bytes="+strings.TrimSpace(fmtSize(int64(q.Current.Network.EgressBytes))))
+			}
 		}
 
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", q.GroupName, q.Parent, strings.Join(grpConstraints, ","), strings.Join(grpCurrent, ","))
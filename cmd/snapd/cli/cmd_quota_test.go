@@ -146,8 +146,8 @@ func (s *quotaSuite) makeFakeQuotaPostHandler(c *check.C, opts fakeQuotaGroupPos
 		c.Assert(err, check.IsNil)
 
 		switch opts.action {
-		case "remove":
-			c.Check(string(buf), check.Equals, fmt.Sprintf(`{"action":"remove","group-name":%q}`+"\n", opts.groupName))
+		case "remove", "thaw":
+			c.Check(string(buf), check.Equals, fmt.Sprintf(`{"action":%q,"group-name":%q}`+"\n", opts.action, opts.groupName))
 		case "ensure":
 			exp := quotasEnsureBody{
 				Action:      "ensure",
@@ -259,6 +259,87 @@ func (s *quotaSuite) TestParseQuotas(c *check.C) {
 	}
 }
 
+func (s *quotaSuite) TestParseIOQuotas(c *check.C) {
+	for _, testData := range []struct {
+		readBandwidth  string
+		writeBandwidth string
+		weight         string
+
+		quotas string
+		err    string
+	}{
+		{readBandwidth: "10MB/s", quotas: `{"io":{"read-bandwidth":10000000}}`},
+		{readBandwidth: "10MB", quotas: `{"io":{"read-bandwidth":10000000}}`},
+		{writeBandwidth: "1GB/s", weight: "200", quotas: `{"io":{"write-bandwidth":1000000000,"weight":200}}`},
+		{weight: "10000", quotas: `{"io":{"weight":10000}}`},
+
+		// Error cases
+		{readBandwidth: "10", err: `cannot parse io read bandwidth "10": cannot parse "10": need a number with a unit as input`},
+		{writeBandwidth: "fast", err: `cannot parse io write bandwidth "fast": cannot parse "fast": no numerical prefix`},
+		{weight: "0", err: `cannot use io weight value "0"`},
+		{weight: "-1", err: `cannot use io weight value "-1"`},
+	} {
+		quotas, err := main.ParseIOQuotaValues(testData.readBandwidth, testData.writeBandwidth, testData.weight)
+		testLabel := check.Commentf("%v", testData)
+		if testData.err == "" {
+			c.Check(err, check.IsNil, testLabel)
+			var jsonQuota bytes.Buffer
+			err := json.NewEncoder(&jsonQuota).Encode(quotas)
+			c.Assert(err, check.IsNil, testLabel)
+			c.Check(strings.TrimSpace(jsonQuota.String()), check.Equals, testData.quotas, testLabel)
+		} else {
+			c.Check(err, check.ErrorMatches, testData.err, testLabel)
+		}
+	}
+}
+
+func (s *quotaSuite) TestParseNetworkQuotas(c *check.C) {
+	quotas, err := main.ParseNetworkQuotaValues("1MB/s")
+	c.Assert(err, check.IsNil)
+	var jsonQuota bytes.Buffer
+	c.Assert(json.NewEncoder(&jsonQuota).Encode(quotas), check.IsNil)
+	c.Check(strings.TrimSpace(jsonQuota.String()), check.Equals, `{"network":{"egress-bandwidth":1000000}}`)
+
+	_, err = main.ParseNetworkQuotaValues("1M")
+	c.Check(err, check.ErrorMatches, `cannot parse network egress bandwidth "1M": .*`)
+}
+
+func (s *quotaSuite) TestParseSoftLimitsQuotas(c *check.C) {
+	for _, testData := range []struct {
+		memory  string
+		cpu     string
+		threads string
+		window  string
+		action  string
+
+		quotas string
+		err    string
+	}{
+		{memory: "800MB", window: "15m", quotas: `{"soft-limits":{"memory":800000000,"window":900000000000}}`},
+		{cpu: "150%", window: "1h", action: "restart", quotas: `{"soft-limits":{"cpu-percentage":150,"window":3600000000000,"action":"restart"}}`},
+		{cpu: "80", threads: "64", action: "freeze", quotas: `{"soft-limits":{"cpu-percentage":80,"threads":64,"action":"freeze"}}`},
+
+		// Error cases
+		{memory: "800", err: `cannot parse soft memory limit "800": cannot parse "800": need a number with a unit as input`},
+		{cpu: "0%", err: `cannot use soft cpu limit value "0%"`},
+		{cpu: "2x50%", err: `cannot use soft cpu limit value "2x50%"`},
+		{threads: "-1", err: `cannot use soft threads limit value "-1"`},
+		{threads: "64", window: "soon", err: `cannot parse soft limits window "soon": time: invalid duration "soon"`},
+	} {
+		quotas, err := main.ParseSoftLimitsQuotaValues(testData.memory, testData.cpu, testData.threads, testData.window, testData.action)
+		testLabel := check.Commentf("%v", testData)
+		if testData.err == "" {
+			c.Check(err, check.IsNil, testLabel)
+			var jsonQuota bytes.Buffer
+			err := json.NewEncoder(&jsonQuota).Encode(quotas)
+			c.Assert(err, check.IsNil, testLabel)
+			c.Check(strings.TrimSpace(jsonQuota.String()), check.Equals, testData.quotas, testLabel)
+		} else {
+			c.Check(err, check.ErrorMatches, testData.err, testLabel)
+		}
+	}
+}
+
 func (s *quotaSuite) TestSetQuotaInvalidArgs(c *check.C) {
 	const json = `{
 		"type": "sync",
@@ -278,6 +359,8 @@ func (s *quotaSuite) TestSetQuotaInvalidArgs(c *check.C) {
 		{[]string{"set-quota", "--cpu=0", "foo"}, `cannot parse cpu quota string "0"`},
 		// remove-quota command
 		{[]string{"remove-quota"}, "the required argument `<group-name>` was not provided"},
+		// thaw-quota command
+		{[]string{"thaw-quota"}, "the required argument `<group-name>` was not provided"},
 	} {
 		s.stdout.Reset()
 		s.stderr.Reset()
@@ -517,6 +600,142 @@ current:
 	c.Check(s.quotaGetGroupHandlerCalls, check.Equals, 1)
 }
 
+func (s *quotaSuite) TestIOQuotaGroupSimple(c *check.C) {
+	const jsonTemplate = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"group-name": "foo",
+			"constraints": {"io":{"read-bandwidth":10000000,"write-bandwidth":5000000,"weight":200}}
+		}
+	}`
+
+	s.RedirectClientToTestServer(s.makeFakeGetQuotaGroupHandler(c, jsonTemplate))
+
+	outputTemplate := `
+name:  foo
+constraints:
+  io-read-bandwidth:   10.0MB/s
+  io-write-bandwidth:  5.00MB/s
+  io-weight:           200
+current:
+`[1:]
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, outputTemplate)
+	c.Check(s.quotaGetGroupHandlerCalls, check.Equals, 1)
+}
+
+func (s *quotaSuite) TestNetworkQuotaGroupSimple(c *check.C) {
+	const jsonTemplate = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"group-name": "foo",
+			"constraints": {"network":{"egress-bandwidth":1000000}},
+			"current": {"network":{"egress-bytes":52000000}}
+		}
+	}`
+
+	s.RedirectClientToTestServer(s.makeFakeGetQuotaGroupHandler(c, jsonTemplate))
+
+	outputTemplate := `
+name:  foo
+constraints:
+  network-egress-bandwidth:  1.00MB/s
+current:
+  network-egress-bytes:  52.0MB
+`[1:]
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, outputTemplate)
+	c.Check(s.quotaGetGroupHandlerCalls, check.Equals, 1)
+}
+
+func (s *quotaSuite) TestSoftLimitsQuotaGroupSimple(c *check.C) {
+	const jsonTemplate = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {
+			"group-name": "foo",
+			"constraints": {"memory":1000000000,"soft-limits":{"memory":800000000,"cpu-percentage":150,"window":900000000000,"action":"restart"}},
+			"current": {"memory":500000000}
+		}
+	}`
+
+	s.RedirectClientToTestServer(s.makeFakeGetQuotaGroupHandler(c, jsonTemplate))
+
+	outputTemplate := `
+name:  foo
+constraints:
+  memory:       1.00GB
+  soft-memory:  800MB
+  soft-cpu:     150%
+  soft-window:  15m0s
+  soft-action:  restart
+current:
+  memory:  500MB
+`[1:]
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, outputTemplate)
+	c.Check(s.quotaGetGroupHandlerCalls, check.Equals, 1)
+}
+
+func (s *quotaSuite) TestQuotaGroupHistory(c *check.C) {
+	const json = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [
+			{"time": "2026-01-01T10:05:00Z", "memory": 1000000, "cpu": 50, "threads": 4},
+			{"time": "2026-01-01T10:10:00Z", "memory": 52000000, "cpu": 150, "threads": 12}
+		]
+	}`
+
+	calls := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		c.Check(r.URL.Path, check.Equals, "/v2/quotas/foo/usage")
+		c.Check(r.Method, check.Equals, "GET")
+		w.WriteHeader(200)
+		fmt.Fprintln(w, json)
+	})
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "--history", "--abs-time", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+Time                  Memory  CPU   Threads
+2026-01-01T10:05:00Z  1.00MB  50%   4
+2026-01-01T10:10:00Z  52.0MB  150%  12
+`[1:])
+	c.Check(calls, check.Equals, 1)
+}
+
+func (s *quotaSuite) TestQuotaGroupHistoryEmpty(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/quotas/foo/usage")
+		w.WriteHeader(200)
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "--history", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No usage history for quota group \"foo\" yet.\n")
+}
+
 func (s *quotaSuite) TestSetQuotaGroupCreateNew(c *check.C) {
 	const postJSON = `{"type": "async", "status-code": 202,"change":"42", "result": []}`
 	fakeHandlerOpts := fakeQuotaGroupPostHandlerOpts{
@@ -695,6 +914,30 @@ func (s *quotaSuite) TestRemoveQuotaGroup(c *check.C) {
 	c.Check(s.quotaPostHandlerCalls, check.Equals, 1)
 }
 
+func (s *quotaSuite) TestThawQuotaGroup(c *check.C) {
+	const json = `{"type": "async", "status-code": 202,"change": "42"}`
+	fakeHandlerOpts := fakeQuotaGroupPostHandlerOpts{
+		action:    "thaw",
+		body:      json,
+		groupName: "foo",
+	}
+
+	routes := map[string]http.HandlerFunc{
+		"/v2/quotas": s.makeFakeQuotaPostHandler(c, fakeHandlerOpts),
+
+		"/v2/changes/42": makeChangesHandler(c),
+	}
+
+	s.RedirectClientToTestServer(dispatchFakeHandlers(c, routes))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"thaw-quota", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.quotaPostHandlerCalls, check.Equals, 1)
+}
+
 func (s *quotaSuite) TestGetAllQuotaGroups(c *check.C) {
 	restore := main.MockIsStdinTTY(true)
 	defer restore()
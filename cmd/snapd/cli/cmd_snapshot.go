@@ -28,6 +28,7 @@ import (
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/strutil/quantity"
@@ -102,6 +103,10 @@ for which users, or a combination of these.
 If a snap is included in a restore operation, excluding its system and
 configuration data from the restore is not currently possible. This
 restriction may be lifted in the future.
+
+With --remote, the snapshot is first pulled back from the remote target
+configured with the snapshots.remote.* system options, <id> being the
+set id the snapshot had when it was pushed there.
 `)
 
 var longExportSnapshotHelp = i18n.G(`
@@ -122,6 +127,22 @@ type savedCmd struct {
 	} `positional-args:"yes"`
 }
 
+// readSnapshotPassphrase prompts for the passphrase of an encrypted
+// snapshot, if asked to.
+func readSnapshotPassphrase(ask bool) (string, error) {
+	if !ask {
+		return "", nil
+	}
+	fmt.Fprint(Stdout, i18n.G("Snapshot passphrase: "))
+	passphrase, err := ReadPassword(0)
+	fmt.Fprint(Stdout, "\n")
+	if err != nil {
+		return "", err
+	}
+	// strings.TrimSpace needed because we get \r from the pty in the tests
+	return strings.TrimSpace(string(passphrase)), nil
+}
+
 func (x *savedCmd) Execute([]string) error {
 	var setID uint64
 	var err error
@@ -162,6 +183,9 @@ func (x *savedCmd) Execute([]string) error {
 			if sh.Auto {
 				notes = append(notes, "auto")
 			}
+			if sh.Origin == client.SnapshotOriginScheduled {
+				notes = append(notes, "scheduled")
+			}
 			if sh.Broken != "" {
 				notes = append(notes, "broken: "+sh.Broken)
 			}
@@ -181,6 +205,7 @@ type saveCmd struct {
 	waitMixin
 	durationMixin
 	Users      string `long:"users"`
+	Passphrase bool   `long:"passphrase"`
 	Positional struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -189,7 +214,11 @@ type saveCmd struct {
 func (x *saveCmd) Execute([]string) error {
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
-	setID, changeID, err := x.client.SnapshotMany(snaps, users)
+	passphrase, err := readSnapshotPassphrase(x.Passphrase)
+	if err != nil {
+		return err
+	}
+	setID, changeID, err := x.client.SnapshotMany(snaps, users, passphrase)
 	if err != nil {
 		return err
 	}
@@ -246,6 +275,7 @@ func (x *forgetCmd) Execute([]string) error {
 type checkSnapshotCmd struct {
 	waitMixin
 	Users      string `long:"users"`
+	Passphrase bool   `long:"passphrase"`
 	Positional struct {
 		ID    snapshotID          `positional-arg-name:"<id>"`
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
@@ -259,7 +289,11 @@ func (x *checkSnapshotCmd) Execute([]string) error {
 	}
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
-	changeID, err := x.client.CheckSnapshots(setID, snaps, users)
+	passphrase, err := readSnapshotPassphrase(x.Passphrase)
+	if err != nil {
+		return err
+	}
+	changeID, err := x.client.CheckSnapshots(setID, snaps, users, passphrase)
 	if err != nil {
 		return err
 	}
@@ -285,6 +319,8 @@ func (x *checkSnapshotCmd) Execute([]string) error {
 type restoreCmd struct {
 	waitMixin
 	Users      string `long:"users"`
+	Passphrase bool   `long:"passphrase"`
+	Remote     bool   `long:"remote"`
 	Positional struct {
 		ID    snapshotID          `positional-arg-name:"<id>"`
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
@@ -298,7 +334,20 @@ func (x *restoreCmd) Execute([]string) error {
 	}
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
-	changeID, err := x.client.RestoreSnapshots(setID, snaps, users)
+	passphrase, err := readSnapshotPassphrase(x.Passphrase)
+	if err != nil {
+		return err
+	}
+	if x.Remote {
+		importSet, err := x.client.PullSnapshot(setID, passphrase)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(Stdout, i18n.G("Pulled snapshot #%s from the remote target as snapshot #%d.\n"),
+			x.Positional.ID, importSet.ID)
+		setID = importSet.ID
+	}
+	changeID, err := x.client.RestoreSnapshots(setID, snaps, users, passphrase)
 	if err != nil {
 		return err
 	}
@@ -313,10 +362,10 @@ func (x *restoreCmd) Execute([]string) error {
 	// TODO: also mention the home archives that were actually restored
 	if len(snaps) > 0 {
 		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
-		fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%s of snaps %s.\n"),
-			x.Positional.ID, strutil.Quoted(snaps))
+		fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%d of snaps %s.\n"),
+			setID, strutil.Quoted(snaps))
 	} else {
-		fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%s.\n"), x.Positional.ID)
+		fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%d.\n"), setID)
 	}
 	return nil
 }
@@ -342,6 +391,8 @@ func init() {
 		}, durationDescs.also(waitDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Snapshot data of only specific users (comma-separated) (default: all users)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"passphrase": i18n.G("Prompt for the passphrase to encrypt the snapshot with"),
 		}), nil)
 
 	addCommand("restore",
@@ -352,6 +403,10 @@ func init() {
 		}, waitDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Restore data of only specific users (comma-separated) (default: all users)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"passphrase": i18n.G("Prompt for the passphrase the snapshot is encrypted with"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"remote": i18n.G("Pull the snapshot from the remote snapshot target first"),
 		}), []argDesc{
 			{
 				name: "<id>",
@@ -389,6 +444,8 @@ func init() {
 		}, waitDescs.also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Check data of only specific users (comma-separated) (default: all users)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"passphrase": i18n.G("Prompt for the passphrase the snapshot is encrypted with"),
 		}), []argDesc{
 			{
 				name: "<id>",
@@ -425,7 +482,10 @@ func init() {
 		longImportSnapshotHelp,
 		func() flags.Commander {
 			return &importSnapshotCmd{}
-		}, nil, []argDesc{
+		}, durationDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"passphrase": i18n.G("Prompt for the passphrase the snapshot is encrypted with"),
+		}), []argDesc{
 			{
 				name: "<filename>",
 				// TRANSLATORS: This should not start with a lowercase letter.
@@ -490,6 +550,7 @@ func (x *exportSnapshotCmd) Execute([]string) (err error) {
 type importSnapshotCmd struct {
 	clientMixin
 	durationMixin
+	Passphrase bool `long:"passphrase"`
 	Positional struct {
 		Filename string `long:"filename"`
 	} `positional-args:"yes" required:"yes"`
@@ -507,7 +568,11 @@ func (x *importSnapshotCmd) Execute([]string) error {
 		return fmt.Errorf("cannot stat file: %v", err)
 	}
 
-	importSet, err := x.client.SnapshotImport(f, st.Size())
+	passphrase, err := readSnapshotPassphrase(x.Passphrase)
+	if err != nil {
+		return err
+	}
+	importSet, err := x.client.SnapshotImport(f, st.Size(), passphrase)
 	if err != nil {
 		return err
 	}
@@ -20,6 +20,7 @@
 package cli_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -44,6 +45,9 @@ var snapshotsTests = []getCmdArgs{{
 }, {
 	args:   "saved --id=3",
 	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n3    htop  .*  2        1168      1B  auto\n",
+}, {
+	args:   "saved --id=5",
+	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n5    htop  .*  2        1168      1B  scheduled\n",
 }, {
 	args:   "saved",
 	stdout: "Set  Snap  Age    Version  Rev   Size    Notes\n1    htop  .*  2        1168      1B  -\n",
@@ -120,6 +124,10 @@ func (s *SnapSuite) mockSnapshotsServer(c *C) {
 			if r.Method == "GET" {
 				// simulate a 1-month old snapshot
 				snapshotTime := time.Now().AddDate(0, -1, 0).Format(time.RFC3339)
+				if r.URL.Query().Get("set") == "5" {
+					fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":5,"snapshots":[{"set":5,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","origin":"scheduled","epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1}]}]}`, snapshotTime)
+					return
+				}
 				if r.URL.Query().Get("set") == "3" {
 					fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":3,"snapshots":[{"set":3,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","auto":true,"epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1}]}]}`, snapshotTime)
 					return
@@ -167,3 +175,91 @@ Set  Snap  Age    Version  Rev   Size    Notes
 1    htop  %-6s 2        1168      1B  -
 `, ageStr))
 }
+
+func (s *SnapSuite) TestSnapshotRestorePassphrase(c *C) {
+	s.password = "sekrit"
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snapshots":
+			c.Check(r.Method, Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]any{
+				"action":     "restore",
+				"set":        json.Number("1"),
+				"passphrase": "sekrit",
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "9"}`)
+		case "/v2/changes/9":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {}}}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"restore", "--passphrase", "1"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "Snapshot passphrase: \nRestored snapshot #1.\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestSnapshotRestoreRemote(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snapshots":
+			c.Check(r.Method, Equals, "POST")
+			n++
+			switch n {
+			case 1:
+				c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]any{
+					"action": "pull",
+					"set":    json.Number("3"),
+				})
+				fmt.Fprintln(w, `{"type": "sync", "result": {"set-id": 12, "snaps": ["htop"]}}`)
+			case 2:
+				c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]any{
+					"action": "restore",
+					"set":    json.Number("12"),
+				})
+				w.WriteHeader(202)
+				fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "9"}`)
+			default:
+				c.Errorf("unexpected request %d", n)
+			}
+		case "/v2/changes/9":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {}}}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"restore", "--remote", "3"})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+	c.Check(s.Stdout(), Equals, "Pulled snapshot #3 from the remote target as snapshot #12.\nRestored snapshot #12.\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestSnapshotImportPassphrase(c *C) {
+	s.password = "sekrit"
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snapshots":
+			if r.Method == "POST" {
+				c.Check(r.Header.Get(client.SnapshotPassphraseHeader), Equals, "sekrit")
+				fmt.Fprintln(w, `{"type": "sync", "result": {"set-id": 42, "snaps": ["htop"]}}`)
+				return
+			}
+			fmt.Fprintln(w, `{"type":"sync","status-code":200,"status":"OK","result":[]}`)
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	exportedSnapshotPath := filepath.Join(c.MkDir(), "mocked-snapshot.snapshot")
+	os.WriteFile(exportedSnapshotPath, []byte("this is really snapshot zip file data"), 0644)
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"import-snapshot", "--passphrase", exportedSnapshotPath})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "Snapshot passphrase: \nImported snapshot as #42\nNo snapshots found.\n")
+}
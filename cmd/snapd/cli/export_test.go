@@ -489,6 +489,36 @@ func ParseQuotaValues(maxMemory, cpuMax, cpuSet, threadsMax, journalSizeMax, jou
 	return quotas.parseQuotas()
 }
 
+func ParseIOQuotaValues(readBandwidth, writeBandwidth, weight string) (*client.QuotaValues, error) {
+	var quotas cmdSetQuota
+
+	quotas.IOReadBandwidth = readBandwidth
+	quotas.IOWriteBandwidth = writeBandwidth
+	quotas.IOWeight = weight
+
+	return quotas.parseQuotas()
+}
+
+func ParseNetworkQuotaValues(egressBandwidth string) (*client.QuotaValues, error) {
+	var quotas cmdSetQuota
+
+	quotas.NetworkEgressBandwidth = egressBandwidth
+
+	return quotas.parseQuotas()
+}
+
+func ParseSoftLimitsQuotaValues(memory, cpu, threads, window, action string) (*client.QuotaValues, error) {
+	var quotas cmdSetQuota
+
+	quotas.SoftMemory = memory
+	quotas.SoftCPU = cpu
+	quotas.SoftThreads = threads
+	quotas.SoftWindow = window
+	quotas.SoftAction = action
+
+	return quotas.parseQuotas()
+}
+
 func MockSeedWriterReadManifest(f func(manifestFile string) (*seedwriter.Manifest, error)) (restore func()) {
 	restore = testutil.Backup(&seedwriterReadManifest)
 	seedwriterReadManifest = f
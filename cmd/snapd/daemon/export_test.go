@@ -62,3 +62,11 @@ func MockNewSlogLogger(f func(io.Writer, string, seclog.Level) seclog.SecurityLo
 		newSlogLogger = oldNewSlogLogger
 	}
 }
+
+func MockNewSyslogLogger(f func(seclog.SyslogConfig, string, seclog.Level) (seclog.SecurityLogger, error)) (restore func()) {
+	oldNewSyslogLogger := newSyslogLogger
+	newSyslogLogger = f
+	return func() {
+		newSyslogLogger = oldNewSyslogLogger
+	}
+}
@@ -21,14 +21,17 @@ package daemon
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
@@ -46,6 +49,7 @@ var (
 	syscheckCheckSystem = syscheck.CheckSystem
 	openAuditWriter     = seclog.OpenAuditWriter
 	newSlogLogger       = seclog.NewSlogLogger
+	newSyslogLogger     = seclog.NewSyslogLogger
 )
 
 const (
@@ -102,18 +106,63 @@ func Main() {
 }
 
 func setupSecurityLogging() (teardown func()) {
+	forwarding := setupSecurityLogForwarding()
+
+	var chainWriter *seclog.HashChainWriter
 	auditWriter, err := openAuditWriter()
 	if err != nil {
 		logger.Noticef("cannot set up security logger: %v", err)
-		return func() {}
+		if !forwarding {
+			return func() {}
+		}
+	} else {
+		// chain the records written to the audit log so that they cannot
+		// be altered unnoticed
+		chainPath := filepath.Join(dirs.SnapSeclogDir, seclog.HashChainFile)
+		auditLogPath := filepath.Join(dirs.GlobalRootDir, seclog.AuditLogFile)
+		chainWriter = seclog.NewHashChainWriter(auditWriter, chainPath, auditLogPath)
+		sl := newSlogLogger(chainWriter, secLogAppID, secLogMinLevel)
+		seclog.Setup(sl)
 	}
-	sl := newSlogLogger(auditWriter, secLogAppID, secLogMinLevel)
-	seclog.Setup(sl)
 	seclog.LogLoggerEnabled()
 	return func() {
 		seclog.LogLoggerDisabled()
-		auditWriter.Close()
+		if forwarding {
+			seclog.SetupRemote(nil)
+		}
+		if chainWriter != nil {
+			// also closes the audit writer
+			chainWriter.Close()
+		}
+	}
+}
+
+// setupSecurityLogForwarding forwards security events to the syslog
+// collector configured with the security-log.syslog.* system options, if
+// any, and returns whether forwarding was set up.
+func setupSecurityLogForwarding() bool {
+	configPath := filepath.Join(dirs.SnapSeclogDir, seclog.SyslogConfigFile)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Noticef("cannot read security log forwarding configuration: %v", err)
+		}
+		return false
+	}
+	var config seclog.SyslogConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		logger.Noticef("cannot decode security log forwarding configuration: %v", err)
+		return false
+	}
+	config.BufferPath = filepath.Join(dirs.SnapSeclogDir, seclog.SyslogBufferFile)
+
+	l, err := newSyslogLogger(config, secLogAppID, secLogMinLevel)
+	if err != nil {
+		logger.Noticef("cannot set up security log forwarding: %v", err)
+		return false
 	}
+	seclog.SetupRemote(l)
+	return true
 }
 
 func runWatchdog(d *daemon.Daemon) (*time.Ticker, error) {
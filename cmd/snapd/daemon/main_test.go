@@ -20,7 +20,9 @@
 package daemon_test
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -36,6 +38,8 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/standby"
+	"github.com/snapcore/snapd/seclog"
+	"github.com/snapcore/snapd/seclog/seclogtest"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -133,3 +137,103 @@ func (s *snapdSuite) TestSyscheckFailGoesIntoDegradedMode(c *C) {
 	close(ch)
 	wg.Wait()
 }
+
+func (s *snapdSuite) TestSetupSecurityLogForwarding(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+	restore = snapd.MockOpenAuditWriter(func() (*seclog.AuditWriter, error) {
+		return nil, fmt.Errorf("no audit")
+	})
+	defer restore()
+
+	c.Assert(os.MkdirAll(dirs.SnapSeclogDir, 0700), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dirs.SnapSeclogDir, "syslog.json"),
+		[]byte(`{"network":"tls","address":"logs.example.com:6514","buffer-size":4000000}`), 0600), IsNil)
+
+	remoteBuf := &bytes.Buffer{}
+	restore = snapd.MockNewSyslogLogger(func(config seclog.SyslogConfig, appID string, minLevel seclog.Level) (seclog.SecurityLogger, error) {
+		c.Check(config, DeepEquals, seclog.SyslogConfig{
+			Network:    "tls",
+			Address:    "logs.example.com:6514",
+			BufferSize: 4000000,
+			BufferPath: filepath.Join(dirs.SnapSeclogDir, "syslog-buffer"),
+		})
+		c.Check(appID, Equals, "canonical.snapd.snapd")
+		c.Check(minLevel, Equals, seclog.LevelInfo)
+		return seclogtest.MockSecurityLogger(remoteBuf), nil
+	})
+	defer restore()
+
+	teardown := snapd.SetupSecurityLogging()
+	c.Check(logbuf.String(), testutil.Contains, "cannot set up security logger: no audit")
+	c.Check(remoteBuf.String(), testutil.Contains, "sys_logging_enabled")
+
+	teardown()
+	c.Check(remoteBuf.String(), testutil.Contains, "sys_logging_disabled")
+
+	// events are no longer forwarded
+	remoteBuf.Reset()
+	seclog.LogLoginSuccess(seclog.SnapdUser{ID: 1})
+	c.Check(remoteBuf.Len(), Equals, 0)
+}
+
+func (s *snapdSuite) TestSetupSecurityLoggingHashChain(c *C) {
+	restore := snapd.MockOpenAuditWriter(func() (*seclog.AuditWriter, error) {
+		return &seclog.AuditWriter{}, nil
+	})
+	defer restore()
+
+	buf := &bytes.Buffer{}
+	restore = snapd.MockNewSlogLogger(func(w io.Writer, appID string, minLevel seclog.Level) seclog.SecurityLogger {
+		c.Check(w, FitsTypeOf, &seclog.HashChainWriter{})
+		c.Check(appID, Equals, "canonical.snapd.snapd")
+		c.Check(minLevel, Equals, seclog.LevelInfo)
+		return seclogtest.MockSecurityLogger(buf)
+	})
+	defer restore()
+
+	teardown := snapd.SetupSecurityLogging()
+	defer seclog.Setup(seclog.NewNopLogger())
+	c.Check(buf.String(), testutil.Contains, "sys_logging_enabled")
+	teardown()
+	c.Check(buf.String(), testutil.Contains, "sys_logging_disabled")
+}
+
+func (s *snapdSuite) TestSetupSecurityLogForwardingNotConfigured(c *C) {
+	restore := snapd.MockOpenAuditWriter(func() (*seclog.AuditWriter, error) {
+		return nil, fmt.Errorf("no audit")
+	})
+	defer restore()
+	restore = snapd.MockNewSyslogLogger(func(seclog.SyslogConfig, string, seclog.Level) (seclog.SecurityLogger, error) {
+		c.Fatal("unexpected call")
+		return nil, nil
+	})
+	defer restore()
+
+	snapd.SetupSecurityLogging()()
+}
+
+func (s *snapdSuite) TestSetupSecurityLogForwardingError(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+	restore = snapd.MockOpenAuditWriter(func() (*seclog.AuditWriter, error) {
+		return nil, fmt.Errorf("no audit")
+	})
+	defer restore()
+
+	c.Assert(os.MkdirAll(dirs.SnapSeclogDir, 0700), IsNil)
+	configPath := filepath.Join(dirs.SnapSeclogDir, "syslog.json")
+	c.Assert(os.WriteFile(configPath, []byte(`{"network":"udp"}`), 0600), IsNil)
+
+	restore = snapd.MockNewSyslogLogger(func(seclog.SyslogConfig, string, seclog.Level) (seclog.SecurityLogger, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer restore()
+
+	snapd.SetupSecurityLogging()()
+	c.Check(logbuf.String(), testutil.Contains, "cannot set up security log forwarding: boom")
+
+	c.Assert(os.WriteFile(configPath, []byte(`{`), 0600), IsNil)
+	snapd.SetupSecurityLogging()()
+	c.Check(logbuf.String(), testutil.Contains, "cannot decode security log forwarding configuration: unexpected end of JSON input")
+}
@@ -84,6 +84,7 @@ var api = []*Command{
 	systemRecoveryKeysCmd,
 	quotaGroupsCmd,
 	quotaGroupInfoCmd,
+	quotaGroupUsageCmd,
 	confdbCmd,
 	confdbControlCmd,
 	noticesCmd,
@@ -93,8 +94,14 @@ var api = []*Command{
 	requestsPromptCmd,
 	requestsRulesCmd,
 	requestsRuleCmd,
+	requestsHistoryCmd,
+	requestsCheckCmd,
 	systemSecurebootCmd,
 	systemVolumesCmd,
+	deviceMgmtMessagesCmd,
+	deviceMgmtResponsesCmd,
+	clusterCmd,
+	clusterStatusCmd,
 }
 
 type featureEndpoint struct {
@@ -0,0 +1,113 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/snapcore/snapd/cluster/assemblestate"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/clusterstate"
+)
+
+var (
+	clusterCmd = &Command{
+		Path:        "/v2/cluster",
+		POST:        postCluster,
+		Actions:     []string{"update-membership"},
+		WriteAccess: rootAccess{},
+	}
+
+	clusterStatusCmd = &Command{
+		Path:       "/v2/cluster/status",
+		GET:        getClusterStatus,
+		ReadAccess: openAccess{},
+	}
+)
+
+var (
+	clusterstateStatus           = clusterstate.Status
+	clusterstateUpdateMembership = clusterstate.UpdateMembership
+)
+
+type clusterAction struct {
+	Action string `json:"action"`
+	// Secret is shared by the devices taking part in the assembly session.
+	Secret string `json:"secret"`
+	// Address is the address to listen on during the session.
+	Address string `json:"address,omitempty"`
+	// Changes describes how the devices of the current cluster change.
+	Changes assemblestate.MembershipChanges `json:"changes"`
+	// ExpectedSize is the size of the cluster that this device joins.
+	ExpectedSize int `json:"expected-size,omitempty"`
+}
+
+// postCluster starts an assembly session that changes the devices of the
+// cluster. Once it is over, the headers of the next cluster assertion to sign
+// are in the "cluster-headers" entry of the data of the change.
+func postCluster(c *Command, r *http.Request, user *auth.UserState) Response {
+	var action clusterAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&action); err != nil {
+		return BadRequest("cannot decode request body into a cluster action: %v", err)
+	}
+
+	if action.Action != "update-membership" {
+		return BadRequest("unsupported cluster action: %q", action.Action)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chg, err := clusterstateUpdateMembership(st, clusterstate.MembershipOptions{
+		Secret:       action.Secret,
+		Address:      action.Address,
+		Changes:      action.Changes,
+		ExpectedSize: action.ExpectedSize,
+	})
+	if err != nil {
+		return BadRequest("cannot update cluster membership: %v", err)
+	}
+
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, chg.ID())
+}
+
+// getClusterStatus returns how far the devices of each subcluster are in
+// applying the current cluster assertion.
+func getClusterStatus(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	status, err := clusterstateStatus(st)
+	if err != nil {
+		if errors.Is(err, clusterstate.ErrNoClusterAssertion) {
+			return NotFound("this device is not part of a cluster")
+		}
+		return InternalError("cannot get cluster status: %v", err)
+	}
+
+	return SyncResponse(status)
+}
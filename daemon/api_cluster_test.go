@@ -0,0 +1,188 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/cluster/assemblestate"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/clusterstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var _ = Suite(&clusterSuite{})
+
+type clusterSuite struct {
+	apiBaseSuite
+}
+
+func (s *clusterSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectOpenAccess()
+	s.expectWriteAccess(daemon.RootAccess{})
+
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {})
+	s.AddCleanup(restore)
+}
+
+func (s *clusterSuite) TestGetStatus(c *C) {
+	d := s.daemon(c)
+
+	status := &clusterstate.ClusterStatus{
+		ClusterID: "cluster-id",
+		Sequence:  2,
+		Subclusters: []clusterstate.SubclusterStatus{{
+			Name: "default",
+			Devices: []clusterstate.DeviceStatus{
+				{ID: 1, Device: "serial-1.model.brand", Status: "converged", Sequence: 2, Local: true},
+				{ID: 2, Device: "serial-2.model.brand", Status: "failed", Sequence: 1, Reason: "boom"},
+			},
+		}},
+	}
+	restore := daemon.MockClusterstateStatus(func(st *state.State) (*clusterstate.ClusterStatus, error) {
+		c.Check(st, Equals, d.Overlord().State())
+		return status, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/cluster/status", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, status)
+}
+
+func (s *clusterSuite) TestGetStatusErrors(c *C) {
+	s.daemon(c)
+
+	for _, tc := range []struct {
+		err     error
+		status  int
+		message string
+	}{{
+		err:     fmt.Errorf("cannot get cluster assertion: %w", clusterstate.ErrNoClusterAssertion),
+		status:  404,
+		message: "this device is not part of a cluster",
+	}, {
+		err:     errors.New("boom"),
+		status:  500,
+		message: "cannot get cluster status: boom",
+	}} {
+		restore := daemon.MockClusterstateStatus(func(st *state.State) (*clusterstate.ClusterStatus, error) {
+			return nil, tc.err
+		})
+
+		req, err := http.NewRequest("GET", "/v2/cluster/status", nil)
+		c.Assert(err, IsNil)
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, Equals, tc.status)
+		c.Check(rspe.Message, Equals, tc.message)
+
+		restore()
+	}
+}
+
+func (s *clusterSuite) TestUpdateMembership(c *C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+
+	var opts clusterstate.MembershipOptions
+	restore := daemon.MockClusterstateUpdateMembership(func(st *state.State, o clusterstate.MembershipOptions) (*state.Change, error) {
+		opts = o
+		return st.NewChange("update-cluster-membership", "..."), nil
+	})
+	defer restore()
+
+	body := `{"action": "update-membership", "secret": "secret", "address": ":8001", "changes": {"replace": {"2": "serial-3.model.brand"}, "add": 1}}`
+	req, err := http.NewRequest("POST", "/v2/cluster", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, NotNil)
+	c.Check(chg.Kind(), Equals, "update-cluster-membership")
+	c.Check(opts, DeepEquals, clusterstate.MembershipOptions{
+		Secret:  "secret",
+		Address: ":8001",
+		Changes: assemblestate.MembershipChanges{
+			Replace: map[int]string{2: "serial-3.model.brand"},
+			Add:     1,
+		},
+	})
+}
+
+func (s *clusterSuite) TestUpdateMembershipJoin(c *C) {
+	s.daemon(c)
+
+	var opts clusterstate.MembershipOptions
+	restore := daemon.MockClusterstateUpdateMembership(func(st *state.State, o clusterstate.MembershipOptions) (*state.Change, error) {
+		opts = o
+		return st.NewChange("update-cluster-membership", "..."), nil
+	})
+	defer restore()
+
+	body := `{"action": "update-membership", "secret": "secret", "expected-size": 3}`
+	req, err := http.NewRequest("POST", "/v2/cluster", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	s.asyncReq(c, req, nil, actionIsExpected)
+	c.Check(opts, DeepEquals, clusterstate.MembershipOptions{Secret: "secret", ExpectedSize: 3})
+}
+
+func (s *clusterSuite) TestUpdateMembershipErrors(c *C) {
+	s.daemon(c)
+
+	restore := daemon.MockClusterstateUpdateMembership(func(st *state.State, o clusterstate.MembershipOptions) (*state.Change, error) {
+		return nil, errors.New("cluster membership update already in progress")
+	})
+	defer restore()
+
+	for _, tc := range []struct {
+		body    string
+		action  actionExpectedBool
+		message string
+	}{{
+		body:    `{`,
+		action:  actionIsUnexpected,
+		message: "cannot decode request body into a cluster action: unexpected EOF",
+	}, {
+		body:    `{"action": "explode"}`,
+		action:  actionIsUnexpected,
+		message: `unsupported cluster action: "explode"`,
+	}, {
+		body:    `{"action": "update-membership", "secret": "secret"}`,
+		action:  actionIsExpected,
+		message: "cannot update cluster membership: cluster membership update already in progress",
+	}} {
+		req, err := http.NewRequest("POST", "/v2/cluster", bytes.NewBufferString(tc.body))
+		c.Assert(err, IsNil)
+		rspe := s.errorReq(c, req, nil, tc.action)
+		c.Check(rspe.Status, Equals, 400)
+		c.Check(rspe.Message, Equals, tc.message)
+	}
+}
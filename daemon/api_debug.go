@@ -21,12 +21,16 @@ package daemon
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sort"
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/osutil/disks"
 	"github.com/snapcore/snapd/overlord/assertstate"
@@ -35,6 +39,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/seclog"
 	"github.com/snapcore/snapd/timings"
 )
 
@@ -382,6 +387,19 @@ func getFeatures(c *Command) Response {
 	return SyncResponse(resp)
 }
 
+// getSecurityLogHead returns the head of the hash chain of the security log,
+// for the records at the end of the log to be checked against it.
+func getSecurityLogHead() Response {
+	head, err := seclog.ReadHashChainHead(filepath.Join(dirs.SnapSeclogDir, seclog.HashChainFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return SyncResponse(nil)
+	}
+	if err != nil {
+		return InternalError("cannot get the head of the security log: %v", err)
+	}
+	return SyncResponse(head)
+}
+
 func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
 	aspect := query.Get("aspect")
@@ -418,6 +436,8 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return getRAAInfo(st)
 	case "features":
 		return getFeatures(c)
+	case "security-log-head":
+		return getSecurityLogHead()
 	default:
 		return BadRequest("unknown debug aspect %q", aspect)
 	}
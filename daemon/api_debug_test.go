@@ -25,13 +25,17 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/seclog"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timings"
@@ -409,3 +413,23 @@ func (s *postDebugSuite) TestRefreshAppAwarenessUnhappy(c *check.C) {
 	c.Check(rsp.Status, check.Equals, 500)
 	c.Check(rsp.Message, check.Equals, "boom!")
 }
+
+func (s *postDebugSuite) TestSecurityLogHead(c *check.C) {
+	s.daemonWithOverlordMock()
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=security-log-head", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Result, check.IsNil)
+
+	c.Assert(os.MkdirAll(dirs.SnapSeclogDir, 0700), check.IsNil)
+	statePath := filepath.Join(dirs.SnapSeclogDir, seclog.HashChainFile)
+	c.Assert(os.WriteFile(statePath, []byte(`{"seq":42,"hash":"abcd"}`), 0600), check.IsNil)
+	rsp = s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Result, check.DeepEquals, &seclog.HashChainHead{Seq: 42, Hash: "abcd"})
+
+	c.Assert(os.WriteFile(statePath, []byte(`garbage`), 0600), check.IsNil)
+	errRsp := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(errRsp.Status, check.Equals, 500)
+	c.Check(errRsp.Message, check.Matches, "cannot get the head of the security log: cannot decode .*")
+}
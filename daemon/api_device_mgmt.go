@@ -0,0 +1,195 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicemgmtstate"
+)
+
+var (
+	deviceMgmtMessagesCmd = &Command{
+		Path:        "/v2/device-management/messages",
+		GET:         getDeviceMgmtMessages,
+		POST:        postDeviceMgmtMessages,
+		Actions:     []string{"drop-sequence"},
+		ReadAccess:  rootAccess{},
+		WriteAccess: rootAccess{},
+	}
+
+	deviceMgmtResponsesCmd = &Command{
+		Path:        "/v2/device-management/responses",
+		GET:         getDeviceMgmtResponses,
+		POST:        postDeviceMgmtResponses,
+		Actions:     []string{"acknowledge"},
+		ReadAccess:  rootAccess{},
+		WriteAccess: rootAccess{},
+	}
+)
+
+var (
+	deviceMgmtMgrQueueInfo            = (*devicemgmtstate.DeviceMgmtManager).QueueInfo
+	deviceMgmtMgrDropSequence         = (*devicemgmtstate.DeviceMgmtManager).DropSequence
+	deviceMgmtMgrImportMessages       = (*devicemgmtstate.DeviceMgmtManager).ImportMessages
+	deviceMgmtMgrExportResponses      = (*devicemgmtstate.DeviceMgmtManager).ExportResponses
+	deviceMgmtMgrAcknowledgeResponses = (*devicemgmtstate.DeviceMgmtManager).AcknowledgeResponses
+)
+
+// getDeviceMgmtMessages returns the state of the device management message
+// queue: the pending request messages by sequence and the queued responses.
+func getDeviceMgmtMessages(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	info, err := deviceMgmtMgrQueueInfo(c.d.overlord.DeviceMgmtManager())
+	if err != nil {
+		return InternalError("cannot get device management messages: %v", err)
+	}
+
+	return SyncResponse(info)
+}
+
+// postDeviceMgmtMessages either imports a batch of request-message assertions
+// or performs an action on the pending messages, depending on the content type.
+func postDeviceMgmtMessages(c *Command, r *http.Request, user *auth.UserState) Response {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return BadRequest("unexpected content type: %q", r.Header.Get("Content-Type"))
+	}
+	switch mediaType {
+	case asserts.MediaType:
+		return importDeviceMgmtMessages(c, r)
+	case "application/json":
+		return postDeviceMgmtMessagesAction(c, r)
+	default:
+		return BadRequest("unexpected content type: %q", r.Header.Get("Content-Type"))
+	}
+}
+
+// importDeviceMgmtMessages imports a batch of request-message assertions,
+// along with the assertions needed to verify them, for devices that cannot
+// exchange messages with the store.
+func importDeviceMgmtMessages(c *Command, r *http.Request) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chgID, err := deviceMgmtMgrImportMessages(c.d.overlord.DeviceMgmtManager(), r.Body)
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+
+	return AsyncResponse(nil, chgID)
+}
+
+type postDeviceMgmtMessagesData struct {
+	Action string `json:"action"`
+	BaseID string `json:"base-id"`
+}
+
+// postDeviceMgmtMessagesAction drops the pending messages of a sequence, like
+// one stuck at missing messages.
+func postDeviceMgmtMessagesAction(c *Command, r *http.Request) Response {
+	var data postDeviceMgmtMessagesData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		return BadRequest("cannot decode request body: %v", err)
+	}
+
+	switch data.Action {
+	case "drop-sequence":
+		if data.BaseID == "" {
+			return BadRequest("no sequence to drop")
+		}
+	default:
+		return BadRequest("unknown action %q", data.Action)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chgID, err := deviceMgmtMgrDropSequence(c.d.overlord.DeviceMgmtManager(), data.BaseID)
+	if err != nil {
+		if errors.Is(err, devicemgmtstate.ErrNoSuchSequence) {
+			return NotFound("%v", err)
+		}
+		return BadRequest("%v", err)
+	}
+
+	return AsyncResponse(nil, chgID)
+}
+
+// getDeviceMgmtResponses returns the response-message assertions to the
+// imported request messages which are pending export.
+func getDeviceMgmtResponses(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	responses, err := deviceMgmtMgrExportResponses(c.d.overlord.DeviceMgmtManager())
+	st.Unlock()
+	if err != nil {
+		return InternalError("cannot export device management responses: %v", err)
+	}
+
+	assertions := make([]asserts.Assertion, 0, len(responses))
+	for _, res := range responses {
+		assertions = append(assertions, res)
+	}
+	return AssertResponse(assertions, true)
+}
+
+type postDeviceMgmtResponsesData struct {
+	Action     string   `json:"action"`
+	MessageIDs []string `json:"message-ids"`
+}
+
+// postDeviceMgmtResponses acknowledges exported response-message assertions so
+// that they are not exported again.
+func postDeviceMgmtResponses(c *Command, r *http.Request, user *auth.UserState) Response {
+	var data postDeviceMgmtResponsesData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		return BadRequest("cannot decode request body: %v", err)
+	}
+
+	switch data.Action {
+	case "acknowledge":
+		if len(data.MessageIDs) == 0 {
+			return BadRequest("no message ids to acknowledge")
+		}
+	default:
+		return BadRequest("unknown action %q", data.Action)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if err := deviceMgmtMgrAcknowledgeResponses(c.d.overlord.DeviceMgmtManager(), data.MessageIDs); err != nil {
+		return InternalError("cannot acknowledge device management responses: %v", err)
+	}
+
+	return SyncResponse(nil)
+}
@@ -0,0 +1,294 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/devicemgmtstate"
+)
+
+var _ = Suite(&deviceMgmtSuite{})
+
+type deviceMgmtSuite struct {
+	apiBaseSuite
+}
+
+func (s *deviceMgmtSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.expectRootAccess()
+}
+
+func (s *deviceMgmtSuite) makeResponseMessage(c *C, messageID string) *asserts.ResponseMessage {
+	devKey, _ := assertstest.GenerateKey(752)
+	a, err := asserts.SignWithoutAuthority(asserts.ResponseMessageType, map[string]any{
+		"account-id": "my-brand",
+		"message-id": messageID,
+		"device":     "serial-1.my-model.my-brand",
+		"status":     "success",
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	}, []byte(`{"values":"ok"}`), devKey)
+	c.Assert(err, IsNil)
+	return a.(*asserts.ResponseMessage)
+}
+
+func (s *deviceMgmtSuite) TestImportMessages(c *C) {
+	d := s.daemon(c)
+
+	var called int
+	restore := daemon.MockDeviceMgmtMgrImportMessages(func(m *devicemgmtstate.DeviceMgmtManager, r io.Reader) (string, error) {
+		called++
+		c.Check(m, Equals, d.Overlord().DeviceMgmtManager())
+		data, err := io.ReadAll(r)
+		c.Assert(err, IsNil)
+		c.Check(string(data), Equals, "assertions")
+
+		st := d.Overlord().State()
+		chg := st.NewChange("device-management-import", "...")
+		return chg.ID(), nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("POST", "/v2/device-management/messages", bytes.NewBufferString("assertions"))
+	c.Assert(err, IsNil)
+	req.Header.Set("Content-Type", asserts.MediaType)
+
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Check(called, Equals, 1)
+	c.Check(rsp.Change, Not(Equals), "")
+}
+
+func (s *deviceMgmtSuite) TestImportMessagesErrors(c *C) {
+	s.daemon(c)
+
+	restore := daemon.MockDeviceMgmtMgrImportMessages(func(m *devicemgmtstate.DeviceMgmtManager, r io.Reader) (string, error) {
+		return "", errors.New("cannot import messages: boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("POST", "/v2/device-management/messages", bytes.NewBufferString("assertions"))
+	c.Assert(err, IsNil)
+	req.Header.Set("Content-Type", "text/plain")
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Message, Equals, `unexpected content type: "text/plain"`)
+
+	req, err = http.NewRequest("POST", "/v2/device-management/messages", bytes.NewBufferString("assertions"))
+	c.Assert(err, IsNil)
+	req.Header.Set("Content-Type", asserts.MediaType)
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Message, Equals, `cannot import messages: boom`)
+}
+
+func (s *deviceMgmtSuite) TestGetMessages(c *C) {
+	s.daemon(c)
+
+	info := &devicemgmtstate.QueueInfo{
+		Sequences: []devicemgmtstate.SequenceInfo{{
+			BaseID:  "stuck",
+			Applied: 1,
+			Blocked: true,
+			Messages: []devicemgmtstate.MessageInfo{
+				{ID: "stuck-3", SeqNum: 3, AccountID: "my-brand", Kind: "snap-install", Stage: "blocked"},
+			},
+		}},
+		Responses: []devicemgmtstate.ResponseInfo{
+			{MessageID: "mesg-1", Status: asserts.MessageStatusRejected, Reason: "unauthorized"},
+		},
+	}
+	restore := daemon.MockDeviceMgmtMgrQueueInfo(func(m *devicemgmtstate.DeviceMgmtManager) (*devicemgmtstate.QueueInfo, error) {
+		return info, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/device-management/messages", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, info)
+}
+
+func (s *deviceMgmtSuite) TestGetMessagesError(c *C) {
+	s.daemon(c)
+
+	restore := daemon.MockDeviceMgmtMgrQueueInfo(func(m *devicemgmtstate.DeviceMgmtManager) (*devicemgmtstate.QueueInfo, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/device-management/messages", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 500)
+	c.Check(rspe.Message, Equals, "cannot get device management messages: boom")
+}
+
+func (s *deviceMgmtSuite) TestDropSequence(c *C) {
+	d := s.daemon(c)
+
+	var dropped string
+	restore := daemon.MockDeviceMgmtMgrDropSequence(func(m *devicemgmtstate.DeviceMgmtManager, baseID string) (string, error) {
+		dropped = baseID
+		chg := d.Overlord().State().NewChange("device-management-drop", "...")
+		return chg.ID(), nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("POST", "/v2/device-management/messages", bytes.NewBufferString(`{"action": "drop-sequence", "base-id": "stuck"}`))
+	c.Assert(err, IsNil)
+	req.Header.Set("Content-Type", "application/json")
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Change, Not(Equals), "")
+	c.Check(dropped, Equals, "stuck")
+}
+
+func (s *deviceMgmtSuite) TestDropSequenceErrors(c *C) {
+	s.daemon(c)
+
+	restore := daemon.MockDeviceMgmtMgrDropSequence(func(m *devicemgmtstate.DeviceMgmtManager, baseID string) (string, error) {
+		if baseID == "unknown" {
+			return "", fmt.Errorf("cannot drop sequence %q: %w", baseID, devicemgmtstate.ErrNoSuchSequence)
+		}
+		return "", fmt.Errorf("cannot drop sequence %q: message %q is being processed in change 1", baseID, baseID+"-1")
+	})
+	defer restore()
+
+	for _, tc := range []struct {
+		body   string
+		status int
+		errMsg string
+	}{
+		{`garbage`, 400, `cannot decode request body: .*`},
+		{`{"action": "foo"}`, 400, `unknown action "foo"`},
+		{`{"action": "drop-sequence"}`, 400, `no sequence to drop`},
+		{`{"action": "drop-sequence", "base-id": "unknown"}`, 404, `cannot drop sequence "unknown": no pending messages for sequence`},
+		{`{"action": "drop-sequence", "base-id": "busy"}`, 400, `cannot drop sequence "busy": message "busy-1" is being processed in change 1`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/device-management/messages", bytes.NewBufferString(tc.body))
+		c.Assert(err, IsNil)
+		req.Header.Set("Content-Type", "application/json")
+		rspe := s.errorReq(c, req, nil, actionIsUnexpected)
+		c.Check(rspe.Status, Equals, tc.status, Commentf(tc.body))
+		c.Check(rspe.Message, Matches, tc.errMsg, Commentf(tc.body))
+	}
+}
+
+func (s *deviceMgmtSuite) TestGetResponses(c *C) {
+	s.daemon(c)
+
+	responses := []*asserts.ResponseMessage{
+		s.makeResponseMessage(c, "mesg-1"),
+		s.makeResponseMessage(c, "other"),
+	}
+	restore := daemon.MockDeviceMgmtMgrExportResponses(func(m *devicemgmtstate.DeviceMgmtManager) ([]*asserts.ResponseMessage, error) {
+		return responses, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/device-management/responses", nil)
+	c.Assert(err, IsNil)
+	s.asRootAuth(req)
+
+	rec := httptest.NewRecorder()
+	s.serveHTTP(c, rec, req)
+	c.Check(rec.Code, Equals, 200, Commentf("body %q", rec.Body))
+	c.Check(rec.Header().Get("Content-Type"), Equals, "application/x.ubuntu.assertion; bundle=y")
+	c.Check(rec.Header().Get("X-Ubuntu-Assertions-Count"), Equals, "2")
+
+	dec := asserts.NewDecoder(rec.Body)
+	for _, expected := range responses {
+		a, err := dec.Decode()
+		c.Assert(err, IsNil)
+		c.Check(a.HeaderString("message-id"), Equals, expected.HeaderString("message-id"))
+	}
+	_, err = dec.Decode()
+	c.Check(err, Equals, io.EOF)
+}
+
+func (s *deviceMgmtSuite) TestGetResponsesError(c *C) {
+	s.daemon(c)
+
+	restore := daemon.MockDeviceMgmtMgrExportResponses(func(m *devicemgmtstate.DeviceMgmtManager) ([]*asserts.ResponseMessage, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/device-management/responses", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 500)
+	c.Check(rspe.Message, Equals, "cannot export device management responses: boom")
+}
+
+func (s *deviceMgmtSuite) TestAcknowledgeResponses(c *C) {
+	s.daemon(c)
+
+	var acked []string
+	restore := daemon.MockDeviceMgmtMgrAcknowledgeResponses(func(m *devicemgmtstate.DeviceMgmtManager, messageIDs []string) error {
+		acked = messageIDs
+		return nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("POST", "/v2/device-management/responses", bytes.NewBufferString(`{"action": "acknowledge", "message-ids": ["mesg-1", "other"]}`))
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(acked, DeepEquals, []string{"mesg-1", "other"})
+}
+
+func (s *deviceMgmtSuite) TestAcknowledgeResponsesErrors(c *C) {
+	s.daemon(c)
+
+	restore := daemon.MockDeviceMgmtMgrAcknowledgeResponses(func(m *devicemgmtstate.DeviceMgmtManager, messageIDs []string) error {
+		return errors.New("boom")
+	})
+	defer restore()
+
+	for _, tc := range []struct {
+		body   string
+		status int
+		errMsg string
+	}{
+		{`garbage`, 400, `cannot decode request body: .*`},
+		{`{"action": "foo"}`, 400, `unknown action "foo"`},
+		{`{"action": "acknowledge"}`, 400, `no message ids to acknowledge`},
+		{`{"action": "acknowledge", "message-ids": ["mesg-1"]}`, 500, `cannot acknowledge device management responses: boom`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/device-management/responses", bytes.NewBufferString(tc.body))
+		c.Assert(err, IsNil)
+		rspe := s.errorReq(c, req, nil, actionIsUnexpected)
+		c.Check(rspe.Status, Equals, tc.status, Commentf(tc.body))
+		c.Check(rspe.Message, Matches, tc.errMsg, Commentf(tc.body))
+	}
+}
@@ -246,6 +246,10 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 	}
 
 	change := newChange(st, changeKind, summary, tasksets, affected)
+	if user != nil {
+		// for the security log
+		change.Set("user-id", user.ID)
+	}
 	st.EnsureBefore(0)
 
 	return AsyncResponse(nil, change.ID())
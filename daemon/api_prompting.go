@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"strconv"
 
 	"github.com/snapcore/snapd/client"
@@ -71,7 +72,7 @@ var (
 		Path:       "/v2/interfaces/requests/rules",
 		GET:        getRules,
 		POST:       postRules,
-		Actions:    []string{"add", "remove"},
+		Actions:    []string{"add", "remove", "export", "import"},
 		ReadAccess: interfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}},
 		// postRules can only operate on rules associated with the user making
 		// the API request, so there is no need for polkit authentication.
@@ -89,6 +90,18 @@ var (
 		// authentication.
 		WriteAccess: interfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}},
 	}
+
+	requestsHistoryCmd = &Command{
+		Path:       "/v2/interfaces/requests/history",
+		GET:        getHistory,
+		ReadAccess: interfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}},
+	}
+
+	requestsCheckCmd = &Command{
+		Path:       "/v2/interfaces/requests/check",
+		GET:        getCheck,
+		ReadAccess: interfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}},
+	}
 )
 
 var (
@@ -272,6 +285,12 @@ func promptingError(err error) *apiError {
 		if errors.As(err, &parseErr) {
 			apiErr.Value = (*promptingParseError)(parseErr)
 		}
+	case errors.Is(err, prompting_errors.ErrRuleIsPolicy):
+		apiErr.Status = 403
+		apiErr.Kind = client.ErrorKindInterfacesRequestsRuleIsPolicy
+	case errors.Is(err, prompting_errors.ErrRuleShadowedByPolicy):
+		apiErr.Status = 400
+		apiErr.Kind = client.ErrorKindInterfacesRequestsRuleShadowedByPolicy
 	case errors.Is(err, prompting_errors.ErrPatchedRuleHasNoPerms):
 		apiErr.Status = 400
 		apiErr.Kind = client.ErrorKindInterfacesRequestsPatchedRuleHasNoPermissions
@@ -359,6 +378,9 @@ type postRulesRequestBody struct {
 	Action         string               `json:"action"`
 	AddRule        *addRuleContents     `json:"rule,omitempty"`
 	RemoveSelector *removeRulesSelector `json:"selector,omitempty"`
+	// ImportRules holds the rules to import, in the form in which they are
+	// exported.
+	ImportRules []*requestrules.PortableRule `json:"rules,omitempty"`
 }
 
 type postRuleRequestBody struct {
@@ -636,12 +658,45 @@ func postRules(c *Command, r *http.Request, user *auth.UserState) Response {
 			return promptingError(err)
 		}
 		return SyncResponse(removedRules)
+	case "export":
+		// The selector is optional when exporting, all the rules of the
+		// user are exported if it is omitted.
+		var snap, iface string
+		if postBody.RemoveSelector != nil {
+			snap = postBody.RemoveSelector.Snap
+			iface = postBody.RemoveSelector.Interface
+		}
+		exportedRules, err := getInterfaceManager(c).InterfacesRequestsManager().ExportRules(userID, snap, iface)
+		if err != nil {
+			return promptingError(err)
+		}
+		if len(exportedRules) == 0 {
+			exportedRules = []*requestrules.PortableRule{}
+		}
+		return SyncResponse(exportedRules)
+	case "import":
+		if len(postBody.ImportRules) == 0 {
+			return promptingError(prompting_errors.NewMissingFieldError("rules", `must include non-empty "rules" field in request body when action is "import"`))
+		}
+		for _, rule := range postBody.ImportRules {
+			if rule.Snap == "" {
+				return promptingError(prompting_errors.NewMissingFieldError("snap", `must have non-empty "snap" field`))
+			}
+			if rule.Interface == "" {
+				return promptingError(prompting_errors.NewMissingFieldError("interface", `must have non-empty "interface" field`))
+			}
+		}
+		importedRules, err := getInterfaceManager(c).InterfacesRequestsManager().ImportRules(userID, postBody.ImportRules)
+		if err != nil {
+			return promptingError(err)
+		}
+		return SyncResponse(importedRules)
 	default:
 		return promptingError(&prompting_errors.UnsupportedValueError{
 			Field:     "action",
-			Msg:       `"action" field must be "add" or "remove"`,
+			Msg:       `"action" field must be "add", "remove", "export" or "import"`,
 			Value:     []string{postBody.Action},
-			Supported: []string{"add", "remove"},
+			Supported: []string{"add", "remove", "export", "import"},
 		})
 	}
 }
@@ -721,3 +776,76 @@ func postRule(c *Command, r *http.Request, user *auth.UserState) Response {
 		})
 	}
 }
+
+func getHistory(c *Command, r *http.Request, user *auth.UserState) Response {
+	userID, errorResp := getUserID(r)
+	if errorResp != nil {
+		return errorResp
+	}
+
+	if !getInterfaceManager(c).AppArmorPromptingRunning() {
+		return promptingNotRunningError()
+	}
+
+	query := r.URL.Query()
+	since, err := parseOptionalTime(query.Get("since"))
+	if err != nil {
+		return BadRequest(`invalid "since" timestamp: %v`, err)
+	}
+	until, err := parseOptionalTime(query.Get("until"))
+	if err != nil {
+		return BadRequest(`invalid "until" timestamp: %v`, err)
+	}
+	filter := &requestprompts.HistoryFilter{
+		Snap:      query.Get("snap"),
+		Interface: query.Get("interface"),
+		Since:     since,
+		Until:     until,
+	}
+
+	history, err := getInterfaceManager(c).InterfacesRequestsManager().History(userID, filter)
+	if err != nil {
+		return promptingError(err)
+	}
+	if len(history) == 0 {
+		history = []*requestprompts.HistoryEntry{}
+	}
+
+	return SyncResponse(history)
+}
+
+// getCheck reports what the rules would decide about a hypothetical request
+// with the given snap, interface, path, and permissions, without creating a
+// prompt.
+func getCheck(c *Command, r *http.Request, user *auth.UserState) Response {
+	userID, errorResp := getUserID(r)
+	if errorResp != nil {
+		return errorResp
+	}
+
+	if !getInterfaceManager(c).AppArmorPromptingRunning() {
+		return promptingNotRunningError()
+	}
+
+	query := r.URL.Query()
+	snap := query.Get("snap")
+	if snap == "" {
+		return BadRequest(`missing "snap" parameter`)
+	}
+	iface := query.Get("interface")
+	if iface == "" {
+		return BadRequest(`missing "interface" parameter`)
+	}
+	path := query.Get("path")
+	if !filepath.IsAbs(path) {
+		return BadRequest(`"path" parameter must be an absolute path: %q`, path)
+	}
+	permissions := strutil.CommaSeparatedList(query.Get("permissions"))
+
+	check, err := getInterfaceManager(c).InterfacesRequestsManager().CheckRequest(userID, snap, iface, path, permissions)
+	if err != nil {
+		return promptingError(err)
+	}
+
+	return SyncResponse(check)
+}
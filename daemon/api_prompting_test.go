@@ -50,6 +50,9 @@ type fakeInterfacesRequestsManager struct {
 	prompt       *requestprompts.Prompt
 	rule         *requestrules.Rule
 	satisfiedIDs []prompting.IDType
+	exported     []*requestrules.PortableRule
+	history      []*requestprompts.HistoryEntry
+	check        *requestrules.RequestCheck
 	err          error
 
 	// Store most recent received values
@@ -66,6 +69,10 @@ type fakeInterfacesRequestsManager struct {
 	lifespan             prompting.LifespanType
 	duration             string
 	clientActivity       bool
+	importRules          []*requestrules.PortableRule
+	historyFilter        *requestprompts.HistoryFilter
+	path                 string
+	permissions          []string
 }
 
 func (m *fakeInterfacesRequestsManager) Ask(uid uint32, iface, snap string, pid int32, cgroup string) (prompting.OutcomeType, error) {
@@ -142,6 +149,34 @@ func (m *fakeInterfacesRequestsManager) RemoveRule(userID uint32, ruleID prompti
 	return m.rule, m.err
 }
 
+func (m *fakeInterfacesRequestsManager) ExportRules(userID uint32, snap string, iface string) ([]*requestrules.PortableRule, error) {
+	m.userID = userID
+	m.snap = snap
+	m.iface = iface
+	return m.exported, m.err
+}
+
+func (m *fakeInterfacesRequestsManager) ImportRules(userID uint32, rules []*requestrules.PortableRule) ([]*requestrules.Rule, error) {
+	m.userID = userID
+	m.importRules = rules
+	return m.rules, m.err
+}
+
+func (m *fakeInterfacesRequestsManager) History(userID uint32, filter *requestprompts.HistoryFilter) ([]*requestprompts.HistoryEntry, error) {
+	m.userID = userID
+	m.historyFilter = filter
+	return m.history, m.err
+}
+
+func (m *fakeInterfacesRequestsManager) CheckRequest(userID uint32, snap string, iface string, path string, permissions []string) (*requestrules.RequestCheck, error) {
+	m.userID = userID
+	m.snap = snap
+	m.iface = iface
+	m.path = path
+	m.permissions = permissions
+	return m.check, m.err
+}
+
 type promptingSuite struct {
 	apiBaseSuite
 
@@ -539,6 +574,30 @@ func (s *promptingSuite) TestPromptingError(c *C) {
 				"type":        "error",
 			},
 		},
+		{
+			err: prompting_errors.ErrRuleIsPolicy,
+			body: map[string]any{
+				"result": map[string]any{
+					"message": "cannot modify or remove a policy rule set by the administrator",
+					"kind":    "interfaces-requests-rule-is-policy",
+				},
+				"status":      "Forbidden",
+				"status-code": 403.0,
+				"type":        "error",
+			},
+		},
+		{
+			err: prompting_errors.ErrRuleShadowedByPolicy,
+			body: map[string]any{
+				"result": map[string]any{
+					"message": "rule has no effect, since policy rules set by the administrator take precedence over it",
+					"kind":    "interfaces-requests-rule-shadowed-by-policy",
+				},
+				"status":      "Bad Request",
+				"status-code": 400.0,
+				"type":        "error",
+			},
+		},
 		{
 			err: prompting_errors.ErrPatchedRuleHasNoPerms,
 			body: map[string]any{
@@ -1713,6 +1772,133 @@ func (s *promptingSuite) TestPostRulesRemoveHappy(c *C) {
 	}
 }
 
+func (s *promptingSuite) TestPostRulesExportHappy(c *C) {
+	s.expectWriteAccess(daemon.InterfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}})
+
+	s.daemon(c)
+
+	exported := []*requestrules.PortableRule{
+		{
+			Snap:      "thunderbird",
+			Interface: "home",
+			Constraints: prompting.ConstraintsJSON{
+				"path-pattern": json.RawMessage(`"/home/test/Mail/**"`),
+				"permissions":  json.RawMessage(`{"read":{"outcome":"allow","lifespan":"forever"}}`),
+			},
+		},
+	}
+
+	for _, testCase := range []struct {
+		body     string
+		snap     string
+		iface    string
+		exported []*requestrules.PortableRule
+	}{
+		{
+			body:     `{"action":"export"}`,
+			exported: exported,
+		},
+		{
+			body:     `{"action":"export","selector":{"snap":"thunderbird"}}`,
+			snap:     "thunderbird",
+			exported: exported,
+		},
+		{
+			body:     `{"action":"export","selector":{"snap":"thunderbird","interface":"home"}}`,
+			snap:     "thunderbird",
+			iface:    "home",
+			exported: exported,
+		},
+		{
+			body:     `{"action":"export","selector":{"interface":"camera"}}`,
+			iface:    "camera",
+			exported: nil,
+		},
+	} {
+		// Make sure manager is zeroed out again
+		s.manager = &fakeInterfacesRequestsManager{}
+		s.manager.exported = testCase.exported
+
+		rsp := s.makeSyncReq(c, "POST", "/v2/interfaces/requests/rules", 1234, []byte(testCase.body))
+
+		// Check parameters
+		c.Check(s.manager.userID, Equals, uint32(1234))
+		c.Check(s.manager.snap, Equals, testCase.snap)
+		c.Check(s.manager.iface, Equals, testCase.iface)
+
+		// Check return value, which must never be null
+		rules, ok := rsp.Result.([]*requestrules.PortableRule)
+		c.Check(ok, Equals, true)
+		c.Check(rules, NotNil)
+		c.Check(rules, HasLen, len(testCase.exported))
+		if len(testCase.exported) > 0 {
+			c.Check(rules, DeepEquals, testCase.exported)
+		}
+	}
+}
+
+func (s *promptingSuite) TestPostRulesImportHappy(c *C) {
+	s.expectWriteAccess(daemon.InterfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}})
+
+	s.daemon(c)
+
+	s.manager.rules = []*requestrules.Rule{
+		{
+			ID:        prompting.IDType(1234),
+			Timestamp: time.Now(),
+			User:      1001,
+			Snap:      "thunderbird",
+			Interface: "home",
+			Constraints: &prompting.RuleConstraints{
+				InterfaceSpecific: &prompting.InterfaceSpecificConstraintsHome{
+					Pattern: mustParsePathPattern(c, "/home/test/Mail/**"),
+				},
+				Permissions: prompting.RulePermissionMap{
+					"read": &prompting.RulePermissionEntry{
+						Outcome:  prompting.OutcomeAllow,
+						Lifespan: prompting.LifespanForever,
+					},
+				},
+			},
+		},
+	}
+
+	importRules := []*requestrules.PortableRule{
+		{
+			Snap:      "thunderbird",
+			Interface: "home",
+			Constraints: prompting.ConstraintsJSON{
+				"path-pattern": json.RawMessage(`"/home/test/Mail/**"`),
+				"permissions":  json.RawMessage(`{"read":{"outcome":"allow","lifespan":"forever"}}`),
+			},
+		},
+		{
+			Snap:      "firefox",
+			Interface: "camera",
+			Constraints: prompting.ConstraintsJSON{
+				"permissions": json.RawMessage(`{"access":{"outcome":"deny","lifespan":"timespan","duration":"1h"}}`),
+			},
+		},
+	}
+	postBody := &daemon.PostRulesRequestBody{
+		Action:      "import",
+		ImportRules: importRules,
+	}
+	marshalled, err := json.Marshal(postBody)
+	c.Assert(err, IsNil)
+
+	rsp := s.makeSyncReq(c, "POST", "/v2/interfaces/requests/rules", 1001, marshalled)
+
+	// Check parameters
+	c.Check(s.manager.userID, Equals, uint32(1001))
+	c.Check(s.manager.importRules, DeepEquals, importRules)
+
+	// Check return value
+	rules, ok := rsp.Result.([]*requestrules.Rule)
+	c.Check(ok, Equals, true)
+	c.Check(rules, DeepEquals, s.manager.rules)
+}
+
 func (s *promptingSuite) TestPostRulesErrors(c *C) {
 	s.expectWriteAccess(daemon.InterfaceOpenAccess{Interfaces: []string{"snap-interfaces-requests-control"}})
 	s.daemon(c)
@@ -1801,6 +1987,33 @@ func (s *promptingSuite) TestPostRulesErrors(c *C) {
 	c.Check(rspe.Kind, Equals, client.ErrorKind(""))
 	c.Check(rspe.Message, Equals, `must include "snap" and/or "interface" field in "selector"`)
 
+	// Missing "rules"
+	req, err = http.NewRequest("POST", "/v2/interfaces/requests/rules", bytes.NewReader([]byte(`{"action":"import","rules":[]}`)))
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Kind, Equals, client.ErrorKindInterfacesRequestsInvalidFields)
+	c.Check(rspe.Message, Equals, `must include non-empty "rules" field in request body when action is "import"`)
+
+	// Missing "snap" in imported rule
+	req, err = http.NewRequest("POST", "/v2/interfaces/requests/rules", bytes.NewReader([]byte(`{"action":"import","rules":[{"snap":"firefox","interface":"camera","constraints":{}},{"snap":"","interface":"home","constraints":{}}]}`)))
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Kind, Equals, client.ErrorKindInterfacesRequestsInvalidFields)
+	c.Check(rspe.Message, Equals, `must have non-empty "snap" field`)
+
+	// Missing "interface" in imported rule
+	req, err = http.NewRequest("POST", "/v2/interfaces/requests/rules", bytes.NewReader([]byte(`{"action":"import","rules":[{"snap":"firefox","interface":"","constraints":{}}]}`)))
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 400)
+	c.Check(rspe.Kind, Equals, client.ErrorKindInterfacesRequestsInvalidFields)
+	c.Check(rspe.Message, Equals, `must have non-empty "interface" field`)
+
 	// Errors from manager
 	for _, testCase := range []struct {
 		body         []byte
@@ -2169,6 +2382,13 @@ func (s *promptingSuite) TestPostRuleErrors(c *C) {
 			expectedKind: client.ErrorKindInterfacesRequestsInvalidFields,
 			expectedMsg:  `invalid permissions for home interface: permissions empty`,
 		},
+		{
+			body:         validPatchBody,
+			err:          prompting_errors.ErrRuleIsPolicy,
+			expectedCode: 403,
+			expectedKind: client.ErrorKindInterfacesRequestsRuleIsPolicy,
+			expectedMsg:  prompting_errors.ErrRuleIsPolicy.Error(),
+		},
 		{
 			body:         validPatchBody,
 			err:          prompting_errors.ErrPatchedRuleHasNoPerms,
@@ -2232,3 +2452,208 @@ func (s *promptingSuite) TestPostRuleErrors(c *C) {
 		s.manager.err = nil
 	}
 }
+
+func (s *promptingSuite) TestGetHistoryHappy(c *C) {
+	s.daemon(c)
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 2, 1, 12, 30, 0, 0, time.UTC)
+
+	for _, testCase := range []struct {
+		vars   string
+		filter *requestprompts.HistoryFilter
+	}{
+		{
+			"",
+			&requestprompts.HistoryFilter{},
+		},
+		{
+			"?snap=firefox&interface=home",
+			&requestprompts.HistoryFilter{Snap: "firefox", Interface: "home"},
+		},
+		{
+			"?since=2026-01-01T00:00:00Z",
+			&requestprompts.HistoryFilter{Since: since},
+		},
+		{
+			"?snap=firefox&since=2026-01-01T00:00:00Z&until=2026-02-01T12:30:00Z",
+			&requestprompts.HistoryFilter{Snap: "firefox", Since: since, Until: until},
+		},
+	} {
+		// Make sure manager is zeroed out again
+		s.manager = &fakeInterfacesRequestsManager{}
+
+		s.manager.history = []*requestprompts.HistoryEntry{
+			{
+				Timestamp:            since,
+				PromptID:             prompting.IDType(0x12),
+				Snap:                 "firefox",
+				Interface:            "home",
+				Path:                 "/home/test/foo",
+				RequestedPermissions: []string{"read", "write"},
+				Resolution:           requestprompts.ResolutionReplied,
+				Outcome:              prompting.OutcomeAllow,
+				Lifespan:             prompting.LifespanForever,
+				AllowedPermissions:   []string{"read", "write"},
+				DeniedPermissions:    []string{},
+				RuleID:               prompting.IDType(0x34),
+			},
+		}
+
+		rsp := s.makeSyncReq(c, "GET", "/v2/interfaces/requests/history"+testCase.vars, 1234, nil)
+
+		// Check parameters
+		c.Check(s.manager.userID, Equals, uint32(1234))
+		c.Check(s.manager.historyFilter, DeepEquals, testCase.filter, Commentf("vars: %s", testCase.vars))
+
+		// Check return value
+		history, ok := rsp.Result.([]*requestprompts.HistoryEntry)
+		c.Check(ok, Equals, true)
+		c.Check(history, DeepEquals, s.manager.history)
+	}
+
+	// Daemon remaps nil to empty slice
+	s.manager.history = nil
+	rsp := s.makeSyncReq(c, "GET", "/v2/interfaces/requests/history", 1234, nil)
+	history, ok := rsp.Result.([]*requestprompts.HistoryEntry)
+	c.Check(ok, Equals, true)
+	c.Check(history, DeepEquals, []*requestprompts.HistoryEntry{})
+}
+
+func (s *promptingSuite) TestGetHistoryErrors(c *C) {
+	s.daemon(c)
+
+	// Can't parse userID
+	req, err := http.NewRequest("GET", "/v2/interfaces/requests/history", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 403)
+	c.Check(rspe.Message, testutil.Contains, "cannot get remote user")
+
+	// Prompting not running
+	s.appArmorPromptingRunning = false
+	req, err = http.NewRequest("GET", "/v2/interfaces/requests/history", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 500)
+	c.Check(rspe.Kind, Equals, client.ErrorKindAppArmorPromptingNotRunning)
+	s.appArmorPromptingRunning = true
+
+	// Invalid timestamps
+	for _, param := range []string{"since", "until"} {
+		req, err = http.NewRequest("GET", "/v2/interfaces/requests/history?"+param+"=yesterday", nil)
+		c.Assert(err, IsNil)
+		req.RemoteAddr = "pid=100;uid=1000;socket=;"
+		rspe = s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, Equals, 400)
+		c.Check(rspe.Message, Matches, fmt.Sprintf(`invalid "%s" timestamp: .*`, param))
+	}
+
+	// Errors from manager
+	s.manager.err = prompting_errors.ErrPromptingClosed
+	req, err = http.NewRequest("GET", "/v2/interfaces/requests/history", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 503)
+	c.Check(rspe.Message, Equals, prompting_errors.ErrPromptingClosed.Error())
+}
+
+func (s *promptingSuite) TestGetCheckHappy(c *C) {
+	s.daemon(c)
+
+	s.manager.check = &requestrules.RequestCheck{
+		Decision: requestrules.CheckDecisionDeny,
+		Permissions: []*requestrules.PermissionCheck{
+			{
+				Permission: "read",
+				Decision:   requestrules.CheckDecisionPrompt,
+				Reason:     "no rule matches the path, so the user would be prompted",
+			},
+			{
+				Permission: "write",
+				Decision:   requestrules.CheckDecisionDeny,
+				Source:     requestrules.CheckSourcePolicy,
+				Variant:    "/home/*/.ssh/**",
+				RuleIDs:    []prompting.IDType{0x12},
+				Matches: []*requestrules.VariantMatch{
+					{Source: requestrules.CheckSourcePolicy, Variant: "/home/*/.ssh/**", Outcome: prompting.OutcomeDeny, RuleIDs: []prompting.IDType{0x12}},
+				},
+				Reason: "/home/*/.ssh/** is the only matching variant of the policy rules for all snaps",
+			},
+		},
+	}
+
+	rsp := s.makeSyncReq(c, "GET", "/v2/interfaces/requests/check?snap=firefox&interface=home&path=/home/test/.ssh/config&permissions=read,write", 1234, nil)
+
+	// Check parameters
+	c.Check(s.manager.userID, Equals, uint32(1234))
+	c.Check(s.manager.snap, Equals, "firefox")
+	c.Check(s.manager.iface, Equals, "home")
+	c.Check(s.manager.path, Equals, "/home/test/.ssh/config")
+	c.Check(s.manager.permissions, DeepEquals, []string{"read", "write"})
+
+	// Check return value
+	check, ok := rsp.Result.(*requestrules.RequestCheck)
+	c.Check(ok, Equals, true)
+	c.Check(check, DeepEquals, s.manager.check)
+}
+
+func (s *promptingSuite) TestGetCheckErrors(c *C) {
+	s.daemon(c)
+
+	// Can't parse userID
+	req, err := http.NewRequest("GET", "/v2/interfaces/requests/check", nil)
+	c.Assert(err, IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 403)
+	c.Check(rspe.Message, testutil.Contains, "cannot get remote user")
+
+	// Prompting not running
+	s.appArmorPromptingRunning = false
+	req, err = http.NewRequest("GET", "/v2/interfaces/requests/check?snap=firefox&interface=home&path=/home/test/foo&permissions=read", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rspe = s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, Equals, 500)
+	c.Check(rspe.Kind, Equals, client.ErrorKindAppArmorPromptingNotRunning)
+	s.appArmorPromptingRunning = true
+
+	// Invalid parameters
+	for _, testCase := range []struct {
+		vars string
+		msg  string
+	}{
+		{"?interface=home&path=/home/test/foo&permissions=read", `missing "snap" parameter`},
+		{"?snap=firefox&path=/home/test/foo&permissions=read", `missing "interface" parameter`},
+		{"?snap=firefox&interface=home&permissions=read", `"path" parameter must be an absolute path: ""`},
+		{"?snap=firefox&interface=home&path=foo&permissions=read", `"path" parameter must be an absolute path: "foo"`},
+	} {
+		req, err = http.NewRequest("GET", "/v2/interfaces/requests/check"+testCase.vars, nil)
+		c.Assert(err, IsNil)
+		req.RemoteAddr = "pid=100;uid=1000;socket=;"
+		rspe = s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, Equals, 400, Commentf("vars: %s", testCase.vars))
+		c.Check(rspe.Message, Equals, testCase.msg, Commentf("vars: %s", testCase.vars))
+	}
+
+	// Errors from manager
+	for _, testCase := range []struct {
+		err    error
+		status int
+		kind   client.ErrorKind
+	}{
+		{prompting_errors.NewInvalidPermissionsError("home", []string{"access"}, []string{"read", "write", "execute"}), 400, client.ErrorKindInterfacesRequestsInvalidFields},
+		{prompting_errors.ErrPromptingClosed, 503, ""},
+	} {
+		s.manager.err = testCase.err
+		req, err = http.NewRequest("GET", "/v2/interfaces/requests/check?snap=firefox&interface=home&path=/home/test/foo&permissions=access", nil)
+		c.Assert(err, IsNil)
+		req.RemoteAddr = "pid=100;uid=1000;socket=;"
+		rspe = s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, Equals, testCase.status)
+		c.Check(rspe.Kind, Equals, testCase.kind)
+		c.Check(rspe.Message, Equals, testCase.err.Error())
+	}
+}
@@ -38,7 +38,7 @@ var (
 		Path:        "/v2/quotas",
 		GET:         getQuotaGroups,
 		POST:        postQuotaGroup,
-		Actions:     []string{"ensure", "remove"},
+		Actions:     []string{"ensure", "remove", "thaw"},
 		WriteAccess: rootAccess{},
 		ReadAccess:  openAccess{},
 	}
@@ -47,10 +47,15 @@ var (
 		GET:        getQuotaGroupInfo,
 		ReadAccess: openAccess{},
 	}
+	quotaGroupUsageCmd = &Command{
+		Path:       "/v2/quotas/{group}/usage",
+		GET:        getQuotaGroupUsage,
+		ReadAccess: openAccess{},
+	}
 )
 
 type postQuotaGroupData struct {
-	// Action can be "ensure" or "remove"
+	// Action can be "ensure", "remove" or "thaw"
 	Action      string             `json:"action"`
 	GroupName   string             `json:"group-name"`
 	Parent      string             `json:"parent,omitempty"`
@@ -60,9 +65,11 @@ type postQuotaGroupData struct {
 }
 
 var (
-	servicestateCreateQuota = servicestate.CreateQuota
-	servicestateUpdateQuota = servicestate.UpdateQuota
-	servicestateRemoveQuota = servicestate.RemoveQuota
+	servicestateCreateQuota       = servicestate.CreateQuota
+	servicestateUpdateQuota       = servicestate.UpdateQuota
+	servicestateRemoveQuota       = servicestate.RemoveQuota
+	servicestateThawQuota         = servicestate.ThawQuota
+	servicestateQuotaUsageHistory = servicestate.QuotaUsageHistory
 )
 
 var quoteControlChangeKind = swfeats.RegisterChangeKind("quota-control")
@@ -86,6 +93,16 @@ var getQuotaUsage = func(grp *quota.Group) (*client.QuotaValues, error) {
 		currentUsage.Threads = threads
 	}
 
+	if grp.NetworkLimit != nil {
+		sent, err := grp.CurrentNetworkUsage()
+		if err != nil {
+			return nil, err
+		}
+		currentUsage.Network = &client.QuotaNetworkValues{
+			EgressBytes: sent,
+		}
+	}
+
 	return &currentUsage, nil
 }
 
@@ -114,6 +131,27 @@ func createQuotaValues(grp *quota.Group) *client.QuotaValues {
 			}
 		}
 	}
+	if grp.IOLimit != nil {
+		constraints.IO = &client.QuotaIOValues{
+			ReadBandwidth:  grp.IOLimit.ReadBandwidth,
+			WriteBandwidth: grp.IOLimit.WriteBandwidth,
+			Weight:         grp.IOLimit.Weight,
+		}
+	}
+	if grp.NetworkLimit != nil {
+		constraints.Network = &client.QuotaNetworkValues{
+			EgressBandwidth: grp.NetworkLimit.EgressBandwidth,
+		}
+	}
+	if grp.SoftLimits != nil {
+		constraints.SoftLimits = &client.QuotaSoftLimitsValues{
+			Memory:        grp.SoftLimits.Memory,
+			CPUPercentage: grp.SoftLimits.CPUPercentage,
+			Threads:       grp.SoftLimits.Threads,
+			Window:        grp.SoftLimits.Window,
+			Action:        string(grp.SoftLimits.Action),
+		}
+	}
 	return &constraints
 }
 
@@ -195,6 +233,49 @@ func getQuotaGroupInfo(c *Command, r *http.Request, _ *auth.UserState) Response
 	return SyncResponse(res)
 }
 
+// getQuotaGroupUsage returns the history of the resource usage of a single
+// quota Group, optionally only since the time given by the "since" query
+// parameter.
+func getQuotaGroupUsage(c *Command, r *http.Request, _ *auth.UserState) Response {
+	vars := muxVars(r)
+	groupName := vars["group"]
+	if err := naming.ValidateQuotaGroup(groupName); err != nil {
+		return BadRequest(err.Error())
+	}
+
+	since, err := parseOptionalTime(r.URL.Query().Get("since"))
+	if err != nil {
+		return BadRequest(`invalid "since" timestamp: %v`, err)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	_, err = servicestate.GetQuota(st, groupName)
+	st.Unlock()
+	if err == servicestate.ErrQuotaNotFound {
+		return NotFound("cannot find quota group %q", groupName)
+	}
+	if err != nil {
+		return InternalError(err.Error())
+	}
+
+	samples, err := servicestateQuotaUsageHistory(groupName, since)
+	if err != nil {
+		return InternalError("cannot get usage history of quota group %q: %v", groupName, err)
+	}
+
+	results := make([]client.QuotaUsageSample, len(samples))
+	for i, sample := range samples {
+		results[i] = client.QuotaUsageSample{
+			Time:    sample.Time,
+			Memory:  sample.Memory,
+			CPU:     sample.CPU,
+			Threads: sample.Threads,
+		}
+	}
+	return SyncResponse(results)
+}
+
 func quotaValuesToResources(values client.QuotaValues) quota.Resources {
 	resourcesBuilder := quota.NewResourcesBuilder()
 	if values.Memory != 0 {
@@ -223,6 +304,29 @@ func quotaValuesToResources(values client.QuotaValues) quota.Resources {
 			resourcesBuilder.WithJournalRate(values.Journal.RateCount, values.Journal.RatePeriod)
 		}
 	}
+	if values.IO != nil {
+		if values.IO.ReadBandwidth != 0 {
+			resourcesBuilder.WithIOReadBandwidth(values.IO.ReadBandwidth)
+		}
+		if values.IO.WriteBandwidth != 0 {
+			resourcesBuilder.WithIOWriteBandwidth(values.IO.WriteBandwidth)
+		}
+		if values.IO.Weight != 0 {
+			resourcesBuilder.WithIOWeight(values.IO.Weight)
+		}
+	}
+	if values.Network != nil && values.Network.EgressBandwidth != 0 {
+		resourcesBuilder.WithNetworkEgressBandwidth(values.Network.EgressBandwidth)
+	}
+	if values.SoftLimits != nil {
+		resourcesBuilder.WithSoftLimits(quota.ResourceSoftLimits{
+			Memory:        values.SoftLimits.Memory,
+			CPUPercentage: values.SoftLimits.CPUPercentage,
+			Threads:       values.SoftLimits.Threads,
+			Window:        values.SoftLimits.Window,
+			Action:        quota.SoftLimitAction(values.SoftLimits.Action),
+		})
+	}
 	return resourcesBuilder.Build()
 }
 
@@ -290,6 +394,13 @@ func postQuotaGroup(c *Command, r *http.Request, _ *auth.UserState) Response {
 			return errToResponse(err, nil, BadRequest, "cannot remove quota group: %v")
 		}
 		chgSummary = "Remove quota group"
+	case "thaw":
+		var err error
+		ts, err = servicestateThawQuota(st, data.GroupName)
+		if err != nil {
+			return errToResponse(err, nil, BadRequest, "cannot thaw quota group: %v")
+		}
+		chgSummary = "Thaw quota group"
 	default:
 		return BadRequest("unknown quota action %q", data.Action)
 	}
@@ -120,6 +120,74 @@ func (s *apiQuotaSuite) TestCreateQuotaValues(c *check.C) {
 	})
 }
 
+func (s *apiQuotaSuite) TestCreateQuotaValuesIO(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	err := servicestatetest.MockQuotaInState(st, "ginger-ale", "", nil, nil,
+		quota.NewResourcesBuilder().
+			WithIOReadBandwidth(10*quantity.SizeMiB).
+			WithIOWriteBandwidth(5*quantity.SizeMiB).
+			WithIOWeight(200).
+			Build())
+	allGroups, err2 := servicestate.AllQuotas(st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Assert(err2, check.IsNil)
+
+	quotaValues := daemon.CreateQuotaValues(allGroups["ginger-ale"])
+	c.Check(quotaValues.IO, check.DeepEquals, &client.QuotaIOValues{
+		ReadBandwidth:  10 * quantity.SizeMiB,
+		WriteBandwidth: 5 * quantity.SizeMiB,
+		Weight:         200,
+	})
+}
+
+func (s *apiQuotaSuite) TestCreateQuotaValuesNetwork(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	err := servicestatetest.MockQuotaInState(st, "ginger-ale", "", nil, nil,
+		quota.NewResourcesBuilder().WithNetworkEgressBandwidth(2*quantity.SizeMiB).Build())
+	allGroups, err2 := servicestate.AllQuotas(st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Assert(err2, check.IsNil)
+
+	quotaValues := daemon.CreateQuotaValues(allGroups["ginger-ale"])
+	c.Check(quotaValues.Network, check.DeepEquals, &client.QuotaNetworkValues{
+		EgressBandwidth: 2 * quantity.SizeMiB,
+	})
+
+	// without the network quota attached yet, nothing was sent
+	usage, err := daemon.GetQuotaUsage(allGroups["ginger-ale"])
+	c.Assert(err, check.IsNil)
+	c.Check(usage.Network, check.DeepEquals, &client.QuotaNetworkValues{})
+}
+
+func (s *apiQuotaSuite) TestCreateQuotaValuesSoftLimits(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	err := servicestatetest.MockQuotaInState(st, "ginger-ale", "", nil, nil,
+		quota.NewResourcesBuilder().
+			WithMemoryLimit(quantity.SizeGiB).
+			WithSoftLimits(quota.ResourceSoftLimits{
+				Memory: 800 * quantity.SizeMiB,
+				Window: 10 * time.Minute,
+				Action: quota.SoftLimitActionRestart,
+			}).
+			Build())
+	allGroups, err2 := servicestate.AllQuotas(st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Assert(err2, check.IsNil)
+
+	quotaValues := daemon.CreateQuotaValues(allGroups["ginger-ale"])
+	c.Check(quotaValues.SoftLimits, check.DeepEquals, &client.QuotaSoftLimitsValues{
+		Memory: 800 * quantity.SizeMiB,
+		Window: 10 * time.Minute,
+		Action: "restart",
+	})
+}
+
 func (s *apiQuotaSuite) TestPostQuotaUnknownAction(c *check.C) {
 	data, err := json.Marshal(daemon.PostQuotaGroupData{Action: "foo", GroupName: "bar"})
 	c.Assert(err, check.IsNil)
@@ -328,6 +396,112 @@ func (s *apiQuotaSuite) TestPostEnsureQuotaCreateJournalRateZeroHappy(c *check.C
 	c.Assert(s.ensureSoonCalled, check.Equals, 1)
 }
 
+func (s *apiQuotaSuite) TestPostEnsureQuotaCreateIOHappy(c *check.C) {
+	var createCalled int
+	r := daemon.MockServicestateCreateQuota(func(st *state.State, name string, createOpts servicestate.CreateQuotaOptions) (*state.TaskSet, error) {
+		createCalled++
+		c.Check(name, check.Equals, "booze")
+		c.Check(createOpts.ResourceLimits, check.DeepEquals, quota.NewResourcesBuilder().
+			WithIOWriteBandwidth(quantity.SizeMiB).
+			WithIOWeight(50).
+			Build())
+		ts := state.NewTaskSet(st.NewTask("foo-quota", "..."))
+		return ts, nil
+	})
+	defer r()
+
+	data, err := json.Marshal(daemon.PostQuotaGroupData{
+		Action:    "ensure",
+		GroupName: "booze",
+		Snaps:     []string{"some-snap"},
+		Constraints: client.QuotaValues{
+			IO: &client.QuotaIOValues{
+				WriteBandwidth: quantity.SizeMiB,
+				Weight:         50,
+			},
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewBuffer(data))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 202)
+	c.Assert(createCalled, check.Equals, 1)
+}
+
+func (s *apiQuotaSuite) TestPostEnsureQuotaCreateNetworkHappy(c *check.C) {
+	var createCalled int
+	r := daemon.MockServicestateCreateQuota(func(st *state.State, name string, createOpts servicestate.CreateQuotaOptions) (*state.TaskSet, error) {
+		createCalled++
+		c.Check(name, check.Equals, "booze")
+		c.Check(createOpts.ResourceLimits, check.DeepEquals, quota.NewResourcesBuilder().
+			WithNetworkEgressBandwidth(quantity.SizeMiB).
+			Build())
+		ts := state.NewTaskSet(st.NewTask("foo-quota", "..."))
+		return ts, nil
+	})
+	defer r()
+
+	data, err := json.Marshal(daemon.PostQuotaGroupData{
+		Action:    "ensure",
+		GroupName: "booze",
+		Snaps:     []string{"some-snap"},
+		Constraints: client.QuotaValues{
+			Network: &client.QuotaNetworkValues{
+				EgressBandwidth: quantity.SizeMiB,
+			},
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewBuffer(data))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 202)
+	c.Assert(createCalled, check.Equals, 1)
+}
+
+func (s *apiQuotaSuite) TestPostEnsureQuotaCreateSoftLimitsHappy(c *check.C) {
+	var createCalled int
+	r := daemon.MockServicestateCreateQuota(func(st *state.State, name string, createOpts servicestate.CreateQuotaOptions) (*state.TaskSet, error) {
+		createCalled++
+		c.Check(name, check.Equals, "booze")
+		c.Check(createOpts.ResourceLimits, check.DeepEquals, quota.NewResourcesBuilder().
+			WithThreadLimit(64).
+			WithSoftLimits(quota.ResourceSoftLimits{
+				Threads: 32,
+				Window:  time.Hour,
+				Action:  quota.SoftLimitActionFreeze,
+			}).
+			Build())
+		ts := state.NewTaskSet(st.NewTask("foo-quota", "..."))
+		return ts, nil
+	})
+	defer r()
+
+	data, err := json.Marshal(daemon.PostQuotaGroupData{
+		Action:    "ensure",
+		GroupName: "booze",
+		Snaps:     []string{"some-snap"},
+		Constraints: client.QuotaValues{
+			Threads: 64,
+			SoftLimits: &client.QuotaSoftLimitsValues{
+				Threads: 32,
+				Window:  time.Hour,
+				Action:  "freeze",
+			},
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewBuffer(data))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 202)
+	c.Assert(createCalled, check.Equals, 1)
+}
+
 func (s *apiQuotaSuite) TestPostEnsureQuotaUpdateCpuHappy(c *check.C) {
 	st := s.d.Overlord().State()
 	st.Lock()
@@ -584,6 +758,59 @@ func (s *apiQuotaSuite) TestPostRemoveQuotaHappy(c *check.C) {
 	c.Assert(s.ensureSoonCalled, check.Equals, 1)
 }
 
+func (s *apiQuotaSuite) TestPostThawQuotaHappy(c *check.C) {
+	var thawCalled int
+	r := daemon.MockServicestateThawQuota(func(st *state.State, name string) (*state.TaskSet, error) {
+		thawCalled++
+		c.Check(name, check.Equals, "booze")
+		ts := state.NewTaskSet(st.NewTask("foo-quota", "..."))
+		return ts, nil
+	})
+	defer r()
+
+	data, err := json.Marshal(daemon.PostQuotaGroupData{
+		Action:    "thaw",
+		GroupName: "booze",
+	})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewBuffer(data))
+	c.Assert(err, check.IsNil)
+	s.asRootAuth(req)
+
+	rec := httptest.NewRecorder()
+	s.serveHTTP(c, rec, req)
+	c.Assert(rec.Code, check.Equals, 202)
+	c.Assert(thawCalled, check.Equals, 1)
+	c.Assert(s.ensureSoonCalled, check.Equals, 1)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chgs := st.Changes()
+	c.Assert(chgs, check.HasLen, 1)
+	c.Check(chgs[0].Summary(), check.Equals, "Thaw quota group")
+}
+
+func (s *apiQuotaSuite) TestPostThawQuotaUnhappy(c *check.C) {
+	r := daemon.MockServicestateThawQuota(func(st *state.State, name string) (*state.TaskSet, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer r()
+
+	data, err := json.Marshal(daemon.PostQuotaGroupData{
+		Action:    "thaw",
+		GroupName: "booze",
+	})
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewBuffer(data))
+	c.Assert(err, check.IsNil)
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `cannot thaw quota group: boom`)
+}
+
 func (s *apiQuotaSuite) TestPostRemoveQuotaConflict(c *check.C) {
 	st := s.d.Overlord().State()
 	st.Lock()
@@ -868,3 +1095,66 @@ func (s *apiQuotaSuite) TestGetQuotaNotFound(c *check.C) {
 	c.Check(rspe.Message, check.Matches, `cannot find quota group "unknown"`)
 	c.Check(s.ensureSoonCalled, check.Equals, 0)
 }
+
+func (s *apiQuotaSuite) TestGetQuotaUsage(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	mockQuotas(st, c)
+	st.Unlock()
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	var sinceArg time.Time
+	r := daemon.MockServicestateQuotaUsageHistory(func(group string, since time.Time) ([]servicestate.QuotaUsageSample, error) {
+		c.Check(group, check.Equals, "bar")
+		sinceArg = since
+		return []servicestate.QuotaUsageSample{
+			{Time: t0, Memory: quantity.SizeKiB, CPU: 50, Threads: 4},
+			{Time: t0.Add(5 * time.Minute), Memory: quantity.SizeMiB, CPU: 150, Threads: 6},
+		}, nil
+	})
+	defer r()
+
+	req, err := http.NewRequest("GET", "/v2/quotas/bar/usage", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []client.QuotaUsageSample{
+		{Time: t0, Memory: quantity.SizeKiB, CPU: 50, Threads: 4},
+		{Time: t0.Add(5 * time.Minute), Memory: quantity.SizeMiB, CPU: 150, Threads: 6},
+	})
+	c.Check(sinceArg.IsZero(), check.Equals, true)
+
+	req, err = http.NewRequest("GET", "/v2/quotas/bar/usage?since=2026-01-01T10:03:00Z", nil)
+	c.Assert(err, check.IsNil)
+	s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(sinceArg.Equal(t0.Add(3*time.Minute)), check.Equals, true)
+}
+
+func (s *apiQuotaSuite) TestGetQuotaUsageErrors(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	mockQuotas(st, c)
+	st.Unlock()
+
+	r := daemon.MockServicestateQuotaUsageHistory(func(group string, since time.Time) ([]servicestate.QuotaUsageSample, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer r()
+
+	for _, tc := range []struct {
+		url    string
+		status int
+		msg    string
+	}{
+		{"/v2/quotas/000/usage", 400, `invalid quota group name: .*`},
+		{"/v2/quotas/bar/usage?since=yesterday", 400, `invalid "since" timestamp: .*`},
+		{"/v2/quotas/unknown/usage", 404, `cannot find quota group "unknown"`},
+		{"/v2/quotas/bar/usage", 500, `cannot get usage history of quota group "bar": boom`},
+	} {
+		req, err := http.NewRequest("GET", tc.url, nil)
+		c.Assert(err, check.IsNil)
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, check.Equals, tc.status, check.Commentf(tc.url))
+		c.Check(rspe.Message, check.Matches, tc.msg, check.Commentf(tc.url))
+	}
+}
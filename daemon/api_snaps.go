@@ -284,6 +284,7 @@ type snapInstruction struct {
 	Snaps                  []string                         `json:"snaps"`
 	Users                  []string                         `json:"users"`
 	SnapshotOptions        map[string]*snap.SnapshotOptions `json:"snapshot-options"`
+	SnapshotPassphrase     string                           `json:"snapshot-passphrase"`
 	ValidationSets         []string                         `json:"validation-sets"`
 	QuotaGroupName         string                           `json:"quota-group"`
 	Time                   string                           `json:"time"`
@@ -464,6 +465,9 @@ func (inst *snapInstruction) validate() error {
 	if err := inst.validateSnapshotOptions(); err != nil {
 		return err
 	}
+	if inst.SnapshotPassphrase != "" && inst.Action != snapshotCmdAction {
+		return fmt.Errorf("snapshot-passphrase can only be specified for snapshot action")
+	}
 
 	if inst.Action == snapshotCmdAction {
 		inst.cleanSnapshotOptions()
@@ -519,6 +519,22 @@ func (s *snapsSuite) TestPostSnapsOptionsUnsupportedActionError(c *check.C) {
 	}
 }
 
+func (s *snapsSuite) TestPostSnapsPassphraseUnsupportedActionError(c *check.C) {
+	s.daemon(c)
+	const expectedErr = "snapshot-passphrase can only be specified for snapshot action"
+
+	for _, action := range []string{"install", "refresh", "remove", "xyzzy"} {
+		buf := strings.NewReader(fmt.Sprintf(`{"action": "%s", "snaps":["foo"], "snapshot-passphrase": "sekrit"}`, action))
+		req, err := http.NewRequest("POST", "/v2/snaps", buf)
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+
+		rspe := s.errorReq(c, req, nil, action != "xyzzy")
+		c.Check(rspe.Status, check.Equals, 400, check.Commentf("%q", action))
+		c.Check(rspe.Message, check.Equals, expectedErr, check.Commentf("%q", action))
+	}
+}
+
 func (s *snapsSuite) TestPostSnapsOptionsOtherErrors(c *check.C) {
 	s.daemon(c)
 	const notListedErr = `cannot use snapshot-options for snap "xyzzy" that is not listed in snaps`
@@ -632,7 +648,7 @@ func (s *snapsSuite) TestPostSnapsRemoveManyWithPurge(c *check.C) {
 func (s *snapsSuite) TestPostSnapsOptionsClean(c *check.C) {
 	var snapshotSaveCalled int
 	defer daemon.MockSnapshotSave(func(s *state.State, snaps, users []string,
-		options map[string]*snap.SnapshotOptions) (uint64, []string, *state.TaskSet, error) {
+		options map[string]*snap.SnapshotOptions, passphrase string) (uint64, []string, *state.TaskSet, error) {
 		snapshotSaveCalled++
 
 		c.Check(snaps, check.HasLen, 3)
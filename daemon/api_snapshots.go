@@ -22,6 +22,7 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -42,7 +43,7 @@ var snapshotCmd = &Command{
 	Path:        "/v2/snapshots",
 	GET:         listSnapshots,
 	POST:        changeSnapshots,
-	Actions:     []string{"check", "restore", "forget"},
+	Actions:     []string{"check", "restore", "forget", "push", "pull"},
 	ReadAccess:  openAccess{},
 	WriteAccess: authenticatedAccess{Polkit: polkitActionManage},
 }
@@ -61,12 +62,18 @@ var (
 	snapshotSave    = snapshotstate.Save
 	snapshotExport  = snapshotstate.Export
 	snapshotImport  = snapshotstate.Import
+	snapshotPush    = snapshotstate.Push
+	snapshotPull    = snapshotstate.Pull
+
+	snapshotSetPassphrase   = snapshotstate.SetPassphrase
+	snapshotCheckPassphrase = snapshotstate.CheckPassphrase
 )
 
 var (
 	checkSnapshotChangeKind   = swfeats.RegisterChangeKind("check-snapshot")
 	restoreSnapshotChangeKind = swfeats.RegisterChangeKind("restore-snapshot")
 	forgetSnapshotChangeKind  = swfeats.RegisterChangeKind("forget-snapshot")
+	pushSnapshotChangeKind    = swfeats.RegisterChangeKind("push-snapshot")
 )
 
 func listSnapshots(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -93,10 +100,11 @@ func listSnapshots(c *Command, r *http.Request, user *auth.UserState) Response {
 // A snapshotAction is used to request an operation on a snapshot
 // keep this in sync with client/snapshotAction...
 type snapshotAction struct {
-	SetID  uint64   `json:"set"`
-	Action string   `json:"action"`
-	Snaps  []string `json:"snaps,omitempty"`
-	Users  []string `json:"users,omitempty"`
+	SetID      uint64   `json:"set"`
+	Action     string   `json:"action"`
+	Snaps      []string `json:"snaps,omitempty"`
+	Users      []string `json:"users,omitempty"`
+	Passphrase string   `json:"passphrase,omitempty"`
 }
 
 func (action snapshotAction) String() string {
@@ -135,6 +143,11 @@ func changeSnapshots(c *Command, r *http.Request, user *auth.UserState) Response
 		return BadRequest("snapshot operation requires action")
 	}
 
+	if action.Action == "pull" {
+		// pulling imports a new snapshot set, it is not a change
+		return doSnapshotPull(c, r, action)
+	}
+
 	var affected []string
 	var ts *state.TaskSet
 	var err error
@@ -143,6 +156,19 @@ func changeSnapshots(c *Command, r *http.Request, user *auth.UserState) Response
 	st.Lock()
 	defer st.Unlock()
 
+	if action.Passphrase != "" {
+		if action.Action != "check" && action.Action != "restore" {
+			return BadRequest(`snapshot %q operation cannot specify a passphrase`, action.Action)
+		}
+		if err := snapshotCheckPassphrase(st, action.SetID, action.Passphrase); err != nil {
+			if errors.Is(err, client.ErrSnapshotSetNotFound) {
+				return NotFound("%v", err)
+			}
+			return BadRequest("%v", err)
+		}
+		snapshotSetPassphrase(st, action.SetID, action.Passphrase)
+	}
+
 	var changeKind string
 	switch action.Action {
 	case "check":
@@ -157,15 +183,28 @@ func changeSnapshots(c *Command, r *http.Request, user *auth.UserState) Response
 		}
 		affected, ts, err = snapshotForget(st, action.SetID, action.Snaps)
 		changeKind = forgetSnapshotChangeKind
+	case "push":
+		if len(action.Snaps) != 0 || len(action.Users) != 0 {
+			return BadRequest(`snapshot "push" operation cannot specify snaps or users`)
+		}
+		ts, err = snapshotPush(st, action.SetID)
+		changeKind = pushSnapshotChangeKind
 	default:
 		return BadRequest("unknown snapshot operation %q", action.Action)
 	}
 
+	if err != nil && action.Passphrase != "" {
+		// there is no change to use the passphrase
+		snapshotSetPassphrase(st, action.SetID, "")
+	}
+
 	switch err {
 	case nil:
 		// woo
 	case client.ErrSnapshotSetNotFound, client.ErrSnapshotSnapsNotFound:
 		return NotFound("%v", err)
+	case snapshotstate.ErrNoRemoteTarget:
+		return BadRequest("%v", err)
 	default:
 		return InternalError("%v", err)
 	}
@@ -220,7 +259,8 @@ func doSnapshotImport(c *Command, r *http.Request, user *auth.UserState) Respons
 
 	// XXX: check that we have enough space to import the compressed snapshots
 	st := c.d.overlord.State()
-	setID, snapNames, err := snapshotImport(r.Context(), st, limitedBodyReader)
+	passphrase := r.Header.Get(client.SnapshotPassphraseHeader)
+	setID, snapNames, err := snapshotImport(r.Context(), st, limitedBodyReader, passphrase)
 	if err != nil {
 		return BadRequest(err.Error())
 	}
@@ -229,8 +269,28 @@ func doSnapshotImport(c *Command, r *http.Request, user *auth.UserState) Respons
 	return SyncResponse(result)
 }
 
+func doSnapshotPull(c *Command, r *http.Request, action snapshotAction) Response {
+	if len(action.Snaps) != 0 || len(action.Users) != 0 {
+		return BadRequest(`snapshot "pull" operation cannot specify snaps or users`)
+	}
+
+	st := c.d.overlord.State()
+	setID, snapNames, err := snapshotPull(r.Context(), st, action.SetID, action.Passphrase)
+	switch {
+	case err == nil:
+		// woo
+	case errors.Is(err, snapshotstate.ErrRemoteSnapshotNotFound):
+		return NotFound("%v", err)
+	default:
+		return BadRequest("%v", err)
+	}
+
+	result := map[string]any{"set-id": setID, "snaps": snapNames}
+	return SyncResponse(result)
+}
+
 func snapshotMany(_ context.Context, inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
-	setID, snapshotted, ts, err := snapshotSave(st, inst.Snaps, inst.Users, inst.SnapshotOptions)
+	setID, snapshotted, ts, err := snapshotSave(st, inst.Snaps, inst.Users, inst.SnapshotOptions, inst.SnapshotPassphrase)
 	if err != nil {
 		return nil, err
 	}
@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -53,7 +54,7 @@ func (s *snapshotSuite) SetUpTest(c *check.C) {
 
 func (s *snapshotSuite) TestSnapshotManyOptionsNone(c *check.C) {
 	defer daemon.MockSnapshotSave(func(s *state.State, snaps, users []string,
-		options map[string]*snap.SnapshotOptions) (uint64, []string, *state.TaskSet, error) {
+		options map[string]*snap.SnapshotOptions, passphrase string) (uint64, []string, *state.TaskSet, error) {
 		c.Check(snaps, check.HasLen, 2)
 		c.Check(options, check.IsNil)
 		t := s.NewTask("fake-snapshot-2", "Snapshot two")
@@ -74,7 +75,7 @@ func (s *snapshotSuite) TestSnapshotManyOptionsNone(c *check.C) {
 func (s *snapshotSuite) TestSnapshotManyOptionsFull(c *check.C) {
 	var snapshotSaveCalled int
 	defer daemon.MockSnapshotSave(func(s *state.State, snaps, users []string,
-		options map[string]*snap.SnapshotOptions) (uint64, []string, *state.TaskSet, error) {
+		options map[string]*snap.SnapshotOptions, passphrase string) (uint64, []string, *state.TaskSet, error) {
 		snapshotSaveCalled++
 		c.Check(snaps, check.HasLen, 2)
 		c.Check(options, check.HasLen, 2)
@@ -99,9 +100,28 @@ func (s *snapshotSuite) TestSnapshotManyOptionsFull(c *check.C) {
 	c.Check(snapshotSaveCalled, check.Equals, 1)
 }
 
+func (s *snapshotSuite) TestSnapshotManyPassphrase(c *check.C) {
+	var passphrase string
+	defer daemon.MockSnapshotSave(func(s *state.State, snaps, users []string,
+		options map[string]*snap.SnapshotOptions, p string) (uint64, []string, *state.TaskSet, error) {
+		passphrase = p
+		t := s.NewTask("fake-snapshot-2", "Snapshot two")
+		return 7, snaps, state.NewTaskSet(t), nil
+	})()
+
+	inst := daemon.MustUnmarshalSnapInstruction(c, `{"action": "snapshot", "snaps": ["foo"], "snapshot-passphrase": "sekrit"}`)
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	_, err := inst.DispatchForMany()(context.Background(), inst, st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(passphrase, check.Equals, "sekrit")
+}
+
 func (s *snapshotSuite) TestSnapshotManyError(c *check.C) {
 	defer daemon.MockSnapshotSave(func(s *state.State, snaps, users []string,
-		options map[string]*snap.SnapshotOptions) (uint64, []string, *state.TaskSet, error) {
+		options map[string]*snap.SnapshotOptions, passphrase string) (uint64, []string, *state.TaskSet, error) {
 		c.Check(snaps, check.HasLen, 2)
 		return 0, nil, nil, &snap.NotInstalledError{Snap: "foo"}
 	})()
@@ -250,6 +270,194 @@ func (s *snapshotSuite) TestChangeSnapshots400(c *check.C) {
 	}
 }
 
+func (s *snapshotSuite) TestChangeSnapshotsPassphrase(c *check.C) {
+	defer daemon.MockSnapshotCheck(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+		return []string{"foo"}, state.NewTaskSet(), nil
+	})()
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+		return []string{"foo"}, state.NewTaskSet(), nil
+	})()
+	var checked string
+	defer daemon.MockSnapshotCheckPassphrase(func(st *state.State, id uint64, p string) error {
+		c.Check(id, check.Equals, uint64(42))
+		checked = p
+		return nil
+	})()
+	var setID uint64
+	var passphrase string
+	defer daemon.MockSnapshotSetPassphrase(func(st *state.State, id uint64, p string) {
+		c.Check(checked, check.Equals, p)
+		setID = id
+		passphrase = p
+	})()
+
+	for _, action := range []string{"check", "restore"} {
+		setID, passphrase, checked = 0, "", ""
+		comm := check.Commentf("%s", action)
+		body := fmt.Sprintf(`{"set": 42, "action": "%s", "passphrase": "sekrit"}`, action)
+		req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+		c.Assert(err, check.IsNil, comm)
+
+		rsp := s.asyncReq(c, req, nil, actionIsExpected)
+		c.Check(rsp.Status, check.Equals, 202, comm)
+		c.Check(setID, check.Equals, uint64(42), comm)
+		c.Check(passphrase, check.Equals, "sekrit", comm)
+	}
+}
+
+func (s *snapshotSuite) TestChangeSnapshotsPassphraseInvalid(c *check.C) {
+	defer daemon.MockSnapshotCheck(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+		c.Fatalf("unexpected snapshot check")
+		return nil, nil, nil
+	})()
+	defer daemon.MockSnapshotSetPassphrase(func(st *state.State, id uint64, p string) {
+		c.Fatalf("unexpected passphrase caching")
+	})()
+
+	for _, tc := range []struct {
+		err     error
+		status  int
+		message string
+	}{
+		{fmt.Errorf("cannot unlock snapshot %q: %w", "42_foo.zip", backend.ErrWrongKey), 400, `cannot unlock snapshot "42_foo.zip": wrong key`},
+		{client.ErrSnapshotSetNotFound, 404, "no snapshot set with the given ID"},
+	} {
+		defer daemon.MockSnapshotCheckPassphrase(func(st *state.State, id uint64, p string) error {
+			return tc.err
+		})()
+		req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(`{"set": 42, "action": "check", "passphrase": "wrong"}`))
+		c.Assert(err, check.IsNil)
+
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, check.Equals, tc.status)
+		c.Check(rspe.Message, check.Equals, tc.message)
+	}
+}
+
+func (s *snapshotSuite) TestChangeSnapshotsPassphraseForgottenOnError(c *check.C) {
+	defer daemon.MockSnapshotCheckPassphrase(func(st *state.State, id uint64, p string) error {
+		return nil
+	})()
+	defer daemon.MockSnapshotRestore(func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+		return nil, nil, client.ErrSnapshotSnapsNotFound
+	})()
+	var passphrases []string
+	defer daemon.MockSnapshotSetPassphrase(func(st *state.State, id uint64, p string) {
+		passphrases = append(passphrases, p)
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(`{"set": 42, "action": "restore", "snaps": ["bar"], "passphrase": "sekrit"}`))
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 404)
+	c.Check(passphrases, check.DeepEquals, []string{"sekrit", ""})
+}
+
+func (s *snapshotSuite) TestChangeSnapshotsPassphraseForget(c *check.C) {
+	body := `{"set": 42, "action": "forget", "passphrase": "sekrit"}`
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rspe := s.errorReq(c, req, nil, actionIsExpected)
+	c.Check(rspe.Status, check.Equals, 400)
+	c.Check(rspe.Message, check.Equals, `snapshot "forget" operation cannot specify a passphrase`)
+}
+
+func (s *snapshotSuite) TestChangeSnapshotsPush(c *check.C) {
+	var pushedSetID uint64
+	defer daemon.MockSnapshotPush(func(st *state.State, setID uint64) (*state.TaskSet, error) {
+		pushedSetID = setID
+		return state.NewTaskSet(st.NewTask("push-snapshot", "...")), nil
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(`{"set": 42, "action": "push"}`))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.asyncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 202)
+	c.Check(pushedSetID, check.Equals, uint64(42))
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "push-snapshot")
+	c.Check(chg.Summary(), check.Equals, "Push of snapshot set #42")
+}
+
+func (s *snapshotSuite) TestChangeSnapshotsPushErrors(c *check.C) {
+	var pushErr error
+	defer daemon.MockSnapshotPush(func(*state.State, uint64) (*state.TaskSet, error) {
+		return nil, pushErr
+	})()
+
+	for _, tc := range []struct {
+		body    string
+		pushErr error
+		status  int
+		message string
+	}{
+		{`{"set": 42, "action": "push", "snaps": ["foo"]}`, nil, 400, `snapshot "push" operation cannot specify snaps or users`},
+		{`{"set": 42, "action": "push"}`, snapshotstate.ErrNoRemoteTarget, 400, `no remote snapshot target is configured`},
+		{`{"set": 42, "action": "push"}`, client.ErrSnapshotSetNotFound, 404, `no snapshot set with the given ID`},
+	} {
+		pushErr = tc.pushErr
+		req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(tc.body))
+		c.Assert(err, check.IsNil)
+
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, check.Equals, tc.status, check.Commentf(tc.body))
+		c.Check(rspe.Message, check.Equals, tc.message, check.Commentf(tc.body))
+	}
+}
+
+func (s *snapshotSuite) TestChangeSnapshotsPull(c *check.C) {
+	var pulledSetID uint64
+	var gotPassphrase string
+	defer daemon.MockSnapshotPull(func(ctx context.Context, st *state.State, setID uint64, passphrase string) (uint64, []string, error) {
+		pulledSetID = setID
+		gotPassphrase = passphrase
+		return 7, []string{"foo"}, nil
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(`{"set": 42, "action": "pull", "passphrase": "sekrit"}`))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, map[string]any{"set-id": uint64(7), "snaps": []string{"foo"}})
+	c.Check(pulledSetID, check.Equals, uint64(42))
+	c.Check(gotPassphrase, check.Equals, "sekrit")
+}
+
+func (s *snapshotSuite) TestChangeSnapshotsPullErrors(c *check.C) {
+	var pullErr error
+	defer daemon.MockSnapshotPull(func(context.Context, *state.State, uint64, string) (uint64, []string, error) {
+		return 0, nil, pullErr
+	})()
+
+	for _, tc := range []struct {
+		body    string
+		pullErr error
+		status  int
+		message string
+	}{
+		{`{"set": 42, "action": "pull", "users": ["me"]}`, nil, 400, `snapshot "pull" operation cannot specify snaps or users`},
+		{`{"set": 42, "action": "pull"}`, snapshotstate.ErrNoRemoteTarget, 400, `no remote snapshot target is configured`},
+		{`{"set": 42, "action": "pull"}`, fmt.Errorf("cannot pull: %w", snapshotstate.ErrRemoteSnapshotNotFound), 404, `cannot pull: snapshot set not found in remote target`},
+	} {
+		pullErr = tc.pullErr
+		req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(tc.body))
+		c.Assert(err, check.IsNil)
+
+		rspe := s.errorReq(c, req, nil, actionIsExpected)
+		c.Check(rspe.Status, check.Equals, tc.status, check.Commentf(tc.body))
+		c.Check(rspe.Message, check.Equals, tc.message, check.Commentf(tc.body))
+	}
+}
+
 func (s *snapshotSuite) TestChangeSnapshots404(c *check.C) {
 	var done string
 	expectedError := errors.New("bzzt")
@@ -403,7 +611,7 @@ func (s *snapshotSuite) TestImportSnapshot(c *check.C) {
 
 	setID := uint64(3)
 	snapNames := []string{"baz", "bar", "foo"}
-	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader) (uint64, []string, error) {
+	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader, string) (uint64, []string, error) {
 		return setID, snapNames, nil
 	})()
 
@@ -417,8 +625,27 @@ func (s *snapshotSuite) TestImportSnapshot(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, map[string]any{"set-id": setID, "snaps": snapNames})
 }
 
+func (s *snapshotSuite) TestImportSnapshotPassphrase(c *check.C) {
+	var gotPassphrase string
+	defer daemon.MockSnapshotImport(func(ctx context.Context, st *state.State, r io.Reader, passphrase string) (uint64, []string, error) {
+		gotPassphrase = passphrase
+		return uint64(3), []string{"foo"}, nil
+	})()
+
+	data := []byte("mocked snapshot export data file")
+	req, err := http.NewRequest("POST", "/v2/snapshots", bytes.NewReader(data))
+	req.Header.Add("Content-Length", strconv.Itoa(len(data)))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", client.SnapshotExportMediaType)
+	req.Header.Set(client.SnapshotPassphraseHeader, "sekrit")
+
+	rsp := s.syncReq(c, req, nil, actionIsExpected)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(gotPassphrase, check.Equals, "sekrit")
+}
+
 func (s *snapshotSuite) TestImportSnapshotError(c *check.C) {
-	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader) (uint64, []string, error) {
+	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader, string) (uint64, []string, error) {
 		return uint64(0), nil, errors.New("no")
 	})()
 
@@ -447,7 +674,7 @@ func (s *snapshotSuite) TestImportSnapshotNoContentLengthError(c *check.C) {
 func (s *snapshotSuite) TestImportSnapshotLimits(c *check.C) {
 	var dataRead int
 
-	defer daemon.MockSnapshotImport(func(ctx context.Context, st *state.State, r io.Reader) (uint64, []string, error) {
+	defer daemon.MockSnapshotImport(func(ctx context.Context, st *state.State, r io.Reader, passphrase string) (uint64, []string, error) {
 		data, err := io.ReadAll(r)
 		c.Assert(err, check.IsNil)
 		dataRead = len(data)
@@ -0,0 +1,48 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"io"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/devicemgmtstate"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func MockDeviceMgmtMgrQueueInfo(f func(m *devicemgmtstate.DeviceMgmtManager) (*devicemgmtstate.QueueInfo, error)) func() {
+	return testutil.Mock(&deviceMgmtMgrQueueInfo, f)
+}
+
+func MockDeviceMgmtMgrDropSequence(f func(m *devicemgmtstate.DeviceMgmtManager, baseID string) (string, error)) func() {
+	return testutil.Mock(&deviceMgmtMgrDropSequence, f)
+}
+
+func MockDeviceMgmtMgrImportMessages(f func(m *devicemgmtstate.DeviceMgmtManager, r io.Reader) (string, error)) func() {
+	return testutil.Mock(&deviceMgmtMgrImportMessages, f)
+}
+
+func MockDeviceMgmtMgrExportResponses(f func(m *devicemgmtstate.DeviceMgmtManager) ([]*asserts.ResponseMessage, error)) func() {
+	return testutil.Mock(&deviceMgmtMgrExportResponses, f)
+}
+
+func MockDeviceMgmtMgrAcknowledgeResponses(f func(m *devicemgmtstate.DeviceMgmtManager, messageIDs []string) error) func() {
+	return testutil.Mock(&deviceMgmtMgrAcknowledgeResponses, f)
+}
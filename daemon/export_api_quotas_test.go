@@ -20,6 +20,8 @@
 package daemon
 
 import (
+	"time"
+
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -30,6 +32,8 @@ type (
 	PostQuotaGroupData = postQuotaGroupData
 )
 
+var GetQuotaUsage = getQuotaUsage
+
 func MockServicestateCreateQuota(f func(st *state.State, name string, createOpts servicestate.CreateQuotaOptions) (*state.TaskSet, error)) func() {
 	old := servicestateCreateQuota
 	servicestateCreateQuota = f
@@ -54,6 +58,14 @@ func MockServicestateRemoveQuota(f func(st *state.State, name string) (*state.Ta
 	}
 }
 
+func MockServicestateThawQuota(f func(st *state.State, name string) (*state.TaskSet, error)) func() {
+	old := servicestateThawQuota
+	servicestateThawQuota = f
+	return func() {
+		servicestateThawQuota = old
+	}
+}
+
 func MockGetQuotaUsage(f func(grp *quota.Group) (*client.QuotaValues, error)) (restore func()) {
 	old := getQuotaUsage
 	getQuotaUsage = f
@@ -61,3 +73,11 @@ func MockGetQuotaUsage(f func(grp *quota.Group) (*client.QuotaValues, error)) (r
 		getQuotaUsage = old
 	}
 }
+
+func MockServicestateQuotaUsageHistory(f func(group string, since time.Time) ([]servicestate.QuotaUsageSample, error)) (restore func()) {
+	old := servicestateQuotaUsageHistory
+	servicestateQuotaUsageHistory = f
+	return func() {
+		servicestateQuotaUsageHistory = old
+	}
+}
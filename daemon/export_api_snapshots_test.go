@@ -32,7 +32,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 )
 
-func MockSnapshotSave(newSave func(*state.State, []string, []string, map[string]*snap.SnapshotOptions) (uint64, []string, *state.TaskSet, error)) (restore func()) {
+func MockSnapshotSave(newSave func(*state.State, []string, []string, map[string]*snap.SnapshotOptions, string) (uint64, []string, *state.TaskSet, error)) (restore func()) {
 	oldSave := snapshotSave
 	snapshotSave = newSave
 	return func() {
@@ -80,7 +80,7 @@ func MockSnapshotForget(newForget func(*state.State, uint64, []string) ([]string
 	}
 }
 
-func MockSnapshotImport(newImport func(context.Context, *state.State, io.Reader) (uint64, []string, error)) (restore func()) {
+func MockSnapshotImport(newImport func(context.Context, *state.State, io.Reader, string) (uint64, []string, error)) (restore func()) {
 	oldImport := snapshotImport
 	snapshotImport = newImport
 	return func() {
@@ -88,6 +88,38 @@ func MockSnapshotImport(newImport func(context.Context, *state.State, io.Reader)
 	}
 }
 
+func MockSnapshotPush(newPush func(*state.State, uint64) (*state.TaskSet, error)) (restore func()) {
+	oldPush := snapshotPush
+	snapshotPush = newPush
+	return func() {
+		snapshotPush = oldPush
+	}
+}
+
+func MockSnapshotPull(newPull func(context.Context, *state.State, uint64, string) (uint64, []string, error)) (restore func()) {
+	oldPull := snapshotPull
+	snapshotPull = newPull
+	return func() {
+		snapshotPull = oldPull
+	}
+}
+
+func MockSnapshotSetPassphrase(newSetPassphrase func(*state.State, uint64, string)) (restore func()) {
+	oldSetPassphrase := snapshotSetPassphrase
+	snapshotSetPassphrase = newSetPassphrase
+	return func() {
+		snapshotSetPassphrase = oldSetPassphrase
+	}
+}
+
+func MockSnapshotCheckPassphrase(newCheckPassphrase func(*state.State, uint64, string) error) (restore func()) {
+	oldCheckPassphrase := snapshotCheckPassphrase
+	snapshotCheckPassphrase = newCheckPassphrase
+	return func() {
+		snapshotCheckPassphrase = oldCheckPassphrase
+	}
+}
+
 func MustUnmarshalSnapInstruction(c *check.C, jinst string) *snapInstruction {
 	var inst snapInstruction
 	if err := json.Unmarshal([]byte(jinst), &inst); err != nil {
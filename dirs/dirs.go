@@ -87,6 +87,7 @@ var (
 	SnapSeqDir            string
 
 	SnapStateFile     string
+	SnapStateDBFile   string
 	SnapStateLockFile string
 	SnapSystemKeyFile string
 
@@ -99,6 +100,8 @@ var (
 
 	SnapRollbackDir string
 
+	SnapSeclogDir string
+
 	SnapCacheDir        string
 	SnapNamesFile       string
 	SnapSectionsFile    string
@@ -391,6 +394,11 @@ func SnapStateFileUnder(rootdir string) string {
 	return filepath.Join(rootdir, snappyDir, "state.json")
 }
 
+// SnapStateDBFileUnder returns the path to snapd state database under rootdir.
+func SnapStateDBFileUnder(rootdir string) string {
+	return filepath.Join(rootdir, snappyDir, "state.db")
+}
+
 // SnapStateLockFileUnder returns the path to snapd state lock file under rootdir.
 func SnapStateLockFileUnder(rootdir string) string {
 	return filepath.Join(rootdir, snappyDir, "state.lock")
@@ -411,6 +419,12 @@ func SnapSystemParamsUnder(rootdir string) string {
 	return filepath.Join(rootdir, snappyDir, "system-params")
 }
 
+// SnapSeclogDirUnder returns the path to the security logging dir under
+// rootdir.
+func SnapSeclogDirUnder(rootdir string) string {
+	return filepath.Join(rootdir, snappyDir, "seclog")
+}
+
 // SnapSystemdConfDirUnder returns the path to the systemd conf dir under
 // rootdir.
 func SnapSystemdConfDirUnder(rootdir string) string {
@@ -624,6 +638,7 @@ func SetRootDir(rootdir string) {
 	SnapSeqDir = filepath.Join(rootdir, snappyDir, "sequence")
 
 	SnapStateFile = SnapStateFileUnder(rootdir)
+	SnapStateDBFile = SnapStateDBFileUnder(rootdir)
 	SnapStateLockFile = SnapStateLockFileUnder(rootdir)
 	SnapSystemKeyFile = filepath.Join(rootdir, snappyDir, "system-key")
 
@@ -655,6 +670,8 @@ func SetRootDir(rootdir string) {
 
 	SnapRollbackDir = filepath.Join(rootdir, snappyDir, "rollback")
 
+	SnapSeclogDir = SnapSeclogDirUnder(rootdir)
+
 	SnapBinariesDir = filepath.Join(SnapMountDir, "bin")
 	SnapServicesDir = SnapServicesDirUnder(rootdir)
 	SnapRuntimeServicesDir = SnapRuntimeServicesDirUnder(rootdir)
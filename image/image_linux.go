@@ -414,6 +414,9 @@ func (s *imageSeeder) setModelessDirs() error {
 	if osutil.FileExists(dirs.SnapStateFileUnder(s.rootDir)) {
 		return fmt.Errorf("cannot prepare seed over existing system or an already booted image, detected state file %s", dirs.SnapStateFileUnder(s.rootDir))
 	}
+	if osutil.FileExists(dirs.SnapStateDBFileUnder(s.rootDir)) {
+		return fmt.Errorf("cannot prepare seed over existing system or an already booted image, detected state database %s", dirs.SnapStateDBFileUnder(s.rootDir))
+	}
 	if snaps, _ := filepath.Glob(filepath.Join(dirs.SnapBlobDirUnder(s.rootDir), "*.snap")); len(snaps) > 0 {
 		return fmt.Errorf("expected empty snap dir in rootdir, got: %v", snaps)
 	}
@@ -68,7 +68,7 @@ func checkChroot(preseedChroot string) error {
 		return fmt.Errorf("cannot verify %q: is not a directory", preseedChroot)
 	}
 
-	if osutil.FileExists(filepath.Join(preseedChroot, dirs.SnapStateFile)) {
+	if osutil.FileExists(filepath.Join(preseedChroot, dirs.SnapStateFile)) || osutil.FileExists(filepath.Join(preseedChroot, dirs.SnapStateDBFile)) {
 		return fmt.Errorf("the system at %q appears to be preseeded, pass --reset flag to clean it up", preseedChroot)
 	}
 
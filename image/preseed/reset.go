@@ -97,6 +97,7 @@ var ResetPreseededChroot = func(preseedChroot string) error {
 	// globs that yield individual files
 	globs := []string{
 		dirs.SnapStateFile,
+		dirs.SnapStateDBFile,
 		dirs.SnapSystemKeyFile,
 		filepath.Join(dirs.SnapBlobDir, "*.snap"),
 		filepath.Join(dirs.SnapUdevRulesDir, "*-snap.*.rules"),
@@ -36,6 +36,9 @@ type commonFilesInterface struct {
 
 	apparmorHeader    string
 	extraPathValidate func(string) error
+	// prompt marks the access rules so that requests matching them can be
+	// prompted for when AppArmor prompting is enabled.
+	prompt bool
 }
 
 // filesAAPerm can either be files{Read,Write} and converted to a string
@@ -76,13 +79,13 @@ func formatPath(ip any) (string, error) {
 	return fmt.Sprintf("%s%q", prefix, p), nil
 }
 
-func allowPathAccess(buf *bytes.Buffer, perm filesAAPerm, paths []any) error {
+func allowPathAccess(buf *bytes.Buffer, rulePrefix string, perm filesAAPerm, paths []any) error {
 	for _, rawPath := range paths {
 		p, err := formatPath(rawPath)
 		if err != nil {
 			return err
 		}
-		fmt.Fprintf(buf, "%s %s,\n", p, perm)
+		fmt.Fprintf(buf, "%s%s %s,\n", rulePrefix, p, perm)
 	}
 	return nil
 }
@@ -161,11 +164,15 @@ func (iface *commonFilesInterface) AppArmorConnectedPlug(spec *apparmor.Specific
 	_ = plug.Attr("write", &writes)
 
 	errPrefix := fmt.Sprintf(`cannot connect plug %s: `, plug.Name())
+	rulePrefix := ""
+	if iface.prompt {
+		rulePrefix = "###PROMPT### "
+	}
 	buf := bytes.NewBufferString(iface.apparmorHeader)
-	if err := allowPathAccess(buf, filesRead, reads); err != nil {
+	if err := allowPathAccess(buf, rulePrefix, filesRead, reads); err != nil {
 		return fmt.Errorf("%s%v", errPrefix, err)
 	}
-	if err := allowPathAccess(buf, filesWrite, writes); err != nil {
+	if err := allowPathAccess(buf, rulePrefix, filesWrite, writes); err != nil {
 		return fmt.Errorf("%s%v", errPrefix, err)
 	}
 	spec.AddSnippet(buf.String())
@@ -19,6 +19,10 @@
 
 package builtin
 
+import (
+	"strings"
+)
+
 const removableMediaSummary = `allows access to mounted removable storage`
 
 const removableMediaBaseDeclarationSlots = `
@@ -41,14 +45,32 @@ const removableMediaConnectedPlugAppArmor = `
 
 # Mount points could be in /run/media/<user>/* or /media/<user>/*
 /{,run/}media/*/ r,
-/{,run/}media/*/** mrwklix,
+###PROMPT### /{,run/}media/*/** mrwklix,
 
 # Allow read-only access to /mnt to enumerate items.
 /mnt/ r,
 # Allow write access to anything under /mnt
-/mnt/** mrwklix,
+###PROMPT### /mnt/** mrwklix,
 `
 
+// RemovableMediaPaths are the paths under which the removable-media interface
+// grants access to mounted removable storage.
+var RemovableMediaPaths = []string{"/media", "/run/media", "/mnt"}
+
+// DetectRemovableMediaFromPath returns true if the given path corresponds to
+// an AppArmor rule with the prompt prefix from the removable-media interface.
+//
+// XXX: this is only necessary until metadata tags are fully supported by the
+// AppArmor parser and kernel. Then, this function should be removed.
+func DetectRemovableMediaFromPath(path string) bool {
+	for _, prefix := range []string{"/media/", "/run/media/", "/mnt/"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
 	registerIface(&commonInterface{
 		name:                  "removable-media",
@@ -79,7 +79,28 @@ func (s *RemovableMediaInterfaceSuite) TestUsedSecuritySystems(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(apparmorSpec.SecurityTags(), DeepEquals, []string{"snap.client-snap.other"})
 	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "/{,run/}media/*/ r")
-	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "/mnt/** mrwklix,")
+	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "###PROMPT### /{,run/}media/*/** mrwklix,")
+	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.other"), testutil.Contains, "###PROMPT### /mnt/** mrwklix,")
+}
+
+func (s *RemovableMediaInterfaceSuite) TestDetectRemovableMediaFromPath(c *C) {
+	for _, path := range []string{
+		"/media/ubuntu/usb/foo",
+		"/run/media/ubuntu/usb/foo",
+		"/mnt/foo",
+	} {
+		c.Check(builtin.DetectRemovableMediaFromPath(path), Equals, true, Commentf("%q should be detected as removable media path", path))
+	}
+
+	for _, path := range []string{
+		"/media",
+		"/mnt",
+		"/mntfoo/bar",
+		"/run/user/1000/foo",
+		"/home/ubuntu/media/foo",
+	} {
+		c.Check(builtin.DetectRemovableMediaFromPath(path), Equals, false, Commentf("%q should not be detected as removable media path", path))
+	}
 }
 
 func (s *RemovableMediaInterfaceSuite) TestInterfaces(c *C) {
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
@@ -73,7 +74,7 @@ type InterfaceSpecificConstraints interface {
 func parseInterfaceSpecificConstraints(iface string, constraintsJSON ConstraintsJSON, isPatch bool) (InterfaceSpecificConstraints, error) {
 	var interfaceSpecific InterfaceSpecificConstraints
 	switch iface {
-	case "home":
+	case "home", "removable-media", "personal-files":
 		interfaceSpecific = &InterfaceSpecificConstraintsHome{}
 	case "camera", "audio-record":
 		interfaceSpecific = &InterfaceSpecificConstraintsEmpty{}
@@ -92,6 +93,9 @@ func parseInterfaceSpecificConstraints(iface string, constraintsJSON Constraints
 	return interfaceSpecific, nil
 }
 
+// InterfaceSpecificConstraintsHome hold a path pattern. These should be used
+// for the home interface and other interfaces which grant access to files, such
+// as the removable-media and personal-files interfaces.
 type InterfaceSpecificConstraintsHome struct {
 	Pattern *patterns.PathPattern
 }
@@ -480,6 +484,15 @@ func UnmarshalRuleConstraintsPatch(iface string, constraintsJSON ConstraintsJSON
 	return constraints, nil
 }
 
+// PathPattern returns the PathPattern provided by the interface-specific
+// constraints of the patch, or nil if the patch leaves it unchanged.
+func (c *RuleConstraintsPatch) PathPattern() *patterns.PathPattern {
+	if c.InterfaceSpecific == nil {
+		return nil
+	}
+	return c.InterfaceSpecific.pathPattern()
+}
+
 // PatchRuleConstraints validates the receiving RuleConstraintsPatch and uses
 // the given existing rule constraints to construct a new RuleConstraints.
 func (c *RuleConstraintsPatch) PatchRuleConstraints(existing *RuleConstraints, at At) (*RuleConstraints, error) {
@@ -888,9 +901,11 @@ var (
 	// List of permissions available for each interface. This also defines the
 	// order in which the permissions should be presented.
 	interfacePermissionsAvailable = map[string][]string{
-		"home":         {"read", "write", "execute"},
-		"camera":       {"access"},
-		"audio-record": {"access"},
+		"home":            {"read", "write", "execute"},
+		"camera":          {"access"},
+		"audio-record":    {"access"},
+		"removable-media": {"read", "write", "execute"},
+		"personal-files":  {"read", "write"},
 	}
 
 	// A mapping from interfaces which support AppArmor file permissions to
@@ -908,14 +923,75 @@ var (
 		"camera": {
 			"access": notify.AA_MAY_READ | notify.AA_MAY_GETATTR | notify.AA_MAY_WRITE | notify.AA_MAY_APPEND,
 		},
+		"removable-media": {
+			"read":    notify.AA_MAY_READ | notify.AA_MAY_GETATTR,
+			"write":   notify.AA_MAY_WRITE | notify.AA_MAY_APPEND | notify.AA_MAY_CREATE | notify.AA_MAY_DELETE | notify.AA_MAY_RENAME | notify.AA_MAY_SETATTR | notify.AA_MAY_CHMOD | notify.AA_MAY_LOCK | notify.AA_MAY_LINK,
+			"execute": notify.AA_MAY_EXEC | notify.AA_EXEC_MMAP,
+		},
+		"personal-files": {
+			"read":  notify.AA_MAY_READ | notify.AA_MAY_GETATTR,
+			"write": notify.AA_MAY_WRITE | notify.AA_MAY_APPEND | notify.AA_MAY_CREATE | notify.AA_MAY_DELETE | notify.AA_MAY_RENAME | notify.AA_MAY_SETATTR | notify.AA_MAY_CHMOD | notify.AA_MAY_LOCK | notify.AA_MAY_LINK,
+		},
 	}
 
 	// Some interfaces do not define AppArmor rules, and thus requests for that
 	// interface are not created by the listener, and permissions do not map to
 	// AppArmor permissions.
 	nonAppArmorInterfaces = []string{"audio-record"}
+
+	// Some interfaces only grant access to the paths declared by the plugs of
+	// a snap, so the path patterns of their rules must be constrained to
+	// those paths, and prompts for them carry those paths.
+	plugPathsInterfaces = []string{"removable-media", "personal-files"}
 )
 
+// UsesPlugPaths returns true if the given interface only grants access to the
+// paths declared by the connected plugs of a snap.
+func UsesPlugPaths(iface string) bool {
+	return strutil.ListContains(plugPathsInterfaces, iface)
+}
+
+// ValidatePathPatternForPlugPaths checks that every path matched by the given
+// path pattern is one of the given plug paths, or is within one of them.
+//
+// The plug paths are literal paths, as declared by the plugs of a snap with
+// any variables expanded.
+func ValidatePathPatternForPlugPaths(pattern *patterns.PathPattern, plugPaths []string) error {
+	if len(plugPaths) == 0 {
+		return prompting_errors.NewInvalidPathPatternError(pattern.String(), "no paths declared by connected plugs")
+	}
+	var outside bool
+	pattern.RenderAllVariants(func(index int, variant patterns.PatternVariant) {
+		if outside {
+			return
+		}
+		v := variant.String()
+		for _, plugPath := range plugPaths {
+			escaped := patterns.EscapeLiteralPath(strings.TrimSuffix(plugPath, "/"))
+			if v == escaped || strings.HasPrefix(v, escaped+"/") {
+				return
+			}
+		}
+		outside = true
+	})
+	if outside {
+		return prompting_errors.NewInvalidPathPatternError(pattern.String(), fmt.Sprintf("pattern matches paths outside of those declared by connected plugs (%s)", strings.Join(plugPaths, ", ")))
+	}
+	return nil
+}
+
+// PathWithinPlugPaths returns true if the given path is one of the given plug
+// paths, or is within one of them.
+func PathWithinPlugPaths(path string, plugPaths []string) bool {
+	for _, plugPath := range plugPaths {
+		plugPath = strings.TrimSuffix(plugPath, "/")
+		if path == plugPath || strings.HasPrefix(path, plugPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // availableInterfaces returns the list of supported interfaces.
 func availableInterfaces() []string {
 	interfaces := make([]string, 0, len(interfacePermissionsAvailable))
@@ -937,6 +1013,28 @@ func AvailablePermissions(iface string) ([]string, error) {
 	return available, nil
 }
 
+// ValidatePermissions checks that the given interface is supported and that
+// the given permissions are not empty and are all available for it.
+func ValidatePermissions(iface string, permissions []string) error {
+	availablePerms, ok := interfacePermissionsAvailable[iface]
+	if !ok {
+		return prompting_errors.NewInvalidInterfaceError(iface, availableInterfaces())
+	}
+	if len(permissions) == 0 {
+		return prompting_errors.NewPermissionsEmptyError(iface, availablePerms)
+	}
+	var invalidPerms []string
+	for _, perm := range permissions {
+		if !strutil.ListContains(availablePerms, perm) {
+			invalidPerms = append(invalidPerms, perm)
+		}
+	}
+	if len(invalidPerms) > 0 {
+		return prompting_errors.NewInvalidPermissionsError(iface, invalidPerms, availablePerms)
+	}
+	return nil
+}
+
 // abstractPermissionsFromAppArmorPermissions returns the list of permissions
 // corresponding to the given AppArmor permissions for the given interface.
 func abstractPermissionsFromAppArmorPermissions(iface string, permissions notify.AppArmorPermission) ([]string, error) {
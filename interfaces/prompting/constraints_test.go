@@ -147,6 +147,24 @@ func (s *constraintsSuite) TestParseInterfaceSpecificConstraintsHappy(c *C) {
 			},
 			expectedPathPattern: mustParsePathPattern(c, "/home/you/**/*.pdf"),
 		},
+		{
+			iface: "removable-media",
+			constraintsJSON: prompting.ConstraintsJSON{
+				"path-pattern": json.RawMessage(`"/media/test/usb/**"`),
+			},
+			isPatch: false,
+			expected: &prompting.InterfaceSpecificConstraintsHome{
+				Pattern: mustParsePathPattern(c, "/media/test/usb/**"),
+			},
+			expectedPathPattern: mustParsePathPattern(c, "/media/test/usb/**"),
+		},
+		{
+			iface:               "personal-files",
+			constraintsJSON:     prompting.ConstraintsJSON{},
+			isPatch:             true,
+			expected:            &prompting.InterfaceSpecificConstraintsHome{},
+			expectedPathPattern: nil,
+		},
 		{
 			iface:               "camera",
 			constraintsJSON:     prompting.ConstraintsJSON{},
@@ -2408,6 +2426,21 @@ func (s *constraintsSuite) TestAvailablePermissions(c *C) {
 	c.Check(available, IsNil)
 }
 
+func (s *constraintsSuite) TestValidatePermissions(c *C) {
+	c.Check(prompting.ValidatePermissions("home", []string{"read", "write"}), IsNil)
+	c.Check(prompting.ValidatePermissions("camera", []string{"access"}), IsNil)
+
+	err := prompting.ValidatePermissions("foo", []string{"read"})
+	c.Check(err, ErrorMatches, `invalid interface: "foo"`)
+	c.Check(err, testutil.ErrorIs, prompting_errors.ErrUnsupportedValue)
+	err = prompting.ValidatePermissions("home", nil)
+	c.Check(err, ErrorMatches, "invalid permissions for home interface: permissions empty")
+	c.Check(err, testutil.ErrorIs, prompting_errors.ErrUnsupportedValue)
+	err = prompting.ValidatePermissions("home", []string{"read", "access", "foo"})
+	c.Check(err, ErrorMatches, `invalid permissions for home interface: "access", "foo"`)
+	c.Check(err, testutil.ErrorIs, prompting_errors.ErrUnsupportedValue)
+}
+
 func (s *constraintsSuite) TestAbstractPermissionsFromAppArmorPermissionsHappy(c *C) {
 	cases := []struct {
 		iface string
@@ -2444,6 +2477,16 @@ func (s *constraintsSuite) TestAbstractPermissionsFromAppArmorPermissionsHappy(c
 			notify.AA_MAY_EXEC | notify.AA_MAY_WRITE | notify.AA_MAY_READ,
 			[]string{"read", "write", "execute"},
 		},
+		{
+			"removable-media",
+			notify.AA_MAY_EXEC | notify.AA_MAY_WRITE | notify.AA_MAY_READ,
+			[]string{"read", "write", "execute"},
+		},
+		{
+			"personal-files",
+			notify.AA_MAY_OPEN | notify.AA_MAY_WRITE | notify.AA_MAY_READ,
+			[]string{"read", "write"},
+		},
 		{
 			"camera",
 			notify.AA_MAY_OPEN,
@@ -2630,3 +2673,67 @@ func (s *constraintsSuite) TestAbstractPermissionsToAppArmorPermissionsUnhappy(c
 		c.Check(err, ErrorMatches, testCase.errStr)
 	}
 }
+
+func (s *constraintsSuite) TestUsesPlugPaths(c *C) {
+	for _, iface := range []string{"removable-media", "personal-files"} {
+		c.Check(prompting.UsesPlugPaths(iface), Equals, true, Commentf("iface: %s", iface))
+	}
+	for _, iface := range []string{"home", "camera", "audio-record", "foo"} {
+		c.Check(prompting.UsesPlugPaths(iface), Equals, false, Commentf("iface: %s", iface))
+	}
+}
+
+func (s *constraintsSuite) TestValidatePathPatternForPlugPaths(c *C) {
+	plugPaths := []string{"/home/test/.config/foo", "/home/test/.local/share/foo.db", "/mnt/"}
+	for _, pattern := range []string{
+		"/home/test/.config/foo",
+		"/home/test/.config/foo/",
+		"/home/test/.config/foo/**",
+		"/home/test/.config/foo/*/bar.{json,yaml}",
+		"/home/test/.local/share/foo.db",
+		"/home/test/{.config/foo/**,.local/share/foo.db}",
+		"/mnt",
+		"/mnt/**",
+	} {
+		err := prompting.ValidatePathPatternForPlugPaths(mustParsePathPattern(c, pattern), plugPaths)
+		c.Check(err, IsNil, Commentf("pattern: %s", pattern))
+	}
+
+	for _, pattern := range []string{
+		"/home/test/.config/**",
+		"/home/test/.config/foo*",
+		"/home/test/.config/foobar",
+		"/home/test/.local/share/foo.db-journal",
+		"/home/test/{.config/foo/**,.bashrc}",
+		"/mnt*",
+		"/**",
+	} {
+		err := prompting.ValidatePathPatternForPlugPaths(mustParsePathPattern(c, pattern), plugPaths)
+		c.Check(err, ErrorMatches, `invalid path pattern: pattern matches paths outside of those declared by connected plugs \(/home/test/.config/foo, /home/test/.local/share/foo.db, /mnt/\): ".*"`, Commentf("pattern: %s", pattern))
+	}
+
+	err := prompting.ValidatePathPatternForPlugPaths(mustParsePathPattern(c, "/mnt/**"), nil)
+	c.Check(err, ErrorMatches, `invalid path pattern: no paths declared by connected plugs: "/mnt/\*\*"`)
+}
+
+func (s *constraintsSuite) TestPathWithinPlugPaths(c *C) {
+	plugPaths := []string{"/home/test/.config/foo", "/home/test/.local/share/foo.db", "/mnt/"}
+	for _, path := range []string{
+		"/home/test/.config/foo",
+		"/home/test/.config/foo/bar/baz.json",
+		"/home/test/.local/share/foo.db",
+		"/mnt",
+		"/mnt/disk/file",
+	} {
+		c.Check(prompting.PathWithinPlugPaths(path, plugPaths), Equals, true, Commentf("path: %s", path))
+	}
+	for _, path := range []string{
+		"/home/test/.config",
+		"/home/test/.config/foobar",
+		"/home/test/.local/share/foo.db-journal",
+		"/mnt2/file",
+	} {
+		c.Check(prompting.PathWithinPlugPaths(path, plugPaths), Equals, false, Commentf("path: %s", path))
+	}
+	c.Check(prompting.PathWithinPlugPaths("/mnt/disk", nil), Equals, false)
+}
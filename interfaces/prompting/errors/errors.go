@@ -39,6 +39,8 @@ var (
 	// Validation errors which may be returned over the API
 	ErrPatchedRuleHasNoPerms   = errors.New("cannot patch rule to have no permissions")
 	ErrNewSessionRuleNoSession = errors.New(`cannot create rule with lifespan "session" when user session is not present`)
+	ErrRuleIsPolicy            = errors.New("cannot modify or remove a policy rule set by the administrator")
+	ErrRuleShadowedByPolicy    = errors.New("rule has no effect, since policy rules set by the administrator take precedence over it")
 
 	// Validation errors which should never be used directly apart from
 	// checking errors.Is(), and should otherwise always be wrapped in
@@ -70,8 +70,19 @@ type Request struct {
 	// and the rules backend, so interfaces without paths can be handled more
 	// ergonomically.
 	Path string
+	// PlugPaths are the paths declared by the connected plugs of the snap for
+	// interfaces which only grant access to those paths, such as
+	// "personal-files". It is set by the manager handling the request.
+	PlugPaths []string
 	// Reply causes a reply to be sent back to the originator of this request.
 	Reply func(allowedPermissions []string) error
+
+	// listenerID is the ID of the notification from which a request received
+	// from the listener was built.
+	listenerID uint64
+	// aaRequested is the AppArmor permissions requested by the notification
+	// from which a request received from the listener was built.
+	aaRequested notify.AppArmorPermission
 }
 
 // NewRequestFromListener parses the given [notify.MsgNotificationGeneric] into
@@ -98,12 +109,19 @@ func NewRequestFromListener(msg notify.MsgNotificationGeneric, sendResponse list
 			return nil, fmt.Errorf("cannot select interface from metadata tags: %w", err)
 		}
 		// There were no tags registered with a snapd interface, so we
-		// look at the path to decide whether it's "home" or "camera".
+		// look at the path to decide whether it's "home", "camera" or
+		// "removable-media". Requests for "personal-files" look like
+		// those for "home" here, and are reclassified through
+		// [Request.SetInterface] by the manager, which knows the paths
+		// declared by the plugs of the snap.
 		// XXX: this is a temporary workaround until metadata tags are
 		// supported by the AppArmor parser and kernel.
-		if builtin.DetectCameraFromPath(path) {
+		switch {
+		case builtin.DetectCameraFromPath(path):
 			iface = "camera"
-		} else {
+		case builtin.DetectRemovableMediaFromPath(path):
+			iface = "removable-media"
+		default:
 			iface = "home"
 		}
 	}
@@ -131,9 +149,11 @@ func NewRequestFromListener(msg notify.MsgNotificationGeneric, sendResponse list
 		Interface:   iface,
 		Permissions: requestedPerms,
 		Path:        path,
+		listenerID:  id,
+		aaRequested: aaRequested,
 	}
 	req.Reply = func(allowedPermissions []string) error {
-		userAllowed, err := abstractPermissionsToAppArmorPermissions(iface, allowedPermissions)
+		userAllowed, err := abstractPermissionsToAppArmorPermissions(req.Interface, allowedPermissions)
 		if err != nil {
 			return err
 		}
@@ -149,6 +169,23 @@ func NewRequestFromListener(msg notify.MsgNotificationGeneric, sendResponse list
 	return req, nil
 }
 
+// SetInterface associates the given interface with a request received from the
+// listener, whose interface could not be told apart from the path alone, and
+// updates the key and abstract permissions of the request to match it.
+func (r *Request) SetInterface(iface string) error {
+	if r.aaRequested == nil {
+		return fmt.Errorf("internal error: cannot change the interface of a request not received from the listener")
+	}
+	requestedPerms, err := abstractPermissionsFromAppArmorPermissions(iface, r.aaRequested)
+	if err != nil {
+		return err
+	}
+	r.Key = buildListenerRequestKey(iface, r.listenerID)
+	r.Interface = iface
+	r.Permissions = requestedPerms
+	return nil
+}
+
 func buildListenerRequestKey(iface string, id uint64) string {
 	return fmt.Sprintf("kernel:%s:%016X", iface, id)
 }
@@ -247,6 +247,31 @@ func (s *promptingSuite) TestNewRequestFromListenerInterfaceSelection(c *C) {
 			},
 			"camera",
 		},
+		{
+			"/media/test/usb/foo",
+			func(tag string) (string, bool) {
+				return "", false
+			},
+			"removable-media",
+		},
+		{
+			"/mnt/foo",
+			func(tag string) (string, bool) {
+				return "", false
+			},
+			"removable-media",
+		},
+		{
+			"/home/test/.config/foo",
+			func(tag string) (string, bool) {
+				switch tag {
+				case "tag1", "tag3", "tag4":
+					return "personal-files", true
+				}
+				return "", false
+			},
+			"personal-files",
+		},
 	} {
 		restore := prompting.MockApparmorInterfaceForMetadataTag(testCase.ifaceForTag)
 		defer restore()
@@ -377,6 +402,58 @@ func (s *promptingSuite) TestNewRequestFromListenerReply(c *C) {
 	c.Check(err, ErrorMatches, "failed to send response")
 }
 
+func (s *promptingSuite) TestRequestSetInterface(c *C) {
+	var (
+		protoVersion = notify.ProtocolVersion(5)
+		id           = uint64(0x1234)
+		label        = "snap.firefox.firefox"
+		path         = "/home/test/.config/foo/prefs.js"
+		aBits        = uint32(0)
+		dBits        = uint32(0b0110) // read, write
+	)
+
+	var sentUserAllowed notify.AppArmorPermission
+	fakeSendResponse := func(recvID uint64, recvAaAllowed, recvAaRequested, userAllowed notify.AppArmorPermission) error {
+		c.Check(recvID, Equals, id)
+		c.Check(recvAaRequested, Equals, notify.FilePermission(dBits))
+		sentUserAllowed = userAllowed
+		return nil
+	}
+
+	msg := newMsgNotificationFile(protoVersion, id, label, path, aBits, dBits, nil)
+	req, err := prompting.NewRequestFromListener(msg, fakeSendResponse)
+	c.Assert(err, IsNil)
+	c.Check(req.Interface, Equals, "home")
+	c.Check(req.Key, Equals, fmt.Sprintf("kernel:home:%016X", id))
+
+	err = req.SetInterface("personal-files")
+	c.Assert(err, IsNil)
+	c.Check(req.Interface, Equals, "personal-files")
+	c.Check(req.Key, Equals, fmt.Sprintf("kernel:personal-files:%016X", id))
+	c.Check(req.Permissions, DeepEquals, []string{"read", "write"})
+	c.Check(req.Path, Equals, path)
+
+	// The reply is mapped through the new interface
+	err = req.Reply([]string{"write"})
+	c.Check(err, IsNil)
+	c.Check(sentUserAllowed, Equals, notify.AA_MAY_OPEN|prompting.InterfaceFilePermissionsMaps["personal-files"]["write"])
+	err = req.Reply([]string{"execute"})
+	c.Check(err, ErrorMatches, `cannot map abstract permission to AppArmor permissions for the personal-files interface: "execute"`)
+
+	// Interfaces to which the permissions cannot be mapped leave the request
+	// unchanged
+	err = req.SetInterface("audio-record")
+	c.Check(err, ErrorMatches, "cannot map the given interface to map from abstract permissions to AppArmor permissions: audio-record")
+	c.Check(req.Interface, Equals, "personal-files")
+	c.Check(req.Key, Equals, fmt.Sprintf("kernel:personal-files:%016X", id))
+
+	// Requests not received from the listener cannot change interface
+	askReq, err := prompting.NewRequestFromAsk(1000, "audio-record", "firefox", 1234, "some-cgroup-path", nil)
+	c.Assert(err, IsNil)
+	err = askReq.SetInterface("personal-files")
+	c.Check(err, ErrorMatches, "internal error: cannot change the interface of a request not received from the listener")
+}
+
 func (s *promptingSuite) TestNewRequestFromListenerErrors(c *C) {
 	var (
 		aBits = uint32(0b1010) // write (and append)
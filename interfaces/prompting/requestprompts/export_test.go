@@ -71,3 +71,13 @@ func (pdb *PromptDB) NextID() (prompting.IDType, error) {
 func MockTimeAfterFunc(f func(d time.Duration, callback func()) timeutil.Timer) (restore func()) {
 	return testutil.Mock(&timeAfterFunc, f)
 }
+
+func MockMaxHistoryFileSize(size int64) (restore func()) {
+	return testutil.Mock(&maxHistoryFileSize, size)
+}
+
+func (pdb *PromptDB) RecordHistory(user uint32, entry *HistoryEntry) {
+	pdb.mutex.Lock()
+	defer pdb.mutex.Unlock()
+	pdb.recordHistory(user, entry)
+}
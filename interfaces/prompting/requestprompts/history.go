@@ -0,0 +1,229 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestprompts
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/logger"
+)
+
+// Resolutions of prompts recorded in the history.
+const (
+	// ResolutionReplied is the resolution of a prompt which received a reply
+	// from the user.
+	ResolutionReplied = "replied"
+	// ResolutionSatisfied is the resolution of a prompt which was satisfied
+	// by a new rule.
+	ResolutionSatisfied = "satisfied"
+	// ResolutionExpired is the resolution of a prompt which expired before
+	// being replied to, and was thus denied.
+	ResolutionExpired = "expired"
+)
+
+// maxHistoryEntrySize is the maximum size of a single line of the history
+// file which is read back. Entries are far smaller than this in practice.
+const maxHistoryEntrySize = 64 * 1024
+
+// maxHistoryFileSize is the size past which the history file of a user is
+// rotated. The history thus holds between one and two files worth of the
+// most recent entries.
+var maxHistoryFileSize int64 = 1024 * 1024
+
+// HistoryEntry records how a prompt was resolved: the reply it received, the
+// permissions which were granted and denied as a result, and the rule, if
+// any, which was created by the reply or which satisfied the prompt.
+type HistoryEntry struct {
+	Timestamp            time.Time              `json:"timestamp"`
+	PromptID             prompting.IDType       `json:"prompt-id"`
+	Snap                 string                 `json:"snap"`
+	Interface            string                 `json:"interface"`
+	Path                 string                 `json:"path"`
+	RequestedPermissions []string               `json:"requested-permissions"`
+	Resolution           string                 `json:"resolution"`
+	Outcome              prompting.OutcomeType  `json:"outcome"`
+	Lifespan             prompting.LifespanType `json:"lifespan,omitempty"`
+	AllowedPermissions   []string               `json:"allowed-permissions"`
+	DeniedPermissions    []string               `json:"denied-permissions"`
+	RuleID               prompting.IDType       `json:"rule-id,omitempty"`
+}
+
+// HistoryFilter selects history entries. Empty fields match any entry.
+type HistoryFilter struct {
+	Snap      string
+	Interface string
+	// Since excludes entries recorded before the given time.
+	Since time.Time
+	// Until excludes entries recorded after the given time.
+	Until time.Time
+}
+
+func (f *HistoryFilter) matches(entry *HistoryEntry) bool {
+	if f == nil {
+		return true
+	}
+	if f.Snap != "" && entry.Snap != f.Snap {
+		return false
+	}
+	if f.Interface != "" && entry.Interface != f.Interface {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// newHistoryEntry returns a history entry for the given prompt which was
+// resolved with the given outcome, where the given permissions were denied
+// and all the other originally requested permissions were allowed.
+func newHistoryEntry(prompt *Prompt, resolution string, outcome prompting.OutcomeType, deniedPermissions []string) *HistoryEntry {
+	allowedPermissions := prompt.Constraints.buildResponse(deniedPermissions)
+	return &HistoryEntry{
+		Timestamp:            time.Now(),
+		PromptID:             prompt.ID,
+		Snap:                 prompt.Snap,
+		Interface:            prompt.Interface,
+		Path:                 prompt.Constraints.Path(),
+		RequestedPermissions: append([]string{}, prompt.Constraints.originalPermissions...),
+		Resolution:           resolution,
+		Outcome:              outcome,
+		AllowedPermissions:   append([]string{}, allowedPermissions...),
+		DeniedPermissions:    append([]string{}, deniedPermissions...),
+	}
+}
+
+// historyFilepath returns the path of the history file of the given user.
+func (pdb *PromptDB) historyFilepath(user uint32) string {
+	return filepath.Join(pdb.historyDir, strconv.FormatUint(uint64(user), 10)+".json")
+}
+
+// rotatedHistoryFilepath returns the path of the history file of the given
+// user which holds the entries preceding those in its current history file.
+func (pdb *PromptDB) rotatedHistoryFilepath(user uint32) string {
+	return pdb.historyFilepath(user) + ".1"
+}
+
+// recordHistory appends the given entry to the history file of the given
+// user. Entries are never modified, but the oldest ones are dropped as the
+// history file is rotated once it grows past maxHistoryFileSize.
+//
+// Errors are logged rather than returned, since by the time an entry is
+// recorded the prompt was already resolved.
+//
+// The caller must ensure that the database lock is held for writing.
+func (pdb *PromptDB) recordHistory(user uint32, entry *HistoryEntry) {
+	if err := pdb.appendHistory(user, entry); err != nil {
+		logger.Noticef("cannot record history of prompt %s: %v", entry.PromptID, err)
+	}
+}
+
+func (pdb *PromptDB) appendHistory(user uint32, entry *HistoryEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if err := os.MkdirAll(pdb.historyDir, 0o700); err != nil {
+		return fmt.Errorf("cannot create history directory: %w", err)
+	}
+	path := pdb.historyFilepath(user)
+	if fi, err := os.Stat(path); err == nil && fi.Size()+int64(len(b)) > maxHistoryFileSize {
+		// this replaces the previously rotated file
+		if err := os.Rename(path, pdb.rotatedHistoryFilepath(user)); err != nil {
+			return fmt.Errorf("cannot rotate history file: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// History returns the entries of the history of the given user which match
+// the given filter, from oldest to newest.
+func (pdb *PromptDB) History(user uint32, filter *HistoryFilter) ([]*HistoryEntry, error) {
+	pdb.mutex.RLock()
+	defer pdb.mutex.RUnlock()
+
+	if pdb.isClosed() {
+		return nil, prompting_errors.ErrPromptingClosed
+	}
+
+	var entries []*HistoryEntry
+	for _, path := range []string{pdb.rotatedHistoryFilepath(user), pdb.historyFilepath(user)} {
+		var err error
+		entries, err = readHistoryFile(path, filter, entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// readHistoryFile appends to the given entries those of the history file at
+// the given path which match the given filter.
+func readHistoryFile(path string, filter *HistoryFilter, entries []*HistoryEntry) ([]*HistoryEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("cannot open prompt history: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxHistoryEntrySize)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line may have been partially written if snapd was
+			// interrupted while recording it, so skip it.
+			logger.Noticef("cannot decode prompt history entry, skipping: %v", err)
+			continue
+		}
+		if filter.matches(&entry) {
+			entries = append(entries, &entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read prompt history: %w", err)
+	}
+	return entries, nil
+}
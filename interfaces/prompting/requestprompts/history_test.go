@@ -0,0 +1,347 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestprompts_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/patterns"
+	"github.com/snapcore/snapd/interfaces/prompting/requestprompts"
+	"github.com/snapcore/snapd/testtime"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timeutil"
+)
+
+func (s *requestpromptsSuite) historyPath(user uint32) string {
+	return filepath.Join(dirs.SnapInterfacesRequestsStateDir, "history", fmt.Sprintf("%d.json", user))
+}
+
+func (s *requestpromptsSuite) TestHistoryReply(c *C) {
+	restore := requestprompts.MockTimeAfterFunc(func(d time.Duration, f func()) timeutil.Timer {
+		return testtime.AfterFunc(d, f)
+	})
+	defer restore()
+
+	pdb, err := requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	defer pdb.Close()
+
+	// No history yet
+	history, err := pdb.History(s.defaultUser, nil)
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+
+	metadata := &prompting.Metadata{
+		User:      s.defaultUser,
+		Snap:      "firefox",
+		PID:       123,
+		Cgroup:    "some-cgroup-path",
+		Interface: "home",
+	}
+	clientActivity := false
+
+	before := time.Now()
+
+	// Deny a prompt for which some permissions were already allowed
+	req1, replyChan1 := newRequestWithReplyChan("fake:1")
+	prompt1, _, err := pdb.AddOrMerge(metadata, "/home/test/foo", []string{"read", "write"}, []string{"write"}, req1)
+	c.Assert(err, IsNil)
+	_, err = pdb.Reply(s.defaultUser, prompt1.ID, prompting.OutcomeDeny, prompting.LifespanSingle, 0, clientActivity)
+	c.Assert(err, IsNil)
+	c.Check(waitForReply(c, replyChan1), DeepEquals, []string{"read"})
+
+	// Allow a prompt with a reply which created a rule
+	req2, replyChan2 := newRequestWithReplyChan("fake:2")
+	prompt2, _, err := pdb.AddOrMerge(metadata, "/home/test/bar", []string{"read"}, []string{"read"}, req2)
+	c.Assert(err, IsNil)
+	_, err = pdb.Reply(s.defaultUser, prompt2.ID, prompting.OutcomeAllow, prompting.LifespanForever, 0x1234, clientActivity)
+	c.Assert(err, IsNil)
+	c.Check(waitForReply(c, replyChan2), DeepEquals, []string{"read"})
+
+	history, err = pdb.History(s.defaultUser, nil)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	for _, entry := range history {
+		c.Check(entry.Timestamp.Before(before), Equals, false)
+		c.Check(entry.Snap, Equals, "firefox")
+		c.Check(entry.Interface, Equals, "home")
+		c.Check(entry.Resolution, Equals, requestprompts.ResolutionReplied)
+	}
+
+	c.Check(history[0].PromptID, Equals, prompt1.ID)
+	c.Check(history[0].Path, Equals, "/home/test/foo")
+	c.Check(history[0].RequestedPermissions, DeepEquals, []string{"read", "write"})
+	c.Check(history[0].Outcome, Equals, prompting.OutcomeDeny)
+	c.Check(history[0].Lifespan, Equals, prompting.LifespanSingle)
+	c.Check(history[0].AllowedPermissions, DeepEquals, []string{"read"})
+	c.Check(history[0].DeniedPermissions, DeepEquals, []string{"write"})
+	c.Check(history[0].RuleID, Equals, prompting.IDType(0))
+
+	c.Check(history[1].PromptID, Equals, prompt2.ID)
+	c.Check(history[1].Path, Equals, "/home/test/bar")
+	c.Check(history[1].Outcome, Equals, prompting.OutcomeAllow)
+	c.Check(history[1].Lifespan, Equals, prompting.LifespanForever)
+	c.Check(history[1].AllowedPermissions, DeepEquals, []string{"read"})
+	c.Check(history[1].DeniedPermissions, DeepEquals, []string{})
+	c.Check(history[1].RuleID, Equals, prompting.IDType(0x1234))
+
+	// The history is only readable by root
+	fi, err := os.Stat(s.historyPath(s.defaultUser))
+	c.Assert(err, IsNil)
+	c.Check(fi.Mode().Perm(), Equals, os.FileMode(0o600))
+
+	// The history of other users is separate
+	history, err = pdb.History(s.defaultUser+1, nil)
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+
+	// The history persists across restarts
+	c.Assert(pdb.Close(), IsNil)
+	pdb, err = requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	history, err = pdb.History(s.defaultUser, nil)
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 2)
+}
+
+func (s *requestpromptsSuite) TestHistorySatisfied(c *C) {
+	restore := requestprompts.MockTimeAfterFunc(func(d time.Duration, f func()) timeutil.Timer {
+		return testtime.AfterFunc(d, f)
+	})
+	defer restore()
+
+	pdb, err := requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	defer pdb.Close()
+
+	metadata := &prompting.Metadata{
+		User:      s.defaultUser,
+		Snap:      "firefox",
+		Interface: "home",
+	}
+	req1, replyChan1 := newRequestWithReplyChan("fake:1")
+	prompt1, _, err := pdb.AddOrMerge(metadata, "/home/test/foo", []string{"read", "write"}, []string{"read", "write"}, req1)
+	c.Assert(err, IsNil)
+	req2, replyChan2 := newRequestWithReplyChan("fake:2")
+	prompt2, _, err := pdb.AddOrMerge(metadata, "/home/test/foo", []string{"read"}, []string{"read"}, req2)
+	c.Assert(err, IsNil)
+
+	pathPattern, err := patterns.ParsePathPattern("/home/test/**")
+	c.Assert(err, IsNil)
+	constraints := &prompting.RuleConstraints{
+		InterfaceSpecific: &prompting.InterfaceSpecificConstraintsHome{
+			Pattern: pathPattern,
+		},
+		Permissions: prompting.RulePermissionMap{
+			"read":  &prompting.RulePermissionEntry{Outcome: prompting.OutcomeAllow},
+			"write": &prompting.RulePermissionEntry{Outcome: prompting.OutcomeDeny},
+		},
+	}
+	satisfied, err := pdb.HandleNewRule(metadata, 0xabcd, constraints)
+	c.Assert(err, IsNil)
+	c.Check(satisfied, HasLen, 2)
+	waitForReplies(c, replyChan1, replyChan2)
+
+	history, err := pdb.History(s.defaultUser, nil)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	byPrompt := make(map[prompting.IDType]*requestprompts.HistoryEntry)
+	for _, entry := range history {
+		c.Check(entry.Resolution, Equals, requestprompts.ResolutionSatisfied)
+		c.Check(entry.RuleID, Equals, prompting.IDType(0xabcd))
+		c.Check(entry.Lifespan, Equals, prompting.LifespanType(""))
+		byPrompt[entry.PromptID] = entry
+	}
+
+	entry1 := byPrompt[prompt1.ID]
+	c.Assert(entry1, NotNil)
+	c.Check(entry1.Outcome, Equals, prompting.OutcomeDeny)
+	c.Check(entry1.AllowedPermissions, DeepEquals, []string{"read"})
+	c.Check(entry1.DeniedPermissions, DeepEquals, []string{"write"})
+
+	entry2 := byPrompt[prompt2.ID]
+	c.Assert(entry2, NotNil)
+	c.Check(entry2.Outcome, Equals, prompting.OutcomeAllow)
+	c.Check(entry2.AllowedPermissions, DeepEquals, []string{"read"})
+	c.Check(entry2.DeniedPermissions, DeepEquals, []string{})
+}
+
+func (s *requestpromptsSuite) TestHistoryExpired(c *C) {
+	var timer *testtime.TestTimer
+	restore := requestprompts.MockTimeAfterFunc(func(d time.Duration, f func()) timeutil.Timer {
+		timer = testtime.AfterFunc(d, f)
+		return timer
+	})
+	defer restore()
+
+	pdb, err := requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	defer pdb.Close()
+
+	metadata := &prompting.Metadata{
+		User:      s.defaultUser,
+		Snap:      "firefox",
+		Interface: "home",
+	}
+	req, replyChan := newRequestWithReplyChan("fake:1")
+	prompt, _, err := pdb.AddOrMerge(metadata, "/home/test/foo", []string{"read"}, []string{"read"}, req)
+	c.Assert(err, IsNil)
+
+	timer.Elapse(requestprompts.InitialTimeout)
+	c.Check(waitForReply(c, replyChan), DeepEquals, []string{})
+
+	history, err := pdb.History(s.defaultUser, nil)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].PromptID, Equals, prompt.ID)
+	c.Check(history[0].Resolution, Equals, requestprompts.ResolutionExpired)
+	c.Check(history[0].Outcome, Equals, prompting.OutcomeDeny)
+	c.Check(history[0].DeniedPermissions, DeepEquals, []string{"read"})
+}
+
+func (s *requestpromptsSuite) TestHistoryFilter(c *C) {
+	c.Assert(os.MkdirAll(filepath.Dir(s.historyPath(s.defaultUser)), 0o700), IsNil)
+	c.Assert(os.WriteFile(s.historyPath(s.defaultUser), []byte(`{"timestamp":"2026-01-01T00:00:00Z","prompt-id":"0000000000000001","snap":"firefox","interface":"home","path":"/home/test/a","resolution":"replied","outcome":"allow"}
+{"timestamp":"2026-01-02T00:00:00Z","prompt-id":"0000000000000002","snap":"firefox","interface":"camera","path":"/dev/video0","resolution":"replied","outcome":"deny"}
+{"timestamp":"2026-01-03T00:00:00Z","prompt-id":"00000000000000
+{"timestamp":"2026-01-04T00:00:00Z","prompt-id":"0000000000000004","snap":"thunderbird","interface":"home","path":"/home/test/b","resolution":"expired","outcome":"deny"}
+`), 0o600), IsNil)
+
+	pdb, err := requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	defer pdb.Close()
+
+	for _, testCase := range []struct {
+		filter   *requestprompts.HistoryFilter
+		expected []prompting.IDType
+	}{
+		{
+			filter:   nil,
+			expected: []prompting.IDType{1, 2, 4},
+		},
+		{
+			filter:   &requestprompts.HistoryFilter{Snap: "firefox"},
+			expected: []prompting.IDType{1, 2},
+		},
+		{
+			filter:   &requestprompts.HistoryFilter{Interface: "home"},
+			expected: []prompting.IDType{1, 4},
+		},
+		{
+			filter:   &requestprompts.HistoryFilter{Snap: "firefox", Interface: "home"},
+			expected: []prompting.IDType{1},
+		},
+		{
+			filter:   &requestprompts.HistoryFilter{Since: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+			expected: []prompting.IDType{2, 4},
+		},
+		{
+			filter:   &requestprompts.HistoryFilter{Until: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+			expected: []prompting.IDType{1, 2},
+		},
+		{
+			filter: &requestprompts.HistoryFilter{
+				Since: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+				Until: time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC),
+			},
+			expected: []prompting.IDType{2},
+		},
+		{
+			filter:   &requestprompts.HistoryFilter{Snap: "chromium"},
+			expected: nil,
+		},
+	} {
+		history, err := pdb.History(s.defaultUser, testCase.filter)
+		c.Assert(err, IsNil)
+		var ids []prompting.IDType
+		for _, entry := range history {
+			ids = append(ids, entry.PromptID)
+		}
+		c.Check(ids, DeepEquals, testCase.expected, Commentf("filter: %+v", testCase.filter))
+	}
+}
+
+func (s *requestpromptsSuite) TestHistoryClosed(c *C) {
+	pdb, err := requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	c.Assert(pdb.Close(), IsNil)
+
+	_, err = pdb.History(s.defaultUser, nil)
+	c.Check(err, Equals, prompting_errors.ErrPromptingClosed)
+}
+
+func (s *requestpromptsSuite) TestHistoryRotated(c *C) {
+	pdb, err := requestprompts.New(s.defaultNotifyPrompt)
+	c.Assert(err, IsNil)
+	defer pdb.Close()
+
+	entry := func(id prompting.IDType) *requestprompts.HistoryEntry {
+		return &requestprompts.HistoryEntry{
+			Timestamp:  time.Date(2026, 1, 1, 0, 0, int(id), 0, time.UTC),
+			PromptID:   id,
+			Snap:       "firefox",
+			Interface:  "home",
+			Path:       "/home/test/foo",
+			Resolution: requestprompts.ResolutionReplied,
+			Outcome:    prompting.OutcomeAllow,
+		}
+	}
+	pdb.RecordHistory(s.defaultUser, entry(1))
+	fi, err := os.Stat(s.historyPath(s.defaultUser))
+	c.Assert(err, IsNil)
+	// room for two entries per file
+	restore := requestprompts.MockMaxHistoryFileSize(2*fi.Size() + 1)
+	defer restore()
+
+	ids := func() []prompting.IDType {
+		history, err := pdb.History(s.defaultUser, nil)
+		c.Assert(err, IsNil)
+		var ids []prompting.IDType
+		for _, entry := range history {
+			ids = append(ids, entry.PromptID)
+		}
+		return ids
+	}
+
+	pdb.RecordHistory(s.defaultUser, entry(2))
+	c.Check(s.historyPath(s.defaultUser)+".1", testutil.FileAbsent)
+	c.Check(ids(), DeepEquals, []prompting.IDType{1, 2})
+
+	pdb.RecordHistory(s.defaultUser, entry(3))
+	c.Check(s.historyPath(s.defaultUser)+".1", testutil.FilePresent)
+	c.Check(ids(), DeepEquals, []prompting.IDType{1, 2, 3})
+
+	pdb.RecordHistory(s.defaultUser, entry(4))
+	pdb.RecordHistory(s.defaultUser, entry(5))
+	// the oldest entries were dropped
+	c.Check(ids(), DeepEquals, []prompting.IDType{3, 4, 5})
+
+	fi, err = os.Stat(s.historyPath(s.defaultUser) + ".1")
+	c.Assert(err, IsNil)
+	c.Check(fi.Mode().Perm(), Equals, os.FileMode(0o600))
+}
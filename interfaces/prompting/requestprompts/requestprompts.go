@@ -198,6 +198,10 @@ type promptConstraints struct {
 	// were explicitly allowed by the user, even if some of those permissions
 	// were allowed by rules instead of by the direct reply to the prompt.
 	originalPermissions []string
+	// plugPaths are the paths declared by the connected plugs of the snap,
+	// for interfaces which only grant access to those paths, so that the
+	// client can offer path patterns within them.
+	plugPaths []string
 }
 
 // promptConstraintsJSONHome defines the marshalled json structure of
@@ -208,6 +212,17 @@ type promptConstraintsJSONHome struct {
 	AvailablePermissions []string `json:"available-permissions"`
 }
 
+// promptConstraintsJSONPlugPaths defines the marshalled json structure of
+// promptConstraints for interfaces which only grant access to the paths
+// declared by the plugs of the snap, such as the removable-media and
+// personal-files interfaces.
+type promptConstraintsJSONPlugPaths struct {
+	Path                 string   `json:"path"`
+	PlugPaths            []string `json:"plug-paths"`
+	RequestedPermissions []string `json:"requested-permissions"`
+	AvailablePermissions []string `json:"available-permissions"`
+}
+
 // promptConstraintsJSONEmpty defines the marshalled json structure of
 // promptConstraints for interfaces which do not have interface-specific
 // constraints, such as the camera and audio-record interfaces.
@@ -231,6 +246,14 @@ func (pc *promptConstraints) marshalForInterface(iface string) ([]byte, error) {
 			AvailablePermissions: pc.availablePermissions,
 		}
 		return json.Marshal(constraintsJSON)
+	case "removable-media", "personal-files":
+		constraintsJSON := &promptConstraintsJSONPlugPaths{
+			Path:                 pc.EscapedPath(),
+			PlugPaths:            pc.plugPaths,
+			RequestedPermissions: pc.outstandingPermissions,
+			AvailablePermissions: pc.availablePermissions,
+		}
+		return json.Marshal(constraintsJSON)
 	case "camera", "audio-record":
 		constraintsJSON := &promptConstraintsJSONEmpty{
 			RequestedPermissions: pc.outstandingPermissions,
@@ -463,6 +486,7 @@ func (udb *userPromptDB) timeoutCallback(pdb *PromptDB, user uint32) {
 		for _, request := range p.requests {
 			delete(pdb.requestMap, request.Key)
 		}
+		pdb.recordHistory(user, newHistoryEntry(p, ResolutionExpired, prompting.OutcomeDeny, p.Constraints.outstandingPermissions))
 	}
 	pdb.saveRequestMap()
 
@@ -527,6 +551,9 @@ type PromptDB struct {
 	// ready is closed when all pending requests have been re-received, or when
 	// the readyTimer times out. The mutex must be held when closing ready.
 	ready chan struct{}
+	// historyDir is the directory in which the history of how the prompts of
+	// each user were resolved is stored.
+	historyDir string
 }
 
 // New creates and returns a new prompt database.
@@ -561,6 +588,7 @@ func New(notifyPrompt func(userID uint32, promptID prompting.IDType, data map[st
 		maxIDMmap:    maxIDMmap,
 
 		requestMapFilepath: filepath.Join(dirs.SnapInterfacesRequestsRunDir, "request-key-mapping.json"),
+		historyDir:         filepath.Join(dirs.SnapInterfacesRequestsStateDir, "history"),
 
 		readyTimer: timeutil.NewTimer(0),
 		ready:      make(chan struct{}),
@@ -799,6 +827,8 @@ func (pdb *PromptDB) readyIfPendingAllReceived() {
 //
 // The caller must ensure that the given permissions are in the order in which
 // they appear in the available permissions list for the given interface.
+//
+// The plug paths of a new prompt are those of the given request.
 func (pdb *PromptDB) AddOrMerge(metadata *prompting.Metadata, path string, requestedPermissions []string, outstandingPermissions []string, request *prompting.Request) (*Prompt, bool, error) {
 	availablePermissions, err := prompting.AvailablePermissions(metadata.Interface)
 	if err != nil {
@@ -832,6 +862,7 @@ func (pdb *PromptDB) AddOrMerge(metadata *prompting.Metadata, path string, reque
 		outstandingPermissions: outstandingPermissions,
 		availablePermissions:   availablePermissions,
 		originalPermissions:    requestedPermissions,
+		plugPaths:              request.PlugPaths,
 	}
 
 	needToSave := false
@@ -1060,9 +1091,13 @@ func (pdb *PromptDB) promptWithID(user uint32, id prompting.IDType, clientActivi
 //
 // Records a notice for the prompt, and returns the prompt's former contents.
 //
+// The reply is recorded in the history of the user, along with the given
+// lifespan of the reply and the ID of the rule which was created from it, if
+// any.
+//
 // If clientActivity is true, reset the expiration timeout for prompts for
 // the given user.
-func (pdb *PromptDB) Reply(user uint32, id prompting.IDType, outcome prompting.OutcomeType, clientActivity bool) (*Prompt, error) {
+func (pdb *PromptDB) Reply(user uint32, id prompting.IDType, outcome prompting.OutcomeType, lifespan prompting.LifespanType, ruleID prompting.IDType, clientActivity bool) (*Prompt, error) {
 	pdb.mutex.Lock()
 	defer pdb.mutex.Unlock()
 	userEntry, prompt, err := pdb.promptWithID(user, id, clientActivity)
@@ -1073,6 +1108,15 @@ func (pdb *PromptDB) Reply(user uint32, id prompting.IDType, outcome prompting.O
 		return nil, err
 	}
 
+	var deniedPermissions []string
+	if outcome == prompting.OutcomeDeny {
+		deniedPermissions = prompt.Constraints.outstandingPermissions
+	}
+	entry := newHistoryEntry(prompt, ResolutionReplied, outcome, deniedPermissions)
+	entry.Lifespan = lifespan
+	entry.RuleID = ruleID
+	pdb.recordHistory(user, entry)
+
 	for _, request := range prompt.requests {
 		delete(pdb.requestMap, request.Key)
 	}
@@ -1101,12 +1145,13 @@ func (pdb *PromptDB) Reply(user uint32, id prompting.IDType, outcome prompting.O
 // satisfied for the prompt as a whole to be satisfied.
 //
 // Returns the IDs of any prompts which were fully satisfied by the given rule
-// contents.
+// contents, and records each of them in the history of the user, along with
+// the given ID of the rule.
 //
 // Since rule is new, we don't check the expiration timestamps for any
 // permissions, since any permissions with lifespan timespan were validated to
 // have a non-zero duration, and we handle this rule as it was at its creation.
-func (pdb *PromptDB) HandleNewRule(metadata *prompting.Metadata, constraints *prompting.RuleConstraints) ([]prompting.IDType, error) {
+func (pdb *PromptDB) HandleNewRule(metadata *prompting.Metadata, ruleID prompting.IDType, constraints *prompting.RuleConstraints) ([]prompting.IDType, error) {
 	pdb.mutex.Lock()
 	defer pdb.mutex.Unlock()
 
@@ -1172,6 +1217,13 @@ func (pdb *PromptDB) HandleNewRule(metadata *prompting.Metadata, constraints *pr
 		// either by this new rule or by previous rules.
 		allowedPermissions := prompt.Constraints.buildResponse(deniedPermissions)
 		prompt.sendReplyWithPermission(allowedPermissions)
+		outcome := prompting.OutcomeAllow
+		if len(deniedPermissions) > 0 {
+			outcome = prompting.OutcomeDeny
+		}
+		entry := newHistoryEntry(prompt, ResolutionSatisfied, outcome, deniedPermissions)
+		entry.RuleID = ruleID
+		pdb.recordHistory(metadata.User, entry)
 		// Now that a response has been sent, remove the rule from the rule DB
 		// and record a notice indicating that it has been satisfied.
 		userEntry.remove(prompt.ID)
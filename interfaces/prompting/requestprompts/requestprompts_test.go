@@ -1371,7 +1371,7 @@ func (s *requestpromptsSuite) TestReply(c *C) {
 		s.checkWrittenRequestMap(c, expectedMap)
 
 		clientActivity := true // doesn't matter if it's true or false for this test
-		repliedPrompt, err := pdb.Reply(metadata.User, prompt1.ID, outcome, clientActivity)
+		repliedPrompt, err := pdb.Reply(metadata.User, prompt1.ID, outcome, prompting.LifespanSingle, 0, clientActivity)
 		c.Check(err, IsNil)
 		c.Check(repliedPrompt, Equals, prompt1)
 		// Make sure we only get one reply per request, even though one request
@@ -1448,13 +1448,13 @@ func (s *requestpromptsSuite) TestReplyErrors(c *C) {
 	outcome := prompting.OutcomeAllow
 
 	clientActivity := true // doesn't matter if it's true or false for this test
-	_, err = pdb.Reply(metadata.User, 1234, outcome, clientActivity)
+	_, err = pdb.Reply(metadata.User, 1234, outcome, prompting.LifespanSingle, 0, clientActivity)
 	c.Check(err, Equals, prompting_errors.ErrPromptNotFound)
 
-	_, err = pdb.Reply(metadata.User+1, prompt.ID, outcome, clientActivity)
+	_, err = pdb.Reply(metadata.User+1, prompt.ID, outcome, prompting.LifespanSingle, 0, clientActivity)
 	c.Check(err, Equals, prompting_errors.ErrPromptNotFound)
 
-	_, err = pdb.Reply(metadata.User, prompt.ID, outcome, clientActivity)
+	_, err = pdb.Reply(metadata.User, prompt.ID, outcome, prompting.LifespanSingle, 0, clientActivity)
 	c.Check(err, Equals, fakeError)
 
 	// Failed replies should not record notice
@@ -1564,7 +1564,7 @@ func (s *requestpromptsSuite) testHandleNewRule(c *C, requestedPath, replyPatter
 	metadata.PID = 0
 	metadata.Cgroup = ""
 
-	satisfied, err := pdb.HandleNewRule(metadata, constraints)
+	satisfied, err := pdb.HandleNewRule(metadata, 0, constraints)
 	c.Assert(err, IsNil)
 	c.Check(satisfied, HasLen, 2, Commentf("requestedPath: %q, replyPattern: %q", requestedPath, replyPattern))
 	c.Check(promptIDListContains(satisfied, prompt1.ID), Equals, true)
@@ -1607,7 +1607,7 @@ func (s *requestpromptsSuite) testHandleNewRule(c *C, requestedPath, replyPatter
 			"write": &prompting.RulePermissionEntry{Outcome: prompting.OutcomeAllow},
 		},
 	}
-	satisfied, err = pdb.HandleNewRule(metadata, constraints)
+	satisfied, err = pdb.HandleNewRule(metadata, 0, constraints)
 
 	c.Assert(err, IsNil)
 	c.Check(satisfied, HasLen, 1)
@@ -1728,7 +1728,7 @@ func (s *requestpromptsSuite) testHandleNewRuleNonMatches(c *C, requestedPath, m
 	c.Assert(stored, HasLen, 1)
 	c.Assert(stored[0], Equals, prompt)
 
-	satisfied, err := pdb.HandleNewRule(metadata, badOutcomeConstraints)
+	satisfied, err := pdb.HandleNewRule(metadata, 0, badOutcomeConstraints)
 	c.Check(err, ErrorMatches, `invalid outcome: "foo"`)
 	c.Check(satisfied, IsNil)
 
@@ -1739,7 +1739,7 @@ func (s *requestpromptsSuite) testHandleNewRuleNonMatches(c *C, requestedPath, m
 		Snap:      snap,
 		Interface: iface,
 	}
-	satisfied, err = pdb.HandleNewRule(otherUserMetadata, constraints)
+	satisfied, err = pdb.HandleNewRule(otherUserMetadata, 0, constraints)
 	c.Check(err, IsNil)
 	c.Check(satisfied, IsNil)
 
@@ -1750,7 +1750,7 @@ func (s *requestpromptsSuite) testHandleNewRuleNonMatches(c *C, requestedPath, m
 		Snap:      otherSnap,
 		Interface: iface,
 	}
-	satisfied, err = pdb.HandleNewRule(otherSnapMetadata, constraints)
+	satisfied, err = pdb.HandleNewRule(otherSnapMetadata, 0, constraints)
 	c.Check(err, IsNil)
 	c.Check(satisfied, IsNil)
 
@@ -1761,19 +1761,19 @@ func (s *requestpromptsSuite) testHandleNewRuleNonMatches(c *C, requestedPath, m
 		Snap:      snap,
 		Interface: otherInterface,
 	}
-	satisfied, err = pdb.HandleNewRule(otherInterfaceMetadata, constraints)
+	satisfied, err = pdb.HandleNewRule(otherInterfaceMetadata, 0, constraints)
 	c.Check(err, IsNil)
 	c.Check(satisfied, IsNil)
 
 	s.checkNewNoticesSimple(c, []prompting.IDType{}, nil)
 
-	satisfied, err = pdb.HandleNewRule(metadata, otherConstraints)
+	satisfied, err = pdb.HandleNewRule(metadata, 0, otherConstraints)
 	c.Check(err, IsNil)
 	c.Check(satisfied, IsNil)
 
 	s.checkNewNoticesSimple(c, []prompting.IDType{}, nil)
 
-	satisfied, err = pdb.HandleNewRule(metadata, constraints)
+	satisfied, err = pdb.HandleNewRule(metadata, 0, constraints)
 	c.Check(err, IsNil)
 	c.Assert(satisfied, HasLen, 1)
 
@@ -1950,11 +1950,11 @@ func (s *requestpromptsSuite) TestCloseThenOperate(c *C) {
 	c.Check(err, Equals, prompting_errors.ErrPromptingClosed)
 	c.Check(prompt, IsNil)
 
-	result, err = pdb.Reply(1000, 1, prompting.OutcomeDeny, clientActivity)
+	result, err = pdb.Reply(1000, 1, prompting.OutcomeDeny, prompting.LifespanSingle, 0, clientActivity)
 	c.Check(err, Equals, prompting_errors.ErrPromptingClosed)
 	c.Check(result, IsNil)
 
-	promptIDs, err := pdb.HandleNewRule(nil, nil)
+	promptIDs, err := pdb.HandleNewRule(nil, 0, nil)
 	c.Check(err, Equals, prompting_errors.ErrPromptingClosed)
 	c.Check(promptIDs, IsNil)
 
@@ -2304,6 +2304,7 @@ func (s *requestpromptsSuite) TestPromptMarshalJSON(c *C) {
 		path             string
 		requestedPerms   []string
 		outstandingPerms []string
+		plugPaths        []string
 		expected         string
 	}{
 		{
@@ -2359,8 +2360,36 @@ func (s *requestpromptsSuite) TestPromptMarshalJSON(c *C) {
 			outstandingPerms: []string{"write"},
 			expected:         `{"id":"0000000000000004","timestamp":"2024-08-14T09:47:03.350324989-05:00","snap":"firefox","pid":1234,"cgroup":"0::/user.slice/user-1000.slice/user@1000.service/app.slice/some-cgroup.scope","interface":"home","constraints":{"path":"/home/test/foo\\*\\?()\\[\\]\\{\\}'\",\\\\","requested-permissions":["write"],"available-permissions":["read","write","execute"]}}`,
 		},
+		{
+			metadata: &prompting.Metadata{
+				User:      s.defaultUser,
+				Snap:      "vlc",
+				PID:       1357,
+				Cgroup:    "0::/user.slice/user-1000.slice/user@1000.service/app.slice/some-cgroup.scope",
+				Interface: "removable-media",
+			},
+			path:             "/media/test/usb/movie.mkv",
+			requestedPerms:   []string{"read"},
+			outstandingPerms: []string{"read"},
+			plugPaths:        []string{"/media", "/mnt", "/run/media"},
+			expected:         `{"id":"0000000000000005","timestamp":"2024-08-14T09:47:03.350324989-05:00","snap":"vlc","pid":1357,"cgroup":"0::/user.slice/user-1000.slice/user@1000.service/app.slice/some-cgroup.scope","interface":"removable-media","constraints":{"path":"/media/test/usb/movie.mkv","plug-paths":["/media","/mnt","/run/media"],"requested-permissions":["read"],"available-permissions":["read","write","execute"]}}`,
+		},
+		{
+			metadata: &prompting.Metadata{
+				User:      s.defaultUser,
+				Snap:      "firefox",
+				PID:       1234,
+				Cgroup:    "0::/user.slice/user-1000.slice/user@1000.service/app.slice/some-cgroup.scope",
+				Interface: "personal-files",
+			},
+			path:             "/home/test/.mozilla/firefox/profiles.ini",
+			requestedPerms:   []string{"read", "write"},
+			outstandingPerms: []string{"write"},
+			plugPaths:        []string{"/home/test/.mozilla/firefox"},
+			expected:         `{"id":"0000000000000006","timestamp":"2024-08-14T09:47:03.350324989-05:00","snap":"firefox","pid":1234,"cgroup":"0::/user.slice/user-1000.slice/user@1000.service/app.slice/some-cgroup.scope","interface":"personal-files","constraints":{"path":"/home/test/.mozilla/firefox/profiles.ini","plug-paths":["/home/test/.mozilla/firefox"],"requested-permissions":["write"],"available-permissions":["read","write"]}}`,
+		},
 	} {
-		fakeRequest := &prompting.Request{Key: fmt.Sprintf("fake:%d", reqCount)}
+		fakeRequest := &prompting.Request{Key: fmt.Sprintf("fake:%d", reqCount), PlugPaths: testCase.plugPaths}
 		reqCount++
 
 		prompt, merged, err := pdb.AddOrMerge(testCase.metadata, testCase.path, testCase.requestedPerms, testCase.outstandingPerms, fakeRequest)
@@ -2481,7 +2510,7 @@ func (s *requestpromptsSuite) TestPromptExpiration(c *C) {
 	c.Assert(timer.FireCount(), Equals, 1)
 
 	// Reply to fake prompt (and get error, but still bump timeout)
-	_, err = pdb.Reply(s.defaultUser, prompt.ID+1, prompting.OutcomeAllow, clientActivity)
+	_, err = pdb.Reply(s.defaultUser, prompt.ID+1, prompting.OutcomeAllow, prompting.LifespanSingle, 0, clientActivity)
 	c.Check(err, NotNil)
 
 	// Prompt should *not* expire after initialTimeout
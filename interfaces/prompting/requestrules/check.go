@@ -0,0 +1,247 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestrules
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+)
+
+// Decisions which the rules would make about a request, or about one of the
+// permissions of a request.
+const (
+	// CheckDecisionAllow means that the rules would allow the request.
+	CheckDecisionAllow = "allow"
+	// CheckDecisionDeny means that the rules would deny the request.
+	CheckDecisionDeny = "deny"
+	// CheckDecisionPrompt means that the user would be prompted about the
+	// request, since no rule applies to it.
+	CheckDecisionPrompt = "prompt"
+)
+
+// Sources of the rules which can decide about a permission, in order of
+// precedence.
+const (
+	// CheckSourcePolicySnap are the policy rules for the requesting snap.
+	CheckSourcePolicySnap = "policy-snap"
+	// CheckSourcePolicy are the policy rules which apply to every snap.
+	CheckSourcePolicy = "policy"
+	// CheckSourceUser are the rules of the user.
+	CheckSourceUser = "user"
+)
+
+// RequestCheck explains what the rules would decide about a request, were it
+// received now.
+type RequestCheck struct {
+	// Decision is the decision about the request as a whole. If any
+	// permission would be denied, the request is denied without prompting.
+	// If every permission would be allowed, the request is allowed.
+	// Otherwise, the user is prompted for the outstanding permissions.
+	Decision    string             `json:"decision"`
+	Permissions []*PermissionCheck `json:"permissions"`
+}
+
+// PermissionCheck explains what the rules would decide about one of the
+// permissions of a request, and why.
+type PermissionCheck struct {
+	Permission string `json:"permission"`
+	Decision   string `json:"decision"`
+	// Source is the source of the rules which decide about the permission,
+	// if any.
+	Source string `json:"source,omitempty"`
+	// Variant is the path pattern variant which has the highest precedence
+	// among the matching variants from the deciding source, if any.
+	Variant string `json:"variant,omitempty"`
+	// RuleIDs are the IDs of the rules which the deciding variant belongs to.
+	RuleIDs []prompting.IDType `json:"rule-ids,omitempty"`
+	// Matches are the path pattern variants which match the path, from every
+	// source, including those which do not take precedence.
+	Matches []*VariantMatch `json:"matches,omitempty"`
+	// Reason explains in human-readable form why the decision was made.
+	Reason string `json:"reason"`
+}
+
+// VariantMatch is a path pattern variant which matches the path of a request.
+type VariantMatch struct {
+	Source  string                `json:"source"`
+	Variant string                `json:"variant"`
+	Outcome prompting.OutcomeType `json:"outcome"`
+	RuleIDs []prompting.IDType    `json:"rule-ids"`
+}
+
+// CheckRequest reports what the rules would decide about a request from the
+// given snap for the given permissions to the given path through the given
+// interface, on behalf of the given user, were it received now, along with
+// the rules and path pattern variants which lead to that decision.
+//
+// Nothing is changed by the check, it is meant for debugging rules.
+func (rdb *RuleDB) CheckRequest(user uint32, snap string, iface string, path string, permissions []string) (*RequestCheck, error) {
+	if err := prompting.ValidatePermissions(iface, permissions); err != nil {
+		return nil, err
+	}
+
+	rdb.mutex.RLock()
+	defer rdb.mutex.RUnlock()
+
+	if rdb.maxIDMmap.IsClosed() {
+		return nil, prompting_errors.ErrPromptingClosed
+	}
+
+	currSession, err := ReadOrAssignUserSessionID(rdb, user)
+	if err != nil && !errors.Is(err, errNoUserSession) {
+		return nil, err
+	}
+	at := prompting.At{
+		Time:      time.Now(),
+		SessionID: currSession,
+	}
+
+	// Policy rules have no expiration, and apply regardless of the session
+	policyAt := prompting.At{Time: at.Time}
+	sources := []struct {
+		name          string
+		permissionMap func(perm string) *permissionDB
+		at            prompting.At
+	}{
+		{CheckSourcePolicySnap, func(perm string) *permissionDB { return rdb.policy.permissionDB(snap, iface, perm) }, policyAt},
+		{CheckSourcePolicy, func(perm string) *permissionDB { return rdb.policy.permissionDB("", iface, perm) }, policyAt},
+		{CheckSourceUser, func(perm string) *permissionDB {
+			return rdb.permissionDBForUserSnapInterfacePermission(user, snap, iface, perm)
+		}, at},
+	}
+
+	check := &RequestCheck{
+		Permissions: make([]*PermissionCheck, 0, len(permissions)),
+	}
+	anyDenied, anyPrompt := false, false
+	for _, perm := range permissions {
+		permCheck := &PermissionCheck{
+			Permission: perm,
+			Decision:   CheckDecisionPrompt,
+		}
+		if rdb.policy.loadErr != nil {
+			permCheck.Decision = CheckDecisionDeny
+			permCheck.Source = CheckSourcePolicy
+			permCheck.Reason = fmt.Sprintf("the policy file cannot be loaded, so every request is denied: %v", rdb.policy.loadErr)
+			anyDenied = true
+			check.Permissions = append(check.Permissions, permCheck)
+			continue
+		}
+		var shadowed []string
+		for _, source := range sources {
+			matching, highest, err := matchPathInPermissionDB(source.permissionMap(perm), path, source.at)
+			if errors.Is(err, prompting_errors.ErrNoMatchingRule) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range matching {
+				permCheck.Matches = append(permCheck.Matches, newVariantMatch(source.name, entry, source.at))
+			}
+			if permCheck.Source != "" {
+				// A source with higher precedence already decided
+				shadowed = append(shadowed, sourceDescription(source.name))
+				continue
+			}
+			allowed, err := highest.Outcome.AsBool()
+			if err != nil {
+				// Should not occur, outcomes are validated when rules are added
+				return nil, err
+			}
+			permCheck.Decision = CheckDecisionDeny
+			if allowed {
+				permCheck.Decision = CheckDecisionAllow
+			}
+			permCheck.Source = source.name
+			permCheck.Variant = highest.Variant.String()
+			permCheck.RuleIDs = unexpiredRuleIDs(highest, source.at)
+			permCheck.Reason = variantReason(permCheck.Variant, len(matching), source.name)
+		}
+		switch {
+		case permCheck.Source == "":
+			permCheck.Reason = "no rule matches the path, so the user would be prompted"
+			anyPrompt = true
+		case len(shadowed) > 0:
+			permCheck.Reason += fmt.Sprintf(", which take precedence over matching %s", strings.Join(shadowed, " and "))
+		}
+		if permCheck.Decision == CheckDecisionDeny {
+			anyDenied = true
+		}
+		check.Permissions = append(check.Permissions, permCheck)
+	}
+
+	switch {
+	case anyDenied:
+		check.Decision = CheckDecisionDeny
+	case anyPrompt:
+		check.Decision = CheckDecisionPrompt
+	default:
+		check.Decision = CheckDecisionAllow
+	}
+	return check, nil
+}
+
+func newVariantMatch(source string, entry variantEntry, at prompting.At) *VariantMatch {
+	return &VariantMatch{
+		Source:  source,
+		Variant: entry.Variant.String(),
+		Outcome: entry.Outcome,
+		RuleIDs: unexpiredRuleIDs(entry, at),
+	}
+}
+
+// unexpiredRuleIDs returns the sorted IDs of the rules of the given variant
+// entry whose permission entries have not expired at the given point in time.
+func unexpiredRuleIDs(entry variantEntry, at prompting.At) []prompting.IDType {
+	ids := make([]prompting.IDType, 0, len(entry.RuleEntries))
+	for id, ruleEntry := range entry.RuleEntries {
+		if ruleEntry.Expired(at) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func sourceDescription(source string) string {
+	switch source {
+	case CheckSourcePolicySnap:
+		return "policy rules for the snap"
+	case CheckSourcePolicy:
+		return "policy rules for all snaps"
+	default:
+		return "user rules"
+	}
+}
+
+func variantReason(variant string, matchingCount int, source string) string {
+	if matchingCount == 1 {
+		return fmt.Sprintf("%s is the only matching variant of the %s", variant, sourceDescription(source))
+	}
+	return fmt.Sprintf("%s has the highest precedence of the %d matching variants of the %s", variant, matchingCount, sourceDescription(source))
+}
@@ -0,0 +1,241 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestrules_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *requestrulesSuite) addHomeRule(c *C, rdb *requestrules.RuleDB, snap string, pathPattern string, permissions prompting.PermissionMap) *requestrules.Rule {
+	constraints := &prompting.Constraints{
+		InterfaceSpecific: &prompting.InterfaceSpecificConstraintsHome{
+			Pattern: mustParsePathPattern(c, pathPattern),
+		},
+		Permissions: permissions,
+	}
+	rule, err := rdb.AddRule(s.defaultUser, snap, "home", constraints)
+	c.Assert(err, IsNil)
+	return rule
+}
+
+func (s *requestrulesSuite) TestCheckRequestUserRules(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+
+	allowForever := &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever}
+	denyForever := &prompting.PermissionEntry{Outcome: prompting.OutcomeDeny, Lifespan: prompting.LifespanForever}
+	broad := s.addHomeRule(c, rdb, "firefox", "/home/test/**", prompting.PermissionMap{"read": allowForever, "write": allowForever})
+	narrow := s.addHomeRule(c, rdb, "firefox", "/home/test/{Private,Secret}/**", prompting.PermissionMap{"write": denyForever})
+	// Rules for other snaps are not considered
+	s.addHomeRule(c, rdb, "thunderbird", "/home/test/**", prompting.PermissionMap{"execute": allowForever})
+
+	check, err := rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/Private/foo", []string{"read", "write", "execute"})
+	c.Assert(err, IsNil)
+	c.Check(check, DeepEquals, &requestrules.RequestCheck{
+		Decision: requestrules.CheckDecisionDeny,
+		Permissions: []*requestrules.PermissionCheck{
+			{
+				Permission: "read",
+				Decision:   requestrules.CheckDecisionAllow,
+				Source:     requestrules.CheckSourceUser,
+				Variant:    "/home/test/**",
+				RuleIDs:    []prompting.IDType{broad.ID},
+				Matches: []*requestrules.VariantMatch{
+					{Source: requestrules.CheckSourceUser, Variant: "/home/test/**", Outcome: prompting.OutcomeAllow, RuleIDs: []prompting.IDType{broad.ID}},
+				},
+				Reason: "/home/test/** is the only matching variant of the user rules",
+			},
+			{
+				Permission: "write",
+				Decision:   requestrules.CheckDecisionDeny,
+				Source:     requestrules.CheckSourceUser,
+				Variant:    "/home/test/Private/**",
+				RuleIDs:    []prompting.IDType{narrow.ID},
+				Matches:    check.Permissions[1].Matches,
+				Reason:     "/home/test/Private/** has the highest precedence of the 2 matching variants of the user rules",
+			},
+			{
+				Permission: "execute",
+				Decision:   requestrules.CheckDecisionPrompt,
+				Reason:     "no rule matches the path, so the user would be prompted",
+			},
+		},
+	})
+	// The order of matching variants is not significant
+	c.Check(check.Permissions[1].Matches, testutil.DeepUnsortedMatches, []*requestrules.VariantMatch{
+		{Source: requestrules.CheckSourceUser, Variant: "/home/test/**", Outcome: prompting.OutcomeAllow, RuleIDs: []prompting.IDType{broad.ID}},
+		{Source: requestrules.CheckSourceUser, Variant: "/home/test/Private/**", Outcome: prompting.OutcomeDeny, RuleIDs: []prompting.IDType{narrow.ID}},
+	})
+
+	check, err = rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/Documents/foo", []string{"read", "write"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionAllow)
+
+	check, err = rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/Documents/foo", []string{"read", "execute"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionPrompt)
+
+	// Rules of other users are not considered
+	check, err = rdb.CheckRequest(s.defaultUser+1, "firefox", "home", "/home/test/Documents/foo", []string{"read"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionPrompt)
+}
+
+func (s *requestrulesSuite) TestCheckRequestMatchesIsRequestAllowed(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+
+	allowForever := &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever}
+	denyForever := &prompting.PermissionEntry{Outcome: prompting.OutcomeDeny, Lifespan: prompting.LifespanForever}
+	s.addHomeRule(c, rdb, "firefox", "/home/test/**", prompting.PermissionMap{"read": allowForever})
+	s.addHomeRule(c, rdb, "firefox", "/home/test/Doc*/**", prompting.PermissionMap{"read": denyForever, "write": allowForever})
+	s.addHomeRule(c, rdb, "firefox", "/home/test/Documents/*.txt", prompting.PermissionMap{"read": allowForever, "write": denyForever})
+
+	for _, path := range []string{
+		"/home/test/foo",
+		"/home/test/Documents/foo",
+		"/home/test/Documents/foo.txt",
+		"/home/test/Docs/foo.txt",
+		"/home/other/foo",
+	} {
+		permissions := []string{"read", "write", "execute"}
+		allowed, anyDenied, outstanding, err := rdb.IsRequestAllowed(s.defaultUser, "firefox", "home", path, permissions)
+		c.Assert(err, IsNil)
+		check, err := rdb.CheckRequest(s.defaultUser, "firefox", "home", path, permissions)
+		c.Assert(err, IsNil)
+
+		checkAllowed := []string{}
+		checkOutstanding := []string{}
+		checkDenied := false
+		for _, permCheck := range check.Permissions {
+			switch permCheck.Decision {
+			case requestrules.CheckDecisionAllow:
+				checkAllowed = append(checkAllowed, permCheck.Permission)
+			case requestrules.CheckDecisionDeny:
+				checkDenied = true
+			case requestrules.CheckDecisionPrompt:
+				checkOutstanding = append(checkOutstanding, permCheck.Permission)
+			}
+		}
+		c.Check(checkAllowed, DeepEquals, allowed, Commentf("path: %s", path))
+		c.Check(checkDenied, Equals, anyDenied, Commentf("path: %s", path))
+		c.Check(checkOutstanding, DeepEquals, outstanding, Commentf("path: %s", path))
+	}
+}
+
+func (s *requestrulesSuite) TestCheckRequestPolicyRules(c *C) {
+	rdb := s.newRuleDBWithPolicy(c, policyRulesJSON)
+
+	allowForever := &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever}
+	userRule := s.addHomeRule(c, rdb, "ssh-client", "/home/test/**", prompting.PermissionMap{"read": allowForever, "write": allowForever})
+
+	policyRules := rdb.Rules(s.defaultUser)[1:]
+	c.Assert(policyRules, HasLen, 3)
+	allSnapsRule, sshClientRule := policyRules[0], policyRules[1]
+
+	check, err := rdb.CheckRequest(s.defaultUser, "ssh-client", "home", "/home/test/.ssh/known_hosts", []string{"read", "write"})
+	c.Assert(err, IsNil)
+	c.Check(check, DeepEquals, &requestrules.RequestCheck{
+		Decision: requestrules.CheckDecisionAllow,
+		Permissions: []*requestrules.PermissionCheck{
+			{
+				Permission: "read",
+				Decision:   requestrules.CheckDecisionAllow,
+				Source:     requestrules.CheckSourceUser,
+				Variant:    "/home/test/**",
+				RuleIDs:    []prompting.IDType{userRule.ID},
+				Matches: []*requestrules.VariantMatch{
+					{Source: requestrules.CheckSourceUser, Variant: "/home/test/**", Outcome: prompting.OutcomeAllow, RuleIDs: []prompting.IDType{userRule.ID}},
+				},
+				Reason: "/home/test/** is the only matching variant of the user rules",
+			},
+			{
+				Permission: "write",
+				Decision:   requestrules.CheckDecisionAllow,
+				Source:     requestrules.CheckSourcePolicySnap,
+				Variant:    "/home/*/.ssh/known_hosts",
+				RuleIDs:    []prompting.IDType{sshClientRule.ID},
+				Matches: []*requestrules.VariantMatch{
+					{Source: requestrules.CheckSourcePolicySnap, Variant: "/home/*/.ssh/known_hosts", Outcome: prompting.OutcomeAllow, RuleIDs: []prompting.IDType{sshClientRule.ID}},
+					{Source: requestrules.CheckSourcePolicy, Variant: "/home/*/.ssh/**", Outcome: prompting.OutcomeDeny, RuleIDs: []prompting.IDType{allSnapsRule.ID}},
+					{Source: requestrules.CheckSourceUser, Variant: "/home/test/**", Outcome: prompting.OutcomeAllow, RuleIDs: []prompting.IDType{userRule.ID}},
+				},
+				Reason: "/home/*/.ssh/known_hosts is the only matching variant of the policy rules for the snap, which take precedence over matching policy rules for all snaps and user rules",
+			},
+		},
+	})
+
+	// The user rule has a more specific pattern, but policy rules take
+	// precedence regardless
+	check, err = rdb.CheckRequest(s.defaultUser, "ssh-client", "home", "/home/test/.ssh/id_ed25519", []string{"write"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionDeny)
+	c.Assert(check.Permissions, HasLen, 1)
+	c.Check(check.Permissions[0].Source, Equals, requestrules.CheckSourcePolicy)
+	c.Check(check.Permissions[0].RuleIDs, DeepEquals, []prompting.IDType{allSnapsRule.ID})
+	c.Check(check.Permissions[0].Reason, Equals, "/home/*/.ssh/** is the only matching variant of the policy rules for all snaps, which take precedence over matching user rules")
+}
+
+func (s *requestrulesSuite) TestCheckRequestExpired(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+
+	allowForever := &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever}
+	denySession := &prompting.PermissionEntry{Outcome: prompting.OutcomeDeny, Lifespan: prompting.LifespanSession}
+	broad := s.addHomeRule(c, rdb, "firefox", "/home/test/**", prompting.PermissionMap{"read": allowForever})
+	s.addHomeRule(c, rdb, "firefox", "/home/test/Private/**", prompting.PermissionMap{"read": denySession})
+
+	check, err := rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/Private/foo", []string{"read"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionDeny)
+
+	// Once the session ends, the session rule no longer applies
+	s.currSession = 0
+	check, err = rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/Private/foo", []string{"read"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionAllow)
+	c.Assert(check.Permissions, HasLen, 1)
+	c.Check(check.Permissions[0].RuleIDs, DeepEquals, []prompting.IDType{broad.ID})
+	c.Check(check.Permissions[0].Matches, HasLen, 1)
+}
+
+func (s *requestrulesSuite) TestCheckRequestErrors(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+
+	_, err = rdb.CheckRequest(s.defaultUser, "firefox", "foo", "/home/test/foo", []string{"read"})
+	c.Check(err, ErrorMatches, `invalid interface: "foo"`)
+	_, err = rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/foo", nil)
+	c.Check(err, ErrorMatches, "invalid permissions for home interface: permissions empty")
+	_, err = rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/foo", []string{"read", "access"})
+	c.Check(err, ErrorMatches, `invalid permissions for home interface: "access"`)
+
+	c.Assert(rdb.Close(), IsNil)
+	_, err = rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/foo", []string{"read"})
+	c.Check(err, Equals, prompting_errors.ErrPromptingClosed)
+}
@@ -61,3 +61,7 @@ func (rdb *RuleDB) ReadOrAssignUserSessionID(user uint32) (userSessionID prompti
 func MockIsPathPermAllowed(f func(rdb *RuleDB, user uint32, snap string, iface string, path string, permission string, at prompting.At) (bool, error)) func() {
 	return testutil.Mock(&isPathPermAllowed, f)
 }
+
+func MockPolicyFileOwner(uid uint32) (restore func()) {
+	return testutil.Mock(&policyFileOwner, uid)
+}
@@ -0,0 +1,402 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestrules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/patterns"
+)
+
+// policyFileOwner is the user ID which must own the policy file. It is a
+// variable so that it can be mocked in tests.
+var policyFileOwner uint32 = 0
+
+// policyDB stores the machine-wide rules set by the administrator in the
+// policy file, which apply to every user and take precedence over the rules
+// of users. Policy rules cannot be added, modified, or removed through the
+// rule DB; the policy file is only read when the rule DB is created.
+type policyDB struct {
+	// index to the rules by their rule ID
+	indexByID map[prompting.IDType]int
+	rules     []*Rule
+
+	// perSnap maps from snap name, or the empty string for rules which
+	// apply to every snap, to the tree of rules for that snap.
+	perSnap map[string]*snapDB
+
+	// loadErr is set when the policy file exists but cannot be loaded. As
+	// the rules which the administrator meant to apply are then unknown,
+	// every request is denied.
+	loadErr error
+}
+
+func newPolicyDB() *policyDB {
+	return &policyDB{
+		indexByID: make(map[prompting.IDType]int),
+		perSnap:   make(map[string]*snapDB),
+	}
+}
+
+// policyFileJSON is the format of the policy file.
+type policyFileJSON struct {
+	Rules []*policyRuleJSON `json:"rules"`
+}
+
+// policyRuleJSON is the format of a rule in the policy file. If the snap is
+// empty, the rule applies to every snap. Every permission in the constraints
+// must have lifespan "forever".
+type policyRuleJSON struct {
+	Snap        string                    `json:"snap,omitempty"`
+	Interface   string                    `json:"interface"`
+	Constraints prompting.ConstraintsJSON `json:"constraints"`
+}
+
+// loadPolicy reads the policy rules from the policy file, if it exists. Each
+// policy rule gets an ID derived from its position in the file and from its
+// contents, so that it keeps its ID as long as it is left unchanged, and a
+// timestamp which is the modification time of the file.
+//
+// The policy file must be owned by root and must not be writable by anyone
+// else, otherwise no policy rule is loaded and an error is returned. An error
+// is also returned if any policy rule is invalid or in conflict with another.
+// The error is kept in the policy DB, which then denies every request.
+//
+// The caller must ensure that the database lock is held for writing.
+func (rdb *RuleDB) loadPolicy() error {
+	err := rdb.doLoadPolicy()
+	if err != nil {
+		rdb.policy = newPolicyDB()
+		rdb.policy.loadErr = err
+	}
+	return err
+}
+
+func (rdb *RuleDB) doLoadPolicy() error {
+	rdb.policy = newPolicyDB()
+
+	f, err := os.Open(rdb.policyPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("cannot open policy file: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot stat policy file: %w", err)
+	}
+	if stat, ok := fi.Sys().(*syscall.Stat_t); !ok || stat.Uid != policyFileOwner {
+		return fmt.Errorf("cannot use policy file %s: must be owned by root", rdb.policyPath)
+	}
+	if fi.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("cannot use policy file %s: must not be writable by group or others", rdb.policyPath)
+	}
+
+	var wrapped policyFileJSON
+	if err := json.NewDecoder(f).Decode(&wrapped); err != nil {
+		return fmt.Errorf("cannot decode policy file: %w", err)
+	}
+
+	policy := newPolicyDB()
+	for i, ruleJSON := range wrapped.Rules {
+		rule, err := makePolicyRule(i, ruleJSON, fi.ModTime())
+		if err != nil {
+			return fmt.Errorf("cannot load policy rule %d: %w", i, err)
+		}
+		if err := policy.addRule(rule); err != nil {
+			return fmt.Errorf("cannot load policy rule %d: %w", i, err)
+		}
+	}
+	rdb.policy = policy
+	return nil
+}
+
+// PolicyError returns the error which prevented the policy file from being
+// loaded, if any, in which case every request is denied.
+func (rdb *RuleDB) PolicyError() error {
+	rdb.mutex.RLock()
+	defer rdb.mutex.RUnlock()
+	return rdb.policy.loadErr
+}
+
+// policyIDFlag is set in the IDs of policy rules, so that they never collide
+// with the IDs of the rules of users, which are allocated sequentially.
+const policyIDFlag prompting.IDType = 1 << 63
+
+// makePolicyRule validates the policy rule at the given index in the policy
+// file and converts it to a rule.
+func makePolicyRule(index int, ruleJSON *policyRuleJSON, timestamp time.Time) (*Rule, error) {
+	if ruleJSON.Interface == "" {
+		return nil, prompting_errors.NewMissingFieldError("interface", `must have non-empty "interface" field`)
+	}
+	constraints, err := prompting.UnmarshalConstraints(ruleJSON.Interface, ruleJSON.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	for permission, entry := range constraints.Permissions {
+		if entry.Lifespan != prompting.LifespanForever {
+			return nil, fmt.Errorf(`invalid lifespan for permission %q: policy rules must have lifespan "forever"`, permission)
+		}
+	}
+	// Policy rules never expire, so the session does not matter
+	at := prompting.At{Time: timestamp}
+	rule := &Rule{
+		Timestamp:   timestamp,
+		Snap:        ruleJSON.Snap,
+		Interface:   ruleJSON.Interface,
+		Constraints: constraints.ToRuleConstraints(at),
+		Policy:      true,
+	}
+	// The constraints are hashed once parsed, so that the ID does not
+	// depend on the formatting of the policy file.
+	contents, err := json.Marshal([]any{rule.Snap, rule.Interface, rule.Constraints})
+	if err != nil {
+		return nil, err
+	}
+	h := fnv.New32a()
+	h.Write(contents)
+	rule.ID = policyIDFlag | prompting.IDType(index)<<32 | prompting.IDType(h.Sum32())
+	return rule, nil
+}
+
+// addRule adds the given rule to the policy DB.
+//
+// If a pattern variant of the rule is identical to one of another policy rule
+// for the same snap, interface, and permission, with a different outcome,
+// returns a rule conflict error and leaves the policy DB unchanged.
+func (pdb *policyDB) addRule(rule *Rule) error {
+	var conflicts []prompting_errors.RuleConflict
+	for permission, entry := range rule.Constraints.Permissions {
+		permVariants := pdb.permissionDB(rule.Snap, rule.Interface, permission)
+		if permVariants == nil {
+			continue
+		}
+		rule.Constraints.PathPattern().RenderAllVariants(func(index int, variant patterns.PatternVariant) {
+			existingEntry, exists := permVariants.VariantEntries[variant.String()]
+			if !exists || existingEntry.Outcome == entry.Outcome {
+				return
+			}
+			for id := range existingEntry.RuleEntries {
+				conflicts = append(conflicts, prompting_errors.RuleConflict{
+					Permission:    permission,
+					Variant:       variant.String(),
+					ConflictingID: id.String(),
+				})
+			}
+		})
+	}
+	if len(conflicts) > 0 {
+		return &prompting_errors.RuleConflictError{
+			Conflicts: conflicts,
+		}
+	}
+
+	for permission, entry := range rule.Constraints.Permissions {
+		permVariants := pdb.ensurePermissionDB(rule.Snap, rule.Interface, permission)
+		rule.Constraints.PathPattern().RenderAllVariants(func(index int, variant patterns.PatternVariant) {
+			variantStr := variant.String()
+			existingEntry, exists := permVariants.VariantEntries[variantStr]
+			if !exists {
+				existingEntry = variantEntry{
+					Variant:     variant,
+					Outcome:     entry.Outcome,
+					RuleEntries: make(map[prompting.IDType]*prompting.RulePermissionEntry),
+				}
+				permVariants.VariantEntries[variantStr] = existingEntry
+			}
+			existingEntry.RuleEntries[rule.ID] = entry
+		})
+	}
+
+	pdb.rules = append(pdb.rules, rule)
+	pdb.indexByID[rule.ID] = len(pdb.rules) - 1
+	return nil
+}
+
+// shadowsRule returns true if policy rules take precedence over the given rule
+// of a user for every path matched by its path pattern and for each of its
+// permissions, in which case the rule would have no effect.
+func (pdb *policyDB) shadowsRule(rule *Rule) bool {
+	if len(rule.Constraints.Permissions) == 0 {
+		return false
+	}
+	for permission := range rule.Constraints.Permissions {
+		shadowed := true
+		rule.Constraints.PathPattern().RenderAllVariants(func(index int, variant patterns.PatternVariant) {
+			if shadowed && !pdb.shadowsVariant(rule.Snap, rule.Interface, permission, variant.String()) {
+				shadowed = false
+			}
+		})
+		if !shadowed {
+			return false
+		}
+	}
+	return true
+}
+
+// shadowsVariant returns true if every path matched by the given pattern
+// variant is matched by a policy rule for the given snap, or for every snap,
+// and for the given interface and permission.
+func (pdb *policyDB) shadowsVariant(snap string, iface string, permission string, variant string) bool {
+	for _, snapName := range []string{snap, ""} {
+		permVariants := pdb.permissionDB(snapName, iface, permission)
+		if permVariants == nil {
+			continue
+		}
+		for policyVariant := range permVariants.VariantEntries {
+			if variantCovers(policyVariant, variant) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// variantCovers returns true if every path matched by the given variant is
+// matched by the given policy variant.
+//
+// This is only determined for identical variants, for variants without
+// wildcards, which match a single path, and for variants which match a path
+// without wildcards and everything below it, against policy variants which
+// also match everything below a path.
+func variantCovers(policyVariant string, variant string) bool {
+	const wildcards = `*?[]{}\`
+	if policyVariant == variant {
+		return true
+	}
+	if !strings.ContainsAny(variant, wildcards) {
+		matched, err := patterns.PathPatternMatches(policyVariant, variant)
+		return err == nil && matched
+	}
+	prefix, ok := strings.CutSuffix(variant, "/**")
+	if !ok || strings.ContainsAny(prefix, wildcards) {
+		return false
+	}
+	policyPrefix, ok := strings.CutSuffix(policyVariant, "/**")
+	if !ok {
+		return false
+	}
+	matched, err := patterns.PathPatternMatches(policyPrefix, prefix)
+	return err == nil && matched
+}
+
+// ruleWithID returns the policy rule with the given ID, or nil if there is
+// no such policy rule.
+func (pdb *policyDB) ruleWithID(id prompting.IDType) *Rule {
+	index, exists := pdb.indexByID[id]
+	if !exists {
+		return nil
+	}
+	return pdb.rules[index]
+}
+
+// rulesForSnapInterface returns the policy rules which apply to the given snap
+// and interface. If either the snap or the interface is empty, rules for any
+// snap or interface, respectively, are included.
+func (pdb *policyDB) rulesForSnapInterface(snap string, iface string) []*Rule {
+	var rules []*Rule
+	for _, rule := range pdb.rules {
+		if snap != "" && rule.Snap != "" && rule.Snap != snap {
+			continue
+		}
+		if iface != "" && rule.Interface != iface {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isPathPermAllowed checks whether the given path with the given permission is
+// allowed or denied by the policy rules for the given snap and interface.
+//
+// Policy rules for the given snap take precedence over policy rules which
+// apply to every snap. If no policy rule applies, returns
+// prompting_errors.ErrNoMatchingRule. If the policy file could not be loaded,
+// every request is denied.
+func (pdb *policyDB) isPathPermAllowed(snap string, iface string, path string, permission string) (bool, error) {
+	if pdb.loadErr != nil {
+		return false, nil
+	}
+	at := prompting.At{Time: time.Now()}
+	for _, snapName := range []string{snap, ""} {
+		allowed, err := isPathPermAllowedByPermissionDB(pdb.permissionDB(snapName, iface, permission), path, at)
+		if errors.Is(err, prompting_errors.ErrNoMatchingRule) {
+			continue
+		}
+		return allowed, err
+	}
+	return false, prompting_errors.ErrNoMatchingRule
+}
+
+// permissionDB returns the permission DB for the given snap, interface, and
+// permission, if it exists, or nil if not.
+func (pdb *policyDB) permissionDB(snap string, iface string, permission string) *permissionDB {
+	snapRules := pdb.perSnap[snap]
+	if snapRules == nil {
+		return nil
+	}
+	interfaceRules := snapRules.PerInterface[iface]
+	if interfaceRules == nil {
+		return nil
+	}
+	return interfaceRules.PerPermission[permission]
+}
+
+// ensurePermissionDB returns the permission DB for the given snap, interface,
+// and permission, or creates it if it does not yet exist.
+func (pdb *policyDB) ensurePermissionDB(snap string, iface string, permission string) *permissionDB {
+	snapRules := pdb.perSnap[snap]
+	if snapRules == nil {
+		snapRules = &snapDB{
+			PerInterface: make(map[string]*interfaceDB),
+		}
+		pdb.perSnap[snap] = snapRules
+	}
+	interfaceRules := snapRules.PerInterface[iface]
+	if interfaceRules == nil {
+		interfaceRules = &interfaceDB{
+			PerPermission: make(map[string]*permissionDB),
+			PathPatterns:  make(map[string]prompting.IDType),
+		}
+		snapRules.PerInterface[iface] = interfaceRules
+	}
+	permVariants := interfaceRules.PerPermission[permission]
+	if permVariants == nil {
+		permVariants = &permissionDB{
+			VariantEntries: make(map[string]variantEntry),
+		}
+		interfaceRules.PerPermission[permission] = permVariants
+	}
+	return permVariants
+}
@@ -0,0 +1,439 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestrules_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
+	"github.com/snapcore/snapd/logger"
+)
+
+const policyRulesJSON = `{
+	"rules": [
+		{
+			"interface": "home",
+			"constraints": {
+				"path-pattern": "/home/*/.ssh/**",
+				"permissions": {
+					"write": {"outcome": "deny", "lifespan": "forever"}
+				}
+			}
+		},
+		{
+			"snap": "ssh-client",
+			"interface": "home",
+			"constraints": {
+				"path-pattern": "/home/*/.ssh/known_hosts",
+				"permissions": {
+					"write": {"outcome": "allow", "lifespan": "forever"}
+				}
+			}
+		},
+		{
+			"snap": "thunderbird",
+			"interface": "camera",
+			"constraints": {
+				"permissions": {
+					"access": {"outcome": "deny", "lifespan": "forever"}
+				}
+			}
+		}
+	]
+}`
+
+func (s *requestrulesSuite) writePolicyFile(c *C, content string, mode os.FileMode) {
+	policyPath := filepath.Join(dirs.SnapInterfacesRequestsStateDir, "policy-rules.json")
+	c.Assert(os.MkdirAll(dirs.SnapInterfacesRequestsStateDir, 0o755), IsNil)
+	c.Assert(os.WriteFile(policyPath, []byte(content), mode), IsNil)
+	c.Assert(os.Chmod(policyPath, mode), IsNil)
+}
+
+func (s *requestrulesSuite) newRuleDBWithPolicy(c *C, content string) *requestrules.RuleDB {
+	s.AddCleanup(requestrules.MockPolicyFileOwner(uint32(os.Getuid())))
+	s.writePolicyFile(c, content, 0o644)
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	s.AddCleanup(func() { rdb.Close() })
+	return rdb
+}
+
+func (s *requestrulesSuite) TestPolicyRulesPrecedence(c *C) {
+	rdb := s.newRuleDBWithPolicy(c, policyRulesJSON)
+
+	// The user allows everything in their home for firefox and ssh-client
+	for _, snap := range []string{"firefox", "ssh-client"} {
+		constraints := &prompting.Constraints{
+			InterfaceSpecific: &prompting.InterfaceSpecificConstraintsHome{
+				Pattern: mustParsePathPattern(c, "/home/test/**"),
+			},
+			Permissions: prompting.PermissionMap{
+				"read":  &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+				"write": &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+			},
+		}
+		_, err := rdb.AddRule(s.defaultUser, snap, "home", constraints)
+		c.Assert(err, IsNil)
+	}
+
+	for _, testCase := range []struct {
+		snap        string
+		path        string
+		permissions []string
+		allowed     []string
+		anyDenied   bool
+	}{
+		// The policy denies writing ~/.ssh to every snap
+		{"firefox", "/home/test/.ssh/id_ed25519", []string{"read", "write"}, []string{"read"}, true},
+		{"ssh-client", "/home/test/.ssh/id_ed25519", []string{"write"}, []string{}, true},
+		// Except known_hosts for ssh-client
+		{"ssh-client", "/home/test/.ssh/known_hosts", []string{"write"}, []string{"write"}, false},
+		{"firefox", "/home/test/.ssh/known_hosts", []string{"write"}, []string{}, true},
+		// Other paths are left to the rules of the user
+		{"firefox", "/home/test/Downloads/foo", []string{"write"}, []string{"write"}, false},
+	} {
+		allowed, anyDenied, outstanding, err := rdb.IsRequestAllowed(s.defaultUser, testCase.snap, "home", testCase.path, testCase.permissions)
+		c.Check(err, IsNil, Commentf("testCase: %+v", testCase))
+		c.Check(allowed, DeepEquals, testCase.allowed, Commentf("testCase: %+v", testCase))
+		c.Check(anyDenied, Equals, testCase.anyDenied, Commentf("testCase: %+v", testCase))
+		c.Check(outstanding, HasLen, 0, Commentf("testCase: %+v", testCase))
+	}
+
+	allowed, anyDenied, outstanding, err := rdb.IsRequestAllowed(s.defaultUser, "thunderbird", "camera", "/dev/video0", []string{"access"})
+	c.Check(err, IsNil)
+	c.Check(allowed, HasLen, 0)
+	c.Check(anyDenied, Equals, true)
+	c.Check(outstanding, HasLen, 0)
+
+	allowed, anyDenied, outstanding, err = rdb.IsRequestAllowed(s.defaultUser, "firefox", "camera", "/dev/video0", []string{"access"})
+	c.Check(err, IsNil)
+	c.Check(allowed, HasLen, 0)
+	c.Check(anyDenied, Equals, false)
+	c.Check(outstanding, DeepEquals, []string{"access"})
+}
+
+func (s *requestrulesSuite) TestPolicyRulesReadOnly(c *C) {
+	rdb := s.newRuleDBWithPolicy(c, policyRulesJSON)
+
+	constraints := &prompting.Constraints{
+		InterfaceSpecific: &prompting.InterfaceSpecificConstraintsHome{
+			Pattern: mustParsePathPattern(c, "/home/test/Downloads/**"),
+		},
+		Permissions: prompting.PermissionMap{
+			"read": &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+		},
+	}
+	userRule, err := rdb.AddRule(s.defaultUser, "firefox", "home", constraints)
+	c.Assert(err, IsNil)
+
+	rules := rdb.Rules(s.defaultUser)
+	c.Assert(rules, HasLen, 4)
+	c.Check(rules[0], Equals, userRule)
+	policyRules := rules[1:]
+	for _, rule := range policyRules {
+		c.Check(rule.Policy, Equals, true)
+		c.Check(rule.User, Equals, uint32(0))
+	}
+	c.Check(policyRules[0].Snap, Equals, "")
+	c.Check(policyRules[0].Constraints.PathPattern().String(), Equals, "/home/*/.ssh/**")
+	c.Check(policyRules[1].Snap, Equals, "ssh-client")
+	c.Check(policyRules[2].Snap, Equals, "thunderbird")
+
+	// Policy rules apply to every user
+	c.Check(rdb.Rules(s.defaultUser+1), DeepEquals, policyRules)
+
+	// Policy rules for every snap are included for any snap
+	c.Check(rdb.RulesForSnap(s.defaultUser, "firefox"), DeepEquals, []*requestrules.Rule{userRule, policyRules[0]})
+	c.Check(rdb.RulesForSnap(s.defaultUser, "ssh-client"), DeepEquals, policyRules[:2])
+	c.Check(rdb.RulesForInterface(s.defaultUser, "camera"), DeepEquals, policyRules[2:])
+	c.Check(rdb.RulesForSnapInterface(s.defaultUser, "thunderbird", "home"), DeepEquals, policyRules[:1])
+
+	rule, err := rdb.RuleWithID(s.defaultUser+1, policyRules[1].ID)
+	c.Check(err, IsNil)
+	c.Check(rule, Equals, policyRules[1])
+
+	marshalled, err := json.Marshal(policyRules[2])
+	c.Assert(err, IsNil)
+	c.Check(string(marshalled), Matches, `.*"user":0,"snap":"thunderbird","interface":"camera",.*,"policy":true}`)
+
+	s.ruleNotices = s.ruleNotices[:0]
+
+	_, err = rdb.RemoveRule(s.defaultUser, policyRules[0].ID)
+	c.Check(err, Equals, prompting_errors.ErrRuleIsPolicy)
+
+	patch := &prompting.RuleConstraintsPatch{
+		Permissions: prompting.RulePermissionMapPatch{
+			"write": &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+		},
+	}
+	_, err = rdb.PatchRule(s.defaultUser, policyRules[0].ID, patch)
+	c.Check(err, Equals, prompting_errors.ErrRuleIsPolicy)
+
+	removed, err := rdb.RemoveRulesForSnap(s.defaultUser, "thunderbird")
+	c.Check(err, IsNil)
+	c.Check(removed, HasLen, 0)
+	removed, err = rdb.RemoveRulesForInterface(s.defaultUser, "home")
+	c.Check(err, IsNil)
+	c.Check(removed, DeepEquals, []*requestrules.Rule{userRule})
+
+	c.Check(s.ruleNotices, HasLen, 1)
+	c.Check(rdb.Rules(s.defaultUser), DeepEquals, policyRules)
+
+	// Policy rules are not saved along with the rules of users
+	data, err := os.ReadFile(filepath.Join(dirs.SnapInterfacesRequestsStateDir, "request-rules.json"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"rules":[]}`)
+}
+
+func (s *requestrulesSuite) TestPolicyRulesStableIDs(c *C) {
+	rdb := s.newRuleDBWithPolicy(c, policyRulesJSON)
+	fi, err := os.Stat(filepath.Join(dirs.SnapInterfacesRequestsStateDir, "policy-rules.json"))
+	c.Assert(err, IsNil)
+	var firstIDs []prompting.IDType
+	for _, rule := range rdb.Rules(s.defaultUser) {
+		// Policy rule IDs never collide with those of the rules of users
+		c.Check(uint64(rule.ID)>>63, Equals, uint64(1))
+		c.Check(rule.Timestamp.Equal(fi.ModTime()), Equals, true)
+		firstIDs = append(firstIDs, rule.ID)
+	}
+	c.Assert(firstIDs, HasLen, 3)
+	c.Check(firstIDs[0], Not(Equals), firstIDs[1])
+	c.Check(firstIDs[1], Not(Equals), firstIDs[2])
+	c.Assert(rdb.Close(), IsNil)
+
+	rulesIDs := func() []prompting.IDType {
+		rdb, err := requestrules.New(s.defaultNotifyRule)
+		c.Assert(err, IsNil)
+		defer rdb.Close()
+		var ids []prompting.IDType
+		for _, rule := range rdb.Rules(s.defaultUser) {
+			ids = append(ids, rule.ID)
+		}
+		return ids
+	}
+
+	// Policy rules keep their IDs across restarts
+	c.Check(rulesIDs(), DeepEquals, firstIDs)
+
+	// Regardless of the formatting of the policy file
+	var policy map[string]any
+	c.Assert(json.Unmarshal([]byte(policyRulesJSON), &policy), IsNil)
+	compact, err := json.Marshal(policy)
+	c.Assert(err, IsNil)
+	s.writePolicyFile(c, string(compact), 0o644)
+	c.Check(rulesIDs(), DeepEquals, firstIDs)
+
+	// Only the ID of a modified policy rule changes
+	modified := strings.Replace(policyRulesJSON, "known_hosts", "known_hosts*", 1)
+	s.writePolicyFile(c, modified, 0o644)
+	ids := rulesIDs()
+	c.Assert(ids, HasLen, 3)
+	c.Check(ids[0], Equals, firstIDs[0])
+	c.Check(ids[1], Not(Equals), firstIDs[1])
+	c.Check(ids[2], Equals, firstIDs[2])
+}
+
+func (s *requestrulesSuite) TestPolicyRulesShadowUserRules(c *C) {
+	rdb := s.newRuleDBWithPolicy(c, policyRulesJSON)
+
+	homeConstraints := func(pattern string, permissions ...string) *prompting.Constraints {
+		constraints := &prompting.Constraints{
+			InterfaceSpecific: &prompting.InterfaceSpecificConstraintsHome{
+				Pattern: mustParsePathPattern(c, pattern),
+			},
+			Permissions: make(prompting.PermissionMap),
+		}
+		for _, permission := range permissions {
+			constraints.Permissions[permission] = &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever}
+		}
+		return constraints
+	}
+
+	for _, testCase := range []struct {
+		snap        string
+		iface       string
+		constraints *prompting.Constraints
+		shadowed    bool
+	}{
+		// Everything below a path matched by a policy rule for every snap
+		{"firefox", "home", homeConstraints("/home/test/.ssh/**", "write"), true},
+		{"firefox", "home", homeConstraints("/home/test/.ssh/{config,known_hosts}", "write"), true},
+		// A path matched by a policy rule for the snap
+		{"ssh-client", "home", homeConstraints("/home/test/.ssh/known_hosts", "write"), true},
+		// Identical pattern
+		{"thunderbird", "camera", &prompting.Constraints{
+			InterfaceSpecific: &prompting.InterfaceSpecificConstraintsEmpty{},
+			Permissions: prompting.PermissionMap{
+				"access": &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+			},
+		}, true},
+		// Some permission or some path is not matched by policy rules
+		{"firefox", "home", homeConstraints("/home/test/.ssh/**", "read", "write"), false},
+		{"firefox", "home", homeConstraints("/home/test/{.ssh,Downloads}/**", "write"), false},
+		{"firefox", "home", homeConstraints("/home/test/.ssh-other/**", "write"), false},
+		// Paths with wildcards are only compared with policy rules matching
+		// everything below a path
+		{"firefox", "home", homeConstraints("/home/*/.ssh/id_*", "write"), false},
+	} {
+		rule, err := rdb.AddRule(s.defaultUser, testCase.snap, testCase.iface, testCase.constraints)
+		if testCase.shadowed {
+			c.Check(err, ErrorMatches, "cannot add rule: rule has no effect, since policy rules set by the administrator take precedence over it", Commentf("pattern: %s", testCase.constraints.PathPattern()))
+			c.Check(errors.Is(err, prompting_errors.ErrRuleShadowedByPolicy), Equals, true)
+			continue
+		}
+		c.Check(err, IsNil, Commentf("pattern: %s", testCase.constraints.PathPattern()))
+		_, err = rdb.RemoveRule(s.defaultUser, rule.ID)
+		c.Assert(err, IsNil)
+	}
+
+	// A rule cannot be patched to be shadowed
+	rule, err := rdb.AddRule(s.defaultUser, "firefox", "home", homeConstraints("/home/test/.ssh/**", "read", "write"))
+	c.Assert(err, IsNil)
+	patch := &prompting.RuleConstraintsPatch{
+		Permissions: prompting.RulePermissionMapPatch{
+			"read": nil,
+		},
+	}
+	_, err = rdb.PatchRule(s.defaultUser, rule.ID, patch)
+	c.Check(errors.Is(err, prompting_errors.ErrRuleShadowedByPolicy), Equals, true)
+	unchanged, err := rdb.RuleWithID(s.defaultUser, rule.ID)
+	c.Assert(err, IsNil)
+	c.Check(unchanged, DeepEquals, rule)
+
+	// Nor imported
+	_, err = rdb.ImportRules(s.defaultUser, []*requestrules.ImportedRule{
+		{Snap: "firefox", Interface: "home", Constraints: homeConstraints("/home/test/Downloads/**", "read")},
+		{Snap: "firefox", Interface: "home", Constraints: homeConstraints("/home/test/.ssh/config", "write")},
+	})
+	c.Check(errors.Is(err, prompting_errors.ErrRuleShadowedByPolicy), Equals, true)
+	c.Check(rdb.RulesForSnapInterface(s.defaultUser, "firefox", "home"), HasLen, 2)
+}
+
+func (s *requestrulesSuite) TestPolicyRulesNoFile(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+	c.Check(rdb.Rules(s.defaultUser), HasLen, 0)
+}
+
+func (s *requestrulesSuite) TestPolicyRulesErrors(c *C) {
+	restore := requestrules.MockPolicyFileOwner(uint32(os.Getuid()))
+	defer restore()
+
+	for _, testCase := range []struct {
+		content string
+		mode    os.FileMode
+		owner   uint32
+		errStr  string
+	}{
+		{
+			content: policyRulesJSON,
+			mode:    0o644,
+			owner:   uint32(os.Getuid()) + 1,
+			errStr:  `cannot use policy file .*/policy-rules.json: must be owned by root`,
+		},
+		{
+			content: policyRulesJSON,
+			mode:    0o664,
+			errStr:  `cannot use policy file .*/policy-rules.json: must not be writable by group or others`,
+		},
+		{
+			content: `{"rules":`,
+			errStr:  `cannot decode policy file: unexpected EOF`,
+		},
+		{
+			content: `{"rules":[{"constraints":{}}]}`,
+			errStr:  `cannot load policy rule 0: must have non-empty "interface" field`,
+		},
+		{
+			content: `{"rules":[{"interface":"home","constraints":{"path-pattern":"/home/**","permissions":{"write":{"outcome":"deny","lifespan":"timespan","duration":"10m"}}}}]}`,
+			errStr:  `cannot load policy rule 0: invalid lifespan for permission "write": policy rules must have lifespan "forever"`,
+		},
+		{
+			content: `{"rules":[{"interface":"home","constraints":{"path-pattern":"/home/*/.ssh/**","permissions":{"write":{"outcome":"deny","lifespan":"forever"}}}},{"interface":"home","constraints":{"path-pattern":"/home/*/.ssh/**","permissions":{"write":{"outcome":"allow","lifespan":"forever"}}}}]}`,
+			errStr:  `cannot load policy rule 1: a rule with conflicting path pattern and permission already exists in the rule database`,
+		},
+	} {
+		if testCase.mode == 0 {
+			testCase.mode = 0o644
+		}
+		s.writePolicyFile(c, testCase.content, testCase.mode)
+		restoreOwner := func() {}
+		if testCase.owner != 0 {
+			restoreOwner = requestrules.MockPolicyFileOwner(testCase.owner)
+		}
+		logbuf, restoreLogger := logger.MockLogger()
+
+		rdb, err := requestrules.New(s.defaultNotifyRule)
+		c.Assert(err, IsNil)
+		c.Check(logbuf.String(), Matches, `(?s).*cannot load policy rules: `+testCase.errStr+`; denying every request until the policy file is fixed.*`, Commentf("testCase: %+v", testCase))
+		c.Check(rdb.PolicyError(), ErrorMatches, testCase.errStr, Commentf("testCase: %+v", testCase))
+		c.Check(rdb.Rules(s.defaultUser), HasLen, 0)
+		c.Assert(rdb.Close(), IsNil)
+
+		restoreLogger()
+		restoreOwner()
+	}
+}
+
+func (s *requestrulesSuite) TestPolicyRulesMalformedFileDeniesRequests(c *C) {
+	restore := requestrules.MockPolicyFileOwner(uint32(os.Getuid()))
+	defer restore()
+	s.writePolicyFile(c, `{"rules":`, 0o644)
+
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+	c.Check(rdb.PolicyError(), ErrorMatches, `cannot decode policy file: unexpected EOF`)
+
+	// even a rule of the user allowing the request does not apply
+	constraints := &prompting.Constraints{
+		InterfaceSpecific: &prompting.InterfaceSpecificConstraintsHome{
+			Pattern: mustParsePathPattern(c, "/home/test/Downloads/**"),
+		},
+		Permissions: prompting.PermissionMap{
+			"read": &prompting.PermissionEntry{Outcome: prompting.OutcomeAllow, Lifespan: prompting.LifespanForever},
+		},
+	}
+	_, err = rdb.AddRule(s.defaultUser, "firefox", "home", constraints)
+	c.Assert(err, IsNil)
+
+	allowed, anyDenied, outstanding, err := rdb.IsRequestAllowed(s.defaultUser, "firefox", "home", "/home/test/Downloads/foo", []string{"read", "write"})
+	c.Assert(err, IsNil)
+	c.Check(allowed, HasLen, 0)
+	c.Check(anyDenied, Equals, true)
+	c.Check(outstanding, HasLen, 0)
+
+	check, err := rdb.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/Downloads/foo", []string{"read"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionDeny)
+	c.Assert(check.Permissions, HasLen, 1)
+	c.Check(check.Permissions[0].Source, Equals, requestrules.CheckSourcePolicy)
+	c.Check(check.Permissions[0].Reason, Equals, "the policy file cannot be loaded, so every request is denied: cannot decode policy file: unexpected EOF")
+}
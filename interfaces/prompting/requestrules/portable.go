@@ -0,0 +1,323 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestrules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/logger"
+)
+
+// PortableRule is a rule in a form which does not depend on the machine nor
+// on the user session it was created on, so that it can be exported from one
+// machine and imported on another.
+//
+// The constraints have the same format as when adding a rule, with the
+// duration of permissions with lifespan "timespan" being the time which was
+// left before they expire when the rule was exported, and with the home
+// directory of the exporting user in the path pattern replaced by $HOME.
+type PortableRule struct {
+	Snap        string                    `json:"snap"`
+	Interface   string                    `json:"interface"`
+	Constraints prompting.ConstraintsJSON `json:"constraints"`
+}
+
+// ImportedRule holds the contents of a rule to be imported.
+type ImportedRule struct {
+	Snap        string
+	Interface   string
+	Constraints *prompting.Constraints
+}
+
+// ExportRules returns the rules of the given user, optionally only those for
+// the given snap and/or interface, in portable form.
+//
+// The home directory of the user in path patterns is replaced by $HOME, so that
+// the rules apply to the home directory of the user importing them.
+//
+// Policy rules are not exported. Permissions with lifespan "session" are not
+// exported either, since they only apply to the current user session, and
+// rules left without permissions are omitted.
+func (rdb *RuleDB) ExportRules(user uint32, snap string, iface string) ([]*PortableRule, error) {
+	rdb.mutex.RLock()
+	defer rdb.mutex.RUnlock()
+
+	ruleFilter := func(rule *Rule) bool {
+		return rule.User == user && (snap == "" || rule.Snap == snap) && (iface == "" || rule.Interface == iface)
+	}
+	rules := rdb.rulesInternal(ruleFilter)
+
+	currSession, err := ReadOrAssignUserSessionID(rdb, user)
+	if err != nil && !errors.Is(err, errNoUserSession) {
+		return nil, err
+	}
+	at := prompting.At{
+		Time:      time.Now(),
+		SessionID: currSession,
+	}
+
+	var home string
+	portableRules := make([]*PortableRule, 0, len(rules))
+	for _, rule := range rules {
+		portable, err := rule.toPortable(at)
+		if err != nil {
+			return nil, err
+		}
+		if portable == nil {
+			continue
+		}
+		if pathPattern, ok := portable.Constraints["path-pattern"]; ok {
+			if home == "" {
+				home, err = userHomeDir(user)
+				if err != nil {
+					return nil, err
+				}
+			}
+			portable.Constraints["path-pattern"], err = replaceHomeDir(pathPattern, home, portableHomeDir)
+			if err != nil {
+				return nil, fmt.Errorf("cannot export rule %s: %w", rule.ID, err)
+			}
+		}
+		portableRules = append(portableRules, portable)
+	}
+	return portableRules, nil
+}
+
+// portableHomeDir stands for the home directory of the user in the path
+// patterns of portable rules.
+const portableHomeDir = "$HOME"
+
+var userLookupId = user.LookupId
+
+// MockUserLookupId allows tests which export or import rules to mock the
+// lookup of the home directory of users.
+func MockUserLookupId(f func(uid string) (*user.User, error)) (restore func()) {
+	old := userLookupId
+	userLookupId = f
+	return func() {
+		userLookupId = old
+	}
+}
+
+// userHomeDir returns the home directory of the given user.
+func userHomeDir(uid uint32) (string, error) {
+	u, err := userLookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return "", fmt.Errorf("cannot get home directory of user %d: %w", uid, err)
+	}
+	return strings.TrimSuffix(u.HomeDir, "/"), nil
+}
+
+// replaceHomeDir replaces the home directory from in the given marshalled
+// path pattern with to, if from is a whole path prefix of the pattern. Path
+// patterns start with '/', so the home directory cannot be the prefix of the
+// alternatives of their groups.
+func replaceHomeDir(pathPattern json.RawMessage, from string, to string) (json.RawMessage, error) {
+	var pattern string
+	if err := json.Unmarshal(pathPattern, &pattern); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal path pattern: %w", err)
+	}
+	if from == "" {
+		// the home directory is the root directory, which is not
+		// specific to the user
+		return pathPattern, nil
+	}
+	rest, ok := strings.CutPrefix(pattern, from)
+	if !ok || (rest != "" && rest[0] != '/') {
+		return pathPattern, nil
+	}
+	return json.Marshal(to + rest)
+}
+
+// ConstraintsForUser returns the constraints of the portable rule with $HOME
+// in the path pattern replaced by the home directory of the given user.
+func (rule *PortableRule) ConstraintsForUser(user uint32) (prompting.ConstraintsJSON, error) {
+	pathPattern, ok := rule.Constraints["path-pattern"]
+	if !ok || !strings.HasPrefix(string(pathPattern), `"`+portableHomeDir) {
+		return rule.Constraints, nil
+	}
+	home, err := userHomeDir(user)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := replaceHomeDir(pathPattern, portableHomeDir, home)
+	if err != nil {
+		return nil, err
+	}
+	constraints := make(prompting.ConstraintsJSON, len(rule.Constraints))
+	for key, value := range rule.Constraints {
+		constraints[key] = value
+	}
+	constraints["path-pattern"] = expanded
+	return constraints, nil
+}
+
+// toPortable converts the receiving rule to a portable rule, keeping only the
+// permissions which are neither expired at the given point in time nor have
+// lifespan "session". If no permission is left, returns nil.
+func (rule *Rule) toPortable(at prompting.At) (*PortableRule, error) {
+	permissions := make(prompting.PermissionMap, len(rule.Constraints.Permissions))
+	for permission, entry := range rule.Constraints.Permissions {
+		if entry.Expired(at) {
+			continue
+		}
+		switch entry.Lifespan {
+		case prompting.LifespanForever:
+			permissions[permission] = &prompting.PermissionEntry{
+				Outcome:  entry.Outcome,
+				Lifespan: entry.Lifespan,
+			}
+		case prompting.LifespanTimespan:
+			left := entry.Expiration.Sub(at.Time).Truncate(time.Second)
+			if left <= 0 {
+				continue
+			}
+			permissions[permission] = &prompting.PermissionEntry{
+				Outcome:  entry.Outcome,
+				Lifespan: entry.Lifespan,
+				Duration: left.String(),
+			}
+		}
+	}
+	if len(permissions) == 0 {
+		return nil, nil
+	}
+
+	// Reuse the marshalling of the rule constraints for the interface-specific
+	// constraints, and only replace the permissions.
+	marshalled, err := json.Marshal(rule.Constraints)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal constraints of rule %s: %w", rule.ID, err)
+	}
+	var constraintsJSON prompting.ConstraintsJSON
+	if err := json.Unmarshal(marshalled, &constraintsJSON); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal constraints of rule %s: %w", rule.ID, err)
+	}
+	constraintsJSON["permissions"], err = json.Marshal(permissions)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal permissions of rule %s: %w", rule.ID, err)
+	}
+
+	portable := &PortableRule{
+		Snap:        rule.Snap,
+		Interface:   rule.Interface,
+		Constraints: constraintsJSON,
+	}
+	return portable, nil
+}
+
+// ImportRules adds the given rules to the rules of the given user, merging
+// each with any existing rule with an identical path pattern, as when adding
+// a rule.
+//
+// Either every rule is imported, or none is: if any of the given rules
+// conflicts with an existing rule or with another of the given rules, returns
+// an error and leaves the rule database unchanged. Otherwise, returns the
+// resulting rules, and saves the database to disk.
+//
+// The caller is expected to validate the constraints and interfaces.
+func (rdb *RuleDB) ImportRules(user uint32, rules []*ImportedRule) ([]*Rule, error) {
+	rdb.mutex.Lock()
+	defer rdb.mutex.Unlock()
+
+	if rdb.maxIDMmap.IsClosed() {
+		return nil, prompting_errors.ErrPromptingClosed
+	}
+
+	currSession, err := ReadOrAssignUserSessionID(rdb, user)
+	// return all errors including when root tries to adjust rules for a user that is not logged in
+	if err != nil {
+		return nil, err
+	}
+	at := prompting.At{
+		Time:      time.Now(),
+		SessionID: currSession,
+	}
+
+	// importStep records the rule added to the database for an imported rule,
+	// and the existing rule it replaced if the imported rule was merged.
+	type importStep struct {
+		added    *Rule
+		replaced *Rule
+	}
+	steps := make([]importStep, 0, len(rules))
+	rollback := func() {
+		for i := len(steps) - 1; i >= 0; i-- {
+			rdb.removeRuleByID(steps[i].added.ID)
+			if steps[i].replaced != nil {
+				// Should not fail, since the existing rule was in the
+				// database before, and every later change was undone.
+				if err := rdb.addNewRule(steps[i].replaced, at, false); err != nil {
+					logger.Noticef("cannot restore rule %s while rolling back import: %v", steps[i].replaced.ID, err)
+				}
+			}
+		}
+	}
+
+	for i, imported := range rules {
+		newRule := rdb.makeNewRule(user, imported.Snap, imported.Interface, imported.Constraints, at)
+		existingRule, _, err := rdb.lookupRuleByPathPattern(user, imported.Snap, imported.Interface, newRule.Constraints)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		const save = false
+		addedRule, _, err := rdb.addOrMergeRule(newRule, at, save)
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("cannot import rule %d: %w", i, err)
+		}
+		steps = append(steps, importStep{added: addedRule, replaced: existingRule})
+	}
+
+	if err := rdb.save(); err != nil {
+		rollback()
+		return nil, err
+	}
+
+	// A rule may have been merged into by later imported rules, so return the
+	// rules which ended up in the database, once each.
+	importedRules := make([]*Rule, 0, len(steps))
+	seen := make(map[prompting.IDType]bool, len(steps))
+	for _, step := range steps {
+		id := step.added.ID
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		rule, err := rdb.lookupRuleByID(id)
+		if err != nil {
+			// Should not occur, since the rule was just added
+			return nil, err
+		}
+		importedRules = append(importedRules, rule)
+		rdb.notifyRule(user, id, nil)
+	}
+	logger.Debugf("imported %d rules", len(importedRules))
+	return importedRules, nil
+}
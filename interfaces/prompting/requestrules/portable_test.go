@@ -0,0 +1,266 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package requestrules_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
+)
+
+func (s *requestrulesSuite) importedRule(c *C, snap string, iface string, constraintsJSON string) *requestrules.ImportedRule {
+	var cj prompting.ConstraintsJSON
+	c.Assert(json.Unmarshal([]byte(constraintsJSON), &cj), IsNil)
+	constraints, err := prompting.UnmarshalConstraints(iface, cj)
+	c.Assert(err, IsNil)
+	return &requestrules.ImportedRule{
+		Snap:        snap,
+		Interface:   iface,
+		Constraints: constraints,
+	}
+}
+
+func (s *requestrulesSuite) mockHomeDirs(c *C) {
+	s.AddCleanup(requestrules.MockUserLookupId(func(uid string) (*user.User, error) {
+		switch uid {
+		case "1000":
+			return &user.User{Uid: uid, HomeDir: "/home/test"}, nil
+		case "1001":
+			return &user.User{Uid: uid, HomeDir: "/home/other/"}, nil
+		}
+		return nil, user.UnknownUserIdError(1002)
+	}))
+}
+
+func (s *requestrulesSuite) TestExportRules(c *C) {
+	s.mockHomeDirs(c)
+
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+
+	for _, rule := range []*requestrules.ImportedRule{
+		s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/Downloads/**","permissions":{"read":{"outcome":"allow","lifespan":"forever"},"write":{"outcome":"allow","lifespan":"timespan","duration":"1h"},"execute":{"outcome":"deny","lifespan":"session"}}}`),
+		s.importedRule(c, "firefox", "camera", `{"permissions":{"access":{"outcome":"allow","lifespan":"forever"}}}`),
+		s.importedRule(c, "thunderbird", "home", `{"path-pattern":"/home/test/Mail/**","permissions":{"read":{"outcome":"allow","lifespan":"session"}}}`),
+	} {
+		_, err := rdb.AddRule(s.defaultUser, rule.Snap, rule.Interface, rule.Constraints)
+		c.Assert(err, IsNil)
+	}
+	// Rules of other users are not exported
+	other := s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/other/**","permissions":{"read":{"outcome":"allow","lifespan":"forever"}}}`)
+	_, err = rdb.AddRule(s.defaultUser+1, other.Snap, other.Interface, other.Constraints)
+	c.Assert(err, IsNil)
+
+	exported, err := rdb.ExportRules(s.defaultUser, "", "")
+	c.Assert(err, IsNil)
+	// The thunderbird rule only has a permission with lifespan "session"
+	c.Assert(exported, HasLen, 2)
+	byInterface := make(map[string]*requestrules.PortableRule)
+	for _, rule := range exported {
+		c.Check(rule.Snap, Equals, "firefox")
+		byInterface[rule.Interface] = rule
+	}
+
+	home := byInterface["home"]
+	c.Assert(home, NotNil)
+	c.Check(string(home.Constraints["path-pattern"]), Equals, `"$HOME/Downloads/**"`)
+	var permissions map[string]map[string]string
+	c.Assert(json.Unmarshal(home.Constraints["permissions"], &permissions), IsNil)
+	c.Check(permissions, HasLen, 2)
+	c.Check(permissions["read"], DeepEquals, map[string]string{"outcome": "allow", "lifespan": "forever"})
+	c.Check(permissions["write"]["outcome"], Equals, "allow")
+	c.Check(permissions["write"]["lifespan"], Equals, "timespan")
+	duration, err := time.ParseDuration(permissions["write"]["duration"])
+	c.Assert(err, IsNil)
+	c.Check(duration > 59*time.Minute && duration <= time.Hour, Equals, true, Commentf("duration: %s", duration))
+
+	camera := byInterface["camera"]
+	c.Assert(camera, NotNil)
+	c.Check(camera.Constraints, DeepEquals, prompting.ConstraintsJSON{
+		"permissions": json.RawMessage(`{"access":{"outcome":"allow","lifespan":"forever"}}`),
+	})
+
+	exported, err = rdb.ExportRules(s.defaultUser, "firefox", "camera")
+	c.Assert(err, IsNil)
+	c.Check(exported, DeepEquals, []*requestrules.PortableRule{camera})
+
+	exported, err = rdb.ExportRules(s.defaultUser, "thunderbird", "")
+	c.Assert(err, IsNil)
+	c.Check(exported, HasLen, 0)
+
+	// Exported rules can be imported back once $HOME is expanded
+	for _, rule := range []*requestrules.PortableRule{home, camera} {
+		constraintsJSON, err := rule.ConstraintsForUser(s.defaultUser)
+		c.Assert(err, IsNil)
+		constraints, err := prompting.UnmarshalConstraints(rule.Interface, constraintsJSON)
+		c.Check(err, IsNil)
+		c.Check(constraints.PathPattern(), NotNil)
+	}
+	constraintsJSON, err := home.ConstraintsForUser(s.defaultUser)
+	c.Assert(err, IsNil)
+	c.Check(string(constraintsJSON["path-pattern"]), Equals, `"/home/test/Downloads/**"`)
+	// The exported rule is left unchanged
+	c.Check(string(home.Constraints["path-pattern"]), Equals, `"$HOME/Downloads/**"`)
+}
+
+func (s *requestrulesSuite) TestExportRulesHomeDir(c *C) {
+	s.mockHomeDirs(c)
+
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+
+	for _, pattern := range []string{"/home/test", "/home/test/{Mail,Documents}/**", "/home/testing/**", "/tmp/home/test/**"} {
+		rule := s.importedRule(c, "firefox", "home", fmt.Sprintf(`{"path-pattern":%q,"permissions":{"read":{"outcome":"allow","lifespan":"forever"}}}`, pattern))
+		_, err = rdb.AddRule(s.defaultUser, rule.Snap, rule.Interface, rule.Constraints)
+		c.Assert(err, IsNil)
+	}
+
+	exported, err := rdb.ExportRules(s.defaultUser, "", "")
+	c.Assert(err, IsNil)
+	c.Assert(exported, HasLen, 4)
+	// Only a whole path prefix of the pattern is replaced
+	var patterns []string
+	for _, rule := range exported {
+		patterns = append(patterns, string(rule.Constraints["path-pattern"]))
+	}
+	c.Check(patterns, DeepEquals, []string{`"$HOME"`, `"$HOME/{Mail,Documents}/**"`, `"/home/testing/**"`, `"/tmp/home/test/**"`})
+
+	// The rules apply to the home directory of the importing user
+	patterns = nil
+	for _, rule := range exported {
+		constraintsJSON, err := rule.ConstraintsForUser(s.defaultUser + 1)
+		c.Assert(err, IsNil)
+		patterns = append(patterns, string(constraintsJSON["path-pattern"]))
+	}
+	c.Check(patterns, DeepEquals, []string{`"/home/other"`, `"/home/other/{Mail,Documents}/**"`, `"/home/testing/**"`, `"/tmp/home/test/**"`})
+
+	_, err = exported[0].ConstraintsForUser(s.defaultUser + 2)
+	c.Check(err, ErrorMatches, `cannot get home directory of user 1002: user: unknown userid 1002`)
+
+	// Rules of users whose home directory is unknown cannot be exported
+	rule := s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/**","permissions":{"read":{"outcome":"allow","lifespan":"forever"}}}`)
+	_, err = rdb.AddRule(s.defaultUser+2, rule.Snap, rule.Interface, rule.Constraints)
+	c.Assert(err, IsNil)
+	_, err = rdb.ExportRules(s.defaultUser+2, "", "")
+	c.Check(err, ErrorMatches, fmt.Sprintf(`cannot get home directory of user %d: .*`, s.defaultUser+2))
+}
+
+func (s *requestrulesSuite) TestImportRules(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+
+	existing := s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/Downloads/**","permissions":{"read":{"outcome":"allow","lifespan":"forever"}}}`)
+	existingRule, err := rdb.AddRule(s.defaultUser, existing.Snap, existing.Interface, existing.Constraints)
+	c.Assert(err, IsNil)
+	s.ruleNotices = s.ruleNotices[:0]
+
+	rules, err := rdb.ImportRules(s.defaultUser, []*requestrules.ImportedRule{
+		// merged with the existing rule
+		s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/Downloads/**","permissions":{"write":{"outcome":"allow","lifespan":"forever"}}}`),
+		s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/.ssh/**","permissions":{"read":{"outcome":"deny","lifespan":"forever"}}}`),
+		// merged with the previous imported rule
+		s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/.ssh/**","permissions":{"write":{"outcome":"deny","lifespan":"timespan","duration":"10m"}}}`),
+		s.importedRule(c, "thunderbird", "camera", `{"permissions":{"access":{"outcome":"allow","lifespan":"forever"}}}`),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(rules, HasLen, 3)
+
+	c.Check(rules[0].ID, Equals, existingRule.ID)
+	c.Check(rules[0].Constraints.Permissions, HasLen, 2)
+	c.Check(rules[1].Constraints.PathPattern().String(), Equals, "/home/test/.ssh/**")
+	c.Check(rules[1].Constraints.Permissions, HasLen, 2)
+	c.Check(rules[1].Constraints.Permissions["write"].Lifespan, Equals, prompting.LifespanTimespan)
+	c.Check(rules[2].Snap, Equals, "thunderbird")
+	for _, rule := range rules {
+		c.Check(rule.User, Equals, s.defaultUser)
+	}
+
+	c.Check(s.ruleNotices, HasLen, 3)
+	for i, notice := range s.ruleNotices {
+		c.Check(notice.userID, Equals, s.defaultUser)
+		c.Check(notice.ruleID, Equals, rules[i].ID)
+		c.Check(notice.data, IsNil)
+	}
+
+	c.Check(rdb.Rules(s.defaultUser), HasLen, 3)
+	allowed, anyDenied, _, err := rdb.IsRequestAllowed(s.defaultUser, "firefox", "home", "/home/test/.ssh/id_rsa", []string{"write"})
+	c.Check(err, IsNil)
+	c.Check(allowed, HasLen, 0)
+	c.Check(anyDenied, Equals, true)
+
+	// The imported rules were saved
+	data, err := os.ReadFile(filepath.Join(dirs.SnapInterfacesRequestsStateDir, "request-rules.json"))
+	c.Assert(err, IsNil)
+	var saved requestrules.RulesDBJSON
+	c.Assert(json.Unmarshal(data, &saved), IsNil)
+	c.Check(saved.Rules, HasLen, 3)
+}
+
+func (s *requestrulesSuite) TestImportRulesConflict(c *C) {
+	rdb, err := requestrules.New(s.defaultNotifyRule)
+	c.Assert(err, IsNil)
+	defer rdb.Close()
+
+	existing := s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/Downloads/**","permissions":{"read":{"outcome":"allow","lifespan":"forever"}}}`)
+	existingRule, err := rdb.AddRule(s.defaultUser, existing.Snap, existing.Interface, existing.Constraints)
+	c.Assert(err, IsNil)
+	s.ruleNotices = s.ruleNotices[:0]
+
+	for _, conflicting := range []*requestrules.ImportedRule{
+		// conflicts with the existing rule
+		s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/{Downloads,Documents}/**","permissions":{"read":{"outcome":"deny","lifespan":"forever"}}}`),
+		// conflicts with the existing rule after merging
+		s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/Downloads/**","permissions":{"read":{"outcome":"deny","lifespan":"forever"}}}`),
+		// conflicts with another imported rule
+		s.importedRule(c, "thunderbird", "home", `{"path-pattern":"/home/test/{Mail,Documents}/**","permissions":{"write":{"outcome":"deny","lifespan":"forever"}}}`),
+	} {
+		rules, err := rdb.ImportRules(s.defaultUser, []*requestrules.ImportedRule{
+			s.importedRule(c, "firefox", "home", `{"path-pattern":"/home/test/Downloads/**","permissions":{"write":{"outcome":"allow","lifespan":"forever"}}}`),
+			s.importedRule(c, "thunderbird", "home", `{"path-pattern":"/home/test/Mail/**","permissions":{"write":{"outcome":"allow","lifespan":"forever"}}}`),
+			conflicting,
+		})
+		c.Check(err, ErrorMatches, "cannot import rule 2: "+prompting_errors.ErrRuleConflict.Error())
+		c.Check(rules, IsNil)
+
+		// Nothing changed
+		c.Check(rdb.Rules(s.defaultUser), DeepEquals, []*requestrules.Rule{existingRule})
+		allowed, _, outstanding, err := rdb.IsRequestAllowed(s.defaultUser, "firefox", "home", "/home/test/Downloads/foo", []string{"read", "write"})
+		c.Check(err, IsNil)
+		c.Check(allowed, DeepEquals, []string{"read"})
+		c.Check(outstanding, DeepEquals, []string{"write"})
+		_, _, outstanding, err = rdb.IsRequestAllowed(s.defaultUser, "thunderbird", "home", "/home/test/Mail/foo", []string{"write"})
+		c.Check(err, IsNil)
+		c.Check(outstanding, DeepEquals, []string{"write"})
+		c.Check(s.ruleNotices, HasLen, 0)
+	}
+}
@@ -54,6 +54,9 @@ var (
 )
 
 // Rule stores the contents of a request rule.
+//
+// Policy rules are set by the administrator and apply to every user, so their
+// user is always 0, and they apply to every snap if their snap is empty.
 type Rule struct {
 	ID          prompting.IDType           `json:"id"`
 	Timestamp   time.Time                  `json:"timestamp"`
@@ -61,6 +64,7 @@ type Rule struct {
 	Snap        string                     `json:"snap"`
 	Interface   string                     `json:"interface"`
 	Constraints *prompting.RuleConstraints `json:"constraints"`
+	Policy      bool                       `json:"policy,omitempty"`
 }
 
 func (rule *Rule) UnmarshalJSON(data []byte) error {
@@ -71,6 +75,7 @@ func (rule *Rule) UnmarshalJSON(data []byte) error {
 		Snap        string                    `json:"snap"`
 		Interface   string                    `json:"interface"`
 		Constraints prompting.ConstraintsJSON `json:"constraints"`
+		Policy      bool                      `json:"policy,omitempty"`
 	}
 	var intermediate ruleJSON
 	if err := json.Unmarshal(data, &intermediate); err != nil {
@@ -86,6 +91,7 @@ func (rule *Rule) UnmarshalJSON(data []byte) error {
 	rule.Snap = intermediate.Snap
 	rule.Interface = intermediate.Interface
 	rule.Constraints = constraints
+	rule.Policy = intermediate.Policy
 	return nil
 }
 
@@ -174,7 +180,12 @@ type RuleDB struct {
 	// is matched by existing rules, and which of those rules has precedence.
 	perUser map[uint32]*userDB
 
-	dbPath string
+	// policy holds the rules set by the administrator, which take precedence
+	// over the rules of every user.
+	policy *policyDB
+
+	dbPath     string
+	policyPath string
 	// notifyRule is a closure which will be called to record a notice when a
 	// rule is added, patched, or removed.
 	notifyRule func(userID uint32, ruleID prompting.IDType, data map[string]string) error
@@ -184,8 +195,10 @@ type RuleDB struct {
 	userSessionIDMu sync.Mutex
 }
 
-// New creates a new rule database, loads existing rules from the database file,
-// and returns the populated database.
+// New creates a new rule database, loads existing rules from the database file
+// and policy rules from the policy file, and returns the populated database.
+// If the policy file exists but cannot be loaded, every request is denied, see
+// PolicyError.
 //
 // The given notifyRule closure may be called before `New()` returns, if a
 // previously-saved rule has expired or if there are conflicts between rules.
@@ -197,6 +210,7 @@ type RuleDB struct {
 func New(notifyRule func(userID uint32, ruleID prompting.IDType, data map[string]string) error) (*RuleDB, error) {
 	maxIDFilepath := filepath.Join(dirs.SnapInterfacesRequestsStateDir, "request-rule-max-id")
 	rulesFilepath := filepath.Join(dirs.SnapInterfacesRequestsStateDir, "request-rules.json")
+	policyFilepath := filepath.Join(dirs.SnapInterfacesRequestsStateDir, "policy-rules.json")
 
 	if err := os.MkdirAll(dirs.SnapInterfacesRequestsStateDir, 0o755); err != nil {
 		return nil, fmt.Errorf("cannot create interfaces requests state directory: %w", err)
@@ -211,10 +225,14 @@ func New(notifyRule func(userID uint32, ruleID prompting.IDType, data map[string
 		maxIDMmap:  maxIDMmap,
 		notifyRule: notifyRule,
 		dbPath:     rulesFilepath,
+		policyPath: policyFilepath,
 	}
 	if err = rdb.load(); err != nil {
 		logger.Noticef("cannot load rule database: %v; using new empty rule database", err)
 	}
+	if err = rdb.loadPolicy(); err != nil {
+		logger.Noticef("cannot load policy rules: %v; denying every request until the policy file is fixed", err)
+	}
 	return rdb, nil
 }
 
@@ -434,10 +452,19 @@ func (rdb *RuleDB) addRuleToRulesList(rule *Rule) error {
 // from the existing rule are pruned.
 //
 // If there is a conflicting rule, or if there is an error while saving the DB,
-// returns an error, and the rule DB is left unchanged.
+// returns an error, and the rule DB is left unchanged. If policy rules take
+// precedence over the given rule for every path and permission, returns
+// prompting_errors.ErrRuleShadowedByPolicy, since the rule would have no
+// effect.
 //
 // The caller must ensure that the database lock is held for writing.
 func (rdb *RuleDB) addOrMergeRule(rule *Rule, at prompting.At, save bool) (addedOrMergedRule *Rule, merged bool, err error) {
+	// The policy is loaded after the rules of users, so rules saved before
+	// the policy changed are kept.
+	if rdb.policy != nil && rdb.policy.shadowsRule(rule) {
+		return nil, false, prompting_errors.ErrRuleShadowedByPolicy
+	}
+
 	// Check if rule with identical path pattern exists.
 	existingRule, exists, err := rdb.lookupRuleByPathPattern(rule.User, rule.Snap, rule.Interface, rule.Constraints)
 	if err != nil {
@@ -1079,7 +1106,8 @@ func (rdb *RuleDB) makeNewRule(user uint32, snap string, iface string, constrain
 }
 
 // IsRequestAllowed checks whether a request with the given parameters is
-// allowed or denied by existing rules.
+// allowed or denied by existing rules. Policy rules take precedence over the
+// rules of the given user.
 //
 // If any of the given permissions are allowed, they are returned as
 // allowedPerms. If any permissions are denied, then returns anyDenied as true.
@@ -1120,20 +1148,51 @@ var isPathPermAllowed = (*RuleDB).isPathPermAllowed
 
 // isPathPermAllowed checks whether the given path with the given permission is
 // allowed or denied by existing rules for the given user, snap, and interface,
-// at the given point in time.
+// at the given point in time. If any policy rule applies, the rules of the user
+// are not considered.
 //
 // If no rule applies, returns prompting_errors.ErrNoMatchingRule.
 func (rdb *RuleDB) isPathPermAllowed(user uint32, snap string, iface string, path string, permission string, at prompting.At) (bool, error) {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
+	allowed, err := rdb.policy.isPathPermAllowed(snap, iface, path, permission)
+	if !errors.Is(err, prompting_errors.ErrNoMatchingRule) {
+		return allowed, err
+	}
 	permissionMap := rdb.permissionDBForUserSnapInterfacePermission(user, snap, iface, permission)
+	return isPathPermAllowedByPermissionDB(permissionMap, path, at)
+}
+
+// isPathPermAllowedByPermissionDB checks whether the given path is allowed or
+// denied by the rules in the given permission DB at the given point in time.
+//
+// If no rule applies, returns prompting_errors.ErrNoMatchingRule.
+//
+// The caller must ensure that the database lock is held.
+func isPathPermAllowedByPermissionDB(permissionMap *permissionDB, path string, at prompting.At) (bool, error) {
+	_, matchingEntry, err := matchPathInPermissionDB(permissionMap, path, at)
+	if err != nil {
+		return false, err
+	}
+	return matchingEntry.Outcome.AsBool()
+}
+
+// matchPathInPermissionDB returns the entries of the unexpired path pattern
+// variants in the given permission DB which match the given path at the given
+// point in time, along with the entry of the variant which has the highest
+// precedence among them, and thus determines the outcome for the path.
+//
+// If no variant matches, returns prompting_errors.ErrNoMatchingRule.
+//
+// The caller must ensure that the database lock is held.
+func matchPathInPermissionDB(permissionMap *permissionDB, path string, at prompting.At) (matchingEntries []variantEntry, highestPrecedenceEntry variantEntry, err error) {
 	if permissionMap == nil {
-		return false, prompting_errors.ErrNoMatchingRule
+		return nil, variantEntry{}, prompting_errors.ErrNoMatchingRule
 	}
 	variantMap := permissionMap.VariantEntries
 	var matchingVariants []patterns.PatternVariant
-	for variantStr, variantEntry := range variantMap {
-		if variantEntry.expired(at) {
+	for variantStr, entry := range variantMap {
+		if entry.expired(at) {
 			continue
 		}
 
@@ -1143,41 +1202,45 @@ func (rdb *RuleDB) isPathPermAllowed(user uint32, snap string, iface string, pat
 		matched, err := patterns.PathPatternMatches(variantStr, path)
 		if err != nil {
 			// Only possible error is ErrBadPattern, which should not occur
-			return false, fmt.Errorf("internal error: while matching path pattern: %w", err)
+			return nil, variantEntry{}, fmt.Errorf("internal error: while matching path pattern: %w", err)
 		}
 		if matched {
-			matchingVariants = append(matchingVariants, variantEntry.Variant)
+			matchingVariants = append(matchingVariants, entry.Variant)
+			matchingEntries = append(matchingEntries, entry)
 		}
 	}
 	if len(matchingVariants) == 0 {
-		return false, prompting_errors.ErrNoMatchingRule
+		return nil, variantEntry{}, prompting_errors.ErrNoMatchingRule
 	}
 	highestPrecedenceVariant, err := patterns.HighestPrecedencePattern(matchingVariants, path)
 	if err != nil {
-		return false, err
+		return nil, variantEntry{}, err
 	}
-	matchingEntry := variantMap[highestPrecedenceVariant.String()]
-	return matchingEntry.Outcome.AsBool()
+	return matchingEntries, variantMap[highestPrecedenceVariant.String()], nil
 }
 
-// RuleWithID returns the rule with the given ID.
+// RuleWithID returns the rule with the given ID, which may be a policy rule.
 // If the rule is not found, returns ErrRuleNotFound.
 // If the rule does not apply to the given user, returns
 // prompting_errors.ErrRuleNotAllowed.
 func (rdb *RuleDB) RuleWithID(user uint32, id prompting.IDType) (*Rule, error) {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
+	if rule := rdb.policy.ruleWithID(id); rule != nil {
+		return rule, nil
+	}
 	return rdb.lookupRuleByIDForUser(user, id)
 }
 
-// Rules returns all rules which apply to the given user.
+// Rules returns all rules which apply to the given user, including policy
+// rules.
 func (rdb *RuleDB) Rules(user uint32) []*Rule {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
 	ruleFilter := func(rule *Rule) bool {
 		return rule.User == user
 	}
-	return rdb.rulesInternal(ruleFilter)
+	return append(rdb.rulesInternal(ruleFilter), rdb.policy.rulesForSnapInterface("", "")...)
 }
 
 // rulesInternal returns all rules matching the given filter.
@@ -1218,36 +1281,37 @@ func (rdb *RuleDB) rulesInternal(ruleFilter func(rule *Rule) bool) []*Rule {
 	return rules
 }
 
-// RulesForSnap returns all rules which apply to the given user and snap.
+// RulesForSnap returns all rules which apply to the given user and snap,
+// including policy rules.
 func (rdb *RuleDB) RulesForSnap(user uint32, snap string) []*Rule {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
 	ruleFilter := func(rule *Rule) bool {
 		return rule.User == user && rule.Snap == snap
 	}
-	return rdb.rulesInternal(ruleFilter)
+	return append(rdb.rulesInternal(ruleFilter), rdb.policy.rulesForSnapInterface(snap, "")...)
 }
 
 // RulesForInterface returns all rules which apply to the given user and
-// interface.
+// interface, including policy rules.
 func (rdb *RuleDB) RulesForInterface(user uint32, iface string) []*Rule {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
 	ruleFilter := func(rule *Rule) bool {
 		return rule.User == user && rule.Interface == iface
 	}
-	return rdb.rulesInternal(ruleFilter)
+	return append(rdb.rulesInternal(ruleFilter), rdb.policy.rulesForSnapInterface("", iface)...)
 }
 
 // RulesForSnapInterface returns all rules which apply to the given user, snap,
-// and interface.
+// and interface, including policy rules.
 func (rdb *RuleDB) RulesForSnapInterface(user uint32, snap string, iface string) []*Rule {
 	rdb.mutex.RLock()
 	defer rdb.mutex.RUnlock()
 	ruleFilter := func(rule *Rule) bool {
 		return rule.User == user && rule.Snap == snap && rule.Interface == iface
 	}
-	return rdb.rulesInternal(ruleFilter)
+	return append(rdb.rulesInternal(ruleFilter), rdb.policy.rulesForSnapInterface(snap, iface)...)
 }
 
 // lookupRuleByIDForUser returns the rule with the given ID, if it exists, for the
@@ -1267,6 +1331,7 @@ func (rdb *RuleDB) lookupRuleByIDForUser(user uint32, id prompting.IDType) (*Rul
 
 // RemoveRule the rule with the given ID from the rule database. If the rule
 // does not apply to the given user, returns prompting_errors.ErrRuleNotAllowed.
+// If the rule is a policy rule, returns prompting_errors.ErrRuleIsPolicy.
 // If successful, saves the database to disk.
 func (rdb *RuleDB) RemoveRule(user uint32, id prompting.IDType) (*Rule, error) {
 	rdb.mutex.Lock()
@@ -1276,6 +1341,10 @@ func (rdb *RuleDB) RemoveRule(user uint32, id prompting.IDType) (*Rule, error) {
 		return nil, prompting_errors.ErrPromptingClosed
 	}
 
+	if rdb.policy.ruleWithID(id) != nil {
+		return nil, prompting_errors.ErrRuleIsPolicy
+	}
+
 	rule, err := rdb.lookupRuleByIDForUser(user, id)
 	if err != nil {
 		// The rule doesn't exist or the user doesn't have access
@@ -1411,6 +1480,9 @@ func (rdb *RuleDB) RemoveRulesForSnapInterface(user uint32, snap string, iface s
 // error while modifying the rule, the rule is rolled back to its previous
 // unmodified state, leaving the database unchanged. If the database is changed,
 // it is saved to disk.
+//
+// Policy rules cannot be patched, so if the rule is a policy rule, returns
+// prompting_errors.ErrRuleIsPolicy.
 func (rdb *RuleDB) PatchRule(user uint32, id prompting.IDType, constraintsPatch *prompting.RuleConstraintsPatch) (r *Rule, err error) {
 	rdb.mutex.Lock()
 	defer rdb.mutex.Unlock()
@@ -1419,6 +1491,10 @@ func (rdb *RuleDB) PatchRule(user uint32, id prompting.IDType, constraintsPatch
 		return nil, prompting_errors.ErrPromptingClosed
 	}
 
+	if rdb.policy.ruleWithID(id) != nil {
+		return nil, prompting_errors.ErrRuleIsPolicy
+	}
+
 	origRule, err := rdb.lookupRuleByIDForUser(user, id)
 	if err != nil {
 		return nil, err
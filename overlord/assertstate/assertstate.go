@@ -44,12 +44,16 @@ import (
 // Add the given assertion to the system assertion database.
 func Add(s *state.State, a asserts.Assertion) error {
 	// TODO: deal together with asserts itself with (cascading) side effects of possible assertion updates
-	return cachedDB(s).Add(a)
+	if err := cachedDB(s).Add(a); err != nil {
+		return err
+	}
+	logAssertionAdded(a)
+	return nil
 }
 
 // AddBatch adds the given assertion batch to the system assertion database.
 func AddBatch(s *state.State, batch *asserts.Batch, opts *asserts.CommitOptions) error {
-	return batch.CommitTo(cachedDB(s), opts)
+	return batch.CommitToAndObserve(cachedDB(s), logAssertionAdded, opts)
 }
 
 func findError(format string, ref *asserts.Ref, err error) error {
@@ -1020,6 +1024,7 @@ func TryEnforcedValidationSets(st *state.State, validationSets []string, userID
 	for _, tr := range newTracking {
 		UpdateValidationSet(st, tr)
 	}
+	logValidationSetsEnforced(st, userID, newTracking...)
 
 	return addCurrentTrackingToValidationSetsHistory(st)
 }
@@ -1044,7 +1049,7 @@ func commitValidationSetPrerequisites(db, source *asserts.Database, valsets []*a
 		}
 	}
 
-	if err := batch.CommitTo(db, nil); err != nil {
+	if err := batch.CommitToAndObserve(db, logAssertionAdded, nil); err != nil {
 		return err
 	}
 
@@ -1128,6 +1133,7 @@ func ApplyLocalEnforcedValidationSets(st *state.State, vsKeys map[string][]strin
 	for _, tr := range valsetsTracking {
 		UpdateValidationSet(st, tr)
 	}
+	logValidationSetsEnforced(st, 0, valsetsTracking...)
 
 	return addCurrentTrackingToValidationSetsHistory(st)
 }
@@ -1194,13 +1200,14 @@ func ApplyEnforcedValidationSets(st *state.State, valsets map[string]*asserts.Va
 		return err
 	}
 
-	if err := batch.CommitTo(db, nil); err != nil {
+	if err := batch.CommitToAndObserve(db, logAssertionAdded, nil); err != nil {
 		return err
 	}
 
 	for _, tr := range valsetsTracking {
 		UpdateValidationSet(st, tr)
 	}
+	logValidationSetsEnforced(st, userID, valsetsTracking...)
 
 	return addCurrentTrackingToValidationSetsHistory(st)
 }
@@ -1279,6 +1286,7 @@ func FetchAndApplyEnforcedValidationSet(st *state.State, accountID, name string,
 	}
 
 	UpdateValidationSet(st, &tr)
+	logValidationSetsEnforced(st, userID, &tr)
 	err = addCurrentTrackingToValidationSetsHistory(st)
 	return &tr, err
 }
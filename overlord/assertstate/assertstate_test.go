@@ -52,6 +52,8 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/swfeats/swfeatstest"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/seclog"
+	"github.com/snapcore/snapd/seclog/seclogtest"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/integrity"
 	"github.com/snapcore/snapd/snap/naming"
@@ -187,6 +189,36 @@ func (s *assertMgrSuite) TestAddBatch(c *C) {
 	c.Check(devAcct.(*asserts.Account).Username(), Equals, "developer1")
 }
 
+func (s *assertMgrSuite) TestAddSecurityLog(c *C) {
+	buf := &bytes.Buffer{}
+	seclog.Setup(seclogtest.MockSecurityLogger(buf))
+	defer seclog.Setup(seclog.NewNopLogger())
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	storeKey := s.storeSigning.StoreAccountKey("")
+	err := assertstate.Add(s.state, storeKey)
+	c.Assert(err, IsNil)
+	c.Check(buf.String(), Equals, fmt.Sprintf("assert_added Added assertion account-key:%[1]s [assertion=seclog.Assertion{Type:\"account-key\", PrimaryKey:\"%[1]s\", Revision:0, SignKeyID:\"%[2]s\"}]\n",
+		storeKey.PublicKeyID(), storeKey.SignKeyID()))
+	buf.Reset()
+
+	// not logged if not added
+	err = assertstate.Add(s.state, storeKey)
+	c.Assert(err, NotNil)
+	c.Check(buf.String(), Equals, "")
+
+	batch := asserts.NewBatch(nil)
+	c.Assert(batch.Add(storeKey), IsNil)
+	c.Assert(batch.Add(s.dev1Acct), IsNil)
+	err = assertstate.AddBatch(s.state, batch, nil)
+	c.Assert(err, IsNil)
+	// only the newly added assertion is logged
+	c.Check(buf.String(), Equals, fmt.Sprintf("assert_added Added assertion account:%[1]s [assertion=seclog.Assertion{Type:\"account\", PrimaryKey:\"%[1]s\", Revision:0, SignKeyID:\"%[2]s\"}]\n",
+		s.dev1Acct.AccountID(), s.dev1Acct.SignKeyID()))
+}
+
 func (s *assertMgrSuite) TestAddBatchPartial(c *C) {
 	// Commit does add any successful assertion until the first error
 	s.state.Lock()
@@ -4818,8 +4850,14 @@ func (s *assertMgrSuite) testApplyLocalEnforcedValidationSets(c *C, pinnedSeq in
 	installedSnaps := []*snapasserts.InstalledSnap{
 		snapasserts.NewInstalledSnap("some-snap", "qOqKhntON3vR7kwEbVPsILm7bUViPDzz", snap.Revision{N: 1}, nil),
 	}
+	buf := &bytes.Buffer{}
+	seclog.Setup(seclogtest.MockSecurityLogger(buf))
+	defer seclog.Setup(seclog.NewNopLogger())
+
 	err := assertstate.ApplyLocalEnforcedValidationSets(st, valSets, pinnedSeqs, installedSnaps, nil)
 	c.Assert(err, IsNil)
+	c.Check(buf.String(), Equals, fmt.Sprintf("assert_validation_set_enforced User <unknown>:<unknown>:<unknown> enforced validation set %[1]s/foo=1 [user=seclog.SnapdUser{ID:0, StoreUserName:\"\", StoreUserEmail:\"\", Expiration:time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)}] [validation_set=seclog.ValidationSet{AccountID:\"%[1]s\", Name:\"foo\", Sequence:1, Pinned:%[2]t}]\n",
+		s.dev1Acct.AccountID(), pinnedSeq != 0))
 
 	var tr assertstate.ValidationSetTracking
 	err = assertstate.GetValidationSet(st, s.dev1Acct.AccountID(), "foo", &tr)
@@ -20,11 +20,14 @@
 package assertstate
 
 import (
+	"strings"
+
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/seclog"
 )
 
 // TODO: snapstate also has this, move to auth, or change a bit the approach now that we have DeviceAndAuthContext in the store?
@@ -90,8 +93,33 @@ func doFetchWithRetrieve(s *state.State, batch *asserts.Batch, retrieve func(*as
 	// TODO: trigger w. caller a global validity check if a is revoked
 	// (but try to save as much possible still), or err is a check error
 	if commitBatch {
-		return batch.CommitTo(db, nil)
+		return batch.CommitToAndObserve(db, logAssertionAdded, nil)
 	}
 
 	return nil
 }
+
+// logAssertionAdded logs the security audit event of the given assertion
+// being added to the system assertion database.
+func logAssertionAdded(a asserts.Assertion) {
+	seclog.LogAssertionAdded(seclog.Assertion{
+		Type:       a.Type().Name,
+		PrimaryKey: strings.Join(a.Ref().PrimaryKey, "/"),
+		Revision:   a.Revision(),
+		SignKeyID:  a.SignKeyID(),
+	})
+}
+
+// logValidationSetsEnforced logs the security audit events of the given
+// validation sets being enforced on behalf of the given user, if any.
+func logValidationSetsEnforced(st *state.State, userID int, trackings ...*ValidationSetTracking) {
+	user := auth.SecurityLogUser(st, userID)
+	for _, tr := range trackings {
+		seclog.LogValidationSetEnforced(user, seclog.ValidationSet{
+			AccountID: tr.AccountID,
+			Name:      tr.Name,
+			Sequence:  tr.Current,
+			Pinned:    tr.PinnedAt != 0,
+		})
+	}
+}
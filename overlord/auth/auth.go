@@ -402,6 +402,20 @@ func User(st *state.State, id int) (*UserState, error) {
 	return findUser(st, func(u *UserState) bool { return u.ID == id })
 }
 
+// SecurityLogUser returns the identity of the user with the given ID for use
+// in security audit log events. Only the ID is set if the user cannot be
+// found, and nothing if the ID is 0, meaning no user.
+func SecurityLogUser(st *state.State, id int) seclog.SnapdUser {
+	if id == 0 {
+		return seclog.SnapdUser{}
+	}
+	u, err := User(st, id)
+	if err != nil {
+		return seclog.SnapdUser{ID: int64(id)}
+	}
+	return u.snapdUser()
+}
+
 // UserByUsername returns a user from the state given its username.
 func UserByUsername(st *state.State, username string) (*UserState, error) {
 	return findUser(st, func(u *UserState) bool { return u.Username == username })
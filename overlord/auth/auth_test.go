@@ -334,6 +334,28 @@ func (as *authSuite) TestUser(c *C) {
 	c.Check(user.HasStoreAuth(), Equals, true)
 }
 
+func (as *authSuite) TestSecurityLogUser(c *C) {
+	as.state.Lock()
+	defer as.state.Unlock()
+
+	user, err := auth.NewUser(as.state, auth.NewUserParams{
+		Username:   "username",
+		Email:      "email@test.com",
+		Macaroon:   "macaroon",
+		Discharges: []string{"discharge"},
+	})
+	c.Assert(err, IsNil)
+
+	c.Check(auth.SecurityLogUser(as.state, user.ID), DeepEquals, seclog.SnapdUser{
+		ID:             int64(user.ID),
+		StoreUserName:  "username",
+		StoreUserEmail: "email@test.com",
+	})
+	// unknown users are identified by their ID only
+	c.Check(auth.SecurityLogUser(as.state, 42), DeepEquals, seclog.SnapdUser{ID: 42})
+	c.Check(auth.SecurityLogUser(as.state, 0), DeepEquals, seclog.SnapdUser{})
+}
+
 func (as *authSuite) TestUserByUsername(c *C) {
 	as.state.Lock()
 	user, err := auth.NewUser(as.state, auth.NewUserParams{
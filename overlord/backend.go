@@ -20,20 +20,34 @@
 package overlord
 
 import (
+	"fmt"
 	"time"
 
-	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 )
 
-type overlordStateBackend struct {
+// overlordStateDBBackend checkpoints the state into the state database,
+// only writing the entries that changed. The database is kept open until
+// the overlord stops, after which it is opened for each checkpoint.
+type overlordStateDBBackend struct {
 	path         string
+	db           *statedb.DB
 	ensureBefore func(d time.Duration)
 }
 
-func (osb *overlordStateBackend) Checkpoint(data []byte) error {
-	return osutil.AtomicWriteFile(osb.path, data, 0600, 0)
+func (osb *overlordStateDBBackend) Checkpoint(data []byte) error {
+	// State uses CheckpointDelta with a state.DeltaBackend
+	return fmt.Errorf("internal error: cannot checkpoint the whole state as a blob into the state database")
 }
 
-func (osb *overlordStateBackend) EnsureBefore(d time.Duration) {
+func (osb *overlordStateDBBackend) CheckpointDelta(delta *state.Delta) error {
+	if osb.db == nil {
+		return statedb.Write(osb.path, delta)
+	}
+	return osb.db.Write(delta)
+}
+
+func (osb *overlordStateDBBackend) EnsureBefore(d time.Duration) {
 	osb.ensureBefore(d)
 }
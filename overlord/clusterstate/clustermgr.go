@@ -22,8 +22,10 @@ package clusterstate
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/snapcore/snapd/features"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/swfeats"
@@ -31,19 +33,31 @@ import (
 
 var applyClusterSubclusterChangeKind = swfeats.RegisterChangeKind("apply-cluster-subcluster")
 
+func init() {
+	swfeats.RegisterEnsure("ClusterManager", "ensureClusterState")
+	swfeats.RegisterEnsure("ClusterManager", "ensureStatusExchange")
+}
+
 type ClusterManager struct {
 	state *state.State
+
+	exchangeMu sync.Mutex
+	exchange   *statusExchange
 }
 
 // Manager returns a new ClusterManager.
-func Manager(st *state.State) *ClusterManager {
-	return &ClusterManager{
+func Manager(st *state.State, runner *state.TaskRunner) *ClusterManager {
+	m := &ClusterManager{
 		state: st,
 	}
+
+	runner.AddHandler("assemble-cluster", m.doAssembleCluster, nil)
+
+	return m
 }
 
 // Ensure ensures that the device state matches the expectations defined by the
-// cluster assertion.
+// cluster assertion, and that this device exchanges statuses with its peers.
 func (m *ClusterManager) Ensure() error {
 	enabled, err := clusteringEnabled(m.state)
 	if err != nil {
@@ -51,9 +65,22 @@ func (m *ClusterManager) Ensure() error {
 	}
 
 	if !enabled {
+		m.stopStatusExchange()
 		return nil
 	}
 
+	err = m.ensureClusterState()
+
+	// statuses are exchanged on a best effort basis, with the state that was
+	// just applied
+	if err := m.ensureStatusExchange(); err != nil {
+		logger.Noticef("cannot exchange status with cluster peers: %v", err)
+	}
+
+	return err
+}
+
+func (m *ClusterManager) ensureClusterState() error {
 	m.state.Lock()
 	defer m.state.Unlock()
 
@@ -65,17 +92,22 @@ func (m *ClusterManager) Ensure() error {
 		return fmt.Errorf("cannot get cluster assertion: %w", err)
 	}
 
+	logger.Trace("ensure", "manager", "ClusterManager", "func", "ensureClusterState")
+
 	tasksets, err := applyClusterState(m.state, cluster)
 	if err != nil {
 		return err
 	}
 
+	clusterChanges := inProgressClusterChanges(m.state)
+
 	if len(tasksets) == 0 {
+		if !applyingCluster(clusterChanges, cluster.ClusterID()) {
+			return setApplied(m.state, cluster.Sequence())
+		}
 		return nil
 	}
 
-	clusterChanges := inProgressClusterChanges(m.state)
-
 	for name, tasks := range tasksets {
 		ref := clusterChangeRef{ClusterID: cluster.ClusterID(), Subcluster: name}
 
@@ -94,6 +126,11 @@ func (m *ClusterManager) Ensure() error {
 	return nil
 }
 
+// Stop stops the exchange of statuses with the peer devices of the cluster.
+func (m *ClusterManager) Stop() {
+	m.stopStatusExchange()
+}
+
 type clusterChangeRef struct {
 	ClusterID  string `json:"cluster-id"`
 	Subcluster string `json:"subcluster"`
@@ -117,6 +154,17 @@ func inProgressClusterChanges(st *state.State) map[clusterChangeRef]bool {
 	return changes
 }
 
+// applyingCluster returns whether any of the given changes applies the state of
+// the cluster with the given id.
+func applyingCluster(changes map[clusterChangeRef]bool, clusterID string) bool {
+	for ref := range changes {
+		if ref.ClusterID == clusterID {
+			return true
+		}
+	}
+	return false
+}
+
 func clusteringEnabled(st *state.State) (bool, error) {
 	st.Lock()
 	defer st.Unlock()
@@ -27,6 +27,7 @@ import (
 	"strconv"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/cluster/assemblestate"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -53,6 +54,17 @@ type clusterState struct {
 	// assertion. Maybe we should consider some sort of sequence container, like
 	// we use in snapstate?
 	Current clusterAssertionState `json:"current"`
+	// Applied is the sequence of the last cluster assertion that was fully
+	// applied on this device.
+	Applied int `json:"applied,omitempty"`
+	// Peers contains the last status received from each peer device, by
+	// device id.
+	Peers map[string]peerStatus `json:"peers,omitempty"`
+	// Fingerprints contains the base64 encoded fingerprints of the
+	// certificates that the devices of the cluster use, by device id, as
+	// learned during the assembly sessions that this device took part in.
+	// They can be known before the cluster assertion is.
+	Fingerprints map[string]string `json:"fingerprints,omitempty"`
 }
 
 // clusterAssertionState contains the information needed to find a specific
@@ -96,12 +108,15 @@ func InitializeNewCluster(st *state.State, bundle io.Reader) error {
 		return fmt.Errorf("cannot add cluster assertion bundle: %w", err)
 	}
 
+	// the fingerprints of the peers are kept, they were learned during the
+	// assembly session that the cluster assertion results from
 	st.Set("cluster", clusterState{
 		Current: clusterAssertionState{
 			ClusterID:   cluster.ClusterID(),
 			Sequence:    cluster.Sequence(),
 			AuthorityID: cluster.AuthorityID(),
 		},
+		Fingerprints: existing.Fingerprints,
 	})
 
 	// trigger an ensure pass so that the new assertion is picked up and applied
@@ -119,12 +134,12 @@ func UpdateCluster(st *state.State, bundle io.Reader) error {
 	}
 
 	var cs clusterState
-	if err := st.Get("cluster", &cs); err != nil {
-		if errors.Is(err, state.ErrNoState) {
-			return ErrNoClusterAssertion
-		}
+	if err := st.Get("cluster", &cs); err != nil && !errors.Is(err, state.ErrNoState) {
 		return err
 	}
+	if cs.Current.ClusterID == "" {
+		return ErrNoClusterAssertion
+	}
 
 	if cluster.AuthorityID() != cs.Current.AuthorityID {
 		return fmt.Errorf(
@@ -154,13 +169,14 @@ func UpdateCluster(st *state.State, bundle io.Reader) error {
 		return fmt.Errorf("cannot add cluster assertion bundle: %w", err)
 	}
 
-	st.Set("cluster", clusterState{
-		Current: clusterAssertionState{
-			ClusterID:   cluster.ClusterID(),
-			Sequence:    cluster.Sequence(),
-			AuthorityID: cluster.AuthorityID(),
-		},
-	})
+	// what was applied and the statuses of the peers are kept, they are
+	// compared against the new assertion
+	cs.Current = clusterAssertionState{
+		ClusterID:   cluster.ClusterID(),
+		Sequence:    cluster.Sequence(),
+		AuthorityID: cluster.AuthorityID(),
+	}
+	st.Set("cluster", cs)
 
 	// trigger an ensure pass so that the new assertion is picked up and applied
 	st.EnsureBefore(0)
@@ -172,12 +188,14 @@ func UpdateCluster(st *state.State, bundle io.Reader) error {
 // hold the state lock.
 func CurrentCluster(st *state.State) (*asserts.Cluster, error) {
 	var cs clusterState
-	if err := st.Get("cluster", &cs); err != nil {
-		if errors.Is(err, state.ErrNoState) {
-			return nil, ErrNoClusterAssertion
-		}
+	if err := st.Get("cluster", &cs); err != nil && !errors.Is(err, state.ErrNoState) {
 		return nil, err
 	}
+	// the fingerprints of the peers can be known before the cluster
+	// assertion is
+	if cs.Current.ClusterID == "" {
+		return nil, ErrNoClusterAssertion
+	}
 
 	headers := map[string]string{
 		"cluster-id": cs.Current.ClusterID,
@@ -195,6 +213,35 @@ func CurrentCluster(st *state.State) (*asserts.Cluster, error) {
 	return cluster, nil
 }
 
+// MembershipSeed returns the addresses to discover and the expected size of an
+// assembly session that adds devices to, or replaces devices of, the current
+// cluster as described by changes. Callers must hold the state lock.
+func MembershipSeed(st *state.State, changes assemblestate.MembershipChanges) (addresses []string, expectedSize int, err error) {
+	cluster, err := CurrentCluster(st)
+	if err != nil {
+		return nil, 0, err
+	}
+	return assemblestate.MembershipSeed(cluster, changes)
+}
+
+// PrepareMembershipUpdate returns the headers of the next cluster assertion in
+// the sequence of the current cluster, given the results of an assembly
+// session started from [MembershipSeed]. Once signed by the authority of the
+// cluster, the assertion is installed with [UpdateCluster]. Callers must hold
+// the state lock.
+func PrepareMembershipUpdate(st *state.State, ids []assemblestate.Identity, routes assemblestate.Routes, changes assemblestate.MembershipChanges) (map[string]any, error) {
+	cluster, err := CurrentCluster(st)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := assemblestate.UpdatedClusterHeaders(cluster, ids, routes, changes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot prepare update of cluster %q: %w", cluster.ClusterID(), err)
+	}
+	return headers, nil
+}
+
 func decodeClusterBundle(bundle io.Reader) (*asserts.Batch, *asserts.Cluster, error) {
 	var cluster *asserts.Cluster
 	batch := asserts.NewBatch(nil)
@@ -29,6 +29,7 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/cluster/assemblestate"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/clusterstate"
@@ -350,6 +351,114 @@ func (s *clusterStateSuite) TestRejectMultipleClusterAssertions(c *check.C) {
 	c.Assert(err, check.ErrorMatches, "cluster assertion bundle contains multiple cluster assertions")
 }
 
+func (s *clusterStateSuite) TestMembershipUpdate(c *check.C) {
+	st, stack := newStateWithStoreStack(c)
+
+	const accountID = "cluster-brand"
+	sa := registerAccount(stack, accountID)
+
+	devices := []map[string]any{
+		{
+			"id":        "1",
+			"device":    "serial-1.ubuntu-core-24-amd64.canonical",
+			"addresses": []any{"192.168.0.10:7070"},
+		},
+		{
+			"id":        "2",
+			"device":    "serial-2.ubuntu-core-24-amd64.canonical",
+			"addresses": []any{"192.168.0.11:7070"},
+		},
+	}
+	subclusters := []map[string]any{
+		{
+			"name":    "default",
+			"devices": []any{"1", "2"},
+			"snaps":   []any{},
+		},
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	bundle, _ := makeClusterBundleWithSigning(c, sa, accountID, "cluster-id", 1, devices, subclusters)
+	err := clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
+	c.Assert(err, check.IsNil)
+
+	// serial-2 failed and is replaced by serial-3
+	changes := assemblestate.MembershipChanges{
+		Replace: map[int]string{2: "serial-3.ubuntu-core-24-amd64.canonical"},
+	}
+
+	addrs, size, err := clusterstate.MembershipSeed(st, changes)
+	c.Assert(err, check.IsNil)
+	c.Check(addrs, check.DeepEquals, []string{"192.168.0.10:7070"})
+	c.Check(size, check.Equals, 2)
+
+	var ids []assemblestate.Identity
+	for _, serial := range []string{"serial-1", "serial-3"} {
+		var buf bytes.Buffer
+		err := asserts.NewEncoder(&buf).Encode(makeSerialAssertion(c, stack, serial))
+		c.Assert(err, check.IsNil)
+		ids = append(ids, assemblestate.Identity{
+			RDT:          assemblestate.DeviceToken(serial),
+			SerialBundle: buf.String(),
+		})
+	}
+	routes := assemblestate.Routes{
+		Devices:   []assemblestate.DeviceToken{"serial-1", "serial-3"},
+		Addresses: []string{"192.168.0.10:7070", "192.168.0.12:7070"},
+		Routes:    []int{0, 1, 1, 1, 0, 0},
+	}
+
+	headers, err := clusterstate.PrepareMembershipUpdate(st, ids, routes, changes)
+	c.Assert(err, check.IsNil)
+	c.Check(headers["sequence"], check.Equals, "2")
+
+	a, err := sa.Signing(accountID).Sign(asserts.ClusterType, headers, nil, "")
+	c.Assert(err, check.IsNil)
+
+	var update bytes.Buffer
+	c.Assert(asserts.NewEncoder(&update).Encode(a), check.IsNil)
+	err = clusterstate.UpdateCluster(st, &update)
+	c.Assert(err, check.IsNil)
+
+	cluster, err := clusterstate.CurrentCluster(st)
+	c.Assert(err, check.IsNil)
+	c.Check(cluster.Sequence(), check.Equals, 2)
+	c.Check(cluster.Devices(), check.DeepEquals, []asserts.ClusterDevice{{
+		ID:        1,
+		Addresses: []string{"192.168.0.10:7070"},
+		DeviceID:  asserts.DeviceID{Serial: "serial-1", Model: "ubuntu-core-24-amd64", BrandID: "canonical"},
+	}, {
+		ID:        3,
+		Addresses: []string{"192.168.0.12:7070"},
+		DeviceID:  asserts.DeviceID{Serial: "serial-3", Model: "ubuntu-core-24-amd64", BrandID: "canonical"},
+	}})
+	c.Check(cluster.Subclusters()[0].Devices, check.DeepEquals, []int{1, 3})
+}
+
+func (s *clusterStateSuite) TestMembershipUpdateErrors(c *check.C) {
+	st, stack := newStateWithStoreStack(c)
+
+	st.Lock()
+	defer st.Unlock()
+
+	_, _, err := clusterstate.MembershipSeed(st, assemblestate.MembershipChanges{})
+	c.Assert(err, testutil.ErrorIs, clusterstate.ErrNoClusterAssertion)
+
+	_, err = clusterstate.PrepareMembershipUpdate(st, nil, assemblestate.Routes{}, assemblestate.MembershipChanges{})
+	c.Assert(err, testutil.ErrorIs, clusterstate.ErrNoClusterAssertion)
+
+	const accountID = "cluster-brand"
+	sa := registerAccount(stack, accountID)
+	bundle, _ := makeClusterBundleWithSigning(c, sa, accountID, "cluster-id", 1, nil, nil)
+	err = clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
+	c.Assert(err, check.IsNil)
+
+	_, err = clusterstate.PrepareMembershipUpdate(st, nil, assemblestate.Routes{}, assemblestate.MembershipChanges{Remove: []int{1}})
+	c.Assert(err, check.ErrorMatches, `cannot prepare update of cluster "cluster-id": cannot remove unknown device id 1`)
+}
+
 type managerSuite struct{}
 
 var _ = check.Suite(&managerSuite{})
@@ -379,7 +488,7 @@ func (s *managerSuite) TestEnsureIdempotent(c *check.C) {
 
 	err := clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
 	c.Assert(err, check.IsNil)
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	st.Unlock()
 	defer st.Lock()
@@ -407,14 +516,14 @@ func (s *managerSuite) TestEnsureClusteringDisabled(c *check.C) {
 	st.Unlock()
 	defer st.Lock()
 
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	err = mgr.Ensure()
 	c.Assert(err, check.IsNil)
 }
 
 func (s *managerSuite) TestEnsureLoopHasLogging(c *check.C) {
-	swfeatstest.CheckEnsureLoopLogging("clustermgr.go", c, false)
+	swfeatstest.CheckEnsureLoopLogging("clustermgr.go", c, true)
 }
 
 func (s *managerSuite) TestApplyClusterStateNoActions(c *check.C) {
@@ -458,7 +567,7 @@ func (s *managerSuite) TestApplyClusterStateNoActions(c *check.C) {
 
 	err := clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
 	c.Assert(err, check.IsNil)
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	st.Unlock()
 	defer st.Lock()
@@ -525,7 +634,7 @@ func (s *managerSuite) TestApplyClusterStateDeviceNotInAnySubcluster(c *check.C)
 	err := clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
 	c.Assert(err, check.IsNil)
 
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	st.Unlock()
 	defer st.Lock()
@@ -652,7 +761,7 @@ func (s *managerSuite) TestApplyClusterStateInstallRemoveAndUpdate(c *check.C) {
 
 	err := clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
 	c.Assert(err, check.IsNil)
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	st.Unlock()
 	defer st.Lock()
@@ -762,7 +871,7 @@ func (s *managerSuite) TestApplyClusterStateMultipleSubclusters(c *check.C) {
 
 	err := clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
 	c.Assert(err, check.IsNil)
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	st.Unlock()
 	defer st.Lock()
@@ -817,7 +926,7 @@ func (s *managerSuite) TestApplyClusterStateDeviceMissing(c *check.C) {
 
 	err := clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
 	c.Assert(err, check.IsNil)
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	st.Unlock()
 	defer st.Lock()
@@ -832,7 +941,7 @@ func (s *managerSuite) TestApplyClusterStateDeviceMissing(c *check.C) {
 func (s *managerSuite) TestApplyClusterStateNoClusterData(c *check.C) {
 	st, _ := newStateWithStoreStack(c)
 
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	c.Assert(mgr.Ensure(), check.IsNil)
 
@@ -928,7 +1037,7 @@ func (s *managerSuite) TestApplyClusterStateSkipsExistingChange(c *check.C) {
 
 	err := clusterstate.InitializeNewCluster(st, bytes.NewReader(bundle))
 	c.Assert(err, check.IsNil)
-	mgr := clusterstate.Manager(st)
+	mgr := clusterstate.Manager(st, state.NewTaskRunner(st))
 
 	st.Unlock()
 	defer st.Lock()
@@ -1005,6 +1114,13 @@ func makeClusterBundleWithSigning(
 }
 
 func makeSerialAssertion(c *check.C, stack *assertstest.StoreStack, serial string) *asserts.Serial {
+	a, _ := makeSerialAssertionAndKey(c, stack, serial)
+	return a
+}
+
+// makeSerialAssertionAndKey returns a serial assertion along with the
+// private part of its device key.
+func makeSerialAssertionAndKey(c *check.C, stack *assertstest.StoreStack, serial string) (*asserts.Serial, asserts.PrivateKey) {
 	deviceKey, _ := assertstest.GenerateKey(752)
 	encodedKey, err := asserts.EncodePublicKey(deviceKey.PublicKey())
 	c.Assert(err, check.IsNil)
@@ -1022,7 +1138,7 @@ func makeSerialAssertion(c *check.C, stack *assertstest.StoreStack, serial strin
 	a, err := stack.Sign(asserts.SerialType, headers, nil, "")
 	c.Assert(err, check.IsNil)
 
-	return a.(*asserts.Serial)
+	return a.(*asserts.Serial), deviceKey
 }
 
 func addSerialToState(c *check.C, st *state.State, serial *asserts.Serial) {
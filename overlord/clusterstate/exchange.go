@@ -0,0 +1,248 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package clusterstate
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/cluster/assemblestate"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// statusPublishPeriod is how often this device publishes its status to the
+// peer devices of its cluster.
+var statusPublishPeriod = time.Minute
+
+// exchangeSetup describes how this device exchanges statuses with the peer
+// devices of its cluster.
+type exchangeSetup struct {
+	// Address is the address that statuses are served at.
+	Address string
+	Peers   []StatusPeer
+}
+
+// statusExchange is a running exchange of statuses.
+type statusExchange struct {
+	setup  exchangeSetup
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// peerFingerprint returns the fingerprint recorded for the certificate of the
+// given device during the last assembly session that it took part in.
+func peerFingerprint(cs *clusterState, device string) (assemblestate.Fingerprint, bool) {
+	var fp assemblestate.Fingerprint
+	encoded, ok := cs.Fingerprints[device]
+	if !ok {
+		return fp, false
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != len(fp) {
+		return fp, false
+	}
+	copy(fp[:], raw)
+	return fp, true
+}
+
+// serveAddress returns the address to serve statuses at, on all interfaces
+// and at the port of the first of the given addresses that has one.
+func serveAddress(addresses []string) (string, bool) {
+	for _, addr := range addresses {
+		if _, port, err := net.SplitHostPort(addr); err == nil && port != "" {
+			return net.JoinHostPort("", port), true
+		}
+	}
+	return "", false
+}
+
+// statusExchangeSetup returns how this device exchanges statuses with the
+// peer devices of its cluster, or nil if it doesn't. Callers must hold the
+// state lock.
+func statusExchangeSetup(st *state.State) (*exchangeSetup, error) {
+	// assembly sessions listen on the same port
+	for _, chg := range st.Changes() {
+		if chg.Kind() == updateClusterMembershipChangeKind && !chg.Status().Ready() {
+			return nil, nil
+		}
+	}
+
+	cluster, err := CurrentCluster(st)
+	if err != nil {
+		if errors.Is(err, ErrNoClusterAssertion) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	serial, err := devicestate.Serial(st)
+	if err != nil {
+		if errors.Is(err, state.ErrNoState) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	self := serial.DeviceID().String()
+	dev, ok := clusterDeviceByID(cluster, self)
+	if !ok {
+		return nil, nil
+	}
+	addr, ok := serveAddress(dev.Addresses)
+	if !ok {
+		return nil, nil
+	}
+
+	var cs clusterState
+	if err := st.Get("cluster", &cs); err != nil {
+		return nil, err
+	}
+
+	setup := &exchangeSetup{Address: addr}
+	for _, dev := range cluster.Devices() {
+		device := dev.DeviceID.String()
+		if device == self || len(dev.Addresses) == 0 {
+			continue
+		}
+		// only the devices that this device assembled with can be trusted
+		fp, ok := peerFingerprint(&cs, device)
+		if !ok {
+			continue
+		}
+		setup.Peers = append(setup.Peers, StatusPeer{
+			Device:    device,
+			Addresses: dev.Addresses,
+			FP:        fp,
+		})
+	}
+	if len(setup.Peers) == 0 {
+		return nil, nil
+	}
+
+	return setup, nil
+}
+
+// ensureStatusExchange starts, restarts or stops the exchange of statuses
+// with the peer devices of the current cluster, so that it matches the
+// current cluster assertion and the peers that this device trusts.
+func (m *ClusterManager) ensureStatusExchange() error {
+	m.state.Lock()
+	setup, err := statusExchangeSetup(m.state)
+	m.state.Unlock()
+	if err != nil {
+		return err
+	}
+
+	m.exchangeMu.Lock()
+	defer m.exchangeMu.Unlock()
+
+	if m.exchange != nil && setup != nil && reflect.DeepEqual(m.exchange.setup, *setup) {
+		return nil
+	}
+	m.stopStatusExchangeLocked()
+
+	if setup == nil {
+		return nil
+	}
+
+	logger.Trace("ensure", "manager", "ClusterManager", "func", "ensureStatusExchange")
+
+	certPEM, keyPEM, err := tlsIdentity()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("cannot load cluster TLS identity: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", setup.Address)
+	if err != nil {
+		return fmt.Errorf("cannot listen for cluster status: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exchange := &statusExchange{
+		setup:  *setup,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(exchange.done)
+		m.exchangeStatus(ctx, ln, cert, setup.Peers)
+	}()
+	m.exchange = exchange
+
+	return nil
+}
+
+// exchangeStatus serves the statuses published by the given peers and
+// periodically publishes the status of this device to them, until the
+// context is cancelled.
+func (m *ClusterManager) exchangeStatus(ctx context.Context, ln net.Listener, cert tls.Certificate, peers []StatusPeer) {
+	transport := assemblestate.NewStreamTransport()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := m.ServeStatus(ctx, transport, ln, cert, peers); err != nil {
+			logger.Noticef("cannot serve cluster status: %v", err)
+		}
+	}()
+	defer wg.Wait()
+
+	client := transport.NewClient(cert)
+	for {
+		if err := m.PublishStatus(ctx, client, peers); err != nil {
+			logger.Debugf("%v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(statusPublishPeriod):
+		}
+	}
+}
+
+func (m *ClusterManager) stopStatusExchange() {
+	m.exchangeMu.Lock()
+	defer m.exchangeMu.Unlock()
+	m.stopStatusExchangeLocked()
+}
+
+func (m *ClusterManager) stopStatusExchangeLocked() {
+	if m.exchange == nil {
+		return
+	}
+	m.exchange.cancel()
+	<-m.exchange.done
+	m.exchange = nil
+}
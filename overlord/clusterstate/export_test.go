@@ -21,6 +21,7 @@ package clusterstate
 
 import (
 	"context"
+	"time"
 
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -57,3 +58,35 @@ func MockStoreInstallGoal(f func(...snapstate.StoreSnap) snapstate.InstallGoal)
 	storeInstallGoal = f
 	return restore
 }
+
+func MockTimeNow(f func() time.Time) func() {
+	restore := testutil.Backup(&timeNow)
+	timeNow = f
+	return restore
+}
+
+func MockSignWithDeviceKey(f func(*state.State, []byte) ([]byte, error)) func() {
+	restore := testutil.Backup(&signWithDeviceKey)
+	signWithDeviceKey = f
+	return restore
+}
+
+func MockDiscoverPeers(f func(ctx context.Context, instance, addr string, discoveries chan<- []string) error) func() {
+	restore := testutil.Backup(&discoverPeers)
+	discoverPeers = f
+	return restore
+}
+
+func MockAssemblyPeriod(d time.Duration) func() {
+	restore := testutil.Backup(&assemblyPeriod)
+	assemblyPeriod = d
+	return restore
+}
+
+func MockStatusPublishPeriod(d time.Duration) func() {
+	restore := testutil.Backup(&statusPublishPeriod)
+	statusPublishPeriod = d
+	return restore
+}
+
+var RecordFingerprints = recordFingerprints
@@ -0,0 +1,142 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package clusterstate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/cluster/assemblestate"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func identityDir() string {
+	return filepath.Join(dirs.SnapDeviceDir, "cluster")
+}
+
+// tlsIdentity returns the PEM encoded certificate and private key that this
+// device uses to communicate with the other devices of its cluster, both
+// during assembly sessions and afterwards. They are created on first use.
+func tlsIdentity() (certPEM, keyPEM []byte, err error) {
+	certPath := filepath.Join(identityDir(), "cert.pem")
+	keyPath := filepath.Join(identityDir(), "key.pem")
+
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if certErr == nil && keyErr == nil {
+		if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+			return nil, nil, fmt.Errorf("cannot load cluster TLS identity: %v", err)
+		}
+		return certPEM, keyPEM, nil
+	}
+	for _, err := range []error{certErr, keyErr} {
+		if err != nil && !os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("cannot load cluster TLS identity: %v", err)
+		}
+	}
+
+	certPEM, keyPEM, err = generateTLSIdentity()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot generate cluster TLS identity: %v", err)
+	}
+
+	if err := os.MkdirAll(identityDir(), 0700); err != nil {
+		return nil, nil, err
+	}
+	// the key goes first, a certificate without its key is useless
+	if err := osutil.AtomicWriteFile(keyPath, keyPEM, 0600, 0); err != nil {
+		return nil, nil, err
+	}
+	if err := osutil.AtomicWriteFile(certPath, certPEM, 0644, 0); err != nil {
+		return nil, nil, err
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+func generateTLSIdentity() (certPEM, keyPEM []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// peers trust the certificate by its fingerprint, its validity period is
+	// not checked
+	now := timeNow()
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "snapd cluster device"},
+		NotBefore:    now,
+		NotAfter:     now.AddDate(100, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, pub, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return certPEM, keyPEM, nil
+}
+
+// recordFingerprints records the fingerprints of the certificates that the
+// given devices used during an assembly session that this device took part
+// in. Callers must hold the state lock.
+func recordFingerprints(st *state.State, fps map[string]assemblestate.Fingerprint) error {
+	var cs clusterState
+	if err := st.Get("cluster", &cs); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+
+	if cs.Fingerprints == nil {
+		cs.Fingerprints = make(map[string]string, len(fps))
+	}
+	for device, fp := range fps {
+		cs.Fingerprints[device] = base64.StdEncoding.EncodeToString(fp[:])
+	}
+	st.Set("cluster", cs)
+
+	return nil
+}
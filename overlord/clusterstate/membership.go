@@ -0,0 +1,327 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package clusterstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/cluster/assemblestate"
+	"github.com/snapcore/snapd/features"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/randutil"
+)
+
+var updateClusterMembershipChangeKind = swfeats.RegisterChangeKind("update-cluster-membership")
+
+// defaultAssemblyAddress is the address that devices listen on during
+// assembly sessions, unless told otherwise.
+const defaultAssemblyAddress = ":7070"
+
+var (
+	signWithDeviceKey = devicestate.SignWithDeviceKey
+
+	// discoverPeers finds the devices taking part in an assembly session on
+	// the local link.
+	discoverPeers = func(ctx context.Context, instance, addr string, discoveries chan<- []string) error {
+		d, err := assemblestate.NewMDNSDiscovery(assemblestate.MDNSOptions{Instance: instance})
+		if err != nil {
+			return err
+		}
+		return d.Discover(ctx, addr, discoveries)
+	}
+
+	// assemblyPeriod is how often routes are published during assembly
+	// sessions.
+	assemblyPeriod = 5 * time.Second
+)
+
+// MembershipOptions carries the options of an assembly session that changes
+// the devices that are part of a cluster.
+type MembershipOptions struct {
+	// Secret is the secret shared by the devices taking part in the session.
+	Secret string
+	// Address is the address that this device listens on during the session.
+	// Defaults to port 7070 on all interfaces.
+	Address string
+	// Changes describes how the session changes the devices of the current
+	// cluster of this device.
+	Changes assemblestate.MembershipChanges
+	// ExpectedSize is the number of devices of the cluster once the session
+	// is over. It is only given to devices that join a cluster, the devices
+	// of the cluster derive it from the changes.
+	ExpectedSize int
+}
+
+// membershipSetup is the data of an "assemble-cluster" task.
+type membershipSetup struct {
+	Secret       string                          `json:"secret"`
+	Address      string                          `json:"address"`
+	Changes      assemblestate.MembershipChanges `json:"changes"`
+	ExpectedSize int                             `json:"expected-size,omitempty"`
+	// RDT is the random device token of this device for the session, it is
+	// kept so that the session can be resumed.
+	RDT assemblestate.DeviceToken `json:"rdt"`
+}
+
+// joining returns whether this device joins a cluster that it isn't part of.
+func (setup *membershipSetup) joining() bool {
+	return setup.ExpectedSize != 0
+}
+
+// UpdateMembership returns a change that takes part in an assembly session
+// adding devices to, or replacing devices of, the current cluster, so that
+// the cluster doesn't have to be assembled again. All the devices taking part
+// in the session must be given the same secret and changes.
+//
+// On devices of the current cluster, the change prepares the headers of the
+// next cluster assertion in the sequence of the cluster, once the session is
+// over. They are available as "cluster-headers" in the "api-data" of the
+// change. Once signed by the authority of the cluster, the assertion is
+// installed with [UpdateCluster], or with [InitializeNewCluster] on the devices
+// that joined the cluster. Callers must hold the state lock.
+func UpdateMembership(st *state.State, opts MembershipOptions) (*state.Change, error) {
+	tr := config.NewTransaction(st)
+	enabled, err := features.Flag(tr, features.Clustering)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, errors.New("experimental feature disabled - test it by setting 'experimental.clustering' to true")
+	}
+
+	if opts.Secret == "" {
+		return nil, errors.New("cannot update cluster membership without a secret")
+	}
+
+	for _, chg := range st.Changes() {
+		if chg.Kind() == updateClusterMembershipChangeKind && !chg.Status().Ready() {
+			return nil, errors.New("cluster membership update already in progress")
+		}
+	}
+
+	summary := "Update cluster membership"
+	_, err = CurrentCluster(st)
+	switch {
+	case err == nil:
+		if opts.ExpectedSize != 0 {
+			return nil, errors.New("cannot set the expected size of an assembly session updating the current cluster")
+		}
+		// check the changes against the cluster right away
+		if _, _, err := MembershipSeed(st, opts.Changes); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, ErrNoClusterAssertion):
+		if len(opts.Changes.Replace) != 0 || len(opts.Changes.Remove) != 0 || opts.Changes.Add != 0 {
+			return nil, errors.New("cannot change the devices of a cluster that this device is not part of")
+		}
+		if opts.ExpectedSize < 2 {
+			return nil, fmt.Errorf("invalid expected size of the cluster to join: %d", opts.ExpectedSize)
+		}
+		summary = "Join cluster"
+	default:
+		return nil, err
+	}
+
+	rdt, err := randutil.CryptoToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	setup := membershipSetup{
+		Secret:       opts.Secret,
+		Address:      opts.Address,
+		Changes:      opts.Changes,
+		ExpectedSize: opts.ExpectedSize,
+		RDT:          assemblestate.DeviceToken(rdt),
+	}
+	if setup.Address == "" {
+		setup.Address = defaultAssemblyAddress
+	}
+
+	chg := st.NewChange(updateClusterMembershipChangeKind, summary)
+	t := st.NewTask("assemble-cluster", "Assemble cluster devices")
+	t.Set("membership-setup", setup)
+	chg.AddTask(t)
+
+	return chg, nil
+}
+
+// membershipSeed returns the addresses of the devices to take part in the
+// session and its expected size.
+func membershipSeed(st *state.State, setup *membershipSetup) (addresses []string, expectedSize int, err error) {
+	if setup.joining() {
+		// the devices of the cluster are found on the local link
+		return nil, setup.ExpectedSize, nil
+	}
+	return MembershipSeed(st, setup.Changes)
+}
+
+func (m *ClusterManager) doAssembleCluster(t *state.Task, tomb *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	var setup membershipSetup
+	var session assemblestate.AssembleSession
+	var seed []string
+	var expectedSize int
+	var serial *asserts.Serial
+	var db asserts.RODatabase
+	err := func() error {
+		if err := t.Get("membership-setup", &setup); err != nil {
+			return err
+		}
+		if err := t.Get("assemble-session", &session); err != nil && !errors.Is(err, state.ErrNoState) {
+			return err
+		}
+
+		var err error
+		seed, expectedSize, err = membershipSeed(st, &setup)
+		if err != nil {
+			return err
+		}
+
+		serial, err = devicestate.Serial(st)
+		if err != nil {
+			return fmt.Errorf("cannot take part in an assembly session without a serial: %v", err)
+		}
+
+		db = assertstate.DB(st)
+		return nil
+	}()
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	certPEM, keyPEM, err := tlsIdentity()
+	if err != nil {
+		return err
+	}
+
+	as, err := assemblestate.NewAssembleState(assemblestate.AssembleConfig{
+		Secret:       setup.Secret,
+		RDT:          setup.RDT,
+		TLSCert:      certPEM,
+		TLSKey:       keyPEM,
+		ExpectedSize: expectedSize,
+		Serial:       serial,
+		Signer: func(data []byte) ([]byte, error) {
+			st.Lock()
+			defer st.Unlock()
+			return signWithDeviceKey(st, data)
+		},
+		Transport: assemblestate.TransportStream,
+	}, session,
+		func(self assemblestate.DeviceToken, identified func(assemblestate.DeviceToken) bool) (assemblestate.RouteSelector, error) {
+			return assemblestate.NewPrioritySelector(self, nil, identified), nil
+		},
+		func(session assemblestate.AssembleSession) {
+			st.Lock()
+			defer st.Unlock()
+			t.Set("assemble-session", session)
+		},
+		db,
+	)
+	if err != nil {
+		return fmt.Errorf("cannot start assembly session: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", setup.Address)
+	if err != nil {
+		return fmt.Errorf("cannot listen for assembly session: %v", err)
+	}
+	defer ln.Close()
+
+	// a resumed session ends an hour after it began, like a new one
+	deadline := timeNow().Add(assemblestate.AssembleSessionLength)
+	if !session.Initiated.IsZero() {
+		deadline = session.Initiated.Add(assemblestate.AssembleSessionLength)
+	}
+	ctx, cancel := context.WithDeadline(tomb.Context(nil), deadline)
+	defer cancel()
+
+	discoveries := make(chan []string, 1)
+	if len(seed) > 0 {
+		discoveries <- seed
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := discoverPeers(ctx, string(setup.RDT), ln.Addr().String(), discoveries); err != nil {
+			logger.Noticef("cannot discover cluster devices on the local link: %v", err)
+		}
+	}()
+
+	ids, routes, err := as.Run(ctx, ln, nil, discoveries, assemblestate.RunOptions{Period: assemblyPeriod})
+	cancel()
+	wg.Wait()
+	if err != nil {
+		return fmt.Errorf("cannot assemble cluster: %v", err)
+	}
+
+	select {
+	case <-tomb.Dying():
+		// the session is resumed from where it was left
+		return &state.Retry{}
+	default:
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("cannot assemble cluster: only %d of %d devices took part in the session", len(ids), expectedSize)
+	}
+
+	fps, err := assemblestate.IdentityFingerprints(ids)
+	if err != nil {
+		return err
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	if err := recordFingerprints(st, fps); err != nil {
+		return err
+	}
+
+	// devices joining the cluster install its assertion once signed
+	if setup.joining() {
+		return nil
+	}
+
+	headers, err := PrepareMembershipUpdate(st, ids, routes, setup.Changes)
+	if err != nil {
+		return err
+	}
+	t.Change().Set("api-data", map[string]any{"cluster-headers": headers})
+
+	return nil
+}
@@ -0,0 +1,433 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package clusterstate_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/cluster/assemblestate"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/clusterstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type membershipSuite struct {
+	testutil.BaseTest
+
+	st     *state.State
+	stack  *assertstest.StoreStack
+	sa     *assertstest.SigningAccounts
+	runner *state.TaskRunner
+
+	// the addresses that this device and its peer listen on, as exchanged
+	// through the discovery of peers
+	addr     chan string
+	peerAddr chan string
+}
+
+var _ = check.Suite(&membershipSuite{})
+
+func (s *membershipSuite) SetUpTest(c *check.C) {
+	s.BaseTest.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.st, s.stack = newStateWithStoreStack(c)
+	s.sa = registerAccount(s.stack, statusAccountID)
+	s.runner = state.NewTaskRunner(s.st)
+	clusterstate.Manager(s.st, s.runner)
+
+	s.AddCleanup(clusterstate.MockAssemblyPeriod(50 * time.Millisecond))
+
+	s.addr = make(chan string, 1)
+	s.peerAddr = make(chan string, 1)
+	s.AddCleanup(clusterstate.MockDiscoverPeers(func(ctx context.Context, instance, addr string, discoveries chan<- []string) error {
+		c.Check(instance, check.Not(check.Equals), "")
+		s.addr <- addr
+		select {
+		case peerAddr := <-s.peerAddr:
+			select {
+			case discoveries <- []string{peerAddr}:
+			case <-ctx.Done():
+			}
+		case <-ctx.Done():
+		}
+		return nil
+	}))
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	serial, key := makeSerialAssertionAndKey(c, s.stack, "serial-1")
+	addSerialToState(c, s.st, serial)
+
+	s.AddCleanup(clusterstate.MockSignWithDeviceKey(func(st *state.State, data []byte) ([]byte, error) {
+		c.Check(st, check.Equals, s.st)
+		return asserts.RawSignWithKey(data, key)
+	}))
+}
+
+func (s *membershipSuite) initializeCluster(c *check.C) {
+	// nothing listens on these addresses
+	bundle, _ := makeClusterBundleWithSigning(c, s.sa, statusAccountID, "cluster-id", 1, []map[string]any{
+		{"id": "1", "device": device1, "addresses": []any{"127.0.0.1:1"}},
+		{"id": "2", "device": device2, "addresses": []any{"127.0.0.1:2"}},
+	}, []map[string]any{{
+		"name":    "default",
+		"devices": []any{"1", "2"},
+		"snaps":   []any{},
+	}})
+	err := clusterstate.InitializeNewCluster(s.st, bytes.NewReader(bundle))
+	c.Assert(err, check.IsNil)
+}
+
+func generateTestCertPEM(c *check.C) (certPEM, keyPEM []byte) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, check.IsNil)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, pub, priv)
+	c.Assert(err, check.IsNil)
+	key, err := x509.MarshalPKCS8PrivateKey(priv)
+	c.Assert(err, check.IsNil)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+}
+
+// runPeer takes part in an assembly session as the device with the given
+// serial, reaching this device at the address that it listens on, until the
+// context is cancelled. It returns the fingerprint of the certificate of the
+// peer and the address that it listens on.
+func (s *membershipSuite) runPeer(c *check.C, ctx context.Context, wg *sync.WaitGroup, serial string) (assemblestate.Fingerprint, string) {
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.stack.Trusted,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(db.Add(s.stack.StoreAccountKey("")), check.IsNil)
+
+	certPEM, keyPEM := generateTestCertPEM(c)
+	block, _ := pem.Decode(certPEM)
+
+	a, key := makeSerialAssertionAndKey(c, s.stack, serial)
+	as, err := assemblestate.NewAssembleState(assemblestate.AssembleConfig{
+		Secret:  "secret",
+		RDT:     assemblestate.DeviceToken("rdt-" + serial),
+		TLSCert: certPEM,
+		TLSKey:  keyPEM,
+		Serial:  a,
+		Signer: func(data []byte) ([]byte, error) {
+			return asserts.RawSignWithKey(data, key)
+		},
+		Transport: assemblestate.TransportStream,
+	}, assemblestate.AssembleSession{},
+		func(self assemblestate.DeviceToken, identified func(assemblestate.DeviceToken) bool) (assemblestate.RouteSelector, error) {
+			return assemblestate.NewPrioritySelector(self, nil, identified), nil
+		},
+		func(assemblestate.AssembleSession) {},
+		db,
+	)
+	c.Assert(err, check.IsNil)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	s.peerAddr <- ln.Addr().String()
+
+	discoveries := make(chan []string, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case addr := <-s.addr:
+			discoveries <- []string{addr}
+		case <-ctx.Done():
+			return
+		}
+		_, _, err := as.Run(ctx, ln, nil, discoveries, assemblestate.RunOptions{Period: 50 * time.Millisecond})
+		c.Check(err, check.IsNil)
+	}()
+
+	return assemblestate.CalculateFP(block.Bytes), ln.Addr().String()
+}
+
+func (s *membershipSuite) runChange(c *check.C, chg *state.Change) {
+	s.st.Unlock()
+	defer s.st.Lock()
+
+	s.runner.Ensure()
+	s.runner.Wait()
+}
+
+func (s *membershipSuite) fingerprints(c *check.C) map[string]string {
+	var cs struct {
+		Fingerprints map[string]string `json:"fingerprints"`
+	}
+	c.Assert(s.st.Get("cluster", &cs), check.IsNil)
+	return cs.Fingerprints
+}
+
+func (s *membershipSuite) localFingerprint(c *check.C) string {
+	certPEM, err := os.ReadFile(filepath.Join(dirs.SnapDeviceDir, "cluster", "cert.pem"))
+	c.Assert(err, check.IsNil)
+	block, _ := pem.Decode(certPEM)
+	fp := assemblestate.CalculateFP(block.Bytes)
+	return base64.StdEncoding.EncodeToString(fp[:])
+}
+
+func (s *membershipSuite) TestUpdateMembership(c *check.C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.initializeCluster(c)
+
+	changes := assemblestate.MembershipChanges{Replace: map[int]string{2: device3}}
+	chg, err := clusterstate.UpdateMembership(s.st, clusterstate.MembershipOptions{
+		Secret:  "secret",
+		Changes: changes,
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(chg.Kind(), check.Equals, "update-cluster-membership")
+	c.Check(chg.Summary(), check.Equals, "Update cluster membership")
+	c.Assert(chg.Tasks(), check.HasLen, 1)
+
+	t := chg.Tasks()[0]
+	c.Check(t.Kind(), check.Equals, "assemble-cluster")
+	var setup map[string]any
+	c.Assert(t.Get("membership-setup", &setup), check.IsNil)
+	c.Check(setup["secret"], check.Equals, "secret")
+	c.Check(setup["address"], check.Equals, ":7070")
+	c.Check(setup["changes"], check.DeepEquals, map[string]any{"replace": map[string]any{"2": device3}})
+	c.Check(setup["rdt"], check.Not(check.Equals), "")
+
+	// only one update at a time
+	_, err = clusterstate.UpdateMembership(s.st, clusterstate.MembershipOptions{Secret: "secret", Changes: changes})
+	c.Check(err, check.ErrorMatches, "cluster membership update already in progress")
+}
+
+func (s *membershipSuite) TestUpdateMembershipErrors(c *check.C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	// devices that aren't part of a cluster join one
+	_, err := clusterstate.UpdateMembership(s.st, clusterstate.MembershipOptions{
+		Secret:  "secret",
+		Changes: assemblestate.MembershipChanges{Add: 1},
+	})
+	c.Check(err, check.ErrorMatches, "cannot change the devices of a cluster that this device is not part of")
+	_, err = clusterstate.UpdateMembership(s.st, clusterstate.MembershipOptions{Secret: "secret"})
+	c.Check(err, check.ErrorMatches, "invalid expected size of the cluster to join: 0")
+
+	s.initializeCluster(c)
+
+	for _, tc := range []struct {
+		opts clusterstate.MembershipOptions
+		err  string
+	}{{
+		opts: clusterstate.MembershipOptions{Changes: assemblestate.MembershipChanges{Add: 1}},
+		err:  "cannot update cluster membership without a secret",
+	}, {
+		opts: clusterstate.MembershipOptions{Secret: "secret", ExpectedSize: 3},
+		err:  "cannot set the expected size of an assembly session updating the current cluster",
+	}, {
+		opts: clusterstate.MembershipOptions{Secret: "secret", Changes: assemblestate.MembershipChanges{Remove: []int{7}}},
+		err:  "cannot remove unknown device id 7",
+	}} {
+		_, err := clusterstate.UpdateMembership(s.st, tc.opts)
+		c.Check(err, check.ErrorMatches, tc.err)
+	}
+
+	tr := config.NewTransaction(s.st)
+	c.Assert(tr.Set("core", "experimental.clustering", false), check.IsNil)
+	tr.Commit()
+
+	_, err = clusterstate.UpdateMembership(s.st, clusterstate.MembershipOptions{Secret: "secret"})
+	c.Check(err, check.ErrorMatches, `experimental feature disabled - test it by setting 'experimental.clustering' to true`)
+	c.Check(s.st.Changes(), check.HasLen, 0)
+}
+
+func (s *membershipSuite) TestAssembleClusterReplace(c *check.C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.initializeCluster(c)
+
+	chg, err := clusterstate.UpdateMembership(s.st, clusterstate.MembershipOptions{
+		Secret:  "secret",
+		Address: "127.0.0.1:0",
+		Changes: assemblestate.MembershipChanges{Replace: map[int]string{2: device3}},
+	})
+	c.Assert(err, check.IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var wg sync.WaitGroup
+	peerFP, peerAddr := s.runPeer(c, ctx, &wg, "serial-3")
+
+	s.runChange(c, chg)
+	cancel()
+	wg.Wait()
+	c.Assert(chg.Status(), check.Equals, state.DoneStatus, check.Commentf("%v", chg.Err()))
+
+	var data map[string]any
+	c.Assert(chg.Get("api-data", &data), check.IsNil)
+	headers := data["cluster-headers"].(map[string]any)
+	c.Check(headers["cluster-id"], check.Equals, "cluster-id")
+	c.Check(headers["sequence"], check.Equals, "2")
+
+	devices := headers["devices"].([]any)
+	c.Assert(devices, check.HasLen, 2)
+	c.Check(devices[0].(map[string]any)["id"], check.Equals, "1")
+	c.Check(devices[0].(map[string]any)["device"], check.Equals, device1)
+	c.Check(devices[1], check.DeepEquals, map[string]any{
+		"id":        "3",
+		"device":    device3,
+		"addresses": []any{peerAddr},
+	})
+	c.Check(headers["subclusters"], check.DeepEquals, []any{map[string]any{
+		"name":    "default",
+		"devices": []any{"1", "3"},
+		"snaps":   []any{},
+	}})
+
+	c.Check(s.fingerprints(c), check.DeepEquals, map[string]string{
+		device1: s.localFingerprint(c),
+		device3: base64.StdEncoding.EncodeToString(peerFP[:]),
+	})
+}
+
+func (s *membershipSuite) TestAssembleClusterJoin(c *check.C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	chg, err := clusterstate.UpdateMembership(s.st, clusterstate.MembershipOptions{
+		Secret:       "secret",
+		Address:      "127.0.0.1:0",
+		ExpectedSize: 2,
+	})
+	c.Assert(err, check.IsNil)
+	c.Check(chg.Summary(), check.Equals, "Join cluster")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var wg sync.WaitGroup
+	peerFP, _ := s.runPeer(c, ctx, &wg, "serial-2")
+
+	s.runChange(c, chg)
+	cancel()
+	wg.Wait()
+	c.Assert(chg.Status(), check.Equals, state.DoneStatus, check.Commentf("%v", chg.Err()))
+
+	// only the devices of the cluster prepare its next assertion
+	var data map[string]any
+	c.Check(chg.Get("api-data", &data), testutil.ErrorIs, state.ErrNoState)
+
+	fps := map[string]string{
+		device1: s.localFingerprint(c),
+		device2: base64.StdEncoding.EncodeToString(peerFP[:]),
+	}
+	c.Check(s.fingerprints(c), check.DeepEquals, fps)
+
+	// the fingerprints are kept once the device gets the cluster assertion
+	s.initializeCluster(c)
+	c.Check(s.fingerprints(c), check.DeepEquals, fps)
+}
+
+func (s *membershipSuite) TestAssembleClusterNoSerial(c *check.C) {
+	st, _ := newStateWithStoreStack(c)
+	runner := state.NewTaskRunner(st)
+	clusterstate.Manager(st, runner)
+
+	st.Lock()
+	defer st.Unlock()
+
+	chg, err := clusterstate.UpdateMembership(st, clusterstate.MembershipOptions{
+		Secret:       "secret",
+		ExpectedSize: 2,
+	})
+	c.Assert(err, check.IsNil)
+
+	st.Unlock()
+	runner.Ensure()
+	runner.Wait()
+	st.Lock()
+
+	c.Check(chg.Status(), check.Equals, state.ErrorStatus)
+	c.Check(chg.Err(), check.ErrorMatches, `(?s).*cannot take part in an assembly session without a serial: no state entry for key.*`)
+}
+
+func (s *membershipSuite) TestTLSIdentityKept(c *check.C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	// the identity of the device is created by the first session and reused
+	// by the next ones
+	var fps []string
+	for i := 0; i < 2; i++ {
+		chg, err := clusterstate.UpdateMembership(s.st, clusterstate.MembershipOptions{
+			Secret:       "secret",
+			Address:      "127.0.0.1:0",
+			ExpectedSize: 2,
+		})
+		c.Assert(err, check.IsNil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		var wg sync.WaitGroup
+		s.runPeer(c, ctx, &wg, "serial-2")
+		s.runChange(c, chg)
+		cancel()
+		wg.Wait()
+		c.Assert(chg.Status(), check.Equals, state.DoneStatus, check.Commentf("%v", chg.Err()))
+
+		fps = append(fps, s.fingerprints(c)[device1])
+	}
+	c.Check(fps[0], check.Equals, s.localFingerprint(c))
+	c.Check(fps[1], check.Equals, fps[0])
+
+	info, err := os.Stat(filepath.Join(dirs.SnapDeviceDir, "cluster", "key.pem"))
+	c.Assert(err, check.IsNil)
+	c.Check(info.Mode().Perm(), check.Equals, os.FileMode(0600))
+}
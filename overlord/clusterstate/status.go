@@ -0,0 +1,468 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package clusterstate
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/cluster/assemblestate"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var timeNow = time.Now
+
+// peerStatus is the last status received from a peer device.
+type peerStatus struct {
+	Status   assemblestate.Status `json:"status"`
+	Received time.Time            `json:"received"`
+}
+
+// Convergence states of a device of a subcluster.
+const (
+	// DeviceConverged is the state of a device that applied the current
+	// cluster assertion.
+	DeviceConverged = "converged"
+	// DeviceLagging is the state of a device that has yet to apply the
+	// current cluster assertion.
+	DeviceLagging = "lagging"
+	// DeviceFailed is the state of a device that failed to apply the current
+	// cluster assertion.
+	DeviceFailed = "failed"
+	// DeviceUnknown is the state of a device that didn't report its status.
+	DeviceUnknown = "unknown"
+)
+
+// ClusterStatus describes how far the devices of the cluster are in applying
+// the current cluster assertion.
+type ClusterStatus struct {
+	ClusterID   string             `json:"cluster-id"`
+	Sequence    int                `json:"sequence"`
+	Subclusters []SubclusterStatus `json:"subclusters"`
+}
+
+// SubclusterStatus contains the state of the devices of a subcluster.
+type SubclusterStatus struct {
+	Name    string         `json:"name"`
+	Devices []DeviceStatus `json:"devices"`
+}
+
+// DeviceStatus contains the state of a device of a subcluster.
+type DeviceStatus struct {
+	ID     int    `json:"id"`
+	Device string `json:"device"`
+	// Status is one of DeviceConverged, DeviceLagging, DeviceFailed or
+	// DeviceUnknown.
+	Status string `json:"status"`
+	// Sequence is the sequence of the last cluster assertion applied by the
+	// device.
+	Sequence int `json:"sequence,omitempty"`
+	// Reason explains why the device is lagging or failed.
+	Reason string `json:"reason,omitempty"`
+	// Local is set for this device.
+	Local bool `json:"local,omitempty"`
+	// Received is when the status of a peer device was last received.
+	Received time.Time `json:"received,omitzero"`
+}
+
+func setApplied(st *state.State, sequence int) error {
+	var cs clusterState
+	if err := st.Get("cluster", &cs); err != nil {
+		return err
+	}
+
+	if cs.Applied == sequence {
+		return nil
+	}
+
+	cs.Applied = sequence
+	st.Set("cluster", cs)
+	return nil
+}
+
+// applyError returns the error of the last change that applied the state of
+// the given cluster, if none is in progress.
+func applyError(st *state.State, clusterID string) string {
+	var last *state.Change
+	for _, chg := range st.Changes() {
+		if chg.Kind() != applyClusterSubclusterChangeKind {
+			continue
+		}
+
+		var ref clusterChangeRef
+		if err := chg.Get("cluster-change-ref", &ref); err != nil || ref.ClusterID != clusterID {
+			continue
+		}
+
+		if !chg.Status().Ready() {
+			return ""
+		}
+
+		if last == nil || chg.ReadyTime().After(last.ReadyTime()) {
+			last = chg
+		}
+	}
+
+	if last == nil || last.Status() != state.ErrorStatus {
+		return ""
+	}
+	return last.Err().Error()
+}
+
+// LocalStatus returns the status of this device to publish to its peers.
+// Callers must hold the state lock.
+func LocalStatus(st *state.State) (*assemblestate.Status, error) {
+	cluster, err := CurrentCluster(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs clusterState
+	if err := st.Get("cluster", &cs); err != nil {
+		return nil, err
+	}
+
+	serial, err := devicestate.Serial(st)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceID, ok := clusterDeviceIDBySerial(cluster, serial.Serial())
+	if !ok {
+		return nil, fmt.Errorf("device with serial %q not found in cluster assertion", serial.Serial())
+	}
+
+	status := &assemblestate.Status{
+		ClusterID: cluster.ClusterID(),
+		Device:    serial.DeviceID().String(),
+		Sequence:  cs.Applied,
+		Snaps:     []assemblestate.SnapStatus{},
+	}
+	if cs.Applied < cluster.Sequence() {
+		status.Error = applyError(st, cluster.ClusterID())
+	}
+
+	seen := make(map[string]bool)
+	for _, subcluster := range cluster.Subclusters() {
+		if !deviceInSubcluster(subcluster, deviceID) {
+			continue
+		}
+
+		for _, sn := range subcluster.Snaps {
+			if seen[sn.Instance] {
+				continue
+			}
+			seen[sn.Instance] = true
+
+			var snapst snapstate.SnapState
+			if err := snapstate.Get(st, sn.Instance, &snapst); err != nil && !errors.Is(err, state.ErrNoState) {
+				return nil, err
+			}
+
+			snapStatus := assemblestate.SnapStatus{Instance: sn.Instance}
+			if snapst.IsInstalled() {
+				snapStatus.Revision = snapst.Current.String()
+				snapStatus.Channel = snapst.TrackingChannel
+			}
+			status.Snaps = append(status.Snaps, snapStatus)
+		}
+	}
+	sort.Slice(status.Snaps, func(i, j int) bool {
+		return status.Snaps[i].Instance < status.Snaps[j].Instance
+	})
+
+	return status, nil
+}
+
+// RecordPeerStatus records the status received from a peer device of the
+// current cluster. Callers must hold the state lock.
+func RecordPeerStatus(st *state.State, status assemblestate.Status) error {
+	cluster, err := CurrentCluster(st)
+	if err != nil {
+		return err
+	}
+
+	if status.ClusterID != cluster.ClusterID() {
+		return fmt.Errorf("cannot record status for cluster %q: current cluster is %q", status.ClusterID, cluster.ClusterID())
+	}
+
+	if _, ok := clusterDeviceByID(cluster, status.Device); !ok {
+		return fmt.Errorf("cannot record status of device %q: device is not part of cluster %q", status.Device, cluster.ClusterID())
+	}
+
+	var cs clusterState
+	if err := st.Get("cluster", &cs); err != nil {
+		return err
+	}
+
+	if cs.Peers == nil {
+		cs.Peers = make(map[string]peerStatus)
+	}
+	cs.Peers[status.Device] = peerStatus{
+		Status:   status,
+		Received: timeNow(),
+	}
+	st.Set("cluster", cs)
+
+	return nil
+}
+
+// Status returns the state of each device of each subcluster of the current
+// cluster, based on the status of this device and the last statuses received
+// from its peers. Callers must hold the state lock.
+func Status(st *state.State) (*ClusterStatus, error) {
+	cluster, err := CurrentCluster(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs clusterState
+	if err := st.Get("cluster", &cs); err != nil {
+		return nil, err
+	}
+
+	local, err := LocalStatus(st)
+	if err != nil {
+		// without a serial, or if this device is not part of the cluster,
+		// the statuses of the peers can still be shown
+		logger.Debugf("cannot get status of this device: %v", err)
+	}
+
+	result := &ClusterStatus{
+		ClusterID:   cluster.ClusterID(),
+		Sequence:    cluster.Sequence(),
+		Subclusters: make([]SubclusterStatus, 0, len(cluster.Subclusters())),
+	}
+	for _, subcluster := range cluster.Subclusters() {
+		sub := SubclusterStatus{
+			Name:    subcluster.Name,
+			Devices: make([]DeviceStatus, 0, len(subcluster.Devices)),
+		}
+
+		for _, id := range subcluster.Devices {
+			device := clusterDeviceString(cluster, id)
+			devStatus := DeviceStatus{
+				ID:     id,
+				Device: device,
+			}
+
+			var report *assemblestate.Status
+			if local != nil && local.Device == device {
+				report = local
+				devStatus.Local = true
+			} else if peer, ok := cs.Peers[device]; ok {
+				report = &peer.Status
+				devStatus.Received = peer.Received
+			}
+
+			devStatus.Status, devStatus.Reason = convergence(subcluster, cluster.Sequence(), report)
+			if report != nil {
+				devStatus.Sequence = report.Sequence
+			}
+			sub.Devices = append(sub.Devices, devStatus)
+		}
+
+		result.Subclusters = append(result.Subclusters, sub)
+	}
+
+	return result, nil
+}
+
+// convergence returns the state of a device of the given subcluster with
+// respect to the cluster assertion with the given sequence, and why it
+// hasn't converged.
+func convergence(subcluster asserts.Subcluster, sequence int, report *assemblestate.Status) (status, reason string) {
+	if report == nil {
+		return DeviceUnknown, ""
+	}
+
+	if report.Sequence < sequence {
+		if report.Error != "" {
+			return DeviceFailed, report.Error
+		}
+		return DeviceLagging, fmt.Sprintf("cluster sequence %d not applied yet", sequence)
+	}
+
+	snaps := make(map[string]assemblestate.SnapStatus, len(report.Snaps))
+	for _, sn := range report.Snaps {
+		snaps[sn.Instance] = sn
+	}
+
+	for _, sn := range subcluster.Snaps {
+		reported, ok := snaps[sn.Instance]
+		if !ok {
+			return DeviceLagging, fmt.Sprintf("no status for snap %q", sn.Instance)
+		}
+
+		// TODO: handle [asserts.ClusterSnapStateEvacuated]
+		switch sn.State {
+		case asserts.ClusterSnapStateClustered:
+			if reported.Revision == "" {
+				return DeviceLagging, fmt.Sprintf("snap %q is not installed", sn.Instance)
+			}
+			if sn.Channel != "" && reported.Channel != sn.Channel {
+				return DeviceLagging, fmt.Sprintf("snap %q tracks %q instead of %q", sn.Instance, reported.Channel, sn.Channel)
+			}
+		case asserts.ClusterSnapStateRemoved:
+			if reported.Revision != "" {
+				return DeviceLagging, fmt.Sprintf("snap %q is not removed", sn.Instance)
+			}
+		}
+	}
+
+	return DeviceConverged, ""
+}
+
+func clusterDeviceByID(cluster *asserts.Cluster, device string) (asserts.ClusterDevice, bool) {
+	for _, dev := range cluster.Devices() {
+		if dev.DeviceID.String() == device {
+			return dev, true
+		}
+	}
+	return asserts.ClusterDevice{}, false
+}
+
+func clusterDeviceString(cluster *asserts.Cluster, id int) string {
+	for _, dev := range cluster.Devices() {
+		if dev.ID == id {
+			return dev.DeviceID.String()
+		}
+	}
+	return ""
+}
+
+// StatusPeer is a peer device of the cluster that statuses are exchanged with.
+type StatusPeer struct {
+	// Device is the device id of the peer.
+	Device string
+	// Addresses are the addresses that the peer serves statuses at, they are
+	// tried in turn.
+	Addresses []string
+	// FP is the fingerprint of the certificate that the peer uses.
+	FP assemblestate.Fingerprint
+}
+
+// PublishStatus sends the status of this device to the given peers over the
+// trusted channel of the assembly transport that the client belongs to.
+func (m *ClusterManager) PublishStatus(ctx context.Context, client assemblestate.Client, peers []StatusPeer) error {
+	m.state.Lock()
+	status, err := LocalStatus(m.state)
+	m.state.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, peer := range peers {
+		if err := publishStatusToPeer(ctx, client, peer, status); err != nil {
+			logger.Debugf("cannot publish cluster status to %s: %v", peer.Device, err)
+			failed = append(failed, peer.Device)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("cannot publish cluster status to %s", strutil.Quoted(failed))
+	}
+	return nil
+}
+
+func publishStatusToPeer(ctx context.Context, client assemblestate.Client, peer StatusPeer, status *assemblestate.Status) error {
+	err := errors.New("no known address")
+	for _, addr := range peer.Addresses {
+		if err = client.Trusted(ctx, addr, peer.FP, "status", status); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// ServeStatus records the statuses that the given peers publish to this device
+// over the given assembly transport, until the context is cancelled.
+func (m *ClusterManager) ServeStatus(ctx context.Context, transport assemblestate.Transport, ln net.Listener, cert tls.Certificate, peers []StatusPeer) error {
+	pa := &statusAuthenticator{
+		state: m.state,
+		peers: make(map[assemblestate.Fingerprint]string, len(peers)),
+	}
+	for _, peer := range peers {
+		pa.peers[peer.FP] = peer.Device
+	}
+
+	return transport.Serve(ctx, ln, cert, pa)
+}
+
+var errClusterAssembled = errors.New("cluster is already assembled")
+
+// statusAuthenticator lets the known peers of an assembled cluster publish
+// their status.
+type statusAuthenticator struct {
+	state *state.State
+	peers map[assemblestate.Fingerprint]string
+}
+
+func (a *statusAuthenticator) AuthenticateAndCommit(auth assemblestate.Auth, fp assemblestate.Fingerprint) error {
+	return errClusterAssembled
+}
+
+func (a *statusAuthenticator) VerifyPeer(fp assemblestate.Fingerprint) (assemblestate.VerifiedPeer, error) {
+	device, ok := a.peers[fp]
+	if !ok {
+		return nil, errors.New("unknown peer certificate")
+	}
+	return &statusPeer{state: a.state, device: device}, nil
+}
+
+// statusPeer is a peer of an assembled cluster, it can only publish its
+// status.
+type statusPeer struct {
+	state  *state.State
+	device string
+}
+
+func (p *statusPeer) CommitDeviceQueries(unknown assemblestate.UnknownDevices) error {
+	return errClusterAssembled
+}
+
+func (p *statusPeer) CommitDevices(devices assemblestate.Devices) error {
+	return errClusterAssembled
+}
+
+func (p *statusPeer) CommitRoutes(routes assemblestate.Routes) error {
+	return errClusterAssembled
+}
+
+func (p *statusPeer) CommitStatus(status assemblestate.Status) error {
+	if status.Device != p.device {
+		return fmt.Errorf("peer %q cannot publish the status of device %q", p.device, status.Device)
+	}
+
+	p.state.Lock()
+	defer p.state.Unlock()
+	return RecordPeerStatus(p.state, status)
+}
@@ -0,0 +1,608 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package clusterstate_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/cluster/assemblestate"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/clusterstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/sequence"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type statusSuite struct {
+	testutil.BaseTest
+
+	st    *state.State
+	stack *assertstest.StoreStack
+	sa    *assertstest.SigningAccounts
+}
+
+var _ = check.Suite(&statusSuite{})
+
+const (
+	statusAccountID = "cluster-brand"
+	device1         = "serial-1.ubuntu-core-24-amd64.canonical"
+	device2         = "serial-2.ubuntu-core-24-amd64.canonical"
+	device3         = "serial-3.ubuntu-core-24-amd64.canonical"
+)
+
+var statusTestTime = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func (s *statusSuite) SetUpTest(c *check.C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.st, s.stack = newStateWithStoreStack(c)
+	s.sa = registerAccount(s.stack, statusAccountID)
+
+	restore := clusterstate.MockTimeNow(func() time.Time { return statusTestTime })
+	s.AddCleanup(restore)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	addSerialToState(c, s.st, makeSerialAssertion(c, s.stack, "serial-1"))
+
+	setInstalled(s.st, "snap-one", 3, "latest/stable")
+
+	bundle, _ := makeClusterBundleWithSigning(c, s.sa, statusAccountID, "cluster-id", 1, statusTestDevices(), []map[string]any{{
+		"name":    "default",
+		"devices": []any{"1", "2", "3"},
+		"snaps": []any{
+			map[string]any{"state": "clustered", "instance": "snap-one", "channel": "latest/stable"},
+			map[string]any{"state": "removed", "instance": "snap-two", "channel": "latest/stable"},
+		},
+	}, {
+		"name":    "db",
+		"devices": []any{"2"},
+		"snaps": []any{
+			map[string]any{"state": "clustered", "instance": "snap-db", "channel": "latest/stable"},
+		},
+	}})
+	err := clusterstate.InitializeNewCluster(s.st, bytes.NewReader(bundle))
+	c.Assert(err, check.IsNil)
+}
+
+func statusTestDevices() []map[string]any {
+	return []map[string]any{
+		{"id": "1", "device": device1, "addresses": []any{"192.168.0.10:7070"}},
+		{"id": "2", "device": device2, "addresses": []any{"192.168.0.11:7070"}},
+		{"id": "3", "device": device3, "addresses": []any{"192.168.0.12:7070"}},
+	}
+}
+
+func setInstalled(st *state.State, name string, rev int, channel string) {
+	snapstate.Set(st, name, &snapstate.SnapState{
+		Active:          true,
+		Current:         snap.R(rev),
+		TrackingChannel: channel,
+		Sequence: sequence.SnapSequence{
+			Revisions: []*sequence.RevisionSideState{
+				sequence.NewRevisionSideState(&snap.SideInfo{RealName: name, Revision: snap.R(rev)}, nil),
+			},
+		},
+	})
+}
+
+func (s *statusSuite) TestLocalStatusApplied(c *check.C) {
+	s.st.Lock()
+	status, err := clusterstate.LocalStatus(s.st)
+	s.st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(status, check.DeepEquals, &assemblestate.Status{
+		ClusterID: "cluster-id",
+		Device:    device1,
+		Snaps: []assemblestate.SnapStatus{
+			{Instance: "snap-one", Revision: "3", Channel: "latest/stable"},
+			{Instance: "snap-two"},
+		},
+	})
+
+	// nothing is left to do on this device, so the assertion is applied
+	c.Assert(clusterstate.Manager(s.st, state.NewTaskRunner(s.st)).Ensure(), check.IsNil)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	c.Check(s.st.Changes(), check.HasLen, 0)
+
+	status, err = clusterstate.LocalStatus(s.st)
+	c.Assert(err, check.IsNil)
+	c.Check(status.Sequence, check.Equals, 1)
+	c.Check(status.Error, check.Equals, "")
+}
+
+func (s *statusSuite) TestLocalStatusApplyError(c *check.C) {
+	s.st.Lock()
+	snapstate.Set(s.st, "snap-one", nil)
+	s.st.Unlock()
+
+	s.AddCleanup(clusterstate.MockInstallWithGoal(func(ctx context.Context, st *state.State, goal snapstate.InstallGoal, opts snapstate.Options) ([]*snap.Info, []*state.TaskSet, error) {
+		return nil, []*state.TaskSet{state.NewTaskSet(st.NewTask("install", "install snap-one"))}, nil
+	}))
+
+	c.Assert(clusterstate.Manager(s.st, state.NewTaskRunner(s.st)).Ensure(), check.IsNil)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	chgs := s.st.Changes()
+	c.Assert(chgs, check.HasLen, 1)
+
+	// the assertion is not applied while the change is in progress
+	status, err := clusterstate.LocalStatus(s.st)
+	c.Assert(err, check.IsNil)
+	c.Check(status.Sequence, check.Equals, 0)
+	c.Check(status.Error, check.Equals, "")
+
+	t := chgs[0].Tasks()[0]
+	t.Errorf("cannot install snap-one")
+	t.SetStatus(state.ErrorStatus)
+
+	status, err = clusterstate.LocalStatus(s.st)
+	c.Assert(err, check.IsNil)
+	c.Check(status.Sequence, check.Equals, 0)
+	c.Check(status.Error, check.Matches, `(?s)cannot perform the following tasks:.*cannot install snap-one.*`)
+	c.Check(status.Snaps, check.DeepEquals, []assemblestate.SnapStatus{
+		{Instance: "snap-one"},
+		{Instance: "snap-two"},
+	})
+}
+
+func (s *statusSuite) TestStatus(c *check.C) {
+	c.Assert(clusterstate.Manager(s.st, state.NewTaskRunner(s.st)).Ensure(), check.IsNil)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	err := clusterstate.RecordPeerStatus(s.st, assemblestate.Status{
+		ClusterID: "cluster-id",
+		Device:    device2,
+		Sequence:  1,
+		Snaps: []assemblestate.SnapStatus{
+			{Instance: "snap-db"},
+			{Instance: "snap-one", Revision: "3", Channel: "latest/stable"},
+			{Instance: "snap-two"},
+		},
+	})
+	c.Assert(err, check.IsNil)
+
+	status, err := clusterstate.Status(s.st)
+	c.Assert(err, check.IsNil)
+	c.Check(status, check.DeepEquals, &clusterstate.ClusterStatus{
+		ClusterID: "cluster-id",
+		Sequence:  1,
+		Subclusters: []clusterstate.SubclusterStatus{{
+			Name: "default",
+			Devices: []clusterstate.DeviceStatus{
+				{ID: 1, Device: device1, Status: "converged", Sequence: 1, Local: true},
+				{ID: 2, Device: device2, Status: "converged", Sequence: 1, Received: statusTestTime},
+				{ID: 3, Device: device3, Status: "unknown"},
+			},
+		}, {
+			Name: "db",
+			Devices: []clusterstate.DeviceStatus{
+				{ID: 2, Device: device2, Status: "lagging", Sequence: 1, Reason: `snap "snap-db" is not installed`, Received: statusTestTime},
+			},
+		}},
+	})
+
+	// a new sequence of the assertion that no device applied yet
+	bundle, _ := makeClusterBundleWithSigning(c, s.sa, statusAccountID, "cluster-id", 2, statusTestDevices(), []map[string]any{{
+		"name":    "default",
+		"devices": []any{"1", "2"},
+		"snaps": []any{
+			map[string]any{"state": "clustered", "instance": "snap-one", "channel": "latest/edge"},
+		},
+	}})
+	c.Assert(clusterstate.UpdateCluster(s.st, bytes.NewReader(bundle)), check.IsNil)
+
+	err = clusterstate.RecordPeerStatus(s.st, assemblestate.Status{
+		ClusterID: "cluster-id",
+		Device:    device2,
+		Sequence:  1,
+		Error:     "cannot refresh snap-one",
+		Snaps:     []assemblestate.SnapStatus{{Instance: "snap-one", Revision: "3", Channel: "latest/stable"}},
+	})
+	c.Assert(err, check.IsNil)
+
+	status, err = clusterstate.Status(s.st)
+	c.Assert(err, check.IsNil)
+	c.Check(status.Sequence, check.Equals, 2)
+	c.Check(status.Subclusters, check.DeepEquals, []clusterstate.SubclusterStatus{{
+		Name: "default",
+		Devices: []clusterstate.DeviceStatus{
+			{ID: 1, Device: device1, Status: "lagging", Sequence: 1, Reason: "cluster sequence 2 not applied yet", Local: true},
+			{ID: 2, Device: device2, Status: "failed", Sequence: 1, Reason: "cannot refresh snap-one", Received: statusTestTime},
+		},
+	}})
+}
+
+func (s *statusSuite) TestConvergence(c *check.C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	for _, tc := range []struct {
+		snaps  []assemblestate.SnapStatus
+		status string
+		reason string
+	}{{
+		snaps:  []assemblestate.SnapStatus{{Instance: "snap-one", Revision: "1", Channel: "latest/stable"}},
+		status: "lagging",
+		reason: `no status for snap "snap-two"`,
+	}, {
+		snaps:  []assemblestate.SnapStatus{{Instance: "snap-one", Revision: "1", Channel: "latest/beta"}, {Instance: "snap-two"}},
+		status: "lagging",
+		reason: `snap "snap-one" tracks "latest/beta" instead of "latest/stable"`,
+	}, {
+		snaps:  []assemblestate.SnapStatus{{Instance: "snap-one", Revision: "1", Channel: "latest/stable"}, {Instance: "snap-two", Revision: "2"}},
+		status: "lagging",
+		reason: `snap "snap-two" is not removed`,
+	}, {
+		snaps:  []assemblestate.SnapStatus{{Instance: "snap-one", Revision: "1", Channel: "latest/stable"}, {Instance: "snap-two"}},
+		status: "converged",
+	}} {
+		err := clusterstate.RecordPeerStatus(s.st, assemblestate.Status{
+			ClusterID: "cluster-id",
+			Device:    device3,
+			Sequence:  1,
+			Snaps:     tc.snaps,
+		})
+		c.Assert(err, check.IsNil)
+
+		status, err := clusterstate.Status(s.st)
+		c.Assert(err, check.IsNil)
+		dev := status.Subclusters[0].Devices[2]
+		c.Check(dev.Device, check.Equals, device3)
+		c.Check(dev.Status, check.Equals, tc.status)
+		c.Check(dev.Reason, check.Equals, tc.reason)
+	}
+}
+
+func (s *statusSuite) TestRecordPeerStatusErrors(c *check.C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	err := clusterstate.RecordPeerStatus(s.st, assemblestate.Status{ClusterID: "other", Device: device2})
+	c.Check(err, check.ErrorMatches, `cannot record status for cluster "other": current cluster is "cluster-id"`)
+
+	err = clusterstate.RecordPeerStatus(s.st, assemblestate.Status{ClusterID: "cluster-id", Device: "serial-9.model.brand"})
+	c.Check(err, check.ErrorMatches, `cannot record status of device "serial-9.model.brand": device is not part of cluster "cluster-id"`)
+}
+
+func (s *statusSuite) TestStatusNoCluster(c *check.C) {
+	st, _ := newStateWithStoreStack(c)
+
+	st.Lock()
+	defer st.Unlock()
+
+	_, err := clusterstate.Status(st)
+	c.Check(err, testutil.ErrorIs, clusterstate.ErrNoClusterAssertion)
+	_, err = clusterstate.LocalStatus(st)
+	c.Check(err, testutil.ErrorIs, clusterstate.ErrNoClusterAssertion)
+}
+
+type publishedStatus struct {
+	addr   string
+	fp     assemblestate.Fingerprint
+	kind   string
+	status *assemblestate.Status
+}
+
+type fakeClient struct {
+	sent []publishedStatus
+	fail map[string]bool
+}
+
+func (f *fakeClient) Trusted(ctx context.Context, addr string, fp assemblestate.Fingerprint, kind string, message any) error {
+	if f.fail[addr] {
+		return errors.New("connection refused")
+	}
+	f.sent = append(f.sent, publishedStatus{addr: addr, fp: fp, kind: kind, status: message.(*assemblestate.Status)})
+	return nil
+}
+
+func (f *fakeClient) Untrusted(ctx context.Context, addr string, kind string, message any) (assemblestate.Fingerprint, error) {
+	panic("unexpected untrusted message")
+}
+
+func (s *statusSuite) TestPublishStatus(c *check.C) {
+	mgr := clusterstate.Manager(s.st, state.NewTaskRunner(s.st))
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	fp2 := assemblestate.CalculateFP([]byte("cert-2"))
+	fp3 := assemblestate.CalculateFP([]byte("cert-3"))
+	peers := []clusterstate.StatusPeer{
+		{Device: device2, Addresses: []string{"192.168.0.11:7070"}, FP: fp2},
+		{Device: device3, Addresses: []string{"10.0.0.12:7070", "192.168.0.12:7070"}, FP: fp3},
+	}
+
+	client := &fakeClient{}
+	c.Assert(mgr.PublishStatus(context.Background(), client, peers), check.IsNil)
+	c.Assert(client.sent, check.HasLen, 2)
+	c.Check(client.sent[0].addr, check.Equals, "192.168.0.11:7070")
+	c.Check(client.sent[0].fp, check.Equals, fp2)
+	c.Check(client.sent[0].kind, check.Equals, "status")
+	c.Check(client.sent[0].status.Device, check.Equals, device1)
+	c.Check(client.sent[0].status.Sequence, check.Equals, 1)
+	c.Check(client.sent[1].addr, check.Equals, "10.0.0.12:7070")
+
+	// publication goes on when a peer cannot be reached, and the other
+	// addresses of a peer are tried in turn
+	client = &fakeClient{fail: map[string]bool{"192.168.0.11:7070": true, "10.0.0.12:7070": true}}
+	err := mgr.PublishStatus(context.Background(), client, peers)
+	c.Assert(err, check.ErrorMatches, `cannot publish cluster status to "`+device2+`"`)
+	c.Assert(client.sent, check.HasLen, 1)
+	c.Check(client.sent[0].addr, check.Equals, "192.168.0.12:7070")
+}
+
+func generateTestCert(c *check.C) (tls.Certificate, assemblestate.Fingerprint) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, check.IsNil)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, pub, priv)
+	c.Assert(err, check.IsNil)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: priv}, assemblestate.CalculateFP(der)
+}
+
+func (s *statusSuite) TestServeStatus(c *check.C) {
+	serverCert, serverFP := generateTestCert(c)
+	peerCert, peerFP := generateTestCert(c)
+	strangerCert, _ := generateTestCert(c)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := clusterstate.Manager(s.st, state.NewTaskRunner(s.st)).ServeStatus(ctx, assemblestate.NewHTTPSTransport(), ln, serverCert, []clusterstate.StatusPeer{
+			{Device: device2, FP: peerFP},
+		})
+		c.Check(err, check.IsNil)
+	}()
+
+	status := assemblestate.Status{
+		ClusterID: "cluster-id",
+		Device:    device2,
+		Sequence:  1,
+		Snaps:     []assemblestate.SnapStatus{{Instance: "snap-one", Revision: "4", Channel: "latest/stable"}},
+	}
+
+	client := assemblestate.NewHTTPSClient(peerCert, nil, nil)
+	err = client.Trusted(ctx, addr, serverFP, "status", status)
+	c.Assert(err, check.IsNil)
+
+	// a peer can only publish its own status
+	other := status
+	other.Device = device3
+	err = client.Trusted(ctx, addr, serverFP, "status", other)
+	c.Check(err, check.ErrorMatches, "response to 'status' message contains status code 400")
+
+	// and cannot take part in assembly anymore
+	_, err = client.Untrusted(ctx, addr, "auth", assemblestate.Auth{})
+	c.Check(err, check.ErrorMatches, "got non-200 status code in response to auth message: 403")
+	err = client.Trusted(ctx, addr, serverFP, "routes", assemblestate.Routes{})
+	c.Check(err, check.ErrorMatches, "response to 'routes' message contains status code 400")
+
+	// unknown devices are refused
+	stranger := assemblestate.NewHTTPSClient(strangerCert, nil, nil)
+	err = stranger.Trusted(ctx, addr, serverFP, "status", status)
+	c.Check(err, check.ErrorMatches, "response to 'status' message contains status code 403")
+
+	cancel()
+	wg.Wait()
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	result, err := clusterstate.Status(s.st)
+	c.Assert(err, check.IsNil)
+	c.Check(result.Subclusters[0].Devices[1], check.DeepEquals, clusterstate.DeviceStatus{
+		ID:       2,
+		Device:   device2,
+		Status:   "lagging",
+		Sequence: 1,
+		Reason:   `no status for snap "snap-two"`,
+		Received: statusTestTime,
+	})
+}
+
+// fakeStatusPeer is a peer device that exchanges statuses with this device.
+type fakeStatusPeer struct {
+	fp       assemblestate.Fingerprint
+	statuses chan assemblestate.Status
+}
+
+func (p *fakeStatusPeer) AuthenticateAndCommit(auth assemblestate.Auth, fp assemblestate.Fingerprint) error {
+	return errors.New("unexpected auth message")
+}
+
+func (p *fakeStatusPeer) VerifyPeer(fp assemblestate.Fingerprint) (assemblestate.VerifiedPeer, error) {
+	if fp != p.fp {
+		return nil, errors.New("unknown peer certificate")
+	}
+	return p, nil
+}
+
+func (p *fakeStatusPeer) CommitDeviceQueries(unknown assemblestate.UnknownDevices) error {
+	return errors.New("unexpected unknown message")
+}
+
+func (p *fakeStatusPeer) CommitDevices(devices assemblestate.Devices) error {
+	return errors.New("unexpected devices message")
+}
+
+func (p *fakeStatusPeer) CommitRoutes(routes assemblestate.Routes) error {
+	return errors.New("unexpected routes message")
+}
+
+func (p *fakeStatusPeer) CommitStatus(status assemblestate.Status) error {
+	p.statuses <- status
+	return nil
+}
+
+func freeAddress(c *check.C) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func (s *statusSuite) TestStatusExchange(c *check.C) {
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+	// only the first publication is awaited
+	s.AddCleanup(clusterstate.MockStatusPublishPeriod(time.Hour))
+
+	// the TLS identity of this device
+	certPEM, keyPEM := generateTestCertPEM(c)
+	c.Assert(os.MkdirAll(filepath.Join(dirs.SnapDeviceDir, "cluster"), 0700), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dirs.SnapDeviceDir, "cluster", "cert.pem"), certPEM, 0644), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dirs.SnapDeviceDir, "cluster", "key.pem"), keyPEM, 0600), check.IsNil)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	c.Assert(err, check.IsNil)
+	fp := assemblestate.CalculateFP(cert.Certificate[0])
+
+	addr := freeAddress(c)
+	peerLn, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, check.IsNil)
+	peerCert, peerFP := generateTestCert(c)
+
+	s.st.Lock()
+	devices := statusTestDevices()
+	devices[0]["addresses"] = []any{addr}
+	devices[1]["addresses"] = []any{peerLn.Addr().String()}
+	bundle, _ := makeClusterBundleWithSigning(c, s.sa, statusAccountID, "cluster-id", 2, devices, []map[string]any{{
+		"name":    "default",
+		"devices": []any{"1", "2", "3"},
+		"snaps": []any{
+			map[string]any{"state": "clustered", "instance": "snap-one", "channel": "latest/stable"},
+		},
+	}})
+	c.Assert(clusterstate.UpdateCluster(s.st, bytes.NewReader(bundle)), check.IsNil)
+	// the third device didn't assemble with this device, it is not trusted
+	err = clusterstate.RecordFingerprints(s.st, map[string]assemblestate.Fingerprint{device2: peerFP})
+	c.Assert(err, check.IsNil)
+	s.st.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	peer := &fakeStatusPeer{fp: fp, statuses: make(chan assemblestate.Status, 1)}
+	transport := assemblestate.NewStreamTransport()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Check(transport.Serve(ctx, peerLn, peerCert, peer), check.IsNil)
+	}()
+	defer wg.Wait()
+	defer cancel()
+
+	mgr := clusterstate.Manager(s.st, state.NewTaskRunner(s.st))
+	defer mgr.Stop()
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	// this device publishes the status that it just applied
+	select {
+	case status := <-peer.statuses:
+		c.Check(status.Device, check.Equals, device1)
+		c.Check(status.Sequence, check.Equals, 2)
+	case <-time.After(10 * time.Second):
+		c.Fatal("status not published")
+	}
+
+	// and records the status that its peer publishes
+	status := assemblestate.Status{
+		ClusterID: "cluster-id",
+		Device:    device2,
+		Sequence:  2,
+		Snaps:     []assemblestate.SnapStatus{{Instance: "snap-one", Revision: "4", Channel: "latest/stable"}},
+	}
+	c.Assert(transport.NewClient(peerCert).Trusted(ctx, addr, fp, "status", status), check.IsNil)
+
+	s.st.Lock()
+	result, err := clusterstate.Status(s.st)
+	s.st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(result.Subclusters[0].Devices, check.DeepEquals, []clusterstate.DeviceStatus{
+		{ID: 1, Device: device1, Status: "converged", Sequence: 2, Local: true},
+		{ID: 2, Device: device2, Status: "converged", Sequence: 2, Received: statusTestTime},
+		{ID: 3, Device: device3, Status: "unknown"},
+	})
+
+	// the exchange stops during assembly sessions, which use the same port
+	s.st.Lock()
+	chg := s.st.NewChange("update-cluster-membership", "...")
+	t := s.st.NewTask("assemble-cluster", "...")
+	chg.AddTask(t)
+	s.st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+	ln, err := net.Listen("tcp", addr)
+	c.Assert(err, check.IsNil)
+	ln.Close()
+
+	s.st.Lock()
+	t.SetStatus(state.DoneStatus)
+	s.st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+	_, err = net.Listen("tcp", addr)
+	c.Check(err, check.ErrorMatches, ".*address already in use")
+
+	mgr.Stop()
+	ln, err = net.Listen("tcp", addr)
+	c.Assert(err, check.IsNil)
+	ln.Close()
+}
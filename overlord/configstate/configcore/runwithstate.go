@@ -60,6 +60,10 @@ func init() {
 	addWithStateHandler(validateRefreshSchedule, nil, validateOnly)
 	addWithStateHandler(validateRefreshRateLimit, nil, validateOnly)
 	addWithStateHandler(validateAutomaticSnapshotsExpiration, nil, validateOnly)
+	addWithStateHandler(validateSnapshotsDeduplicate, nil, validateOnly)
+	addWithStateHandler(validateSnapshotsEncryption, nil, validateOnly)
+	addWithStateHandler(validateSnapshotsSchedule, nil, validateOnly)
+	addWithStateHandler(validateSnapshotsRemote, nil, validateOnly)
 
 	// netplan.*
 	addWithStateHandler(validateNetplanSettings, handleNetplanConfiguration, coreOnly)
@@ -76,6 +80,9 @@ func init() {
 	// experimental.apparmor-prompting
 	addWithStateHandler(nil, doExperimentalApparmorPromptingDaemonRestart, nil)
 
+	// security-log.syslog.{address,transport,buffer-size}
+	addWithStateHandler(validateSecurityLogSettings, handleSecurityLogConfiguration, nil)
+
 	// interface.*.allow-auto-connection
 	addWithStateHandler(validateAllowAutoConnectionValue, nil, &flags{validatedOnlyStateConfig: true})
 
@@ -173,6 +180,8 @@ func applyHandlers(dev sysconfig.Device, cfg RunTransaction, handlers []configHa
 			}
 		case strings.HasPrefix(k, "core."+customCertPrefix+"."):
 			// validated by validateCustomCertificateRequest
+		case isSnapshotsScheduleChange(k):
+			// validated by validateSnapshotsSchedule
 		case isNetplanChange(k):
 			if release.OnClassic {
 				return fmt.Errorf("cannot set netplan configuration on classic")
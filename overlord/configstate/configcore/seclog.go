@@ -0,0 +1,192 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build !nomanagers
+
+/*
+ * Copyright (C) 2024 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package configcore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/restart"
+	"github.com/snapcore/snapd/seclog"
+	"github.com/snapcore/snapd/strutil"
+)
+
+const (
+	optionSecurityLogSyslogAddress    = "security-log.syslog.address"
+	optionSecurityLogSyslogTransport  = "security-log.syslog.transport"
+	optionSecurityLogSyslogBufferSize = "security-log.syslog.buffer-size"
+
+	// defaultSecurityLogBufferSize is the size in bytes of the buffer of
+	// events kept while the collector cannot be reached, unless configured
+	// otherwise.
+	defaultSecurityLogBufferSize = 4 * 1000 * 1000
+	// minSecurityLogBufferSize, in bytes, ensures the buffer can hold a few
+	// events.
+	minSecurityLogBufferSize = 64 * 1000
+)
+
+func init() {
+	supportedConfigurations["core."+optionSecurityLogSyslogAddress] = true
+	supportedConfigurations["core."+optionSecurityLogSyslogTransport] = true
+	supportedConfigurations["core."+optionSecurityLogSyslogBufferSize] = true
+}
+
+func validateSecurityLogSettings(tr RunTransaction) error {
+	address, err := coreCfg(tr, optionSecurityLogSyslogAddress)
+	if err != nil {
+		return err
+	}
+	if address != "" {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("cannot set %s: %v", optionSecurityLogSyslogAddress, err)
+		}
+		if host == "" || port == "" {
+			return fmt.Errorf("cannot set %s: %q must be of the form host:port", optionSecurityLogSyslogAddress, address)
+		}
+	}
+
+	transport, err := coreCfg(tr, optionSecurityLogSyslogTransport)
+	if err != nil {
+		return err
+	}
+	switch transport {
+	case "", seclog.SyslogTCP, seclog.SyslogTLS:
+		// noop
+	default:
+		return fmt.Errorf("%s can only be set to %q or %q", optionSecurityLogSyslogTransport, seclog.SyslogTCP, seclog.SyslogTLS)
+	}
+
+	bufferSize, err := coreCfg(tr, optionSecurityLogSyslogBufferSize)
+	if err != nil {
+		return err
+	}
+	if bufferSize != "" {
+		size, err := strutil.ParseByteSize(bufferSize)
+		if err != nil {
+			return fmt.Errorf("cannot set %s: %v", optionSecurityLogSyslogBufferSize, err)
+		}
+		if size < minSecurityLogBufferSize {
+			return fmt.Errorf("cannot set %s: size must be at least %s", optionSecurityLogSyslogBufferSize, strutil.SizeToStr(minSecurityLogBufferSize))
+		}
+	}
+
+	return nil
+}
+
+func securityLogChanged(tr RunTransaction) bool {
+	for _, name := range tr.Changes() {
+		if strings.HasPrefix(name, "core.security-log.") {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSecurityLogConfiguration writes the configuration of the forwarding
+// of security events read by snapd on startup, restarting it so that the
+// configuration takes effect.
+func handleSecurityLogConfiguration(tr RunTransaction, opts *fsOnlyContext) error {
+	// Run only if the options changed to avoid extra filesystem access
+	if !securityLogChanged(tr) {
+		return nil
+	}
+
+	address, err := coreCfg(tr, optionSecurityLogSyslogAddress)
+	if err != nil {
+		return err
+	}
+	transport, err := coreCfg(tr, optionSecurityLogSyslogTransport)
+	if err != nil {
+		return err
+	}
+	if transport == "" {
+		transport = seclog.SyslogTLS
+	}
+	bufferSize, err := coreCfg(tr, optionSecurityLogSyslogBufferSize)
+	if err != nil {
+		return err
+	}
+	size := int64(defaultSecurityLogBufferSize)
+	if bufferSize != "" {
+		// already validated
+		if size, err = strutil.ParseByteSize(bufferSize); err != nil {
+			return err
+		}
+	}
+
+	rootDir := dirs.GlobalRootDir
+	if opts != nil {
+		rootDir = opts.RootDir
+	}
+	seclogDir := dirs.SnapSeclogDirUnder(rootDir)
+	configPath := filepath.Join(seclogDir, seclog.SyslogConfigFile)
+
+	if address == "" {
+		// forwarding is disabled, events that were not forwarded yet are
+		// kept in case it is enabled again
+		err := os.Remove(configPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		data, err := json.Marshal(seclog.SyslogConfig{
+			Network:    transport,
+			Address:    address,
+			BufferSize: size,
+		})
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(seclogDir, 0700); err != nil {
+			return err
+		}
+		err = osutil.EnsureFileState(configPath, &osutil.MemoryFileState{
+			Content: data,
+			Mode:    0600,
+		})
+		if errors.Is(err, osutil.ErrSameState) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts == nil {
+		// snapd sets up forwarding on startup
+		st := tr.State()
+		st.Lock()
+		defer st.Unlock()
+		restartRequest(st, restart.RestartDaemon, nil)
+	}
+
+	return nil
+}
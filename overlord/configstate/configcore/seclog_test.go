@@ -0,0 +1,167 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build !nomanagers
+
+/*
+ * Copyright (C) 2024 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package configcore_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/overlord/restart"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type seclogSuite struct {
+	configcoreSuite
+
+	configPath string
+	restarts   int
+}
+
+var _ = Suite(&seclogSuite{})
+
+func (s *seclogSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+
+	s.configPath = filepath.Join(dirs.SnapSeclogDir, "syslog.json")
+
+	err := os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc/"), 0755)
+	c.Assert(err, IsNil)
+	err = os.WriteFile(filepath.Join(dirs.GlobalRootDir, "/etc/environment"), nil, 0644)
+	c.Assert(err, IsNil)
+
+	s.restarts = 0
+	s.AddCleanup(configcore.MockRestartRequest(func(st *state.State, t restart.RestartType, rebootInfo *boot.RebootInfo) {
+		c.Check(st, Equals, s.state)
+		c.Check(t, Equals, restart.RestartDaemon)
+		c.Check(rebootInfo, IsNil)
+		s.restarts++
+	}))
+}
+
+func (s *seclogSuite) TestConfigureSyslog(c *C) {
+	err := configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		changes: map[string]any{
+			"security-log.syslog.address": "logs.example.com:6514",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.configPath, testutil.FileEquals, `{"network":"tls","address":"logs.example.com:6514","buffer-size":4000000}`)
+	c.Check(s.restarts, Equals, 1)
+
+	fi, err := os.Stat(s.configPath)
+	c.Assert(err, IsNil)
+	c.Check(fi.Mode().Perm(), Equals, os.FileMode(0600))
+
+	err = configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		conf: map[string]any{
+			"security-log.syslog.address": "logs.example.com:6514",
+		},
+		changes: map[string]any{
+			"security-log.syslog.transport":   "tcp",
+			"security-log.syslog.buffer-size": "10MB",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.configPath, testutil.FileEquals, `{"network":"tcp","address":"logs.example.com:6514","buffer-size":10000000}`)
+	c.Check(s.restarts, Equals, 2)
+}
+
+func (s *seclogSuite) TestConfigureSyslogUnchanged(c *C) {
+	conf := map[string]any{
+		"security-log.syslog.address": "logs.example.com:6514",
+	}
+	err := configcore.Run(coreDev, &mockConf{state: s.state, changes: conf})
+	c.Assert(err, IsNil)
+	c.Check(s.restarts, Equals, 1)
+
+	// setting the same values does not restart snapd again
+	err = configcore.Run(coreDev, &mockConf{state: s.state, conf: conf, changes: conf})
+	c.Assert(err, IsNil)
+	c.Check(s.restarts, Equals, 1)
+
+	// neither does changing unrelated options
+	err = configcore.Run(coreDev, &mockConf{
+		state:   s.state,
+		conf:    conf,
+		changes: map[string]any{"debug.snapd.log": "false"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.restarts, Equals, 1)
+}
+
+func (s *seclogSuite) TestConfigureSyslogDisable(c *C) {
+	c.Assert(os.MkdirAll(dirs.SnapSeclogDir, 0700), IsNil)
+	c.Assert(os.WriteFile(s.configPath, []byte(`{}`), 0600), IsNil)
+	bufferPath := filepath.Join(dirs.SnapSeclogDir, "syslog-buffer")
+	c.Assert(os.WriteFile(bufferPath, []byte("3 foo"), 0600), IsNil)
+
+	err := configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		conf: map[string]any{
+			"security-log.syslog.address": "logs.example.com:6514",
+		},
+		changes: map[string]any{
+			"security-log.syslog.address": "",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.configPath, testutil.FileAbsent)
+	c.Check(s.restarts, Equals, 1)
+	// events not forwarded yet are kept
+	c.Check(bufferPath, testutil.FilePresent)
+
+	// nothing to do when already disabled
+	err = configcore.Run(coreDev, &mockConf{
+		state: s.state,
+		changes: map[string]any{
+			"security-log.syslog.address": "",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Check(s.restarts, Equals, 1)
+}
+
+func (s *seclogSuite) TestConfigureSyslogInvalid(c *C) {
+	for _, tc := range []struct {
+		key, value, err string
+	}{
+		{"security-log.syslog.address", "logs.example.com", `cannot set security-log.syslog.address: address logs.example.com: missing port in address`},
+		{"security-log.syslog.address", ":6514", `cannot set security-log.syslog.address: ":6514" must be of the form host:port`},
+		{"security-log.syslog.transport", "udp", `security-log.syslog.transport can only be set to "tcp" or "tls"`},
+		{"security-log.syslog.buffer-size", "lots", `cannot set security-log.syslog.buffer-size: cannot parse "lots": .*`},
+		{"security-log.syslog.buffer-size", "1kB", `cannot set security-log.syslog.buffer-size: size must be at least 64kB`},
+	} {
+		err := configcore.Run(coreDev, &mockConf{
+			state:   s.state,
+			changes: map[string]any{tc.key: tc.value},
+		})
+		c.Check(err, ErrorMatches, tc.err, Commentf("%s=%s", tc.key, tc.value))
+	}
+	c.Check(s.configPath, testutil.FileAbsent)
+	c.Check(s.restarts, Equals, 0)
+}
@@ -22,12 +22,42 @@ package configcore
 
 import (
 	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/timeutil"
 )
 
 func init() {
 	// add supported configuration of this module
 	supportedConfigurations["core.snapshots.automatic.retention"] = true
+	supportedConfigurations["core.snapshots.deduplicate"] = true
+	supportedConfigurations["core.snapshots.encryption"] = true
+	supportedConfigurations["core.snapshots.encryption-key-file"] = true
+	supportedConfigurations["core.snapshots.scheduled.keep-last"] = true
+	supportedConfigurations["core.snapshots.scheduled.keep-daily"] = true
+	supportedConfigurations["core.snapshots.scheduled.keep-weekly"] = true
+	supportedConfigurations["core.snapshots.remote.type"] = true
+	supportedConfigurations["core.snapshots.remote.directory"] = true
+	supportedConfigurations["core.snapshots.remote.device-path"] = true
+	supportedConfigurations["core.snapshots.remote.s3.endpoint"] = true
+	supportedConfigurations["core.snapshots.remote.s3.bucket"] = true
+	supportedConfigurations["core.snapshots.remote.s3.region"] = true
+	supportedConfigurations["core.snapshots.remote.s3.prefix"] = true
+	supportedConfigurations["core.snapshots.remote.s3.credentials-file"] = true
+}
+
+const snapshotsSchedulePrefix = "core.snapshots.schedule."
+
+// isSnapshotsScheduleChange returns whether the given change sets the
+// timer of the scheduled snapshots of a snap, i.e. snapshots.schedule.<snap>.
+func isSnapshotsScheduleChange(chg string) bool {
+	return strings.HasPrefix(chg, snapshotsSchedulePrefix)
 }
 
 func validateAutomaticSnapshotsExpiration(tr RunTransaction) error {
@@ -46,3 +76,135 @@ func validateAutomaticSnapshotsExpiration(tr RunTransaction) error {
 	}
 	return nil
 }
+
+func validateSnapshotsDeduplicate(tr RunTransaction) error {
+	return validateBoolFlag(tr, "snapshots.deduplicate")
+}
+
+func validateSnapshotsEncryption(tr RunTransaction) error {
+	encryption, err := coreCfg(tr, "snapshots.encryption")
+	if err != nil {
+		return err
+	}
+	switch encryption {
+	case "", "none", "key-file", "tpm", "passphrase":
+	default:
+		return fmt.Errorf("snapshots.encryption can only be set to 'none', 'key-file', 'tpm' or 'passphrase'")
+	}
+
+	keyFile, err := coreCfg(tr, "snapshots.encryption-key-file")
+	if err != nil {
+		return err
+	}
+	if keyFile != "" && !filepath.IsAbs(keyFile) {
+		return fmt.Errorf("snapshots.encryption-key-file must be an absolute path")
+	}
+	if encryption == "key-file" && keyFile == "" {
+		return fmt.Errorf("snapshots.encryption-key-file must be set to use 'key-file' snapshot encryption")
+	}
+	return nil
+}
+
+func validateSnapshotsSchedule(tr RunTransaction) error {
+	for _, name := range tr.Changes() {
+		if !isSnapshotsScheduleChange(name) {
+			continue
+		}
+		snapName := strings.TrimPrefix(name, snapshotsSchedulePrefix)
+		if err := naming.ValidateInstance(snapName); err != nil {
+			return fmt.Errorf("cannot set snapshot schedule for %q: %v", snapName, err)
+		}
+		timer, err := coreCfg(tr, strings.TrimPrefix(name, "core."))
+		if err != nil {
+			return err
+		}
+		if timer == "" {
+			continue
+		}
+		if _, err := timeutil.ParseSchedule(timer); err != nil {
+			return fmt.Errorf("cannot parse snapshot schedule for %q: %v", snapName, err)
+		}
+	}
+
+	for _, opt := range []string{"keep-last", "keep-daily", "keep-weekly"} {
+		key := "snapshots.scheduled." + opt
+		value, err := coreCfg(tr, key)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.ParseUint(value, 10, 16); err != nil {
+			return fmt.Errorf("%s must be a non-negative number, not %q", key, value)
+		}
+	}
+	return nil
+}
+
+func validateSnapshotsRemote(tr RunTransaction) error {
+	remoteType, err := coreCfg(tr, "snapshots.remote.type")
+	if err != nil {
+		return err
+	}
+	devicePath, err := coreCfg(tr, "snapshots.remote.device-path")
+	if err != nil {
+		return err
+	}
+	if devicePath != "" && !validRemoteDevicePath(devicePath) {
+		return fmt.Errorf("snapshots.remote.device-path must be a relative path such as <brand>/<model>/<serial>, not %q", devicePath)
+	}
+
+	switch remoteType {
+	case "", "none":
+		return nil
+	case "directory":
+		dir, err := coreCfg(tr, "snapshots.remote.directory")
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("snapshots.remote.directory must be an absolute path")
+		}
+	case "s3":
+		endpoint, err := coreCfg(tr, "snapshots.remote.s3.endpoint")
+		if err != nil {
+			return err
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("snapshots.remote.s3.endpoint must be an http or https URL")
+		}
+		bucket, err := coreCfg(tr, "snapshots.remote.s3.bucket")
+		if err != nil {
+			return err
+		}
+		if bucket == "" {
+			return fmt.Errorf("snapshots.remote.s3.bucket must be set to use an 's3' remote snapshot target")
+		}
+		credentialsFile, err := coreCfg(tr, "snapshots.remote.s3.credentials-file")
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(credentialsFile) {
+			return fmt.Errorf("snapshots.remote.s3.credentials-file must be an absolute path")
+		}
+	default:
+		return fmt.Errorf("snapshots.remote.type can only be set to 'none', 'directory' or 's3'")
+	}
+	return nil
+}
+
+// validRemoteDevicePath returns whether the given path can identify a device
+// in a remote snapshot target, both as a directory and in an object name.
+func validRemoteDevicePath(devicePath string) bool {
+	if path.IsAbs(devicePath) || path.Clean(devicePath) != devicePath {
+		return false
+	}
+	for _, part := range strings.Split(devicePath, "/") {
+		if part == "." || part == ".." || url.PathEscape(part) != part {
+			return false
+		}
+	}
+	return true
+}
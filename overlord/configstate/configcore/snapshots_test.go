@@ -71,3 +71,154 @@ func (s *refreshSuite) TestConfigureAutomaticSnapshotsExpirationInvalid(c *C) {
 	})
 	c.Assert(err, ErrorMatches, `snapshots.automatic.retention cannot be parsed:.*`)
 }
+
+func (s *snapshotsSuite) TestConfigureSnapshotsDeduplicate(c *C) {
+	for _, v := range []any{"true", "false", true, false} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]any{
+				"snapshots.deduplicate": v,
+			},
+		})
+		c.Check(err, IsNil)
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsDeduplicateInvalid(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		conf: map[string]any{
+			"snapshots.deduplicate": "maybe",
+		},
+	})
+	c.Assert(err, ErrorMatches, `snapshots.deduplicate can only be set to 'true' or 'false'`)
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsEncryption(c *C) {
+	for _, conf := range []map[string]any{
+		{"snapshots.encryption": "none"},
+		{"snapshots.encryption": "tpm"},
+		{"snapshots.encryption": "passphrase"},
+		{"snapshots.encryption": "key-file", "snapshots.encryption-key-file": "/root/snapshots.key"},
+		{"snapshots.encryption-key-file": "/root/snapshots.key"},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf:  conf,
+		})
+		c.Check(err, IsNil, Commentf("%v", conf))
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsEncryptionInvalid(c *C) {
+	for _, tc := range []struct {
+		conf map[string]any
+		err  string
+	}{
+		{map[string]any{"snapshots.encryption": "rot13"}, `snapshots.encryption can only be set to 'none', 'key-file', 'tpm' or 'passphrase'`},
+		{map[string]any{"snapshots.encryption": "key-file"}, `snapshots.encryption-key-file must be set to use 'key-file' snapshot encryption`},
+		{map[string]any{"snapshots.encryption-key-file": "snapshots.key"}, `snapshots.encryption-key-file must be an absolute path`},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf:  tc.conf,
+		})
+		c.Check(err, ErrorMatches, tc.err)
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsSchedule(c *C) {
+	err := configcore.Run(classicDev, &mockConf{
+		state: s.state,
+		changes: map[string]any{
+			"snapshots.schedule.foo":          "mon,10:00-12:00",
+			"snapshots.schedule.bar_instance": "00:00~24:00/4",
+			"snapshots.schedule.baz":          "",
+			"snapshots.scheduled.keep-last":   5,
+			"snapshots.scheduled.keep-daily":  "7",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsScheduleInvalid(c *C) {
+	for _, tc := range []struct {
+		changes map[string]any
+		err     string
+	}{
+		{map[string]any{"snapshots.schedule.foo": "whenever"}, `cannot parse snapshot schedule for "foo": .*`},
+		{map[string]any{"snapshots.schedule.Foo": "mon"}, `cannot set snapshot schedule for "Foo": invalid snap name: "Foo"`},
+		{map[string]any{"snapshots.scheduled.keep-weekly": "-1"}, `snapshots.scheduled.keep-weekly must be a non-negative number, not "-1"`},
+		{map[string]any{"snapshots.scheduled.keep-last": "lots"}, `snapshots.scheduled.keep-last must be a non-negative number, not "lots"`},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state:   s.state,
+			changes: tc.changes,
+		})
+		c.Check(err, ErrorMatches, tc.err, Commentf("%v", tc.changes))
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsRemote(c *C) {
+	for _, conf := range []map[string]any{
+		{"snapshots.remote.type": "none"},
+		{"snapshots.remote.type": "directory", "snapshots.remote.directory": "/mnt/nfs/snapshots"},
+		{
+			"snapshots.remote.type":        "directory",
+			"snapshots.remote.directory":   "/mnt/nfs/snapshots",
+			"snapshots.remote.device-path": "my-brand/my-model/old-serial",
+		},
+		{
+			"snapshots.remote.type":                "s3",
+			"snapshots.remote.s3.endpoint":         "http://minio.local:9000",
+			"snapshots.remote.s3.bucket":           "snapshots",
+			"snapshots.remote.s3.credentials-file": "/root/s3.json",
+		},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf:  conf,
+		})
+		c.Check(err, IsNil, Commentf("%v", conf))
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureSnapshotsRemoteInvalid(c *C) {
+	s3 := func(endpoint, bucket, credentialsFile string) map[string]any {
+		return map[string]any{
+			"snapshots.remote.type":                "s3",
+			"snapshots.remote.s3.endpoint":         endpoint,
+			"snapshots.remote.s3.bucket":           bucket,
+			"snapshots.remote.s3.credentials-file": credentialsFile,
+		}
+	}
+	for _, tc := range []struct {
+		conf map[string]any
+		err  string
+	}{
+		{map[string]any{"snapshots.remote.type": "ftp"}, `snapshots.remote.type can only be set to 'none', 'directory' or 's3'`},
+		{map[string]any{"snapshots.remote.type": "directory"}, `snapshots.remote.directory must be an absolute path`},
+		{map[string]any{"snapshots.remote.type": "directory", "snapshots.remote.directory": "nfs"}, `snapshots.remote.directory must be an absolute path`},
+		{s3("minio.local", "snapshots", "/root/s3.json"), `snapshots.remote.s3.endpoint must be an http or https URL`},
+		{s3("http://minio.local", "", "/root/s3.json"), `snapshots.remote.s3.bucket must be set to use an 's3' remote snapshot target`},
+		{s3("http://minio.local", "snapshots", ""), `snapshots.remote.s3.credentials-file must be an absolute path`},
+	} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf:  tc.conf,
+		})
+		c.Check(err, ErrorMatches, tc.err, Commentf("%v", tc.conf))
+	}
+
+	for _, devicePath := range []string{"/brand/model", "brand/model/", "brand//model", "brand/../model", "..", ".", "brand/mo del"} {
+		err := configcore.Run(classicDev, &mockConf{
+			state: s.state,
+			conf: map[string]any{
+				"snapshots.remote.type":        "directory",
+				"snapshots.remote.directory":   "/mnt/nfs/snapshots",
+				"snapshots.remote.device-path": devicePath,
+			},
+		})
+		c.Check(err, ErrorMatches, `snapshots.remote.device-path must be a relative path such as <brand>/<model>/<serial>, not .*`, Commentf("%q", devicePath))
+	}
+}
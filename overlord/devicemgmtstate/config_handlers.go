@@ -0,0 +1,170 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/jsonutil"
+	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+var configstateConfigureInstalled = configstate.ConfigureInstalled
+
+var configureSnapChangeKind = swfeats.RegisterChangeKind("configure-snap")
+
+// configActions maps the message kinds of the built-in configuration
+// handlers to the action they perform.
+var configActions = map[string]string{
+	"snap-config-set":   "set",
+	"snap-config-unset": "unset",
+}
+
+// configMessageBody is the body of the request-messages changing the
+// configuration of a snap.
+type configMessageBody struct {
+	// Snap is the instance name of the snap to configure, "system" can be
+	// used for the system configuration.
+	Snap string `json:"snap"`
+	// Values maps the configuration options to set to their new value.
+	Values map[string]any `json:"values,omitempty"`
+	// Keys lists the configuration options to unset.
+	Keys []string `json:"keys,omitempty"`
+}
+
+// configHandler is a MessageHandler turning request-messages into changes
+// setting or unsetting snap configuration options. Only the brand of the
+// device model is allowed to configure the snaps of the device.
+type configHandler struct {
+	action string
+}
+
+func (h *configHandler) parseBody(msg *RequestMessage) (*configMessageBody, error) {
+	var body configMessageBody
+	if err := jsonutil.DecodeWithNumber(strings.NewReader(msg.Body), &body); err != nil {
+		return nil, fmt.Errorf("cannot decode %s message body: %v", msg.Kind, err)
+	}
+	if body.Snap == "" {
+		return nil, fmt.Errorf("cannot %s snap configuration: snap name not provided", h.action)
+	}
+	body.Snap = configstate.RemapSnapFromRequest(body.Snap)
+	if err := naming.ValidateInstance(body.Snap); err != nil {
+		return nil, fmt.Errorf("cannot %s snap configuration: %v", h.action, err)
+	}
+
+	var keys []string
+	switch h.action {
+	case "set":
+		if len(body.Keys) != 0 {
+			return nil, fmt.Errorf("cannot set configuration of snap %q: keys are not supported, use values", body.Snap)
+		}
+		for key := range body.Values {
+			keys = append(keys, key)
+		}
+	case "unset":
+		if len(body.Values) != 0 {
+			return nil, fmt.Errorf("cannot unset configuration of snap %q: values are not supported, use keys", body.Snap)
+		}
+		keys = body.Keys
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("cannot %s configuration of snap %q: no options provided", h.action, body.Snap)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("cannot %s configuration of snap %q: empty option name", h.action, body.Snap)
+		}
+		if _, err := config.ParseKey(key); err != nil {
+			return nil, fmt.Errorf("cannot %s configuration of snap %q: %v", h.action, body.Snap, err)
+		}
+	}
+	return &body, nil
+}
+
+// patch returns the configuration patch described by the message body,
+// unset options being patched to nil.
+func (h *configHandler) patch(body *configMessageBody) map[string]any {
+	if h.action == "set" {
+		return body.Values
+	}
+	patch := make(map[string]any, len(body.Keys))
+	for _, key := range body.Keys {
+		patch[key] = nil
+	}
+	return patch
+}
+
+// Validate checks that the message comes from the brand of the device model
+// and that its body describes a valid configuration patch.
+func (h *configHandler) Validate(st *state.State, msg *RequestMessage) error {
+	if err := checkBrandOperator(st, msg); err != nil {
+		return err
+	}
+
+	_, err := h.parseBody(msg)
+	return err
+}
+
+// Apply creates the change applying the configuration patch.
+func (h *configHandler) Apply(st *state.State, msg *RequestMessage) (changeID string, err error) {
+	body, err := h.parseBody(msg)
+	if err != nil {
+		return "", err
+	}
+
+	ts, err := configstateConfigureInstalled(st, body.Snap, h.patch(body), 0)
+	if err != nil {
+		return "", err
+	}
+
+	chg := st.NewChange(configureSnapChangeKind, fmt.Sprintf("Change configuration of %q snap", body.Snap))
+	chg.AddAll(ts)
+	chg.Set("snap-names", []string{body.Snap})
+	MarkChangeForMessage(chg, msg)
+	st.EnsureBefore(0)
+
+	return chg.ID(), nil
+}
+
+// ResultFromChange reports the outcome of the configuration change.
+func (h *configHandler) ResultFromChange(chg *state.Change) (body map[string]any, err error) {
+	var snapNames []string
+	if err := chg.Get("snap-names", &snapNames); err != nil || len(snapNames) != 1 {
+		return nil, fmt.Errorf("internal error: cannot get snap name of change %s", chg.ID())
+	}
+	name := configstate.RemapSnapToResponse(snapNames[0])
+
+	if chg.Status() != state.DoneStatus {
+		return nil, changeError(chg, fmt.Sprintf("%s configuration of snap %q", h.action, name))
+	}
+
+	return map[string]any{
+		"change-id": chg.ID(),
+		"status":    chg.Status().String(),
+		"snap":      name,
+	}, nil
+}
@@ -0,0 +1,176 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/devicemgmtstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *deviceMgmtMgrSuite) TestConfigHandlersRegistered(c *C) {
+	for _, kind := range []string{"snap-config-set", "snap-config-unset"} {
+		c.Check(s.mgr.Handler(kind), NotNil, Commentf("kind %q", kind))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestConfigHandlerValidateOK(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	for _, tc := range []struct {
+		kind string
+		body string
+	}{
+		{"snap-config-set", `{"snap": "foo", "values": {"a": 1, "b.c": "x", "d": null}}`},
+		{"snap-config-set", `{"snap": "system", "values": {"service.ssh.disable": true}}`},
+		{"snap-config-unset", `{"snap": "foo_bar", "keys": ["a", "b.c"]}`},
+	} {
+		msg := s.makeSnapRequestMessage(tc.kind, tc.body)
+		err := s.mgr.Handler(tc.kind).Validate(s.st, msg)
+		c.Check(err, IsNil, Commentf("%s: %s", tc.kind, tc.body))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestConfigHandlerValidateErrors(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	for _, tc := range []struct {
+		kind   string
+		body   string
+		errMsg string
+	}{
+		{"snap-config-set", `not json`, `cannot decode snap-config-set message body: .*`},
+		{"snap-config-set", `{"values": {"a": 1}}`, `cannot set snap configuration: snap name not provided`},
+		{"snap-config-set", `{"snap": "Foo!", "values": {"a": 1}}`, `cannot set snap configuration: invalid snap name: "Foo!"`},
+		{"snap-config-set", `{"snap": "foo"}`, `cannot set configuration of snap "foo": no options provided`},
+		{"snap-config-set", `{"snap": "foo", "values": {"a": 1}, "keys": ["b"]}`, `cannot set configuration of snap "foo": keys are not supported, use values`},
+		{"snap-config-set", `{"snap": "foo", "values": {"": 1}}`, `cannot set configuration of snap "foo": empty option name`},
+		{"snap-config-set", `{"snap": "foo", "values": {"A_b": 1}}`, `cannot set configuration of snap "foo": invalid option name: "A_b"`},
+		{"snap-config-unset", `{"snap": "foo", "keys": []}`, `cannot unset configuration of snap "foo": no options provided`},
+		{"snap-config-unset", `{"snap": "foo", "values": {"a": 1}}`, `cannot unset configuration of snap "foo": values are not supported, use keys`},
+		{"snap-config-unset", `{"snap": "foo", "keys": ["a..b"]}`, `cannot unset configuration of snap "foo": invalid option name: ""`},
+	} {
+		msg := s.makeSnapRequestMessage(tc.kind, tc.body)
+		err := s.mgr.Handler(tc.kind).Validate(s.st, msg)
+		c.Check(err, ErrorMatches, tc.errMsg, Commentf("%s: %s", tc.kind, tc.body))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestConfigHandlerValidateUnauthorized(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	msg := s.makeSnapRequestMessage("snap-config-set", `{"snap": "foo", "values": {"a": 1}}`)
+	msg.AccountID = "other-brand"
+
+	err := s.mgr.Handler("snap-config-set").Validate(s.st, msg)
+	var unauthorized *devicemgmtstate.UnauthorizedError
+	c.Check(errors.As(err, &unauthorized), Equals, true)
+}
+
+func (s *deviceMgmtMgrSuite) TestConfigHandlerApplySet(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	var calls int
+	restore := devicemgmtstate.MockConfigstateConfigureInstalled(func(st *state.State, snapName string, patch map[string]any, flags int) (*state.TaskSet, error) {
+		calls++
+		c.Check(snapName, Equals, "core")
+		c.Check(patch, DeepEquals, map[string]any{
+			"service.ssh.disable": true,
+			"watchdog.timeout":    json.Number("10"),
+		})
+		c.Check(flags, Equals, 0)
+		return s.mockTaskSet(st, "configure core"), nil
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-config-set", `{"snap": "system", "values": {"service.ssh.disable": true, "watchdog.timeout": 10}}`)
+	chgID, err := s.mgr.Handler("snap-config-set").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+	c.Check(calls, Equals, 1)
+
+	chg := s.checkSnapChange(c, chgID, "configure-snap", `Change configuration of "core" snap`, "core", msg)
+	c.Check(chg.Tasks(), HasLen, 1)
+}
+
+func (s *deviceMgmtMgrSuite) TestConfigHandlerApplyUnset(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	restore := devicemgmtstate.MockConfigstateConfigureInstalled(func(st *state.State, snapName string, patch map[string]any, flags int) (*state.TaskSet, error) {
+		c.Check(snapName, Equals, "foo")
+		c.Check(patch, DeepEquals, map[string]any{"a": nil, "b.c": nil})
+		return s.mockTaskSet(st, "configure foo"), nil
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-config-unset", `{"snap": "foo", "keys": ["a", "b.c"]}`)
+	chgID, err := s.mgr.Handler("snap-config-unset").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+
+	s.checkSnapChange(c, chgID, "configure-snap", `Change configuration of "foo" snap`, "foo", msg)
+}
+
+func (s *deviceMgmtMgrSuite) TestConfigHandlerApplyNotInstalled(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	msg := s.makeSnapRequestMessage("snap-config-set", `{"snap": "foo", "values": {"a": 1}}`)
+	_, err := s.mgr.Handler("snap-config-set").Apply(s.st, msg)
+	c.Assert(err, ErrorMatches, `snap "foo" is not installed`)
+	var notInstalled *snap.NotInstalledError
+	c.Check(errors.As(err, &notInstalled), Equals, true)
+	c.Check(s.st.Changes(), HasLen, 0)
+}
+
+func (s *deviceMgmtMgrSuite) TestConfigHandlerResultFromChange(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	chg := s.st.NewChange("configure-snap", "...")
+	chg.Set("snap-names", []string{"core"})
+	chg.SetStatus(state.DoneStatus)
+
+	body, err := s.mgr.Handler("snap-config-set").ResultFromChange(chg)
+	c.Assert(err, IsNil)
+	c.Check(body, DeepEquals, map[string]any{
+		"change-id": chg.ID(),
+		"status":    "Done",
+		"snap":      "system",
+	})
+
+	chg = s.st.NewChange("configure-snap", "...")
+	chg.Set("snap-names", []string{"foo"})
+	t := s.st.NewTask("run-hook", "...")
+	t.Errorf("configure hook failed")
+	t.SetStatus(state.ErrorStatus)
+	chg.AddTask(t)
+
+	_, err = s.mgr.Handler("snap-config-unset").ResultFromChange(chg)
+	c.Assert(err, ErrorMatches, `(?s)cannot unset configuration of snap "foo" \(change [0-9]+ is Error\): .*configure hook failed.*`)
+}
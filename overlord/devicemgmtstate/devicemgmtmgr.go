@@ -20,7 +20,8 @@
 // Package devicemgmtstate implements the manager and state aspects responsible
 // for message-based remote device management. It receives signed request-message
 // assertions from the store via periodic message exchanges, validates them against
-// SD187 requirements, dispatches them to subsystem-specific handlers (like confdb),
+// SD187 requirements, dispatches them to subsystem-specific handlers (like confdb,
+// or the built-in handlers acting on snaps, their configuration and services),
 // and sends back response-message assertions with processing results.
 package devicemgmtstate
 
@@ -123,6 +124,9 @@ type RequestMessage struct {
 	ReceiveTime time.Time `json:"receive-time"`
 	Dispatched  bool      `json:"dispatched"`
 
+	// Source is where the message came from, empty for the store.
+	Source string `json:"source,omitempty"`
+
 	// ApplyChangeID is set when Apply schedules async work.
 	ApplyChangeID string `json:"apply-change-id,omitempty"`
 
@@ -191,6 +195,10 @@ type deviceMgmtState struct {
 
 	// LastExchangeTime is the timestamp of the last message exchange.
 	LastExchangeTime time.Time `json:"last-exchange-time"`
+
+	// LocalResponses are response messages to locally imported request
+	// messages, kept until they are exported and acknowledged.
+	LocalResponses map[string]store.Message `json:"local-responses,omitempty"`
 }
 
 // getRequestMessage retrieves a request message from the state.
@@ -252,34 +260,7 @@ func (ms *deviceMgmtState) enqueueRequestMessages(pollResp *store.MessageExchang
 			continue
 		}
 
-		seq := ms.Sequences[reqMsg.BaseID]
-		if seq == nil {
-			seq = &sequenceState{}
-			ms.Sequences[reqMsg.BaseID] = seq
-		}
-
-		// Drop any sequenced message that has already been applied.
-		if reqMsg.SeqNum > 0 && reqMsg.SeqNum <= seq.Applied {
-			continue
-		}
-
-		// TODO:GOVERSION:1.21: replace with slices.BinarySearchFunc
-		i := sort.Search(len(seq.Messages), func(i int) bool {
-			return seq.Messages[i].SeqNum >= reqMsg.SeqNum
-		})
-		if i < len(seq.Messages) && seq.Messages[i].SeqNum == reqMsg.SeqNum {
-			continue // duplicate
-		}
-		// TODO:GOVERSION:1.21: replace with slices.Insert(seq.Messages, i, reqMsg)
-		seq.Messages = append(seq.Messages, nil)
-		copy(seq.Messages[i+1:], seq.Messages[i:])
-		seq.Messages[i] = reqMsg
-
-		if reqMsg.SeqNum > 0 {
-			// Move to end of LRU to mark as recently used.
-			ms.removeSequenceFromLRU(reqMsg.BaseID)
-			ms.SequenceLRU = append(ms.SequenceLRU, reqMsg.BaseID)
-		}
+		ms.enqueueRequestMessage(reqMsg)
 	}
 
 	if len(pollResp.Messages) > 0 {
@@ -297,6 +278,42 @@ func (ms *deviceMgmtState) enqueueRequestMessages(pollResp *store.MessageExchang
 	ms.ReadyResponses = make(map[string]store.Message)
 }
 
+// enqueueRequestMessage queues a single request message for processing.
+// It returns false if the message was dropped as a duplicate or because it
+// was already applied.
+func (ms *deviceMgmtState) enqueueRequestMessage(reqMsg *RequestMessage) bool {
+	seq := ms.Sequences[reqMsg.BaseID]
+	if seq == nil {
+		seq = &sequenceState{}
+		ms.Sequences[reqMsg.BaseID] = seq
+	}
+
+	// Drop any sequenced message that has already been applied.
+	if reqMsg.SeqNum > 0 && reqMsg.SeqNum <= seq.Applied {
+		return false
+	}
+
+	// TODO:GOVERSION:1.21: replace with slices.BinarySearchFunc
+	i := sort.Search(len(seq.Messages), func(i int) bool {
+		return seq.Messages[i].SeqNum >= reqMsg.SeqNum
+	})
+	if i < len(seq.Messages) && seq.Messages[i].SeqNum == reqMsg.SeqNum {
+		return false // duplicate
+	}
+	// TODO:GOVERSION:1.21: replace with slices.Insert(seq.Messages, i, reqMsg)
+	seq.Messages = append(seq.Messages, nil)
+	copy(seq.Messages[i+1:], seq.Messages[i:])
+	seq.Messages[i] = reqMsg
+
+	if reqMsg.SeqNum > 0 {
+		// Move to end of LRU to mark as recently used.
+		ms.removeSequenceFromLRU(reqMsg.BaseID)
+		ms.SequenceLRU = append(ms.SequenceLRU, reqMsg.BaseID)
+	}
+
+	return true
+}
+
 // removeSequenceFromLRU removes a sequence from the LRU list, if present.
 func (ms *deviceMgmtState) removeSequenceFromLRU(baseID string) {
 	for i, id := range ms.SequenceLRU {
@@ -328,6 +345,16 @@ func Manager(state *state.State, runner *state.TaskRunner, backend deviceBackend
 	runner.AddHandler("apply-mgmt-message", m.doApplyMessage, nil)
 	runner.AddHandler("queue-mgmt-response", m.doQueueResponse, nil)
 
+	for kind, action := range snapActions {
+		m.RegisterHandler(kind, &snapHandler{action: action})
+	}
+	for kind, action := range configActions {
+		m.RegisterHandler(kind, &configHandler{action: action})
+	}
+	for kind, action := range serviceActions {
+		m.RegisterHandler(kind, &serviceHandler{action: action})
+	}
+
 	return m
 }
 
@@ -345,6 +372,7 @@ func (m *DeviceMgmtManager) getState() (*deviceMgmtState, error) {
 			return &deviceMgmtState{
 				Sequences:      make(map[string]*sequenceState),
 				ReadyResponses: make(map[string]store.Message),
+				LocalResponses: make(map[string]store.Message),
 			}, nil
 		}
 
@@ -359,6 +387,10 @@ func (m *DeviceMgmtManager) getState() (*deviceMgmtState, error) {
 		ms.ReadyResponses = map[string]store.Message{}
 	}
 
+	if ms.LocalResponses == nil {
+		ms.LocalResponses = map[string]store.Message{}
+	}
+
 	return &ms, nil
 }
 
@@ -685,8 +717,8 @@ func (m *DeviceMgmtManager) doValidateMessage(t *state.Task, _ *tomb.Tomb) error
 	// TODO: implement assumes checks (SD187, SD251). The design is somewhat in
 	// flux: SD251 "extends" messages to non-run-mode contexts (e.g. first boot,
 	// before the device has an identity), which will require assumes entries
-	// like "seeding". For now, only the confdb subsystem is supported and
-	// no specific features need to be declared.
+	// like "seeding". For now, no specific features need to be declared by
+	// the supported subsystems.
 
 	handler, ok := m.handlers[msg.Kind]
 	if !ok {
@@ -849,10 +881,16 @@ func (m *DeviceMgmtManager) doQueueResponse(t *state.Task, _ *tomb.Tomb) error {
 		return fmt.Errorf("cannot sign response message: %w", err)
 	}
 
-	ms.ReadyResponses[msg.ID()] = store.Message{
+	resMsg := store.Message{
 		Format: "assertion",
 		Data:   string(asserts.Encode(resAs)),
 	}
+	if msg.Source == messageSourceLocal {
+		// Local messages are answered through the same local channel.
+		ms.LocalResponses[msg.ID()] = resMsg
+	} else {
+		ms.ReadyResponses[msg.ID()] = resMsg
+	}
 
 	// TODO: rejecting sequences currently happens in 2 ways:
 	// 1. doDispatchMessage can evict the sequence immediately if it's rejected early.
@@ -20,9 +20,15 @@
 package devicemgmtstate
 
 import (
+	"context"
 	"time"
 
+	"github.com/snapcore/snapd/osutil/user"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/testutil"
 	"gopkg.in/tomb.v2"
@@ -106,3 +112,35 @@ func MockTimeNow(t time.Time) func() {
 
 	return testutil.Mock(&timeNow, f)
 }
+
+func (m *DeviceMgmtManager) Handler(kind string) MessageHandler {
+	return m.handlers[kind]
+}
+
+func MockSnapstateInstall(f func(ctx context.Context, st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error)) func() {
+	return testutil.Mock(&snapstateInstall, f)
+}
+
+func MockSnapstateUpdate(f func(st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error)) func() {
+	return testutil.Mock(&snapstateUpdate, f)
+}
+
+func MockSnapstateRemove(f func(st *state.State, name string, revision snap.Revision, flags *snapstate.RemoveFlags) (*state.TaskSet, error)) func() {
+	return testutil.Mock(&snapstateRemove, f)
+}
+
+func MockSnapstateRevert(f func(st *state.State, name string, flags snapstate.Flags, fromChange string) (*state.TaskSet, error)) func() {
+	return testutil.Mock(&snapstateRevert, f)
+}
+
+func MockSnapstateRevertToRevision(f func(st *state.State, name string, rev snap.Revision, flags snapstate.Flags, fromChange string) (*state.TaskSet, error)) func() {
+	return testutil.Mock(&snapstateRevertToRevision, f)
+}
+
+func MockConfigstateConfigureInstalled(f func(st *state.State, snapName string, patch map[string]any, flags int) (*state.TaskSet, error)) func() {
+	return testutil.Mock(&configstateConfigureInstalled, f)
+}
+
+func MockServicestateControl(f func(st *state.State, appInfos []*snap.AppInfo, inst *servicestate.Instruction, cu *user.User, flags *servicestate.Flags, context *hookstate.Context) ([]*state.TaskSet, error)) func() {
+	return testutil.Mock(&servicestateControl, f)
+}
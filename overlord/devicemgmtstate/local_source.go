@@ -0,0 +1,164 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/store"
+)
+
+// messageSourceLocal is the source of request messages imported locally,
+// e.g. from removable media on devices without access to the store.
+const messageSourceLocal = "local"
+
+var deviceMgmtImportChangeKind = swfeats.RegisterChangeKind("device-management-import")
+
+// ImportMessages imports a batch of request-message assertions fed locally
+// instead of through the store, along with any other assertions needed to
+// verify their signatures, like account-key assertions. The messages go
+// through the same processing as the ones from the store, and their responses
+// are kept for ExportResponses instead of being sent to the store. It returns
+// the ID of the change dispatching the imported messages.
+// The caller must hold the state lock.
+func (m *DeviceMgmtManager) ImportMessages(r io.Reader) (changeID string, err error) {
+	if !m.isRemoteDeviceManagementEnabled() {
+		return "", errors.New("cannot import messages: remote device management is disabled")
+	}
+	if _, err := snapstate.DevicePastSeeding(m.state, nil); err != nil {
+		return "", fmt.Errorf("cannot import messages: %w", err)
+	}
+
+	var reqMsgs []*RequestMessage
+	batch := asserts.NewBatch(nil)
+	hasOthers := false
+	dec := asserts.NewDecoder(r)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot decode assertions: %v", err)
+		}
+
+		if a.Type() != asserts.RequestMessageType {
+			if err := batch.Add(a); err != nil {
+				return "", fmt.Errorf("cannot import messages: %v", err)
+			}
+			hasOthers = true
+			continue
+		}
+
+		reqMsg, err := parseRequestMessage(store.Message{
+			Format: "assertion",
+			Data:   string(asserts.Encode(a)),
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot import messages: %v", err)
+		}
+		reqMsg.Source = messageSourceLocal
+		reqMsgs = append(reqMsgs, reqMsg)
+	}
+	if len(reqMsgs) == 0 {
+		return "", errors.New("cannot import messages: no request-message assertions found")
+	}
+
+	if hasOthers {
+		err := assertstate.AddBatch(m.state, batch, &asserts.CommitOptions{Precheck: true})
+		if err != nil {
+			return "", fmt.Errorf("cannot import messages: %v", err)
+		}
+	}
+
+	ms, err := m.getState()
+	if err != nil {
+		return "", err
+	}
+	for _, reqMsg := range reqMsgs {
+		ms.enqueueRequestMessage(reqMsg)
+	}
+	m.setState(ms)
+
+	chg := m.state.NewChange(deviceMgmtImportChangeKind, "Process imported device management messages")
+	dispatch := m.state.NewTask("dispatch-mgmt-messages", "Dispatch message(s) to subsystems")
+	chg.AddTask(dispatch)
+
+	m.state.EnsureBefore(0)
+
+	return chg.ID(), nil
+}
+
+// ExportResponses returns the response-message assertions for the locally
+// imported request messages processed so far, ordered by message ID. They are
+// kept until acknowledged with AcknowledgeResponses.
+// The caller must hold the state lock.
+func (m *DeviceMgmtManager) ExportResponses() ([]*asserts.ResponseMessage, error) {
+	ms, err := m.getState()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(ms.LocalResponses))
+	for id := range ms.LocalResponses {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	responses := make([]*asserts.ResponseMessage, 0, len(ids))
+	for _, id := range ids {
+		a, err := asserts.Decode([]byte(ms.LocalResponses[id].Data))
+		if err != nil {
+			return nil, fmt.Errorf("internal error: cannot decode response to message %q: %v", id, err)
+		}
+		resAs, ok := a.(*asserts.ResponseMessage)
+		if !ok {
+			return nil, fmt.Errorf("internal error: unexpected %q assertion as response to message %q", a.Type().Name, id)
+		}
+		responses = append(responses, resAs)
+	}
+
+	return responses, nil
+}
+
+// AcknowledgeResponses drops the exported responses to the given locally
+// imported request messages. Unknown message IDs are ignored, which makes
+// acknowledging idempotent.
+// The caller must hold the state lock.
+func (m *DeviceMgmtManager) AcknowledgeResponses(messageIDs []string) error {
+	ms, err := m.getState()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range messageIDs {
+		delete(ms.LocalResponses, id)
+	}
+	m.setState(ms)
+
+	return nil
+}
@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate_test
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/overlord/devicemgmtstate"
+	"github.com/snapcore/snapd/store"
+)
+
+func (s *deviceMgmtMgrSuite) mockSigningBackend(c *C) {
+	devKey, _ := assertstest.GenerateKey(752)
+	s.mgr.MockBackend(&mockDeviceBackend{
+		serial: s.makeSerial(c, "serial-1"),
+		sign: func(accountID, messageID string, status asserts.MessageStatus, body []byte) (*asserts.ResponseMessage, error) {
+			a, err := asserts.SignWithoutAuthority(asserts.ResponseMessageType, map[string]any{
+				"account-id": accountID,
+				"message-id": messageID,
+				"device":     "serial-1.my-model.my-brand",
+				"status":     string(status),
+				"timestamp":  fixedTestTime.UTC().Format(time.RFC3339),
+			}, body, devKey)
+			if err != nil {
+				return nil, err
+			}
+			return a.(*asserts.ResponseMessage), nil
+		},
+	})
+}
+
+func (s *deviceMgmtMgrSuite) decodeMessage(c *C, msg store.MessageWithToken) asserts.Assertion {
+	a, err := asserts.Decode([]byte(msg.Data))
+	c.Assert(err, IsNil)
+	return a
+}
+
+func (s *deviceMgmtMgrSuite) TestImportMessagesOK(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	var exchanged []store.Message
+	s.mockStore(func(_ context.Context, req *store.MessageExchangeRequest) (*store.MessageExchangeResponse, error) {
+		exchanged = append(exchanged, req.Messages...)
+		return &store.MessageExchangeResponse{}, nil
+	})
+	s.mockSigningBackend(c)
+
+	var buf bytes.Buffer
+	enc := asserts.NewEncoder(&buf)
+	for _, a := range []asserts.Assertion{
+		s.decodeMessage(c, s.makeStoreRequestMessage(c, "mesg-1", "test-kind", "")),
+		// other assertions are added to the database
+		s.storeStack.StoreAccountKey(""),
+		s.decodeMessage(c, s.makeStoreRequestMessage(c, "other", "test-kind", "")),
+	} {
+		c.Assert(enc.Encode(a), IsNil)
+	}
+
+	chgID, err := s.mgr.ImportMessages(&buf)
+	c.Assert(err, IsNil)
+
+	chg := s.st.Change(chgID)
+	c.Assert(chg, NotNil)
+	c.Check(chg.Kind(), Equals, "device-management-import")
+	c.Check(chg.Summary(), Equals, "Process imported device management messages")
+
+	ms, err := s.mgr.GetState()
+	c.Assert(err, IsNil)
+	c.Assert(ms.Sequences["mesg"].Messages, HasLen, 1)
+	c.Check(ms.Sequences["mesg"].Messages[0].Source, Equals, "local")
+	c.Assert(ms.Sequences["other"].Messages, HasLen, 1)
+
+	s.settle(c)
+
+	c.Check(chg.Status().Ready(), Equals, true)
+	c.Check(chg.Err(), IsNil)
+
+	ms, err = s.mgr.GetState()
+	c.Assert(err, IsNil)
+	c.Check(ms.Sequences["mesg"].Applied, Equals, 1)
+	c.Check(ms.ReadyResponses, HasLen, 0)
+	c.Check(ms.LocalResponses, HasLen, 2)
+	// responses to local messages are never sent to the store
+	c.Check(exchanged, HasLen, 0)
+
+	responses, err := s.mgr.ExportResponses()
+	c.Assert(err, IsNil)
+	c.Assert(responses, HasLen, 2)
+	c.Check(responses[0].HeaderString("message-id"), Equals, "mesg-1")
+	c.Check(responses[0].Status(), Equals, asserts.MessageStatusSuccess)
+	c.Check(string(responses[0].Body()), Equals, `{"values":"ok"}`)
+	c.Check(responses[1].HeaderString("message-id"), Equals, "other")
+
+	// exporting again yields the same until acknowledged
+	responses, err = s.mgr.ExportResponses()
+	c.Assert(err, IsNil)
+	c.Check(responses, HasLen, 2)
+
+	c.Assert(s.mgr.AcknowledgeResponses([]string{"mesg-1", "unknown"}), IsNil)
+	responses, err = s.mgr.ExportResponses()
+	c.Assert(err, IsNil)
+	c.Assert(responses, HasLen, 1)
+	c.Check(responses[0].HeaderString("message-id"), Equals, "other")
+}
+
+func (s *deviceMgmtMgrSuite) TestImportMessagesDuplicateIgnored(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	ms, err := s.mgr.GetState()
+	c.Assert(err, IsNil)
+	ms.Sequences["mesg"] = &devicemgmtstate.SequenceState{Applied: 1}
+	s.mgr.SetState(ms)
+
+	_, err = s.mgr.ImportMessages(bytes.NewBufferString(s.makeStoreRequestMessage(c, "mesg-1", "test-kind", "").Data))
+	c.Assert(err, IsNil)
+
+	ms, err = s.mgr.GetState()
+	c.Assert(err, IsNil)
+	c.Check(ms.Sequences["mesg"].Messages, HasLen, 0)
+}
+
+func (s *deviceMgmtMgrSuite) TestImportMessagesErrors(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	_, err := s.mgr.ImportMessages(bytes.NewBufferString("garbage"))
+	c.Check(err, ErrorMatches, `cannot decode assertions: .*`)
+
+	_, err = s.mgr.ImportMessages(bytes.NewReader(asserts.Encode(s.storeStack.StoreAccountKey(""))))
+	c.Check(err, ErrorMatches, `cannot import messages: no request-message assertions found`)
+
+	setRemoteMgmtFeatureFlag(c, s.st, false)
+	_, err = s.mgr.ImportMessages(bytes.NewBufferString(s.makeStoreRequestMessage(c, "mesg-1", "test-kind", "").Data))
+	c.Check(err, ErrorMatches, `cannot import messages: remote device management is disabled`)
+
+	c.Check(changesOfKind(s.st.Changes(), "device-management-import"), HasLen, 0)
+}
@@ -0,0 +1,286 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/store"
+)
+
+var deviceMgmtDropChangeKind = swfeats.RegisterChangeKind("device-management-drop")
+
+// Processing stages of a pending request message, as reported by QueueInfo.
+const (
+	// MessageQueued is for a message waiting for the next dispatch.
+	MessageQueued = "queued"
+	// MessageBlocked is for a sequenced message waiting on missing
+	// predecessors in its sequence.
+	MessageBlocked = "blocked"
+	// MessageDispatched is for a message being validated.
+	MessageDispatched = "dispatched"
+	// MessageApplying is for a message whose subsystem change is running.
+	MessageApplying = "applying"
+	// MessageResponding is for a message with a final outcome whose response
+	// is not queued yet.
+	MessageResponding = "responding"
+)
+
+// ErrNoSuchSequence is returned by DropSequence when there are no pending
+// messages for the given base ID.
+var ErrNoSuchSequence = errors.New("no pending messages for sequence")
+
+// MessageInfo describes a pending request message.
+type MessageInfo struct {
+	ID          string    `json:"id"`
+	SeqNum      int       `json:"seq-num,omitempty"`
+	AccountID   string    `json:"account-id"`
+	Kind        string    `json:"kind"`
+	Source      string    `json:"source,omitempty"`
+	ReceiveTime time.Time `json:"receive-time"`
+	ValidUntil  time.Time `json:"valid-until"`
+
+	// Stage is where the message is in its processing.
+	Stage string `json:"stage"`
+	// ChangeID is the change processing the message, if dispatched.
+	ChangeID string `json:"change-id,omitempty"`
+	// ApplyChangeID is the subsystem change applying the message, if any.
+	ApplyChangeID string `json:"apply-change-id,omitempty"`
+	// Status and Reason hold the outcome of the message, if known, like
+	// why it was rejected.
+	Status asserts.MessageStatus `json:"status,omitempty"`
+	Reason string                `json:"reason,omitempty"`
+}
+
+// SequenceInfo describes the pending messages sharing a base ID.
+type SequenceInfo struct {
+	BaseID string `json:"base-id"`
+	// Applied is the highest sequence number applied so far.
+	Applied int `json:"applied,omitempty"`
+	// Blocked is set when the sequence is stuck at missing messages.
+	Blocked  bool          `json:"blocked,omitempty"`
+	Messages []MessageInfo `json:"messages"`
+}
+
+// ResponseInfo describes a response-message queued for delivery.
+type ResponseInfo struct {
+	MessageID string                `json:"message-id"`
+	Status    asserts.MessageStatus `json:"status"`
+	Reason    string                `json:"reason,omitempty"`
+	// Source is where the response goes, empty for the store and "local"
+	// for responses waiting to be exported.
+	Source string `json:"source,omitempty"`
+}
+
+// QueueInfo is a snapshot of the device management message queue.
+type QueueInfo struct {
+	Sequences        []SequenceInfo `json:"sequences"`
+	Responses        []ResponseInfo `json:"responses"`
+	LastExchangeTime time.Time      `json:"last-exchange-time,omitzero"`
+}
+
+// messageChanges maps the ID of each dispatched message to the change
+// processing it, preferring changes still in progress.
+func messageChanges(st *state.State) map[string]*state.Change {
+	changes := make(map[string]*state.Change)
+	for _, t := range st.Tasks() {
+		var msgID string
+		if err := t.Get("message-id", &msgID); err != nil {
+			continue
+		}
+		chg := t.Change()
+		if chg == nil {
+			continue
+		}
+		if prev := changes[msgID]; prev != nil && !prev.Status().Ready() {
+			continue
+		}
+		changes[msgID] = chg
+	}
+	return changes
+}
+
+// responseReason returns the message found in a response body, if any.
+func responseReason(body map[string]any) string {
+	reason, _ := body["message"].(string)
+	return reason
+}
+
+func sequenceInfo(baseID string, seq *sequenceState, changes map[string]*state.Change) SequenceInfo {
+	info := SequenceInfo{
+		BaseID:   baseID,
+		Applied:  seq.Applied,
+		Messages: make([]MessageInfo, 0, len(seq.Messages)),
+	}
+
+	expectedSeqNum := seq.Applied + 1
+	for _, msg := range seq.Messages {
+		msgInfo := MessageInfo{
+			ID:            msg.ID(),
+			SeqNum:        msg.SeqNum,
+			AccountID:     msg.AccountID,
+			Kind:          msg.Kind,
+			Source:        msg.Source,
+			ReceiveTime:   msg.ReceiveTime,
+			ValidUntil:    msg.ValidUntil,
+			ApplyChangeID: msg.ApplyChangeID,
+			Status:        msg.ResponseStatus,
+			Reason:        responseReason(msg.ResponseBody),
+		}
+		if chg := changes[msg.ID()]; chg != nil {
+			msgInfo.ChangeID = chg.ID()
+		}
+
+		if msg.SeqNum > 0 {
+			if msg.SeqNum != expectedSeqNum {
+				// everything from the first gap on waits on it
+				info.Blocked = true
+			}
+			expectedSeqNum = msg.SeqNum + 1
+		}
+
+		switch {
+		case msg.ResponseStatus != "":
+			msgInfo.Stage = MessageResponding
+		case msg.ApplyChangeID != "":
+			msgInfo.Stage = MessageApplying
+		case msg.Dispatched:
+			msgInfo.Stage = MessageDispatched
+		case info.Blocked:
+			msgInfo.Stage = MessageBlocked
+		default:
+			msgInfo.Stage = MessageQueued
+		}
+
+		info.Messages = append(info.Messages, msgInfo)
+	}
+
+	return info
+}
+
+func responseInfos(responses map[string]store.Message, source string) ([]ResponseInfo, error) {
+	infos := make([]ResponseInfo, 0, len(responses))
+	for id, resMsg := range responses {
+		a, err := asserts.Decode([]byte(resMsg.Data))
+		if err != nil {
+			return nil, fmt.Errorf("internal error: cannot decode response to message %q: %v", id, err)
+		}
+		resAs, ok := a.(*asserts.ResponseMessage)
+		if !ok {
+			return nil, fmt.Errorf("internal error: unexpected %q assertion as response to message %q", a.Type().Name, id)
+		}
+
+		info := ResponseInfo{
+			MessageID: id,
+			Status:    resAs.Status(),
+			Source:    source,
+		}
+		var body map[string]any
+		if err := json.Unmarshal(resAs.Body(), &body); err == nil {
+			info.Reason = responseReason(body)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// QueueInfo returns a snapshot of the pending request messages, grouped by
+// sequence and ordered by base ID, and of the responses waiting to be sent to
+// the store or exported.
+// The caller must hold the state lock.
+func (m *DeviceMgmtManager) QueueInfo() (*QueueInfo, error) {
+	ms, err := m.getState()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := messageChanges(m.state)
+	info := &QueueInfo{
+		Sequences:        make([]SequenceInfo, 0, len(ms.Sequences)),
+		LastExchangeTime: ms.LastExchangeTime,
+	}
+	for baseID, seq := range ms.Sequences {
+		if len(seq.Messages) == 0 {
+			// only kept to remember the sequence progress
+			continue
+		}
+		info.Sequences = append(info.Sequences, sequenceInfo(baseID, seq, changes))
+	}
+	sort.Slice(info.Sequences, func(i, j int) bool {
+		return info.Sequences[i].BaseID < info.Sequences[j].BaseID
+	})
+
+	storeResponses, err := responseInfos(ms.ReadyResponses, "")
+	if err != nil {
+		return nil, err
+	}
+	localResponses, err := responseInfos(ms.LocalResponses, messageSourceLocal)
+	if err != nil {
+		return nil, err
+	}
+	info.Responses = append(storeResponses, localResponses...)
+	sort.Slice(info.Responses, func(i, j int) bool {
+		return info.Responses[i].MessageID < info.Responses[j].MessageID
+	})
+
+	return info, nil
+}
+
+// DropSequence drops the pending messages sharing the given base ID, for
+// instance a sequence stuck at missing messages. As when evicting sequences,
+// the earliest message is rejected so that its sender learns about it and the
+// others are discarded. Messages being processed cannot be dropped. It returns
+// the ID of the change queueing the rejection.
+// The caller must hold the state lock.
+func (m *DeviceMgmtManager) DropSequence(baseID string) (changeID string, err error) {
+	ms, err := m.getState()
+	if err != nil {
+		return "", err
+	}
+
+	seq := ms.Sequences[baseID]
+	if seq == nil || len(seq.Messages) == 0 {
+		return "", fmt.Errorf("cannot drop sequence %q: %w", baseID, ErrNoSuchSequence)
+	}
+
+	changes := messageChanges(m.state)
+	for _, msg := range seq.Messages {
+		if chg := changes[msg.ID()]; chg != nil && !chg.Status().Ready() {
+			return "", fmt.Errorf("cannot drop sequence %q: message %q is being processed in change %s", baseID, msg.ID(), chg.ID())
+		}
+	}
+
+	chg := m.state.NewChange(deviceMgmtDropChangeKind, fmt.Sprintf("Drop device management sequence %q", baseID))
+	if err := m.rejectSequence(ms, chg, baseID, "cannot process message: sequence dropped by the device administrator"); err != nil {
+		return "", err
+	}
+	m.setState(ms)
+
+	m.state.EnsureBefore(0)
+
+	return chg.ID(), nil
+}
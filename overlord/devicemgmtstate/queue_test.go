@@ -0,0 +1,226 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate_test
+
+import (
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/overlord/devicemgmtstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/store"
+)
+
+func (s *deviceMgmtMgrSuite) mockQueue(c *C) {
+	ms, err := s.mgr.GetState()
+	c.Assert(err, IsNil)
+
+	ms.Sequences["stuck"] = &devicemgmtstate.SequenceState{
+		Applied: 1,
+		Messages: []*devicemgmtstate.RequestMessage{
+			{BaseID: "stuck", SeqNum: 3, AccountID: "my-brand", Kind: "test-kind", ReceiveTime: fixedTestTime},
+			{BaseID: "stuck", SeqNum: 4, AccountID: "my-brand", Kind: "test-kind", ReceiveTime: fixedTestTime},
+		},
+	}
+	ms.Sequences["seq"] = &devicemgmtstate.SequenceState{
+		Messages: []*devicemgmtstate.RequestMessage{
+			{BaseID: "seq", SeqNum: 1, AccountID: "my-brand", Kind: "test-kind", Dispatched: true, ApplyChangeID: "42"},
+			{BaseID: "seq", SeqNum: 2, AccountID: "my-brand", Kind: "test-kind", Dispatched: true},
+			{BaseID: "seq", SeqNum: 3, AccountID: "my-brand", Kind: "test-kind"},
+		},
+	}
+	ms.Sequences["single"] = &devicemgmtstate.SequenceState{
+		Messages: []*devicemgmtstate.RequestMessage{{
+			BaseID:         "single",
+			AccountID:      "my-brand",
+			Kind:           "test-kind",
+			Source:         "local",
+			Dispatched:     true,
+			ResponseStatus: asserts.MessageStatusRejected,
+			ResponseBody:   map[string]any{"message": "unauthorized"},
+		}},
+	}
+	// progress of a sequence without pending messages
+	ms.Sequences["applied"] = &devicemgmtstate.SequenceState{Applied: 5}
+	s.mgr.SetState(ms)
+
+	chg := s.st.NewChange("device-management-exchange", "...")
+	for _, msgID := range []string{"seq-1", "seq-2"} {
+		t := s.st.NewTask("validate-mgmt-message", "...")
+		t.Set("message-id", msgID)
+		chg.AddTask(t)
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestQueueInfo(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.mockQueue(c)
+
+	devKey, _ := assertstest.GenerateKey(752)
+	ms, err := s.mgr.GetState()
+	c.Assert(err, IsNil)
+	ms.LastExchangeTime = fixedTestTime
+	for _, res := range []struct {
+		id     string
+		status asserts.MessageStatus
+		body   string
+		local  bool
+	}{
+		{"mesg-1", asserts.MessageStatusSuccess, `{"values":"ok"}`, false},
+		{"local", asserts.MessageStatusError, `{"message":"cannot apply"}`, true},
+	} {
+		resAs, err := asserts.SignWithoutAuthority(asserts.ResponseMessageType, map[string]any{
+			"account-id": "my-brand",
+			"message-id": res.id,
+			"device":     "serial-1.my-model.my-brand",
+			"status":     string(res.status),
+			"timestamp":  fixedTestTime.UTC().Format(time.RFC3339),
+		}, []byte(res.body), devKey)
+		c.Assert(err, IsNil)
+		resMsg := store.Message{Format: "assertion", Data: string(asserts.Encode(resAs))}
+		if res.local {
+			ms.LocalResponses[res.id] = resMsg
+		} else {
+			ms.ReadyResponses[res.id] = resMsg
+		}
+	}
+	s.mgr.SetState(ms)
+
+	info, err := s.mgr.QueueInfo()
+	c.Assert(err, IsNil)
+
+	chgID := changesOfKind(s.st.Changes(), "device-management-exchange")[0].ID()
+	c.Check(info.LastExchangeTime.Equal(fixedTestTime), Equals, true)
+	c.Check(info.Sequences, DeepEquals, []devicemgmtstate.SequenceInfo{{
+		BaseID: "seq",
+		Messages: []devicemgmtstate.MessageInfo{
+			{ID: "seq-1", SeqNum: 1, AccountID: "my-brand", Kind: "test-kind", Stage: "applying", ChangeID: chgID, ApplyChangeID: "42"},
+			{ID: "seq-2", SeqNum: 2, AccountID: "my-brand", Kind: "test-kind", Stage: "dispatched", ChangeID: chgID},
+			{ID: "seq-3", SeqNum: 3, AccountID: "my-brand", Kind: "test-kind", Stage: "queued"},
+		},
+	}, {
+		BaseID: "single",
+		Messages: []devicemgmtstate.MessageInfo{
+			{ID: "single", AccountID: "my-brand", Kind: "test-kind", Source: "local", Stage: "responding", Status: asserts.MessageStatusRejected, Reason: "unauthorized"},
+		},
+	}, {
+		BaseID:  "stuck",
+		Applied: 1,
+		Blocked: true,
+		Messages: []devicemgmtstate.MessageInfo{
+			{ID: "stuck-3", SeqNum: 3, AccountID: "my-brand", Kind: "test-kind", ReceiveTime: fixedTestTime, Stage: "blocked"},
+			{ID: "stuck-4", SeqNum: 4, AccountID: "my-brand", Kind: "test-kind", ReceiveTime: fixedTestTime, Stage: "blocked"},
+		},
+	}})
+	c.Check(info.Responses, DeepEquals, []devicemgmtstate.ResponseInfo{
+		{MessageID: "local", Status: asserts.MessageStatusError, Reason: "cannot apply", Source: "local"},
+		{MessageID: "mesg-1", Status: asserts.MessageStatusSuccess},
+	})
+}
+
+func (s *deviceMgmtMgrSuite) TestQueueInfoEmpty(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	info, err := s.mgr.QueueInfo()
+	c.Assert(err, IsNil)
+	c.Check(info.Sequences, HasLen, 0)
+	c.Check(info.Responses, HasLen, 0)
+}
+
+func (s *deviceMgmtMgrSuite) TestDropSequence(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.mockSigningBackend(c)
+	s.mockQueue(c)
+	ms, err := s.mgr.GetState()
+	c.Assert(err, IsNil)
+	ms.SequenceLRU = []string{"seq", "stuck"}
+	// no exchange sending the responses away
+	ms.LastExchangeTime = fixedTestTime
+	s.mgr.SetState(ms)
+	// the processing of the other sequence is over
+	for _, t := range s.st.Tasks() {
+		t.SetStatus(state.DoneStatus)
+	}
+
+	chgID, err := s.mgr.DropSequence("stuck")
+	c.Assert(err, IsNil)
+
+	chg := s.st.Change(chgID)
+	c.Assert(chg, NotNil)
+	c.Check(chg.Kind(), Equals, "device-management-drop")
+	c.Check(chg.Summary(), Equals, `Drop device management sequence "stuck"`)
+	c.Assert(chg.Tasks(), HasLen, 1)
+	c.Check(chg.Tasks()[0].Kind(), Equals, "queue-mgmt-response")
+
+	ms, err = s.mgr.GetState()
+	c.Assert(err, IsNil)
+	c.Check(ms.SequenceLRU, DeepEquals, []string{"seq"})
+	// only the earliest message is kept to be rejected
+	seq := ms.Sequences["stuck"]
+	c.Assert(seq.Messages, HasLen, 1)
+	c.Check(seq.Messages[0].SeqNum, Equals, 3)
+	c.Check(seq.Messages[0].ResponseStatus, Equals, asserts.MessageStatusRejected)
+
+	s.settle(c)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+
+	ms, err = s.mgr.GetState()
+	c.Assert(err, IsNil)
+	c.Check(ms.Sequences["stuck"].Messages, HasLen, 0)
+	c.Check(ms.Sequences["stuck"].Applied, Equals, 1)
+	c.Assert(ms.ReadyResponses["stuck-3"], NotNil)
+
+	info, err := s.mgr.QueueInfo()
+	c.Assert(err, IsNil)
+	c.Check(info.Responses, DeepEquals, []devicemgmtstate.ResponseInfo{{
+		MessageID: "stuck-3",
+		Status:    asserts.MessageStatusRejected,
+		Reason:    "cannot process message: sequence dropped by the device administrator",
+	}})
+}
+
+func (s *deviceMgmtMgrSuite) TestDropSequenceErrors(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.mockQueue(c)
+
+	_, err := s.mgr.DropSequence("unknown")
+	c.Check(err, ErrorMatches, `cannot drop sequence "unknown": no pending messages for sequence`)
+	c.Check(errors.Is(err, devicemgmtstate.ErrNoSuchSequence), Equals, true)
+
+	_, err = s.mgr.DropSequence("applied")
+	c.Check(errors.Is(err, devicemgmtstate.ErrNoSuchSequence), Equals, true)
+
+	chgID := changesOfKind(s.st.Changes(), "device-management-exchange")[0].ID()
+	_, err = s.mgr.DropSequence("seq")
+	c.Check(err, ErrorMatches, `cannot drop sequence "seq": message "seq-1" is being processed in change `+chgID)
+
+	c.Check(changesOfKind(s.st.Changes(), "device-management-drop"), HasLen, 0)
+}
@@ -0,0 +1,216 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var servicestateControl = servicestate.Control
+
+var serviceControlChangeKind = swfeats.RegisterChangeKind("service-control")
+
+// serviceActions maps the message kinds of the built-in service control
+// handlers to the action they perform.
+var serviceActions = map[string]string{
+	"snap-service-start":   "start",
+	"snap-service-stop":    "stop",
+	"snap-service-restart": "restart",
+}
+
+// serviceMessageBody is the body of the request-messages controlling the
+// services of snaps.
+type serviceMessageBody struct {
+	// Services lists the services to act on, either as <snap> for all the
+	// services of a snap or as <snap>.<app> for a single one.
+	Services []string `json:"services"`
+	// Enable the services as well as starting them.
+	Enable bool `json:"enable,omitempty"`
+	// Disable the services as well as stopping them.
+	Disable bool `json:"disable,omitempty"`
+	// Reload the services instead of restarting them, where supported.
+	Reload bool `json:"reload,omitempty"`
+}
+
+// serviceHandler is a MessageHandler turning request-messages into changes
+// starting, stopping or restarting the system services of snaps. Only the
+// brand of the device model is allowed to control the services of the device.
+type serviceHandler struct {
+	action string
+}
+
+func (h *serviceHandler) parseBody(msg *RequestMessage) (*serviceMessageBody, error) {
+	var body serviceMessageBody
+	if err := json.Unmarshal([]byte(msg.Body), &body); err != nil {
+		return nil, fmt.Errorf("cannot decode %s message body: %v", msg.Kind, err)
+	}
+	if len(body.Services) == 0 {
+		return nil, fmt.Errorf("cannot %s services: no services provided", h.action)
+	}
+	for _, name := range body.Services {
+		snapName, appName := snap.SplitSnapApp(name)
+		if err := naming.ValidateInstance(snapName); err != nil {
+			return nil, fmt.Errorf("cannot %s services: %v", h.action, err)
+		}
+		if appName != "" && appName != snapName {
+			if err := naming.ValidateApp(appName); err != nil {
+				return nil, fmt.Errorf("cannot %s services: %v", h.action, err)
+			}
+		}
+	}
+
+	switch {
+	case body.Enable && h.action != "start":
+		return nil, fmt.Errorf("cannot %s services: enable is not supported", h.action)
+	case body.Disable && h.action != "stop":
+		return nil, fmt.Errorf("cannot %s services: disable is not supported", h.action)
+	case body.Reload && h.action != "restart":
+		return nil, fmt.Errorf("cannot %s services: reload is not supported", h.action)
+	}
+	return &body, nil
+}
+
+// serviceAppInfos returns the services referred to by the given names, which
+// are either snap names or <snap>.<app>.
+func serviceAppInfos(st *state.State, names []string) ([]*snap.AppInfo, error) {
+	infos := make(map[string]*snap.Info)
+	var appInfos []*snap.AppInfo
+	seen := make(map[string]bool)
+	for _, name := range names {
+		snapName, appName := snap.SplitSnapApp(name)
+		info := infos[snapName]
+		if info == nil {
+			var err error
+			info, err = snapstate.CurrentInfo(st, snapName)
+			if err != nil {
+				return nil, err
+			}
+			infos[snapName] = info
+		}
+
+		var apps []*snap.AppInfo
+		if name == snapName {
+			apps = info.Services()
+			if len(apps) == 0 {
+				return nil, fmt.Errorf("snap %q has no services", snapName)
+			}
+		} else {
+			app := info.Apps[appName]
+			if app == nil || !app.IsService() {
+				return nil, fmt.Errorf("snap %q has no service %q", snapName, appName)
+			}
+			apps = []*snap.AppInfo{app}
+		}
+		for _, app := range apps {
+			if !seen[app.String()] {
+				seen[app.String()] = true
+				appInfos = append(appInfos, app)
+			}
+		}
+	}
+	sort.Sort(snap.AppInfoBySnapApp(appInfos))
+	return appInfos, nil
+}
+
+// Validate checks that the message comes from the brand of the device model
+// and that its body describes a valid service operation.
+func (h *serviceHandler) Validate(st *state.State, msg *RequestMessage) error {
+	if err := checkBrandOperator(st, msg); err != nil {
+		return err
+	}
+
+	_, err := h.parseBody(msg)
+	return err
+}
+
+// Apply creates the change performing the service operation.
+func (h *serviceHandler) Apply(st *state.State, msg *RequestMessage) (changeID string, err error) {
+	body, err := h.parseBody(msg)
+	if err != nil {
+		return "", err
+	}
+
+	appInfos, err := serviceAppInfos(st, body.Services)
+	if err != nil {
+		return "", fmt.Errorf("cannot %s services: %v", h.action, err)
+	}
+
+	// the device is managed as a whole, only the system services are
+	// controlled
+	inst := &servicestate.Instruction{
+		Action:         h.action,
+		Names:          body.Services,
+		Scope:          client.ScopeSelector{"system"},
+		StartOptions:   client.StartOptions{Enable: body.Enable},
+		StopOptions:    client.StopOptions{Disable: body.Disable},
+		RestartOptions: client.RestartOptions{Reload: body.Reload},
+	}
+	tss, err := servicestateControl(st, appInfos, inst, nil, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var snapNames []string
+	for _, app := range appInfos {
+		if !strutil.ListContains(snapNames, app.Snap.InstanceName()) {
+			snapNames = append(snapNames, app.Snap.InstanceName())
+		}
+	}
+
+	chg := st.NewChange(serviceControlChangeKind, fmt.Sprintf("Run service command %q for %s", h.action, strutil.Quoted(body.Services)))
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+	chg.Set("snap-names", snapNames)
+	chg.Set("services", body.Services)
+	MarkChangeForMessage(chg, msg)
+	st.EnsureBefore(0)
+
+	return chg.ID(), nil
+}
+
+// ResultFromChange reports the outcome of the service operation.
+func (h *serviceHandler) ResultFromChange(chg *state.Change) (body map[string]any, err error) {
+	var services []string
+	if err := chg.Get("services", &services); err != nil {
+		return nil, fmt.Errorf("internal error: cannot get services of change %s: %v", chg.ID(), err)
+	}
+
+	if chg.Status() != state.DoneStatus {
+		return nil, changeError(chg, fmt.Sprintf("%s services %s", h.action, strutil.Quoted(services)))
+	}
+
+	return map[string]any{
+		"change-id": chg.ID(),
+		"status":    chg.Status().String(),
+		"services":  services,
+	}, nil
+}
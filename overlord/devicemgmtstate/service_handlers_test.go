@@ -0,0 +1,236 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/osutil/user"
+	"github.com/snapcore/snapd/overlord/devicemgmtstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+const servicesSnapYaml = `name: foo
+version: 1
+apps:
+  svc1:
+    daemon: simple
+  svc2:
+    daemon: simple
+  cmd:
+    command: bin/cmd
+`
+
+func (s *deviceMgmtMgrSuite) mockServicesSnap(c *C) {
+	si := &snap.SideInfo{RealName: "foo", Revision: snap.R(1)}
+	snaptest.MockSnap(c, servicesSnapYaml, si)
+	snapstate.Set(s.st, "foo", &snapstate.SnapState{
+		Active:   true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{si}),
+		Current:  si.Revision,
+		SnapType: "app",
+	})
+}
+
+func appNames(appInfos []*snap.AppInfo) []string {
+	names := make([]string, 0, len(appInfos))
+	for _, app := range appInfos {
+		names = append(names, app.String())
+	}
+	return names
+}
+
+func (s *deviceMgmtMgrSuite) TestServiceHandlersRegistered(c *C) {
+	for _, kind := range []string{"snap-service-start", "snap-service-stop", "snap-service-restart"} {
+		c.Check(s.mgr.Handler(kind), NotNil, Commentf("kind %q", kind))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestServiceHandlerValidateOK(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	for _, tc := range []struct {
+		kind string
+		body string
+	}{
+		{"snap-service-start", `{"services": ["foo"], "enable": true}`},
+		{"snap-service-stop", `{"services": ["foo.svc1", "bar_x"], "disable": true}`},
+		{"snap-service-restart", `{"services": ["foo"], "reload": true}`},
+	} {
+		msg := s.makeSnapRequestMessage(tc.kind, tc.body)
+		err := s.mgr.Handler(tc.kind).Validate(s.st, msg)
+		c.Check(err, IsNil, Commentf("%s: %s", tc.kind, tc.body))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestServiceHandlerValidateErrors(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	for _, tc := range []struct {
+		kind   string
+		body   string
+		errMsg string
+	}{
+		{"snap-service-start", `not json`, `cannot decode snap-service-start message body: .*`},
+		{"snap-service-start", `{}`, `cannot start services: no services provided`},
+		{"snap-service-start", `{"services": ["Foo!"]}`, `cannot start services: invalid snap name: "Foo!"`},
+		{"snap-service-start", `{"services": ["foo.svc!"]}`, `cannot start services: invalid app name: "svc!"`},
+		{"snap-service-start", `{"services": ["foo"], "disable": true}`, `cannot start services: disable is not supported`},
+		{"snap-service-stop", `{"services": ["foo"], "reload": true}`, `cannot stop services: reload is not supported`},
+		{"snap-service-restart", `{"services": ["foo"], "enable": true}`, `cannot restart services: enable is not supported`},
+	} {
+		msg := s.makeSnapRequestMessage(tc.kind, tc.body)
+		err := s.mgr.Handler(tc.kind).Validate(s.st, msg)
+		c.Check(err, ErrorMatches, tc.errMsg, Commentf("%s: %s", tc.kind, tc.body))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestServiceHandlerValidateUnauthorized(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	msg := s.makeSnapRequestMessage("snap-service-stop", `{"services": ["foo"]}`)
+	msg.AccountID = "other-brand"
+
+	err := s.mgr.Handler("snap-service-stop").Validate(s.st, msg)
+	var unauthorized *devicemgmtstate.UnauthorizedError
+	c.Check(errors.As(err, &unauthorized), Equals, true)
+}
+
+func (s *deviceMgmtMgrSuite) TestServiceHandlerApplyWholeSnap(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.mockServicesSnap(c)
+
+	var calls int
+	restore := devicemgmtstate.MockServicestateControl(func(st *state.State, appInfos []*snap.AppInfo, inst *servicestate.Instruction, cu *user.User, flags *servicestate.Flags, context *hookstate.Context) ([]*state.TaskSet, error) {
+		calls++
+		c.Check(appNames(appInfos), DeepEquals, []string{"foo.svc1", "foo.svc2"})
+		c.Check(inst, DeepEquals, &servicestate.Instruction{
+			Action:       "start",
+			Names:        []string{"foo"},
+			Scope:        client.ScopeSelector{"system"},
+			StartOptions: client.StartOptions{Enable: true},
+		})
+		c.Check(cu, IsNil)
+		c.Check(flags, IsNil)
+		c.Check(context, IsNil)
+		return []*state.TaskSet{s.mockTaskSet(st, "start services")}, nil
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-service-start", `{"services": ["foo"], "enable": true}`)
+	chgID, err := s.mgr.Handler("snap-service-start").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+	c.Check(calls, Equals, 1)
+
+	chg := s.checkSnapChange(c, chgID, "service-control", `Run service command "start" for "foo"`, "foo", msg)
+	c.Check(chg.Tasks(), HasLen, 1)
+	var services []string
+	c.Assert(chg.Get("services", &services), IsNil)
+	c.Check(services, DeepEquals, []string{"foo"})
+}
+
+func (s *deviceMgmtMgrSuite) TestServiceHandlerApplySingleService(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.mockServicesSnap(c)
+
+	restore := devicemgmtstate.MockServicestateControl(func(st *state.State, appInfos []*snap.AppInfo, inst *servicestate.Instruction, cu *user.User, flags *servicestate.Flags, context *hookstate.Context) ([]*state.TaskSet, error) {
+		c.Check(appNames(appInfos), DeepEquals, []string{"foo.svc2"})
+		c.Check(inst.Action, Equals, "restart")
+		c.Check(inst.Reload, Equals, true)
+		return []*state.TaskSet{s.mockTaskSet(st, "restart services")}, nil
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-service-restart", `{"services": ["foo.svc2"], "reload": true}`)
+	chgID, err := s.mgr.Handler("snap-service-restart").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+
+	s.checkSnapChange(c, chgID, "service-control", `Run service command "restart" for "foo.svc2"`, "foo", msg)
+}
+
+func (s *deviceMgmtMgrSuite) TestServiceHandlerApplyErrors(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	s.mockServicesSnap(c)
+
+	restore := devicemgmtstate.MockServicestateControl(func(st *state.State, appInfos []*snap.AppInfo, inst *servicestate.Instruction, cu *user.User, flags *servicestate.Flags, context *hookstate.Context) ([]*state.TaskSet, error) {
+		return nil, errors.New("conflict")
+	})
+	defer restore()
+
+	for _, tc := range []struct {
+		body   string
+		errMsg string
+	}{
+		{`{"services": ["bar"]}`, `cannot stop services: snap "bar" is not installed`},
+		{`{"services": ["foo.cmd"]}`, `cannot stop services: snap "foo" has no service "cmd"`},
+		{`{"services": ["foo.other"]}`, `cannot stop services: snap "foo" has no service "other"`},
+		{`{"services": ["foo"]}`, `conflict`},
+	} {
+		msg := s.makeSnapRequestMessage("snap-service-stop", tc.body)
+		_, err := s.mgr.Handler("snap-service-stop").Apply(s.st, msg)
+		c.Check(err, ErrorMatches, tc.errMsg, Commentf(tc.body))
+	}
+	c.Check(s.st.Changes(), HasLen, 0)
+}
+
+func (s *deviceMgmtMgrSuite) TestServiceHandlerResultFromChange(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	chg := s.st.NewChange("service-control", "...")
+	chg.Set("services", []string{"foo.svc1"})
+	chg.SetStatus(state.DoneStatus)
+
+	body, err := s.mgr.Handler("snap-service-stop").ResultFromChange(chg)
+	c.Assert(err, IsNil)
+	c.Check(body, DeepEquals, map[string]any{
+		"change-id": chg.ID(),
+		"status":    "Done",
+		"services":  []string{"foo.svc1"},
+	})
+
+	chg = s.st.NewChange("service-control", "...")
+	chg.Set("services", []string{"foo"})
+	t := s.st.NewTask("service-control", "...")
+	t.Errorf("systemctl failed")
+	t.SetStatus(state.ErrorStatus)
+	chg.AddTask(t)
+
+	_, err = s.mgr.Handler("snap-service-start").ResultFromChange(chg)
+	c.Assert(err, ErrorMatches, `(?s)cannot start services "foo" \(change [0-9]+ is Error\): .*systemctl failed.*`)
+}
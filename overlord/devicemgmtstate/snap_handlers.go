@@ -0,0 +1,228 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/store"
+)
+
+var (
+	snapstateInstall          = snapstate.Install
+	snapstateUpdate           = snapstate.Update
+	snapstateRemove           = snapstate.Remove
+	snapstateRevert           = snapstate.Revert
+	snapstateRevertToRevision = snapstate.RevertToRevision
+)
+
+var (
+	installSnapChangeKind = swfeats.RegisterChangeKind("install-snap")
+	refreshSnapChangeKind = swfeats.RegisterChangeKind("refresh-snap")
+	removeSnapChangeKind  = swfeats.RegisterChangeKind("remove-snap")
+	revertSnapChangeKind  = swfeats.RegisterChangeKind("revert-snap")
+)
+
+// snapActions maps the message kinds of the built-in snap handlers to the
+// action they perform.
+var snapActions = map[string]string{
+	"snap-install": "install",
+	"snap-refresh": "refresh",
+	"snap-remove":  "remove",
+	"snap-revert":  "revert",
+}
+
+// snapMessageBody is the body of the request-messages acting on snaps.
+type snapMessageBody struct {
+	// Snap is the instance name of the snap to act on.
+	Snap string `json:"snap"`
+	// Channel is the channel to install or refresh the snap from.
+	Channel string `json:"channel,omitempty"`
+	// Revision is the revision to install, refresh or revert the snap to.
+	Revision snap.Revision `json:"revision,omitempty"`
+	// Purge removes the snap without saving a snapshot of its data.
+	Purge bool `json:"purge,omitempty"`
+}
+
+// snapHandler is a MessageHandler turning request-messages into snap
+// install, refresh, remove or revert changes. Only the brand of the device
+// model is allowed to act on the snaps of the device.
+type snapHandler struct {
+	action string
+}
+
+func (h *snapHandler) parseBody(msg *RequestMessage) (*snapMessageBody, error) {
+	var body snapMessageBody
+	if err := json.Unmarshal([]byte(msg.Body), &body); err != nil {
+		return nil, fmt.Errorf("cannot decode %s message body: %v", msg.Kind, err)
+	}
+	if body.Snap == "" {
+		return nil, fmt.Errorf("cannot %s snap: snap name not provided", h.action)
+	}
+	if err := naming.ValidateInstance(body.Snap); err != nil {
+		return nil, fmt.Errorf("cannot %s snap: %v", h.action, err)
+	}
+
+	switch {
+	case body.Channel != "" && h.action != "install" && h.action != "refresh":
+		return nil, fmt.Errorf("cannot %s snap %q: channel is not supported", h.action, body.Snap)
+	case !body.Revision.Unset() && h.action == "remove":
+		return nil, fmt.Errorf("cannot %s snap %q: revision is not supported", h.action, body.Snap)
+	case body.Revision.Local() && h.action != "revert":
+		return nil, fmt.Errorf("cannot %s snap %q: local revision %s is not supported", h.action, body.Snap, body.Revision)
+	case body.Purge && h.action != "remove":
+		return nil, fmt.Errorf("cannot %s snap %q: purge is not supported", h.action, body.Snap)
+	}
+	return &body, nil
+}
+
+// checkBrandOperator checks that the message comes from the brand of the
+// device model, the only operator allowed to act on the snaps of the device.
+func checkBrandOperator(st *state.State, msg *RequestMessage) error {
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		return err
+	}
+	if msg.AccountID != deviceCtx.Model().BrandID() {
+		return &UnauthorizedError{Operator: msg.AccountID}
+	}
+	return nil
+}
+
+// changeError returns the error reported for a change that did not complete
+// successfully, what describes the failed operation.
+func changeError(chg *state.Change, what string) error {
+	status := chg.Status()
+	if chgErr := chg.Err(); chgErr != nil {
+		return fmt.Errorf("cannot %s (change %s is %s): %v", what, chg.ID(), status, chgErr)
+	}
+	return fmt.Errorf("cannot %s: change %s is %s", what, chg.ID(), status)
+}
+
+// Validate checks that the message comes from the brand of the device model
+// and that its body describes a valid snap operation.
+func (h *snapHandler) Validate(st *state.State, msg *RequestMessage) error {
+	if err := checkBrandOperator(st, msg); err != nil {
+		return err
+	}
+
+	_, err := h.parseBody(msg)
+	return err
+}
+
+// Apply creates the change performing the snap operation.
+func (h *snapHandler) Apply(st *state.State, msg *RequestMessage) (changeID string, err error) {
+	body, err := h.parseBody(msg)
+	if err != nil {
+		return "", err
+	}
+
+	var ts *state.TaskSet
+	var kind, summary string
+	revOpts := &snapstate.RevisionOptions{
+		Channel:  body.Channel,
+		Revision: body.Revision,
+	}
+	switch h.action {
+	case "install":
+		kind = installSnapChangeKind
+		summary = fmt.Sprintf("Install %q snap", body.Snap)
+		ts, err = snapstateInstall(context.Background(), st, body.Snap, revOpts, 0, snapstate.Flags{})
+	case "refresh":
+		kind = refreshSnapChangeKind
+		summary = fmt.Sprintf("Refresh %q snap", body.Snap)
+		ts, err = snapstateUpdate(st, body.Snap, revOpts, 0, snapstate.Flags{})
+		if errors.Is(err, store.ErrNoUpdateAvailable) {
+			// already up to date, which is what was asked for
+			err = nil
+		}
+	case "remove":
+		kind = removeSnapChangeKind
+		summary = fmt.Sprintf("Remove %q snap", body.Snap)
+		ts, err = snapstateRemove(st, body.Snap, snap.R(0), &snapstate.RemoveFlags{Purge: body.Purge})
+	case "revert":
+		kind = revertSnapChangeKind
+		summary = fmt.Sprintf("Revert %q snap", body.Snap)
+		if body.Revision.Unset() {
+			ts, err = snapstateRevert(st, body.Snap, snapstate.Flags{}, "")
+		} else {
+			ts, err = snapstateRevertToRevision(st, body.Snap, body.Revision, snapstate.Flags{}, "")
+		}
+	default:
+		return "", fmt.Errorf("internal error: unknown snap action %q", h.action)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	chg := st.NewChange(kind, summary)
+	if ts != nil {
+		chg.AddAll(ts)
+	}
+	if len(chg.Tasks()) == 0 {
+		chg.SetStatus(state.DoneStatus)
+	}
+	chg.Set("snap-names", []string{body.Snap})
+	MarkChangeForMessage(chg, msg)
+	st.EnsureBefore(0)
+
+	return chg.ID(), nil
+}
+
+// ResultFromChange reports the outcome of the snap operation along with the
+// revision and channel the snap is at, unless it was removed.
+func (h *snapHandler) ResultFromChange(chg *state.Change) (body map[string]any, err error) {
+	var snapNames []string
+	if err := chg.Get("snap-names", &snapNames); err != nil || len(snapNames) != 1 {
+		return nil, fmt.Errorf("internal error: cannot get snap name of change %s", chg.ID())
+	}
+	name := snapNames[0]
+
+	if chg.Status() != state.DoneStatus {
+		return nil, changeError(chg, fmt.Sprintf("%s snap %q", h.action, name))
+	}
+
+	body = map[string]any{
+		"change-id": chg.ID(),
+		"status":    chg.Status().String(),
+		"snap":      name,
+	}
+	if h.action == "remove" {
+		return body, nil
+	}
+
+	var snapst snapstate.SnapState
+	if err := snapstate.Get(chg.State(), name, &snapst); err != nil {
+		return nil, err
+	}
+	body["revision"] = snapst.Current.String()
+	if snapst.TrackingChannel != "" {
+		body["channel"] = snapst.TrackingChannel
+	}
+	return body, nil
+}
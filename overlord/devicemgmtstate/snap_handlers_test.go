@@ -0,0 +1,321 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicemgmtstate_test
+
+import (
+	"context"
+	"errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/devicemgmtstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+func (s *deviceMgmtMgrSuite) makeSnapRequestMessage(kind, body string) *devicemgmtstate.RequestMessage {
+	return &devicemgmtstate.RequestMessage{
+		AccountID:   "my-brand",
+		AuthorityID: "my-brand",
+		BaseID:      "msg-1",
+		Kind:        kind,
+		Devices:     []string{"serial-1"},
+		Body:        body,
+	}
+}
+
+func (s *deviceMgmtMgrSuite) mockTaskSet(st *state.State, summary string) *state.TaskSet {
+	return state.NewTaskSet(st.NewTask("fake-task", summary))
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlersRegistered(c *C) {
+	for _, kind := range []string{"snap-install", "snap-refresh", "snap-remove", "snap-revert"} {
+		c.Check(s.mgr.Handler(kind), NotNil, Commentf("kind %q", kind))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerValidateOK(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	for _, tc := range []struct {
+		kind string
+		body string
+	}{
+		{"snap-install", `{"snap": "foo"}`},
+		{"snap-install", `{"snap": "foo_bar", "channel": "latest/edge", "revision": "7"}`},
+		{"snap-refresh", `{"snap": "foo", "channel": "2/stable"}`},
+		{"snap-remove", `{"snap": "foo", "purge": true}`},
+		{"snap-revert", `{"snap": "foo"}`},
+		{"snap-revert", `{"snap": "foo", "revision": "x1"}`},
+	} {
+		msg := s.makeSnapRequestMessage(tc.kind, tc.body)
+		err := s.mgr.Handler(tc.kind).Validate(s.st, msg)
+		c.Check(err, IsNil, Commentf("%s: %s", tc.kind, tc.body))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerValidateErrors(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	for _, tc := range []struct {
+		kind   string
+		body   string
+		errMsg string
+	}{
+		{"snap-install", `not json`, `cannot decode snap-install message body: .*`},
+		{"snap-install", `{}`, `cannot install snap: snap name not provided`},
+		{"snap-install", `{"snap": "Foo!"}`, `cannot install snap: invalid snap name: "Foo!"`},
+		{"snap-install", `{"snap": "foo", "purge": true}`, `cannot install snap "foo": purge is not supported`},
+		{"snap-install", `{"snap": "foo", "revision": "x1"}`, `cannot install snap "foo": local revision x1 is not supported`},
+		{"snap-refresh", `{"snap": "foo", "revision": "x2"}`, `cannot refresh snap "foo": local revision x2 is not supported`},
+		{"snap-remove", `{"snap": "foo", "channel": "edge"}`, `cannot remove snap "foo": channel is not supported`},
+		{"snap-remove", `{"snap": "foo", "revision": "3"}`, `cannot remove snap "foo": revision is not supported`},
+		{"snap-revert", `{"snap": "foo", "channel": "edge"}`, `cannot revert snap "foo": channel is not supported`},
+	} {
+		msg := s.makeSnapRequestMessage(tc.kind, tc.body)
+		err := s.mgr.Handler(tc.kind).Validate(s.st, msg)
+		c.Check(err, ErrorMatches, tc.errMsg, Commentf("%s: %s", tc.kind, tc.body))
+	}
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerValidateUnauthorized(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	msg := s.makeSnapRequestMessage("snap-install", `{"snap": "foo"}`)
+	msg.AccountID = "other-brand"
+
+	err := s.mgr.Handler("snap-install").Validate(s.st, msg)
+	c.Assert(err, ErrorMatches, `cannot perform action: operator "other-brand" is not authorized`)
+	var unauthorized *devicemgmtstate.UnauthorizedError
+	c.Check(errors.As(err, &unauthorized), Equals, true)
+}
+
+func (s *deviceMgmtMgrSuite) checkSnapChange(c *C, chgID, kind, summary, name string, msg *devicemgmtstate.RequestMessage) *state.Change {
+	chg := s.st.Change(chgID)
+	c.Assert(chg, NotNil)
+	c.Check(chg.Kind(), Equals, kind)
+	c.Check(chg.Summary(), Equals, summary)
+
+	var snapNames []string
+	c.Assert(chg.Get("snap-names", &snapNames), IsNil)
+	c.Check(snapNames, DeepEquals, []string{name})
+
+	var msgID string
+	c.Assert(chg.Get("mgmt-message-id", &msgID), IsNil)
+	c.Check(msgID, Equals, msg.ID())
+	return chg
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerApplyInstall(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	var calls int
+	restore := devicemgmtstate.MockSnapstateInstall(func(ctx context.Context, st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		calls++
+		c.Check(name, Equals, "foo")
+		c.Check(opts, DeepEquals, &snapstate.RevisionOptions{Channel: "latest/edge", Revision: snap.R(7)})
+		c.Check(userID, Equals, 0)
+		c.Check(flags, DeepEquals, snapstate.Flags{})
+		return s.mockTaskSet(st, "install foo"), nil
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-install", `{"snap": "foo", "channel": "latest/edge", "revision": "7"}`)
+	chgID, err := s.mgr.Handler("snap-install").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+	c.Check(calls, Equals, 1)
+
+	chg := s.checkSnapChange(c, chgID, "install-snap", `Install "foo" snap`, "foo", msg)
+	c.Check(chg.Tasks(), HasLen, 1)
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerApplyRefresh(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	restore := devicemgmtstate.MockSnapstateUpdate(func(st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		c.Check(name, Equals, "foo")
+		c.Check(opts, DeepEquals, &snapstate.RevisionOptions{Channel: "2/stable"})
+		return s.mockTaskSet(st, "refresh foo"), nil
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-refresh", `{"snap": "foo", "channel": "2/stable"}`)
+	chgID, err := s.mgr.Handler("snap-refresh").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+
+	chg := s.checkSnapChange(c, chgID, "refresh-snap", `Refresh "foo" snap`, "foo", msg)
+	c.Check(chg.Tasks(), HasLen, 1)
+	c.Check(chg.Status(), Equals, state.DoStatus)
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerApplyRefreshNoUpdate(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	restore := devicemgmtstate.MockSnapstateUpdate(func(st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		return nil, store.ErrNoUpdateAvailable
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-refresh", `{"snap": "foo"}`)
+	chgID, err := s.mgr.Handler("snap-refresh").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+
+	chg := s.checkSnapChange(c, chgID, "refresh-snap", `Refresh "foo" snap`, "foo", msg)
+	c.Check(chg.Tasks(), HasLen, 0)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerApplyRemove(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	restore := devicemgmtstate.MockSnapstateRemove(func(st *state.State, name string, revision snap.Revision, flags *snapstate.RemoveFlags) (*state.TaskSet, error) {
+		c.Check(name, Equals, "foo")
+		c.Check(revision.Unset(), Equals, true)
+		c.Check(flags, DeepEquals, &snapstate.RemoveFlags{Purge: true})
+		return s.mockTaskSet(st, "remove foo"), nil
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-remove", `{"snap": "foo", "purge": true}`)
+	chgID, err := s.mgr.Handler("snap-remove").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+
+	s.checkSnapChange(c, chgID, "remove-snap", `Remove "foo" snap`, "foo", msg)
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerApplyRevert(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	var revertCalls, revertToRevCalls int
+	restore := devicemgmtstate.MockSnapstateRevert(func(st *state.State, name string, flags snapstate.Flags, fromChange string) (*state.TaskSet, error) {
+		revertCalls++
+		c.Check(name, Equals, "foo")
+		return s.mockTaskSet(st, "revert foo"), nil
+	})
+	defer restore()
+	restore = devicemgmtstate.MockSnapstateRevertToRevision(func(st *state.State, name string, rev snap.Revision, flags snapstate.Flags, fromChange string) (*state.TaskSet, error) {
+		revertToRevCalls++
+		c.Check(name, Equals, "foo")
+		c.Check(rev, Equals, snap.R(-1))
+		return s.mockTaskSet(st, "revert foo to x1"), nil
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-revert", `{"snap": "foo"}`)
+	chgID, err := s.mgr.Handler("snap-revert").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+	s.checkSnapChange(c, chgID, "revert-snap", `Revert "foo" snap`, "foo", msg)
+	c.Check(revertCalls, Equals, 1)
+	c.Check(revertToRevCalls, Equals, 0)
+
+	msg = s.makeSnapRequestMessage("snap-revert", `{"snap": "foo", "revision": "x1"}`)
+	chgID, err = s.mgr.Handler("snap-revert").Apply(s.st, msg)
+	c.Assert(err, IsNil)
+	s.checkSnapChange(c, chgID, "revert-snap", `Revert "foo" snap`, "foo", msg)
+	c.Check(revertCalls, Equals, 1)
+	c.Check(revertToRevCalls, Equals, 1)
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerApplyError(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	restore := devicemgmtstate.MockSnapstateInstall(func(ctx context.Context, st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		return nil, errors.New("boom")
+	})
+	defer restore()
+
+	msg := s.makeSnapRequestMessage("snap-install", `{"snap": "foo"}`)
+	chgID, err := s.mgr.Handler("snap-install").Apply(s.st, msg)
+	c.Assert(err, ErrorMatches, "boom")
+	c.Check(chgID, Equals, "")
+	c.Check(s.st.Changes(), HasLen, 0)
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerResultFromChangeDone(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	snapstate.Set(s.st, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(7)},
+		}),
+		Current:         snap.R(7),
+		TrackingChannel: "latest/edge",
+	})
+
+	chg := s.st.NewChange("install-snap", "...")
+	chg.Set("snap-names", []string{"foo"})
+	chg.SetStatus(state.DoneStatus)
+
+	body, err := s.mgr.Handler("snap-install").ResultFromChange(chg)
+	c.Assert(err, IsNil)
+	c.Check(body, DeepEquals, map[string]any{
+		"change-id": chg.ID(),
+		"status":    "Done",
+		"snap":      "foo",
+		"revision":  "7",
+		"channel":   "latest/edge",
+	})
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerResultFromChangeRemoveDone(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	chg := s.st.NewChange("remove-snap", "...")
+	chg.Set("snap-names", []string{"foo"})
+	chg.SetStatus(state.DoneStatus)
+
+	body, err := s.mgr.Handler("snap-remove").ResultFromChange(chg)
+	c.Assert(err, IsNil)
+	c.Check(body, DeepEquals, map[string]any{
+		"change-id": chg.ID(),
+		"status":    "Done",
+		"snap":      "foo",
+	})
+}
+
+func (s *deviceMgmtMgrSuite) TestSnapHandlerResultFromChangeError(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	chg := s.st.NewChange("refresh-snap", "...")
+	chg.Set("snap-names", []string{"foo"})
+	t := s.st.NewTask("fake-task", "...")
+	t.Errorf("download failed")
+	t.SetStatus(state.ErrorStatus)
+	chg.AddTask(t)
+
+	_, err := s.mgr.Handler("snap-refresh").ResultFromChange(chg)
+	c.Assert(err, ErrorMatches, `(?s)cannot refresh snap "foo" \(change [0-9]+ is Error\): .*download failed.*`)
+}
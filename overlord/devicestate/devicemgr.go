@@ -59,6 +59,7 @@ import (
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/secboot/keys"
+	"github.com/snapcore/snapd/seclog"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snapfile"
@@ -107,6 +108,7 @@ func init() {
 	swfeats.RegisterEnsure("DeviceManager", "ensureExpiredUsersRemoved")
 	swfeats.RegisterEnsure("DeviceManager", "ensureEarlyBootXKBConfigUpdated")
 	swfeats.RegisterEnsure("DeviceManager", "ensureExtraSnapdKernelCommandLineFragmentsApplied")
+	swfeats.RegisterEnsure("DeviceManager", "ensureSecurityLogCheckpointSigner")
 
 	snapstate.RegisterResealingTaskKind("set-model")
 	snapstate.RegisterResealingTaskKind("create-recovery-system")
@@ -163,6 +165,10 @@ type DeviceManager struct {
 
 	cachedKeypairMgr asserts.KeypairManager
 
+	// seclogSignerKeyID is the ID of the device key signing the
+	// checkpoints of the security log, if any
+	seclogSignerKeyID string
+
 	// newStore can make new stores for remodeling
 	newStore func(storecontext.DeviceBackend) snapstate.StoreService
 
@@ -1378,6 +1384,39 @@ func (m *DeviceManager) ensureBootOk() error {
 	return nil
 }
 
+// ensureSecurityLogCheckpointSigner makes the device key sign the
+// checkpoints of the security log once the device is registered.
+func (m *DeviceManager) ensureSecurityLogCheckpointSigner() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	device, err := m.device()
+	if err != nil {
+		return err
+	}
+	if device.Serial == "" || device.KeyID == "" || device.KeyID == m.seclogSignerKeyID {
+		return nil
+	}
+
+	logger.Trace("ensure", "manager", "DeviceManager", "func", "ensureSecurityLogCheckpointSigner")
+
+	// do not try again with the same key, the failure would only repeat
+	m.seclogSignerKeyID = device.KeyID
+	privKey, err := m.keyPair()
+	if err != nil {
+		logger.Noticef("cannot set up security log checkpoint signing: %v", err)
+		return nil
+	}
+	keyID := privKey.PublicKey().ID()
+	// the signer is called while logging, possibly with the state locked,
+	// so it must only use the key
+	seclog.SetupCheckpointSigner(func(data []byte) ([]byte, string, error) {
+		sig, err := asserts.RawSignWithKey(data, privKey)
+		return sig, keyID, err
+	})
+	return nil
+}
+
 func (m *DeviceManager) ensureCloudInitRestricted() error {
 	m.state.Lock()
 	defer m.state.Unlock()
@@ -2194,6 +2233,10 @@ func (m *DeviceManager) Ensure() error {
 			errs = append(errs, err)
 		}
 
+		if err := m.ensureSecurityLogCheckpointSigner(); err != nil {
+			errs = append(errs, err)
+		}
+
 		// XXX: This might trigger a reseal (auto-repair) but
 		// it should not affect resealing tasks since it is
 		// run at most once during startup before
@@ -2459,6 +2502,10 @@ func (m *DeviceManager) Unregister(opts *UnregisterOptions) error {
 		}
 	}
 	oldKeyID := device.KeyID
+	if m.seclogSignerKeyID != "" {
+		seclog.SetupCheckpointSigner(nil)
+		m.seclogSignerKeyID = ""
+	}
 	device.Serial = ""
 	device.KeyID = ""
 	device.SessionMacaroon = ""
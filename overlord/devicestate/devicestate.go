@@ -145,6 +145,19 @@ func Serial(st *state.State) (*asserts.Serial, error) {
 	return findSerial(st, nil)
 }
 
+// SignWithDeviceKey signs the given data with the device key, whose public
+// part is in the serial assertion of the device, so that peers holding the
+// serial assertion can verify it. Callers must hold the state lock.
+//
+// XXX: This is currently only used by clusterstate, like Serial.
+func SignWithDeviceKey(st *state.State, data []byte) ([]byte, error) {
+	privKey, err := deviceMgr(st).keyPair()
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign without device key: %w", err)
+	}
+	return asserts.RawSignWithKey(data, privKey)
+}
+
 // findKnownRevisionOfModel returns the model assertion revision if any in the
 // assertion database for the given model, otherwise it returns -1.
 func findKnownRevisionOfModel(st *state.State, mod *asserts.Model) (modRevision int, err error) {
@@ -20,6 +20,7 @@
 package devicestate_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -67,6 +68,7 @@ import (
 	"github.com/snapcore/snapd/sandbox/cgroup"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/secboot/keys"
+	"github.com/snapcore/snapd/seclog"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedtest"
 	"github.com/snapcore/snapd/seed/seedwriter"
@@ -195,6 +197,9 @@ func (s *deviceMgrBaseSuite) setupBaseTest(c *C, classic bool) {
 	c.Assert(err, IsNil)
 
 	s.AddCleanup(osutil.MockMountInfo(``))
+	// ensuring the device manager with a registered device makes its key
+	// sign the checkpoints of the security log
+	s.AddCleanup(func() { seclog.SetupCheckpointSigner(nil) })
 
 	s.restartRequests = nil
 
@@ -4015,6 +4020,23 @@ func (s *deviceMgrSuite) TestSignConfdbControlOK(c *C) {
 	assertstatetest.AddMany(s.state, cc)
 }
 
+func (s *deviceMgrSuite) TestSignWithDeviceKey(c *C) {
+	s.setPCModelInState(c)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.makeSerialAssertionInState(c, "canonical", "pc", "serialserialserial")
+
+	_, err := devicestate.SignWithDeviceKey(s.state, []byte("data"))
+	c.Assert(err, ErrorMatches, "cannot sign without device key: no state entry for key")
+
+	s.addKeyToManagerInState(c)
+
+	sig, err := devicestate.SignWithDeviceKey(s.state, []byte("data"))
+	c.Assert(err, IsNil)
+	c.Check(asserts.RawVerifyWithKey([]byte("data"), sig, devKey.PublicKey()), IsNil)
+}
+
 func (s *deviceMgrSuite) TestConfdbControlNoSerial(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -4090,6 +4112,54 @@ func (s *deviceMgrSuite) TestConfdbControlFindExisting(c *C) {
 	c.Assert(found, DeepEquals, cc)
 }
 
+func (s *deviceMgrSuite) TestEnsureSecurityLogCheckpointSigner(c *C) {
+	defer seclog.SetupCheckpointSigner(nil)
+
+	// nothing to sign with before registration
+	c.Assert(devicestate.EnsureSecurityLogCheckpointSigner(s.mgr), IsNil)
+
+	buf := &bytes.Buffer{}
+	hw := seclog.NewHashChainWriter(buf, filepath.Join(c.MkDir(), seclog.HashChainFile), "")
+	_, err := hw.Write([]byte(`{"description":"unsigned"}`))
+	c.Assert(err, IsNil)
+	c.Check(buf.String(), Not(testutil.Contains), "signature")
+
+	s.setPCModelInState(c)
+	s.state.Lock()
+	s.makeSerialAssertionInState(c, "canonical", "pc", "serialserialserial")
+	s.addKeyToManagerInState(c)
+	s.state.Unlock()
+
+	c.Assert(devicestate.EnsureSecurityLogCheckpointSigner(s.mgr), IsNil)
+
+	buf.Reset()
+	_, err = hw.Write([]byte(`{"description":"signed"}`))
+	c.Assert(err, IsNil)
+	c.Check(buf.String(), testutil.Contains, fmt.Sprintf(`"sign_key_id":"%s"}`, devKey.PublicKey().ID()))
+
+	report, err := seclog.VerifyHashChain(buf, nil, func(data, signature []byte, keyID string) error {
+		c.Check(keyID, Equals, devKey.PublicKey().ID())
+		return asserts.RawVerifyWithKey(data, signature, devKey.PublicKey())
+	})
+	c.Assert(err, IsNil)
+	c.Check(report.Checkpoints, Equals, 1)
+	c.Check(report.Problems, HasLen, 0)
+
+	// the signer is dropped with the key
+	restore := release.MockOnClassic(true)
+	defer restore()
+	s.state.Lock()
+	err = s.mgr.Unregister(nil)
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+
+	buf.Reset()
+	hw = seclog.NewHashChainWriter(buf, filepath.Join(c.MkDir(), seclog.HashChainFile), "")
+	_, err = hw.Write([]byte(`{"description":"unsigned again"}`))
+	c.Assert(err, IsNil)
+	c.Check(buf.String(), Not(testutil.Contains), "signature")
+}
+
 func (s *deviceMgrSuite) TestEnsureLoopLogging(c *C) {
 	swfeatstest.CheckEnsureLoopLogging("devicemgr.go", c, true)
 }
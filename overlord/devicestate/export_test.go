@@ -210,6 +210,10 @@ func EnsureCloudInitRestricted(m *DeviceManager) error {
 	return m.ensureCloudInitRestricted()
 }
 
+func EnsureSecurityLogCheckpointSigner(m *DeviceManager) error {
+	return m.ensureSecurityLogCheckpointSigner()
+}
+
 func EnsureSerialBoundSystemUserAssertionsProcessed(m *DeviceManager) error {
 	return m.ensureSerialBoundSystemUserAssertionsProcessed()
 }
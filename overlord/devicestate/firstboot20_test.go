@@ -22,7 +22,6 @@ package devicestate_test
 import (
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -45,6 +44,7 @@ import (
 	"github.com/snapcore/snapd/overlord/restart"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox/cgroup"
 	"github.com/snapcore/snapd/seed/seedtest"
@@ -478,9 +478,7 @@ func (s *firstBoot20Suite) testPopulateFromSeedCore20Happy(c *C, m *boot.Modeenv
 	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("%s", chg.Err()))
 
 	// verify
-	f, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, f)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
@@ -1032,9 +1030,7 @@ func (s *firstBoot20Suite) TestPopulateFromSeedClassicWithModesRunModeNoKernelAn
 	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("%s", chg.Err()))
 
 	// verify
-	f, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, f)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
@@ -1208,9 +1204,7 @@ apps:
 	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("%s", chg.Err()))
 
 	// verify
-	f, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, f)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
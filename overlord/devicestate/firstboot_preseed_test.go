@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/overlord/restart"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/seed/seedtest"
 	"github.com/snapcore/snapd/snap"
@@ -321,9 +322,7 @@ snaps:
 	c.Check(chg.Status(), Equals, state.DoingStatus)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	diskState, err := state.ReadState(nil, r)
+	diskState, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	diskState.Lock()
@@ -407,9 +406,7 @@ snaps:
 	c.Check(chg.Status(), Equals, state.DoingStatus)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	diskState, err := state.ReadState(nil, r)
+	diskState, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	diskState.Lock()
@@ -542,9 +539,7 @@ snaps:
 
 	// Verify state between the two change runs
 	st.Lock()
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	diskState, err := state.ReadState(nil, r)
+	diskState, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	diskState.Lock()
@@ -57,6 +57,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox/cgroup"
 	"github.com/snapcore/snapd/seed/seedtest"
@@ -628,9 +629,7 @@ func (s *firstBoot16Suite) TestPopulateFromSeedHappy(c *C) {
 	c.Check(osutil.FileExists(filepath.Join(dirs.SnapMountDir, "local", "x1", "meta", "snap.yaml")), Equals, true)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
@@ -825,9 +824,7 @@ snaps:
 	c.Check(osutil.FileExists(filepath.Join(dirs.SnapMountDir, "bar", "65", "meta", "snap.yaml")), Equals, true)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
@@ -963,9 +960,7 @@ snaps:
 	c.Check(osutil.FileExists(filepath.Join(dirs.SnapMountDir, "foo", "128", "meta", "snap.yaml")), Equals, true)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
@@ -1089,9 +1084,7 @@ snaps:
 	c.Check(osutil.FileExists(filepath.Join(dirs.SnapMountDir, "foo", "128", "meta", "snap.yaml")), Equals, true)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
@@ -1495,9 +1488,7 @@ snaps:
 	c.Assert(chg.Status(), Equals, state.DoneStatus)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
@@ -1940,9 +1931,7 @@ snaps:
 	c.Assert(chg.Status(), Equals, state.DoneStatus)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
@@ -2081,9 +2070,7 @@ snaps:
 	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("%s", chg.Err()))
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
+	state, err := statedb.ReadState(nil, dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 
 	state.Lock()
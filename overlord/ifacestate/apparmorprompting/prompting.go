@@ -27,6 +27,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces/prompting"
 	prompting_errors "github.com/snapcore/snapd/interfaces/prompting/errors"
+	"github.com/snapcore/snapd/interfaces/prompting/patterns"
 	"github.com/snapcore/snapd/interfaces/prompting/requestprompts"
 	"github.com/snapcore/snapd/interfaces/prompting/requestrules"
 	"github.com/snapcore/snapd/logger"
@@ -49,6 +50,11 @@ type listenerBackend interface {
 	Reqs() <-chan *prompting.Request
 }
 
+// PlugPathsFunc returns the paths declared by the connected plugs of the given
+// interface of the given snap, as they apply to the given user, for interfaces
+// which only grant access to the paths declared by their plugs.
+type PlugPathsFunc func(userID uint32, snap string, iface string) ([]string, error)
+
 // A Manager holds outstanding prompts and mediates their replies, further it
 // stores and applies persistent rules.
 type Manager interface {
@@ -62,6 +68,10 @@ type Manager interface {
 	RuleWithID(userID uint32, ruleID prompting.IDType) (*requestrules.Rule, error)
 	PatchRule(userID uint32, ruleID prompting.IDType, constraintsPatchJSON prompting.ConstraintsJSON) (*requestrules.Rule, error)
 	RemoveRule(userID uint32, ruleID prompting.IDType) (*requestrules.Rule, error)
+	ExportRules(userID uint32, snap string, iface string) ([]*requestrules.PortableRule, error)
+	ImportRules(userID uint32, rules []*requestrules.PortableRule) ([]*requestrules.Rule, error)
+	History(userID uint32, filter *requestprompts.HistoryFilter) ([]*requestprompts.HistoryEntry, error)
+	CheckRequest(userID uint32, snap string, iface string, path string, permissions []string) (*requestrules.RequestCheck, error)
 }
 
 // verify that InterfacesRequestsManager implements Manager
@@ -93,9 +103,16 @@ type InterfacesRequestsManager struct {
 	shutDownOnce      sync.Once
 
 	askRequests chan *prompting.Request
+
+	// plugPaths returns the paths declared by the connected plugs of a snap,
+	// to which the path patterns of some interfaces are constrained.
+	plugPaths PlugPathsFunc
 }
 
-func New(noticeMgr *notices.NoticeManager) (m *InterfacesRequestsManager, retErr error) {
+// New creates a new InterfacesRequestsManager. The given plugPaths function is
+// used to constrain the prompts and rules of interfaces which only grant
+// access to the paths declared by their plugs, such as personal-files.
+func New(noticeMgr *notices.NoticeManager, plugPaths PlugPathsFunc) (m *InterfacesRequestsManager, retErr error) {
 	// First initialize notice backends to load notices from disk and allow
 	// prompting managers to record notices. Don't register the notice backends
 	// with the state until we're sure initialization was successful and
@@ -149,6 +166,7 @@ func New(noticeMgr *notices.NoticeManager) (m *InterfacesRequestsManager, retErr
 		listenerAlreadySignalled: make(chan struct{}),
 		snapdShuttingDown:        make(chan struct{}),
 		askRequests:              make(chan *prompting.Request),
+		plugPaths:                plugPaths,
 	}
 
 	m.tomb.Go(m.run)
@@ -255,6 +273,10 @@ func (m *InterfacesRequestsManager) handleRequest(req *prompting.Request) error
 		return req.Reply(nil)
 	}
 
+	if req.Interface == "home" {
+		m.reclassifyPersonalFilesRequest(req)
+	}
+
 	// we're done with early checks, serious business starts now, and we can
 	// take the lock
 	m.lock.Lock()
@@ -288,6 +310,14 @@ func (m *InterfacesRequestsManager) handleRequest(req *prompting.Request) error
 	// TODO: metadata isn't really necessary, since req holds almost all info;
 	// or, req isn't really necessary, and could instead just pass Reply() ?
 
+	if prompting.UsesPlugPaths(req.Interface) {
+		plugPaths, err := m.lookupPlugPaths(req.UID, req.Snap, req.Interface)
+		if err != nil {
+			logger.Noticef("cannot get paths of plugs for request: %v", err)
+		}
+		req.PlugPaths = plugPaths
+	}
+
 	newPrompt, merged, err := m.prompts.AddOrMerge(metadata, req.Path, req.Permissions, outstandingPerms, req)
 	if err != nil {
 		logger.Noticef("error while checking request against prompt DB: %v", err)
@@ -466,7 +496,8 @@ func (m *InterfacesRequestsManager) HandleReply(userID uint32, promptID promptin
 	// Check that constraints matches original requested path.
 	// We do not assert anything else particular about the constraints, such
 	// as check that the path pattern does not match any paths not granted by
-	// the interface.
+	// the interface, except for interfaces which only grant access to the
+	// paths declared by their plugs.
 	// TODO: Should this be reconsidered?
 	matches, err := constraints.PathPattern().Match(prompt.Constraints.Path())
 	if err != nil {
@@ -480,6 +511,9 @@ func (m *InterfacesRequestsManager) HandleReply(userID uint32, promptID promptin
 			Replied:   constraints.PathPattern().String(),
 		}
 	}
+	if err := m.validatePlugPaths(userID, prompt.Snap, prompt.Interface, constraints.PathPattern()); err != nil {
+		return nil, err
+	}
 
 	// XXX: do we want to allow only replying to a select subset of permissions, and
 	// auto-deny the rest?
@@ -520,7 +554,11 @@ func (m *InterfacesRequestsManager) HandleReply(userID uint32, promptID promptin
 		}()
 	}
 
-	prompt, retErr = m.prompts.Reply(userID, promptID, outcome, clientActivity)
+	var ruleID prompting.IDType
+	if newRule != nil {
+		ruleID = newRule.ID
+	}
+	prompt, retErr = m.prompts.Reply(userID, promptID, outcome, lifespan, ruleID, clientActivity)
 	if retErr != nil {
 		// Error should not occur unless the listener has closed
 		return nil, retErr
@@ -536,13 +574,54 @@ func (m *InterfacesRequestsManager) HandleReply(userID uint32, promptID promptin
 	return satisfiedPromptIDs, nil
 }
 
+// lookupPlugPaths returns the paths declared by the connected plugs of the
+// given interface of the given snap.
+func (m *InterfacesRequestsManager) lookupPlugPaths(userID uint32, snap string, iface string) ([]string, error) {
+	if m.plugPaths == nil {
+		return nil, nil
+	}
+	return m.plugPaths(userID, snap, iface)
+}
+
+// reclassifyPersonalFilesRequest associates the "personal-files" interface with
+// the given request for the "home" interface if the requested path is within
+// the paths declared by the connected "personal-files" plugs of the snap. The
+// listener cannot tell such requests apart from those for "home" by their path.
+func (m *InterfacesRequestsManager) reclassifyPersonalFilesRequest(req *prompting.Request) {
+	plugPaths, err := m.lookupPlugPaths(req.UID, req.Snap, "personal-files")
+	if err != nil {
+		logger.Noticef("cannot get paths of personal-files plugs for request: %v", err)
+		return
+	}
+	if !prompting.PathWithinPlugPaths(req.Path, plugPaths) {
+		return
+	}
+	if err := req.SetInterface("personal-files"); err != nil {
+		logger.Noticef("cannot associate request with the personal-files interface: %v", err)
+	}
+}
+
+// validatePlugPaths checks that the given path pattern only matches paths
+// declared by the connected plugs of the given snap, if the given interface
+// only grants access to those paths.
+func (m *InterfacesRequestsManager) validatePlugPaths(userID uint32, snap string, iface string, pathPattern *patterns.PathPattern) error {
+	if !prompting.UsesPlugPaths(iface) {
+		return nil
+	}
+	plugPaths, err := m.lookupPlugPaths(userID, snap, iface)
+	if err != nil {
+		return fmt.Errorf("cannot get paths of plugs of snap %q for interface %q: %w", snap, iface, err)
+	}
+	return prompting.ValidatePathPatternForPlugPaths(pathPattern, plugPaths)
+}
+
 func (m *InterfacesRequestsManager) applyRuleToOutstandingPrompts(rule *requestrules.Rule) []prompting.IDType {
 	metadata := &prompting.Metadata{
 		User:      rule.User,
 		Snap:      rule.Snap,
 		Interface: rule.Interface,
 	}
-	satisfiedPromptIDs, err := m.prompts.HandleNewRule(metadata, rule.Constraints)
+	satisfiedPromptIDs, err := m.prompts.HandleNewRule(metadata, rule.ID, rule.Constraints)
 	if err != nil {
 		// The rule's constraints and outcome were already validated, so an
 		// error should not occur here unless the prompt DB was already closed.
@@ -585,6 +664,9 @@ func (m *InterfacesRequestsManager) AddRule(userID uint32, snap string, iface st
 	if err != nil {
 		return nil, fmt.Errorf("cannot decode request body for rules endpoint: %w", err)
 	}
+	if err := m.validatePlugPaths(userID, snap, iface, constraints.PathPattern()); err != nil {
+		return nil, err
+	}
 
 	newRule, err := m.rules.AddRule(userID, snap, iface, constraints)
 	if err != nil {
@@ -642,11 +724,19 @@ func (m *InterfacesRequestsManager) PatchRule(userID uint32, ruleID prompting.ID
 	if err != nil {
 		return nil, err
 	}
+	if origRule.Policy {
+		return nil, prompting_errors.ErrRuleIsPolicy
+	}
 	constraintsPatch, err := prompting.UnmarshalRuleConstraintsPatch(origRule.Interface, constraintsPatchJSON)
 	if err != nil {
 		// XXX: should this say "... or deletion" like daemon does?
 		return nil, fmt.Errorf("cannot decode request body into request rule modification: %w", err)
 	}
+	if pathPattern := constraintsPatch.PathPattern(); pathPattern != nil {
+		if err := m.validatePlugPaths(userID, origRule.Snap, origRule.Interface, pathPattern); err != nil {
+			return nil, err
+		}
+	}
 
 	patchedRule, err := m.rules.PatchRule(userID, ruleID, constraintsPatch)
 	if err != nil {
@@ -669,3 +759,84 @@ func (m *InterfacesRequestsManager) RemoveRule(userID uint32, ruleID prompting.I
 	rule, err := m.rules.RemoveRule(userID, ruleID)
 	return rule, err
 }
+
+// ExportRules returns the rules of the user with the given user ID and,
+// optionally, only those for the given snap and/or interface, in a portable
+// form which can be imported on another machine.
+func (m *InterfacesRequestsManager) ExportRules(userID uint32, snap string, iface string) ([]*requestrules.PortableRule, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.rules.ExportRules(userID, snap, iface)
+}
+
+// ImportRules validates the given portable rules and adds them to the rules of
+// the user with the given user ID, then checks them against outstanding
+// prompts, resolving any prompts which they satisfy. Either all the rules are
+// imported, or none is.
+func (m *InterfacesRequestsManager) ImportRules(userID uint32, rules []*requestrules.PortableRule) ([]*requestrules.Rule, error) {
+	<-m.prompts.Ready()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	importedRules := make([]*requestrules.ImportedRule, 0, len(rules))
+	for i, rule := range rules {
+		constraintsJSON, err := rule.ConstraintsForUser(userID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot import rule %d: %w", i, err)
+		}
+		constraints, err := prompting.UnmarshalConstraints(rule.Interface, constraintsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("cannot import rule %d: %w", i, err)
+		}
+		if err := m.validatePlugPaths(userID, rule.Snap, rule.Interface, constraints.PathPattern()); err != nil {
+			return nil, fmt.Errorf("cannot import rule %d: %w", i, err)
+		}
+		importedRules = append(importedRules, &requestrules.ImportedRule{
+			Snap:        rule.Snap,
+			Interface:   rule.Interface,
+			Constraints: constraints,
+		})
+	}
+
+	newRules, err := m.rules.ImportRules(userID, importedRules)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range newRules {
+		m.applyRuleToOutstandingPrompts(rule)
+	}
+	return newRules, nil
+}
+
+// History returns the entries of the history of how the prompts of the user
+// with the given user ID were resolved which match the given filter, from
+// oldest to newest.
+func (m *InterfacesRequestsManager) History(userID uint32, filter *requestprompts.HistoryFilter) ([]*requestprompts.HistoryEntry, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.prompts.History(userID, filter)
+}
+
+// CheckRequest reports what the rules of the user with the given user ID,
+// along with any policy rules, would decide about a request from the given
+// snap for the given permissions to the given path through the given
+// interface, and which rules and path pattern variants lead to that decision.
+// No prompt is created and no rule is changed.
+func (m *InterfacesRequestsManager) CheckRequest(userID uint32, snap string, iface string, path string, permissions []string) (*requestrules.RequestCheck, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.rules.CheckRequest(userID, snap, iface, path, permissions)
+}
+
+// PolicyError returns the error which prevented the policy rules from being
+// loaded, if any, in which case every request is denied.
+func (m *InterfacesRequestsManager) PolicyError() error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.rules.PolicyError()
+}
@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
 	"testing"
 	"time"
@@ -41,6 +42,7 @@ import (
 	"github.com/snapcore/snapd/overlord/ifacestate/apparmorprompting"
 	"github.com/snapcore/snapd/overlord/notices"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/sandbox/apparmor/notify"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -92,7 +94,7 @@ func (s *apparmorpromptingSuite) TestNew(c *C) {
 	_, _, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	select {
@@ -113,7 +115,7 @@ func (s *apparmorpromptingSuite) TestNewErrorListener(c *C) {
 	})
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, ErrorMatches, fmt.Sprintf("cannot register prompting listener: %v", registerFailure))
 	c.Assert(mgr, IsNil)
 }
@@ -130,7 +132,7 @@ func (s *apparmorpromptingSuite) TestNewErrorPromptDB(c *C) {
 	c.Assert(f.Chmod(0o400), IsNil)
 	defer f.Chmod(0o600)
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, ErrorMatches, "cannot open request prompts backend:.*")
 	c.Assert(mgr, IsNil)
 
@@ -159,7 +161,7 @@ func (s *apparmorpromptingSuite) TestNewErrorRuleDB(c *C) {
 	c.Assert(f.Chmod(0o400), IsNil)
 	defer f.Chmod(0o600)
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, ErrorMatches, "cannot open request rules backend:.*")
 	c.Assert(mgr, IsNil)
 
@@ -175,7 +177,7 @@ func (s *apparmorpromptingSuite) TestStop(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	promptDB := mgr.PromptDB()
@@ -224,7 +226,7 @@ func (s *apparmorpromptingSuite) TestHandleRequestDenyRoot(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Send request for root
@@ -248,7 +250,7 @@ func (s *apparmorpromptingSuite) TestHandleRequestErrors(c *C) {
 	logbuf, restore := logger.MockLogger()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	clientActivity := true
@@ -319,7 +321,7 @@ func (s *apparmorpromptingSuite) TestHandleReplySimple(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	req, replyChan := requestWithReplyChan(&prompting.Request{})
@@ -398,7 +400,7 @@ func (s *apparmorpromptingSuite) simulateRequest(c *C, reqChan chan *prompting.R
 	c.Check(prompt.Snap, Equals, req.Snap)
 	c.Check(prompt.PID, Equals, req.PID)
 	c.Check(prompt.Cgroup, Equals, req.Cgroup)
-	c.Check(prompt.Interface, Equals, "home")
+	c.Check(prompt.Interface, Equals, req.Interface)
 	c.Check(prompt.Constraints.Path(), Equals, req.Path)
 
 	// Check that we can retrieve that prompt by ID
@@ -457,7 +459,7 @@ func (s *apparmorpromptingSuite) TestHandleReplyUnusualPaths(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	const clientActivity = true
@@ -540,7 +542,7 @@ func (s *apparmorpromptingSuite) TestHandleReplyErrors(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	_, prompt := s.simulateRequest(c, reqChan, mgr, &prompting.Request{}, false)
@@ -620,7 +622,7 @@ func (s *apparmorpromptingSuite) testAskWithOutcome(c *C, outcome prompting.Outc
 	logbuf, restore := logger.MockDebugLogger()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 	defer func() {
 		c.Check(mgr.Stop(), IsNil)
@@ -729,7 +731,7 @@ func (s *apparmorpromptingSuite) TestAskShutdownBeforeSending(c *C) {
 	_, _, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	const (
@@ -772,7 +774,7 @@ func (s *apparmorpromptingSuite) TestAskShutdownBeforeReplyWithStop(c *C) {
 	c.Assert(os.MkdirAll(dirs.SnapInterfacesRequestsRunDir, 0o777), IsNil)
 	c.Assert(osutil.AtomicWriteFile(requestMapFilepath, []byte(requestMapping), 0o600, 0), IsNil)
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Call Ask, then signal when response has been validated
@@ -819,7 +821,7 @@ func (s *apparmorpromptingSuite) TestAskShutdownBeforeReplyWithStop(c *C) {
 
 	// Try to reply, see that we get ErrPromptingClosed from the Reply closure
 	outcome := prompting.OutcomeAllow
-	_, err = mgr.PromptDB().Reply(uid, promptID, outcome, clientActivity)
+	_, err = mgr.PromptDB().Reply(uid, promptID, outcome, prompting.LifespanSingle, 0, clientActivity)
 	c.Check(err, Equals, prompting_errors.ErrPromptingClosed)
 
 	// To confirm this was not because the prompts backend was closed, check
@@ -871,7 +873,7 @@ func (s *apparmorpromptingSuite) TestAskShutdownBeforeReplyWithShutDown(c *C) {
 	c.Assert(os.MkdirAll(dirs.SnapInterfacesRequestsRunDir, 0o777), IsNil)
 	c.Assert(osutil.AtomicWriteFile(requestMapFilepath, []byte(requestMapping), 0o600, 0), IsNil)
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Call Ask, then signal when response has been validated
@@ -905,7 +907,7 @@ func (s *apparmorpromptingSuite) TestAskShutdownBeforeReplyWithShutDown(c *C) {
 	// channel has closed.
 	outcome := prompting.OutcomeAllow
 	clientActivity := false
-	_, err = mgr.PromptDB().Reply(uid, promptID, outcome, clientActivity)
+	_, err = mgr.PromptDB().Reply(uid, promptID, outcome, prompting.LifespanSingle, 0, clientActivity)
 	c.Check(err, Equals, prompting_errors.ErrPromptingClosed)
 
 	c.Check(mgr.Stop(), IsNil)
@@ -915,7 +917,7 @@ func (s *apparmorpromptingSuite) TestExistingRuleAllowsNewPrompt(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Add allow rule to match read permission
@@ -981,7 +983,7 @@ func (s *apparmorpromptingSuite) TestExistingRulePartiallyAllowsNewPrompt(c *C)
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Add rule to match read permission
@@ -1010,7 +1012,7 @@ func (s *apparmorpromptingSuite) TestExistingRulePartiallyDeniesNewPrompt(c *C)
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Add deny rule to match read permission
@@ -1053,7 +1055,7 @@ func (s *apparmorpromptingSuite) TestExistingRulesMixedMatchNewPromptDenies(c *C
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Add deny rule to match read permission
@@ -1108,7 +1110,7 @@ func (s *apparmorpromptingSuite) TestNewRuleAllowExistingPrompt(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Add read request
@@ -1180,7 +1182,7 @@ func (s *apparmorpromptingSuite) TestNewRuleDenyExistingPrompt(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Add read request
@@ -1246,7 +1248,7 @@ func (s *apparmorpromptingSuite) TestReplyNewRuleHandlesExistingPrompt(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Already tested HandleReply errors, and that applyRuleToOutstandingPrompts
@@ -1341,7 +1343,7 @@ func (s *apparmorpromptingSuite) testReplyRuleHandlesFuturePrompts(c *C, outcome
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Already tested HandleReply errors, and that applyRuleToOutstandingPrompts
@@ -1435,7 +1437,7 @@ func (s *apparmorpromptingSuite) TestRequestMerged(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Requests with identical *original* abstract permissions are merged into
@@ -1508,7 +1510,7 @@ func (s *apparmorpromptingSuite) TestRules(c *C) {
 
 func (s *apparmorpromptingSuite) prepManagerWithRules(c *C) (mgr *apparmorprompting.InterfacesRequestsManager, rules []*requestrules.Rule) {
 	var err error
-	mgr, err = apparmorprompting.New(s.noticeMgr)
+	mgr, err = apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	whenAdded := time.Now()
@@ -1628,7 +1630,7 @@ func (s *apparmorpromptingSuite) TestAddRuleWithIDPatchRemove(c *C) {
 	_, reqChan, restore := apparmorprompting.MockListener()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Add read request
@@ -1706,6 +1708,410 @@ func (s *apparmorpromptingSuite) TestAddRuleWithIDPatchRemove(c *C) {
 	c.Assert(mgr.Stop(), IsNil)
 }
 
+func (s *apparmorpromptingSuite) TestPlugPaths(c *C) {
+	_, reqChan, restore := apparmorprompting.MockListener()
+	defer restore()
+
+	var plugPathsCalls []string
+	plugPaths := func(userID uint32, snap string, iface string) ([]string, error) {
+		c.Check(userID, Equals, s.defaultUser)
+		plugPathsCalls = append(plugPathsCalls, fmt.Sprintf("%s:%s", snap, iface))
+		if snap == "firefox" && iface == "personal-files" {
+			return []string{"/home/test/.config/foo"}, nil
+		}
+		return nil, fmt.Errorf("unexpected snap %q or interface %q", snap, iface)
+	}
+	mgr, err := apparmorprompting.New(s.noticeMgr, plugPaths)
+	c.Assert(err, IsNil)
+
+	// Requests for interfaces without plug paths are not affected, though
+	// requests for "home" are checked against the paths of the
+	// "personal-files" plugs
+	_, homePrompt := s.simulateRequest(c, reqChan, mgr, &prompting.Request{}, false)
+	c.Check(homePrompt.Interface, Equals, "home")
+	c.Check(plugPathsCalls, DeepEquals, []string{"firefox:personal-files"})
+	plugPathsCalls = nil
+	marshalled, err := json.Marshal(homePrompt)
+	c.Assert(err, IsNil)
+	c.Check(string(marshalled), Not(testutil.Contains), "plug-paths")
+
+	req, replyChan := requestWithReplyChan(&prompting.Request{
+		Interface: "personal-files",
+		Path:      "/home/test/.config/foo/settings.json",
+	})
+	_, prompt := s.simulateRequest(c, reqChan, mgr, req, false)
+	c.Check(plugPathsCalls, DeepEquals, []string{"firefox:personal-files"})
+	marshalled, err = json.Marshal(prompt)
+	c.Assert(err, IsNil)
+	c.Check(string(marshalled), testutil.Contains, `"plug-paths":["/home/test/.config/foo"]`)
+
+	// Replies cannot cover paths outside of those of the plugs
+	constraintsJSON := prompting.ConstraintsJSON{
+		"path-pattern": json.RawMessage(`"/home/test/.config/**"`),
+		"permissions":  json.RawMessage(`["read"]`),
+	}
+	_, err = mgr.HandleReply(s.defaultUser, prompt.ID, constraintsJSON, prompting.OutcomeAllow, prompting.LifespanForever, "", false)
+	c.Check(err, ErrorMatches, `invalid path pattern: pattern matches paths outside of those declared by connected plugs \(/home/test/.config/foo\): "/home/test/.config/\*\*"`)
+
+	constraintsJSON["path-pattern"] = json.RawMessage(`"/home/test/.config/foo/**"`)
+	_, err = mgr.HandleReply(s.defaultUser, prompt.ID, constraintsJSON, prompting.OutcomeAllow, prompting.LifespanForever, "", false)
+	c.Check(err, IsNil)
+	allowedPermissions, err := waitForReply(replyChan)
+	c.Assert(err, IsNil)
+	c.Check(allowedPermissions, DeepEquals, []string{"read"})
+
+	// Neither can new rules
+	constraints := prompting.ConstraintsJSON{
+		"path-pattern": json.RawMessage(`"/home/test/.bashrc"`),
+		"permissions":  json.RawMessage(`{"write":{"outcome":"allow","lifespan":"forever"}}`),
+	}
+	_, err = mgr.AddRule(s.defaultUser, "firefox", "personal-files", constraints)
+	c.Check(err, ErrorMatches, `invalid path pattern: pattern matches paths outside of those declared by connected plugs \(/home/test/.config/foo\): "/home/test/.bashrc"`)
+
+	constraints["path-pattern"] = json.RawMessage(`"/home/test/.config/foo/*.json"`)
+	rule, err := mgr.AddRule(s.defaultUser, "firefox", "personal-files", constraints)
+	c.Assert(err, IsNil)
+
+	// Nor patched ones
+	constraintsPatch := prompting.ConstraintsJSON{
+		"path-pattern": json.RawMessage(`"/home/test/**/*.json"`),
+	}
+	_, err = mgr.PatchRule(s.defaultUser, rule.ID, constraintsPatch)
+	c.Check(err, ErrorMatches, `invalid path pattern: pattern matches paths outside of those declared by connected plugs \(/home/test/.config/foo\): "/home/test/\*\*/\*.json"`)
+
+	constraintsPatch = prompting.ConstraintsJSON{
+		"permissions": json.RawMessage(`{"write":{"outcome":"deny","lifespan":"forever"}}`),
+	}
+	_, err = mgr.PatchRule(s.defaultUser, rule.ID, constraintsPatch)
+	c.Check(err, IsNil)
+
+	// Errors getting the plug paths are reported
+	_, err = mgr.AddRule(s.defaultUser, "thunderbird", "personal-files", constraints)
+	c.Check(err, ErrorMatches, `cannot get paths of plugs of snap "thunderbird" for interface "personal-files": unexpected snap "thunderbird" or interface "personal-files"`)
+
+	c.Assert(mgr.Stop(), IsNil)
+}
+
+func (s *apparmorpromptingSuite) TestPersonalFilesRequestFromListener(c *C) {
+	_, reqChan, restore := apparmorprompting.MockListener()
+	defer restore()
+
+	restore = prompting.MockCgroupProcessPathInTrackingCgroup(func(pid int) (string, error) {
+		return "0::/user.slice/user-1000.slice/user@1000.service/app.slice/snap.firefox.firefox-someuuid.scope", nil
+	})
+	defer restore()
+
+	plugPaths := func(userID uint32, snap string, iface string) ([]string, error) {
+		c.Check(userID, Equals, s.defaultUser)
+		c.Check(snap, Equals, "firefox")
+		c.Check(iface, Equals, "personal-files")
+		return []string{"/home/test/.config/foo"}, nil
+	}
+	mgr, err := apparmorprompting.New(s.noticeMgr, plugPaths)
+	c.Assert(err, IsNil)
+
+	sentResponses := make(chan notify.AppArmorPermission, 1)
+	sendResponse := func(id uint64, aaAllowed, aaRequested, userAllowed notify.AppArmorPermission) error {
+		sentResponses <- userAllowed
+		return nil
+	}
+	msgForPath := func(id uint64, path string) *notify.MsgNotificationFile {
+		msg := &notify.MsgNotificationFile{}
+		msg.Version = notify.ProtocolVersion(5)
+		msg.NotificationType = notify.APPARMOR_NOTIF_OP
+		msg.KernelNotificationID = id
+		msg.Deny = uint32(notify.AA_MAY_READ)
+		msg.Pid = 1234
+		msg.Label = "snap.firefox.firefox"
+		msg.Class = notify.AA_CLASS_FILE
+		msg.SUID = s.defaultUser
+		msg.Filename = path
+		return msg
+	}
+
+	// A request within the paths of the personal-files plugs results in a
+	// prompt for personal-files
+	req, err := prompting.NewRequestFromListener(msgForPath(1, "/home/test/.config/foo/prefs.js"), sendResponse)
+	c.Assert(err, IsNil)
+	c.Check(req.Interface, Equals, "home")
+	_, prompt := s.simulateRequest(c, reqChan, mgr, req, false)
+	c.Check(prompt.Interface, Equals, "personal-files")
+	c.Check(prompt.Constraints.Path(), Equals, "/home/test/.config/foo/prefs.js")
+	marshalled, err := json.Marshal(prompt)
+	c.Assert(err, IsNil)
+	c.Check(string(marshalled), testutil.Contains, `"plug-paths":["/home/test/.config/foo"]`)
+
+	// The reply is sent to the kernel for the request
+	constraintsJSON := prompting.ConstraintsJSON{
+		"path-pattern": json.RawMessage(`"/home/test/.config/foo/**"`),
+		"permissions":  json.RawMessage(`["read"]`),
+	}
+	_, err = mgr.HandleReply(s.defaultUser, prompt.ID, constraintsJSON, prompting.OutcomeAllow, prompting.LifespanForever, "", false)
+	c.Assert(err, IsNil)
+	select {
+	case userAllowed := <-sentResponses:
+		c.Check(userAllowed, Equals, notify.AA_MAY_OPEN|notify.AA_MAY_READ|notify.AA_MAY_GETATTR)
+	case <-time.After(time.Second):
+		c.Fatalf("no response sent to the kernel")
+	}
+
+	// The resulting rule applies to later requests for personal-files
+	rules, err := mgr.Rules(s.defaultUser, "firefox", "personal-files")
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 1)
+	req, err = prompting.NewRequestFromListener(msgForPath(2, "/home/test/.config/foo/cookies.sqlite"), sendResponse)
+	c.Assert(err, IsNil)
+	reqChan <- req
+	select {
+	case userAllowed := <-sentResponses:
+		c.Check(userAllowed, Equals, notify.AA_MAY_OPEN|notify.AA_MAY_READ|notify.AA_MAY_GETATTR)
+	case <-time.After(time.Second):
+		c.Fatalf("no response sent to the kernel")
+	}
+	c.Check(req.Interface, Equals, "personal-files")
+
+	// A request outside of those paths remains a request for home
+	req, err = prompting.NewRequestFromListener(msgForPath(3, "/home/test/.config/foobar"), sendResponse)
+	c.Assert(err, IsNil)
+	_, prompt = s.simulateRequest(c, reqChan, mgr, req, false)
+	c.Check(prompt.Interface, Equals, "home")
+
+	c.Assert(mgr.Stop(), IsNil)
+}
+
+func (s *apparmorpromptingSuite) TestExportImportRules(c *C) {
+	_, reqChan, restore := apparmorprompting.MockListener()
+	defer restore()
+
+	restore = requestrules.MockUserLookupId(func(uid string) (*user.User, error) {
+		c.Check(uid, Equals, "1000")
+		return &user.User{Uid: uid, HomeDir: "/home/test"}, nil
+	})
+	defer restore()
+
+	mgr, rules := s.prepManagerWithRules(c)
+
+	exported, err := mgr.ExportRules(s.defaultUser, "firefox", "")
+	c.Assert(err, IsNil)
+	c.Assert(exported, HasLen, 2)
+	for i, rule := range []*requestrules.Rule{rules[0], rules[2]} {
+		c.Check(exported[i].Snap, Equals, rule.Snap)
+		c.Check(exported[i].Interface, Equals, rule.Interface)
+	}
+	// The home directory of the user is replaced by $HOME, and expanded
+	// again on import
+	c.Check(string(exported[0].Constraints["path-pattern"]), Equals, `"$HOME/1"`)
+
+	// Remove the exported rules so that a new request is not allowed
+	_, err = mgr.RemoveRules(s.defaultUser, "firefox", "")
+	c.Assert(err, IsNil)
+
+	req, replyChan := requestWithReplyChan(&prompting.Request{
+		Path:        "/home/test/1",
+		Permissions: []string{"read"},
+	})
+	_, prompt := s.simulateRequest(c, reqChan, mgr, req, false)
+
+	// Import the exported rules back
+	whenSent := time.Now()
+	imported, err := mgr.ImportRules(s.defaultUser, exported)
+	c.Assert(err, IsNil)
+	c.Assert(imported, HasLen, 2)
+	for i, rule := range imported {
+		c.Check(rule.User, Equals, s.defaultUser)
+		c.Check(rule.Snap, Equals, exported[i].Snap)
+		c.Check(rule.Interface, Equals, exported[i].Interface)
+	}
+
+	// The outstanding prompt was satisfied by the imported rule
+	allowedPermissions, err := waitForReply(replyChan)
+	c.Assert(err, IsNil)
+	c.Check(allowedPermissions, DeepEquals, []string{"read"})
+	clientActivity := false
+	_, err = mgr.PromptWithID(s.defaultUser, prompt.ID, clientActivity)
+	c.Check(err, NotNil)
+
+	s.checkRecordedPromptNotices(c, whenSent, 1)
+	s.checkRecordedRuleUpdateNotices(c, whenSent, 2)
+
+	snapRules, err := mgr.Rules(s.defaultUser, "firefox", "")
+	c.Assert(err, IsNil)
+	c.Check(snapRules, DeepEquals, imported)
+
+	c.Assert(mgr.Stop(), IsNil)
+}
+
+func (s *apparmorpromptingSuite) TestImportRulesErrors(c *C) {
+	_, _, restore := apparmorprompting.MockListener()
+	defer restore()
+
+	plugPaths := func(userID uint32, snap string, iface string) ([]string, error) {
+		return []string{"/home/test/.config/foo"}, nil
+	}
+	mgr, err := apparmorprompting.New(s.noticeMgr, plugPaths)
+	c.Assert(err, IsNil)
+
+	valid := &requestrules.PortableRule{
+		Snap:      "firefox",
+		Interface: "home",
+		Constraints: prompting.ConstraintsJSON{
+			"path-pattern": json.RawMessage(`"/home/test/Downloads/**"`),
+			"permissions":  json.RawMessage(`{"read":{"outcome":"allow","lifespan":"forever"}}`),
+		},
+	}
+
+	for _, testCase := range []struct {
+		rule   *requestrules.PortableRule
+		errStr string
+	}{
+		{
+			rule: &requestrules.PortableRule{
+				Snap:      "firefox",
+				Interface: "home",
+				Constraints: prompting.ConstraintsJSON{
+					"path-pattern": json.RawMessage(`"foo"`),
+					"permissions":  json.RawMessage(`{"read":{"outcome":"allow","lifespan":"forever"}}`),
+				},
+			},
+			errStr: `cannot import rule 1: invalid path pattern: pattern must start with '/': "foo"`,
+		},
+		{
+			rule: &requestrules.PortableRule{
+				Snap:      "firefox",
+				Interface: "personal-files",
+				Constraints: prompting.ConstraintsJSON{
+					"path-pattern": json.RawMessage(`"/home/test/.bashrc"`),
+					"permissions":  json.RawMessage(`{"read":{"outcome":"allow","lifespan":"forever"}}`),
+				},
+			},
+			errStr: `cannot import rule 1: invalid path pattern: pattern matches paths outside of those declared by connected plugs \(/home/test/.config/foo\): "/home/test/.bashrc"`,
+		},
+		{
+			rule: &requestrules.PortableRule{
+				Snap:      "firefox",
+				Interface: "home",
+				Constraints: prompting.ConstraintsJSON{
+					"path-pattern": json.RawMessage(`"/home/test/Downloads/**"`),
+					"permissions":  json.RawMessage(`{"read":{"outcome":"deny","lifespan":"forever"}}`),
+				},
+			},
+			errStr: "cannot import rule 1: " + prompting_errors.ErrRuleConflict.Error(),
+		},
+	} {
+		rules, err := mgr.ImportRules(s.defaultUser, []*requestrules.PortableRule{valid, testCase.rule})
+		c.Check(err, ErrorMatches, testCase.errStr)
+		c.Check(rules, IsNil)
+
+		// No rule was imported
+		rules, err = mgr.Rules(s.defaultUser, "", "")
+		c.Check(err, IsNil)
+		c.Check(rules, HasLen, 0)
+	}
+
+	c.Assert(mgr.Stop(), IsNil)
+}
+
+func (s *apparmorpromptingSuite) TestHistory(c *C) {
+	_, reqChan, restore := apparmorprompting.MockListener()
+	defer restore()
+
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
+	c.Assert(err, IsNil)
+
+	// Reply once to a first request
+	req1, replyChan1 := requestWithReplyChan(&prompting.Request{Path: "/home/test/foo"})
+	_, prompt1 := s.simulateRequest(c, reqChan, mgr, req1, false)
+	constraintsJSON := prompting.ConstraintsJSON{
+		"path-pattern": json.RawMessage(`"/home/test/foo"`),
+		"permissions":  json.RawMessage(`["read"]`),
+	}
+	clientActivity := true
+	_, err = mgr.HandleReply(s.defaultUser, prompt1.ID, constraintsJSON, prompting.OutcomeDeny, prompting.LifespanSingle, "", clientActivity)
+	c.Assert(err, IsNil)
+	_, err = waitForReply(replyChan1)
+	c.Assert(err, IsNil)
+
+	// Reply to a second request with a reply which creates a rule
+	req2, replyChan2 := requestWithReplyChan(&prompting.Request{Path: "/home/test/bar"})
+	_, prompt2 := s.simulateRequest(c, reqChan, mgr, req2, false)
+	// A third request is satisfied by that rule
+	req3, replyChan3 := requestWithReplyChan(&prompting.Request{Path: "/home/test/baz", Snap: "firefox"})
+	_, prompt3 := s.simulateRequest(c, reqChan, mgr, req3, false)
+	constraintsJSON["path-pattern"] = json.RawMessage(`"/home/test/ba{r,z}"`)
+	satisfied, err := mgr.HandleReply(s.defaultUser, prompt2.ID, constraintsJSON, prompting.OutcomeAllow, prompting.LifespanForever, "", clientActivity)
+	c.Assert(err, IsNil)
+	c.Check(satisfied, DeepEquals, []prompting.IDType{prompt3.ID})
+	_, err = waitForReply(replyChan2)
+	c.Assert(err, IsNil)
+	_, err = waitForReply(replyChan3)
+	c.Assert(err, IsNil)
+
+	rules, err := mgr.Rules(s.defaultUser, "", "")
+	c.Assert(err, IsNil)
+	c.Assert(rules, HasLen, 1)
+
+	history, err := mgr.History(s.defaultUser, nil)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+
+	c.Check(history[0].PromptID, Equals, prompt1.ID)
+	c.Check(history[0].Resolution, Equals, requestprompts.ResolutionReplied)
+	c.Check(history[0].Outcome, Equals, prompting.OutcomeDeny)
+	c.Check(history[0].Lifespan, Equals, prompting.LifespanSingle)
+	c.Check(history[0].DeniedPermissions, DeepEquals, []string{"read"})
+	c.Check(history[0].RuleID, Equals, prompting.IDType(0))
+
+	c.Check(history[1].PromptID, Equals, prompt2.ID)
+	c.Check(history[1].Resolution, Equals, requestprompts.ResolutionReplied)
+	c.Check(history[1].Outcome, Equals, prompting.OutcomeAllow)
+	c.Check(history[1].Lifespan, Equals, prompting.LifespanForever)
+	c.Check(history[1].AllowedPermissions, DeepEquals, []string{"read"})
+	c.Check(history[1].RuleID, Equals, rules[0].ID)
+
+	c.Check(history[2].PromptID, Equals, prompt3.ID)
+	c.Check(history[2].Resolution, Equals, requestprompts.ResolutionSatisfied)
+	c.Check(history[2].Outcome, Equals, prompting.OutcomeAllow)
+	c.Check(history[2].RuleID, Equals, rules[0].ID)
+
+	history, err = mgr.History(s.defaultUser, &requestprompts.HistoryFilter{Interface: "camera"})
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+
+	c.Assert(mgr.Stop(), IsNil)
+}
+
+func (s *apparmorpromptingSuite) TestCheckRequest(c *C) {
+	_, _, restore := apparmorprompting.MockListener()
+	defer restore()
+
+	mgr, rules := s.prepManagerWithRules(c)
+
+	check, err := mgr.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/1", []string{"read", "write"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionPrompt)
+	c.Assert(check.Permissions, HasLen, 2)
+	c.Check(check.Permissions[0].Decision, Equals, requestrules.CheckDecisionAllow)
+	c.Check(check.Permissions[0].Source, Equals, requestrules.CheckSourceUser)
+	c.Check(check.Permissions[0].Variant, Equals, "/home/test/1")
+	c.Check(check.Permissions[0].RuleIDs, DeepEquals, []prompting.IDType{rules[0].ID})
+	c.Check(check.Permissions[1].Decision, Equals, requestrules.CheckDecisionPrompt)
+
+	// The rules of other users are not considered
+	check, err = mgr.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/4", []string{"read"})
+	c.Assert(err, IsNil)
+	c.Check(check.Decision, Equals, requestrules.CheckDecisionPrompt)
+
+	// Checking a request does not create a prompt
+	prompts, err := mgr.Prompts(s.defaultUser, false)
+	c.Assert(err, IsNil)
+	c.Check(prompts, HasLen, 0)
+
+	_, err = mgr.CheckRequest(s.defaultUser, "firefox", "home", "/home/test/1", []string{"access"})
+	c.Check(err, ErrorMatches, `invalid permissions for home interface: "access"`)
+
+	c.Assert(mgr.Stop(), IsNil)
+}
+
 func (s *apparmorpromptingSuite) TestListenerReadyAfterPromptsReady(c *C) {
 	listenerReady, _, restore := apparmorprompting.MockListener()
 	defer restore()
@@ -1713,7 +2119,7 @@ func (s *apparmorpromptingSuite) TestListenerReadyAfterPromptsReady(c *C) {
 	logbuf, restore := logger.MockDebugLogger()
 	defer restore()
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	select {
@@ -1754,7 +2160,7 @@ func (s *apparmorpromptingSuite) TestListenerReadyAfterPromptsNotReady(c *C) {
 	c.Assert(os.MkdirAll(dirs.SnapInterfacesRequestsRunDir, 0o777), IsNil)
 	c.Assert(osutil.AtomicWriteFile(requestMapFilepath, []byte(requestMapping), 0o600, 0), IsNil)
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	select {
@@ -1798,7 +2204,7 @@ func (s *apparmorpromptingSuite) TestListenerReadyCausesPromptsHandleReadyingIfN
 	c.Assert(os.MkdirAll(dirs.SnapInterfacesRequestsRunDir, 0o777), IsNil)
 	c.Assert(osutil.AtomicWriteFile(requestMapFilepath, []byte(requestMapping), 0o600, 0), IsNil)
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Check that the prompts are not ready yet
@@ -1843,7 +2249,7 @@ func (s *apparmorpromptingSuite) TestListenerReadyCausesPromptsHandleReadyingIfO
 	c.Assert(os.MkdirAll(dirs.SnapInterfacesRequestsRunDir, 0o777), IsNil)
 	c.Assert(osutil.AtomicWriteFile(requestMapFilepath, []byte(requestMapping), 0o600, 0), IsNil)
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Check that the prompts are not ready yet
@@ -1913,7 +2319,7 @@ func (s *apparmorpromptingSuite) TestListenerReadyNotCausesPromptsHandleReadying
 	c.Assert(os.MkdirAll(dirs.SnapInterfacesRequestsRunDir, 0o777), IsNil)
 	c.Assert(osutil.AtomicWriteFile(requestMapFilepath, []byte(requestMapping), 0o600, 0), IsNil)
 
-	mgr, err := apparmorprompting.New(s.noticeMgr)
+	mgr, err := apparmorprompting.New(s.noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	// Check that the prompts are not ready yet
@@ -2081,7 +2487,7 @@ func (s *apparmorpromptingSuite) testReadyBlocks(c *C, f func(mgr *apparmorpromp
 	st := state.New(nil)
 	noticeMgr := notices.NewNoticeManager(st)
 
-	mgr, err := apparmorprompting.New(noticeMgr)
+	mgr, err := apparmorprompting.New(noticeMgr, nil)
 	c.Assert(err, IsNil)
 
 	startChan := make(chan time.Time)
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil/user"
 	"github.com/snapcore/snapd/overlord/ifacestate/apparmorprompting"
 	"github.com/snapcore/snapd/overlord/ifacestate/schema"
 	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
@@ -52,6 +53,7 @@ var (
 	SetHotplugAttrs              = setHotplugAttrs
 	GetHotplugSlots              = getHotplugSlots
 	SetHotplugSlots              = setHotplugSlots
+	SuperPrivilegedInterface     = superPrivilegedInterface
 	UpdateDevice                 = updateDevice
 	FindConnsForHotplugKey       = findConnsForHotplugKey
 	CheckSystemSnapIsPresent     = checkSystemSnapIsPresent
@@ -63,6 +65,7 @@ var (
 	AddHotplugSeqWaitTask        = addHotplugSeqWaitTask
 	AddHotplugSlot               = addHotplugSlot
 	HasActiveConnection          = hasActiveConnection
+	LogConnectionChange          = logConnectionChange
 
 	BatchConnectTasks                = batchConnectTasks
 	FirstTaskAfterBootWhenPreseeding = firstTaskAfterBootWhenPreseeding
@@ -136,7 +139,7 @@ func MockCreateUDevMonitor(new func(udevmonitor.DeviceAddedFunc, udevmonitor.Dev
 	}
 }
 
-func MockCreateInterfacesRequestsManager(new func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error)) (restore func()) {
+func MockCreateInterfacesRequestsManager(new func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error)) (restore func()) {
 	return testutil.Mock(&createInterfacesRequestsManager, new)
 }
 
@@ -144,6 +147,10 @@ func MockInterfacesRequestsManagerShutDown(new func(m *apparmorprompting.Interfa
 	return testutil.Mock(&interfacesRequestsManagerShutDown, new)
 }
 
+func MockInterfacesRequestsManagerPolicyError(new func(m *apparmorprompting.InterfacesRequestsManager) error) (restore func()) {
+	return testutil.Mock(&interfacesRequestsManagerPolicyError, new)
+}
+
 func MockInterfacesRequestsManagerStop(new func(m *apparmorprompting.InterfacesRequestsManager) error) (restore func()) {
 	return testutil.Mock(&interfacesRequestsManagerStop, new)
 }
@@ -163,6 +170,21 @@ func CallInterfacesRequestsControlHandlerServicePresent(s *state.State) (bool, e
 	return interfacesRequestsControlHandlerServicePresent(manager)
 }
 
+var InterfacesRequestsPlugPaths = interfacesRequestsPlugPaths
+
+// ManagerInterfacesRequestsPlugPaths returns the plug paths lookup which the
+// interfaces manager hands to the interfaces requests manager.
+func ManagerInterfacesRequestsPlugPaths(s *state.State) func(userID uint32, snapName, iface string) ([]string, error) {
+	manager := &InterfaceManager{
+		state: s,
+	}
+	return manager.interfacesRequestsPlugPaths
+}
+
+func MockUserLookupId(new func(uid string) (*user.User, error)) (restore func()) {
+	return testutil.Mock(&userLookupId, new)
+}
+
 func MockUDevInitRetryTimeout(t time.Duration) (restore func()) {
 	old := udevInitRetryTimeout
 	udevInitRetryTimeout = t
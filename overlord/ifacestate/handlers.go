@@ -1048,6 +1048,8 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) (err error)
 	// the dynamic attributes might have been updated by the interface's BeforeConnectPlug/Slot code,
 	// so we need to update the task for connect-plug- and connect-slot- hooks to see new values.
 	setDynamicHookAttributes(task, conn.Plug.DynamicAttrs(), conn.Slot.DynamicAttrs())
+
+	logConnectionChange(task, connRef, conn.Interface(), autoConnect, true)
 	return nil
 }
 
@@ -1164,6 +1166,7 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 	}
 	setConns(st, conns)
 
+	logConnectionChange(task, &cref, conn.Interface, autoDisconnect || byHotplug, false)
 	return nil
 }
 
@@ -1270,6 +1273,7 @@ func (m *InterfaceManager) undoDisconnect(task *state.Task, _ *tomb.Tomb) error
 	conns[connRef.ID()] = &oldconn
 	setConns(st, conns)
 
+	logConnectionChange(task, connRef, oldconn.Interface, oldconn.Auto, true)
 	return nil
 }
 
@@ -1296,6 +1300,7 @@ func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
 	if err != nil && !errors.Is(err, state.ErrNoState) {
 		return err
 	}
+	conn := conns[connRef.ID()]
 	if err == nil {
 		conns[connRef.ID()] = &old
 	} else {
@@ -1306,6 +1311,9 @@ func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
 	if err := m.repo.Disconnect(connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name); err != nil {
 		return err
 	}
+	if conn != nil {
+		logConnectionChange(task, &connRef, conn.Interface, conn.Auto, false)
+	}
 
 	var delayedSetupProfiles bool
 	if err := task.Get("delayed-setup-profiles", &delayedSetupProfiles); err != nil && !errors.Is(err, state.ErrNoState) {
@@ -38,10 +38,12 @@ import (
 	"github.com/snapcore/snapd/jsonutil"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/ifacestate/schema"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/seclog"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timings"
@@ -1293,6 +1295,7 @@ func setConns(st *state.State, conns map[string]*schema.ConnState) {
 		remapped[cref.ID()] = cstate
 	}
 	st.Set("conns", remapped)
+	invalidatePlugPathsCache(st)
 }
 
 // snapsWithSecurityProfiles returns all snaps that have active
@@ -1780,3 +1783,72 @@ func hasAppArmorBackend(backends []interfaces.SecurityBackend) bool {
 	}
 	return false
 }
+
+// superPrivilegedInterface returns whether the base declaration prevents the
+// installation of plugs or slots of the given interface unless a snap
+// declaration grants them, that is for interfaces with
+// "allow-installation: false" or "deny-installation: true" rules.
+func superPrivilegedInterface(baseDecl *asserts.BaseDeclaration, iface string) bool {
+	if rule := baseDecl.PlugRule(iface); rule != nil {
+		for _, cstrs := range rule.AllowInstallation {
+			if cstrs.PlugAttributes == asserts.NeverMatchAttributes {
+				return true
+			}
+		}
+		for _, cstrs := range rule.DenyInstallation {
+			if cstrs.PlugAttributes == asserts.AlwaysMatchAttributes {
+				return true
+			}
+		}
+	}
+	if rule := baseDecl.SlotRule(iface); rule != nil {
+		for _, cstrs := range rule.AllowInstallation {
+			if cstrs.SlotAttributes == asserts.NeverMatchAttributes {
+				return true
+			}
+		}
+		for _, cstrs := range rule.DenyInstallation {
+			if cstrs.SlotAttributes == asserts.AlwaysMatchAttributes {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// logConnectionChange logs the security audit event of the connection or
+// disconnection done by the given task. The user is the one recorded as
+// "user-id" by the change, if any.
+func logConnectionChange(task *state.Task, connRef *interfaces.ConnRef, iface string, auto, connected bool) {
+	st := task.State()
+
+	var userID int
+	var changeID string
+	if chg := task.Change(); chg != nil {
+		changeID = chg.ID()
+		if err := chg.Get("user-id", &userID); err != nil && !errors.Is(err, state.ErrNoState) {
+			logger.Noticef("cannot get user of change %s: %v", changeID, err)
+		}
+	}
+
+	conn := seclog.Connection{
+		Interface: iface,
+		PlugSnap:  connRef.PlugRef.Snap,
+		PlugName:  connRef.PlugRef.Name,
+		SlotSnap:  connRef.SlotRef.Snap,
+		SlotName:  connRef.SlotRef.Name,
+		Auto:      auto,
+	}
+	if baseDecl, err := assertstate.BaseDeclaration(st); err == nil {
+		conn.SuperPrivileged = superPrivilegedInterface(baseDecl, iface)
+	} else {
+		logger.Noticef("cannot find base declaration: %v", err)
+	}
+
+	user := auth.SecurityLogUser(st, userID)
+	if connected {
+		seclog.LogInterfaceConnected(user, conn, changeID)
+	} else {
+		seclog.LogInterfaceDisconnected(user, conn, changeID)
+	}
+}
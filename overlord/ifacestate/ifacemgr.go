@@ -75,6 +75,7 @@ type InterfaceManager struct {
 	useAppArmorPrompting        bool
 	interfacesRequestsManagerMu sync.Mutex
 	interfacesRequestsManager   *apparmorprompting.InterfacesRequestsManager
+	plugPathsCache              plugPathsCache
 
 	preseed bool
 }
@@ -261,6 +262,10 @@ func (m *InterfaceManager) StartUp() error {
 			// user intends for prompting to be enabled, but do record a
 			// warning so the user knows prompting is not current running.
 			m.state.AddWarning(fmt.Sprintf("cannot start prompting backend: %v; prompting will be inactive until snapd is restarted", err), nil)
+		} else if err := interfacesRequestsManagerPolicyError(m.interfacesRequestsManager); err != nil {
+			// The requests are denied rather than allowed regardless of
+			// the policy the administrator meant to set.
+			m.state.AddWarning(fmt.Sprintf("cannot load prompting policy rules: %v; all requests will be denied until the policy file is fixed and snapd is restarted", err), nil)
 		}
 	}
 	if m.profilesNeedRegeneration() {
@@ -330,6 +335,12 @@ func (m *InterfaceManager) Ensure() error {
 	return nil
 }
 
+// interfacesRequestsManagerPolicyError returns the error which prevented the
+// given manager from loading the policy rules, if any.
+var interfacesRequestsManagerPolicyError = func(interfacesRequestsManager *apparmorprompting.InterfacesRequestsManager) error {
+	return interfacesRequestsManager.PolicyError()
+}
+
 // interfacesRequestsManagerShutDown calls shutdown on the given manager.
 var interfacesRequestsManagerShutDown = func(interfacesRequestsManager *apparmorprompting.InterfacesRequestsManager) {
 	interfacesRequestsManager.ShutDown()
@@ -650,7 +661,7 @@ var interfacesRequestsControlHandlerServicePresent = func(m *InterfaceManager) (
 func (m *InterfaceManager) initInterfacesRequestsManager() error {
 	m.interfacesRequestsManagerMu.Lock()
 	defer m.interfacesRequestsManagerMu.Unlock()
-	interfacesRequestsManager, err := createInterfacesRequestsManager(m.noticeManager)
+	interfacesRequestsManager, err := createInterfacesRequestsManager(m.noticeManager, m.interfacesRequestsPlugPaths)
 	if err != nil {
 		return err
 	}
@@ -658,6 +669,25 @@ func (m *InterfaceManager) initInterfacesRequestsManager() error {
 	return nil
 }
 
+// interfacesRequestsPlugPaths returns the paths declared by the connected
+// plugs of the given interface of the given snap, as they apply to the given
+// user. The interfaces requests manager calls it without holding the state
+// lock, which is only taken when the paths are not cached yet or the
+// connections changed since they were cached.
+func (m *InterfaceManager) interfacesRequestsPlugPaths(userID uint32, snapName, iface string) ([]string, error) {
+	declared, ok := m.plugPathsCache.lookup(snapName, iface)
+	if !ok {
+		var err error
+		m.state.Lock()
+		declared, err = m.plugPathsCache.refresh(m.state, snapName, iface)
+		m.state.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return expandPlugPaths(userID, declared)
+}
+
 var securityBackendsOverride []interfaces.SecurityBackend
 
 // allSecurityBackends returns a set of the available security backends or the mocked ones, ready to be initialized.
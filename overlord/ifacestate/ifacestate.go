@@ -24,20 +24,27 @@ package ifacestate
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/policy"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil/user"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/swfeats"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
 )
 
 var connectRetryTimeout = time.Second * 5
@@ -651,6 +658,148 @@ func InterfacesRequestsControlHandlerServices(st *state.State) ([]*snap.AppInfo,
 	return handlers, nil
 }
 
+var userLookupId = user.LookupId
+
+// interfacesRequestsPlugPaths returns the paths declared by the connected plugs
+// of the given interface of the given snap, with $HOME expanded to the home
+// directory of the given user, for interfaces which only grant access to the
+// paths declared by their plugs.
+//
+// The caller must ensure that the given state is locked.
+func interfacesRequestsPlugPaths(st *state.State, userID uint32, snapName, iface string) ([]string, error) {
+	declared, err := declaredPlugPaths(st, snapName, iface)
+	if err != nil {
+		return nil, err
+	}
+	return expandPlugPaths(userID, declared)
+}
+
+// declaredPlugPaths returns the paths, as declared and without expanding
+// $HOME, of the connected plugs of the given interface of the given snap.
+//
+// The caller must ensure that the given state is locked.
+func declaredPlugPaths(st *state.State, snapName, iface string) ([]string, error) {
+	conns, err := ConnectionStates(st)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot get connections: %w", err)
+	}
+
+	var paths []string
+	for connId, connState := range conns {
+		if connState.Interface != iface || !connState.Active() {
+			continue
+		}
+
+		connRef, err := interfaces.ParseConnRef(connId)
+		if err != nil {
+			return nil, err
+		}
+		if connRef.PlugRef.Snap != snapName {
+			continue
+		}
+
+		switch iface {
+		case "removable-media":
+			paths = append(paths, builtin.RemovableMediaPaths...)
+		case "personal-files":
+			// the attributes were validated by BeforePreparePlug
+			for _, attr := range []string{"read", "write"} {
+				declared, _ := connState.StaticPlugAttrs[attr].([]any)
+				for _, path := range declared {
+					if path, ok := path.(string); ok {
+						paths = append(paths, path)
+					}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("internal error: interface %q does not declare paths in its plugs", iface)
+		}
+	}
+	return paths, nil
+}
+
+// expandPlugPaths returns a sorted copy of the given declared paths, with
+// $HOME expanded to the home directory of the given user.
+func expandPlugPaths(userID uint32, declared []string) ([]string, error) {
+	paths := make([]string, 0, len(declared))
+	var home string
+	for _, path := range declared {
+		if strings.HasPrefix(path, "$HOME/") {
+			if home == "" {
+				u, err := userLookupId(strconv.FormatUint(uint64(userID), 10))
+				if err != nil {
+					return nil, fmt.Errorf("cannot get home directory of user %d: %w", userID, err)
+				}
+				home = u.HomeDir
+			}
+			path = filepath.Join(home, strings.TrimPrefix(path, "$HOME/"))
+		}
+		paths = append(paths, path)
+	}
+	paths = strutil.Deduplicate(paths)
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+type plugPathsCacheKey struct{}
+
+// plugPathsCache caches the declared plug paths looked up by the interfaces
+// requests manager, so that it does not need to take the state lock for
+// every request. It is registered in the state cache so that setConns can
+// invalidate it whenever the connections change.
+type plugPathsCache struct {
+	mu sync.Mutex
+	// indexed by snap name and interface name
+	paths map[string]map[string][]string
+}
+
+// lookup returns the cached declared paths of the given interface of the
+// given snap, if any.
+func (c *plugPathsCache) lookup(snapName, iface string) (paths []string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	paths, ok = c.paths[snapName][iface]
+	return paths, ok
+}
+
+// refresh looks up the declared paths of the given interface of the given
+// snap in the state and caches them.
+//
+// The caller must ensure that the given state is locked.
+func (c *plugPathsCache) refresh(st *state.State, snapName, iface string) ([]string, error) {
+	st.Cache(plugPathsCacheKey{}, c)
+
+	paths, err := declaredPlugPaths(st, snapName, iface)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paths == nil {
+		c.paths = make(map[string]map[string][]string)
+	}
+	if c.paths[snapName] == nil {
+		c.paths[snapName] = make(map[string][]string)
+	}
+	c.paths[snapName][iface] = paths
+	return paths, nil
+}
+
+// invalidatePlugPathsCache drops the cached declared plug paths, if any.
+//
+// The caller must ensure that the given state is locked.
+func invalidatePlugPathsCache(st *state.State) {
+	c, ok := st.Cached(plugPathsCacheKey{}).(*plugPathsCache)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paths = nil
+}
+
 // AdviseReportedSystemKeyMismatch inspects the system key, which is reportedly
 // in a mismatch with the recoded one, and decides to either create a state
 // change for regenerating security profiles, thus returning a change, or do
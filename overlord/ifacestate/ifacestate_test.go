@@ -43,8 +43,10 @@ import (
 	"github.com/snapcore/snapd/interfaces/ifacetest"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/osutil/user"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/confdbstate"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/devicestate"
@@ -52,6 +54,7 @@ import (
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/ifacestate/apparmorprompting"
 	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
+	"github.com/snapcore/snapd/overlord/ifacestate/schema"
 	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
 	"github.com/snapcore/snapd/overlord/notices"
 	"github.com/snapcore/snapd/overlord/restart"
@@ -62,6 +65,8 @@ import (
 	"github.com/snapcore/snapd/overlord/swfeats/swfeatstest"
 	"github.com/snapcore/snapd/release"
 	seccomp_compiler "github.com/snapcore/snapd/sandbox/seccomp"
+	"github.com/snapcore/snapd/seclog"
+	"github.com/snapcore/snapd/seclog/seclogtest"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -323,9 +328,12 @@ slots:
 
 	s.BaseTest.AddCleanup(ifacestate.MockCreateInterfacesRequestsManager(fakeCreateInterfacesRequestsManager))
 	s.BaseTest.AddCleanup(ifacestate.MockInterfacesRequestsManagerStop(fakeInterfacesRequestsManagerStop))
+	s.BaseTest.AddCleanup(ifacestate.MockInterfacesRequestsManagerPolicyError(func(m *apparmorprompting.InterfacesRequestsManager) error {
+		return nil
+	}))
 }
 
-var fakeCreateInterfacesRequestsManager = func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error) {
+var fakeCreateInterfacesRequestsManager = func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
 	return nil, nil
 }
 
@@ -406,7 +414,7 @@ func (s *interfaceManagerSuite) TestSmokeAppArmorPromptingEnabled(c *C) {
 	defer restore()
 	createCount := 0
 	fakeManager := &apparmorprompting.InterfacesRequestsManager{}
-	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error) {
+	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
 		createCount++
 		return fakeManager, nil
 	})
@@ -438,6 +446,39 @@ func (s *interfaceManagerSuite) TestSmokeAppArmorPromptingEnabled(c *C) {
 	c.Check(mgr.InterfacesRequestsManager(), testutil.IsInterfaceNil)
 }
 
+func (s *interfaceManagerSuite) TestSmokeAppArmorPromptingPolicyError(c *C) {
+	restore := ifacestate.MockAssessAppArmorPrompting(func(m *ifacestate.InterfaceManager) bool {
+		return true
+	})
+	defer restore()
+	restore = ifacestate.MockInterfacesRequestsControlHandlerServicePresent(func(m *ifacestate.InterfaceManager) (bool, error) {
+		return true, nil
+	})
+	defer restore()
+	fakeManager := &apparmorprompting.InterfacesRequestsManager{}
+	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
+		return fakeManager, nil
+	})
+	defer restore()
+	restore = ifacestate.MockInterfacesRequestsManagerPolicyError(func(m *apparmorprompting.InterfacesRequestsManager) error {
+		c.Check(m, Equals, fakeManager)
+		return fmt.Errorf("cannot decode policy file: unexpected EOF")
+	})
+	defer restore()
+
+	mgr := s.manager(c)
+
+	// prompting keeps running, denying the requests
+	c.Check(mgr.AppArmorPromptingRunning(), Equals, true)
+	c.Check(mgr.InterfacesRequestsManager(), Equals, fakeManager)
+
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Equals, "cannot load prompting policy rules: cannot decode policy file: unexpected EOF; all requests will be denied until the policy file is fixed and snapd is restarted")
+
+	mgr.Stop()
+}
+
 func (s *interfaceManagerSuite) TestSmokeAppArmorPromptingDisabled(c *C) {
 	restore := ifacestate.MockAssessAppArmorPrompting(func(m *ifacestate.InterfaceManager) bool {
 		return false
@@ -452,7 +493,7 @@ func (s *interfaceManagerSuite) TestSmokeAppArmorPromptingDisabled(c *C) {
 	defer restore()
 	createCount := 0
 	fakeManager := &apparmorprompting.InterfacesRequestsManager{}
-	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error) {
+	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
 		c.Errorf("unexpectedly called m.initInterfacesRequestsManager")
 		createCount++
 		return fakeManager, nil
@@ -1572,6 +1613,153 @@ slots:
 	check(change)
 }
 
+func (s *interfaceManagerSuite) TestConnectDisconnectSecurityLog(c *C) {
+	buf := &bytes.Buffer{}
+	seclog.Setup(seclogtest.MockSecurityLogger(buf))
+	defer seclog.Setup(seclog.NewNopLogger())
+
+	s.MockModel(c, nil)
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	s.state.Lock()
+	user, err := auth.NewUser(s.state, auth.NewUserParams{
+		Username: "username",
+		Email:    "email@test.com",
+	})
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("connect-snap", "...")
+	chg.Set("user-id", user.ID)
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+	c.Check(buf.String(), testutil.Contains, fmt.Sprintf("iface_connected User %d:email@test.com:username connected consumer:plug producer:slot (test)", user.ID))
+	c.Check(buf.String(), testutil.Contains, `[connection=seclog.Connection{Interface:"test", PlugSnap:"consumer", PlugName:"plug", SlotSnap:"producer", SlotName:"slot", Auto:false, SuperPrivileged:false}]`)
+	c.Check(buf.String(), testutil.Contains, fmt.Sprintf(`[change_id="%s"]`, chg.ID()))
+	buf.Reset()
+
+	// no user recorded by the change
+	chg = s.state.NewChange("disconnect", "...")
+	conn, err := s.manager(c).Repository().Connection(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	})
+	c.Assert(err, IsNil)
+	ts, err = ifacestate.Disconnect(s.state, conn)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Err(), IsNil)
+	c.Check(buf.String(), testutil.Contains, "iface_disconnected User <unknown>:<unknown>:<unknown> disconnected consumer:plug producer:slot (test)")
+	c.Check(buf.String(), testutil.Contains, fmt.Sprintf(`[change_id="%s"]`, chg.ID()))
+}
+
+func (s *interfaceManagerSuite) TestConnectDisconnectUndoSecurityLog(c *C) {
+	buf := &bytes.Buffer{}
+	seclog.Setup(seclogtest.MockSecurityLogger(buf))
+	defer seclog.Setup(seclog.NewNopLogger())
+
+	s.MockModel(c, nil)
+	s.mockIfaces(&ifacetest.TestInterface{InterfaceName: "test"}, &ifacetest.TestInterface{InterfaceName: "test2"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	_ = s.manager(c)
+
+	s.state.Lock()
+	chg := s.state.NewChange("connect-snap", "...")
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(strings.Count(buf.String(), "\n"), Equals, 2)
+	c.Check(buf.String(), testutil.Contains, "iface_connected User <unknown>:<unknown>:<unknown> connected consumer:plug producer:slot (test)")
+	// the undo is logged after the connection
+	c.Check(buf.String(), Matches, `(?s)iface_connected .*\niface_disconnected User <unknown>:<unknown>:<unknown> disconnected consumer:plug producer:slot \(test\).*`)
+
+	// connect for real, then undo a disconnect
+	chg = s.state.NewChange("connect-snap", "...")
+	ts, err = ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	c.Assert(chg.Err(), IsNil)
+	buf.Reset()
+
+	chg = s.state.NewChange("disconnect", "...")
+	conn, err := s.manager(c).Repository().Connection(&interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	})
+	c.Assert(err, IsNil)
+	ts, err = ifacestate.Disconnect(s.state, conn)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	terr = s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(buf.String(), Matches, `(?s)iface_disconnected .*\niface_connected User <unknown>:<unknown>:<unknown> connected consumer:plug producer:slot \(test\).*`)
+	c.Check(buf.String(), testutil.Contains, fmt.Sprintf(`[change_id="%s"]`, chg.ID()))
+}
+
+func (s *interfaceManagerSuite) TestLogConnectionChangeWithoutChange(c *C) {
+	buf := &bytes.Buffer{}
+	seclog.Setup(seclogtest.MockSecurityLogger(buf))
+	defer seclog.Setup(seclog.NewNopLogger())
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// tasks which are not part of a change do not record a user
+	task := s.state.NewTask("connect", "...")
+	connRef := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}
+	ifacestate.LogConnectionChange(task, connRef, "test", false, true)
+	c.Check(buf.String(), testutil.Contains, "iface_connected User <unknown>:<unknown>:<unknown> connected consumer:plug producer:slot (test)")
+	c.Check(buf.String(), testutil.Contains, `[change_id=""]`)
+}
+
+func (s *interfaceManagerSuite) TestSuperPrivilegedInterface(c *C) {
+	baseDecl := asserts.BuiltinBaseDeclaration()
+	c.Assert(baseDecl, NotNil)
+
+	c.Check(ifacestate.SuperPrivilegedInterface(baseDecl, "docker-support"), Equals, true)
+	c.Check(ifacestate.SuperPrivilegedInterface(baseDecl, "snapd-control"), Equals, true)
+	c.Check(ifacestate.SuperPrivilegedInterface(baseDecl, "network"), Equals, false)
+	c.Check(ifacestate.SuperPrivilegedInterface(baseDecl, "unknown"), Equals, false)
+}
+
 func (s *interfaceManagerSuite) TestConnectTaskCheckDeviceScopeNoStore(c *C) {
 	s.MockModel(c, nil)
 
@@ -7876,7 +8064,7 @@ func (s *interfaceManagerSuite) TestInterfacesRequestsManagerNoHandlerService(c
 
 	createCount := 0
 	fakeManager := &apparmorprompting.InterfacesRequestsManager{}
-	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error) {
+	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
 		createCount++
 		return fakeManager, nil
 	})
@@ -7923,7 +8111,7 @@ func (s *interfaceManagerSuite) TestInterfacesRequestsManagerHandlerServicePrese
 
 	createCount := 0
 	fakeManager := &apparmorprompting.InterfacesRequestsManager{}
-	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error) {
+	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
 		createCount++
 		return fakeManager, nil
 	})
@@ -7964,7 +8152,7 @@ func (s *interfaceManagerSuite) TestInitInterfacesRequestsManagerError(c *C) {
 	defer restore()
 
 	createError := fmt.Errorf("custom error")
-	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error) {
+	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
 		return nil, createError
 	})
 	defer restore()
@@ -8011,7 +8199,7 @@ func (s *interfaceManagerSuite) TestShutDownInterfacesRequestsManager(c *C) {
 	})
 	defer restore()
 	fakeManager := &apparmorprompting.InterfacesRequestsManager{}
-	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error) {
+	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
 		return fakeManager, nil
 	})
 	defer restore()
@@ -8035,7 +8223,7 @@ func (s *interfaceManagerSuite) TestStopInterfacesRequestsManagerError(c *C) {
 	})
 	defer restore()
 	fakeManager := &apparmorprompting.InterfacesRequestsManager{}
-	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager) (*apparmorprompting.InterfacesRequestsManager, error) {
+	restore = ifacestate.MockCreateInterfacesRequestsManager(func(noticeMgr *notices.NoticeManager, plugPaths apparmorprompting.PlugPathsFunc) (*apparmorprompting.InterfacesRequestsManager, error) {
 		return fakeManager, nil
 	})
 	defer restore()
@@ -12021,6 +12209,136 @@ func (s *interfaceManagerSuite) TestInterfacesRequestsControlHandlerServicesDisc
 	c.Check(present, Equals, false)
 }
 
+func (s *interfaceManagerSuite) TestInterfacesRequestsPlugPaths(c *C) {
+	var lookups []string
+	restore := ifacestate.MockUserLookupId(func(uid string) (*user.User, error) {
+		lookups = append(lookups, uid)
+		return &user.User{Uid: uid, HomeDir: "/home/test"}, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("conns", map[string]any{
+		"test-snap:dot-foo core:personal-files": map[string]any{
+			"interface": "personal-files",
+			"plug-static": map[string]any{
+				"read":  []any{"$HOME/.foo", "/etc/foo"},
+				"write": []any{"$HOME/.foo/cache"},
+			},
+		},
+		"test-snap:dot-bar core:personal-files": map[string]any{
+			"interface": "personal-files",
+			"plug-static": map[string]any{
+				"read": []any{"$HOME/.bar"},
+			},
+			// manually disconnected
+			"undesired": true,
+		},
+		"other-snap:dot-baz core:personal-files": map[string]any{
+			"interface": "personal-files",
+			"plug-static": map[string]any{
+				"read": []any{"$HOME/.baz"},
+			},
+		},
+		"test-snap:removable-media core:removable-media": map[string]any{
+			"interface": "removable-media",
+		},
+	})
+
+	paths, err := ifacestate.InterfacesRequestsPlugPaths(s.state, 1000, "test-snap", "personal-files")
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []string{"/etc/foo", "/home/test/.foo", "/home/test/.foo/cache"})
+	c.Check(lookups, DeepEquals, []string{"1000"})
+
+	paths, err = ifacestate.InterfacesRequestsPlugPaths(s.state, 1000, "test-snap", "removable-media")
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []string{"/media", "/mnt", "/run/media"})
+
+	paths, err = ifacestate.InterfacesRequestsPlugPaths(s.state, 1000, "unconnected-snap", "personal-files")
+	c.Assert(err, IsNil)
+	c.Check(paths, HasLen, 0)
+
+	s.state.Set("conns", map[string]any{
+		"test-snap:camera core:camera": map[string]any{
+			"interface": "camera",
+		},
+	})
+	_, err = ifacestate.InterfacesRequestsPlugPaths(s.state, 1000, "test-snap", "camera")
+	c.Check(err, ErrorMatches, `internal error: interface "camera" does not declare paths in its plugs`)
+}
+
+func (s *interfaceManagerSuite) TestInterfacesRequestsPlugPathsCached(c *C) {
+	restore := ifacestate.MockUserLookupId(func(uid string) (*user.User, error) {
+		return &user.User{Uid: uid, HomeDir: "/home/test"}, nil
+	})
+	defer restore()
+
+	s.state.Lock()
+	ifacestate.SetConns(s.state, map[string]*schema.ConnState{
+		"test-snap:dot-foo core:personal-files": {
+			Interface:       "personal-files",
+			StaticPlugAttrs: map[string]any{"read": []any{"$HOME/.foo"}},
+		},
+	})
+	s.state.Unlock()
+
+	plugPaths := ifacestate.ManagerInterfacesRequestsPlugPaths(s.state)
+
+	paths, err := plugPaths(1000, "test-snap", "personal-files")
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []string{"/home/test/.foo"})
+
+	// the paths are now cached, so looking them up again does not need the
+	// state lock
+	s.state.Lock()
+	paths, err = plugPaths(1000, "test-snap", "personal-files")
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []string{"/home/test/.foo"})
+
+	// changing the connections drops the cached paths
+	ifacestate.SetConns(s.state, map[string]*schema.ConnState{
+		"test-snap:dot-bar core:personal-files": {
+			Interface:       "personal-files",
+			StaticPlugAttrs: map[string]any{"read": []any{"$HOME/.bar", "/etc/bar"}},
+		},
+	})
+	s.state.Unlock()
+
+	paths, err = plugPaths(1000, "test-snap", "personal-files")
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []string{"/etc/bar", "/home/test/.bar"})
+
+	s.state.Lock()
+	ifacestate.SetConns(s.state, nil)
+	s.state.Unlock()
+
+	paths, err = plugPaths(1000, "test-snap", "personal-files")
+	c.Assert(err, IsNil)
+	c.Check(paths, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestInterfacesRequestsPlugPathsUserError(c *C) {
+	restore := ifacestate.MockUserLookupId(func(uid string) (*user.User, error) {
+		return nil, fmt.Errorf("no such user")
+	})
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("conns", map[string]any{
+		"test-snap:dot-foo core:personal-files": map[string]any{
+			"interface": "personal-files",
+			"plug-static": map[string]any{
+				"read": []any{"$HOME/.foo"},
+			},
+		},
+	})
+
+	_, err := ifacestate.InterfacesRequestsPlugPaths(s.state, 1000, "test-snap", "personal-files")
+	c.Check(err, ErrorMatches, `cannot get home directory of user 1000: no such user`)
+}
+
 func (s *interfaceManagerSuite) mockSnapd(c *C) {
 	const snapdSnapYaml = `
 name: snapd
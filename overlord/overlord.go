@@ -65,6 +65,7 @@ import (
 	// import to register linkNotify callback
 	_ "github.com/snapcore/snapd/overlord/snapstate/agentnotify"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/overlord/storecontext"
 	"github.com/snapcore/snapd/snapdenv"
 	"github.com/snapcore/snapd/store"
@@ -94,7 +95,8 @@ var pruneTickerC = func(t *time.Ticker) <-chan time.Time {
 // Overlord is the central manager of a snappy system, keeping
 // track of all available state managers and related helpers.
 type Overlord struct {
-	stateFLock *osutil.FileLock
+	stateFLock   *osutil.FileLock
+	stateBackend *overlordStateDBBackend
 
 	stateEng *StateEngine
 	// ensure loop
@@ -144,8 +146,8 @@ func New(restartHandler restart.Handler) (*Overlord, error) {
 	// create the loop goroutine
 	o.loopTomb.Go(o.loop)
 
-	backend := &overlordStateBackend{
-		path:         dirs.SnapStateFile,
+	backend := &overlordStateDBBackend{
+		path:         dirs.SnapStateDBFile,
 		ensureBefore: o.ensureBefore,
 	}
 	s, restartMgr, err := o.loadState(backend, restartHandler)
@@ -206,7 +208,7 @@ func New(restartHandler restart.Handler) (*Overlord, error) {
 	deviceMgr.AddOnInit(fdeMgr)
 	o.addManager(deviceMgr)
 
-	o.addManager(clusterstate.Manager(s))
+	o.addManager(clusterstate.Manager(s, o.runner))
 
 	o.addManager(cmdstate.Manager(s, o.runner))
 	o.addManager(snapshotstate.Manager(s, o.runner))
@@ -306,7 +308,7 @@ func lockWithTimeout(l *osutil.FileLock, timeout time.Duration) error {
 	}
 }
 
-func (o *Overlord) loadState(backend state.Backend, restartHandler restart.Handler) (*state.State, *restart.RestartManager, error) {
+func (o *Overlord) loadState(backend *overlordStateDBBackend, restartHandler restart.Handler) (*state.State, *restart.RestartManager, error) {
 	flock, err := initStateFileLock()
 	if err != nil {
 		return nil, nil, fmt.Errorf("fatal: error opening lock file: %v", err)
@@ -327,13 +329,33 @@ func (o *Overlord) loadState(backend state.Backend, restartHandler restart.Handl
 
 	perfTimings := timings.New(map[string]string{"startup": "load-state"})
 
-	if !osutil.FileExists(dirs.SnapStateFile) {
+	// a state file is migrated if there is no state database yet, either
+	// because it is from before the state database or because it was
+	// seeded into the image; otherwise it is the copy kept for a snapd
+	// that predates the state database, which is only migrated if such a
+	// snapd changed it after a revert, leaving the state database stale
+	migrate, err := stateFileNeedsMigration()
+	if err != nil {
+		return nil, nil, err
+	}
+	fresh := !migrate && !osutil.FileExists(dirs.SnapStateDBFile)
+	if fresh {
 		// fail fast, mostly interesting for tests, this dir is setup
 		// by the snapd package
 		stateDir := filepath.Dir(dirs.SnapStateFile)
 		if !osutil.IsDirectory(stateDir) {
 			return nil, nil, fmt.Errorf("fatal: directory %q must be present", stateDir)
 		}
+	}
+	// the state database is kept open until Stop
+	db, err := statedb.Open(dirs.SnapStateDBFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fatal: %v", err)
+	}
+	backend.db = db
+	o.stateBackend = backend
+
+	if fresh {
 		s := state.New(backend)
 		restartMgr, err := initRestart(s, curBootID, restartHandler)
 		if err != nil {
@@ -343,18 +365,24 @@ func (o *Overlord) loadState(backend state.Backend, restartHandler restart.Handl
 		return s, restartMgr, nil
 	}
 
-	r, err := os.Open(dirs.SnapStateFile)
-	if err != nil {
-		return nil, nil, fmt.Errorf("cannot read the state file: %s", err)
-	}
-	defer r.Close()
-
 	var s *state.State
-	timings.Run(perfTimings, "read-state", "read snapd state from disk", func(tm timings.Measurer) {
-		s, err = state.ReadState(backend, r)
-	})
-	if err != nil {
-		return nil, nil, err
+	if migrate {
+		timings.Run(perfTimings, "read-state", "read snapd state from disk", func(tm timings.Measurer) {
+			// the state file is kept for reverting to a snapd that
+			// predates the state database
+			s, err = db.MigrateStateFile(backend, dirs.SnapStateFile)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Noticef("Migrated the state from %s to %s", dirs.SnapStateFile, dirs.SnapStateDBFile)
+	} else {
+		timings.Run(perfTimings, "read-state", "read snapd state from disk", func(tm timings.Measurer) {
+			s, err = db.ReadState(backend)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 	s.Lock()
 	perfTimings.Save(s)
@@ -373,6 +401,31 @@ func (o *Overlord) loadState(backend state.Backend, restartHandler restart.Handl
 	return s, restartMgr, nil
 }
 
+// stateFileNeedsMigration returns whether the state file needs to be
+// migrated into the state database. A stale state database is moved aside
+// rather than overwritten.
+func stateFileNeedsMigration() (bool, error) {
+	if !osutil.FileExists(dirs.SnapStateFile) {
+		return false, nil
+	}
+	if !osutil.FileExists(dirs.SnapStateDBFile) {
+		return true, nil
+	}
+	changed, err := statedb.StateFileChanged(dirs.SnapStateDBFile, dirs.SnapStateFile)
+	if err != nil {
+		return false, err
+	}
+	if !changed {
+		return false, nil
+	}
+	stale := dirs.SnapStateDBFile + ".stale"
+	if err := os.Rename(dirs.SnapStateDBFile, stale); err != nil {
+		return false, fmt.Errorf("cannot move aside stale state database: %v", err)
+	}
+	logger.Noticef("State file %s was changed by a snapd that predates the state database, moved the state database to %s", dirs.SnapStateFile, stale)
+	return true, nil
+}
+
 func initRestart(s *state.State, curBootID string, restartHandler restart.Handler) (*restart.RestartManager, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -571,6 +624,9 @@ func (o *Overlord) Stop() error {
 	o.loopTomb.Kill(nil)
 	err := o.loopTomb.Wait()
 	o.stateEng.Stop()
+	if o.stateBackend != nil && o.stateBackend.db != nil {
+		o.closeStateDB()
+	}
 	if o.stateFLock != nil {
 		// This will also unlock the file
 		o.stateFLock.Close()
@@ -579,6 +635,26 @@ func (o *Overlord) Stop() error {
 	return err
 }
 
+// closeStateDB closes the state database, after writing the state file
+// from it if needed.
+func (o *Overlord) closeStateDB() {
+	// no checkpoint happens meanwhile
+	st := o.State()
+	st.Lock()
+	defer st.Unlock()
+
+	db := o.stateBackend.db
+	// keep the state file current for a snapd that predates the state
+	// database, in case snapd gets reverted to it
+	if err := db.WriteStateFile(dirs.SnapStateFile); err != nil {
+		logger.Noticef("Cannot write the state file for older snapd: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		logger.Noticef("Cannot close the state database: %v", err)
+	}
+	o.stateBackend.db = nil
+}
+
 func (o *Overlord) settle(timeout time.Duration, beforeCleanups func(), breakHint func() bool) error {
 	if err := o.StartUp(); err != nil {
 		return err
@@ -49,6 +49,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snapdenv"
@@ -934,11 +935,112 @@ func (ovs *overlordSuite) TestCheckpoint(c *C) {
 	s.Set("mark", 1)
 	s.Unlock()
 
-	st, err := os.Stat(dirs.SnapStateFile)
+	st, err := os.Stat(dirs.SnapStateDBFile)
 	c.Assert(err, IsNil)
 	c.Assert(st.Mode(), Equals, os.FileMode(0600))
+	c.Check(dirs.SnapStateFile, testutil.FileAbsent)
 
+	entries, err := statedb.ReadEntries(dirs.SnapStateDBFile)
+	c.Assert(err, IsNil)
+	c.Check(string(entries[state.EntriesData]["mark"]), Equals, "1")
+}
+
+func (ovs *overlordSuite) TestNewMigratesStateFile(c *C) {
+	fakeState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"patch-sublevel":%d,"patch-sublevel-last-version":%q,"some":"data"},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0,"last-lane-id":0}`, patch.Level, patch.Sublevel, snapdtool.Version))
+	err := os.WriteFile(dirs.SnapStateFile, fakeState, 0600)
+	c.Assert(err, IsNil)
+
+	_, err = overlord.New(nil)
+	c.Assert(err, IsNil)
+
+	// the state is now in the state database
+	entries, err := statedb.ReadEntries(dirs.SnapStateDBFile)
+	c.Assert(err, IsNil)
+	c.Check(string(entries[state.EntriesData]["some"]), Equals, `"data"`)
+	// the state file is kept for reverting to a snapd that predates the
+	// state database
+	c.Check(dirs.SnapStateFile, testutil.FileContains, `"some":"data"`)
+	changed, err := statedb.StateFileChanged(dirs.SnapStateDBFile, dirs.SnapStateFile)
+	c.Assert(err, IsNil)
+	c.Check(changed, Equals, false)
+}
+
+func (ovs *overlordSuite) TestNewIgnoresStateFileWrittenForOlderSnapd(c *C) {
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	s := o.State()
+	s.Lock()
+	s.Set("mark", 1)
+	s.Unlock()
+	// stopping writes the state file for older snapd
+	c.Assert(o.Stop(), IsNil)
 	c.Check(dirs.SnapStateFile, testutil.FileContains, `"mark":1`)
+
+	// snapd changed the state after that but did not get to stop
+	err = statedb.Write(dirs.SnapStateDBFile, &state.Delta{
+		Entries: map[string]map[string][]byte{
+			state.EntriesData: {"mark": []byte("2")},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	o, err = overlord.New(nil)
+	c.Assert(err, IsNil)
+	s = o.State()
+	s.Lock()
+	defer s.Unlock()
+	var mark int
+	c.Check(s.Get("mark", &mark), IsNil)
+	c.Check(mark, Equals, 2)
+	c.Check(dirs.SnapStateDBFile+".stale", testutil.FileAbsent)
+}
+
+func (ovs *overlordSuite) TestNewMigratesStateFileChangedByOlderSnapd(c *C) {
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	s := o.State()
+	s.Lock()
+	s.Set("mark", 1)
+	s.Unlock()
+	c.Assert(o.Stop(), IsNil)
+
+	// snapd was reverted to a version that predates the state database,
+	// which changed the state file
+	fakeState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"patch-sublevel":%d,"patch-sublevel-last-version":%q,"mark":3},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0,"last-lane-id":0}`, patch.Level, patch.Sublevel, snapdtool.Version))
+	c.Assert(os.WriteFile(dirs.SnapStateFile, fakeState, 0600), IsNil)
+
+	o, err = overlord.New(nil)
+	c.Assert(err, IsNil)
+	s = o.State()
+	s.Lock()
+	defer s.Unlock()
+	var mark int
+	c.Check(s.Get("mark", &mark), IsNil)
+	c.Check(mark, Equals, 3)
+
+	// the stale state database was moved aside
+	entries, err := statedb.ReadEntries(dirs.SnapStateDBFile + ".stale")
+	c.Assert(err, IsNil)
+	c.Check(string(entries[state.EntriesData]["mark"]), Equals, "1")
+}
+
+func (ovs *overlordSuite) TestNewFromStateDB(c *C) {
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	s := o.State()
+	s.Lock()
+	s.Set("mark", 1)
+	s.Unlock()
+	c.Assert(o.Stop(), IsNil)
+
+	o, err = overlord.New(nil)
+	c.Assert(err, IsNil)
+	s = o.State()
+	s.Lock()
+	defer s.Unlock()
+	var mark int
+	c.Check(s.Get("mark", &mark), IsNil)
+	c.Check(mark, Equals, 1)
 }
 
 type sampleManager struct {
@@ -20,10 +20,14 @@
 package servicestate
 
 import (
+	"time"
+
 	tomb "gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -33,6 +37,8 @@ var (
 	ServiceControlTs                     = serviceControlTs
 	ValidateSnapServicesForAddingToGroup = validateSnapServicesForAddingToGroup
 	AffectedSnapServices                 = affectedSnapServices
+	ReadQuotaGroupUsage                  = readQuotaGroupUsage
+	ThawQuotaGroup                       = thawQuotaGroup
 )
 
 func (m *ServiceManager) DoQuotaControl(t *state.Task, to *tomb.Tomb) error {
@@ -58,8 +64,65 @@ func EnsureSnapServicesForGroupOptions(allGrps map[string]*quota.Group, extraSna
 	}
 }
 
+func MockEbpfNetworkQuota(load func(group string, rate uint64) (bool, error), attach func(group, cgroupPath string) error, detach func(group string) error) (restore func()) {
+	r1 := testutil.Backup(&ebpfLoadNetworkQuota)
+	r2 := testutil.Backup(&ebpfAttachNetworkQuota)
+	r3 := testutil.Backup(&ebpfDetachNetworkQuota)
+	ebpfLoadNetworkQuota = load
+	ebpfAttachNetworkQuota = attach
+	ebpfDetachNetworkQuota = detach
+	return func() {
+		r1()
+		r2()
+		r3()
+	}
+}
+
 func MockResourcesCheckFeatureRequirements(f func(*quota.Resources) error) (restore func()) {
 	r := testutil.Backup(&resourcesCheckFeatureRequirements)
 	resourcesCheckFeatureRequirements = f
 	return r
 }
+
+type QuotaGroupReading = quotaGroupReading
+
+func NewQuotaGroupReading(memory quantity.Size, cpu time.Duration, threads int) *QuotaGroupReading {
+	return &quotaGroupReading{memory: memory, cpu: cpu, threads: threads}
+}
+
+func (r *QuotaGroupReading) Values() (memory quantity.Size, cpu time.Duration, threads int) {
+	return r.memory, r.cpu, r.threads
+}
+
+func SampleQuotaUsage(grps map[string]*quota.Group, now time.Time) error {
+	_, err := sampleQuotaUsage(grps, now)
+	return err
+}
+
+func MockReadQuotaGroupUsage(f func(sysd systemd.Systemd, grp *quota.Group) (*QuotaGroupReading, error)) (restore func()) {
+	r := testutil.Backup(&readQuotaGroupUsage)
+	readQuotaGroupUsage = f
+	return r
+}
+
+func MockQuotaUsageMaxSamples(n int) (restore func()) {
+	r := testutil.Backup(&quotaUsageMaxSamples)
+	quotaUsageMaxSamples = n
+	return r
+}
+
+func (m *ServiceManager) EnsureQuotaUsageSampled() error {
+	return m.ensureQuotaUsageSampled()
+}
+
+func MockQuotaUsageSampleInterval(d time.Duration) (restore func()) {
+	r := testutil.Backup(&quotaUsageSampleInterval)
+	quotaUsageSampleInterval = d
+	return r
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	r := testutil.Backup(&timeNow)
+	timeNow = f
+	return r
+}
@@ -100,6 +100,8 @@ func verifyQuotaRequirements(st *state.State, resourceLimits quota.Resources) er
 	// MemoryLimit requires systemd 211, so it's covered by the initial check
 	// CPUQuota requires systemd 213, so no further checks need to be done
 	// TasksMax requires systemd 228, so no further checks need to be done
+	// IOWeight and IO{Read,Write}BandwidthMax require systemd 230, so no
+	// further checks need to be done either
 
 	// AllowedCPUs requires systemd 243, so we need to verify the version here
 	if resourceLimits.CPUSet != nil {
@@ -119,6 +121,26 @@ func verifyQuotaRequirements(st *state.State, resourceLimits quota.Resources) er
 			return err
 		}
 	}
+
+	// Network quotas are enforced by eBPF programs managed by snapd, which
+	// systemd attaches with BPFProgram since systemd 249, and are
+	// experimental as well.
+	if resourceLimits.Network != nil {
+		if err := systemd.EnsureAtLeast(249); err != nil {
+			return fmt.Errorf("cannot use network quota with incompatible systemd: %v", err)
+		}
+		if err := isExperimentalQuotasAvailable(st, "network"); err != nil {
+			return err
+		}
+	}
+
+	// Soft limits are acted upon by snapd itself based on the sampled
+	// usage of the group, they are experimental too.
+	if resourceLimits.SoftLimits != nil {
+		if err := isExperimentalQuotasAvailable(st, "soft limits"); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -259,6 +281,38 @@ func RemoveQuota(st *state.State, name string) (*state.TaskSet, error) {
 	return ts, nil
 }
 
+// ThawQuota thaws the given quota group after it was frozen by its soft
+// limits action, without otherwise changing the group.
+func ThawQuota(st *state.State, name string) (*state.TaskSet, error) {
+	allGrps, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure the group exists
+	if _, ok := allGrps[name]; !ok {
+		return nil, fmt.Errorf("cannot thaw non-existent quota group %q", name)
+	}
+
+	if err := CheckQuotaChangeConflictMany(st, []string{name}); err != nil {
+		return nil, err
+	}
+
+	qc := QuotaControlAction{
+		Action:    "thaw",
+		QuotaName: name,
+	}
+
+	ts := state.NewTaskSet()
+
+	summary := fmt.Sprintf("Thaw quota group %q", name)
+	task := st.NewTask("quota-control", summary)
+	task.Set("quota-control-actions", []QuotaControlAction{qc})
+	ts.AddTask(task)
+
+	return ts, nil
+}
+
 // UpdateQuotaOptions reflects all of the modifications that can be performed on
 // a quota group in one operation.
 type UpdateQuotaOptions struct {
@@ -145,7 +145,8 @@ func checkQuotaState(c *C, st *state.State, exp map[string]quotaGroupState) {
 func shouldMentionSlice(resources quota.Resources) bool {
 	if resources.Memory == nil && resources.CPU == nil &&
 		resources.CPUSet == nil && resources.Threads == nil &&
-		resources.Journal == nil {
+		resources.Journal == nil && resources.IO == nil &&
+		resources.Network == nil && resources.SoftLimits == nil {
 		return false
 	}
 	return true
@@ -338,6 +339,7 @@ func (s *quotaControlSuite) TestCreateQuotaPerQuotaSystemdTooOld(c *C) {
 
 		{quota.NewResourcesBuilder().WithCPUSet([]int{0, 1}).Build(), 243, `cannot use the cpu-set quota with incompatible systemd: systemd version 242 is too old \(expected at least 243\)`},
 		{quota.NewResourcesBuilder().WithJournalSize(quantity.SizeGiB).Build(), 245, `cannot use journal quota with incompatible systemd: systemd version 244 is too old \(expected at least 245\)`},
+		{quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build(), 249, `cannot use network quota with incompatible systemd: systemd version 248 is too old \(expected at least 249\)`},
 	}
 
 	for _, t := range tests {
@@ -29,10 +29,13 @@ import (
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/servicestate/internal"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/sandbox/ebpf"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/snap/quota"
@@ -50,7 +53,7 @@ type QuotaControlAction struct {
 	QuotaName string `json:"quota-name,omitempty"`
 
 	// Action is the action being taken on the quota group. It can be either
-	// "create", "update", or "remove".
+	// "create", "update", "remove" or "thaw".
 	Action string `json:"action,omitempty"`
 
 	// AddSnaps is the set of snaps to add to the quota group, valid for either
@@ -103,6 +106,15 @@ func (m *ServiceManager) doQuotaControl(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	if qc.Action == "thaw" {
+		// thawing changes neither the group nor its services
+		if err := quotaThaw(qc, allGrps); err != nil {
+			return err
+		}
+		t.SetStatus(state.DoneStatus)
+		return nil
+	}
+
 	data, err := internal.GetQuotaState(t)
 	if err != nil {
 		return err
@@ -350,6 +362,15 @@ func quotaCreate(st *state.State, action QuotaControlAction, allGrps map[string]
 	return grp, allGrps, refreshProfiles, nil
 }
 
+func quotaThaw(action QuotaControlAction, allGrps map[string]*quota.Group) error {
+	// make sure the group exists
+	grp, ok := allGrps[action.QuotaName]
+	if !ok {
+		return fmt.Errorf("cannot thaw non-existent quota group %q", action.QuotaName)
+	}
+	return thawQuotaGroup(grp)
+}
+
 func quotaRemove(st *state.State, action QuotaControlAction, allGrps map[string]*quota.Group) (*quota.Group, map[string]*quota.Group, bool, error) {
 	// make sure the group exists
 	grp, ok := allGrps[action.QuotaName]
@@ -710,6 +731,11 @@ func ensureSnapServicesForGroup(st *state.State, t *state.Task, grp *quota.Group
 	// TODO: should this logic move to wrappers in wrappers.RemoveQuotaGroup()?
 	systemSysd := systemd.New(systemd.SystemMode, meterLocked)
 
+	// any change to the group lifts a freeze by its soft limits action
+	if err := thawQuotaGroup(grp); err != nil {
+		return nil, err
+	}
+
 	// now start the slices
 	for _, grp := range grpsToStart {
 		// TODO: what should these timeouts for stopping/restart slices be?
@@ -738,6 +764,15 @@ func ensureSnapServicesForGroup(st *state.State, t *state.Task, grp *quota.Group
 		if err != nil {
 			return nil, err
 		}
+
+		if err := ebpfDetachNetworkQuota(grp.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	// load the network quotas, now that the slices are in place
+	if err := ensureNetworkQuotas(allGrps); err != nil {
+		return nil, err
 	}
 
 	// lastly, lets restart journald services which were affected
@@ -751,6 +786,45 @@ func ensureSnapServicesForGroup(st *state.State, t *state.Task, grp *quota.Group
 	return appsToRestartBySnap, nil
 }
 
+var (
+	ebpfLoadNetworkQuota   = ebpf.LoadNetworkQuota
+	ebpfAttachNetworkQuota = ebpf.AttachNetworkQuota
+	ebpfDetachNetworkQuota = ebpf.DetachNetworkQuota
+)
+
+// ensureNetworkQuotas loads the eBPF programs enforcing the network quotas
+// of the quota groups with a network limit, or updates their limit. The
+// programs are attached by systemd whenever the slices start, so a slice is
+// only attached to here if it was active when its program got loaded, as
+// when the slice was started first after a reboot. The cgroup of a slice
+// only exists while the slice is active.
+func ensureNetworkQuotas(allGrps map[string]*quota.Group) error {
+	names := make([]string, 0, len(allGrps))
+	for name, grp := range allGrps {
+		if grp.NetworkLimit != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		grp := allGrps[name]
+		bandwidth := uint64(grp.NetworkLimit.EgressBandwidth)
+		loaded, err := ebpfLoadNetworkQuota(grp.Name, bandwidth)
+		if err != nil {
+			return fmt.Errorf("cannot enforce network quota of group %q: %v", grp.Name, err)
+		}
+		cgroupPath := cgroup.SlicePath(grp.SliceFileName())
+		if !loaded || !osutil.IsDirectory(cgroupPath) {
+			continue
+		}
+		if err := ebpfAttachNetworkQuota(grp.Name, cgroupPath); err != nil {
+			return fmt.Errorf("cannot enforce network quota of group %q: %v", grp.Name, err)
+		}
+	}
+	return nil
+}
+
 // restartSnapServices is used to restart the services for snaps that
 // have been modified. Snaps and services are sorted before they are
 // restarted to provide a consistent ordering of restarts to be testable.
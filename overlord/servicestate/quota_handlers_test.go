@@ -20,6 +20,7 @@
 package servicestate_test
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -3203,3 +3204,165 @@ func (s *quotaHandlersSuite) TestQuotaSnapServicesRestartOnlyRelevantServices(c
 		c.Check(svc.ServiceFile(), testutil.FileContains, fmt.Sprintf(`Slice=%s`, grp.SliceFileName()))
 	}
 }
+
+func (s *quotaHandlersSuite) TestQuotaNetworkQuotaLoadAttachDetach(c *C) {
+	r := s.mockSystemctlCalls(c, join(
+		// CreateQuota for foo
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForSliceStart("foo"),
+		systemctlCallsForServiceRestart("test-snap"),
+
+		// UpdateQuota for foo does not change the slice
+
+		// RemoveQuota for foo
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForSliceStop("foo"),
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForServiceRestart("test-snap"),
+	))
+	defer r()
+
+	type loadCall struct {
+		group string
+		rate  uint64
+	}
+	var loads []loadCall
+	var attached, detached []string
+	restore := servicestate.MockEbpfNetworkQuota(func(group string, rate uint64) (bool, error) {
+		loads = append(loads, loadCall{group, rate})
+		// the program is only loaded the first time
+		return len(loads) == 1, nil
+	}, func(group, cgroupPath string) error {
+		attached = append(attached, group+":"+cgroupPath)
+		return nil
+	}, func(group string) error {
+		detached = append(detached, group)
+		return nil
+	})
+	defer restore()
+
+	// the slice is started before the program is loaded
+	cgroupPath := filepath.Join(dirs.GlobalRootDir, "/sys/fs/cgroup/snap.foo.slice")
+	c.Assert(os.MkdirAll(cgroupPath, 0755), IsNil)
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	// setup the snap so it exists
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYaml, s.testSnapSideInfo)
+
+	qc := servicestate.QuotaControlAction{
+		Action:         "create",
+		QuotaName:      "foo",
+		ResourceLimits: quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build(),
+		AddSnaps:       []string{"test-snap"},
+	}
+	err := s.callDoQuotaControl(&qc)
+	c.Assert(err, IsNil)
+
+	c.Check(loads, DeepEquals, []loadCall{
+		{"foo", uint64(quantity.SizeMiB)},
+	})
+	c.Check(attached, DeepEquals, []string{"foo:" + cgroupPath})
+	checkQuotaState(c, st, map[string]quotaGroupState{
+		"foo": {
+			ResourceLimits: quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build(),
+			Snaps:          []string{"test-snap"},
+		},
+	})
+
+	// changing the limit updates the loaded program, which is attached
+	// already
+	qc2 := servicestate.QuotaControlAction{
+		Action:         "update",
+		QuotaName:      "foo",
+		ResourceLimits: quota.NewResourcesBuilder().WithNetworkEgressBandwidth(512 * quantity.SizeKiB).Build(),
+	}
+	err = s.callDoQuotaControl(&qc2)
+	c.Assert(err, IsNil)
+	c.Check(loads, DeepEquals, []loadCall{
+		{"foo", uint64(quantity.SizeMiB)},
+		{"foo", uint64(512 * quantity.SizeKiB)},
+	})
+	c.Check(attached, HasLen, 1)
+	c.Check(detached, HasLen, 0)
+
+	qc3 := servicestate.QuotaControlAction{
+		Action:    "remove",
+		QuotaName: "foo",
+	}
+	err = s.callDoQuotaControl(&qc3)
+	c.Assert(err, IsNil)
+	c.Check(loads, HasLen, 2)
+	c.Check(detached, DeepEquals, []string{"foo"})
+}
+
+func (s *quotaHandlersSuite) TestQuotaNetworkQuotaSliceNotActive(c *C) {
+	r := s.mockSystemctlCalls(c, join(
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForSliceStart("foo"),
+		systemctlCallsForServiceRestart("test-snap"),
+	))
+	defer r()
+
+	var attached []string
+	restore := servicestate.MockEbpfNetworkQuota(func(group string, rate uint64) (bool, error) {
+		return true, nil
+	}, func(group, cgroupPath string) error {
+		attached = append(attached, group)
+		return nil
+	}, nil)
+	defer restore()
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYaml, s.testSnapSideInfo)
+
+	// without the cgroup of the slice, systemd attaches the program when
+	// the slice starts
+	qc := servicestate.QuotaControlAction{
+		Action:         "create",
+		QuotaName:      "foo",
+		ResourceLimits: quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build(),
+		AddSnaps:       []string{"test-snap"},
+	}
+	err := s.callDoQuotaControl(&qc)
+	c.Assert(err, IsNil)
+	c.Check(attached, HasLen, 0)
+}
+
+func (s *quotaHandlersSuite) TestQuotaNetworkQuotaLoadError(c *C) {
+	r := s.mockSystemctlCalls(c, join(
+		[]expectedSystemctl{{expArgs: []string{"daemon-reload"}}},
+		systemctlCallsForSliceStart("foo"),
+	))
+	defer r()
+
+	restore := servicestate.MockEbpfNetworkQuota(func(group string, rate uint64) (bool, error) {
+		return false, errors.New("boom")
+	}, nil, func(group string) error {
+		return nil
+	})
+	defer restore()
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYaml, s.testSnapSideInfo)
+
+	qc := servicestate.QuotaControlAction{
+		Action:         "create",
+		QuotaName:      "foo",
+		ResourceLimits: quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build(),
+		AddSnaps:       []string{"test-snap"},
+	}
+	err := s.callDoQuotaControl(&qc)
+	c.Assert(err, ErrorMatches, `cannot enforce network quota of group "foo": boom`)
+}
@@ -0,0 +1,602 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/swfeats"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/snapdenv"
+	"github.com/snapcore/snapd/systemd"
+)
+
+var (
+	// quotaUsageSampleInterval is how often the usage of the quota groups
+	// is sampled, this matches the interval of the ensure loop which does
+	// the sampling.
+	quotaUsageSampleInterval = 5 * time.Minute
+	// quotaUsageMaxSamples bounds the number of samples kept for each quota
+	// group, by default one day worth of samples.
+	quotaUsageMaxSamples = 24 * 60 / 5
+
+	timeNow = time.Now
+)
+
+var serviceControlChangeKind = swfeats.RegisterChangeKind("service-control")
+
+// QuotaUsageSample is the resource usage of a quota group at a given time.
+type QuotaUsageSample struct {
+	Time time.Time `json:"time"`
+	// Memory is the memory used by the group.
+	Memory quantity.Size `json:"memory"`
+	// CPU is the CPU time used by the group since the previous sample, as a
+	// percentage of a single CPU.
+	CPU int `json:"cpu"`
+	// Threads is the number of threads running in the group.
+	Threads int `json:"threads"`
+}
+
+// quotaGroupReading is the raw usage of a quota group as reported by systemd.
+type quotaGroupReading struct {
+	memory  quantity.Size
+	cpu     time.Duration
+	threads int
+}
+
+type quotaGroupUsageHistory struct {
+	// CPUTime and CPUTimeAt record the cumulative CPU time used by the
+	// group at the last reading, the CPU usage of the next sample is
+	// computed from them.
+	CPUTime   time.Duration `json:"cpu-time"`
+	CPUTimeAt time.Time     `json:"cpu-time-at"`
+
+	// OverSoftLimitsSince is the time of the first of the consecutive
+	// samples above the soft limits of the group.
+	OverSoftLimitsSince time.Time `json:"over-soft-limits-since,omitempty"`
+	// Frozen is set when the group was frozen by the soft limits action,
+	// the group stays frozen until it is updated or removed. As the
+	// history, a freeze does not survive a reboot.
+	Frozen bool `json:"frozen,omitempty"`
+
+	Samples []QuotaUsageSample `json:"samples"`
+}
+
+// quotaUsageHistoryMu serializes the access to the usage history, which is
+// modified both by the sampling and by the changes to the quota groups.
+var quotaUsageHistoryMu sync.Mutex
+
+// quotaUsageHistoryFile returns the file the usage history of all quota
+// groups is kept in. It lives in the run directory, the history does not
+// survive a reboot.
+func quotaUsageHistoryFile() string {
+	return filepath.Join(dirs.SnapRunDir, "quota-usage.json")
+}
+
+func loadQuotaUsageHistory() (map[string]*quotaGroupUsageHistory, error) {
+	history := make(map[string]*quotaGroupUsageHistory)
+	f, err := os.Open(quotaUsageHistoryFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&history); err != nil {
+		return nil, fmt.Errorf("cannot decode quota usage history: %v", err)
+	}
+	return history, nil
+}
+
+func saveQuotaUsageHistory(history map[string]*quotaGroupUsageHistory) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dirs.SnapRunDir, 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(quotaUsageHistoryFile(), data, 0600, 0)
+}
+
+// readQuotaGroupUsage reads the current usage of the given quota group. It
+// returns nil if the group is not active, which is the case for groups
+// without any snaps in them.
+var readQuotaGroupUsage = func(sysd systemd.Systemd, grp *quota.Group) (*quotaGroupReading, error) {
+	sliceName := grp.SliceFileName()
+	isActive, err := sysd.IsActive(sliceName)
+	if err != nil {
+		return nil, err
+	}
+	if !isActive {
+		return nil, nil
+	}
+
+	var reading quotaGroupReading
+	reading.memory, err = sysd.CurrentMemoryUsage(sliceName)
+	if err != nil {
+		return nil, err
+	}
+	threads, err := sysd.CurrentTasksCount(sliceName)
+	if err != nil {
+		return nil, err
+	}
+	reading.threads = int(threads)
+	reading.cpu, err = sysd.CurrentCPUUsage(sliceName)
+	if err != nil {
+		return nil, err
+	}
+	return &reading, nil
+}
+
+// softLimitsOveruse describes a quota group which has been over its soft
+// limits for the configured window.
+type softLimitsOveruse struct {
+	group  string
+	action quota.SoftLimitAction
+	// exceeded is the list of resources over their soft limit in the
+	// latest sample.
+	exceeded []string
+}
+
+// exceededSoftLimits returns the resources of the sample which are above the
+// given soft limits.
+func exceededSoftLimits(limits *quota.GroupQuotaSoftLimits, sample *QuotaUsageSample) []string {
+	var exceeded []string
+	if limits.Memory != 0 && sample.Memory > limits.Memory {
+		exceeded = append(exceeded, "memory")
+	}
+	if limits.CPUPercentage != 0 && sample.CPU > limits.CPUPercentage {
+		exceeded = append(exceeded, "cpu")
+	}
+	if limits.Threads != 0 && sample.Threads > limits.Threads {
+		exceeded = append(exceeded, "threads")
+	}
+	return exceeded
+}
+
+// checkSoftLimits checks the latest sample of the group against its soft
+// limits and returns the overuse to act upon once the group has been over
+// them for the whole window, after which the window starts over.
+func checkSoftLimits(grp *quota.Group, grpHistory *quotaGroupUsageHistory, sample *QuotaUsageSample) *softLimitsOveruse {
+	if grp.SoftLimits == nil || grpHistory.Frozen {
+		grpHistory.OverSoftLimitsSince = time.Time{}
+		return nil
+	}
+	exceeded := exceededSoftLimits(grp.SoftLimits, sample)
+	if len(exceeded) == 0 {
+		grpHistory.OverSoftLimitsSince = time.Time{}
+		return nil
+	}
+	if grpHistory.OverSoftLimitsSince.IsZero() {
+		grpHistory.OverSoftLimitsSince = sample.Time
+	}
+	if sample.Time.Sub(grpHistory.OverSoftLimitsSince) < grp.SoftLimits.Window {
+		return nil
+	}
+	grpHistory.OverSoftLimitsSince = time.Time{}
+	return &softLimitsOveruse{
+		group:    grp.Name,
+		action:   grp.SoftLimits.Action,
+		exceeded: exceeded,
+	}
+}
+
+// freezeQuotaGroups freezes the slices of the quota groups which are over
+// their soft limits and whose action is to freeze them, and returns the
+// overuses of the groups which are still to be acted upon. sampleQuotaUsage
+// already recorded these groups as frozen in the usage history, so that they
+// are not sampled meanwhile. The slices are frozen without holding
+// quotaUsageHistoryMu, as freezing waits for all the processes to stop, which
+// would block the changes to the quota groups.
+func freezeQuotaGroups(grps map[string]*quota.Group, overuses []*softLimitsOveruse) []*softLimitsOveruse {
+	var acted []*softLimitsOveruse
+	for _, overuse := range overuses {
+		if overuse.action != quota.SoftLimitActionFreeze {
+			acted = append(acted, overuse)
+			continue
+		}
+		grp := grps[overuse.group]
+		if err := cgroup.FreezeSlice(context.Background(), grp.SliceFileName()); err != nil {
+			logger.Noticef("cannot freeze quota group %q over its soft limits: %v", grp.Name, err)
+			if err := clearQuotaGroupFrozen(grp); err != nil {
+				logger.Noticef("cannot record quota group %q as not frozen: %v", grp.Name, err)
+			}
+			continue
+		}
+		// the group may have been thawed while it was being frozen, in
+		// which case the freeze is lifted right away
+		stillFrozen, err := quotaGroupFrozen(grp)
+		if err != nil || !stillFrozen {
+			if err := cgroup.ThawSlice(grp.SliceFileName()); err != nil {
+				logger.Noticef("cannot thaw quota group %q: %v", grp.Name, err)
+			}
+			continue
+		}
+		acted = append(acted, overuse)
+	}
+	return acted
+}
+
+// quotaGroupFrozen returns whether the given quota group is recorded as
+// frozen by its soft limits action in the usage history.
+func quotaGroupFrozen(grp *quota.Group) (bool, error) {
+	quotaUsageHistoryMu.Lock()
+	defer quotaUsageHistoryMu.Unlock()
+
+	history, err := loadQuotaUsageHistory()
+	if err != nil {
+		return false, err
+	}
+	grpHistory := history[grp.Name]
+	return grpHistory != nil && grpHistory.Frozen, nil
+}
+
+// clearQuotaGroupFrozen records in the usage history that the given quota
+// group is not frozen by its soft limits action.
+func clearQuotaGroupFrozen(grp *quota.Group) error {
+	quotaUsageHistoryMu.Lock()
+	defer quotaUsageHistoryMu.Unlock()
+
+	history, err := loadQuotaUsageHistory()
+	if err != nil {
+		// the history is discarded by the next sampling
+		return err
+	}
+	grpHistory := history[grp.Name]
+	if grpHistory == nil || !grpHistory.Frozen {
+		return nil
+	}
+	grpHistory.Frozen = false
+	grpHistory.OverSoftLimitsSince = time.Time{}
+	return saveQuotaUsageHistory(history)
+}
+
+// thawQuotaGroup lifts a freeze of the given quota group by its soft limits
+// action. This is called whenever the group is updated, removed or
+// explicitly thawed. The slice is thawed whether or not the usage history
+// records the group as frozen, as the history is discarded when it cannot be
+// loaded.
+func thawQuotaGroup(grp *quota.Group) error {
+	// slices can only be frozen with cgroup v2
+	if cgroup.IsUnified() {
+		if err := cgroup.ThawSlice(grp.SliceFileName()); err != nil {
+			return fmt.Errorf("cannot thaw quota group %q: %v", grp.Name, err)
+		}
+	}
+	if err := clearQuotaGroupFrozen(grp); err != nil {
+		logger.Noticef("cannot record quota group %q as not frozen: %v", grp.Name, err)
+	}
+	return nil
+}
+
+// sampleQuotaUsage adds a sample of the current usage of the given quota
+// groups to the usage history. The history of groups that no longer exist is
+// dropped. Groups over their soft limits for the configured window are
+// returned so that their soft limits action can be taken by the caller, the
+// groups to be frozen are already recorded as frozen in the history.
+func sampleQuotaUsage(grps map[string]*quota.Group, now time.Time) ([]*softLimitsOveruse, error) {
+	quotaUsageHistoryMu.Lock()
+	defer quotaUsageHistoryMu.Unlock()
+
+	history, err := loadQuotaUsageHistory()
+	if err != nil {
+		// the history is only informational, start over
+		logger.Noticef("cannot load quota usage history, discarding it: %v", err)
+		history = make(map[string]*quotaGroupUsageHistory)
+	}
+	for name := range history {
+		if _, ok := grps[name]; !ok {
+			delete(history, name)
+		}
+	}
+
+	names := make([]string, 0, len(grps))
+	for name := range grps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var overuses []*softLimitsOveruse
+	sysd := systemd.New(systemd.SystemMode, progress.Null)
+	for _, name := range names {
+		grp := grps[name]
+		reading, err := readQuotaGroupUsage(sysd, grp)
+		if err != nil {
+			logger.Noticef("cannot sample usage of quota group %q: %v", name, err)
+			continue
+		}
+		grpHistory := history[name]
+		if reading == nil {
+			// the group is not running anything, it has no CPU time
+			// to compare the next reading with
+			if grpHistory != nil {
+				grpHistory.CPUTimeAt = time.Time{}
+				grpHistory.OverSoftLimitsSince = time.Time{}
+			}
+			continue
+		}
+		if grpHistory == nil {
+			grpHistory = &quotaGroupUsageHistory{}
+			history[name] = grpHistory
+		}
+
+		// the CPU usage is relative to the previous reading, so the
+		// first reading of a group only sets the starting point, as does
+		// a reading after the slice was restarted
+		elapsed := now.Sub(grpHistory.CPUTimeAt)
+		if !grpHistory.CPUTimeAt.IsZero() && elapsed > 0 && reading.cpu >= grpHistory.CPUTime {
+			cpu := reading.cpu - grpHistory.CPUTime
+			sample := QuotaUsageSample{
+				Time:    now,
+				Memory:  reading.memory,
+				CPU:     int(cpu * 100 / elapsed),
+				Threads: reading.threads,
+			}
+			grpHistory.Samples = append(grpHistory.Samples, sample)
+			if len(grpHistory.Samples) > quotaUsageMaxSamples {
+				grpHistory.Samples = grpHistory.Samples[len(grpHistory.Samples)-quotaUsageMaxSamples:]
+			}
+
+			overuse := checkSoftLimits(grp, grpHistory, &sample)
+			if overuse != nil && overuse.action == quota.SoftLimitActionFreeze {
+				grpHistory.Frozen = true
+			}
+			if overuse != nil {
+				overuses = append(overuses, overuse)
+			}
+		}
+		grpHistory.CPUTime = reading.cpu
+		grpHistory.CPUTimeAt = now
+	}
+
+	return overuses, saveQuotaUsageHistory(history)
+}
+
+// ensureQuotaUsageSampled samples the usage of all quota groups once per
+// sampling interval.
+func (m *ServiceManager) ensureQuotaUsageSampled() error {
+	if snapdenv.Preseeding() {
+		return nil
+	}
+
+	now := timeNow()
+	if now.Before(m.lastQuotaUsageSample.Add(quotaUsageSampleInterval)) {
+		return nil
+	}
+
+	m.state.Lock()
+	var seeded bool
+	err := m.state.Get("seeded", &seeded)
+	if err != nil && !errors.Is(err, state.ErrNoState) {
+		m.state.Unlock()
+		return err
+	}
+	if !seeded {
+		m.state.Unlock()
+		return nil
+	}
+	allGrps, err := AllQuotas(m.state)
+	m.state.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(allGrps) == 0 {
+		// nothing to sample until a quota group is created
+		if err := os.Remove(quotaUsageHistoryFile()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	logger.Trace("ensure", "manager", "ServiceManager", "func", "ensureQuotaUsageSampled")
+
+	// sample without holding the state lock, this talks to systemd
+	m.lastQuotaUsageSample = now
+	overuses, err := sampleQuotaUsage(allGrps, now)
+	if err != nil {
+		return err
+	}
+	overuses = freezeQuotaGroups(allGrps, overuses)
+	if len(overuses) == 0 {
+		return nil
+	}
+
+	m.state.Lock()
+	defer m.state.Unlock()
+	for _, overuse := range overuses {
+		if err := actOnSoftLimitsOveruse(m.state, overuse); err != nil {
+			// the action is taken again if the group is still over
+			// its soft limits after another window
+			logger.Noticef("cannot act on quota group %q over its soft limits: %v", overuse.group, err)
+		}
+	}
+	return nil
+}
+
+// actOnSoftLimitsOveruse takes the soft limits action of a quota group which
+// has been over its soft limits for the configured window, and records a
+// notice of it. Freezing the group is done by freezeQuotaGroups.
+func actOnSoftLimitsOveruse(st *state.State, overuse *softLimitsOveruse) error {
+	if overuse.action == quota.SoftLimitActionRestart {
+		if err := restartQuotaGroupServices(st, overuse.group); err != nil {
+			return err
+		}
+	}
+	_, err := st.AddNotice(nil, state.QuotaSoftLimitNotice, overuse.group, &state.AddNoticeOptions{
+		Data: map[string]string{
+			"action":   string(overuse.action),
+			"exceeded": strings.Join(overuse.exceeded, ","),
+		},
+	})
+	return err
+}
+
+// quotaGroupServices returns the services of the snaps in the given quota
+// group and its sub-groups.
+func quotaGroupServices(st *state.State, grp *quota.Group, allGrps map[string]*quota.Group) (map[*snap.Info][]*snap.AppInfo, error) {
+	infos := make(map[string]*snap.Info)
+	currentInfo := func(snapName string) (*snap.Info, error) {
+		if info := infos[snapName]; info != nil {
+			return info, nil
+		}
+		info, err := snapstate.CurrentInfo(st, snapName)
+		if err != nil {
+			return nil, err
+		}
+		infos[snapName] = info
+		return info, nil
+	}
+
+	services := make(map[*snap.Info][]*snap.AppInfo)
+	addService := func(info *snap.Info, app *snap.AppInfo) {
+		for _, a := range services[info] {
+			if a.Name == app.Name {
+				return
+			}
+		}
+		services[info] = append(services[info], app)
+	}
+
+	var collect func(grp *quota.Group) error
+	collect = func(grp *quota.Group) error {
+		for _, snapName := range grp.Snaps {
+			info, err := currentInfo(snapName)
+			if err != nil {
+				return err
+			}
+			for _, app := range info.Services() {
+				addService(info, app)
+			}
+		}
+		for _, svc := range grp.Services {
+			snapName, svcName, err := splitSnapServiceName(svc)
+			if err != nil {
+				return err
+			}
+			info, err := currentInfo(snapName)
+			if err != nil {
+				return err
+			}
+			if app := info.Apps[svcName]; app != nil && app.IsService() {
+				addService(info, app)
+			}
+		}
+		for _, subName := range grp.SubGroups {
+			if err := collect(allGrps[subName]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := collect(grp); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// restartQuotaGroupServices creates a change restarting the services in the
+// given quota group and its sub-groups.
+func restartQuotaGroupServices(st *state.State, name string) error {
+	allGrps, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	grp := allGrps[name]
+	if grp == nil {
+		// removed since it was sampled
+		return nil
+	}
+	servicesAffected, err := quotaGroupServices(st, grp, allGrps)
+	if err != nil {
+		return err
+	}
+	if len(servicesAffected) == 0 {
+		return nil
+	}
+
+	snapNames := make([]string, 0, len(servicesAffected))
+	for info := range servicesAffected {
+		snapNames = append(snapNames, info.InstanceName())
+	}
+	sort.Strings(snapNames)
+	if err := CheckQuotaChangeConflictMany(st, []string{name}); err != nil {
+		return err
+	}
+	if err := snapstate.CheckChangeConflictMany(st, snapNames, ""); err != nil {
+		return err
+	}
+
+	ts := state.NewTaskSet()
+	var prevTask *state.Task
+	queueTask := func(task *state.Task) {
+		if prevTask != nil {
+			task.WaitFor(prevTask)
+		}
+		ts.AddTask(task)
+		prevTask = task
+	}
+	addRestartServicesTasks(st, queueTask, name, servicesAffected)
+
+	chg := st.NewChange(serviceControlChangeKind, fmt.Sprintf("Restart services of quota group %q over its soft limits", name))
+	chg.AddAll(ts)
+	st.EnsureBefore(0)
+	return nil
+}
+
+// QuotaUsageHistory returns the samples of the resource usage of the given
+// quota group taken since the given time, oldest first. The history is kept
+// for a bounded time only and does not survive a reboot.
+func QuotaUsageHistory(group string, since time.Time) ([]QuotaUsageSample, error) {
+	history, err := loadQuotaUsageHistory()
+	if err != nil {
+		return nil, err
+	}
+	grpHistory := history[group]
+	if grpHistory == nil {
+		return nil, nil
+	}
+	var samples []QuotaUsageSample
+	for _, sample := range grpHistory.Samples {
+		if !sample.Time.Before(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,634 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/servicestate/servicestatetest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type quotaUsageSuite struct {
+	baseServiceMgrTestSuite
+}
+
+var _ = Suite(&quotaUsageSuite{})
+
+func (s *quotaUsageSuite) SetUpTest(c *C) {
+	s.baseServiceMgrTestSuite.SetUpTest(c)
+
+	// we don't need the EnsureSnapServices ensure loop to run by default
+	servicestate.MockEnsuredSnapServices(s.mgr, true)
+
+	// slices can only be frozen with cgroup v2
+	s.AddCleanup(cgroup.MockVersion(cgroup.V2, nil))
+}
+
+type systemctlInactiveError struct{}
+
+func (systemctlInactiveError) Msg() []byte   { return []byte("inactive") }
+func (systemctlInactiveError) ExitCode() int { return 3 }
+func (systemctlInactiveError) Error() string { return "inactive" }
+
+func (s *quotaUsageSuite) TestReadQuotaGroupUsage(c *C) {
+	active := false
+	var calls [][]string
+	r := systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		switch args[len(args)-2] {
+		case "is-active":
+			if !active {
+				return []byte("inactive"), systemctlInactiveError{}
+			}
+			return []byte("active"), nil
+		case "MemoryCurrent":
+			return []byte("MemoryCurrent=4096"), nil
+		case "TasksCurrent":
+			return []byte("TasksCurrent=12"), nil
+		case "CPUUsageNSec":
+			return []byte("CPUUsageNSec=2000000000"), nil
+		}
+		return nil, fmt.Errorf("unexpected systemctl call %v", args)
+	})
+	defer r()
+
+	grp, err := quota.NewGroup("foo", quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	sysd := systemd.New(systemd.SystemMode, progress.Null)
+
+	// an inactive group has no usage
+	reading, err := servicestate.ReadQuotaGroupUsage(sysd, grp)
+	c.Assert(err, IsNil)
+	c.Check(reading, IsNil)
+	c.Check(calls, DeepEquals, [][]string{{"is-active", "snap.foo.slice"}})
+
+	calls = nil
+	active = true
+	reading, err = servicestate.ReadQuotaGroupUsage(sysd, grp)
+	c.Assert(err, IsNil)
+	c.Assert(reading, NotNil)
+	mem, cpu, threads := reading.Values()
+	c.Check(mem, Equals, 4*quantity.SizeKiB)
+	c.Check(cpu, Equals, 2*time.Second)
+	c.Check(threads, Equals, 12)
+	c.Check(calls, DeepEquals, [][]string{
+		{"is-active", "snap.foo.slice"},
+		{"show", "--property", "MemoryCurrent", "snap.foo.slice"},
+		{"show", "--property", "TasksCurrent", "snap.foo.slice"},
+		{"show", "--property", "CPUUsageNSec", "snap.foo.slice"},
+	})
+}
+
+func (s *quotaUsageSuite) TestSampleQuotaUsage(c *C) {
+	readings := map[string]*servicestate.QuotaGroupReading{}
+	r := servicestate.MockReadQuotaGroupUsage(func(sysd systemd.Systemd, grp *quota.Group) (*servicestate.QuotaGroupReading, error) {
+		if grp.Name == "broken" {
+			return nil, fmt.Errorf("boom")
+		}
+		return readings[grp.Name], nil
+	})
+	defer r()
+	s.AddCleanup(servicestate.MockQuotaUsageMaxSamples(2))
+
+	foo, err := quota.NewGroup("foo", quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	bar, err := quota.NewGroup("bar", quota.NewResourcesBuilder().WithThreadLimit(32).Build())
+	c.Assert(err, IsNil)
+	broken, err := quota.NewGroup("broken", quota.NewResourcesBuilder().WithThreadLimit(32).Build())
+	c.Assert(err, IsNil)
+	grps := map[string]*quota.Group{"foo": foo, "bar": bar, "broken": broken}
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// the first reading only sets the starting point of the CPU usage, bar
+	// is not active
+	readings["foo"] = servicestate.NewQuotaGroupReading(quantity.SizeMiB, time.Second, 4)
+	c.Assert(servicestate.SampleQuotaUsage(grps, t0), IsNil)
+	c.Check(filepath.Join(dirs.SnapRunDir, "quota-usage.json"), testutil.FilePresent)
+	samples, err := servicestate.QuotaUsageHistory("foo", time.Time{})
+	c.Assert(err, IsNil)
+	c.Check(samples, HasLen, 0)
+
+	// half a CPU used over the minute
+	readings["foo"] = servicestate.NewQuotaGroupReading(2*quantity.SizeMiB, 31*time.Second, 5)
+	readings["bar"] = servicestate.NewQuotaGroupReading(0, 0, 1)
+	c.Assert(servicestate.SampleQuotaUsage(grps, t0.Add(time.Minute)), IsNil)
+	samples, err = servicestate.QuotaUsageHistory("foo", time.Time{})
+	c.Assert(err, IsNil)
+	c.Check(samples, DeepEquals, []servicestate.QuotaUsageSample{
+		{Time: t0.Add(time.Minute), Memory: 2 * quantity.SizeMiB, CPU: 50, Threads: 5},
+	})
+	samples, err = servicestate.QuotaUsageHistory("bar", time.Time{})
+	c.Assert(err, IsNil)
+	c.Check(samples, HasLen, 0)
+
+	// two CPUs fully used
+	readings["foo"] = servicestate.NewQuotaGroupReading(3*quantity.SizeMiB, 151*time.Second, 6)
+	c.Assert(servicestate.SampleQuotaUsage(grps, t0.Add(2*time.Minute)), IsNil)
+	// only the last two samples are kept
+	readings["foo"] = servicestate.NewQuotaGroupReading(quantity.SizeMiB, 151*time.Second, 6)
+	c.Assert(servicestate.SampleQuotaUsage(grps, t0.Add(3*time.Minute)), IsNil)
+	samples, err = servicestate.QuotaUsageHistory("foo", time.Time{})
+	c.Assert(err, IsNil)
+	c.Check(samples, DeepEquals, []servicestate.QuotaUsageSample{
+		{Time: t0.Add(2 * time.Minute), Memory: 3 * quantity.SizeMiB, CPU: 200, Threads: 6},
+		{Time: t0.Add(3 * time.Minute), Memory: quantity.SizeMiB, CPU: 0, Threads: 6},
+	})
+	samples, err = servicestate.QuotaUsageHistory("bar", time.Time{})
+	c.Assert(err, IsNil)
+	c.Check(samples, HasLen, 2)
+
+	// samples can be filtered by time
+	samples, err = servicestate.QuotaUsageHistory("foo", t0.Add(3*time.Minute))
+	c.Assert(err, IsNil)
+	c.Check(samples, DeepEquals, []servicestate.QuotaUsageSample{
+		{Time: t0.Add(3 * time.Minute), Memory: quantity.SizeMiB, CPU: 0, Threads: 6},
+	})
+
+	// the group stops, and when it starts again the CPU usage starts over
+	readings["foo"] = nil
+	c.Assert(servicestate.SampleQuotaUsage(grps, t0.Add(4*time.Minute)), IsNil)
+	readings["foo"] = servicestate.NewQuotaGroupReading(quantity.SizeMiB, time.Second, 2)
+	c.Assert(servicestate.SampleQuotaUsage(grps, t0.Add(5*time.Minute)), IsNil)
+	samples, err = servicestate.QuotaUsageHistory("foo", t0.Add(4*time.Minute))
+	c.Assert(err, IsNil)
+	c.Check(samples, HasLen, 0)
+
+	// the history of removed groups is dropped
+	delete(grps, "bar")
+	c.Assert(servicestate.SampleQuotaUsage(grps, t0.Add(6*time.Minute)), IsNil)
+	samples, err = servicestate.QuotaUsageHistory("bar", time.Time{})
+	c.Assert(err, IsNil)
+	c.Check(samples, HasLen, 0)
+	samples, err = servicestate.QuotaUsageHistory("foo", time.Time{})
+	c.Assert(err, IsNil)
+	c.Check(samples, HasLen, 2)
+}
+
+func (s *quotaUsageSuite) TestSampleQuotaUsageCorruptHistory(c *C) {
+	r := servicestate.MockReadQuotaGroupUsage(func(sysd systemd.Systemd, grp *quota.Group) (*servicestate.QuotaGroupReading, error) {
+		return servicestate.NewQuotaGroupReading(quantity.SizeMiB, time.Second, 1), nil
+	})
+	defer r()
+
+	historyFile := filepath.Join(dirs.SnapRunDir, "quota-usage.json")
+	c.Assert(os.MkdirAll(dirs.SnapRunDir, 0755), IsNil)
+	c.Assert(os.WriteFile(historyFile, []byte("{"), 0600), IsNil)
+
+	_, err := servicestate.QuotaUsageHistory("foo", time.Time{})
+	c.Assert(err, ErrorMatches, "cannot decode quota usage history: .*")
+
+	// sampling starts over
+	foo, err := quota.NewGroup("foo", quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	c.Assert(servicestate.SampleQuotaUsage(map[string]*quota.Group{"foo": foo}, time.Now()), IsNil)
+	samples, err := servicestate.QuotaUsageHistory("foo", time.Time{})
+	c.Assert(err, IsNil)
+	c.Check(samples, HasLen, 0)
+}
+
+func (s *quotaUsageSuite) TestEnsureQuotaUsageSampled(c *C) {
+	var sampled []string
+	r := servicestate.MockReadQuotaGroupUsage(func(sysd systemd.Systemd, grp *quota.Group) (*servicestate.QuotaGroupReading, error) {
+		sampled = append(sampled, grp.Name)
+		return nil, nil
+	})
+	defer r()
+
+	historyFile := filepath.Join(dirs.SnapRunDir, "quota-usage.json")
+
+	// without quota groups there is nothing to sample
+	c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+	c.Check(sampled, HasLen, 0)
+	c.Check(historyFile, testutil.FileAbsent)
+
+	s.state.Lock()
+	grp, err := quota.NewGroup("foo", quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	_, err = servicestatetest.PatchQuotas(s.state, grp)
+	c.Assert(err, IsNil)
+
+	// nothing is sampled until seeded
+	s.state.Set("seeded", false)
+	s.state.Unlock()
+	c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+	c.Check(sampled, HasLen, 0)
+
+	s.state.Lock()
+	s.state.Set("seeded", true)
+	s.state.Unlock()
+	c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+	c.Check(sampled, DeepEquals, []string{"foo"})
+	c.Check(historyFile, testutil.FilePresent)
+
+	// and not again until the sampling interval passed
+	c.Assert(s.mgr.Ensure(), IsNil)
+	c.Check(sampled, DeepEquals, []string{"foo"})
+
+	// once the groups are gone the history goes too
+	s.state.Lock()
+	s.state.Set("quotas", nil)
+	s.state.Unlock()
+	restore := servicestate.MockQuotaUsageSampleInterval(0)
+	defer restore()
+	c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+	c.Check(historyFile, testutil.FileAbsent)
+}
+
+// mockSoftLimitsGroup sets up the test snap in a quota group with the given
+// soft limits, and mocks its usage to be read from the returned reading.
+func (s *quotaUsageSuite) mockSoftLimitsGroup(c *C, limits quota.ResourceSoftLimits) *servicestate.QuotaGroupReading {
+	reading := servicestate.NewQuotaGroupReading(0, 0, 1)
+	s.AddCleanup(servicestate.MockReadQuotaGroupUsage(func(sysd systemd.Systemd, grp *quota.Group) (*servicestate.QuotaGroupReading, error) {
+		return reading, nil
+	}))
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded", true)
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYaml, s.testSnapSideInfo)
+	grp, err := quota.NewGroup("foo", quota.NewResourcesBuilder().
+		WithMemoryLimit(quantity.SizeGiB).
+		WithSoftLimits(limits).
+		Build())
+	c.Assert(err, IsNil)
+	grp.Snaps = []string{"test-snap"}
+	_, err = servicestatetest.PatchQuotas(s.state, grp)
+	c.Assert(err, IsNil)
+	return reading
+}
+
+// sampleAt samples the usage of the quota groups at the given time, with the
+// group using the given memory and half a CPU since the previous sample.
+func (s *quotaUsageSuite) sampleAt(c *C, reading *servicestate.QuotaGroupReading, now time.Time, memory quantity.Size) {
+	_, cpu, threads := reading.Values()
+	*reading = *servicestate.NewQuotaGroupReading(memory, cpu+150*time.Second, threads)
+	restore := servicestate.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	c.Assert(s.mgr.EnsureQuotaUsageSampled(), IsNil)
+}
+
+func (s *quotaUsageSuite) softLimitNotices() []*state.Notice {
+	s.state.Lock()
+	defer s.state.Unlock()
+	return s.state.Notices(&state.NoticeFilter{Types: []state.NoticeType{state.QuotaSoftLimitNotice}})
+}
+
+func (s *quotaUsageSuite) TestSoftLimitsNotify(c *C) {
+	reading := s.mockSoftLimitsGroup(c, quota.ResourceSoftLimits{
+		Memory: 100 * quantity.SizeMiB,
+		Window: 10 * time.Minute,
+	})
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	// the first reading sets the starting point
+	s.sampleAt(c, reading, t0, 200*quantity.SizeMiB)
+	// the group is over its soft limit, but not for the whole window yet
+	s.sampleAt(c, reading, t0.Add(5*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(10*time.Minute), 200*quantity.SizeMiB)
+	c.Check(s.softLimitNotices(), HasLen, 0)
+
+	s.sampleAt(c, reading, t0.Add(15*time.Minute), 200*quantity.SizeMiB)
+	notices := s.softLimitNotices()
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].Key(), Equals, "foo")
+	c.Check(notices[0].LastData(), DeepEquals, map[string]string{
+		"action":   "notify",
+		"exceeded": "memory",
+	})
+	_, isSet := notices[0].UserID()
+	c.Check(isSet, Equals, false)
+	lastRepeated := notices[0].LastRepeated()
+
+	// the window starts over, and is interrupted by a sample below the
+	// soft limits
+	s.sampleAt(c, reading, t0.Add(20*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(25*time.Minute), 50*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(30*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(35*time.Minute), 200*quantity.SizeMiB)
+	c.Check(s.softLimitNotices()[0].LastRepeated(), Equals, lastRepeated)
+
+	// no changes are made to the group's services
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *quotaUsageSuite) TestSoftLimitsRestart(c *C) {
+	reading := s.mockSoftLimitsGroup(c, quota.ResourceSoftLimits{
+		CPUPercentage: 20,
+		Window:        5 * time.Minute,
+		Action:        quota.SoftLimitActionRestart,
+	})
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.sampleAt(c, reading, t0, quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(5*time.Minute), quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(10*time.Minute), quantity.SizeMiB)
+
+	notices := s.softLimitNotices()
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].LastData(), DeepEquals, map[string]string{
+		"action":   "restart",
+		"exceeded": "cpu",
+	})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	chgs := s.state.Changes()
+	c.Assert(chgs, HasLen, 1)
+	c.Check(chgs[0].Kind(), Equals, "service-control")
+	c.Check(chgs[0].Summary(), Equals, `Restart services of quota group "foo" over its soft limits`)
+	tasks := chgs[0].Tasks()
+	c.Assert(tasks, HasLen, 1)
+	var action servicestate.ServiceAction
+	c.Assert(tasks[0].Get("service-action", &action), IsNil)
+	c.Check(action, DeepEquals, servicestate.ServiceAction{
+		Action:   "restart",
+		SnapName: "test-snap",
+		Services: []string{"svc1"},
+	})
+}
+
+func (s *quotaUsageSuite) TestSoftLimitsRestartConflict(c *C) {
+	reading := s.mockSoftLimitsGroup(c, quota.ResourceSoftLimits{
+		CPUPercentage: 20,
+		Window:        5 * time.Minute,
+		Action:        quota.SoftLimitActionRestart,
+	})
+
+	s.state.Lock()
+	chg := s.state.NewChange("other", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: s.testSnapSideInfo})
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.sampleAt(c, reading, t0, quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(5*time.Minute), quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(10*time.Minute), quantity.SizeMiB)
+
+	// the services are not restarted under the feet of the other change
+	c.Check(s.softLimitNotices(), HasLen, 0)
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 1)
+}
+
+func (s *quotaUsageSuite) TestSoftLimitsFreeze(c *C) {
+	var frozen, thawed []string
+	s.AddCleanup(cgroup.MockSliceFreezing(func(ctx context.Context, slice string) error {
+		frozen = append(frozen, slice)
+		return nil
+	}, func(slice string) error {
+		thawed = append(thawed, slice)
+		return nil
+	}))
+
+	reading := s.mockSoftLimitsGroup(c, quota.ResourceSoftLimits{
+		Memory:  100 * quantity.SizeMiB,
+		Threads: 1,
+		Window:  5 * time.Minute,
+		Action:  quota.SoftLimitActionFreeze,
+	})
+	_, cpu, _ := reading.Values()
+	*reading = *servicestate.NewQuotaGroupReading(0, cpu, 10)
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.sampleAt(c, reading, t0, 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(5*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(10*time.Minute), 200*quantity.SizeMiB)
+	c.Check(frozen, DeepEquals, []string{"snap.foo.slice"})
+
+	notices := s.softLimitNotices()
+	c.Assert(notices, HasLen, 1)
+	c.Check(notices[0].LastData(), DeepEquals, map[string]string{
+		"action":   "freeze",
+		"exceeded": "memory,threads",
+	})
+	lastRepeated := notices[0].LastRepeated()
+
+	// the group stays frozen
+	s.sampleAt(c, reading, t0.Add(15*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(20*time.Minute), 200*quantity.SizeMiB)
+	c.Check(frozen, HasLen, 1)
+	c.Check(s.softLimitNotices()[0].LastRepeated(), Equals, lastRepeated)
+
+	// until it is thawed when the group is changed
+	s.state.Lock()
+	grp, err := servicestate.GetQuota(s.state, "foo")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Assert(servicestate.ThawQuotaGroup(grp), IsNil)
+	c.Check(thawed, DeepEquals, []string{"snap.foo.slice"})
+
+	// after which the soft limits apply again
+	s.sampleAt(c, reading, t0.Add(25*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(30*time.Minute), 200*quantity.SizeMiB)
+	c.Check(frozen, HasLen, 2)
+}
+
+func (s *quotaUsageSuite) TestSoftLimitsFreezeError(c *C) {
+	s.AddCleanup(cgroup.MockSliceFreezing(func(ctx context.Context, slice string) error {
+		return fmt.Errorf("boom")
+	}, func(slice string) error {
+		return nil
+	}))
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	reading := s.mockSoftLimitsGroup(c, quota.ResourceSoftLimits{
+		Memory: 100 * quantity.SizeMiB,
+		Window: 5 * time.Minute,
+		Action: quota.SoftLimitActionFreeze,
+	})
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.sampleAt(c, reading, t0, 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(5*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(10*time.Minute), 200*quantity.SizeMiB)
+	c.Check(logbuf.String(), testutil.Contains, `cannot freeze quota group "foo" over its soft limits: boom`)
+	c.Check(s.softLimitNotices(), HasLen, 0)
+
+	s.state.Lock()
+	grp, err := servicestate.GetQuota(s.state, "foo")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Assert(servicestate.ThawQuotaGroup(grp), IsNil)
+}
+
+func (s *quotaUsageSuite) TestThawQuota(c *C) {
+	var thawed []string
+	s.AddCleanup(cgroup.MockSliceFreezing(func(ctx context.Context, slice string) error {
+		return nil
+	}, func(slice string) error {
+		thawed = append(thawed, slice)
+		return nil
+	}))
+
+	reading := s.mockSoftLimitsGroup(c, quota.ResourceSoftLimits{
+		Memory: 100 * quantity.SizeMiB,
+		Window: 5 * time.Minute,
+		Action: quota.SoftLimitActionFreeze,
+	})
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.sampleAt(c, reading, t0, 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(5*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(10*time.Minute), 200*quantity.SizeMiB)
+	c.Check(s.softLimitNotices(), HasLen, 1)
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	ts, err := servicestate.ThawQuota(st, "foo")
+	c.Assert(err, IsNil)
+	chg := st.NewChange("quota-control", "...")
+	chg.AddAll(ts)
+	checkQuotaControlTasks(c, chg.Tasks(), &servicestate.QuotaControlAction{
+		Action:    "thaw",
+		QuotaName: "foo",
+	})
+
+	st.Unlock()
+	defer s.se.Stop()
+	err = s.o.Settle(5 * time.Second)
+	st.Lock()
+	c.Assert(err, IsNil)
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+	c.Check(thawed, DeepEquals, []string{"snap.foo.slice"})
+
+	_, err = servicestate.ThawQuota(st, "bar")
+	c.Check(err, ErrorMatches, `cannot thaw non-existent quota group "bar"`)
+}
+
+func (s *quotaUsageSuite) TestThawQuotaGroupCorruptHistory(c *C) {
+	var thawed []string
+	s.AddCleanup(cgroup.MockSliceFreezing(func(ctx context.Context, slice string) error {
+		c.Fatalf("unexpected freeze")
+		return nil
+	}, func(slice string) error {
+		thawed = append(thawed, slice)
+		return nil
+	}))
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	historyFile := filepath.Join(dirs.SnapRunDir, "quota-usage.json")
+	c.Assert(os.MkdirAll(dirs.SnapRunDir, 0755), IsNil)
+	c.Assert(os.WriteFile(historyFile, []byte("{"), 0600), IsNil)
+
+	// whether the group is frozen is unknown, so it is thawed anyway
+	foo, err := quota.NewGroup("foo", quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	c.Assert(servicestate.ThawQuotaGroup(foo), IsNil)
+	c.Check(thawed, DeepEquals, []string{"snap.foo.slice"})
+	c.Check(logbuf.String(), testutil.Contains, `cannot record quota group "foo" as not frozen: cannot decode quota usage history: `)
+}
+
+func (s *quotaUsageSuite) TestThawQuotaGroupAfterHistoryLost(c *C) {
+	var thawed []string
+	s.AddCleanup(cgroup.MockSliceFreezing(func(ctx context.Context, slice string) error {
+		return nil
+	}, func(slice string) error {
+		thawed = append(thawed, slice)
+		return nil
+	}))
+
+	reading := s.mockSoftLimitsGroup(c, quota.ResourceSoftLimits{
+		Memory: 100 * quantity.SizeMiB,
+		Window: 5 * time.Minute,
+		Action: quota.SoftLimitActionFreeze,
+	})
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.sampleAt(c, reading, t0, 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(5*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(10*time.Minute), 200*quantity.SizeMiB)
+	c.Check(s.softLimitNotices(), HasLen, 1)
+
+	// the history is lost, and discarded by the next sampling
+	historyFile := filepath.Join(dirs.SnapRunDir, "quota-usage.json")
+	c.Assert(os.WriteFile(historyFile, []byte("{"), 0600), IsNil)
+	s.sampleAt(c, reading, t0.Add(15*time.Minute), 200*quantity.SizeMiB)
+
+	// the group can still be thawed
+	s.state.Lock()
+	grp, err := servicestate.GetQuota(s.state, "foo")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Assert(servicestate.ThawQuotaGroup(grp), IsNil)
+	c.Check(thawed, DeepEquals, []string{"snap.foo.slice"})
+}
+
+func (s *quotaUsageSuite) TestSoftLimitsFreezeDoesNotBlockThaw(c *C) {
+	var grp *quota.Group
+	var thawed []string
+	s.AddCleanup(cgroup.MockSliceFreezing(func(ctx context.Context, slice string) error {
+		// the group is thawed while it is being frozen, which
+		// would deadlock if the usage history was locked meanwhile
+		c.Assert(servicestate.ThawQuotaGroup(grp), IsNil)
+		return nil
+	}, func(slice string) error {
+		thawed = append(thawed, slice)
+		return nil
+	}))
+
+	reading := s.mockSoftLimitsGroup(c, quota.ResourceSoftLimits{
+		Memory: 100 * quantity.SizeMiB,
+		Window: 5 * time.Minute,
+		Action: quota.SoftLimitActionFreeze,
+	})
+	s.state.Lock()
+	grp, err := servicestate.GetQuota(s.state, "foo")
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+
+	t0 := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.sampleAt(c, reading, t0, 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(5*time.Minute), 200*quantity.SizeMiB)
+	s.sampleAt(c, reading, t0.Add(10*time.Minute), 200*quantity.SizeMiB)
+
+	// the freeze is lifted again once the slice is frozen, and no
+	// action is reported
+	c.Check(thawed, DeepEquals, []string{"snap.foo.slice", "snap.foo.slice"})
+	c.Check(s.softLimitNotices(), HasLen, 0)
+}
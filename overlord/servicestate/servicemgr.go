@@ -46,6 +46,7 @@ import (
 
 func init() {
 	swfeats.RegisterEnsure("ServiceManager", "ensureSnapServicesUpdated")
+	swfeats.RegisterEnsure("ServiceManager", "ensureQuotaUsageSampled")
 }
 
 // ServiceManager is responsible for starting and stopping snap services.
@@ -53,6 +54,8 @@ type ServiceManager struct {
 	state *state.State
 
 	ensuredSnapSvcs bool
+
+	lastQuotaUsageSample time.Time
 }
 
 // Manager returns a new service manager.
@@ -183,6 +186,13 @@ func (m *ServiceManager) ensureSnapServicesUpdated() (err error) {
 		return err
 	}
 
+	// the network quotas do not survive a reboot, load them again
+	if !ensureOpts.Preseeding {
+		if err := ensureNetworkQuotas(allGrps); err != nil {
+			logger.Noticef("cannot ensure network quotas: %v", err)
+		}
+	}
+
 	// if nothing was modified or we are not on UC18+, we are done
 	if len(rewrittenServices) == 0 || deviceCtx.Classic() || deviceCtx.Model().Base() == "" || !serviceKillingMightHaveOccurred {
 		m.ensuredSnapSvcs = true
@@ -211,6 +221,9 @@ func (m *ServiceManager) Ensure() error {
 	if err := m.ensureSnapServicesUpdated(); err != nil {
 		return err
 	}
+	if err := m.ensureQuotaUsageSampled(); err != nil {
+		logger.Noticef("cannot sample quota usage: %v", err)
+	}
 	return nil
 }
 
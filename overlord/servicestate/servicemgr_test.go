@@ -31,16 +31,19 @@ import (
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/dirs/dirstest"
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/restart"
 	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/servicestate/servicestatetest"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
@@ -140,6 +143,11 @@ func (s *baseServiceMgrTestSuite) SetUpTest(c *C) {
 	}
 
 	s.AddCleanup(osutil.MockMountInfo(""))
+
+	// don't sample the usage of the quota groups by default
+	s.AddCleanup(servicestate.MockReadQuotaGroupUsage(func(systemd.Systemd, *quota.Group) (*servicestate.QuotaGroupReading, error) {
+		return nil, nil
+	}))
 }
 
 type expectedSystemctl struct {
@@ -324,6 +332,77 @@ func (s *ensureSnapServiceSuite) TestEnsureSnapServicesSimpleWritesServicesFiles
 	c.Assert(s.restartRequests, HasLen, 0)
 }
 
+func (s *ensureSnapServiceSuite) TestEnsureSnapServicesLoadsNetworkQuotas(c *C) {
+	s.state.Lock()
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYaml, s.testSnapSideInfo)
+	s.AddCleanup(snapstatetest.MockDeviceModel(s.uc16Model))
+	err := servicestatetest.MockQuotaInState(s.state, "foo", "", []string{"test-snap"}, nil,
+		quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	s.state.Unlock()
+
+	var loaded []string
+	restore := servicestate.MockEbpfNetworkQuota(func(group string, rate uint64) (bool, error) {
+		loaded = append(loaded, fmt.Sprintf("%s:%d", group, rate))
+		return false, fmt.Errorf("boom")
+	}, nil, nil)
+	defer restore()
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	// the slice is not started to load the network quota, systemd
+	// attaches it when the slice starts
+	r := s.mockSystemctlCalls(c, []expectedSystemctl{
+		{expArgs: []string{"daemon-reload"}},
+	})
+	defer r()
+
+	// failing to load the network quota does not prevent the ensure
+	// from succeeding
+	err = s.mgr.Ensure()
+	c.Assert(err, IsNil)
+	c.Check(loaded, DeepEquals, []string{
+		fmt.Sprintf("foo:%d", quantity.SizeMiB),
+	})
+	c.Check(logbuf.String(), testutil.Contains, `cannot ensure network quotas: cannot enforce network quota of group "foo": boom`)
+}
+
+func (s *ensureSnapServiceSuite) TestEnsureSnapServicesAttachesNetworkQuotasOfActiveSlices(c *C) {
+	s.state.Lock()
+	snapstate.Set(s.state, "test-snap", s.testSnapState)
+	snaptest.MockSnapCurrent(c, testYaml, s.testSnapSideInfo)
+	s.AddCleanup(snapstatetest.MockDeviceModel(s.uc16Model))
+	for _, name := range []string{"foo", "bar"} {
+		err := servicestatetest.MockQuotaInState(s.state, name, "", nil, nil,
+			quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build())
+		c.Assert(err, IsNil)
+	}
+	s.state.Unlock()
+
+	// the slice of foo was started before snapd, as after a reboot
+	fooCgroup := filepath.Join(dirs.GlobalRootDir, "/sys/fs/cgroup/snap.foo.slice")
+	c.Assert(os.MkdirAll(fooCgroup, 0755), IsNil)
+
+	var attached []string
+	restore := servicestate.MockEbpfNetworkQuota(func(group string, rate uint64) (bool, error) {
+		return true, nil
+	}, func(group, cgroupPath string) error {
+		attached = append(attached, group+":"+cgroupPath)
+		return nil
+	}, nil)
+	defer restore()
+
+	r := s.mockSystemctlCalls(c, []expectedSystemctl{
+		{expArgs: []string{"daemon-reload"}},
+	})
+	defer r()
+
+	err := s.mgr.Ensure()
+	c.Assert(err, IsNil)
+	c.Check(attached, DeepEquals, []string{"foo:" + fooCgroup})
+}
+
 func (s *ensureSnapServiceSuite) TestEnsureSnapServicesSkipsSnapdSnap(c *C) {
 	s.state.Lock()
 	// add an unexpected snapd snap which has services in it, but we
@@ -50,12 +50,14 @@ import (
 )
 
 const (
-	archiveName  = "archive.tgz"
-	metadataName = "meta.json"
-	metaHashName = "meta.sha3_384"
-
-	userArchivePrefix = "user/"
-	userArchiveSuffix = ".tgz"
+	archiveName        = "archive.tgz"
+	chunkedArchiveName = "archive.tar.chunks"
+	metadataName       = "meta.json"
+	metaHashName       = "meta.sha3_384"
+
+	userArchivePrefix        = "user/"
+	userArchiveSuffix        = ".tgz"
+	userChunkedArchiveSuffix = ".tar.chunks"
 )
 
 var (
@@ -323,11 +325,28 @@ func MapSnapDataDirToSnapVar(si *snap.Info, opts *dirs.SnapDirOptions, usernames
 	return mappings, nil
 }
 
+// SaveFlags carries extra flags to drive save behavior.
+type SaveFlags struct {
+	// Deduplicate tells save to store the snapshot data as chunks in the
+	// content-addressed chunk store shared by all snapshot sets, writing
+	// only the chunks that are not there already.
+	Deduplicate bool
+	// KeySource, if set, tells save to encrypt the snapshot data with a
+	// key obtained from it.
+	KeySource KeySource
+}
+
 // Save a snapshot
-func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, dynSnapshotOpts *snap.SnapshotOptions, dirOpts *dirs.SnapDirOptions) (*client.Snapshot, error) {
+func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, dynSnapshotOpts *snap.SnapshotOptions, dirOpts *dirs.SnapDirOptions, flags *SaveFlags) (*client.Snapshot, error) {
 	if err := os.MkdirAll(dirs.SnapshotsDir, 0700); err != nil {
 		return nil, err
 	}
+	if flags == nil {
+		flags = &SaveFlags{}
+	}
+	if flags.Deduplicate && flags.KeySource != nil {
+		return nil, fmt.Errorf("internal error: cannot save an encrypted snapshot deduplicated")
+	}
 
 	snapshot := &client.Snapshot{
 		SetID:    id,
@@ -357,6 +376,31 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]any, use
 		}
 	}
 
+	entry := archiveName
+	userEntry := userArchiveName
+	if flags.Deduplicate {
+		// hold the chunk store so that the chunks written for this
+		// snapshot are not collected before the snapshot is committed
+		lock, err := newChunkStore().lock()
+		if err != nil {
+			return nil, fmt.Errorf("cannot lock chunk store: %v", err)
+		}
+		defer lock.Close()
+		if err := lock.ReadLock(); err != nil {
+			return nil, fmt.Errorf("cannot lock chunk store: %v", err)
+		}
+		entry = chunkedArchiveName
+		userEntry = userChunkedArchiveName
+	}
+
+	var encKey []byte
+	if flags.KeySource != nil {
+		snapshot.Encryption, encKey, err = newEncryption(flags.KeySource)
+		if err != nil {
+			return nil, fmt.Errorf("cannot obtain snapshot key: %v", err)
+		}
+	}
+
 	aw, err := osutil.NewAtomicFile(Filename(snapshot), 0600, 0, osutil.NoChown, osutil.NoChown)
 	if err != nil {
 		return nil, err
@@ -368,7 +412,7 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]any, use
 	defer w.Close() // note this does not close the file descriptor (that's done by hand on the atomic writer, above)
 	savingUserData := false
 	baseDataDir := snap.BaseDataDir(si.InstanceName())
-	if err := addSnapDirToZip(ctx, snapshot, w, "root", archiveName, baseDataDir, savingUserData, snapshotOptions.Exclude); err != nil {
+	if err := addSnapDirToZip(ctx, snapshot, w, "root", entry, baseDataDir, savingUserData, snapshotOptions.Exclude, encKey); err != nil {
 		return nil, err
 	}
 
@@ -380,11 +424,19 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]any, use
 	savingUserData = true
 	for _, usr := range users {
 		snapDataDir := filepath.Dir(si.UserDataDir(usr.HomeDir, dirOpts))
-		if err := addSnapDirToZip(ctx, snapshot, w, usr.Username, userArchiveName(usr), snapDataDir, savingUserData, snapshotOptions.Exclude); err != nil {
+		if err := addSnapDirToZip(ctx, snapshot, w, usr.Username, userEntry(usr), snapDataDir, savingUserData, snapshotOptions.Exclude, encKey); err != nil {
 			return nil, err
 		}
 	}
 
+	if encKey != nil && snapshot.Conf != nil {
+		// the configuration is data of the snap too
+		if err := addConfToZip(snapshot, w, encKey); err != nil {
+			return nil, err
+		}
+		snapshot.Conf = nil
+	}
+
 	metaWriter, err := w.Create(metadataName)
 	if err != nil {
 		return nil, err
@@ -421,7 +473,7 @@ var isTesting = snapdenv.Testing()
 // addSnapDirToZip adds the 'common' and the 'rev' revisioned dir under 'snapDir'
 // to the snapshot. If one doesn't exist, it's ignored. If none exists, the
 // operation is skipped.
-func addSnapDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, username, entry, snapDir string, savingUserData bool, excludePaths []string) error {
+func addSnapDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, username, entry, snapDir string, savingUserData bool, excludePaths []string, encKey []byte) error {
 	paths, err := pathsForSnapshot(snapDir, snapshot)
 	if err != nil {
 		return err
@@ -464,24 +516,38 @@ func addSnapDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writ
 		expExcludePaths = append(expExcludePaths, expandedPath)
 	}
 
-	return addToZip(ctx, snapshot, w, username, entry, paths, expExcludePaths)
+	return addToZip(ctx, snapshot, w, username, entry, paths, expExcludePaths, encKey)
 }
 
 // addToZip adds 'paths' to the snapshot. tar will change into the paths' parent
 // directory before creating the archive so that parent dirs are not added.
-func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, username, entry string, paths []string, excludePaths []string) error {
+//
+// If entry is a chunked entry, the (uncompressed) archive is split into
+// chunks that are added to the chunk store, and only the manifest listing
+// them is added to the zip.
+//
+// If encKey is set, the archive is encrypted with it.
+func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, username, entry string, paths []string, excludePaths []string, encKey []byte) error {
 	archiveWriter, err := w.CreateHeader(&zip.FileHeader{Name: entry})
 	if err != nil {
 		return err
 	}
 
+	chunked := isChunkedEntry(entry)
 	tarArgs := []string{
 		"--create",
-		"--sparse", "--gzip",
+		"--sparse",
+	}
+	if !chunked {
+		// compressing the stream as a whole would defeat
+		// deduplication; chunks are compressed individually
+		tarArgs = append(tarArgs, "--gzip")
+	}
+	tarArgs = append(tarArgs,
 		"--format", "gnu",
 		"--anchored",
 		"--no-wildcards-match-slash",
-	}
+	)
 
 	for _, path := range excludePaths {
 		tarArgs = append(tarArgs, fmt.Sprintf("--exclude=%s", path))
@@ -496,8 +562,27 @@ func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, use
 	var sz osutil.Sizer
 	hasher := crypto.SHA3_384.New()
 
+	var chunks *chunker
+	var encWriter *encryptingWriter
+	var dataWriter io.Writer
+	switch {
+	case chunked:
+		chunks = newChunker(newChunkStore())
+		dataWriter = io.MultiWriter(chunks, hasher)
+	case encKey != nil:
+		// the hash is that of the sealed entry, so that the metadata
+		// does not give away fingerprints of the data
+		encWriter, err = newEncryptingWriter(io.MultiWriter(archiveWriter, hasher), encKey, entryAAD(snapshot.Snap, entry))
+		if err != nil {
+			return err
+		}
+		dataWriter = encWriter
+	default:
+		dataWriter = io.MultiWriter(archiveWriter, hasher)
+	}
+
 	cmd := tarAsUser(ctx, username, tarArgs...)
-	cmd.Stdout = io.MultiWriter(archiveWriter, hasher, &sz)
+	cmd.Stdout = io.MultiWriter(dataWriter, &sz)
 
 	// keep (at most) the last 5 non-empty lines of what 'tar' writes to stderr
 	// (those are the most likely contain the reason for fatal errors)
@@ -527,12 +612,48 @@ func addToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, use
 		return fmt.Errorf("tar failed: %v", err)
 	}
 
+	if chunked {
+		if err := chunks.Close(); err != nil {
+			return err
+		}
+		if err := json.NewEncoder(archiveWriter).Encode(&chunks.manifest); err != nil {
+			return err
+		}
+	}
+	if encWriter != nil {
+		if err := encWriter.Close(); err != nil {
+			return err
+		}
+	}
+
 	snapshot.SHA3_384[entry] = fmt.Sprintf("%x", hasher.Sum(nil))
 	snapshot.Size += sz.Size()
 
 	return nil
 }
 
+// addConfToZip adds the snap configuration of the snapshot as an encrypted
+// entry.
+func addConfToZip(snapshot *client.Snapshot, w *zip.Writer, encKey []byte) error {
+	confWriter, err := w.CreateHeader(&zip.FileHeader{Name: confName})
+	if err != nil {
+		return err
+	}
+	hasher := crypto.SHA3_384.New()
+	encWriter, err := newEncryptingWriter(io.MultiWriter(confWriter, hasher), encKey, entryAAD(snapshot.Snap, confName))
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(encWriter).Encode(snapshot.Conf); err != nil {
+		return err
+	}
+	if err := encWriter.Close(); err != nil {
+		return err
+	}
+	snapshot.SHA3_384[confName] = fmt.Sprintf("%x", hasher.Sum(nil))
+	return nil
+}
+
 // pathsForSnapshot returns a list of absolute paths under 'snapDir' that should
 // be included in the snapshot (based on what directories exist).
 func pathsForSnapshot(snapDir string, snapshot *client.Snapshot) ([]string, error) {
@@ -754,6 +875,9 @@ type ImportFlags struct {
 	// noDuplicatedImportCheck tells import not to check for existing snapshot
 	// with same content hash (and not report DuplicatedSnapshotImportError).
 	NoDuplicatedImportCheck bool
+	// KeySourceFor, if set, returns the key source to verify imported
+	// snapshots encrypted with a key of the given kind.
+	KeySourceFor func(kind string) (KeySource, error)
 }
 
 // Import a snapshot from the export file format
@@ -762,6 +886,30 @@ func Import(ctx context.Context, id uint64, r io.Reader, flags *ImportFlags) (sn
 		return nil, err
 	}
 
+	// the import may bring chunks along, which must not be collected
+	// before the snapshots referencing them are committed
+	var chunkLock *osutil.FileLock
+	defer func() {
+		if chunkLock != nil {
+			chunkLock.Close()
+		}
+	}()
+	lockChunkStore := func() error {
+		if chunkLock != nil {
+			return nil
+		}
+		lock, err := newChunkStore().lock()
+		if err != nil {
+			return fmt.Errorf("cannot lock chunk store: %v", err)
+		}
+		if err := lock.ReadLock(); err != nil {
+			lock.Close()
+			return fmt.Errorf("cannot lock chunk store: %v", err)
+		}
+		chunkLock = lock
+		return nil
+	}
+
 	errPrefix := fmt.Sprintf("cannot import snapshot %d", id)
 
 	tr := newImportTransaction(id)
@@ -779,7 +927,7 @@ func Import(ctx context.Context, id uint64, r io.Reader, flags *ImportFlags) (sn
 	// XXX: this will leak snapshot IDs, i.e. we allocate a new
 	// snapshot ID before but then we error here because of e.g.
 	// duplicated import attempts
-	snapNames, err = unpackVerifySnapshotImport(ctx, r, id, flags)
+	snapNames, err = unpackVerifySnapshotImport(ctx, r, id, flags, lockChunkStore)
 	if err != nil {
 		if _, ok := err.(DuplicatedSnapshotImportError); ok {
 			return nil, err
@@ -847,7 +995,7 @@ func checkDuplicatedSnapshotSetWithContentHash(ctx context.Context, contentHash
 	return nil
 }
 
-func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint64, flags *ImportFlags) (snapNames []string, err error) {
+func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint64, flags *ImportFlags, lockChunkStore func() error) (snapNames []string, err error) {
 	var exportFound bool
 
 	tr := tar.NewReader(r)
@@ -895,6 +1043,18 @@ func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint
 			continue
 		}
 
+		if strings.HasPrefix(header.Name, exportChunksPrefix) {
+			// chunks come before the snapshots referencing them
+			if err := lockChunkStore(); err != nil {
+				return nil, err
+			}
+			h := strings.TrimPrefix(header.Name, exportChunksPrefix)
+			if err := newChunkStore().importChunk(h, tr); err != nil {
+				return nil, fmt.Errorf("cannot import chunk: %v", err)
+			}
+			continue
+		}
+
 		if header.Name == "export.json" {
 			// XXX: read into memory and validate once we
 			// hashes in export.json
@@ -922,7 +1082,10 @@ func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint
 		if err != nil {
 			return snapNames, fmt.Errorf("cannot open snapshot: %v", err)
 		}
-		err = r.Check(context.TODO(), nil)
+		err = unlockImported(r, flags)
+		if err == nil {
+			err = r.Check(context.TODO(), nil)
+		}
 		r.Close()
 		snapNames = append(snapNames, r.Snap)
 		if err != nil {
@@ -938,16 +1101,39 @@ func unpackVerifySnapshotImport(ctx context.Context, r io.Reader, realSetID uint
 	return snapNames, nil
 }
 
+// unlockImported unlocks an imported snapshot if it is encrypted, so that
+// its authentication tags can be checked.
+func unlockImported(r *Reader, flags *ImportFlags) error {
+	if r.Encryption == nil {
+		return nil
+	}
+	if flags.KeySourceFor == nil {
+		return fmt.Errorf("snapshot is encrypted with a %q key, but no key was provided", r.Encryption.KeySource)
+	}
+	ks, err := flags.KeySourceFor(r.Encryption.KeySource)
+	if err != nil {
+		return err
+	}
+	return r.Unlock(ks)
+}
+
 type exportMetadata struct {
 	Format int       `json:"format"`
 	Date   time.Time `json:"date"`
 	Files  []string  `json:"files"`
 }
 
+// exportChunksPrefix is the prefix in the export stream of the chunks
+// referenced by deduplicated snapshots.
+const exportChunksPrefix = "chunks/"
+
 type SnapshotExport struct {
 	// open snapshot files
 	snapshotFiles []*os.File
 
+	// hashes of the chunks referenced by the snapshots
+	chunks []string
+
 	// contentHash of the full snapshot
 	contentHash []byte
 
@@ -963,6 +1149,7 @@ type SnapshotExport struct {
 func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, err error) {
 	var snapshotFiles []*os.File
 	var snapshotSet client.SnapshotSet
+	chunks := make(map[string]bool)
 
 	defer func() {
 		// cleanup any open FDs if anything goes wrong
@@ -981,6 +1168,14 @@ func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, e
 		if reader.SetID == setID {
 			snapshotSet.Snapshots = append(snapshotSet.Snapshots, &reader.Snapshot)
 
+			refs, err := reader.chunkRefs()
+			if err != nil {
+				return err
+			}
+			for _, h := range refs {
+				chunks[h] = true
+			}
+
 			// Duplicate the file descriptor of the reader
 			// we were handed as Iter() closes those as
 			// soon as this unnamed returns. We re-package
@@ -1010,6 +1205,10 @@ func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, e
 		return nil, fmt.Errorf("cannot calculate content hash for snapshot export %v: %v", setID, err)
 	}
 	se = &SnapshotExport{snapshotFiles: snapshotFiles, setID: setID, contentHash: h}
+	for h := range chunks {
+		se.chunks = append(se.chunks, h)
+	}
+	sort.Strings(se.chunks)
 
 	// ensure we never leak FDs even if the user does not call close
 	runtime.SetFinalizer(se, (*SnapshotExport).Close)
@@ -1077,6 +1276,15 @@ func (se *SnapshotExport) StreamTo(w io.Writer) error {
 		return err
 	}
 
+	// write out the chunks referenced by deduplicated snapshots, so that
+	// they are in place by the time the snapshots are checked on import
+	store := newChunkStore()
+	for _, h := range se.chunks {
+		if err := writeChunkToTar(tw, store, h); err != nil {
+			return err
+		}
+	}
+
 	// write out the individual snapshots
 	for _, snapshotFile := range se.snapshotFiles {
 		stat, err := snapshotFile.Stat()
@@ -1141,3 +1349,29 @@ func (se *SnapshotExport) StreamTo(w io.Writer) error {
 
 	return nil
 }
+
+func writeChunkToTar(tw *tar.Writer, store *chunkStore, h string) error {
+	f, err := os.Open(store.path(h))
+	if err != nil {
+		return fmt.Errorf("cannot open chunk %.7s…: %v", h, err)
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     exportChunksPrefix + h,
+		Size:     stat.Size(),
+		Mode:     0600,
+		ModTime:  stat.ModTime(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("cannot write header for chunk %.7s…: %v", h, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("cannot write data for chunk %.7s…: %v", h, err)
+	}
+	return nil
+}
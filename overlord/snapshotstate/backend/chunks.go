@@ -0,0 +1,555 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// Deduplicated snapshots store the (uncompressed) tar stream of each data
+// directory as a list of content-defined chunks. The chunks themselves live
+// in a content-addressed store shared by all snapshot sets, and the zip
+// entry for the archive only carries the manifest listing them. Sets saved
+// one after the other thus form a chain through the chunks they share, and
+// saving a set only writes the chunks that changed since the previous ones.
+
+const (
+	chunksDirName  = "chunks"
+	chunksLockName = "lock"
+
+	chunkManifestFormat = 1
+)
+
+var (
+	// chunk size parameters for the content-defined chunker; the average
+	// chunk size is determined by the number of bits set in the mask. With
+	// the gear hash, a byte is shifted out of bit i of the fingerprint after
+	// i+1 more bytes, so as in FastCDC the mask uses the most significant
+	// bits, for the boundaries to depend on the last 64 bytes rather than
+	// on as few bytes as there are bits in the mask.
+	chunkMinSize = 256 * 1024
+	chunkMaxSize = 4 * 1024 * 1024
+	chunkMask    = uint64(1<<20-1) << (64 - 20)
+)
+
+// maxCompressedChunkSize returns the largest size a gzip-compressed chunk
+// can have, allowing for the overhead of storing incompressible data.
+func maxCompressedChunkSize() int64 {
+	return int64(chunkMaxSize) + int64(chunkMaxSize)/64 + 1024
+}
+
+// gearTable holds the per-byte values for the gear rolling hash used to find
+// chunk boundaries. It must never change, or previously stored chunks would
+// no longer be reused.
+var gearTable = func() (table [256]uint64) {
+	// splitmix64, seeded with a fixed value
+	seed := uint64(0x736e617073686f74)
+	for i := range table {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// chunkRef is a reference to a chunk in the chunk store.
+type chunkRef struct {
+	SHA3_384 string `json:"sha3-384"`
+	Size     int64  `json:"size"`
+}
+
+// chunkManifest is stored in the snapshot zip in place of the archive of a
+// deduplicated snapshot.
+type chunkManifest struct {
+	Format int        `json:"format"`
+	Size   int64      `json:"size"`
+	Chunks []chunkRef `json:"chunks"`
+}
+
+// isChunkedEntry returns true if the given zip entry is a chunk manifest
+// rather than an archive.
+func isChunkedEntry(entry string) bool {
+	return entry == chunkedArchiveName || strings.HasSuffix(entry, userChunkedArchiveSuffix)
+}
+
+// chunkStore is a content-addressed store of gzip-compressed chunks,
+// keyed by the hash of their uncompressed data.
+type chunkStore struct {
+	dir string
+}
+
+func newChunkStore() *chunkStore {
+	return &chunkStore{dir: filepath.Join(dirs.SnapshotsDir, chunksDirName)}
+}
+
+func isChunkHash(h string) bool {
+	if len(h) != crypto.SHA3_384.Size()*2 {
+		return false
+	}
+	_, err := hex.DecodeString(h)
+	return err == nil
+}
+
+func (cs *chunkStore) path(h string) string {
+	return filepath.Join(cs.dir, h[:2], h)
+}
+
+// lock returns the lock for the chunk store. Operations adding chunks take
+// a shared lock for as long as the chunks they add are not referenced from a
+// snapshot on disk; garbage collection takes an exclusive one.
+func (cs *chunkStore) lock() (*osutil.FileLock, error) {
+	if err := os.MkdirAll(cs.dir, 0700); err != nil {
+		return nil, err
+	}
+	return osutil.NewFileLockWithMode(filepath.Join(cs.dir, chunksLockName), 0600)
+}
+
+func (cs *chunkStore) has(h string) bool {
+	return osutil.FileExists(cs.path(h))
+}
+
+// add stores the given chunk data unless it's already present.
+func (cs *chunkStore) add(h string, data []byte) error {
+	if cs.has(h) {
+		return nil
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return cs.addCompressed(h, &buf)
+}
+
+func (cs *chunkStore) addCompressed(h string, r io.Reader) error {
+	p := cs.path(h)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	return osutil.AtomicWrite(p, r, 0600, 0)
+}
+
+// importChunk adds an already compressed chunk to the store, verifying that
+// its content matches its hash.
+func (cs *chunkStore) importChunk(h string, r io.Reader) error {
+	if !isChunkHash(h) {
+		return fmt.Errorf("invalid chunk name %q", h)
+	}
+	maxSize := maxCompressedChunkSize()
+	compressed, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(compressed)) > maxSize {
+		return fmt.Errorf("chunk %.7s… is too big", h)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return fmt.Errorf("cannot decompress chunk %.7s…: %v", h, err)
+	}
+	hasher := crypto.SHA3_384.New()
+	n, err := io.Copy(hasher, io.LimitReader(gz, int64(chunkMaxSize)+1))
+	if err != nil {
+		return fmt.Errorf("cannot decompress chunk %.7s…: %v", h, err)
+	}
+	if n > int64(chunkMaxSize) {
+		return fmt.Errorf("chunk %.7s… is too big", h)
+	}
+	if actual := fmt.Sprintf("%x", hasher.Sum(nil)); actual != h {
+		return fmt.Errorf("chunk %.7s… does not match its content hash (%.7s…)", h, actual)
+	}
+	if cs.has(h) {
+		return nil
+	}
+	return cs.addCompressed(h, bytes.NewReader(compressed))
+}
+
+// chunker splits the data written to it into content-defined chunks, and
+// stores them in the chunk store.
+type chunker struct {
+	store    *chunkStore
+	manifest chunkManifest
+
+	buf []byte
+	// pos is the offset in buf up to which boundaries have been looked for
+	pos int
+	// fp is the rolling hash at pos
+	fp uint64
+}
+
+func newChunker(store *chunkStore) *chunker {
+	return &chunker{
+		store:    store,
+		manifest: chunkManifest{Format: chunkManifestFormat},
+	}
+}
+
+func (c *chunker) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+	for {
+		cut := c.findBoundary()
+		if cut < 0 {
+			break
+		}
+		if err := c.emit(c.buf[:cut]); err != nil {
+			return 0, err
+		}
+		c.buf = append(c.buf[:0], c.buf[cut:]...)
+		c.pos = 0
+		c.fp = 0
+	}
+	return len(p), nil
+}
+
+// findBoundary returns the length of the next chunk in the buffer, or -1 if
+// more data is needed to determine it.
+func (c *chunker) findBoundary() int {
+	if c.pos < chunkMinSize {
+		c.pos = chunkMinSize
+	}
+	for ; c.pos < len(c.buf); c.pos++ {
+		if c.pos >= chunkMaxSize {
+			return chunkMaxSize
+		}
+		c.fp = (c.fp << 1) + gearTable[c.buf[c.pos]]
+		if c.fp&chunkMask == 0 {
+			return c.pos + 1
+		}
+	}
+	if len(c.buf) >= chunkMaxSize {
+		return chunkMaxSize
+	}
+	return -1
+}
+
+func (c *chunker) emit(data []byte) error {
+	hasher := crypto.SHA3_384.New()
+	hasher.Write(data)
+	h := fmt.Sprintf("%x", hasher.Sum(nil))
+	if err := c.store.add(h, data); err != nil {
+		return fmt.Errorf("cannot store chunk %.7s…: %v", h, err)
+	}
+	c.manifest.Chunks = append(c.manifest.Chunks, chunkRef{SHA3_384: h, Size: int64(len(data))})
+	c.manifest.Size += int64(len(data))
+	return nil
+}
+
+// Close stores whatever data is left as the last chunk.
+func (c *chunker) Close() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	if err := c.emit(c.buf); err != nil {
+		return err
+	}
+	c.buf = nil
+	return nil
+}
+
+// chunkReader reassembles the data described by a manifest from the chunk
+// store, verifying each chunk as it goes.
+type chunkReader struct {
+	store  *chunkStore
+	chunks []chunkRef
+
+	cur    io.ReadCloser
+	ref    chunkRef
+	hasher hash.Hash
+	sz     osutil.Sizer
+}
+
+func newChunkReader(store *chunkStore, manifest *chunkManifest) *chunkReader {
+	return &chunkReader{
+		store:  store,
+		chunks: manifest.Chunks,
+		hasher: crypto.SHA3_384.New(),
+	}
+}
+
+func (r *chunkReader) next() error {
+	ref := r.chunks[0]
+	r.chunks = r.chunks[1:]
+	if !isChunkHash(ref.SHA3_384) {
+		return fmt.Errorf("invalid chunk reference %q", ref.SHA3_384)
+	}
+	f, err := os.Open(r.store.path(ref.SHA3_384))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("chunk %.7s… is missing from the chunk store", ref.SHA3_384)
+		}
+		return err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("cannot decompress chunk %.7s…: %v", ref.SHA3_384, err)
+	}
+	r.cur = &gzipFile{Reader: gz, f: f}
+	r.ref = ref
+	r.hasher.Reset()
+	r.sz.Reset()
+	return nil
+}
+
+func (r *chunkReader) finishChunk() error {
+	if err := r.cur.Close(); err != nil {
+		return err
+	}
+	r.cur = nil
+	if r.sz.Size() != r.ref.Size {
+		return fmt.Errorf("chunk %.7s… size (%d) does not match actual (%d)", r.ref.SHA3_384, r.ref.Size, r.sz.Size())
+	}
+	if actual := fmt.Sprintf("%x", r.hasher.Sum(nil)); actual != r.ref.SHA3_384 {
+		return fmt.Errorf("chunk %.7s… does not match its content hash (%.7s…)", r.ref.SHA3_384, actual)
+	}
+	return nil
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+		n, err := r.cur.Read(p)
+		r.hasher.Write(p[:n])
+		r.sz.Write(p[:n])
+		if err == io.EOF {
+			if err := r.finishChunk(); err != nil {
+				return n, err
+			}
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+	return nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// readChunkManifest reads the manifest stored in the given entry of the
+// snapshot zip.
+func readChunkManifest(f *os.File, entry string) (*chunkManifest, error) {
+	body, reportedSize, err := zipMember(f, entry)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var sz osutil.Sizer
+	var manifest chunkManifest
+	if err := json.NewDecoder(io.TeeReader(body, &sz)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("cannot decode chunk manifest %q: %v", entry, err)
+	}
+	// drain the (trailing newline of the) entry so the size is accurate
+	if _, err := io.Copy(&sz, body); err != nil {
+		return nil, err
+	}
+	if sz.Size() != reportedSize {
+		return nil, fmt.Errorf("chunk manifest %q size (%d) different from actual (%d)", entry, reportedSize, sz.Size())
+	}
+	if manifest.Format != chunkManifestFormat {
+		return nil, fmt.Errorf("unsupported chunk manifest format %d in %q", manifest.Format, entry)
+	}
+	return &manifest, nil
+}
+
+// openEntry returns a reader for the archive data of the given entry of the
+// snapshot together with its expected size. For deduplicated snapshots the
+// data is reassembled from the chunk store, and for encrypted ones it is
+// decrypted. If a hasher is given, it is fed the data the hash recorded in
+// the metadata for the entry covers as it is read: the archive data, or the
+// sealed entry for encrypted snapshots.
+func (r *Reader) openEntry(entry string, hasher hash.Hash) (io.ReadCloser, int64, error) {
+	hashed := func(rc io.ReadCloser) io.ReadCloser {
+		if hasher == nil {
+			return rc
+		}
+		return &teeReadCloser{Reader: io.TeeReader(rc, hasher), Closer: rc}
+	}
+	if r.Encryption != nil {
+		if r.key == nil {
+			return nil, -1, fmt.Errorf("snapshot %q is encrypted and has not been unlocked", r.Name())
+		}
+		body, size, err := zipMember(r.File, entry)
+		if err != nil {
+			return nil, -1, err
+		}
+		dr, err := newDecryptingReader(hashed(body), r.key, entryAAD(r.Snap, entry))
+		if err != nil {
+			body.Close()
+			return nil, -1, fmt.Errorf("snapshot entry %q: %v", entry, err)
+		}
+		return dr, plaintextSize(size), nil
+	}
+	if !isChunkedEntry(entry) {
+		body, size, err := zipMember(r.File, entry)
+		if err != nil {
+			return nil, -1, err
+		}
+		return hashed(body), size, nil
+	}
+	manifest, err := readChunkManifest(r.File, entry)
+	if err != nil {
+		return nil, -1, err
+	}
+	return hashed(newChunkReader(newChunkStore(), manifest)), manifest.Size, nil
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// chunkRefs returns the hashes of all the chunks referenced by the
+// snapshot.
+func (r *Reader) chunkRefs() ([]string, error) {
+	var refs []string
+	for entry := range r.SHA3_384 {
+		if !isChunkedEntry(entry) {
+			continue
+		}
+		manifest, err := readChunkManifest(r.File, entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range manifest.Chunks {
+			refs = append(refs, ref.SHA3_384)
+		}
+	}
+	return refs, nil
+}
+
+// GarbageCollectChunks removes from the chunk store all the chunks that are
+// no longer referenced by any snapshot, returning how many were removed. It
+// does nothing if deduplicated snapshots were never saved, and backs off if
+// a save or import is currently adding chunks.
+func GarbageCollectChunks(ctx context.Context) (removed int, err error) {
+	store := newChunkStore()
+	if !osutil.IsDirectory(store.dir) {
+		return 0, nil
+	}
+
+	lock, err := store.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Close()
+	if err := lock.TryLock(); err != nil {
+		if errors.Is(err, osutil.ErrAlreadyLocked) {
+			logger.Debugf("Not collecting snapshot chunks: chunk store in use.")
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	referenced := make(map[string]bool)
+	err = Iter(ctx, func(r *Reader) error {
+		if r.Broken != "" {
+			// it's not possible to know what chunks a broken
+			// snapshot needs; removing anything could make it
+			// worse
+			return fmt.Errorf("snapshot %q is broken: %s", r.Name(), r.Broken)
+		}
+		refs, err := r.chunkRefs()
+		if err != nil {
+			return fmt.Errorf("cannot read chunk references of %q: %v", r.Name(), err)
+		}
+		for _, h := range refs {
+			referenced[h] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot collect snapshot chunks: %v", err)
+	}
+
+	prefixes, err := filepath.Glob(filepath.Join(store.dir, "??"))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(prefixes)
+	var errs []error
+	for _, prefix := range prefixes {
+		names, err := filepath.Glob(filepath.Join(prefix, "*"))
+		if err != nil {
+			return removed, err
+		}
+		for _, p := range names {
+			if h := filepath.Base(p); !isChunkHash(h) || referenced[h] {
+				continue
+			}
+			if err := os.Remove(p); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			removed++
+		}
+		// drop the prefix directory if it became empty
+		os.Remove(prefix)
+	}
+	if len(errs) > 0 {
+		return removed, newMultiError("cannot remove unreferenced chunks", errs)
+	}
+	return removed, nil
+}
@@ -0,0 +1,297 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/osutil/user"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/systemd/systemdtest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type chunksSuite struct {
+	testutil.BaseTest
+	info *snap.Info
+}
+
+var _ = check.Suite(&chunksSuite{})
+
+func (s *chunksSuite) SetUpTest(c *check.C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	// only the system data is saved; user data requires running tar
+	// as another user
+	s.AddCleanup(backend.MockUsersForUsernames(func([]string, *dirs.SnapDirOptions) ([]*user.User, error) {
+		return nil, nil
+	}))
+	s.AddCleanup(backend.MockIsTesting(true))
+	s.AddCleanup(backend.MockChunkSizes(1024, 16*1024, (1<<11-1)<<(64-11)))
+	s.AddCleanup(osutil.MockMountInfo(""))
+	s.AddCleanup(systemd.MockNewSystemd(func(systemd.Backend, string, systemd.InstanceMode, systemd.Reporter) systemd.Systemd {
+		return &systemdtest.FakeSystemd{}
+	}))
+	logger.SimpleSetup(nil)
+
+	s.info = &snap.Info{SideInfo: snap.SideInfo{RealName: "hello-snap", Revision: snap.R(42), SnapID: "hello-id"}, Version: "v1.33"}
+
+	// some data that is big enough to be split into several chunks
+	data := make([]byte, 256*1024)
+	rand.New(rand.NewSource(42)).Read(data)
+	c.Assert(os.MkdirAll(s.info.DataDir(), 0755), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(s.info.DataDir(), "big"), data, 0644), check.IsNil)
+	c.Assert(os.MkdirAll(s.info.CommonDataDir(), 0755), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(s.info.CommonDataDir(), "common"), []byte("common canary\n"), 0644), check.IsNil)
+}
+
+func chunkFiles(c *check.C) []string {
+	names, err := filepath.Glob(filepath.Join(dirs.SnapshotsDir, "chunks", "??", "*"))
+	c.Assert(err, check.IsNil)
+	return names
+}
+
+func (s *chunksSuite) save(c *check.C, setID uint64) *client.Snapshot {
+	shw, err := backend.Save(context.TODO(), setID, s.info, nil, nil, nil, nil, &backend.SaveFlags{Deduplicate: true})
+	c.Assert(err, check.IsNil)
+	return shw
+}
+
+func (s *chunksSuite) TestSaveDeduplicated(c *check.C) {
+	shw := s.save(c, 1)
+	c.Check(hashkeys(shw), check.DeepEquals, []string{"archive.tar.chunks"})
+	c.Check(shw.Size > 256*1024, check.Equals, true)
+
+	chunks := chunkFiles(c)
+	c.Check(len(chunks) > 1, check.Equals, true)
+
+	// saving the same data again does not add any chunks
+	s.save(c, 2)
+	c.Check(chunkFiles(c), check.DeepEquals, chunks)
+
+	// adding a file only adds the chunks around it
+	c.Assert(os.WriteFile(filepath.Join(s.info.DataDir(), "small"), []byte("small change\n"), 0644), check.IsNil)
+	s.save(c, 3)
+	added := len(chunkFiles(c)) - len(chunks)
+	c.Check(added > 0, check.Equals, true)
+	c.Check(added < len(chunks)/2, check.Equals, true, check.Commentf("added %d chunks out of %d", added, len(chunks)))
+
+	sets, err := backend.List(context.TODO(), 0, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(sets, check.HasLen, 3)
+}
+
+func (s *chunksSuite) TestCheckAndRestoreDeduplicated(c *check.C) {
+	shw := s.save(c, 1)
+
+	shr, err := backend.Open(backend.Filename(shw), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer shr.Close()
+	c.Check(shr.SHA3_384, check.DeepEquals, shw.SHA3_384)
+	c.Check(shr.Check(context.TODO(), nil), check.IsNil)
+
+	bigPath := filepath.Join(s.info.DataDir(), "big")
+	data, err := os.ReadFile(bigPath)
+	c.Assert(err, check.IsNil)
+	c.Assert(os.WriteFile(bigPath, []byte("scribble"), 0644), check.IsNil)
+
+	rs, err := shr.Restore(context.TODO(), snap.R(0), nil, logger.Debugf, nil)
+	c.Assert(err, check.IsNil)
+	rs.Cleanup()
+
+	restored, err := os.ReadFile(bigPath)
+	c.Assert(err, check.IsNil)
+	c.Check(bytes.Equal(restored, data), check.Equals, true)
+}
+
+func (s *chunksSuite) TestCheckDeduplicatedMissingChunk(c *check.C) {
+	shw := s.save(c, 1)
+
+	chunks := chunkFiles(c)
+	c.Assert(chunks, check.Not(check.HasLen), 0)
+	c.Assert(os.Remove(chunks[0]), check.IsNil)
+
+	shr, err := backend.Open(backend.Filename(shw), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer shr.Close()
+	c.Check(shr.Check(context.TODO(), nil), check.ErrorMatches, `chunk [0-9a-f]{7}… is missing from the chunk store`)
+
+	_, err = shr.Restore(context.TODO(), snap.R(0), nil, logger.Debugf, nil)
+	c.Check(err, check.NotNil)
+}
+
+func (s *chunksSuite) TestCheckDeduplicatedCorruptChunk(c *check.C) {
+	shw := s.save(c, 1)
+
+	chunks := chunkFiles(c)
+	c.Assert(chunks, check.Not(check.HasLen), 0)
+	// a valid gzip stream, but of the wrong data
+	other := chunks[len(chunks)-1]
+	if other == chunks[0] {
+		c.Skip("need at least two chunks")
+	}
+	otherData, err := os.ReadFile(other)
+	c.Assert(err, check.IsNil)
+	c.Assert(os.WriteFile(chunks[0], otherData, 0600), check.IsNil)
+
+	shr, err := backend.Open(backend.Filename(shw), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer shr.Close()
+	c.Check(shr.Check(context.TODO(), nil), check.ErrorMatches, `chunk [0-9a-f]{7}… (size .*|does not match its content hash .*)`)
+}
+
+func (s *chunksSuite) TestImportChunkTooBig(c *check.C) {
+	compress := func(data []byte) (string, *bytes.Buffer) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, err := gz.Write(data)
+		c.Assert(err, check.IsNil)
+		c.Assert(gz.Close(), check.IsNil)
+		hasher := crypto.SHA3_384.New()
+		hasher.Write(data)
+		return fmt.Sprintf("%x", hasher.Sum(nil)), &buf
+	}
+
+	// data which compresses well, but is bigger than a chunk can be
+	h, compressed := compress(make([]byte, 32*1024))
+	c.Check(backend.ImportChunk(h, compressed), check.ErrorMatches, `chunk [0-9a-f]{7}… is too big`)
+
+	// data which does not compress, and is read only up to the limit
+	data := make([]byte, 64*1024)
+	rand.New(rand.NewSource(42)).Read(data)
+	c.Check(backend.ImportChunk(h, bytes.NewReader(data)), check.ErrorMatches, `chunk [0-9a-f]{7}… is too big`)
+
+	c.Check(chunkFiles(c), check.HasLen, 0)
+
+	// a chunk of the maximum size is fine
+	h, compressed = compress(data[:16*1024])
+	c.Check(backend.ImportChunk(h, compressed), check.IsNil)
+	c.Check(chunkFiles(c), check.HasLen, 1)
+}
+
+func (s *chunksSuite) TestGarbageCollectChunks(c *check.C) {
+	// nothing to do without a chunk store
+	removed, err := backend.GarbageCollectChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(removed, check.Equals, 0)
+
+	sh1 := s.save(c, 1)
+	initial := chunkFiles(c)
+	c.Assert(os.WriteFile(filepath.Join(s.info.DataDir(), "big"), []byte("all different now"), 0644), check.IsNil)
+	sh2 := s.save(c, 2)
+	all := chunkFiles(c)
+	c.Assert(len(all) > len(initial), check.Equals, true)
+
+	// everything is still referenced
+	removed, err = backend.GarbageCollectChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(removed, check.Equals, 0)
+
+	// forgetting the first set leaves the chunks of the second one
+	c.Assert(os.Remove(backend.Filename(sh1)), check.IsNil)
+	removed, err = backend.GarbageCollectChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(removed > 0, check.Equals, true)
+	c.Check(len(chunkFiles(c)), check.Equals, len(all)-removed)
+
+	shr, err := backend.Open(backend.Filename(sh2), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer shr.Close()
+	c.Check(shr.Check(context.TODO(), nil), check.IsNil)
+
+	// and forgetting the last one leaves nothing behind
+	c.Assert(os.Remove(backend.Filename(sh2)), check.IsNil)
+	_, err = backend.GarbageCollectChunks(context.TODO())
+	c.Assert(err, check.IsNil)
+	c.Check(chunkFiles(c), check.HasLen, 0)
+}
+
+func (s *chunksSuite) TestGarbageCollectChunksBrokenSnapshot(c *check.C) {
+	sh1 := s.save(c, 1)
+	chunks := chunkFiles(c)
+	c.Assert(os.Remove(backend.Filename(sh1)), check.IsNil)
+
+	// a snapshot whose metadata does not match its hash
+	f, err := os.Create(filepath.Join(dirs.SnapshotsDir, "2_hello-snap_v1.33_42.zip"))
+	c.Assert(err, check.IsNil)
+	w := zip.NewWriter(f)
+	mw, err := w.Create("meta.json")
+	c.Assert(err, check.IsNil)
+	sh1.SetID = 2
+	c.Assert(json.NewEncoder(mw).Encode(sh1), check.IsNil)
+	hw, err := w.Create("meta.sha3_384")
+	c.Assert(err, check.IsNil)
+	_, err = hw.Write([]byte("deadbeef\n"))
+	c.Assert(err, check.IsNil)
+	c.Assert(w.Close(), check.IsNil)
+	c.Assert(f.Close(), check.IsNil)
+
+	// the chunks it needs cannot be known, so nothing is collected
+	removed, err := backend.GarbageCollectChunks(context.TODO())
+	c.Assert(err, check.ErrorMatches, `cannot collect snapshot chunks: snapshot ".*/2_hello-snap_v1.33_42.zip" is broken: declared hash .*`)
+	c.Check(removed, check.Equals, 0)
+	c.Check(chunkFiles(c), check.DeepEquals, chunks)
+}
+
+func (s *chunksSuite) TestImportExportRoundtripDeduplicated(c *check.C) {
+	ctx := context.TODO()
+	shw := s.save(c, 12)
+
+	export, err := backend.NewSnapshotExport(ctx, shw.SetID)
+	c.Assert(err, check.IsNil)
+	c.Assert(export.Init(), check.IsNil)
+
+	buf := bytes.NewBuffer(nil)
+	c.Assert(export.StreamTo(buf), check.IsNil)
+	c.Check(buf.Len(), check.Equals, int(export.Size()))
+	export.Close()
+
+	// import into a pristine snapshots directory
+	c.Assert(os.RemoveAll(dirs.SnapshotsDir), check.IsNil)
+
+	names, err := backend.Import(ctx, 123, buf, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(names, check.DeepEquals, []string{"hello-snap"})
+
+	rdr, err := backend.Open(filepath.Join(dirs.SnapshotsDir, "123_hello-snap_v1.33_42.zip"), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer rdr.Close()
+	c.Check(rdr.SHA3_384, check.DeepEquals, shw.SHA3_384)
+	c.Check(rdr.Check(ctx, nil), check.IsNil)
+}
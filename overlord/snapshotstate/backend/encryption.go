@@ -0,0 +1,405 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/secboot"
+)
+
+// Encrypted snapshots keep the zip layout and the metadata of plain ones,
+// but every data entry is sealed with AES-256-GCM. Entries are split in
+// segments so that they can be streamed, following the STREAM construction:
+// each segment is sealed with a nonce made of a per-entry random prefix, the
+// segment counter and a flag marking the last segment, so that reordered,
+// dropped or truncated segments fail authentication. The name of the snap
+// and of the entry are authenticated as well, so entries cannot be swapped.
+//
+// Sizes in the metadata are those of the plaintext, but hashes are those of
+// the sealed entries, so that they do not give away fingerprints of the
+// data; checking an encrypted snapshot verifies both the hashes and the
+// authentication tags, which cover the plaintext.
+
+const (
+	// KeySourceFile is the kind of a key source reading the secret from a
+	// file.
+	KeySourceFile = "key-file"
+	// KeySourceTPM is the kind of a key source using the primary key of
+	// the encrypted disks, which is sealed by the TPM.
+	KeySourceTPM = "tpm"
+	// KeySourcePassphrase is the kind of a key source using a passphrase.
+	KeySourcePassphrase = "passphrase"
+
+	// confName is the entry holding the snap configuration of encrypted
+	// snapshots, as it cannot be left in the metadata
+	confName = "conf.json"
+
+	encryptionMagic   = "snapenc1"
+	encryptionKeySize = 32
+	encryptionSaltLen = 32
+	noncePrefixLen    = 7
+	keyCheckInfo      = "snapd snapshot key check"
+	keyDerivationInfo = "snapd snapshot encryption"
+)
+
+var (
+	// size of the plaintext of all but the last segment of an entry
+	encryptionSegmentSize = 64 * 1024
+
+	// argon2id parameters used for passphrases
+	passphraseTime    uint32 = 4
+	passphraseMemory  uint32 = 64 * 1024
+	passphraseThreads uint8  = 4
+
+	secbootGetPrimaryKey = secboot.GetPrimaryKey
+)
+
+// ErrWrongKey is returned when unlocking an encrypted snapshot with a key
+// other than the one it was encrypted with.
+var ErrWrongKey = errors.New("wrong key")
+
+// A KeySource provides the key that protects the data of encrypted
+// snapshots.
+type KeySource interface {
+	// Kind returns the kind of the key source, as recorded in the
+	// snapshot metadata.
+	Kind() string
+	// Key returns the key derived from the secret of the source and the
+	// given salt.
+	Key(salt []byte) ([]byte, error)
+}
+
+type fileKeySource struct {
+	path string
+}
+
+// NewFileKeySource returns a key source deriving keys from the secret in
+// the given file, which must hold at least 32 bytes.
+func NewFileKeySource(path string) KeySource {
+	return &fileKeySource{path: path}
+}
+
+func (ks *fileKeySource) Kind() string { return KeySourceFile }
+
+func (ks *fileKeySource) Key(salt []byte) ([]byte, error) {
+	secret, err := os.ReadFile(ks.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snapshot key file: %v", err)
+	}
+	if len(secret) < encryptionKeySize {
+		return nil, fmt.Errorf("snapshot key file %q is too short (need at least %d bytes)", ks.path, encryptionKeySize)
+	}
+	return deriveKey(secret, salt)
+}
+
+type tpmKeySource struct {
+	devices []string
+}
+
+// NewTPMKeySource returns a key source deriving keys from the primary key
+// of the given encrypted devices, as unsealed with the TPM at boot.
+func NewTPMKeySource(devices []string) KeySource {
+	return &tpmKeySource{devices: devices}
+}
+
+func (ks *tpmKeySource) Kind() string { return KeySourceTPM }
+
+func (ks *tpmKeySource) Key(salt []byte) ([]byte, error) {
+	if len(ks.devices) == 0 {
+		return nil, errors.New("cannot use TPM-sealed snapshot key: no encrypted disks")
+	}
+	primaryKey, err := secbootGetPrimaryKey(ks.devices, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain primary key for snapshots: %v", err)
+	}
+	return deriveKey(primaryKey, salt)
+}
+
+type passphraseKeySource struct {
+	passphrase string
+}
+
+// NewPassphraseKeySource returns a key source deriving keys from the given
+// passphrase.
+func NewPassphraseKeySource(passphrase string) KeySource {
+	return &passphraseKeySource{passphrase: passphrase}
+}
+
+func (ks *passphraseKeySource) Kind() string { return KeySourcePassphrase }
+
+func (ks *passphraseKeySource) Key(salt []byte) ([]byte, error) {
+	if ks.passphrase == "" {
+		return nil, errors.New("snapshot passphrase cannot be empty")
+	}
+	return argon2.IDKey([]byte(ks.passphrase), salt, passphraseTime, passphraseMemory, passphraseThreads, encryptionKeySize), nil
+}
+
+func deriveKey(secret, salt []byte) ([]byte, error) {
+	key := make([]byte, encryptionKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(keyDerivationInfo)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func keyCheck(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(keyCheckInfo))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newEncryption obtains a key for a new snapshot from the key source,
+// returning it together with the metadata needed to obtain it again.
+func newEncryption(ks KeySource) (*client.SnapshotEncryption, []byte, error) {
+	salt := make([]byte, encryptionSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	key, err := ks.Key(salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := &client.SnapshotEncryption{
+		KeySource: ks.Kind(),
+		Salt:      salt,
+		KeyCheck:  keyCheck(key),
+	}
+	return enc, key, nil
+}
+
+// unlockKey obtains the key of an existing snapshot from the key source,
+// returning ErrWrongKey if it is not the one the snapshot was encrypted
+// with.
+func unlockKey(enc *client.SnapshotEncryption, ks KeySource) ([]byte, error) {
+	if ks.Kind() != enc.KeySource {
+		return nil, fmt.Errorf("snapshot is encrypted with a %q key, not a %q one", enc.KeySource, ks.Kind())
+	}
+	key, err := ks.Key(enc.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(keyCheck(key)), []byte(enc.KeyCheck)) {
+		return nil, ErrWrongKey
+	}
+	return key, nil
+}
+
+func entryAAD(snapName, entry string) []byte {
+	return []byte(snapName + "\x00" + entry)
+}
+
+func segmentNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, noncePrefixLen+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixLen:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// plaintextSize returns the size of the plaintext of an encrypted entry of
+// the given size.
+func plaintextSize(size int64) int64 {
+	segmentLen := int64(encryptionSegmentSize + 16)
+	size -= int64(len(encryptionMagic) + noncePrefixLen)
+	segments := (size + segmentLen - 1) / segmentLen
+	if segments == 0 {
+		segments = 1
+	}
+	return size - segments*16
+}
+
+// encryptingWriter seals what is written to it in segments; the last
+// segment is only written out on Close.
+type encryptingWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	aad     []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte
+}
+
+func newEncryptingWriter(w io.Writer, key []byte, aad []byte) (*encryptingWriter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixLen)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, encryptionMagic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{
+		w:      w,
+		aead:   aead,
+		aad:    aad,
+		prefix: prefix,
+		buf:    make([]byte, 0, encryptionSegmentSize),
+	}, nil
+}
+
+func (ew *encryptingWriter) seal(last bool) error {
+	if ew.counter == ^uint32(0) {
+		return errors.New("too much data to encrypt")
+	}
+	sealed := ew.aead.Seal(nil, segmentNonce(ew.prefix, ew.counter, last), ew.buf, ew.aad)
+	ew.counter++
+	ew.buf = ew.buf[:0]
+	_, err := ew.w.Write(sealed)
+	return err
+}
+
+func (ew *encryptingWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if len(ew.buf) == encryptionSegmentSize {
+			// only now is it known that this is not the last segment
+			if err := ew.seal(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(ew.buf[len(ew.buf):cap(ew.buf)], p)
+		ew.buf = ew.buf[:len(ew.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close writes out the last segment; it does not close the underlying
+// writer.
+func (ew *encryptingWriter) Close() error {
+	return ew.seal(true)
+}
+
+// decryptingReader opens the segments sealed by an encryptingWriter,
+// failing if any of them does not authenticate.
+type decryptingReader struct {
+	r       *bufio.Reader
+	closer  io.Closer
+	aead    cipher.AEAD
+	aad     []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	plain   []byte
+	segment []byte
+	done    bool
+}
+
+func newDecryptingReader(rc io.ReadCloser, key []byte, aad []byte) (*decryptingReader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(rc)
+	header := make([]byte, len(encryptionMagic)+noncePrefixLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("cannot read encryption header: %v", err)
+	}
+	if !bytes.HasPrefix(header, []byte(encryptionMagic)) {
+		return nil, errors.New("invalid encryption header")
+	}
+	return &decryptingReader{
+		r:       r,
+		closer:  rc,
+		aead:    aead,
+		aad:     aad,
+		prefix:  header[len(encryptionMagic):],
+		plain:   make([]byte, 0, encryptionSegmentSize),
+		segment: make([]byte, encryptionSegmentSize+aead.Overhead()),
+	}, nil
+}
+
+func (dr *decryptingReader) open() error {
+	n, err := io.ReadFull(dr.r, dr.segment)
+	last := false
+	switch err {
+	case nil:
+		// a full segment is the last one if nothing follows it
+		if _, err := dr.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.ErrUnexpectedEOF, io.EOF:
+		last = true
+	default:
+		return err
+	}
+	opened, err := dr.aead.Open(dr.plain[:0], segmentNonce(dr.prefix, dr.counter, last), dr.segment[:n], dr.aad)
+	if err != nil {
+		return errors.New("cannot decrypt snapshot data: authentication failed")
+	}
+	dr.counter++
+	dr.buf = opened
+	dr.done = last
+	return nil
+}
+
+func (dr *decryptingReader) Read(p []byte) (int, error) {
+	for len(dr.buf) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		if err := dr.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+	return n, nil
+}
+
+func (dr *decryptingReader) Close() error {
+	return dr.closer.Close()
+}
@@ -0,0 +1,372 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/osutil/user"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/systemd/systemdtest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type encryptionSuite struct {
+	testutil.BaseTest
+	info    *snap.Info
+	keyFile string
+}
+
+var _ = check.Suite(&encryptionSuite{})
+
+const secretData = "the secret canary\n"
+
+func (s *encryptionSuite) SetUpTest(c *check.C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	// only the system data is saved; user data requires running tar
+	// as another user
+	s.AddCleanup(backend.MockUsersForUsernames(func([]string, *dirs.SnapDirOptions) ([]*user.User, error) {
+		return nil, nil
+	}))
+	s.AddCleanup(backend.MockIsTesting(true))
+	s.AddCleanup(backend.MockEncryptionSegmentSize(1024))
+	s.AddCleanup(backend.MockPassphraseCost())
+	s.AddCleanup(osutil.MockMountInfo(""))
+	s.AddCleanup(systemd.MockNewSystemd(func(systemd.Backend, string, systemd.InstanceMode, systemd.Reporter) systemd.Systemd {
+		return &systemdtest.FakeSystemd{}
+	}))
+	logger.SimpleSetup(nil)
+
+	s.info = &snap.Info{SideInfo: snap.SideInfo{RealName: "hello-snap", Revision: snap.R(42), SnapID: "hello-id"}, Version: "v1.33"}
+	c.Assert(os.MkdirAll(s.info.DataDir(), 0755), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(s.info.DataDir(), "secret"), []byte(secretData), 0644), check.IsNil)
+	// uncompressible, so the archive spans several segments
+	c.Assert(os.WriteFile(filepath.Join(s.info.DataDir(), "random"), randomData(c, 16*1024), 0644), check.IsNil)
+
+	s.keyFile = filepath.Join(c.MkDir(), "key")
+	c.Assert(os.WriteFile(s.keyFile, randomData(c, 32), 0600), check.IsNil)
+}
+
+func randomData(c *check.C, n int) []byte {
+	data := make([]byte, n)
+	f, err := os.Open("/dev/urandom")
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	_, err = io.ReadFull(f, data)
+	c.Assert(err, check.IsNil)
+	return data
+}
+
+func (s *encryptionSuite) save(c *check.C, setID uint64, ks backend.KeySource) *client.Snapshot {
+	cfg := map[string]any{"password": "hunter2"}
+	shw, err := backend.Save(context.TODO(), setID, s.info, cfg, nil, nil, nil, &backend.SaveFlags{KeySource: ks})
+	c.Assert(err, check.IsNil)
+	return shw
+}
+
+func (s *encryptionSuite) open(c *check.C, shw *client.Snapshot) *backend.Reader {
+	shr, err := backend.Open(backend.Filename(shw), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	s.AddCleanup(func() { shr.Close() })
+	return shr
+}
+
+func zipContains(c *check.C, fn string, needle string) bool {
+	zr, err := zip.OpenReader(fn)
+	c.Assert(err, check.IsNil)
+	defer zr.Close()
+	for _, f := range zr.File {
+		r, err := f.Open()
+		c.Assert(err, check.IsNil)
+		data, err := io.ReadAll(r)
+		r.Close()
+		c.Assert(err, check.IsNil)
+		if bytes.Contains(data, []byte(needle)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *encryptionSuite) TestSaveEncrypted(c *check.C) {
+	shw := s.save(c, 1, backend.NewFileKeySource(s.keyFile))
+	c.Assert(shw.Encryption, check.NotNil)
+	c.Check(shw.Encryption.KeySource, check.Equals, "key-file")
+	c.Check(shw.Encryption.Salt, check.HasLen, 32)
+	c.Check(shw.Encryption.KeyCheck, check.Not(check.Equals), "")
+	// the configuration is not left in the clear
+	c.Check(shw.Conf, check.IsNil)
+	c.Check(hashkeys(shw), check.DeepEquals, []string{"archive.tgz", "conf.json"})
+
+	// the data is not in the clear either
+	c.Check(zipContains(c, backend.Filename(shw), "hunter2"), check.Equals, false)
+	c.Check(zipContains(c, backend.Filename(shw), "secret canary"), check.Equals, false)
+
+	shr := s.open(c, shw)
+	c.Check(shr.Encryption, check.DeepEquals, shw.Encryption)
+	c.Check(shr.Conf, check.IsNil)
+
+	// the hashes in the metadata are those of the sealed entries, not of
+	// the data
+	zr, err := zip.OpenReader(backend.Filename(shw))
+	c.Assert(err, check.IsNil)
+	defer zr.Close()
+	for _, f := range zr.File {
+		expected, ok := shw.SHA3_384[f.Name]
+		if !ok {
+			continue
+		}
+		r, err := f.Open()
+		c.Assert(err, check.IsNil)
+		hasher := crypto.SHA3_384.New()
+		_, err = io.Copy(hasher, r)
+		r.Close()
+		c.Assert(err, check.IsNil)
+		c.Check(fmt.Sprintf("%x", hasher.Sum(nil)), check.Equals, expected, check.Commentf("entry %q", f.Name))
+	}
+}
+
+func (s *encryptionSuite) TestCheckAndRestoreEncrypted(c *check.C) {
+	for _, ks := range []backend.KeySource{
+		backend.NewFileKeySource(s.keyFile),
+		backend.NewPassphraseKeySource("correct horse battery staple"),
+	} {
+		shw := s.save(c, 1, ks)
+		shr := s.open(c, shw)
+
+		// nothing can be read before unlocking
+		c.Check(shr.Check(context.TODO(), nil), check.ErrorMatches, `snapshot ".*" is encrypted and has not been unlocked`)
+
+		c.Assert(shr.Unlock(ks), check.IsNil)
+		c.Check(shr.Conf, check.DeepEquals, map[string]any{"password": "hunter2"})
+		c.Check(shr.Check(context.TODO(), nil), check.IsNil)
+
+		secretPath := filepath.Join(s.info.DataDir(), "secret")
+		c.Assert(os.WriteFile(secretPath, []byte("scribble"), 0644), check.IsNil)
+
+		rs, err := shr.Restore(context.TODO(), snap.R(0), nil, logger.Debugf, nil)
+		c.Assert(err, check.IsNil)
+		rs.Cleanup()
+		c.Check(secretPath, testutil.FileEquals, secretData)
+	}
+}
+
+func (s *encryptionSuite) TestUnlockWrongKey(c *check.C) {
+	shw := s.save(c, 1, backend.NewPassphraseKeySource("right"))
+	shr := s.open(c, shw)
+
+	err := shr.Unlock(backend.NewPassphraseKeySource("wrong"))
+	c.Check(err, check.ErrorMatches, `cannot unlock snapshot ".*": wrong key`)
+	c.Check(errors.Is(err, backend.ErrWrongKey), check.Equals, true)
+
+	otherKey := filepath.Join(c.MkDir(), "key")
+	c.Assert(os.WriteFile(otherKey, randomData(c, 32), 0600), check.IsNil)
+	err = shr.Unlock(backend.NewFileKeySource(otherKey))
+	c.Check(err, check.ErrorMatches, `cannot unlock snapshot ".*": snapshot is encrypted with a "passphrase" key, not a "key-file" one`)
+
+	// and the data stays locked
+	_, err = shr.Restore(context.TODO(), snap.R(0), nil, logger.Debugf, nil)
+	c.Check(err, check.ErrorMatches, `snapshot ".*" is encrypted and has not been unlocked`)
+}
+
+func (s *encryptionSuite) TestKeyFileErrors(c *check.C) {
+	ks := backend.NewFileKeySource(filepath.Join(c.MkDir(), "missing"))
+	_, err := backend.Save(context.TODO(), 1, s.info, nil, nil, nil, nil, &backend.SaveFlags{KeySource: ks})
+	c.Check(err, check.ErrorMatches, `cannot obtain snapshot key: cannot read snapshot key file: open .*: no such file or directory`)
+
+	c.Assert(os.WriteFile(s.keyFile, []byte("short"), 0600), check.IsNil)
+	ks = backend.NewFileKeySource(s.keyFile)
+	_, err = backend.Save(context.TODO(), 1, s.info, nil, nil, nil, nil, &backend.SaveFlags{KeySource: ks})
+	c.Check(err, check.ErrorMatches, `cannot obtain snapshot key: snapshot key file ".*" is too short \(need at least 32 bytes\)`)
+
+	ks = backend.NewPassphraseKeySource("")
+	_, err = backend.Save(context.TODO(), 1, s.info, nil, nil, nil, nil, &backend.SaveFlags{KeySource: ks})
+	c.Check(err, check.ErrorMatches, `cannot obtain snapshot key: snapshot passphrase cannot be empty`)
+
+	_, err = backend.Save(context.TODO(), 1, s.info, nil, nil, nil, nil, &backend.SaveFlags{KeySource: ks, Deduplicate: true})
+	c.Check(err, check.ErrorMatches, `internal error: cannot save an encrypted snapshot deduplicated`)
+}
+
+func (s *encryptionSuite) TestTPMKeySource(c *check.C) {
+	primaryKey := randomData(c, 32)
+	var calls int
+	s.AddCleanup(backend.MockSecbootGetPrimaryKey(func(devices []string, fallbackKeyFiles []string) ([]byte, error) {
+		calls++
+		c.Check(devices, check.DeepEquals, []string{"/dev/disk/by-uuid/data", "/dev/disk/by-uuid/save"})
+		c.Check(fallbackKeyFiles, check.HasLen, 0)
+		return primaryKey, nil
+	}))
+
+	ks := backend.NewTPMKeySource([]string{"/dev/disk/by-uuid/data", "/dev/disk/by-uuid/save"})
+	shw := s.save(c, 1, ks)
+	c.Check(shw.Encryption.KeySource, check.Equals, "tpm")
+
+	shr := s.open(c, shw)
+	c.Assert(shr.Unlock(ks), check.IsNil)
+	c.Check(shr.Check(context.TODO(), nil), check.IsNil)
+	c.Check(calls, check.Equals, 2)
+
+	// no encrypted disks, no key
+	_, err := backend.NewTPMKeySource(nil).Key([]byte("salt"))
+	c.Check(err, check.ErrorMatches, "cannot use TPM-sealed snapshot key: no encrypted disks")
+}
+
+func (s *encryptionSuite) TestCheckTampered(c *check.C) {
+	ks := backend.NewFileKeySource(s.keyFile)
+	shw := s.save(c, 1, ks)
+
+	// flip a bit in the middle of the encrypted archive, rewriting the
+	// zip so that its own checksums are fine
+	fn := backend.Filename(shw)
+	zr, err := zip.OpenReader(fn)
+	c.Assert(err, check.IsNil)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		c.Assert(err, check.IsNil)
+		data, err := io.ReadAll(r)
+		r.Close()
+		c.Assert(err, check.IsNil)
+		if f.Name == "archive.tgz" {
+			data[len(data)/2] ^= 1
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name})
+		c.Assert(err, check.IsNil)
+		_, err = w.Write(data)
+		c.Assert(err, check.IsNil)
+	}
+	zr.Close()
+	c.Assert(zw.Close(), check.IsNil)
+	c.Assert(os.WriteFile(fn, buf.Bytes(), 0600), check.IsNil)
+
+	shr := s.open(c, shw)
+	c.Assert(shr.Unlock(ks), check.IsNil)
+	c.Check(shr.Check(context.TODO(), nil), check.ErrorMatches, "cannot decrypt snapshot data: authentication failed")
+}
+
+func (s *encryptionSuite) TestStreamRoundtrip(c *check.C) {
+	key := randomData(c, 32)
+	for _, size := range []int{0, 1, 1023, 1024, 1025, 2048, 5000} {
+		data := randomData(c, size)
+		var buf bytes.Buffer
+		ew, err := backend.NewEncryptingWriter(&buf, key, []byte("aad"))
+		c.Assert(err, check.IsNil)
+		_, err = ew.Write(data)
+		c.Assert(err, check.IsNil)
+		c.Assert(ew.Close(), check.IsNil)
+
+		dr, err := backend.NewDecryptingReader(io.NopCloser(bytes.NewReader(buf.Bytes())), key, []byte("aad"))
+		c.Assert(err, check.IsNil)
+		out, err := io.ReadAll(dr)
+		c.Assert(err, check.IsNil, check.Commentf("size %d", size))
+		c.Check(bytes.Equal(out, data), check.Equals, true, check.Commentf("size %d", size))
+
+		// the data is bound to its entry
+		dr, err = backend.NewDecryptingReader(io.NopCloser(bytes.NewReader(buf.Bytes())), key, []byte("other"))
+		c.Assert(err, check.IsNil)
+		_, err = io.ReadAll(dr)
+		c.Check(err, check.ErrorMatches, "cannot decrypt snapshot data: authentication failed")
+	}
+}
+
+func (s *encryptionSuite) TestStreamTruncated(c *check.C) {
+	key := randomData(c, 32)
+	var buf bytes.Buffer
+	ew, err := backend.NewEncryptingWriter(&buf, key, nil)
+	c.Assert(err, check.IsNil)
+	_, err = ew.Write(randomData(c, 3*1024))
+	c.Assert(err, check.IsNil)
+	c.Assert(ew.Close(), check.IsNil)
+
+	// header, and then segments of 1024+16 bytes
+	headerLen := 8 + 7
+	for _, cut := range []int{headerLen + 1040, headerLen + 2*1040, buf.Len() - 1} {
+		dr, err := backend.NewDecryptingReader(io.NopCloser(bytes.NewReader(buf.Bytes()[:cut])), key, nil)
+		c.Assert(err, check.IsNil)
+		_, err = io.ReadAll(dr)
+		c.Check(err, check.ErrorMatches, "cannot decrypt snapshot data: authentication failed", check.Commentf("cut at %d", cut))
+	}
+}
+
+func (s *encryptionSuite) TestImportExportRoundtripEncrypted(c *check.C) {
+	ctx := context.TODO()
+	ks := backend.NewPassphraseKeySource("sekrit")
+	shw := s.save(c, 12, ks)
+
+	export, err := backend.NewSnapshotExport(ctx, shw.SetID)
+	c.Assert(err, check.IsNil)
+	c.Assert(export.Init(), check.IsNil)
+	buf := bytes.NewBuffer(nil)
+	c.Assert(export.StreamTo(buf), check.IsNil)
+	export.Close()
+	exported := buf.Bytes()
+
+	// the export is encrypted too
+	c.Check(bytes.Contains(exported, []byte("hunter2")), check.Equals, false)
+
+	// import needs the key to verify the data
+	_, err = backend.Import(ctx, 123, bytes.NewReader(exported), &backend.ImportFlags{NoDuplicatedImportCheck: true})
+	c.Check(err, check.ErrorMatches, `cannot import snapshot 123: validation failed for ".*": snapshot is encrypted with a "passphrase" key, but no key was provided`)
+
+	var kinds []string
+	flags := &backend.ImportFlags{
+		NoDuplicatedImportCheck: true,
+		KeySourceFor: func(kind string) (backend.KeySource, error) {
+			kinds = append(kinds, kind)
+			return backend.NewPassphraseKeySource("wrong"), nil
+		},
+	}
+	_, err = backend.Import(ctx, 124, bytes.NewReader(exported), flags)
+	c.Check(err, check.ErrorMatches, `cannot import snapshot 124: validation failed for ".*": cannot unlock snapshot ".*": wrong key`)
+	c.Check(kinds, check.DeepEquals, []string{"passphrase"})
+
+	flags.KeySourceFor = func(kind string) (backend.KeySource, error) {
+		return ks, nil
+	}
+	names, err := backend.Import(ctx, 125, bytes.NewReader(exported), flags)
+	c.Assert(err, check.IsNil)
+	c.Check(names, check.DeepEquals, []string{"hello-snap"})
+
+	rdr, err := backend.Open(filepath.Join(dirs.SnapshotsDir, "125_hello-snap_v1.33_42.zip"), backend.ExtractFnameSetID)
+	c.Assert(err, check.IsNil)
+	defer rdr.Close()
+	c.Assert(rdr.Unlock(ks), check.IsNil)
+	c.Check(rdr.Check(ctx, nil), check.IsNil)
+}
@@ -21,12 +21,15 @@ package backend
 
 import (
 	"context"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"time"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/httputil"
 	"github.com/snapcore/snapd/osutil/sys"
 	"github.com/snapcore/snapd/osutil/user"
 	"github.com/snapcore/snapd/snap"
@@ -138,6 +141,18 @@ func MockSnapshot(setID uint64, snapName string, revision snap.Revision, size in
 	}
 }
 
+func ImportChunk(h string, r io.Reader) error {
+	return newChunkStore().importChunk(h, r)
+}
+
+func MockChunkSizes(minSize, maxSize int, mask uint64) (restore func()) {
+	oldMin, oldMax, oldMask := chunkMinSize, chunkMaxSize, chunkMask
+	chunkMinSize, chunkMaxSize, chunkMask = minSize, maxSize, mask
+	return func() {
+		chunkMinSize, chunkMaxSize, chunkMask = oldMin, oldMax, oldMask
+	}
+}
+
 func MockFilepathGlob(new func(pattern string) (matches []string, err error)) (restore func()) {
 	oldFilepathGlob := filepathGlob
 	filepathGlob = new
@@ -158,3 +173,43 @@ func MockReadSnapshotYaml(f func(si *snap.Info) (*snap.SnapshotOptions, error))
 		snapReadSnapshotYaml = oldReadSnapshotYaml
 	}
 }
+
+var (
+	NewEncryptingWriter = newEncryptingWriter
+	NewDecryptingReader = newDecryptingReader
+)
+
+func MockEncryptionSegmentSize(size int) (restore func()) {
+	return testutil.Mock(&encryptionSegmentSize, size)
+}
+
+// MockPassphraseCost makes deriving keys from passphrases cheap.
+func MockPassphraseCost() (restore func()) {
+	restoreTime := testutil.Mock(&passphraseTime, uint32(1))
+	restoreMemory := testutil.Mock(&passphraseMemory, uint32(64))
+	return func() {
+		restoreMemory()
+		restoreTime()
+	}
+}
+
+func MockSecbootGetPrimaryKey(f func(devices []string, fallbackKeyFiles []string) ([]byte, error)) (restore func()) {
+	return testutil.Mock(&secbootGetPrimaryKey, f)
+}
+
+var SignS3Request = signS3Request
+
+func MockS3Multipart(threshold, partSize int64) (restore func()) {
+	r1 := testutil.Backup(&s3MultipartThreshold)
+	r2 := testutil.Backup(&s3PartSize)
+	s3MultipartThreshold = threshold
+	s3PartSize = partSize
+	return func() {
+		r1()
+		r2()
+	}
+}
+
+func MockHttputilNewHTTPClient(f func(*httputil.ClientOptions) *http.Client) (restore func()) {
+	return testutil.Mock(&httputilNewHTTPClient, f)
+}
@@ -51,6 +51,9 @@ const ExtractFnameSetID = 0
 type Reader struct {
 	*os.File
 	client.Snapshot
+
+	// key of an encrypted snapshot, once unlocked
+	key []byte
 }
 
 // Open a Snapshot given its full filename.
@@ -143,15 +146,45 @@ func Open(fn string, setID uint64) (reader *Reader, e error) {
 	return reader, nil
 }
 
+// Unlock makes the data of an encrypted snapshot available, using a key
+// from the given key source. It returns an error wrapping ErrWrongKey if
+// the key is not the one the snapshot was encrypted with. Unlocking a
+// snapshot that is not encrypted does nothing.
+func (r *Reader) Unlock(ks KeySource) error {
+	if r.Encryption == nil {
+		return nil
+	}
+	key, err := unlockKey(r.Encryption, ks)
+	if err != nil {
+		return fmt.Errorf("cannot unlock snapshot %q: %w", r.Name(), err)
+	}
+	r.key = key
+
+	if _, ok := r.SHA3_384[confName]; !ok {
+		return nil
+	}
+	body, _, err := r.openEntry(confName, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	var conf map[string]any
+	if err := jsonutil.DecodeWithNumber(body, &conf); err != nil {
+		return fmt.Errorf("cannot read configuration of snapshot %q: %v", r.Name(), err)
+	}
+	r.Conf = conf
+	return nil
+}
+
 func (r *Reader) checkOne(ctx context.Context, entry string, hasher hash.Hash) error {
-	body, reportedSize, err := zipMember(r.File, entry)
+	body, reportedSize, err := r.openEntry(entry, hasher)
 	if err != nil {
 		return err
 	}
 	defer body.Close()
 
 	expectedHash := r.SHA3_384[entry]
-	readSize, err := io.Copy(io.MultiWriter(osutil.ContextWriter(ctx), hasher), body)
+	readSize, err := io.Copy(osutil.ContextWriter(ctx), body)
 	if err != nil {
 		return err
 	}
@@ -229,8 +262,12 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 		uid := sys.UserID(osutil.NoChown)
 		gid := sys.GroupID(osutil.NoChown)
 
+		if entry == confName {
+			// restored by the caller from Conf
+			continue
+		}
 		if !isUser {
-			if entry != archiveName {
+			if entry != archiveName && entry != chunkedArchiveName {
 				// hmmm
 				logf("Skipping restore of unknown entry %q.", entry)
 				continue
@@ -311,51 +348,10 @@ func (r *Reader) Restore(ctx context.Context, current snap.Revision, usernames [
 
 		logger.Debugf("Restoring %q from %q into %q.", entry, r.Name(), tempdir)
 
-		body, expectedSize, err := zipMember(r.File, entry)
-		if err != nil {
+		if err := r.unpackEntry(ctx, entry, username, tempdir, hasher, &sz); err != nil {
 			return rs, err
 		}
 
-		expectedHash := r.SHA3_384[entry]
-
-		tr := io.TeeReader(body, io.MultiWriter(hasher, &sz))
-
-		// resist the temptation of using archive/tar unless it's proven
-		// that calling out to tar has issues -- there are a lot of
-		// special cases we'd need to consider otherwise
-		cmd := tarAsUser(ctx, username,
-			"--extract",
-			"--preserve-permissions", "--preserve-order", "--gunzip",
-			"--directory", tempdir)
-		cmd.Env = []string{}
-		cmd.Stdin = tr
-		matchCounter := &strutil.MatchCounter{N: 1}
-		cmd.Stderr = matchCounter
-		cmd.Stdout = os.Stderr
-		if isTesting {
-			matchCounter.N = -1
-			cmd.Stderr = io.MultiWriter(os.Stderr, matchCounter)
-		}
-
-		// cmd is cancellable if ctx is a cancellable context
-		if err = cmd.Run(); err != nil {
-			matches, count := matchCounter.Matches()
-			if count > 0 {
-				return rs, fmt.Errorf("cannot unpack archive: %s (and %d more)", matches[0], count-1)
-			}
-			return rs, fmt.Errorf("tar failed: %v", err)
-		}
-
-		if sz.Size() != expectedSize {
-			return rs, fmt.Errorf("snapshot %q entry %q expected size (%d) does not match actual (%d)",
-				r.Name(), entry, expectedSize, sz.Size())
-		}
-
-		if actualHash := fmt.Sprintf("%x", hasher.Sum(nil)); actualHash != expectedHash {
-			return rs, fmt.Errorf("snapshot %q entry %q expected hash (%.7s…) does not match actual (%.7s…)",
-				r.Name(), entry, expectedHash, actualHash)
-		}
-
 		if curdir != "" && curdir != revdir {
 			// rename it in tempdir
 			// this is where we assume the current revision can read the snapshot revision's data
@@ -435,3 +431,61 @@ func moveFile(rs *RestoreState, file, sourceDir, targetDir string) error {
 
 	return nil
 }
+
+// unpackEntry unpacks the given entry into the given directory as the given
+// user, checking its size and hash as it goes. The entry is closed before
+// returning, so that restoring a set never keeps more than one entry, and its
+// chunks, open at a time.
+func (r *Reader) unpackEntry(ctx context.Context, entry, username, dir string, hasher hash.Hash, sz *osutil.Sizer) error {
+	body, expectedSize, err := r.openEntry(entry, hasher)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	expectedHash := r.SHA3_384[entry]
+
+	tr := io.TeeReader(body, sz)
+
+	// resist the temptation of using archive/tar unless it's proven
+	// that calling out to tar has issues -- there are a lot of
+	// special cases we'd need to consider otherwise
+	tarArgs := []string{
+		"--extract",
+		"--preserve-permissions", "--preserve-order",
+	}
+	if !isChunkedEntry(entry) {
+		tarArgs = append(tarArgs, "--gunzip")
+	}
+	tarArgs = append(tarArgs, "--directory", dir)
+	cmd := tarAsUser(ctx, username, tarArgs...)
+	cmd.Env = []string{}
+	cmd.Stdin = tr
+	matchCounter := &strutil.MatchCounter{N: 1}
+	cmd.Stderr = matchCounter
+	cmd.Stdout = os.Stderr
+	if isTesting {
+		matchCounter.N = -1
+		cmd.Stderr = io.MultiWriter(os.Stderr, matchCounter)
+	}
+
+	// cmd is cancellable if ctx is a cancellable context
+	if err = cmd.Run(); err != nil {
+		matches, count := matchCounter.Matches()
+		if count > 0 {
+			return fmt.Errorf("cannot unpack archive: %s (and %d more)", matches[0], count-1)
+		}
+		return fmt.Errorf("tar failed: %v", err)
+	}
+
+	if sz.Size() != expectedSize {
+		return fmt.Errorf("snapshot %q entry %q expected size (%d) does not match actual (%d)",
+			r.Name(), entry, expectedSize, sz.Size())
+	}
+
+	if actualHash := fmt.Sprintf("%x", hasher.Sum(nil)); actualHash != expectedHash {
+		return fmt.Errorf("snapshot %q entry %q expected hash (%.7s…) does not match actual (%.7s…)",
+			r.Name(), entry, expectedHash, actualHash)
+	}
+	return nil
+}
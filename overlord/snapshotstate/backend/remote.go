@@ -0,0 +1,116 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/osutil"
+)
+
+// Kinds of remote targets snapshot sets can be pushed to.
+const (
+	RemoteTargetDirectory = "directory"
+	RemoteTargetS3        = "s3"
+)
+
+// ErrRemoteSnapshotNotFound is returned when pulling a snapshot set that the
+// remote target does not have.
+var ErrRemoteSnapshotNotFound = errors.New("snapshot set not found in remote target")
+
+// RemoteTarget is somewhere off the device that exported snapshot sets (as
+// streamed by SnapshotExport) can be pushed to, and pulled back from.
+type RemoteTarget interface {
+	// Push stores the export of the given snapshot set, of the given
+	// size, replacing any earlier one.
+	Push(ctx context.Context, setID uint64, r io.Reader, size int64) error
+	// Pull returns the export of the given snapshot set, or an error
+	// wrapping ErrRemoteSnapshotNotFound. It must be closed after use.
+	Pull(ctx context.Context, setID uint64) (io.ReadCloser, error)
+	// String describes the target, for messages.
+	String() string
+}
+
+// remoteName returns the name the export of the given snapshot set of the
+// device with the given path is stored under in a remote target. Sets are
+// kept under a path per device so that devices sharing a target do not
+// overwrite each other's sets.
+func remoteName(devicePath string, setID uint64) string {
+	return path.Join(devicePath, fmt.Sprintf("%d.snapshot", setID))
+}
+
+type directoryTarget struct {
+	dir        string
+	devicePath string
+}
+
+// NewDirectoryTarget returns a remote target keeping snapshot sets as files
+// in the given directory, typically a network filesystem mount point, under
+// the given path identifying the device.
+func NewDirectoryTarget(dir, devicePath string) RemoteTarget {
+	return &directoryTarget{dir: dir, devicePath: devicePath}
+}
+
+func (t *directoryTarget) filename(setID uint64) string {
+	return filepath.Join(t.dir, filepath.FromSlash(remoteName(t.devicePath, setID)))
+}
+
+func (t *directoryTarget) String() string {
+	return fmt.Sprintf("directory %q", t.dir)
+}
+
+func (t *directoryTarget) Push(ctx context.Context, setID uint64, r io.Reader, size int64) error {
+	filename := t.filename(setID)
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	aw, err := osutil.NewAtomicFile(filename, 0600, 0, osutil.NoChown, osutil.NoChown)
+	if err != nil {
+		return err
+	}
+	// Cancel once Committed is a NOP
+	defer aw.Cancel()
+
+	n, err := io.Copy(aw, r)
+	if err != nil {
+		return fmt.Errorf("cannot push snapshot set #%d: %v", setID, err)
+	}
+	if n != size {
+		return fmt.Errorf("cannot push snapshot set #%d: expected %d bytes, got %d", setID, size, n)
+	}
+	return aw.Commit()
+}
+
+func (t *directoryTarget) Pull(ctx context.Context, setID uint64) (io.ReadCloser, error) {
+	f, err := os.Open(t.filename(setID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("cannot pull snapshot set #%d from %s: %w", setID, t, ErrRemoteSnapshotNotFound)
+		}
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,405 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/httputil"
+)
+
+const (
+	s3DefaultRegion = "us-east-1"
+
+	// s3UnsignedPayload is used instead of the hash of the body of
+	// requests streaming a snapshot set, which is not known upfront
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// s3EmptyPayload is the hash of an empty body
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// s3Timeout bounds a whole request to the API, pushing or pulling a snapshot
+// set, or a part of it, included, while stalled connections time out earlier
+// in the transport.
+var s3Timeout = time.Hour
+
+var (
+	// s3MultipartThreshold is the size above which snapshot sets are
+	// pushed with a multipart upload, below the 5GiB limit of a single
+	// upload
+	s3MultipartThreshold int64 = 1 << 30
+	// s3PartSize is the size of the parts of a multipart upload, which
+	// is raised for sets that would need more than s3MaxParts parts
+	s3PartSize int64 = 256 << 20
+)
+
+// s3MaxParts is the maximum number of parts of a multipart upload.
+const s3MaxParts = 10000
+
+var (
+	httputilNewHTTPClient = httputil.NewHTTPClient
+	s3TimeNow             = time.Now
+)
+
+// S3Config is the configuration of a remote target using an S3-compatible
+// object storage API.
+type S3Config struct {
+	// Endpoint is the base URL of the API, e.g. https://minio.local:9000;
+	// buckets are addressed path-style under it
+	Endpoint string
+	Bucket   string
+	// Region defaults to us-east-1, which most S3-compatible stores
+	// accept whatever their actual location
+	Region string
+	// Prefix is prepended to the name of the objects
+	Prefix string
+	// DevicePath identifies the device in the name of the objects, after
+	// the prefix
+	DevicePath string
+
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Proxy returns the proxy to use for requests to the API
+	Proxy func(*http.Request) (*url.URL, error)
+	// ProxyConnectHeader is sent to proxies for connecting to the API
+	ProxyConnectHeader http.Header
+	// ExtraSSLCerts are trusted for connecting to the API, in addition
+	// to the system ones
+	ExtraSSLCerts httputil.ExtraSSLCerts
+}
+
+type s3Target struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Target returns a remote target keeping snapshot sets as objects in a
+// bucket of an S3-compatible object storage API.
+func NewS3Target(cfg *S3Config) (RemoteTarget, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse S3 endpoint: %v", err)
+	}
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint must be an http or https URL, not %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket must be set")
+	}
+	t := &s3Target{
+		cfg:      *cfg,
+		endpoint: endpoint,
+		client: httputilNewHTTPClient(&httputil.ClientOptions{
+			Timeout:            s3Timeout,
+			Proxy:              cfg.Proxy,
+			ProxyConnectHeader: cfg.ProxyConnectHeader,
+			ExtraSSLCerts:      cfg.ExtraSSLCerts,
+		}),
+	}
+	if t.cfg.Region == "" {
+		t.cfg.Region = s3DefaultRegion
+	}
+	return t, nil
+}
+
+func (t *s3Target) String() string {
+	return fmt.Sprintf("S3 bucket %q at %s", t.cfg.Bucket, t.endpoint.Redacted())
+}
+
+func (t *s3Target) objectURL(setID uint64) *url.URL {
+	u := *t.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + t.cfg.Bucket + "/" + t.cfg.Prefix + remoteName(t.cfg.DevicePath, setID)
+	u.RawPath = ""
+	return &u
+}
+
+func (t *s3Target) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	signS3Request(req, &t.cfg, payloadHash, s3TimeNow())
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, s3ResponseError(resp)
+}
+
+func (t *s3Target) objectURLWithQuery(setID uint64, query url.Values) *url.URL {
+	u := t.objectURL(setID)
+	u.RawQuery = query.Encode()
+	return u
+}
+
+func (t *s3Target) Push(ctx context.Context, setID uint64, r io.Reader, size int64) error {
+	var err error
+	if size > s3MultipartThreshold {
+		err = t.pushMultipart(ctx, setID, r, size)
+	} else {
+		_, err = t.putPart(ctx, t.objectURL(setID), r, size)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot push snapshot set #%d to %s: %v", setID, t, err)
+	}
+	return nil
+}
+
+// putPart uploads size bytes from r to the given URL, of the object or of a
+// part of it, returning the ETag of the upload.
+func (t *s3Target) putPart(ctx context.Context, u *url.URL, r io.Reader, size int64) (etag string, err error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", u.String(), r)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	resp, err := t.do(req, s3UnsignedPayload)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+// pushMultipart uploads the set in parts, for sets too big to be uploaded
+// at once. The upload is aborted on errors, so that the store does not keep
+// the parts uploaded so far.
+func (t *s3Target) pushMultipart(ctx context.Context, setID uint64, r io.Reader, size int64) (err error) {
+	partSize := s3PartSize
+	if minPartSize := (size + s3MaxParts - 1) / s3MaxParts; partSize < minPartSize {
+		partSize = minPartSize
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.objectURLWithQuery(setID, url.Values{"uploads": {""}}).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.do(req, s3EmptyPayload)
+	if err != nil {
+		return err
+	}
+	var initiated s3InitiateMultipartUploadResult
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("cannot initiate multipart upload: invalid response")
+	}
+	uploadID := initiated.UploadID
+	defer func() {
+		if err != nil {
+			t.abortMultipart(setID, uploadID)
+		}
+	}()
+
+	var complete s3CompleteMultipartUpload
+	for part, left := 1, size; left > 0; part++ {
+		n := partSize
+		if left < n {
+			n = left
+		}
+		query := url.Values{
+			"partNumber": {fmt.Sprint(part)},
+			"uploadId":   {uploadID},
+		}
+		lr := &io.LimitedReader{R: r, N: n}
+		etag, err := t.putPart(ctx, t.objectURLWithQuery(setID, query), lr, n)
+		if err != nil {
+			return fmt.Errorf("cannot upload part %d: %v", part, err)
+		}
+		if lr.N != 0 {
+			return fmt.Errorf("cannot upload part %d: expected %d bytes, got %d", part, n, n-lr.N)
+		}
+		complete.Parts = append(complete.Parts, s3CompletedPart{PartNumber: part, ETag: etag})
+		left -= n
+	}
+
+	body, err := xml.Marshal(&complete)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(body)
+	req, err = http.NewRequestWithContext(ctx, "POST", t.objectURLWithQuery(setID, url.Values{"uploadId": {uploadID}}).String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err = t.do(req, hex.EncodeToString(hash[:]))
+	if err != nil {
+		return fmt.Errorf("cannot complete multipart upload: %v", err)
+	}
+	defer resp.Body.Close()
+	// completing the upload can fail after the response status was sent
+	e := &s3Error{status: resp.StatusCode}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(e); err == nil && e.Code != "" {
+		return fmt.Errorf("cannot complete multipart upload: %v", e)
+	}
+	return nil
+}
+
+func (t *s3Target) abortMultipart(setID uint64, uploadID string) {
+	// the push might have failed because the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "DELETE", t.objectURLWithQuery(setID, url.Values{"uploadId": {uploadID}}).String(), nil)
+	if err != nil {
+		return
+	}
+	if resp, err := t.do(req, s3EmptyPayload); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (t *s3Target) Pull(ctx context.Context, setID uint64) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.objectURL(setID).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.do(req, s3EmptyPayload)
+	if err != nil {
+		if e, ok := err.(*s3Error); ok && e.status == http.StatusNotFound {
+			err = ErrRemoteSnapshotNotFound
+		}
+		return nil, fmt.Errorf("cannot pull snapshot set #%d from %s: %w", setID, t, err)
+	}
+	return resp.Body, nil
+}
+
+type s3Error struct {
+	status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("unexpected status %d", e.status)
+	}
+	if e.Message == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func s3ResponseError(resp *http.Response) error {
+	e := &s3Error{status: resp.StatusCode}
+	// the body has the details, if any
+	xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(e)
+	return e
+}
+
+// signS3Request signs the given request as per AWS Signature Version 4,
+// covering its host, date and payload hash.
+func signS3Request(req *http.Request, cfg *S3Config, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", req.URL.Host, payloadHash, amzDate),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := strings.Join([]string{date, cfg.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := []byte("AWS4" + cfg.SecretAccessKey)
+	for _, s := range []string{date, cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery returns the query sorted by key and escaped as expected
+// by AWS signatures.
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but the unreserved characters of
+// RFC 3986.
+func s3Escape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,193 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/fdestate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var fdestateGetEncryptedContainers = fdestate.GetEncryptedContainers
+
+type passphraseKey struct {
+	setID uint64
+}
+
+// SetPassphrase provides the passphrase of the given snapshot set, for the
+// tasks saving, checking or restoring it. It is only kept in memory, until
+// the changes of those tasks are done.
+// Note that the state must be locked by the caller.
+func SetPassphrase(st *state.State, setID uint64, passphrase string) {
+	if passphrase == "" {
+		st.Cache(passphraseKey{setID}, nil)
+		return
+	}
+	st.Cache(passphraseKey{setID}, passphrase)
+}
+
+// CheckPassphrase checks that the given passphrase unlocks the snapshots of
+// the given set that are encrypted with a passphrase.
+// Note that the state must be locked by the caller.
+func CheckPassphrase(st *state.State, setID uint64, passphrase string) error {
+	summaries, err := snapSummariesInSnapshotSet(setID, nil)
+	if err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		if summary.encryption != backend.KeySourcePassphrase {
+			continue
+		}
+		// all the snapshots of a set are encrypted with the same
+		// passphrase, so unlocking one of them is enough
+		reader, err := backendOpen(summary.filename, setID)
+		if err != nil {
+			return fmt.Errorf("cannot open snapshot: %v", err)
+		}
+		defer reader.Close()
+		return backendUnlock(reader, backend.NewPassphraseKeySource(passphrase))
+	}
+	return fmt.Errorf("snapshot set #%d is not encrypted with a passphrase", setID)
+}
+
+// passphraseTaskKinds are the kinds of the tasks that use the passphrase of
+// their snapshot set.
+var passphraseTaskKinds = []string{"save-snapshot", "check-snapshot", "restore-snapshot"}
+
+// forgetPassphrases forgets the passphrases provided for the snapshot sets
+// of the given change once it is done, unless another change in progress
+// still needs them.
+func forgetPassphrases(chg *state.Change, old, new state.Status) {
+	if old.Ready() || !new.Ready() {
+		return
+	}
+	st := chg.State()
+	setIDs := passphraseSetIDs(chg)
+	if len(setIDs) == 0 {
+		return
+	}
+	for _, other := range st.Changes() {
+		if other == chg || other.IsReady() {
+			continue
+		}
+		for setID := range passphraseSetIDs(other) {
+			delete(setIDs, setID)
+		}
+	}
+	for setID := range setIDs {
+		SetPassphrase(st, setID, "")
+	}
+}
+
+// passphraseSetIDs returns the IDs of the snapshot sets of the tasks of the
+// given change that have a passphrase provided.
+func passphraseSetIDs(chg *state.Change) map[uint64]bool {
+	st := chg.State()
+	var setIDs map[uint64]bool
+	for _, t := range chg.Tasks() {
+		if !strutil.ListContains(passphraseTaskKinds, t.Kind()) {
+			continue
+		}
+		var snapshot snapshotSetup
+		if err := t.Get("snapshot-setup", &snapshot); err != nil {
+			continue
+		}
+		if cachedPassphrase(st, snapshot.SetID) == "" {
+			continue
+		}
+		if setIDs == nil {
+			setIDs = make(map[uint64]bool)
+		}
+		setIDs[snapshot.SetID] = true
+	}
+	return setIDs
+}
+
+func cachedPassphrase(st *state.State, setID uint64) string {
+	passphrase, _ := st.Cached(passphraseKey{setID}).(string)
+	return passphrase
+}
+
+// snapshotsEncryption returns the kind of key source new snapshots should
+// be encrypted with, as per the snapshots.encryption core option, or an
+// empty string if they should not be encrypted.
+func snapshotsEncryption(st *state.State) (string, error) {
+	var encryption string
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "snapshots.encryption", &encryption); err != nil && !config.IsNoOption(err) {
+		return "", err
+	}
+	if encryption == "none" {
+		return "", nil
+	}
+	return encryption, nil
+}
+
+// keySource returns the key source of the given kind for the given
+// snapshot set.
+func keySource(st *state.State, kind string, setID uint64) (backend.KeySource, error) {
+	switch kind {
+	case backend.KeySourceFile:
+		var keyFile string
+		tr := config.NewTransaction(st)
+		if err := tr.Get("core", "snapshots.encryption-key-file", &keyFile); err != nil && !config.IsNoOption(err) {
+			return nil, err
+		}
+		if keyFile == "" {
+			return nil, fmt.Errorf("cannot find snapshot key: snapshots.encryption-key-file is not set")
+		}
+		return backend.NewFileKeySource(keyFile), nil
+	case backend.KeySourceTPM:
+		containers, err := fdestateGetEncryptedContainers(st)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find encrypted disks for snapshot key: %v", err)
+		}
+		devices := make([]string, 0, len(containers))
+		for _, container := range containers {
+			devices = append(devices, container.DevPath())
+		}
+		return backend.NewTPMKeySource(devices), nil
+	case backend.KeySourcePassphrase:
+		passphrase := cachedPassphrase(st, setID)
+		if passphrase == "" {
+			return nil, fmt.Errorf("snapshot set #%d is encrypted with a passphrase, but none was provided", setID)
+		}
+		return backend.NewPassphraseKeySource(passphrase), nil
+	}
+	return nil, fmt.Errorf("unsupported snapshot key source %q", kind)
+}
+
+// unlockSnapshot unlocks the given snapshot reader if it is encrypted.
+func unlockSnapshot(st *state.State, reader *backend.Reader, setID uint64) error {
+	if reader.Encryption == nil {
+		return nil
+	}
+	st.Lock()
+	ks, err := keySource(st, reader.Encryption.KeySource, setID)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+	return backendUnlock(reader, ks)
+}
@@ -25,8 +25,10 @@ import (
 	"io"
 	"time"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
+	fdeBackend "github.com/snapcore/snapd/overlord/fdestate/backend"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -121,6 +123,20 @@ func MockBackendCleanupAbandonedImports(f func() (int, error)) (restore func())
 	return testutil.Mock(&backendCleanupAbandonedImports, f)
 }
 
+func MockBackendGarbageCollectChunks(f func(context.Context) (int, error)) (restore func()) {
+	return testutil.Mock(&backendGarbageCollectChunks, f)
+}
+
+var CachedPassphrase = cachedPassphrase
+
+func MockBackendUnlock(f func(*backend.Reader, backend.KeySource) error) (restore func()) {
+	return testutil.Mock(&backendUnlock, f)
+}
+
+func MockFdestateGetEncryptedContainers(f func(*state.State) ([]fdeBackend.EncryptedContainer, error)) (restore func()) {
+	return testutil.Mock(&fdestateGetEncryptedContainers, f)
+}
+
 func MockBackendEstimateSnapshotSize(f func(*snap.Info, []string, *dirs.SnapDirOptions) (uint64, error)) (restore func()) {
 	return testutil.Mock(&backendEstimateSnapshotSize, f)
 }
@@ -142,6 +158,11 @@ func SetLastForgetExpiredSnapshotTime(mgr *SnapshotManager, t time.Time) {
 	mgr.lastForgetExpiredSnapshotTime = t
 }
 
+// For testing only
+func SetLastScheduledPruneTime(mgr *SnapshotManager, t time.Time) {
+	mgr.lastScheduledPruneTime = t
+}
+
 func MockGetSnapDirOptions(f func(*state.State, string) (*dirs.SnapDirOptions, error)) (restore func()) {
 	return testutil.Mock(&getSnapDirOpts, f)
 }
@@ -149,3 +170,52 @@ func MockGetSnapDirOptions(f func(*state.State, string) (*dirs.SnapDirOptions, e
 func MockBackendMapSnapDataDirToSnapVar(f func(*snap.Info, *dirs.SnapDirOptions, []string) (map[string]string, error)) (restore func()) {
 	return testutil.Mock(&backendMapSnapDataDirToSnapVar, f)
 }
+
+type ScheduledRetention = scheduledRetention
+
+var (
+	ScheduledSnapshotsRetention = scheduledSnapshotsRetention
+	PrunedScheduledSnapshotSets = prunedScheduledSnapshotSets
+	SaveScheduled               = saveScheduled
+)
+
+func NewScheduledRetention(keepLast, keepDaily, keepWeekly int) *ScheduledRetention {
+	return &scheduledRetention{keepLast: keepLast, keepDaily: keepDaily, keepWeekly: keepWeekly}
+}
+
+func NextScheduledSnapshot(mgr *SnapshotManager, snapName string) time.Time {
+	return mgr.nextScheduled[snapName]
+}
+
+func SetNextScheduledSnapshot(mgr *SnapshotManager, snapName string, t time.Time) {
+	mgr.nextScheduled[snapName] = t
+}
+
+var (
+	RemoteTarget    = remoteTarget
+	MarkPushPending = markPushPending
+	PendingPushes   = pendingPushes
+	DoPush          = doPush
+)
+
+func MockRemotePushRetryInterval(d time.Duration) (restore func()) {
+	return testutil.Mock(&remotePushRetryInterval, d)
+}
+
+var (
+	MarkPushed        = markPushed
+	MarkPushAttempted = markPushAttempted
+	NextPushAttempt   = nextPushAttempt
+
+	ErrNoDeviceIdentity = errNoDeviceIdentity
+)
+
+type SnapshotState = snapshotState
+
+func MockRemotePushMaxAttempts(n int) (restore func()) {
+	return testutil.Mock(&remotePushMaxAttempts, n)
+}
+
+func MockDevicestateSerial(f func(*state.State) (*asserts.Serial, error)) (restore func()) {
+	return testutil.Mock(&devicestateSerial, f)
+}
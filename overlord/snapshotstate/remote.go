@@ -0,0 +1,356 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/httputil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/configstate/proxyconf"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snapdenv"
+)
+
+var (
+	// remotePushRetryInterval is how long to wait before trying again to
+	// push a snapshot set after a first failed attempt; the wait doubles
+	// after each further one, up to remotePushMaxRetryInterval.
+	remotePushRetryInterval    = time.Hour
+	remotePushMaxRetryInterval = 24 * time.Hour
+	// remotePushMaxAttempts is how many times pushing a snapshot set is
+	// attempted before giving up.
+	remotePushMaxAttempts = 10
+)
+
+var devicestateSerial = devicestate.Serial
+
+// errNoDeviceIdentity is returned when using the remote target before the
+// device has the serial that identifies its snapshot sets there.
+var errNoDeviceIdentity = errors.New("cannot use a remote snapshot target before the device is registered")
+
+var (
+	// ErrNoRemoteTarget is returned when pushing or pulling snapshot
+	// sets while no remote target is configured.
+	ErrNoRemoteTarget = errors.New("no remote snapshot target is configured")
+	// ErrRemoteSnapshotNotFound is wrapped by the error returned when
+	// pulling a snapshot set the remote target does not have.
+	ErrRemoteSnapshotNotFound = backend.ErrRemoteSnapshotNotFound
+)
+
+// s3Credentials is the content of the snapshots.remote.s3.credentials-file.
+type s3Credentials struct {
+	AccessKeyID     string `json:"access-key-id"`
+	SecretAccessKey string `json:"secret-access-key"`
+}
+
+func readS3Credentials(path string) (*s3Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read S3 credentials: %v", err)
+	}
+	var creds s3Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("cannot decode S3 credentials from %q: %v", path, err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 credentials in %q must have an access-key-id and a secret-access-key", path)
+	}
+	return &creds, nil
+}
+
+func remoteTargetOption(tr *config.Transaction, opt string) (string, error) {
+	var v string
+	if err := tr.Get("core", "snapshots.remote."+opt, &v); err != nil && !config.IsNoOption(err) {
+		return "", err
+	}
+	return v, nil
+}
+
+// remoteTargetKind returns the kind of the remote target snapshot sets are
+// pushed to, as per the snapshots.remote.type core option, or an empty
+// string if there is none.
+// The state needs to be locked by the caller.
+func remoteTargetKind(st *state.State) (string, error) {
+	kind, err := remoteTargetOption(config.NewTransaction(st), "type")
+	if err != nil {
+		return "", err
+	}
+	if kind == "none" {
+		return "", nil
+	}
+	return kind, nil
+}
+
+// remoteDevicePath returns the path identifying the device in a remote
+// target, made of the brand, model and serial of the device, so that devices
+// sharing a target do not overwrite each other's snapshot sets. The
+// snapshots.remote.device-path core option overrides it, e.g. for a device
+// replacing another one to push and pull the sets of the latter.
+// The state needs to be locked by the caller.
+func remoteDevicePath(st *state.State) (string, error) {
+	devicePath, err := remoteTargetOption(config.NewTransaction(st), "device-path")
+	if err != nil {
+		return "", err
+	}
+	if devicePath != "" {
+		return devicePath, nil
+	}
+
+	serial, err := devicestateSerial(st)
+	if errors.Is(err, state.ErrNoState) {
+		return "", errNoDeviceIdentity
+	}
+	if err != nil {
+		return "", err
+	}
+	parts := []string{serial.BrandID(), serial.Model(), serial.Serial()}
+	for i, part := range parts {
+		part = url.PathEscape(part)
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("cannot use device serial %q in the path of a remote snapshot target", serial.Serial())
+		}
+		parts[i] = part
+	}
+	return path.Join(parts...), nil
+}
+
+// remoteTarget returns the remote target snapshot sets are pushed to, as
+// per the snapshots.remote.* core options, or nil if there is none.
+// The state needs to be locked by the caller.
+func remoteTarget(st *state.State) (backend.RemoteTarget, error) {
+	kind, err := remoteTargetKind(st)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "" {
+		return nil, nil
+	}
+	devicePath, err := remoteDevicePath(st)
+	if err != nil {
+		return nil, err
+	}
+	tr := config.NewTransaction(st)
+	get := func(opt string) (string, error) {
+		return remoteTargetOption(tr, opt)
+	}
+
+	switch kind {
+	case backend.RemoteTargetDirectory:
+		dir, err := get("directory")
+		if err != nil {
+			return nil, err
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("snapshots.remote.directory is not set")
+		}
+		return backend.NewDirectoryTarget(dir, devicePath), nil
+	case backend.RemoteTargetS3:
+		var cfg backend.S3Config
+		for _, opt := range []struct {
+			name string
+			v    *string
+		}{
+			{"s3.endpoint", &cfg.Endpoint},
+			{"s3.bucket", &cfg.Bucket},
+			{"s3.region", &cfg.Region},
+			{"s3.prefix", &cfg.Prefix},
+		} {
+			if *opt.v, err = get(opt.name); err != nil {
+				return nil, err
+			}
+		}
+		credentialsFile, err := get("s3.credentials-file")
+		if err != nil {
+			return nil, err
+		}
+		if credentialsFile == "" {
+			return nil, fmt.Errorf("snapshots.remote.s3.credentials-file is not set")
+		}
+		creds, err := readS3Credentials(credentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.AccessKeyID = creds.AccessKeyID
+		cfg.SecretAccessKey = creds.SecretAccessKey
+		cfg.DevicePath = devicePath
+		cfg.Proxy = proxyconf.New(st).Conf
+		cfg.ProxyConnectHeader = http.Header{"User-Agent": []string{snapdenv.UserAgent()}}
+		cfg.ExtraSSLCerts = &httputil.ExtraSSLCertsFromDir{Dir: dirs.SnapdStoreSSLCertsDir}
+		return backend.NewS3Target(&cfg)
+	}
+	return nil, fmt.Errorf("unsupported remote snapshot target %q", kind)
+}
+
+// updateSnapshotState applies the given update to the state of the given
+// snapshot set, creating it if needed.
+// The state needs to be locked by the caller.
+func updateSnapshotState(st *state.State, setID uint64, update func(*snapshotState)) error {
+	var snapshots map[uint64]*snapshotState
+	if err := st.Get("snapshots", &snapshots); err != nil && !errors.Is(err, state.ErrNoState) {
+		return err
+	}
+	snapshot := snapshots[setID]
+	if snapshot == nil {
+		snapshot = &snapshotState{}
+	}
+	update(snapshot)
+	return saveSnapshotState(st, setID, snapshot)
+}
+
+// markPushPending records that the given snapshot set is to be pushed to
+// the remote target once complete.
+// The state needs to be locked by the caller.
+func markPushPending(st *state.State, setID uint64) error {
+	return updateSnapshotState(st, setID, func(snapshot *snapshotState) {
+		snapshot.PushPending = true
+	})
+}
+
+// markPushed records that the given snapshot set was pushed to the remote
+// target at the given time.
+// The state needs to be locked by the caller.
+func markPushed(st *state.State, setID uint64, t time.Time) error {
+	return updateSnapshotState(st, setID, func(snapshot *snapshotState) {
+		snapshot.PushPending = false
+		snapshot.PushTime = t
+		snapshot.PushAttempts = 0
+		snapshot.PushAttemptTime = time.Time{}
+	})
+}
+
+// markPushAttempted records that pushing the given snapshot set to the remote
+// target was attempted at the given time.
+// The state needs to be locked by the caller.
+func markPushAttempted(st *state.State, setID uint64, t time.Time) error {
+	return updateSnapshotState(st, setID, func(snapshot *snapshotState) {
+		snapshot.PushAttempts++
+		snapshot.PushAttemptTime = t
+	})
+}
+
+// markPushAbandoned records that pushing the given snapshot set to the
+// remote target was given up on.
+// The state needs to be locked by the caller.
+func markPushAbandoned(st *state.State, setID uint64) error {
+	return updateSnapshotState(st, setID, func(snapshot *snapshotState) {
+		snapshot.PushPending = false
+		snapshot.PushAttempts = 0
+		snapshot.PushAttemptTime = time.Time{}
+	})
+}
+
+// nextPushAttempt returns when pushing the given snapshot set to the remote
+// target is to be attempted next, after the attempts made so far.
+func nextPushAttempt(snapshot *snapshotState) time.Time {
+	if snapshot.PushAttempts == 0 {
+		return time.Time{}
+	}
+	interval := remotePushRetryInterval
+	for i := 1; i < snapshot.PushAttempts && interval < remotePushMaxRetryInterval; i++ {
+		interval *= 2
+	}
+	if interval > remotePushMaxRetryInterval {
+		interval = remotePushMaxRetryInterval
+	}
+	return snapshot.PushAttemptTime.Add(interval)
+}
+
+// pendingPushes returns the snapshot sets waiting to be pushed to the remote
+// target, oldest first, with their state.
+// The state needs to be locked by the caller.
+func pendingPushes(st *state.State) ([]uint64, map[uint64]*snapshotState, error) {
+	var snapshots map[uint64]*snapshotState
+	if err := st.Get("snapshots", &snapshots); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, nil, err
+	}
+	var pending []uint64
+	for setID, snapshot := range snapshots {
+		if snapshot.PushPending {
+			pending = append(pending, setID)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+	return pending, snapshots, nil
+}
+
+func newPushTask(st *state.State, setID uint64, target backend.RemoteTarget) *state.Task {
+	desc := fmt.Sprintf("Push snapshot set #%d to %s", setID, target)
+	task := st.NewTask("push-snapshot", desc)
+	task.Set("snapshot-setup", &snapshotSetup{SetID: setID})
+	return task
+}
+
+// Push creates a taskset for pushing a snapshot set to the remote target.
+// Note that the state must be locked by the caller.
+func Push(st *state.State, setID uint64) (*state.TaskSet, error) {
+	target, err := remoteTarget(st)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrNoRemoteTarget
+	}
+	// push needs to conflict with forget of itself, and with itself
+	if err := checkSnapshotConflict(st, setID, "forget-snapshot", "push-snapshot"); err != nil {
+		return nil, err
+	}
+	if _, err := snapSummariesInSnapshotSet(setID, nil); err != nil {
+		return nil, err
+	}
+
+	return state.NewTaskSet(newPushTask(st, setID, target)), nil
+}
+
+// Pull imports a snapshot set that was pushed to the remote target under the
+// given ID, returning the ID it is known by locally. The passphrase is only
+// needed to verify snapshots encrypted with one.
+// Note that the state must not be locked by the caller.
+func Pull(ctx context.Context, st *state.State, setID uint64, passphrase string) (newSetID uint64, snapNames []string, err error) {
+	st.Lock()
+	target, err := remoteTarget(st)
+	st.Unlock()
+	if err != nil {
+		return 0, nil, err
+	}
+	if target == nil {
+		return 0, nil, ErrNoRemoteTarget
+	}
+
+	r, err := target.Pull(ctx, setID)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer r.Close()
+
+	return Import(ctx, st, r, passphrase)
+}
@@ -0,0 +1,507 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+var (
+	serialStack     *assertstest.StoreStack
+	serialDeviceKey asserts.PrivateKey
+)
+
+func mockSerial(c *check.C, brandID, model, serial string) *asserts.Serial {
+	if serialStack == nil {
+		serialStack = assertstest.NewStoreStack("canonical", nil)
+		serialDeviceKey, _ = assertstest.GenerateKey(752)
+	}
+	encodedKey, err := asserts.EncodePublicKey(serialDeviceKey.PublicKey())
+	c.Assert(err, check.IsNil)
+	a, err := serialStack.Sign(asserts.SerialType, map[string]any{
+		"authority-id":        "canonical",
+		"brand-id":            brandID,
+		"model":               model,
+		"serial":              serial,
+		"device-key":          string(encodedKey),
+		"device-key-sha3-384": serialDeviceKey.PublicKey().ID(),
+		"timestamp":           time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	return a.(*asserts.Serial)
+}
+
+func (snapshotSuite) TestRemoteTarget(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	target, err := snapshotstate.RemoteTarget(st)
+	c.Assert(err, check.IsNil)
+	c.Check(target, check.IsNil)
+
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": "/mnt/nfs",
+	})
+	target, err = snapshotstate.RemoteTarget(st)
+	c.Assert(err, check.IsNil)
+	c.Check(target.String(), check.Equals, `directory "/mnt/nfs"`)
+
+	credentialsFile := filepath.Join(c.MkDir(), "s3.json")
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.remote.type":                "s3",
+		"snapshots.remote.s3.endpoint":         "http://minio.local:9000",
+		"snapshots.remote.s3.bucket":           "snapshots",
+		"snapshots.remote.s3.credentials-file": credentialsFile,
+	})
+	_, err = snapshotstate.RemoteTarget(st)
+	c.Check(err, check.ErrorMatches, `cannot read S3 credentials: open .*/s3.json: no such file or directory`)
+
+	c.Assert(os.WriteFile(credentialsFile, []byte(`{"access-key-id": "key"}`), 0600), check.IsNil)
+	_, err = snapshotstate.RemoteTarget(st)
+	c.Check(err, check.ErrorMatches, `S3 credentials in ".*/s3.json" must have an access-key-id and a secret-access-key`)
+
+	c.Assert(os.WriteFile(credentialsFile, []byte(`{"access-key-id": "key", "secret-access-key": "secret"}`), 0600), check.IsNil)
+	target, err = snapshotstate.RemoteTarget(st)
+	c.Assert(err, check.IsNil)
+	c.Check(target.String(), check.Equals, `S3 bucket "snapshots" at http://minio.local:9000`)
+
+	setCoreConfig(c, st, map[string]any{"snapshots.remote.type": "none"})
+	target, err = snapshotstate.RemoteTarget(st)
+	c.Assert(err, check.IsNil)
+	c.Check(target, check.IsNil)
+}
+
+func (snapshotSuite) TestRemoteTargetDeviceIdentity(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": "/mnt/nfs",
+	})
+
+	restore := snapshotstate.MockDevicestateSerial(func(*state.State) (*asserts.Serial, error) {
+		return nil, state.ErrNoState
+	})
+	defer restore()
+	_, err := snapshotstate.RemoteTarget(st)
+	c.Check(err, check.Equals, snapshotstate.ErrNoDeviceIdentity)
+
+	serial := mockSerial(c, "canonical", "pc", "..")
+	restore = snapshotstate.MockDevicestateSerial(func(*state.State) (*asserts.Serial, error) {
+		return serial, nil
+	})
+	defer restore()
+	_, err = snapshotstate.RemoteTarget(st)
+	c.Check(err, check.ErrorMatches, `cannot use device serial ".." in the path of a remote snapshot target`)
+}
+
+func (snapshotSuite) TestNextPushAttempt(c *check.C) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		attempts int
+		next     time.Time
+	}{
+		{0, time.Time{}},
+		{1, t0.Add(time.Hour)},
+		{2, t0.Add(2 * time.Hour)},
+		{3, t0.Add(4 * time.Hour)},
+		{5, t0.Add(16 * time.Hour)},
+		{6, t0.Add(24 * time.Hour)},
+		{9, t0.Add(24 * time.Hour)},
+	} {
+		snapshot := &snapshotstate.SnapshotState{PushAttempts: tc.attempts, PushAttemptTime: t0}
+		c.Check(snapshotstate.NextPushAttempt(snapshot), check.Equals, tc.next, check.Commentf("%d attempts", tc.attempts))
+	}
+}
+
+func (snapshotSuite) TestMarkPushedResetsAttempts(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	c.Assert(snapshotstate.MarkPushPending(st, 42), check.IsNil)
+	c.Assert(snapshotstate.MarkPushAttempted(st, 42, time.Now()), check.IsNil)
+	c.Assert(snapshotstate.MarkPushAttempted(st, 42, time.Now()), check.IsNil)
+	_, snapshots, err := snapshotstate.PendingPushes(st)
+	c.Assert(err, check.IsNil)
+	c.Check(snapshots[42].PushAttempts, check.Equals, 2)
+
+	c.Assert(snapshotstate.MarkPushed(st, 42, time.Now()), check.IsNil)
+	pending, snapshots, err := snapshotstate.PendingPushes(st)
+	c.Assert(err, check.IsNil)
+	c.Check(pending, check.HasLen, 0)
+	c.Check(snapshots[42].PushAttempts, check.Equals, 0)
+	c.Check(snapshots[42].PushAttemptTime.IsZero(), check.Equals, true)
+}
+
+func (snapshotSuite) TestDoSaveMarksPushPending(c *check.C) {
+	snapInfo := snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "a-snap",
+			Revision: snap.R(-1),
+		},
+		Version: "1.33",
+	}
+	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) {
+		return &snapInfo, nil
+	})()
+	defer osutil.MockMountInfo("")()
+	defer snapshotstate.MockBackendSave(func(context.Context, uint64, *snap.Info, map[string]any, []string, *snap.SnapshotOptions, *dirs.SnapDirOptions, *backend.SaveFlags) (*client.Snapshot, error) {
+		return nil, nil
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": c.MkDir(),
+	})
+	var tasks []*state.Task
+	for setID, auto := range map[uint64]bool{41: true, 42: false} {
+		task := st.NewTask("save-snapshot", "...")
+		task.Set("snapshot-setup", map[string]any{
+			"set-id": setID,
+			"snap":   "a-snap",
+			"auto":   auto,
+		})
+		tasks = append(tasks, task)
+	}
+	st.Unlock()
+
+	for _, task := range tasks {
+		c.Assert(snapshotstate.DoSave(task, &tomb.Tomb{}), check.IsNil)
+	}
+
+	st.Lock()
+	defer st.Unlock()
+	// automatic snapshots are not pushed
+	pending, _, err := snapshotstate.PendingPushes(st)
+	c.Assert(err, check.IsNil)
+	c.Check(pending, check.DeepEquals, []uint64{42})
+}
+
+func (s *snapshotSuite) TestEnsureRemotePushes(c *check.C) {
+	st, mgr := s.mockScheduledSnapshotManager(c)
+
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.schedule.foo":     "",
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": "/mnt/nfs",
+	})
+	for _, setID := range []uint64{2, 3} {
+		c.Assert(snapshotstate.MarkPushPending(st, setID), check.IsNil)
+	}
+	// set 3 is still being saved
+	chg := st.NewChange("save-snapshot", "...")
+	task := st.NewTask("save-snapshot", "...")
+	task.Set("snapshot-setup", map[string]any{"set-id": 3, "snap": "foo"})
+	chg.AddTask(task)
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	var pushChanges []*state.Change
+	for _, chg := range st.Changes() {
+		if chg.Kind() == "push-snapshot" {
+			pushChanges = append(pushChanges, chg)
+		}
+	}
+	c.Assert(pushChanges, check.HasLen, 1)
+	c.Check(pushChanges[0].Summary(), check.Equals, `Push snapshot set #2 to directory "/mnt/nfs"`)
+	tasks := pushChanges[0].Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Kind(), check.Equals, "push-snapshot")
+	var snapshot map[string]any
+	c.Assert(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot["set-id"], check.Equals, 2.)
+
+	// the push fails, and is only retried later
+	tasks[0].SetStatus(state.ErrorStatus)
+	c.Check(pushChanges[0].IsReady(), check.Equals, true)
+	task.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	c.Check(st.Changes(), check.HasLen, 3)
+	st.Unlock()
+
+	restore := snapshotstate.MockRemotePushRetryInterval(0)
+	defer restore()
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 4)
+}
+
+func (s *snapshotSuite) TestEnsureRemotePushesNoTarget(c *check.C) {
+	st, mgr := s.mockScheduledSnapshotManager(c)
+
+	st.Lock()
+	c.Assert(snapshotstate.MarkPushPending(st, 2), check.IsNil)
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+	// still waiting for a target
+	pending, _, err := snapshotstate.PendingPushes(st)
+	c.Assert(err, check.IsNil)
+	c.Check(pending, check.DeepEquals, []uint64{2})
+}
+
+func (s *snapshotSuite) TestEnsureRemotePushesNoDeviceIdentity(c *check.C) {
+	restore := snapshotstate.MockDevicestateSerial(func(*state.State) (*asserts.Serial, error) {
+		return nil, state.ErrNoState
+	})
+	defer restore()
+	st, mgr := s.mockScheduledSnapshotManager(c)
+
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.schedule.foo":     "",
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": "/mnt/nfs",
+	})
+	c.Assert(snapshotstate.MarkPushPending(st, 2), check.IsNil)
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+	c.Check(st.AllWarnings(), check.HasLen, 0)
+	pending, _, err := snapshotstate.PendingPushes(st)
+	c.Assert(err, check.IsNil)
+	c.Check(pending, check.DeepEquals, []uint64{2})
+}
+
+func (s *snapshotSuite) TestEnsureRemotePushesGivesUp(c *check.C) {
+	defer snapshotstate.MockRemotePushRetryInterval(0)()
+	defer snapshotstate.MockRemotePushMaxAttempts(2)()
+	st, mgr := s.mockScheduledSnapshotManager(c)
+
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.schedule.foo":     "",
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": "/mnt/nfs",
+	})
+	c.Assert(snapshotstate.MarkPushPending(st, 2), check.IsNil)
+	st.Unlock()
+
+	for i := 0; i < 3; i++ {
+		c.Assert(mgr.Ensure(), check.IsNil)
+		st.Lock()
+		for _, chg := range st.Changes() {
+			for _, t := range chg.Tasks() {
+				if t.Status() == state.DoStatus {
+					t.SetStatus(state.ErrorStatus)
+				}
+			}
+		}
+		st.Unlock()
+	}
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 2)
+	pending, snapshots, err := snapshotstate.PendingPushes(st)
+	c.Assert(err, check.IsNil)
+	c.Check(pending, check.HasLen, 0)
+	c.Check(snapshots[2].PushAttempts, check.Equals, 0)
+	warnings := st.AllWarnings()
+	c.Assert(warnings, check.HasLen, 1)
+	c.Check(warnings[0].String(), check.Equals, `cannot push snapshot set #2 to directory "/mnt/nfs": giving up after 2 attempts`)
+}
+
+func (snapshotSuite) TestPushErrors(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	_, err := snapshotstate.Push(st, 42)
+	c.Check(err, check.Equals, snapshotstate.ErrNoRemoteTarget)
+
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": "/mnt/nfs",
+	})
+	_, err = snapshotstate.Push(st, 42)
+	c.Check(err, check.Equals, client.ErrSnapshotSetNotFound)
+
+	chg := st.NewChange("forget-snapshot", "...")
+	task := st.NewTask("forget-snapshot", "...")
+	task.Set("snapshot-setup", map[string]any{"set-id": 42})
+	chg.AddTask(task)
+	_, err = snapshotstate.Push(st, 42)
+	c.Check(err, check.ErrorMatches, `cannot operate on snapshot set #42 while change "1" is in progress`)
+}
+
+func (snapshotSuite) TestForgetConflictsWithPush(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("push-snapshot", "...")
+	task := st.NewTask("push-snapshot", "...")
+	task.Set("snapshot-setup", map[string]any{"set-id": 42})
+	chg.AddTask(task)
+
+	_, _, err := snapshotstate.Forget(st, 42, nil)
+	c.Check(err, check.ErrorMatches, `cannot operate on snapshot set #42 while change "1" is in progress`)
+}
+
+func (s *snapshotSuite) TestPushPullRoundTrip(c *check.C) {
+	c.Assert(os.MkdirAll(dirs.SnapshotsDir, 0755), check.IsNil)
+	sideInfo := &snap.SideInfo{RealName: "a-snap", Revision: snap.R(1)}
+	snapInfo := snaptest.MockSnap(c, "{name: a-snap, version: v1}", sideInfo)
+	c.Assert(os.MkdirAll(snapInfo.DataDir(), 0755), check.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(snapInfo.DataDir(), "canary"), []byte("tweet"), 0644), check.IsNil)
+	// only the system data is saved
+	_, err := backend.Save(context.TODO(), 42, snapInfo, nil, []string{}, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	remoteDir := c.MkDir()
+	st := state.New(nil)
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": remoteDir,
+	})
+	c.Assert(snapshotstate.MarkPushPending(st, 42), check.IsNil)
+	ts, err := snapshotstate.Push(st, 42)
+	c.Assert(err, check.IsNil)
+	tasks := ts.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Summary(), check.Equals, fmt.Sprintf("Push snapshot set #42 to directory %q", remoteDir))
+	st.Unlock()
+
+	before := time.Now()
+	c.Assert(snapshotstate.DoPush(tasks[0], &tomb.Tomb{}), check.IsNil)
+	c.Check(filepath.Join(remoteDir, "canonical/pc/serial-1/42.snapshot"), testutil.FilePresent)
+
+	st.Lock()
+	pending, _, err := snapshotstate.PendingPushes(st)
+	c.Assert(err, check.IsNil)
+	c.Check(pending, check.HasLen, 0)
+	var snapshots map[uint64]map[string]any
+	c.Assert(st.Get("snapshots", &snapshots), check.IsNil)
+	pushTime, err := time.Parse(time.RFC3339Nano, snapshots[42]["push-time"].(string))
+	c.Assert(err, check.IsNil)
+	c.Check(pushTime.Before(before), check.Equals, false)
+	st.Unlock()
+
+	// the set is lost locally
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapshotsDir, "42_*.zip"))
+	c.Assert(err, check.IsNil)
+	c.Assert(matches, check.HasLen, 1)
+	c.Assert(os.Remove(matches[0]), check.IsNil)
+
+	// and pulled back under a new ID
+	setID, snapNames, err := snapshotstate.Pull(context.TODO(), st, 42, "")
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Not(check.Equals), uint64(42))
+	c.Check(snapNames, check.DeepEquals, []string{"a-snap"})
+
+	st.Lock()
+	defer st.Unlock()
+	sets, err := snapshotstate.List(context.TODO(), st, setID, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 1)
+	c.Check(sets[0].Snapshots[0].Snap, check.Equals, "a-snap")
+}
+
+func (s *snapshotSuite) TestPushToConfiguredDevicePath(c *check.C) {
+	// the device is not registered yet, its path is configured
+	restore := snapshotstate.MockDevicestateSerial(func(*state.State) (*asserts.Serial, error) {
+		return nil, state.ErrNoState
+	})
+	defer restore()
+	c.Assert(os.MkdirAll(dirs.SnapshotsDir, 0755), check.IsNil)
+	sideInfo := &snap.SideInfo{RealName: "a-snap", Revision: snap.R(1)}
+	snapInfo := snaptest.MockSnap(c, "{name: a-snap, version: v1}", sideInfo)
+	c.Assert(os.MkdirAll(snapInfo.DataDir(), 0755), check.IsNil)
+	_, err := backend.Save(context.TODO(), 42, snapInfo, nil, []string{}, nil, nil, nil)
+	c.Assert(err, check.IsNil)
+
+	remoteDir := c.MkDir()
+	st := state.New(nil)
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.remote.type":        "directory",
+		"snapshots.remote.directory":   remoteDir,
+		"snapshots.remote.device-path": "canonical/pc/old-serial",
+	})
+	c.Assert(snapshotstate.MarkPushPending(st, 42), check.IsNil)
+	ts, err := snapshotstate.Push(st, 42)
+	c.Assert(err, check.IsNil)
+	st.Unlock()
+
+	c.Assert(snapshotstate.DoPush(ts.Tasks()[0], &tomb.Tomb{}), check.IsNil)
+	c.Check(filepath.Join(remoteDir, "canonical/pc/old-serial/42.snapshot"), testutil.FilePresent)
+}
+
+func (snapshotSuite) TestPullNotFound(c *check.C) {
+	st := state.New(nil)
+
+	_, _, err := snapshotstate.Pull(context.TODO(), st, 42, "")
+	c.Check(err, check.Equals, snapshotstate.ErrNoRemoteTarget)
+
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.remote.type":      "directory",
+		"snapshots.remote.directory": c.MkDir(),
+	})
+	st.Unlock()
+
+	_, _, err = snapshotstate.Pull(context.TODO(), st, 42, "")
+	c.Check(err, check.ErrorMatches, `cannot pull snapshot set #42 from directory ".*": snapshot set not found in remote target`)
+}
@@ -0,0 +1,259 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	// scheduledSnapshotMaxInterval is the longest time between two
+	// scheduled snapshots of a snap, whatever its timer says
+	scheduledSnapshotMaxInterval = 31 * 24 * time.Hour
+
+	// defaultScheduledKeepLast is how many scheduled snapshots of a snap
+	// are kept when no retention policy is configured
+	defaultScheduledKeepLast = 7
+)
+
+// scheduledRetention is the policy deciding which scheduled snapshots of a
+// snap are kept: the keepLast most recent ones, plus the most recent one of
+// each of the last keepDaily days and of the last keepWeekly weeks that
+// have any.
+type scheduledRetention struct {
+	keepLast   int
+	keepDaily  int
+	keepWeekly int
+}
+
+// snapshotSchedules returns the snapshot timers of the snaps that have one,
+// as per the snapshots.schedule.<snap> core options.
+func snapshotSchedules(st *state.State) (map[string]string, error) {
+	var timers map[string]string
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "snapshots.schedule", &timers); err != nil && !config.IsNoOption(err) {
+		return nil, err
+	}
+	return timers, nil
+}
+
+func scheduledRetentionOption(tr *config.Transaction, opt string) (int, error) {
+	var v any
+	if err := tr.Get("core", "snapshots.scheduled."+opt, &v); err != nil {
+		if config.IsNoOption(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	n, err := strconv.Atoi(fmt.Sprint(v))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("snapshots.scheduled.%s is not a valid number: %q", opt, v)
+	}
+	return n, nil
+}
+
+// scheduledSnapshotsRetention returns the retention policy of scheduled
+// snapshots, as per the snapshots.scheduled.keep-{last,daily,weekly} core
+// options.
+func scheduledSnapshotsRetention(st *state.State) (*scheduledRetention, error) {
+	tr := config.NewTransaction(st)
+	var retention scheduledRetention
+	var err error
+	if retention.keepLast, err = scheduledRetentionOption(tr, "keep-last"); err != nil {
+		return nil, err
+	}
+	if retention.keepDaily, err = scheduledRetentionOption(tr, "keep-daily"); err != nil {
+		return nil, err
+	}
+	if retention.keepWeekly, err = scheduledRetentionOption(tr, "keep-weekly"); err != nil {
+		return nil, err
+	}
+	if retention.keepLast == 0 && retention.keepDaily == 0 && retention.keepWeekly == 0 {
+		retention.keepLast = defaultScheduledKeepLast
+	}
+	return &retention, nil
+}
+
+// keepNewestPerPeriod marks as kept the most recent of the given times in
+// each of the last n periods that have any; times must be sorted newest
+// first.
+func keepNewestPerPeriod(kept []bool, times []time.Time, n int, period func(time.Time) string) {
+	seen := make(map[string]bool, n)
+	for i, t := range times {
+		if len(seen) >= n {
+			break
+		}
+		p := period(t)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		kept[i] = true
+	}
+}
+
+// keep returns which of the given times the retention policy keeps; times
+// must be sorted newest first.
+func (r *scheduledRetention) keep(times []time.Time) []bool {
+	kept := make([]bool, len(times))
+	for i := 0; i < len(times) && i < r.keepLast; i++ {
+		kept[i] = true
+	}
+	keepNewestPerPeriod(kept, times, r.keepDaily, func(t time.Time) string {
+		return t.Local().Format("2006-01-02")
+	})
+	keepNewestPerPeriod(kept, times, r.keepWeekly, func(t time.Time) string {
+		year, week := t.Local().ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+	return kept
+}
+
+// prunedScheduledSnapshotSets returns the scheduled snapshot sets that are
+// not kept by the given retention policy.
+// The state needs to be locked by the caller.
+func prunedScheduledSnapshotSets(st *state.State, retention *scheduledRetention) (map[uint64]bool, error) {
+	var snapshots map[uint64]*snapshotState
+	if err := st.Get("snapshots", &snapshots); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+
+	type scheduledSet struct {
+		id uint64
+		t  time.Time
+	}
+	bySnap := make(map[string][]scheduledSet)
+	for setID, snapshot := range snapshots {
+		if snapshot.Origin != client.SnapshotOriginScheduled {
+			continue
+		}
+		bySnap[snapshot.Snap] = append(bySnap[snapshot.Snap], scheduledSet{id: setID, t: snapshot.Time})
+	}
+
+	pruned := make(map[uint64]bool)
+	for _, sets := range bySnap {
+		sort.Slice(sets, func(i, j int) bool {
+			if sets[i].t.Equal(sets[j].t) {
+				return sets[i].id > sets[j].id
+			}
+			return sets[i].t.After(sets[j].t)
+		})
+		times := make([]time.Time, len(sets))
+		for i, set := range sets {
+			times[i] = set.t
+		}
+		for i, kept := range retention.keep(times) {
+			if !kept {
+				pruned[sets[i].id] = true
+			}
+		}
+	}
+	return pruned, nil
+}
+
+// lastScheduledSnapshots returns when the last scheduled snapshot of each
+// snap was taken.
+// The state needs to be locked by the caller.
+func lastScheduledSnapshots(st *state.State) (map[string]time.Time, error) {
+	var last map[string]time.Time
+	if err := st.Get("last-scheduled-snapshots", &last); err != nil && !errors.Is(err, state.ErrNoState) {
+		return nil, err
+	}
+	if last == nil {
+		last = make(map[string]time.Time)
+	}
+	return last, nil
+}
+
+// scheduledSnapshot returns a taskset saving the data of the given snap in
+// a new scheduled snapshot set.
+// Note that the state must be locked by the caller.
+func scheduledSnapshot(st *state.State, snapName string) (setID uint64, ts *state.TaskSet, err error) {
+	if _, err := snapstateCurrentInfo(st, snapName); err != nil {
+		return 0, nil, err
+	}
+	if err := snapstateCheckChangeConflictMany(st, []string{snapName}, ""); err != nil {
+		return 0, nil, err
+	}
+	deduplicate, encryption, err := snapshotsFormat(st)
+	if err != nil {
+		return 0, nil, err
+	}
+	if encryption == backend.KeySourcePassphrase {
+		// there is nobody around to provide the passphrase
+		return 0, nil, fmt.Errorf("snapshots are encrypted with a passphrase")
+	}
+	setID, err = newSnapshotSetID(st)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	desc := fmt.Sprintf("Save data of snap %q in scheduled snapshot set #%d", snapName, setID)
+	task := st.NewTask("save-snapshot", desc)
+	task.Set("snapshot-setup", &snapshotSetup{
+		SetID:       setID,
+		Snap:        snapName,
+		Scheduled:   true,
+		Deduplicate: deduplicate,
+		Encryption:  encryption,
+	})
+	return setID, state.NewTaskSet(task), nil
+}
+
+// pruneScheduledSnapshots forgets the scheduled snapshots that the
+// retention policy no longer keeps.
+// The state needs to be locked by the caller, but it is unlocked while the
+// snapshot files are listed.
+func (mgr *SnapshotManager) pruneScheduledSnapshots() error {
+	retention, err := scheduledSnapshotsRetention(mgr.state)
+	if err != nil {
+		return err
+	}
+	sets, err := prunedScheduledSnapshotSets(mgr.state, retention)
+	if err != nil {
+		return fmt.Errorf("internal error: cannot determine scheduled snapshots to prune: %v", err)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	conflicting, err := mgr.forgetSnapshotSets(sets)
+	if err != nil {
+		return fmt.Errorf("cannot prune scheduled snapshots: %v", err)
+	}
+	// what is left was either removed behind our back, or is busy
+	for setID := range sets {
+		if !conflicting[setID] {
+			if err := removeSnapshotState(mgr.state, setID); err != nil {
+				return fmt.Errorf("internal error: cannot remove state of snapshot set %d: %v", setID, err)
+			}
+		}
+	}
+	return nil
+}
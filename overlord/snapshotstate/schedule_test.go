@@ -0,0 +1,427 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func setCoreConfig(c *check.C, st *state.State, conf map[string]any) {
+	tr := config.NewTransaction(st)
+	for k, v := range conf {
+		c.Assert(tr.Set("core", k, v), check.IsNil)
+	}
+	tr.Commit()
+}
+
+func (snapshotSuite) TestScheduledSnapshotsRetention(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	retention, err := snapshotstate.ScheduledSnapshotsRetention(st)
+	c.Assert(err, check.IsNil)
+	c.Check(retention, check.DeepEquals, snapshotstate.NewScheduledRetention(7, 0, 0))
+
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.scheduled.keep-last":  3,
+		"snapshots.scheduled.keep-daily": "5",
+	})
+	retention, err = snapshotstate.ScheduledSnapshotsRetention(st)
+	c.Assert(err, check.IsNil)
+	c.Check(retention, check.DeepEquals, snapshotstate.NewScheduledRetention(3, 5, 0))
+
+	setCoreConfig(c, st, map[string]any{
+		"snapshots.scheduled.keep-weekly": "lots",
+	})
+	_, err = snapshotstate.ScheduledSnapshotsRetention(st)
+	c.Check(err, check.ErrorMatches, `snapshots.scheduled.keep-weekly is not a valid number: "lots"`)
+}
+
+func (snapshotSuite) TestPrunedScheduledSnapshotSets(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	// a Sunday
+	t0 := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+	for setID, t := range map[uint64]time.Time{
+		1: t0,
+		2: t0.Add(-time.Hour),
+		3: t0.AddDate(0, 0, -1),
+		4: t0.AddDate(0, 0, -2),
+		5: t0.AddDate(0, 0, -8),
+	} {
+		c.Assert(snapshotstate.SaveScheduled(st, setID, "foo", t), check.IsNil)
+	}
+	c.Assert(snapshotstate.SaveScheduled(st, 6, "bar", t0.AddDate(0, 0, -30)), check.IsNil)
+	c.Assert(snapshotstate.SaveExpiration(st, 7, t0), check.IsNil)
+
+	for _, tc := range []struct {
+		retention *snapshotstate.ScheduledRetention
+		pruned    map[uint64]bool
+	}{
+		{snapshotstate.NewScheduledRetention(1, 0, 0), map[uint64]bool{2: true, 3: true, 4: true, 5: true}},
+		{snapshotstate.NewScheduledRetention(3, 0, 0), map[uint64]bool{4: true, 5: true}},
+		{snapshotstate.NewScheduledRetention(1, 2, 0), map[uint64]bool{2: true, 4: true, 5: true}},
+		{snapshotstate.NewScheduledRetention(0, 0, 2), map[uint64]bool{2: true, 3: true, 4: true}},
+		{snapshotstate.NewScheduledRetention(0, 10, 0), map[uint64]bool{2: true}},
+		{snapshotstate.NewScheduledRetention(10, 0, 0), map[uint64]bool{}},
+	} {
+		pruned, err := snapshotstate.PrunedScheduledSnapshotSets(st, tc.retention)
+		c.Assert(err, check.IsNil)
+		c.Check(pruned, check.DeepEquals, tc.pruned, check.Commentf("%+v", tc.retention))
+	}
+}
+
+func (snapshotSuite) TestExpiredSnapshotSetsIgnoresScheduled(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	c.Assert(snapshotstate.SaveScheduled(st, 1, "foo", time.Now().AddDate(-1, 0, 0)), check.IsNil)
+
+	expired, err := snapshotstate.ExpiredSnapshotSets(st, time.Now())
+	c.Assert(err, check.IsNil)
+	c.Check(expired, check.HasLen, 0)
+}
+
+func (s *snapshotSuite) mockScheduledSnapshotManager(c *check.C) (*state.State, *snapshotstate.SnapshotManager) {
+	s.AddCleanup(snapshotstate.MockBackendIter(func(context.Context, func(*backend.Reader) error) error {
+		return nil
+	}))
+	s.AddCleanup(snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, snapName string) (*snap.Info, error) {
+		return &snap.Info{SideInfo: snap.SideInfo{RealName: snapName, Revision: snap.R(1)}}, nil
+	}))
+	s.AddCleanup(snapshotstate.MockSnapstateCheckChangeConflictMany(func(*state.State, []string, string) error {
+		return nil
+	}))
+
+	st := state.New(nil)
+	runner := state.NewTaskRunner(st)
+	mgr := snapshotstate.Manager(st, runner)
+
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{"snapshots.schedule.foo": "mon,10:00"})
+	st.Unlock()
+	return st, mgr
+}
+
+func (s *snapshotSuite) TestEnsureScheduledSnapshotFirstRun(c *check.C) {
+	st, mgr := s.mockScheduledSnapshotManager(c)
+
+	before := time.Now()
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	// nothing is taken right away
+	c.Check(st.Changes(), check.HasLen, 0)
+	// but the time the timer was first seen is remembered
+	var lastRuns map[string]time.Time
+	c.Assert(st.Get("last-scheduled-snapshots", &lastRuns), check.IsNil)
+	c.Check(lastRuns["foo"].Before(before), check.Equals, false)
+	next := snapshotstate.NextScheduledSnapshot(mgr, "foo")
+	c.Check(next.After(before), check.Equals, true)
+	c.Check(next.Weekday(), check.Equals, time.Monday)
+}
+
+func (s *snapshotSuite) TestEnsureScheduledSnapshotDue(c *check.C) {
+	st, mgr := s.mockScheduledSnapshotManager(c)
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+	snapshotstate.SetNextScheduledSnapshot(mgr, "foo", time.Now().Add(-time.Minute))
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Assert(st.Changes(), check.HasLen, 1)
+	chg := st.Changes()[0]
+	c.Check(chg.Kind(), check.Equals, "scheduled-snapshot")
+	c.Check(chg.Summary(), check.Equals, `Save scheduled snapshot #1 of snap "foo"`)
+	tasks := chg.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Kind(), check.Equals, "save-snapshot")
+	var snapshot map[string]any
+	c.Assert(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]any{
+		"set-id":    1.,
+		"snap":      "foo",
+		"current":   "unset",
+		"scheduled": true,
+	})
+
+	// the next one is scheduled on the next ensure
+	c.Check(snapshotstate.NextScheduledSnapshot(mgr, "foo").IsZero(), check.Equals, true)
+	st.Unlock()
+	c.Assert(mgr.Ensure(), check.IsNil)
+	st.Lock()
+	c.Check(snapshotstate.NextScheduledSnapshot(mgr, "foo").After(time.Now()), check.Equals, true)
+	c.Check(st.Changes(), check.HasLen, 1)
+}
+
+func (s *snapshotSuite) TestEnsureScheduledSnapshotConflict(c *check.C) {
+	st, mgr := s.mockScheduledSnapshotManager(c)
+	s.AddCleanup(snapshotstate.MockSnapstateCheckChangeConflictMany(func(*state.State, []string, string) error {
+		return &snapstate.ChangeConflictError{Snap: "foo", ChangeKind: "refresh"}
+	}))
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+	due := time.Now().Add(-time.Minute)
+	snapshotstate.SetNextScheduledSnapshot(mgr, "foo", due)
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+	// tried again on the next ensure
+	c.Check(snapshotstate.NextScheduledSnapshot(mgr, "foo").Equal(due), check.Equals, true)
+}
+
+func (s *snapshotSuite) TestEnsureScheduledSnapshotNotInstalled(c *check.C) {
+	st, mgr := s.mockScheduledSnapshotManager(c)
+	s.AddCleanup(snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, snapName string) (*snap.Info, error) {
+		return nil, &snap.NotInstalledError{Snap: snapName}
+	}))
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+	snapshotstate.SetNextScheduledSnapshot(mgr, "foo", time.Now().Add(-time.Minute))
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+	c.Check(snapshotstate.NextScheduledSnapshot(mgr, "foo").IsZero(), check.Equals, true)
+}
+
+func (s *snapshotSuite) TestEnsureScheduledSnapshotTimerChanged(c *check.C) {
+	st, mgr := s.mockScheduledSnapshotManager(c)
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(snapshotstate.NextScheduledSnapshot(mgr, "foo").Weekday(), check.Equals, time.Monday)
+
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{"snapshots.schedule.foo": "fri,10:00"})
+	st.Unlock()
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(snapshotstate.NextScheduledSnapshot(mgr, "foo").Weekday(), check.Equals, time.Friday)
+
+	// unsetting the timer forgets about the snap
+	st.Lock()
+	setCoreConfig(c, st, map[string]any{"snapshots.schedule.foo": nil})
+	st.Unlock()
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(snapshotstate.NextScheduledSnapshot(mgr, "foo").IsZero(), check.Equals, true)
+	st.Lock()
+	defer st.Unlock()
+	var lastRuns map[string]time.Time
+	c.Check(st.Get("last-scheduled-snapshots", &lastRuns), testutil.ErrorIs, state.ErrNoState)
+}
+
+func (s *snapshotSuite) TestEnsurePrunesScheduledSnapshots(c *check.C) {
+	var removed []string
+	s.AddCleanup(snapshotstate.MockOsRemove(func(fileName string) error {
+		removed = append(removed, fileName)
+		return nil
+	}))
+	s.AddCleanup(mockFakeSnapshot(c))
+
+	st := state.New(nil)
+	runner := state.NewTaskRunner(st)
+	mgr := snapshotstate.Manager(st, runner)
+
+	st.Lock()
+	now := time.Now()
+	setCoreConfig(c, st, map[string]any{"snapshots.scheduled.keep-last": 1})
+	// set 1 is on disk, set 3 is not anymore
+	c.Assert(snapshotstate.SaveScheduled(st, 1, "a-snap", now.Add(-2*time.Hour)), check.IsNil)
+	c.Assert(snapshotstate.SaveScheduled(st, 2, "a-snap", now.Add(-time.Hour)), check.IsNil)
+	c.Assert(snapshotstate.SaveScheduled(st, 3, "a-snap", now.Add(-3*time.Hour)), check.IsNil)
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	var snapshots map[uint64]any
+	c.Assert(st.Get("snapshots", &snapshots), check.IsNil)
+	c.Check(snapshots, check.HasLen, 1)
+	c.Check(snapshots[2], check.NotNil)
+	c.Assert(removed, check.HasLen, 1)
+	c.Check(removed[0], check.Matches, ".*/foo.zip")
+}
+
+func (s *snapshotSuite) TestEnsurePrunesScheduledSnapshotsAfterScheduledSave(c *check.C) {
+	s.AddCleanup(snapshotstate.MockOsRemove(func(string) error { return nil }))
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "foo.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+
+	st := state.New(nil)
+	iterCalls := 0
+	s.AddCleanup(snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		// the snapshot files are listed without the state lock
+		st.Lock()
+		st.Unlock()
+		iterCalls++
+		return f(&backend.Reader{
+			Snapshot: client.Snapshot{SetID: 1, Snap: "a-snap"},
+			File:     shotfile,
+		})
+	}))
+
+	runner := state.NewTaskRunner(st)
+	mgr := snapshotstate.Manager(st, runner)
+	c.Assert(mgr.StartUp(), check.IsNil)
+	defer mgr.Stop()
+
+	st.Lock()
+	now := time.Now()
+	setCoreConfig(c, st, map[string]any{"snapshots.scheduled.keep-last": 1})
+	c.Assert(snapshotstate.SaveScheduled(st, 1, "a-snap", now.Add(-2*time.Hour)), check.IsNil)
+	c.Assert(snapshotstate.SaveScheduled(st, 2, "a-snap", now.Add(-time.Hour)), check.IsNil)
+	st.Unlock()
+
+	// pruned on the first ensure
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(iterCalls, check.Equals, 1)
+
+	st.Lock()
+	c.Assert(snapshotstate.SaveScheduled(st, 1, "a-snap", now.Add(-2*time.Hour)), check.IsNil)
+	st.Unlock()
+
+	// but not on the next ones
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(iterCalls, check.Equals, 1)
+
+	// until a scheduled snapshot is saved
+	st.Lock()
+	chg := st.NewChange("scheduled-snapshot", "...")
+	chg.AddTask(st.NewTask("save-snapshot", "..."))
+	chg.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(iterCalls, check.Equals, 2)
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(iterCalls, check.Equals, 2)
+
+	// or enough time passed
+	snapshotstate.SetLastScheduledPruneTime(mgr, now.Add(-2*time.Hour))
+	st.Lock()
+	c.Assert(snapshotstate.SaveScheduled(st, 1, "a-snap", now.Add(-2*time.Hour)), check.IsNil)
+	st.Unlock()
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(iterCalls, check.Equals, 3)
+
+	st.Lock()
+	defer st.Unlock()
+	var snapshots map[uint64]any
+	c.Assert(st.Get("snapshots", &snapshots), check.IsNil)
+	c.Check(snapshots, check.HasLen, 1)
+	c.Check(snapshots[2], check.NotNil)
+}
+
+func (snapshotSuite) TestListSetsScheduledOrigin(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	c.Assert(snapshotstate.SaveExpiration(st, 1, time.Now().Add(time.Hour)), check.IsNil)
+	c.Assert(snapshotstate.SaveScheduled(st, 2, "foo", time.Now()), check.IsNil)
+
+	defer snapshotstate.MockBackendList(func(context.Context, uint64, []string) ([]client.SnapshotSet, error) {
+		return []client.SnapshotSet{
+			{ID: 1, Snapshots: []*client.Snapshot{{Snap: "foo", SetID: 1}}},
+			{ID: 2, Snapshots: []*client.Snapshot{{Snap: "foo", SetID: 2}}},
+			{ID: 3, Snapshots: []*client.Snapshot{{Snap: "foo", SetID: 3}}},
+		}, nil
+	})()
+
+	sets, err := snapshotstate.List(context.TODO(), st, 0, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 3)
+	c.Check(sets[0].Snapshots[0].Auto, check.Equals, true)
+	c.Check(sets[0].Snapshots[0].Origin, check.Equals, client.SnapshotOriginAutomatic)
+	c.Check(sets[1].Snapshots[0].Auto, check.Equals, false)
+	c.Check(sets[1].Snapshots[0].Origin, check.Equals, client.SnapshotOriginScheduled)
+	c.Check(sets[2].Snapshots[0].Auto, check.Equals, false)
+	c.Check(sets[2].Snapshots[0].Origin, check.Equals, "")
+}
+
+func (snapshotSuite) TestDoSaveScheduled(c *check.C) {
+	snapInfo := snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "a-snap",
+			Revision: snap.R(-1),
+		},
+		Version: "1.33",
+	}
+	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) {
+		return &snapInfo, nil
+	})()
+	defer osutil.MockMountInfo("")()
+	defer snapshotstate.MockBackendSave(func(context.Context, uint64, *snap.Info, map[string]any, []string, *snap.SnapshotOptions, *dirs.SnapDirOptions, *backend.SaveFlags) (*client.Snapshot, error) {
+		return nil, nil
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	task := st.NewTask("save-snapshot", "...")
+	task.Set("snapshot-setup", map[string]any{
+		"set-id":    42,
+		"snap":      "a-snap",
+		"scheduled": true,
+	})
+	st.Unlock()
+
+	before := time.Now()
+	c.Assert(snapshotstate.DoSave(task, &tomb.Tomb{}), check.IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	pruned, err := snapshotstate.PrunedScheduledSnapshotSets(st, snapshotstate.NewScheduledRetention(0, 0, 0))
+	c.Assert(err, check.IsNil)
+	c.Check(pruned, check.DeepEquals, map[uint64]bool{42: true})
+	var snapshots map[uint64]map[string]any
+	c.Assert(st.Get("snapshots", &snapshots), check.IsNil)
+	c.Check(snapshots[42]["origin"], check.Equals, "scheduled")
+	c.Check(snapshots[42]["snap"], check.Equals, "a-snap")
+	t, err := time.Parse(time.RFC3339Nano, snapshots[42]["time"].(string))
+	c.Assert(err, check.IsNil)
+	c.Check(t.Before(before), check.Equals, false)
+}
@@ -22,8 +22,11 @@ package snapshotstate
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -37,7 +40,9 @@ import (
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/swfeats"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timeutil"
 )
 
 var (
@@ -50,23 +55,51 @@ var (
 	backendImport        = backend.Import
 	backendRestore       = (*backend.Reader).Restore // TODO: look into using an interface instead
 	backendCheck         = (*backend.Reader).Check
+	backendUnlock        = (*backend.Reader).Unlock
 	backendRevert        = (*backend.RestoreState).Revert // ditto
 	backendCleanup       = (*backend.RestoreState).Cleanup
 
 	backendCleanupAbandonedImports = backend.CleanupAbandonedImports
+	backendGarbageCollectChunks    = backend.GarbageCollectChunks
 
 	autoExpirationInterval = time.Hour * 24 // interval between forgetExpiredSnapshots runs as part of Ensure()
+	scheduledPruneInterval = time.Hour      // longest interval between pruneScheduledSnapshots runs as part of Ensure()
 
 	getSnapDirOpts = snapstate.GetSnapDirOpts
 
 	backendMapSnapDataDirToSnapVar = backend.MapSnapDataDirToSnapVar
 )
 
+var (
+	scheduledSnapshotChangeKind = swfeats.RegisterChangeKind("scheduled-snapshot")
+	pushSnapshotChangeKind      = swfeats.RegisterChangeKind("push-snapshot")
+)
+
+func init() {
+	swfeats.RegisterEnsure("SnapshotManager", "ensureScheduledSnapshots")
+	swfeats.RegisterEnsure("SnapshotManager", "ensureScheduledSnapshotsPruned")
+	swfeats.RegisterEnsure("SnapshotManager", "ensureRemotePushes")
+}
+
 // SnapshotManager takes snapshots of active snaps
 type SnapshotManager struct {
 	state *state.State
 
 	lastForgetExpiredSnapshotTime time.Time
+
+	// lastScheduledPruneTime is when scheduled snapshots were last
+	// pruned, and scheduledPruneNeeded is set when a scheduled snapshot
+	// was saved since
+	lastScheduledPruneTime time.Time
+	scheduledPruneNeeded   bool
+
+	// scheduleTimers and nextScheduled keep, for each snap with a
+	// snapshot timer, the timer last seen and when its next scheduled
+	// snapshot is due
+	scheduleTimers map[string]string
+	nextScheduled  map[string]time.Time
+
+	changeCallbackID int
 }
 
 // Manager returns a new SnapshotManager
@@ -78,9 +111,12 @@ func Manager(st *state.State, runner *state.TaskRunner) *SnapshotManager {
 	runner.AddHandler("check-snapshot", doCheck, nil)
 	runner.AddHandler("restore-snapshot", doRestore, undoRestore)
 	runner.AddHandler("cleanup-after-restore", doCleanupAfterRestore, nil)
+	runner.AddHandler("push-snapshot", doPush, nil)
 
 	manager := &SnapshotManager{
-		state: st,
+		state:          st,
+		scheduleTimers: make(map[string]string),
+		nextScheduled:  make(map[string]time.Time),
 	}
 	snapstate.RegisterAffectedSnapsByAttr("snapshot-setup", manager.affectedSnaps)
 
@@ -89,6 +125,16 @@ func Manager(st *state.State, runner *state.TaskRunner) *SnapshotManager {
 
 // Ensure is part of the overlord.StateManager interface.
 func (mgr *SnapshotManager) Ensure() error {
+	if err := mgr.ensureScheduledSnapshots(); err != nil {
+		logger.Noticef("Cannot handle scheduled snapshots: %v", err)
+	}
+	if err := mgr.ensureScheduledSnapshotsPruned(); err != nil {
+		logger.Noticef("Cannot prune scheduled snapshots: %v", err)
+	}
+	if err := mgr.ensureRemotePushes(); err != nil {
+		logger.Noticef("Cannot push snapshots to the remote target: %v", err)
+	}
+
 	// process expired snapshots once a day.
 	if time.Now().After(mgr.lastForgetExpiredSnapshotTime.Add(autoExpirationInterval)) {
 		return mgr.forgetExpiredSnapshots()
@@ -97,13 +143,222 @@ func (mgr *SnapshotManager) Ensure() error {
 	return nil
 }
 
+// ensureScheduledSnapshots takes the snapshots that are due as per the
+// snapshot timers of the snaps.
+func (mgr *SnapshotManager) ensureScheduledSnapshots() error {
+	st := mgr.state
+	st.Lock()
+	defer st.Unlock()
+
+	timers, err := snapshotSchedules(st)
+	if err != nil {
+		return err
+	}
+	lastRuns, err := lastScheduledSnapshots(st)
+	if err != nil {
+		return err
+	}
+
+	// forget about snaps whose timer was unset
+	for snapName := range mgr.scheduleTimers {
+		if timers[snapName] == "" {
+			delete(mgr.scheduleTimers, snapName)
+			delete(mgr.nextScheduled, snapName)
+		}
+	}
+	lastRunsChanged := false
+	for snapName := range lastRuns {
+		if timers[snapName] == "" {
+			delete(lastRuns, snapName)
+			lastRunsChanged = true
+		}
+	}
+
+	snapNames := make([]string, 0, len(timers))
+	for snapName, timer := range timers {
+		if timer != "" {
+			snapNames = append(snapNames, snapName)
+		}
+	}
+	sort.Strings(snapNames)
+	if len(snapNames) == 0 {
+		if lastRunsChanged {
+			st.Set("last-scheduled-snapshots", nil)
+		}
+		return nil
+	}
+
+	logger.Trace("ensure", "manager", "SnapshotManager", "func", "ensureScheduledSnapshots")
+
+	now := time.Now()
+	for _, snapName := range snapNames {
+		timer := timers[snapName]
+		if mgr.scheduleTimers[snapName] != timer {
+			// the timer has changed
+			delete(mgr.nextScheduled, snapName)
+			mgr.scheduleTimers[snapName] = timer
+		}
+
+		next, ok := mgr.nextScheduled[snapName]
+		if !ok {
+			schedule, err := timeutil.ParseSchedule(timer)
+			if err != nil {
+				logger.Noticef("Cannot parse snapshot schedule of %q: %v", snapName, err)
+				continue
+			}
+			last := lastRuns[snapName]
+			if last.IsZero() {
+				// the first snapshot is taken at the first window
+				// after the timer was set
+				last = now
+				lastRuns[snapName] = now
+				lastRunsChanged = true
+			}
+			next = now.Add(timeutil.Next(schedule, last, scheduledSnapshotMaxInterval))
+			mgr.nextScheduled[snapName] = next
+			logger.Debugf("Next scheduled snapshot of %q at %s.", snapName, next.Format(time.RFC3339))
+		}
+		if next.After(now) {
+			continue
+		}
+
+		setID, ts, err := scheduledSnapshot(st, snapName)
+		var conflictErr *snapstate.ChangeConflictError
+		var notInstalledErr *snap.NotInstalledError
+		switch {
+		case errors.As(err, &conflictErr):
+			// try again on the next ensure
+			continue
+		case errors.As(err, &notInstalledErr):
+			logger.Debugf("Skipping scheduled snapshot of %q: %v", snapName, err)
+		case err != nil:
+			logger.Noticef("Cannot take scheduled snapshot of %q: %v", snapName, err)
+		default:
+			msg := fmt.Sprintf("Save scheduled snapshot #%d of snap %q", setID, snapName)
+			chg := st.NewChange(scheduledSnapshotChangeKind, msg)
+			chg.AddAll(ts)
+			chg.Set("snap-names", []string{snapName})
+		}
+		lastRuns[snapName] = now
+		lastRunsChanged = true
+		delete(mgr.nextScheduled, snapName)
+	}
+	if lastRunsChanged {
+		st.Set("last-scheduled-snapshots", lastRuns)
+	}
+
+	return nil
+}
+
+// ensureScheduledSnapshotsPruned prunes the scheduled snapshots that the
+// retention policy no longer keeps, once a scheduled snapshot was saved or
+// at least every scheduledPruneInterval, as the retention policy may have
+// changed in the meantime.
+func (mgr *SnapshotManager) ensureScheduledSnapshotsPruned() error {
+	st := mgr.state
+	st.Lock()
+	defer st.Unlock()
+
+	now := time.Now()
+	if !mgr.scheduledPruneNeeded && now.Before(mgr.lastScheduledPruneTime.Add(scheduledPruneInterval)) {
+		return nil
+	}
+	mgr.scheduledPruneNeeded = false
+	mgr.lastScheduledPruneTime = now
+
+	logger.Trace("ensure", "manager", "SnapshotManager", "func", "ensureScheduledSnapshotsPruned")
+
+	return mgr.pruneScheduledSnapshots()
+}
+
+// ensureRemotePushes creates changes pushing the snapshot sets that are
+// complete and waiting for it to the remote target.
+func (mgr *SnapshotManager) ensureRemotePushes() error {
+	st := mgr.state
+	st.Lock()
+	defer st.Unlock()
+
+	pending, snapshots, err := pendingPushes(st)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	target, err := remoteTarget(st)
+	if errors.Is(err, errNoDeviceIdentity) {
+		// the sets are pushed once the device is registered
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		// the sets are pushed if a target is configured again
+		return nil
+	}
+
+	logger.Trace("ensure", "manager", "SnapshotManager", "func", "ensureRemotePushes")
+
+	now := time.Now()
+	for _, setID := range pending {
+		snapshot := snapshots[setID]
+		if snapshot.PushAttempts >= remotePushMaxAttempts {
+			st.Warnf("cannot push snapshot set #%d to %s: giving up after %d attempts", setID, target, snapshot.PushAttempts)
+			if err := markPushAbandoned(st, setID); err != nil {
+				return err
+			}
+			continue
+		}
+		if now.Before(nextPushAttempt(snapshot)) {
+			continue
+		}
+		// wait for the set to be complete, and leave it alone while it
+		// is being forgotten or pushed already
+		if err := checkSnapshotConflict(st, setID, "save-snapshot", "forget-snapshot", "push-snapshot"); err != nil {
+			continue
+		}
+		if err := markPushAttempted(st, setID, now); err != nil {
+			return err
+		}
+
+		chg := st.NewChange(pushSnapshotChangeKind, fmt.Sprintf("Push snapshot set #%d to %s", setID, target))
+		chg.AddTask(newPushTask(st, setID, target))
+	}
+
+	return nil
+}
+
 func (mgr *SnapshotManager) StartUp() error {
 	if _, err := backendCleanupAbandonedImports(); err != nil {
 		logger.Noticef("cannot cleanup incomplete imports: %v", err)
 	}
+
+	mgr.state.Lock()
+	defer mgr.state.Unlock()
+	mgr.changeCallbackID = mgr.state.AddChangeStatusChangedHandler(mgr.changeStatusChanged)
+
 	return nil
 }
 
+// Stop implements StateStopper. It unregisters the change callback handler
+// from the state.
+func (mgr *SnapshotManager) Stop() {
+	mgr.state.Lock()
+	defer mgr.state.Unlock()
+	mgr.state.RemoveChangeStatusChangedHandler(mgr.changeCallbackID)
+}
+
+// changeStatusChanged forgets the passphrases of the snapshot sets of a
+// change once it is ready, and has the scheduled snapshots pruned once a
+// scheduled snapshot change is.
+func (mgr *SnapshotManager) changeStatusChanged(chg *state.Change, old, new state.Status) {
+	forgetPassphrases(chg, old, new)
+	if chg.Kind() == scheduledSnapshotChangeKind && !old.Ready() && new.Ready() {
+		mgr.scheduledPruneNeeded = true
+	}
+}
+
 func (mgr *SnapshotManager) forgetExpiredSnapshots() error {
 	mgr.state.Lock()
 	defer mgr.state.Unlock()
@@ -117,44 +372,100 @@ func (mgr *SnapshotManager) forgetExpiredSnapshots() error {
 		return nil
 	}
 
-	err = backendIter(context.TODO(), func(r *backend.Reader) error {
-		// forget needs to conflict with check and restore
-		if err := checkSnapshotConflict(mgr.state, r.SetID, "export-snapshot",
-			"check-snapshot", "restore-snapshot"); err != nil {
+	if _, err := mgr.forgetSnapshotSets(sets); err != nil {
+		return fmt.Errorf("cannot process expired snapshots: %v", err)
+	}
+
+	// only reset time if there are no sets left because of conflicts
+	if len(sets) == 0 {
+		mgr.lastForgetExpiredSnapshotTime = time.Now()
+	}
+
+	garbageCollectChunks(context.TODO())
+
+	return nil
+}
+
+// forgetSnapshotSets removes the given snapshot sets from the state and the
+// disk, dropping them from sets as it goes. Sets with a conflicting operation
+// in progress are left alone and returned, to be retried later.
+// The state needs to be locked by the caller, but it is unlocked while the
+// snapshot files are listed.
+func (mgr *SnapshotManager) forgetSnapshotSets(sets map[uint64]bool) (conflicting map[uint64]bool, err error) {
+	files, err := mgr.snapshotSetFiles(sets)
+	if err != nil {
+		return nil, err
+	}
+	setIDs := make([]uint64, 0, len(files))
+	for setID := range files {
+		setIDs = append(setIDs, setID)
+	}
+	sort.Slice(setIDs, func(i, j int) bool { return setIDs[i] < setIDs[j] })
+
+	conflicting = make(map[uint64]bool)
+	for _, setID := range setIDs {
+		// forget needs to conflict with check, restore and push
+		if err := checkSnapshotConflict(mgr.state, setID, "export-snapshot",
+			"check-snapshot", "restore-snapshot", "push-snapshot"); err != nil {
 			// there is a conflict, do nothing and we will retry this set on next Ensure().
-			return nil
+			conflicting[setID] = true
+			continue
 		}
-		if sets[r.SetID] {
-			delete(sets, r.SetID)
-			// remove from state first: in case removeSnapshotState succeeds but osRemove fails we will never attempt
-			// to automatically remove this snapshot again and will leave it on the disk (so the user can still try to remove it manually);
-			// this is better than the other way around where a failing osRemove would be retried forever because snapshot would never
-			// leave the state.
-			if err := removeSnapshotState(mgr.state, r.SetID); err != nil {
-				return fmt.Errorf("internal error: cannot remove state of snapshot set %d: %v", r.SetID, err)
-			}
-			if err := osRemove(r.Name()); err != nil {
-				return fmt.Errorf("cannot remove snapshot file %q: %v", r.Name(), err)
+		delete(sets, setID)
+		// remove from state first: in case removeSnapshotState succeeds but osRemove fails we will never attempt
+		// to automatically remove this snapshot again and will leave it on the disk (so the user can still try to remove it manually);
+		// this is better than the other way around where a failing osRemove would be retried forever because snapshot would never
+		// leave the state.
+		if err := removeSnapshotState(mgr.state, setID); err != nil {
+			return conflicting, fmt.Errorf("internal error: cannot remove state of snapshot set %d: %v", setID, err)
+		}
+		for _, name := range files[setID] {
+			// the file may have been forgotten while the state was unlocked
+			if err := osRemove(name); err != nil && !os.IsNotExist(err) {
+				return conflicting, fmt.Errorf("cannot remove snapshot file %q: %v", name, err)
 			}
 		}
+	}
+	return conflicting, nil
+}
+
+// snapshotSetFiles returns the names of the files of the given snapshot sets
+// found on disk. The state needs to be locked by the caller, it is unlocked
+// while the snapshot files are read.
+func (mgr *SnapshotManager) snapshotSetFiles(sets map[uint64]bool) (map[uint64][]string, error) {
+	mgr.state.Unlock()
+	defer mgr.state.Lock()
+
+	files := make(map[uint64][]string)
+	err := backendIter(context.TODO(), func(r *backend.Reader) error {
+		if sets[r.SetID] {
+			files[r.SetID] = append(files[r.SetID], r.Name())
+		}
 		return nil
 	})
-
 	if err != nil {
-		return fmt.Errorf("cannot process expired snapshots: %v", err)
+		return nil, err
 	}
+	return files, nil
+}
 
-	// only reset time if there are no sets left because of conflicts
-	if len(sets) == 0 {
-		mgr.lastForgetExpiredSnapshotTime = time.Now()
+// garbageCollectChunks drops the chunks of deduplicated snapshots that are
+// no longer referenced by any snapshot. Failing to do so is not fatal, the
+// chunks will be collected on a later attempt.
+func garbageCollectChunks(ctx context.Context) {
+	removed, err := backendGarbageCollectChunks(ctx)
+	if err != nil {
+		logger.Noticef("cannot remove unused snapshot chunks: %v", err)
+		return
+	}
+	if removed > 0 {
+		logger.Debugf("Removed %d unused snapshot chunks.", removed)
 	}
-
-	return nil
 }
 
 func (SnapshotManager) affectedSnaps(t *state.Task) ([]string, error) {
-	if k := t.Kind(); k == "check-snapshot" || k == "forget-snapshot" {
-		// check and forget don't affect snaps
+	if k := t.Kind(); k == "check-snapshot" || k == "forget-snapshot" || k == "push-snapshot" {
+		// check, forget and push don't affect snaps
 		// (this could also be written k != save && k != restore, but it's safer this way around)
 		return nil, nil
 	}
@@ -174,6 +485,15 @@ type snapshotSetup struct {
 	Filename string                `json:"filename,omitempty"`
 	Current  snap.Revision         `json:"current"`
 	Auto     bool                  `json:"auto,omitempty"`
+	// Scheduled is set if the snapshot is taken as per the snap's
+	// snapshot timer
+	Scheduled bool `json:"scheduled,omitempty"`
+	// Deduplicate is set if the snapshot is to be saved as chunks in the
+	// shared chunk store
+	Deduplicate bool `json:"deduplicate,omitempty"`
+	// Encryption is the kind of key source to encrypt the snapshot with,
+	// if any
+	Encryption string `json:"encryption,omitempty"`
 }
 
 func filename(setID uint64, si *snap.Info) string {
@@ -219,6 +539,24 @@ func prepareSave(task *state.Task) (snapshot *snapshotSetup, cur *snap.Info, cfg
 			return nil, nil, nil, err
 		}
 	}
+	if snapshot.Scheduled {
+		if err := saveScheduled(st, snapshot.SetID, snapshot.Snap, time.Now()); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if !snapshot.Auto {
+		// automatic snapshots are only kept around for a while, there
+		// is no point in pushing them
+		kind, err := remoteTargetKind(st)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if kind != "" {
+			if err := markPushPending(st, snapshot.SetID); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
 
 	return snapshot, cur, cfg, nil
 }
@@ -241,7 +579,15 @@ func doSave(task *state.Task, tomb *tomb.Tomb) error {
 	if err := snapshot.excludeMountPoints(cur, opts); err != nil {
 		logger.Noticef("cannot exclude mount points: %v", err)
 	}
-	_, err = backendSave(tomb.Context(nil), snapshot.SetID, cur, cfg, snapshot.Users, snapshot.Options, opts)
+	flags := &backend.SaveFlags{Deduplicate: snapshot.Deduplicate}
+	if snapshot.Encryption != "" {
+		st.Lock()
+		flags.KeySource, err = keySource(st, snapshot.Encryption, snapshot.SetID)
+		st.Unlock()
+	}
+	if err == nil {
+		_, err = backendSave(tomb.Context(nil), snapshot.SetID, cur, cfg, snapshot.Users, snapshot.Options, opts, flags)
+	}
 	if err != nil {
 		st.Lock()
 		defer st.Unlock()
@@ -371,6 +717,10 @@ func doRestore(task *state.Task, tomb *tomb.Tomb) error {
 	defer reader.Close()
 
 	st := task.State()
+	if err := unlockSnapshot(st, reader, snapshot.SetID); err != nil {
+		return err
+	}
+
 	logf := func(format string, args ...any) {
 		st.Lock()
 		defer st.Unlock()
@@ -500,10 +850,58 @@ func doCheck(task *state.Task, tomb *tomb.Tomb) error {
 	}
 	defer reader.Close()
 
+	if err := unlockSnapshot(st, reader, snapshot.SetID); err != nil {
+		return err
+	}
+
 	return backendCheck(reader, tomb.Context(nil), snapshot.Users)
 }
 
-func doForget(task *state.Task, _ *tomb.Tomb) error {
+func doPush(task *state.Task, tomb *tomb.Tomb) error {
+	var snapshot snapshotSetup
+
+	st := task.State()
+	st.Lock()
+	if err := task.Get("snapshot-setup", &snapshot); err != nil {
+		st.Unlock()
+		return taskGetErrMsg(task, err, "snapshot")
+	}
+	target, err := remoteTarget(st)
+	if err == nil && target == nil {
+		err = ErrNoRemoteTarget
+	}
+	var export *backend.SnapshotExport
+	if err == nil {
+		// the files are opened with the state locked so that they
+		// cannot be forgotten meanwhile
+		export, err = backendNewSnapshotExport(tomb.Context(nil), snapshot.SetID)
+	}
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+	defer export.Close()
+
+	if err := export.Init(); err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(export.StreamTo(pw))
+	}()
+	err = target.Push(tomb.Context(nil), snapshot.SetID, pr, export.Size())
+	// unblocks the writer if pushing stopped early
+	pr.Close()
+	if err != nil {
+		return err
+	}
+
+	st.Lock()
+	defer st.Unlock()
+	return markPushed(st, snapshot.SetID, time.Now())
+}
+
+func doForget(task *state.Task, tomb *tomb.Tomb) error {
 	// note this is also undoSave
 	st := task.State()
 	st.Lock()
@@ -525,7 +923,17 @@ func doForget(task *state.Task, _ *tomb.Tomb) error {
 		return fmt.Errorf("internal error: cannot remove state of snapshot set %d: %v", snapshot.SetID, err)
 	}
 
-	return osRemove(snapshot.Filename)
+	if err := osRemove(snapshot.Filename); err != nil {
+		return err
+	}
+
+	// collecting chunks needs to go through all snapshots, don't hold
+	// the state lock while doing so
+	st.Unlock()
+	defer st.Lock()
+	garbageCollectChunks(tomb.Context(nil))
+
+	return nil
 }
 
 func delayedCrossMgrInit() {
@@ -535,7 +943,7 @@ func delayedCrossMgrInit() {
 	snapstate.EstimateSnapshotSize = EstimateSnapshotSize
 }
 
-func MockBackendSave(f func(context.Context, uint64, *snap.Info, map[string]any, []string, *snap.SnapshotOptions, *dirs.SnapDirOptions) (*client.Snapshot, error)) (restore func()) {
+func MockBackendSave(f func(context.Context, uint64, *snap.Info, map[string]any, []string, *snap.SnapshotOptions, *dirs.SnapDirOptions, *backend.SaveFlags) (*client.Snapshot, error)) (restore func()) {
 	old := backendSave
 	backendSave = f
 	return func() {
@@ -37,6 +37,8 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	fdeBackend "github.com/snapcore/snapd/overlord/fdestate/backend"
 	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
@@ -57,6 +59,7 @@ func (snapshotSuite) TestManager(c *check.C) {
 		"check-snapshot",
 		"cleanup-after-restore",
 		"forget-snapshot",
+		"push-snapshot",
 		"restore-snapshot",
 		"save-snapshot",
 	})
@@ -285,7 +288,7 @@ func (snapshotSuite) TestDoSave(c *check.C) {
 
 	expectedOptions := &snap.SnapshotOptions{}
 	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string,
-		options *snap.SnapshotOptions, _ *dirs.SnapDirOptions) (*client.Snapshot, error) {
+		options *snap.SnapshotOptions, _ *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		c.Check(id, check.Equals, uint64(42))
 		c.Check(si, check.DeepEquals, &snapInfo)
 		c.Check(cfg, check.DeepEquals, map[string]any{"hello": "there"})
@@ -563,7 +566,7 @@ func (snapshotSuite) TestDoSaveMountExcludes(c *check.C) {
 
 	var gotOptions *snap.SnapshotOptions
 	defer snapshotstate.MockBackendSave(func(_ context.Context, _ uint64, _ *snap.Info, _ map[string]any, _ []string,
-		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions) (*client.Snapshot, error) {
+		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		gotOptions = opts
 		return nil, nil
 	})()
@@ -618,7 +621,7 @@ func (snapshotSuite) TestDoSaveMountExcludesPreservesSetupOptions(c *check.C) {
 
 	var gotOptions *snap.SnapshotOptions
 	defer snapshotstate.MockBackendSave(func(_ context.Context, _ uint64, _ *snap.Info, _ map[string]any, _ []string,
-		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions) (*client.Snapshot, error) {
+		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		gotOptions = opts
 		return nil, nil
 	})()
@@ -660,7 +663,7 @@ func (snapshotSuite) TestDoSaveMountExcludesLoadMountInfoError(c *check.C) {
 	setupOptions := &snap.SnapshotOptions{Exclude: []string{"$SNAP_DATA/logs"}}
 	var gotOptions *snap.SnapshotOptions
 	defer snapshotstate.MockBackendSave(func(_ context.Context, _ uint64, _ *snap.Info, _ map[string]any, _ []string,
-		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions) (*client.Snapshot, error) {
+		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		gotOptions = opts
 		return nil, nil
 	})()
@@ -705,7 +708,7 @@ func (snapshotSuite) TestDoSaveNoMounts(c *check.C) {
 	setupOptions := &snap.SnapshotOptions{Exclude: []string{"$SNAP_DATA/cache"}}
 	var gotOptions *snap.SnapshotOptions
 	defer snapshotstate.MockBackendSave(func(_ context.Context, _ uint64, _ *snap.Info, _ map[string]any, _ []string,
-		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions) (*client.Snapshot, error) {
+		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		gotOptions = opts
 		return nil, nil
 	})()
@@ -752,7 +755,7 @@ func (snapshotSuite) TestDoSaveMountExcludesMapDirToVarError(c *check.C) {
 	setupOptions := &snap.SnapshotOptions{Exclude: []string{"$SNAP_DATA/logs"}}
 	var gotOptions *snap.SnapshotOptions
 	defer snapshotstate.MockBackendSave(func(_ context.Context, _ uint64, _ *snap.Info, _ map[string]any, _ []string,
-		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions) (*client.Snapshot, error) {
+		opts *snap.SnapshotOptions, _ *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		gotOptions = opts
 		return nil, nil
 	})()
@@ -798,7 +801,7 @@ func (snapshotSuite) TestDoSaveGetsSnapDirOpts(c *check.C) {
 	defer osutil.MockMountInfo("")()
 
 	var checkOpts bool
-	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, opts *dirs.SnapDirOptions) (*client.Snapshot, error) {
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, opts *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		c.Check(opts.HiddenSnapDataDir, check.Equals, true)
 		checkOpts = true
 		return nil, nil
@@ -817,12 +820,150 @@ func (snapshotSuite) TestDoSaveGetsSnapDirOpts(c *check.C) {
 	c.Check(checkOpts, check.Equals, true)
 }
 
+func (snapshotSuite) TestDoSaveDeduplicated(c *check.C) {
+	snapInfo := snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "a-snap",
+			Revision: snap.R(-1),
+		},
+		Version: "1.33",
+	}
+	defer snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, snapname string) (*snap.Info, error) {
+		return &snapInfo, nil
+	})()
+	defer osutil.MockMountInfo("")()
+
+	for _, deduplicate := range []bool{true, false} {
+		var called bool
+		restore := snapshotstate.MockBackendSave(func(_ context.Context, _ uint64, _ *snap.Info, _ map[string]any, _ []string, _ *snap.SnapshotOptions, _ *dirs.SnapDirOptions, flags *backend.SaveFlags) (*client.Snapshot, error) {
+			c.Check(flags, check.DeepEquals, &backend.SaveFlags{Deduplicate: deduplicate})
+			called = true
+			return nil, nil
+		})
+
+		st := state.New(nil)
+		st.Lock()
+		task := st.NewTask("save-snapshot", "...")
+		task.Set("snapshot-setup", map[string]any{
+			"snap":        "a-snap",
+			"deduplicate": deduplicate,
+		})
+		st.Unlock()
+
+		err := snapshotstate.DoSave(task, &tomb.Tomb{})
+		restore()
+		c.Assert(err, check.IsNil)
+		c.Check(called, check.Equals, true)
+	}
+}
+
+type fakeEncryptedContainer struct {
+	devPath string
+}
+
+func (fakeEncryptedContainer) ContainerRole() string         { return "system-save" }
+func (d fakeEncryptedContainer) DevPath() string             { return d.devPath }
+func (fakeEncryptedContainer) LegacyKeys() map[string]string { return nil }
+
+func (snapshotSuite) TestDoSaveEncrypted(c *check.C) {
+	snapInfo := snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "a-snap",
+			Revision: snap.R(-1),
+		},
+		Version: "1.33",
+	}
+	defer snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, snapname string) (*snap.Info, error) {
+		return &snapInfo, nil
+	})()
+	defer osutil.MockMountInfo("")()
+	defer snapshotstate.MockFdestateGetEncryptedContainers(func(*state.State) ([]fdeBackend.EncryptedContainer, error) {
+		return []fdeBackend.EncryptedContainer{
+			fakeEncryptedContainer{devPath: "/dev/disk/by-uuid/data"},
+			fakeEncryptedContainer{devPath: "/dev/disk/by-uuid/save"},
+		}, nil
+	})()
+
+	for _, encryption := range []string{"key-file", "tpm", "passphrase"} {
+		var keySource backend.KeySource
+		restore := snapshotstate.MockBackendSave(func(_ context.Context, _ uint64, _ *snap.Info, _ map[string]any, _ []string, _ *snap.SnapshotOptions, _ *dirs.SnapDirOptions, flags *backend.SaveFlags) (*client.Snapshot, error) {
+			c.Check(flags.Deduplicate, check.Equals, false)
+			keySource = flags.KeySource
+			return nil, nil
+		})
+
+		st := state.New(nil)
+		st.Lock()
+		tr := config.NewTransaction(st)
+		tr.Set("core", "snapshots.encryption-key-file", "/root/snapshots.key")
+		tr.Commit()
+		snapshotstate.SetPassphrase(st, 42, "sekrit")
+		task := st.NewTask("save-snapshot", "...")
+		task.Set("snapshot-setup", map[string]any{
+			"set-id":     42,
+			"snap":       "a-snap",
+			"encryption": encryption,
+		})
+		st.Unlock()
+
+		err := snapshotstate.DoSave(task, &tomb.Tomb{})
+		restore()
+		c.Assert(err, check.IsNil)
+		c.Assert(keySource, check.NotNil)
+		c.Check(keySource.Kind(), check.Equals, encryption)
+	}
+}
+
+func (snapshotSuite) TestDoSaveEncryptedNoKey(c *check.C) {
+	snapInfo := snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "a-snap",
+			Revision: snap.R(-1),
+		},
+		Version: "1.33",
+	}
+	defer snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, snapname string) (*snap.Info, error) {
+		return &snapInfo, nil
+	})()
+	defer osutil.MockMountInfo("")()
+	defer snapshotstate.MockBackendSave(func(context.Context, uint64, *snap.Info, map[string]any, []string, *snap.SnapshotOptions, *dirs.SnapDirOptions, *backend.SaveFlags) (*client.Snapshot, error) {
+		c.Fatal("unexpected call to backend.Save")
+		return nil, nil
+	})()
+
+	for encryption, expectedErr := range map[string]string{
+		"key-file":   `cannot find snapshot key: snapshots.encryption-key-file is not set`,
+		"passphrase": `snapshot set #42 is encrypted with a passphrase, but none was provided`,
+	} {
+		st := state.New(nil)
+		st.Lock()
+		task := st.NewTask("save-snapshot", "...")
+		task.Set("snapshot-setup", map[string]any{
+			"set-id":     42,
+			"snap":       "a-snap",
+			"auto":       true,
+			"encryption": encryption,
+		})
+		st.Unlock()
+
+		err := snapshotstate.DoSave(task, &tomb.Tomb{})
+		c.Check(err, check.ErrorMatches, expectedErr)
+
+		// the expiration of the set is forgotten
+		st.Lock()
+		var snapshots map[uint64]any
+		c.Check(st.Get("snapshots", &snapshots), check.IsNil)
+		c.Check(snapshots, check.HasLen, 0)
+		st.Unlock()
+	}
+}
+
 func (snapshotSuite) TestDoSaveFailsWithNoSnap(c *check.C) {
 	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) {
 		return nil, errors.New("bzzt")
 	})()
 	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) { return nil, nil })()
-	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions) (*client.Snapshot, error) {
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		return nil, nil
 	})()
 
@@ -849,7 +990,7 @@ func (snapshotSuite) TestDoSaveFailsWithNoSnapshot(c *check.C) {
 	}
 	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) { return &snapInfo, nil })()
 	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) { return nil, nil })()
-	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions) (*client.Snapshot, error) {
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		return nil, nil
 	})()
 
@@ -874,7 +1015,7 @@ func (snapshotSuite) TestDoSaveFailsBackendError(c *check.C) {
 	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) { return &snapInfo, nil })()
 	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) { return nil, nil })()
 	defer osutil.MockMountInfo("")()
-	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions) (*client.Snapshot, error) {
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		return nil, errors.New("bzzt")
 	})()
 
@@ -903,7 +1044,7 @@ func (snapshotSuite) TestDoSaveFailsConfigError(c *check.C) {
 	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) {
 		return nil, errors.New("bzzt")
 	})()
-	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions) (*client.Snapshot, error) {
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		return nil, nil
 	})()
 
@@ -934,7 +1075,7 @@ func (snapshotSuite) TestDoSaveFailsBadConfig(c *check.C) {
 		buf := json.RawMessage(`"hello-there"`)
 		return &buf, nil
 	})()
-	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions) (*client.Snapshot, error) {
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		return nil, nil
 	})()
 
@@ -968,7 +1109,7 @@ func (snapshotSuite) TestDoSaveFailureRemovesStateEntry(c *check.C) {
 		return nil, nil
 	})()
 	defer osutil.MockMountInfo("")()
-	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions) (*client.Snapshot, error) {
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]any, usernames []string, _ *snap.SnapshotOptions, options *dirs.SnapDirOptions, _ *backend.SaveFlags) (*client.Snapshot, error) {
 		var expirations map[uint64]any
 		st.Lock()
 		defer st.Unlock()
@@ -1052,6 +1193,10 @@ func (rs *readerSuite) SetUpTest(c *check.C) {
 		snapshotstate.MockBackendCleanup(func(*backend.RestoreState) {
 			rs.calls = append(rs.calls, "cleanup")
 		}),
+		snapshotstate.MockBackendGarbageCollectChunks(func(context.Context) (int, error) {
+			rs.calls = append(rs.calls, "gc")
+			return 0, nil
+		}),
 	}
 }
 
@@ -1300,6 +1445,61 @@ func (rs *readerSuite) TestDoCheck(c *check.C) {
 	c.Check(rs.calls, check.DeepEquals, []string{"open", "check"})
 }
 
+func (rs *readerSuite) TestDoCheckEncrypted(c *check.C) {
+	defer snapshotstate.MockBackendOpen(func(filename string, setID uint64) (*backend.Reader, error) {
+		rs.calls = append(rs.calls, "open")
+		return &backend.Reader{
+			Snapshot: client.Snapshot{Encryption: &client.SnapshotEncryption{KeySource: "passphrase"}},
+		}, nil
+	})()
+	defer snapshotstate.MockBackendUnlock(func(_ *backend.Reader, ks backend.KeySource) error {
+		rs.calls = append(rs.calls, "unlock")
+		c.Check(ks.Kind(), check.Equals, "passphrase")
+		return nil
+	})()
+
+	st := rs.task.State()
+	st.Lock()
+	rs.task.Set("snapshot-setup", map[string]any{
+		"set-id":   1,
+		"snap":     "a-snap",
+		"filename": "/some/1_file.zip",
+	})
+	snapshotstate.SetPassphrase(st, 1, "sekrit")
+	st.Unlock()
+
+	err := snapshotstate.DoCheck(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+	c.Check(rs.calls, check.DeepEquals, []string{"open", "unlock", "check"})
+}
+
+func (rs *readerSuite) TestDoRestoreEncryptedWrongKey(c *check.C) {
+	defer snapshotstate.MockBackendOpen(func(filename string, setID uint64) (*backend.Reader, error) {
+		rs.calls = append(rs.calls, "open")
+		return &backend.Reader{
+			Snapshot: client.Snapshot{Encryption: &client.SnapshotEncryption{KeySource: "passphrase"}},
+		}, nil
+	})()
+	defer snapshotstate.MockBackendUnlock(func(*backend.Reader, backend.KeySource) error {
+		rs.calls = append(rs.calls, "unlock")
+		return fmt.Errorf("cannot unlock snapshot %q: %w", "/some/1_file.zip", backend.ErrWrongKey)
+	})()
+
+	st := rs.task.State()
+	st.Lock()
+	rs.task.Set("snapshot-setup", map[string]any{
+		"set-id":   1,
+		"snap":     "a-snap",
+		"filename": "/some/1_file.zip",
+	})
+	snapshotstate.SetPassphrase(st, 1, "wrong")
+	st.Unlock()
+
+	err := snapshotstate.DoRestore(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, `cannot unlock snapshot "/some/1_file.zip": wrong key`)
+	c.Check(rs.calls, check.DeepEquals, []string{"get config", "open", "unlock"})
+}
+
 func (rs *readerSuite) TestDoRemove(c *check.C) {
 	defer snapshotstate.MockOsRemove(func(filename string) error {
 		c.Check(filename, check.Equals, "/some/1_file.zip")
@@ -1308,9 +1508,30 @@ func (rs *readerSuite) TestDoRemove(c *check.C) {
 	})()
 	err := snapshotstate.DoForget(rs.task, &tomb.Tomb{})
 	c.Assert(err, check.IsNil)
+	c.Check(rs.calls, check.DeepEquals, []string{"remove", "gc"})
+}
+
+func (rs *readerSuite) TestDoForgetRemoveFails(c *check.C) {
+	defer snapshotstate.MockOsRemove(func(filename string) error {
+		rs.calls = append(rs.calls, "remove")
+		return errors.New("bzzt")
+	})()
+	err := snapshotstate.DoForget(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, "bzzt")
+	// chunks are not collected if nothing was removed
 	c.Check(rs.calls, check.DeepEquals, []string{"remove"})
 }
 
+func (rs *readerSuite) TestDoForgetGarbageCollectFailureIsNotFatal(c *check.C) {
+	defer snapshotstate.MockBackendGarbageCollectChunks(func(context.Context) (int, error) {
+		rs.calls = append(rs.calls, "gc")
+		return 0, errors.New("bzzt")
+	})()
+	err := snapshotstate.DoForget(rs.task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+	c.Check(rs.calls, check.DeepEquals, []string{"remove", "gc"})
+}
+
 func (rs *readerSuite) TestDoForgetRemovesAutomaticSnapshotExpiry(c *check.C) {
 	defer snapshotstate.MockOsRemove(func(filename string) error {
 		return nil
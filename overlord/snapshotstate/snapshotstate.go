@@ -52,7 +52,21 @@ var (
 )
 
 type snapshotState struct {
+	// ExpiryTime is set for automatic snapshots
 	ExpiryTime time.Time `json:"expiry-time"`
+	// Origin, Snap and Time are set for scheduled snapshots, for the
+	// retention policy to pick the ones to keep
+	Origin string    `json:"origin,omitempty"`
+	Snap   string    `json:"snap,omitempty"`
+	Time   time.Time `json:"time,omitzero"`
+	// PushPending is set for snapshots to be pushed to the remote
+	// target, and PushTime once they were
+	PushPending bool      `json:"push-pending,omitempty"`
+	PushTime    time.Time `json:"push-time,omitzero"`
+	// PushAttempts and PushAttemptTime track the automatic attempts at
+	// pushing snapshots to the remote target, for backing off
+	PushAttempts    int       `json:"push-attempts,omitempty"`
+	PushAttemptTime time.Time `json:"push-attempt-time,omitzero"`
 }
 
 func newSnapshotSetID(st *state.State) (uint64, error) {
@@ -151,9 +165,55 @@ func AutomaticSnapshotExpiration(st *state.State) (time.Duration, error) {
 	return defaultAutomaticSnapshotExpiration, nil
 }
 
+// deduplicateSnapshots returns whether new snapshots should be saved in the
+// deduplicated format, as per the snapshots.deduplicate core option.
+func deduplicateSnapshots(st *state.State) (bool, error) {
+	var deduplicate bool
+	tr := config.NewTransaction(st)
+	if err := tr.Get("core", "snapshots.deduplicate", &deduplicate); err != nil && !config.IsNoOption(err) {
+		return false, err
+	}
+	return deduplicate, nil
+}
+
+// snapshotsFormat returns whether new snapshots should be deduplicated and
+// the kind of key source they should be encrypted with, if any. Encrypted
+// snapshots cannot be deduplicated, so encryption wins.
+func snapshotsFormat(st *state.State) (deduplicate bool, encryption string, err error) {
+	deduplicate, err = deduplicateSnapshots(st)
+	if err != nil {
+		return false, "", err
+	}
+	encryption, err = snapshotsEncryption(st)
+	if err != nil {
+		return false, "", err
+	}
+	if encryption != "" {
+		deduplicate = false
+	}
+	return deduplicate, encryption, nil
+}
+
 // saveExpiration saves expiration date of the given snapshot set, in the state.
 // The state needs to be locked by the caller.
 func saveExpiration(st *state.State, setID uint64, expiryTime time.Time) error {
+	return saveSnapshotState(st, setID, &snapshotState{
+		ExpiryTime: expiryTime,
+	})
+}
+
+// saveScheduled records the given snapshot set as a scheduled snapshot of
+// the given snap, taken at the given time, in the state.
+// The state needs to be locked by the caller.
+func saveScheduled(st *state.State, setID uint64, snapName string, t time.Time) error {
+	return saveSnapshotState(st, setID, &snapshotState{
+		Origin: client.SnapshotOriginScheduled,
+		Snap:   snapName,
+		Time:   t,
+	})
+}
+
+func saveSnapshotState(st *state.State, setID uint64, snapshot *snapshotState) error {
 	var snapshots map[uint64]*json.RawMessage
 	err := st.Get("snapshots", &snapshots)
 	if err != nil && !errors.Is(err, state.ErrNoState) {
@@ -162,9 +222,7 @@ func saveExpiration(st *state.State, setID uint64, expiryTime time.Time) error {
 	if snapshots == nil {
 		snapshots = make(map[uint64]*json.RawMessage)
 	}
-	data, err := json.Marshal(&snapshotState{
-		ExpiryTime: expiryTime,
-	})
+	data, err := json.Marshal(snapshot)
 	if err != nil {
 		return err
 	}
@@ -207,7 +265,7 @@ func expiredSnapshotSets(st *state.State, cutoffTime time.Time) (map[uint64]bool
 
 	expired := make(map[uint64]bool)
 	for setID, snapshotSet := range snapshots {
-		if snapshotSet.ExpiryTime.Before(cutoffTime) {
+		if !snapshotSet.ExpiryTime.IsZero() && snapshotSet.ExpiryTime.Before(cutoffTime) {
 			expired[setID] = true
 		}
 	}
@@ -228,10 +286,11 @@ func (summaries snapshotSnapSummaries) snapNames() []string {
 }
 
 type snapshotSnapSummary struct {
-	snap     string
-	snapID   string
-	filename string
-	epoch    snap.Epoch
+	snap       string
+	snapID     string
+	filename   string
+	epoch      snap.Epoch
+	encryption string
 }
 
 // snapSummariesInSnapshotSet goes looking for the requested snaps in the
@@ -243,12 +302,16 @@ func snapSummariesInSnapshotSet(setID uint64, requested []string) (summaries sna
 		if r.SetID == setID {
 			found = true
 			if len(requested) == 0 || strutil.SortedListContains(requested, r.Snap) {
-				summaries = append(summaries, &snapshotSnapSummary{
+				summary := &snapshotSnapSummary{
 					filename: r.Name(),
 					snap:     r.Snap,
 					snapID:   r.SnapID,
 					epoch:    r.Epoch,
-				})
+				}
+				if r.Encryption != nil {
+					summary.encryption = r.Encryption.KeySource
+				}
+				summaries = append(summaries, summary)
 			}
 		}
 
@@ -267,6 +330,17 @@ func snapSummariesInSnapshotSet(setID uint64, requested []string) (summaries sna
 	return summaries, nil
 }
 
+// checkPassphraseProvided checks that the passphrase of the snapshot set was
+// provided if any of the summarised snapshots need it.
+func checkPassphraseProvided(st *state.State, setID uint64, summaries snapshotSnapSummaries) error {
+	for _, summary := range summaries {
+		if summary.encryption == backend.KeySourcePassphrase && cachedPassphrase(st, setID) == "" {
+			return fmt.Errorf("snapshot set #%d is encrypted with a passphrase, but none was provided", setID)
+		}
+	}
+	return nil
+}
+
 func taskGetErrMsg(task *state.Task, err error, what string) error {
 	if errors.Is(err, state.ErrNoState) {
 		return fmt.Errorf("internal error: task %s (%s) is missing %s information", task.ID(), task.Kind(), what)
@@ -320,14 +394,20 @@ func List(ctx context.Context, st *state.State, setID uint64, snapNames []string
 		return nil, err
 	}
 
-	// decorate all snapshots with "auto" flag if we have expiry time set for them.
+	// decorate all snapshots with "auto" flag if we have expiry time set for
+	// them, and with their origin.
 	for _, sset := range sets {
-		// at the moment we only keep records with expiry time so checking non-zero
-		// expiry-time is not strictly necessary, but it makes it future-proof in case
-		// we add more attributes to these entries.
-		if snapshotState, ok := snapshots[sset.ID]; ok && !snapshotState.ExpiryTime.IsZero() {
-			for _, snapshot := range sset.Snapshots {
+		snapshotState, ok := snapshots[sset.ID]
+		if !ok {
+			continue
+		}
+		for _, snapshot := range sset.Snapshots {
+			switch {
+			case !snapshotState.ExpiryTime.IsZero():
 				snapshot.Auto = true
+				snapshot.Origin = client.SnapshotOriginAutomatic
+			case snapshotState.Origin == client.SnapshotOriginScheduled:
+				snapshot.Origin = client.SnapshotOriginScheduled
 			}
 		}
 	}
@@ -335,8 +415,9 @@ func List(ctx context.Context, st *state.State, setID uint64, snapNames []string
 	return sets, nil
 }
 
-// Import a given snapshot ID from an exported snapshot
-func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, snapNames []string, err error) {
+// Import a given snapshot ID from an exported snapshot. The passphrase is
+// only needed to verify snapshots encrypted with one.
+func Import(ctx context.Context, st *state.State, r io.Reader, passphrase string) (setID uint64, snapNames []string, err error) {
 	st.Lock()
 	setID, err = newSnapshotSetID(st)
 	// note, this is a new set id which is not exposed yet, no need to mark it
@@ -347,7 +428,19 @@ func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, sn
 		return 0, nil, err
 	}
 
-	snapNames, err = backendImport(ctx, setID, r, nil)
+	keySourceFor := func(kind string) (backend.KeySource, error) {
+		if kind == backend.KeySourcePassphrase {
+			if passphrase == "" {
+				return nil, fmt.Errorf("snapshot is encrypted with a passphrase, but none was provided")
+			}
+			return backend.NewPassphraseKeySource(passphrase), nil
+		}
+		st.Lock()
+		defer st.Unlock()
+		return keySource(st, kind, setID)
+	}
+
+	snapNames, err = backendImport(ctx, setID, r, &backend.ImportFlags{KeySourceFor: keySourceFor})
 	if err != nil {
 		if dupErr, ok := err.(backend.DuplicatedSnapshotImportError); ok {
 			st.Lock()
@@ -356,7 +449,7 @@ func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, sn
 			if err := checkSnapshotConflict(st, dupErr.SetID, "forget-snapshot"); err != nil {
 				// we found an existing snapshot but it's being forgotten, so
 				// retry the import without checking for existing snapshot.
-				flags := &backend.ImportFlags{NoDuplicatedImportCheck: true}
+				flags := &backend.ImportFlags{NoDuplicatedImportCheck: true, KeySourceFor: keySourceFor}
 				st.Unlock()
 				snapNames, err = backendImport(ctx, setID, r, flags)
 				st.Lock()
@@ -378,9 +471,11 @@ func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, sn
 	return setID, snapNames, nil
 }
 
-// Save creates a taskset for taking snapshots of snaps' data.
+// Save creates a taskset for taking snapshots of snaps' data. The passphrase
+// is required if snapshots are encrypted with one, and is kept for the tasks
+// of the new snapshot set.
 // Note that the state must be locked by the caller.
-func Save(st *state.State, instanceNames []string, users []string, options map[string]*snap.SnapshotOptions) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
+func Save(st *state.State, instanceNames []string, users []string, options map[string]*snap.SnapshotOptions, passphrase string) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
 	if len(instanceNames) == 0 {
 		instanceNames, err = allActiveSnapNames(st)
 		if err != nil {
@@ -404,10 +499,23 @@ func Save(st *state.State, instanceNames []string, users []string, options map[s
 		return 0, nil, nil, err
 	}
 
+	deduplicate, encryption, err := snapshotsFormat(st)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if encryption == backend.KeySourcePassphrase && passphrase == "" {
+		// fail now rather than once the tasks run
+		return 0, nil, nil, fmt.Errorf("snapshots are encrypted with a passphrase, but none was provided")
+	}
+
 	setID, err = newSnapshotSetID(st)
 	if err != nil {
 		return 0, nil, nil, err
 	}
+	if passphrase != "" {
+		// the state is locked, so the tasks cannot run before this
+		SetPassphrase(st, setID, passphrase)
+	}
 
 	ts = state.NewTaskSet()
 
@@ -418,10 +526,12 @@ func Save(st *state.State, instanceNames []string, users []string, options map[s
 		task := st.NewTask("save-snapshot", desc)
 
 		snapshot := snapshotSetup{
-			SetID:   setID,
-			Snap:    name,
-			Users:   users,
-			Options: options[name],
+			SetID:       setID,
+			Snap:        name,
+			Users:       users,
+			Options:     options[name],
+			Deduplicate: deduplicate,
+			Encryption:  encryption,
 		}
 
 		task.Set("snapshot-setup", &snapshot)
@@ -452,6 +562,16 @@ func AutomaticSnapshot(st *state.State, snapName string) (ts *state.TaskSet, err
 	if expiration == 0 {
 		return nil, snapstate.ErrNothingToDo
 	}
+	deduplicate, encryption, err := snapshotsFormat(st)
+	if err != nil {
+		return nil, err
+	}
+	if encryption == backend.KeySourcePassphrase {
+		// there is nobody around to provide the passphrase, so let the
+		// user know that the data of the snap is not kept
+		st.Warnf("data of snap %q is not saved in an automatic snapshot on removal, as snapshots are encrypted with a passphrase: use \"snap save --passphrase\" to save it first", snapName)
+		return nil, snapstate.ErrNothingToDo
+	}
 	setID, err := newSnapshotSetID(st)
 	if err != nil {
 		return nil, err
@@ -464,9 +584,11 @@ func AutomaticSnapshot(st *state.State, snapName string) (ts *state.TaskSet, err
 	desc := fmt.Sprintf("Save data of snap %q in automatic snapshot set #%d", snapName, setID)
 	task := st.NewTask("save-snapshot", desc)
 	snapshot := snapshotSetup{
-		SetID: setID,
-		Snap:  snapName,
-		Auto:  true,
+		SetID:       setID,
+		Snap:        snapName,
+		Auto:        true,
+		Deduplicate: deduplicate,
+		Encryption:  encryption,
 	}
 	task.Set("snapshot-setup", &snapshot)
 	ts.AddTask(task)
@@ -499,6 +621,10 @@ func Restore(st *state.State, setID uint64, snapNames []string, users []string)
 		return nil, nil, err
 	}
 
+	if err := checkPassphraseProvided(st, setID, summaries); err != nil {
+		return nil, nil, err
+	}
+
 	ts = state.NewTaskSet()
 
 	for _, summary := range summaries {
@@ -564,6 +690,10 @@ func Check(st *state.State, setID uint64, snapNames []string, users []string) (s
 		return nil, nil, err
 	}
 
+	if err := checkPassphraseProvided(st, setID, summaries); err != nil {
+		return nil, nil, err
+	}
+
 	ts = state.NewTaskSet()
 
 	for _, summary := range summaries {
@@ -585,9 +715,9 @@ func Check(st *state.State, setID uint64, snapNames []string, users []string) (s
 // Forget creates a taskset for deletinig a snapshot.
 // Note that the state must be locked by the caller.
 func Forget(st *state.State, setID uint64, snapNames []string) (snapsFound []string, ts *state.TaskSet, err error) {
-	// forget needs to conflict with check, restore, import and export.
+	// forget needs to conflict with check, restore, import, export and push.
 	if err := checkSnapshotConflict(st, setID, "export-snapshot",
-		"check-snapshot", "restore-snapshot"); err != nil {
+		"check-snapshot", "restore-snapshot", "push-snapshot"); err != nil {
 		return nil, nil, err
 	}
 
@@ -84,6 +84,10 @@ func (s *snapshotSuite) SetUpTest(c *check.C) {
 	s.AddCleanup(systemd.MockNewSystemd(func(_ systemd.Backend, _ string, _ systemd.InstanceMode, _ systemd.Reporter) systemd.Systemd {
 		return &systemdtest.FakeSystemd{}
 	}))
+	serial := mockSerial(c, "canonical", "pc", "serial-1")
+	s.AddCleanup(snapshotstate.MockDevicestateSerial(func(*state.State) (*asserts.Serial, error) {
+		return serial, nil
+	}))
 }
 
 func (s *snapshotSuite) TearDownTest(c *check.C) {
@@ -366,7 +370,7 @@ func (snapshotSuite) TestSaveChecksSnapnamesError(c *check.C) {
 	st := state.New(nil)
 	st.Lock()
 	defer st.Unlock()
-	_, _, _, err := snapshotstate.Save(st, nil, nil, nil)
+	_, _, _, err := snapshotstate.Save(st, nil, nil, nil, "")
 	c.Check(err, check.ErrorMatches, "bzzt")
 }
 
@@ -431,7 +435,7 @@ func (s snapshotSuite) TestSaveChecksSnapstateConflict(c *check.C) {
 	st, restore := s.createConflictingChange(c)
 	defer restore()
 
-	_, _, _, err := snapshotstate.Save(st, []string{"foo"}, nil, nil)
+	_, _, _, err := snapshotstate.Save(st, []string{"foo"}, nil, nil, "")
 	c.Assert(err, check.NotNil)
 	c.Check(err, check.FitsTypeOf, &snapstate.ChangeConflictError{})
 }
@@ -467,7 +471,7 @@ func (snapshotSuite) TestSaveConflictsWithSnapstate(c *check.C) {
 	})
 
 	chg := st.NewChange("snapshot-save", "...")
-	_, _, saveTasks, err := snapshotstate.Save(st, nil, nil, nil)
+	_, _, saveTasks, err := snapshotstate.Save(st, nil, nil, nil, "")
 	c.Assert(err, check.IsNil)
 	chg.AddAll(saveTasks)
 
@@ -483,7 +487,7 @@ func (snapshotSuite) TestSaveChecksSnapstateConflictError(c *check.C) {
 	st := state.New(nil)
 	st.Lock()
 	defer st.Unlock()
-	_, _, _, err := snapshotstate.Save(st, nil, nil, nil)
+	_, _, _, err := snapshotstate.Save(st, nil, nil, nil, "")
 	c.Check(err, check.ErrorMatches, "bzzt")
 }
 
@@ -494,7 +498,7 @@ func (snapshotSuite) TestSaveChecksSetIDError(c *check.C) {
 
 	st.Set("last-snapshot-set-id", "3/4")
 
-	_, _, _, err := snapshotstate.Save(st, nil, nil, nil)
+	_, _, _, err := snapshotstate.Save(st, nil, nil, nil, "")
 	c.Check(err, check.ErrorMatches, ".* could not unmarshal .*")
 }
 
@@ -503,7 +507,7 @@ func (snapshotSuite) TestSaveNoSnapsInState(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	setID, saved, taskset, err := snapshotstate.Save(st, nil, nil, nil)
+	setID, saved, taskset, err := snapshotstate.Save(st, nil, nil, nil, "")
 	c.Assert(err, check.IsNil)
 	c.Check(setID, check.Equals, uint64(1))
 	c.Check(saved, check.HasLen, 0)
@@ -515,7 +519,7 @@ func (snapshotSuite) TestSaveSnapNotInstalled(c *check.C) {
 	st.Lock()
 	defer st.Unlock()
 
-	setID, saved, taskset, err := snapshotstate.Save(st, []string{"foo"}, nil, nil)
+	setID, saved, taskset, err := snapshotstate.Save(st, []string{"foo"}, nil, nil, "")
 	c.Assert(err, check.ErrorMatches, `snap "foo" is not installed`)
 	c.Check(setID, check.Equals, uint64(0))
 	c.Check(saved, check.HasLen, 0)
@@ -541,7 +545,7 @@ func (snapshotSuite) TestSaveSomeSnaps(c *check.C) {
 		"a-snap": {Exclude: []string{"$SNAP_COMMON/exclude", "$SNAP_DATA/exclude"}},
 	}
 
-	setID, saved, taskset, err := snapshotstate.Save(st, nil, nil, snapshotOptions)
+	setID, saved, taskset, err := snapshotstate.Save(st, nil, nil, snapshotOptions, "")
 	c.Assert(err, check.IsNil)
 	c.Check(setID, check.Equals, uint64(1))
 	c.Check(saved, check.DeepEquals, []string{"a-snap", "c-snap"})
@@ -587,7 +591,7 @@ func (s snapshotSuite) TestSaveOneSnap(c *check.C) {
 		Current: snap.R(1),
 	})
 
-	setID, saved, taskset, err := snapshotstate.Save(st, []string{"a-snap"}, []string{"a-user"}, nil)
+	setID, saved, taskset, err := snapshotstate.Save(st, []string{"a-snap"}, []string{"a-user"}, nil, "")
 	c.Assert(err, check.IsNil)
 	c.Check(setID, check.Equals, uint64(1))
 	c.Check(saved, check.DeepEquals, []string{"a-snap"})
@@ -605,6 +609,103 @@ func (s snapshotSuite) TestSaveOneSnap(c *check.C) {
 	})
 }
 
+func (s snapshotSuite) TestSaveDeduplicated(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	snapstate.Set(st, "a-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{
+			{RealName: "a-snap", Revision: snap.R(1)},
+		}),
+		Current: snap.R(1),
+	})
+
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.deduplicate", true)
+	tr.Commit()
+
+	_, _, taskset, err := snapshotstate.Save(st, []string{"a-snap"}, nil, nil, "")
+	c.Assert(err, check.IsNil)
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	var snapshot map[string]any
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]any{
+		"set-id":      1.,
+		"snap":        "a-snap",
+		"current":     "unset",
+		"deduplicate": true,
+	})
+}
+
+func (s snapshotSuite) TestSaveEncrypted(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	snapstate.Set(st, "a-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{
+			{RealName: "a-snap", Revision: snap.R(1)},
+		}),
+		Current: snap.R(1),
+	})
+
+	for _, encryption := range []string{"none", "passphrase"} {
+		tr := config.NewTransaction(st)
+		tr.Set("core", "snapshots.encryption", encryption)
+		tr.Commit()
+
+		passphrase := ""
+		if encryption == "passphrase" {
+			passphrase = "sekrit"
+		}
+		setID, _, taskset, err := snapshotstate.Save(st, []string{"a-snap"}, nil, nil, passphrase)
+		c.Assert(err, check.IsNil)
+		tasks := taskset.Tasks()
+		c.Assert(tasks, check.HasLen, 1)
+		var snapshot map[string]any
+		c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+		expected := map[string]any{
+			"set-id":  float64(setID),
+			"snap":    "a-snap",
+			"current": "unset",
+		}
+		if encryption != "none" {
+			expected["encryption"] = encryption
+		}
+		c.Check(snapshot, check.DeepEquals, expected)
+		c.Check(snapshotstate.CachedPassphrase(st, setID), check.Equals, passphrase)
+	}
+}
+
+func (s snapshotSuite) TestSaveEncryptedNoPassphrase(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	snapstate.Set(st, "a-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{
+			{RealName: "a-snap", Revision: snap.R(1)},
+		}),
+		Current: snap.R(1),
+	})
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.encryption", "passphrase")
+	tr.Commit()
+
+	_, _, _, err := snapshotstate.Save(st, []string{"a-snap"}, nil, nil, "")
+	c.Assert(err, check.ErrorMatches, "snapshots are encrypted with a passphrase, but none was provided")
+
+	// no snapshot set was allocated
+	setID, _, _, err := snapshotstate.Save(st, []string{"a-snap"}, nil, nil, "sekrit")
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(1))
+}
+
 func (snapshotSuite) TestSaveIntegration(c *check.C) {
 	if os.Geteuid() == 0 {
 		c.Skip("this test cannot run as root (runuser will fail)")
@@ -666,7 +767,7 @@ func (snapshotSuite) TestSaveIntegration(c *check.C) {
 		}
 	}
 
-	setID, saved, taskset, err := snapshotstate.Save(st, nil, []string{"a-user"}, snapshotOptions)
+	setID, saved, taskset, err := snapshotstate.Save(st, nil, []string{"a-user"}, snapshotOptions, "")
 	c.Assert(err, check.IsNil)
 	c.Check(setID, check.Equals, uint64(1))
 	c.Check(saved, check.DeepEquals, []string{"one-snap", "too-snap", "tri-snap"})
@@ -773,7 +874,7 @@ exec /bin/tar "$@"
 		c.Assert(os.Mkdir(filepath.Join(homedir, "snap", name, "common", "common-"+name), mode), check.IsNil)
 	}
 
-	setID, saved, taskset, err := snapshotstate.Save(st, nil, []string{"a-user"}, nil)
+	setID, saved, taskset, err := snapshotstate.Save(st, nil, []string{"a-user"}, nil, "")
 	c.Assert(err, check.IsNil)
 	c.Check(setID, check.Equals, uint64(1))
 	c.Check(saved, check.DeepEquals, []string{"one-snap", "too-snap", "tri-snap"})
@@ -873,7 +974,7 @@ exec /bin/tar "$@"
 	// these dir permissions (000) make tar unhappy
 	c.Assert(os.Mkdir(filepath.Join(homedir, "snap/tar-fail-snap/common/common-tar-fail-snap"), 00), check.IsNil)
 
-	setID, saved, taskset, err := snapshotstate.Save(st, nil, []string{"a-user"}, nil)
+	setID, saved, taskset, err := snapshotstate.Save(st, nil, []string{"a-user"}, nil, "")
 	c.Assert(err, check.IsNil)
 	c.Check(setID, check.Equals, uint64(1))
 	c.Check(saved, check.DeepEquals, []string{"tar-fail-snap"})
@@ -1269,7 +1370,7 @@ func testRestoreIntegration(c *check.C, snapDataDir string, opts *dirs.SnapDirOp
 			c.Assert(os.MkdirAll(filepath.Join(home, snapDataDir, name, "common", "common-"+name), 0755), check.IsNil)
 		}
 
-		_, err := backend.Save(context.TODO(), 42, snapInfo, nil, []string{"a-user", "b-user"}, nil, opts)
+		_, err := backend.Save(context.TODO(), 42, snapInfo, nil, []string{"a-user", "b-user"}, nil, opts, nil)
 		c.Assert(err, check.IsNil)
 	}
 
@@ -1348,7 +1449,7 @@ func (snapshotSuite) TestRestoreIntegrationFails(c *check.C) {
 		c.Assert(os.MkdirAll(filepath.Join(homedir, "snap", name, fmt.Sprint(i+1), "canary-"+name), 0755), check.IsNil)
 		c.Assert(os.MkdirAll(filepath.Join(homedir, "snap", name, "common", "common-"+name), 0755), check.IsNil)
 
-		_, err := backend.Save(context.TODO(), 42, snapInfo, nil, []string{"a-user"}, nil, nil)
+		_, err := backend.Save(context.TODO(), 42, snapInfo, nil, []string{"a-user"}, nil, nil, nil)
 		c.Assert(err, check.IsNil)
 	}
 
@@ -1490,6 +1591,147 @@ func (snapshotSuite) TestCheck(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestCheckAndRestoreNeedPassphrase(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "yadda.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	fakeIter := func(_ context.Context, f func(*backend.Reader) error) error {
+		c.Assert(f(&backend.Reader{
+			Snapshot: client.Snapshot{
+				SetID:      42,
+				Snap:       "a-snap",
+				Encryption: &client.SnapshotEncryption{KeySource: "passphrase"},
+			},
+			File: shotfile,
+		}), check.IsNil)
+
+		return nil
+	}
+	defer snapshotstate.MockBackendIter(fakeIter)()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	_, _, err = snapshotstate.Check(st, 42, nil, nil)
+	c.Check(err, check.ErrorMatches, `snapshot set #42 is encrypted with a passphrase, but none was provided`)
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil)
+	c.Check(err, check.ErrorMatches, `snapshot set #42 is encrypted with a passphrase, but none was provided`)
+
+	// a passphrase for another set does not help
+	snapshotstate.SetPassphrase(st, 41, "sekrit")
+	_, _, err = snapshotstate.Check(st, 42, nil, nil)
+	c.Check(err, check.ErrorMatches, `snapshot set #42 is encrypted with a passphrase, but none was provided`)
+
+	snapshotstate.SetPassphrase(st, 42, "sekrit")
+	_, _, err = snapshotstate.Check(st, 42, nil, nil)
+	c.Check(err, check.IsNil)
+	_, _, err = snapshotstate.Restore(st, 42, nil, nil)
+	c.Check(err, check.IsNil)
+}
+
+func (snapshotSuite) TestCheckPassphrase(c *check.C) {
+	shotfile, err := os.Create(filepath.Join(c.MkDir(), "42_b-snap.zip"))
+	c.Assert(err, check.IsNil)
+	defer shotfile.Close()
+	fakeIter := func(_ context.Context, f func(*backend.Reader) error) error {
+		for _, r := range []*backend.Reader{{
+			Snapshot: client.Snapshot{SetID: 41, Snap: "a-snap"},
+			File:     shotfile,
+		}, {
+			Snapshot: client.Snapshot{
+				SetID:      42,
+				Snap:       "a-snap",
+				Encryption: &client.SnapshotEncryption{KeySource: "tpm"},
+			},
+			File: shotfile,
+		}, {
+			Snapshot: client.Snapshot{
+				SetID:      42,
+				Snap:       "b-snap",
+				Encryption: &client.SnapshotEncryption{KeySource: "passphrase"},
+			},
+			File: shotfile,
+		}} {
+			c.Assert(f(r), check.IsNil)
+		}
+		return nil
+	}
+	defer snapshotstate.MockBackendIter(fakeIter)()
+	var opened []string
+	defer snapshotstate.MockBackendOpen(func(filename string, setID uint64) (*backend.Reader, error) {
+		c.Check(setID, check.Equals, uint64(42))
+		opened = append(opened, filename)
+		return &backend.Reader{
+			Snapshot: client.Snapshot{Encryption: &client.SnapshotEncryption{KeySource: "passphrase"}},
+		}, nil
+	})()
+	defer snapshotstate.MockBackendUnlock(func(_ *backend.Reader, ks backend.KeySource) error {
+		c.Check(ks.Kind(), check.Equals, "passphrase")
+		key, err := ks.Key(nil)
+		c.Assert(err, check.IsNil)
+		expected, err := backend.NewPassphraseKeySource("sekrit").Key(nil)
+		c.Assert(err, check.IsNil)
+		if string(key) != string(expected) {
+			return fmt.Errorf("cannot unlock snapshot: %w", backend.ErrWrongKey)
+		}
+		return nil
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	c.Check(snapshotstate.CheckPassphrase(st, 42, "sekrit"), check.IsNil)
+	c.Check(opened, check.DeepEquals, []string{shotfile.Name()})
+	err = snapshotstate.CheckPassphrase(st, 42, "wrong")
+	c.Check(err, check.ErrorMatches, `cannot unlock snapshot: wrong key`)
+	c.Check(errors.Is(err, backend.ErrWrongKey), check.Equals, true)
+
+	err = snapshotstate.CheckPassphrase(st, 41, "sekrit")
+	c.Check(err, check.ErrorMatches, `snapshot set #41 is not encrypted with a passphrase`)
+	err = snapshotstate.CheckPassphrase(st, 43, "sekrit")
+	c.Check(err, check.Equals, client.ErrSnapshotSetNotFound)
+}
+
+func (snapshotSuite) TestPassphraseForgottenWhenChangesDone(c *check.C) {
+	defer snapshotstate.MockBackendCleanupAbandonedImports(func() (int, error) { return 0, nil })()
+
+	st := state.New(nil)
+	runner := state.NewTaskRunner(st)
+	mgr := snapshotstate.Manager(st, runner)
+	c.Assert(mgr.StartUp(), check.IsNil)
+	defer mgr.Stop()
+
+	st.Lock()
+	defer st.Unlock()
+
+	newChange := func(kind string, setID uint64) (*state.Change, *state.Task) {
+		chg := st.NewChange(kind, "...")
+		t := st.NewTask(kind, "...")
+		t.Set("snapshot-setup", map[string]any{"set-id": setID, "snap": "a-snap"})
+		chg.AddTask(t)
+		return chg, t
+	}
+	snapshotstate.SetPassphrase(st, 42, "sekrit")
+	snapshotstate.SetPassphrase(st, 43, "other")
+	_, check42 := newChange("check-snapshot", 42)
+	_, restore42 := newChange("restore-snapshot", 42)
+	_, save43 := newChange("save-snapshot", 43)
+
+	// another change still needs the passphrase
+	check42.SetStatus(state.DoneStatus)
+	c.Check(snapshotstate.CachedPassphrase(st, 42), check.Equals, "sekrit")
+
+	// forgotten once done, whether successful or not
+	restore42.SetStatus(state.ErrorStatus)
+	c.Check(snapshotstate.CachedPassphrase(st, 42), check.Equals, "")
+	c.Check(snapshotstate.CachedPassphrase(st, 43), check.Equals, "other")
+
+	save43.SetStatus(state.DoneStatus)
+	c.Check(snapshotstate.CachedPassphrase(st, 43), check.Equals, "")
+}
+
 func (snapshotSuite) TestForgetChecksIterError(c *check.C) {
 	defer snapshotstate.MockBackendIter(func(context.Context, func(*backend.Reader) error) error {
 		return errors.New("bzzt")
@@ -1733,6 +1975,79 @@ func (snapshotSuite) TestAutomaticSnapshot(c *check.C) {
 	})
 }
 
+func (snapshotSuite) TestAutomaticSnapshotDeduplicated(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.automatic.retention", "24h")
+	tr.Set("core", "snapshots.deduplicate", true)
+	tr.Commit()
+
+	ts, err := snapshotstate.AutomaticSnapshot(st, "foo")
+	c.Assert(err, check.IsNil)
+
+	tasks := ts.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	var snapshot map[string]any
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]any{
+		"set-id":      1.,
+		"snap":        "foo",
+		"current":     "unset",
+		"auto":        true,
+		"deduplicate": true,
+	})
+}
+
+func (snapshotSuite) TestAutomaticSnapshotEncrypted(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.automatic.retention", "24h")
+	tr.Set("core", "snapshots.deduplicate", true)
+	tr.Set("core", "snapshots.encryption", "tpm")
+	tr.Commit()
+
+	ts, err := snapshotstate.AutomaticSnapshot(st, "foo")
+	c.Assert(err, check.IsNil)
+
+	tasks := ts.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	var snapshot map[string]any
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	// encrypted snapshots are not deduplicated
+	c.Check(snapshot, check.DeepEquals, map[string]any{
+		"set-id":     1.,
+		"snap":       "foo",
+		"current":    "unset",
+		"auto":       true,
+		"encryption": "tpm",
+	})
+}
+
+func (snapshotSuite) TestAutomaticSnapshotPassphraseEncrypted(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.automatic.retention", "24h")
+	tr.Set("core", "snapshots.encryption", "passphrase")
+	tr.Commit()
+
+	_, err := snapshotstate.AutomaticSnapshot(st, "foo")
+	c.Assert(err, check.Equals, snapstate.ErrNothingToDo)
+
+	// the user is told that the data is not saved
+	warnings := st.AllWarnings()
+	c.Assert(warnings, check.HasLen, 1)
+	c.Check(warnings[0].String(), check.Equals, `data of snap "foo" is not saved in an automatic snapshot on removal, as snapshots are encrypted with a passphrase: use "snap save --passphrase" to save it first`)
+}
+
 func (snapshotSuite) TestAutomaticSnapshotDefaultClassic(c *check.C) {
 	release.MockOnClassic(true)
 
@@ -1859,7 +2174,7 @@ func (snapshotSuite) TestImportSnapshotHappy(c *check.C) {
 	})
 	defer restore()
 
-	sid, names, err := snapshotstate.Import(context.TODO(), st, buf)
+	sid, names, err := snapshotstate.Import(context.TODO(), st, buf, "")
 	c.Assert(err, check.IsNil)
 	c.Check(sid, check.Equals, uint64(1))
 	c.Check(names, check.DeepEquals, fakeSnapNames)
@@ -1874,7 +2189,7 @@ func (snapshotSuite) TestImportSnapshotImportError(c *check.C) {
 	defer restore()
 
 	r := bytes.NewBufferString("faked-import-data")
-	sid, _, err := snapshotstate.Import(context.TODO(), st, r)
+	sid, _, err := snapshotstate.Import(context.TODO(), st, r, "")
 	c.Assert(err, check.NotNil)
 	c.Assert(err.Error(), check.Equals, "some-error")
 	c.Check(sid, check.Equals, uint64(0))
@@ -1895,7 +2210,7 @@ func (snapshotSuite) TestImportSnapshotDuplicate(c *check.C) {
 	})
 	st.Unlock()
 
-	sid, snapNames, err := snapshotstate.Import(context.TODO(), st, bytes.NewBufferString(""))
+	sid, snapNames, err := snapshotstate.Import(context.TODO(), st, bytes.NewBufferString(""), "")
 	c.Assert(err, check.IsNil)
 	c.Check(sid, check.Equals, uint64(3))
 	c.Check(snapNames, check.DeepEquals, []string{"foo-snap"})
@@ -2029,7 +2344,7 @@ func (snapshotSuite) TestImportSnapshotDuplicatedNoConflict(c *check.C) {
 	defer restore()
 
 	st := state.New(nil)
-	setID, snaps, err := snapshotstate.Import(context.TODO(), st, buf)
+	setID, snaps, err := snapshotstate.Import(context.TODO(), st, buf, "")
 	c.Check(importCalls, check.Equals, 1)
 	c.Assert(err, check.IsNil)
 	c.Check(setID, check.Equals, uint64(42))
@@ -2043,7 +2358,8 @@ func (snapshotSuite) TestImportSnapshotConflictsWithForget(c *check.C) {
 		importCalls++
 		switch importCalls {
 		case 1:
-			c.Assert(flags, check.IsNil)
+			c.Assert(flags, check.NotNil)
+			c.Assert(flags.NoDuplicatedImportCheck, check.Equals, false)
 		case 2:
 			c.Assert(flags, check.NotNil)
 			c.Assert(flags.NoDuplicatedImportCheck, check.Equals, true)
@@ -2069,7 +2385,7 @@ func (snapshotSuite) TestImportSnapshotConflictsWithForget(c *check.C) {
 	chg.AddTask(tsk)
 
 	st.Unlock()
-	setID, snaps, err := snapshotstate.Import(context.TODO(), st, buf)
+	setID, snaps, err := snapshotstate.Import(context.TODO(), st, buf, "")
 	st.Lock()
 	c.Check(importCalls, check.Equals, 2)
 	c.Assert(err, check.IsNil)
@@ -2126,5 +2442,5 @@ func (snapshotSuite) TestSetSnapshotOpInProgress(c *check.C) {
 }
 
 func (s *snapshotSuite) TestEnsureLoopLogging(c *check.C) {
-	swfeatstest.CheckEnsureLoopLogging("snapshotmgr.go", c, false)
+	swfeatstest.CheckEnsureLoopLogging("snapshotmgr.go", c, true)
 }
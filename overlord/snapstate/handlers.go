@@ -54,6 +54,7 @@ import (
 	"github.com/snapcore/snapd/release"
 	apparmor_sandbox "github.com/snapcore/snapd/sandbox/apparmor"
 	"github.com/snapcore/snapd/sandbox/cgroup"
+	"github.com/snapcore/snapd/seclog"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/snapdenv"
@@ -2162,6 +2163,12 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (retErr error) {
 	// Notify link snap participants about link changes.
 	notifyLinkParticipants(t, snapsup)
 
+	// enabling a snap links its current revision again, which is neither
+	// an install nor a refresh
+	if firstInstall || oldCurrent != cand.Snap.Revision {
+		logSnapLinked(t, snapsup, oldCurrent)
+	}
+
 	// Unfortunately this is needed to make sure we actually request a reboot as a part
 	// of link-snap for the gadget (which is the task that has a restart-boundary set).
 	// The gadget does not by default set `rebootInfo.RebootRequired` as its difficult for
@@ -2677,6 +2684,10 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	// Notify link snap participants about link changes.
 	notifyLinkParticipants(t, snapsup)
 
+	if firstInstall || oldCurrent != snapsup.Revision() {
+		logSnapLinkUndone(t, snapsup, oldCurrent)
+	}
+
 	// Finish task: set status, possibly restart
 
 	// Make sure if state commits and snapst is mutated we won't be rerun
@@ -3648,9 +3659,51 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 	Set(st, snapsup.InstanceName(), snapst)
+
+	if len(snapst.Sequence.Revisions) == 0 {
+		seclog.LogSnapRemoved(auth.SecurityLogUser(st, snapsup.UserID), securityLogSnap(snapsup), t.Change().ID())
+	}
 	return nil
 }
 
+// securityLogSnap returns the snap revision of snapsup for use in security
+// audit log events.
+func securityLogSnap(snapsup *SnapSetup) seclog.Snap {
+	return seclog.Snap{
+		Name:     snapsup.InstanceName(),
+		SnapID:   snapsup.SideInfo.SnapID,
+		Revision: snapsup.Revision().String(),
+	}
+}
+
+// logSnapLinked logs the security audit event of the install, refresh or
+// revert of the snap of the given link-snap task.
+func logSnapLinked(t *state.Task, snapsup *SnapSetup, oldCurrent snap.Revision) {
+	user := auth.SecurityLogUser(t.State(), snapsup.UserID)
+	switch {
+	case oldCurrent.Unset():
+		seclog.LogSnapInstalled(user, securityLogSnap(snapsup), t.Change().ID())
+	case snapsup.Revert:
+		seclog.LogSnapReverted(user, securityLogSnap(snapsup), oldCurrent.String(), t.Change().ID())
+	default:
+		seclog.LogSnapRefreshed(user, securityLogSnap(snapsup), oldCurrent.String(), t.Change().ID())
+	}
+}
+
+// logSnapLinkUndone logs the security audit event of undoing the link of the
+// snap of the given link-snap task: the removal of a snap whose install was
+// undone, or the revert to the revision it had before.
+func logSnapLinkUndone(t *state.Task, snapsup *SnapSetup, oldCurrent snap.Revision) {
+	user := auth.SecurityLogUser(t.State(), snapsup.UserID)
+	if oldCurrent.Unset() {
+		seclog.LogSnapRemoved(user, securityLogSnap(snapsup), t.Change().ID())
+		return
+	}
+	reverted := securityLogSnap(snapsup)
+	reverted.Revision = oldCurrent.String()
+	seclog.LogSnapReverted(user, reverted, snapsup.Revision().String(), t.Change().ID())
+}
+
 /* aliases v2
 
 aliases v2 implementation uses the following tasks:
@@ -20,6 +20,7 @@
 package snapstate_test
 
 import (
+	"bytes"
 	"os"
 
 	. "gopkg.in/check.v1"
@@ -31,6 +32,8 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/snapstatetest"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/seclog"
+	"github.com/snapcore/snapd/seclog/seclogtest"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -168,6 +171,48 @@ func (s *discardSnapSuite) TestDoDiscardSnapToEmpty(c *C) {
 	c.Assert(err, testutil.ErrorIs, state.ErrNoState)
 }
 
+func (s *discardSnapSuite) TestDoDiscardSnapSecurityLog(c *C) {
+	buf := &bytes.Buffer{}
+	seclog.Setup(seclogtest.MockSecurityLogger(buf))
+	defer seclog.Setup(seclog.NewNopLogger())
+
+	s.state.Lock()
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(3)},
+			{RealName: "foo", Revision: snap.R(4)},
+		}),
+		Current:  snap.R(4),
+		SnapType: "app",
+	})
+	for _, rev := range []snap.Revision{snap.R(3), snap.R(4)} {
+		t := s.state.NewTask("discard-snap", "test")
+		t.Set("snap-setup", &snapstate.SnapSetup{
+			SideInfo: &snap.SideInfo{
+				RealName: "foo",
+				Revision: rev,
+				SnapID:   "foo-id",
+			},
+		})
+		s.state.NewChange("sample", "...").AddTask(t)
+		s.state.Unlock()
+
+		s.se.Ensure()
+		s.se.Wait()
+
+		s.state.Lock()
+		c.Assert(t.Status(), Equals, state.DoneStatus)
+		if rev == snap.R(3) {
+			// a revision is left
+			c.Check(buf.Len(), Equals, 0)
+		}
+	}
+	s.state.Unlock()
+
+	c.Check(buf.String(), testutil.Contains, "snap_removed User <unknown>:<unknown>:<unknown> removed snap foo:4")
+	c.Check(buf.String(), testutil.Contains, `[snap=seclog.Snap{Name:"foo", SnapID:"foo-id", Revision:"4"}]`)
+}
+
 func (s *discardSnapSuite) TestDoDiscardSnapErrorsForActive(c *C) {
 	s.state.Lock()
 	snapstate.Set(s.state, "foo", &snapstate.SnapState{
@@ -20,6 +20,7 @@
 package snapstate_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -51,6 +52,8 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/seclog"
+	"github.com/snapcore/snapd/seclog/seclogtest"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -157,6 +160,137 @@ func (s *linkSnapSuite) TestDoLinkSnapSuccess(c *C) {
 	c.Check(lp.instanceNames, DeepEquals, []string{"foo"})
 }
 
+func (s *linkSnapSuite) TestDoLinkSnapSecurityLog(c *C) {
+	buf := &bytes.Buffer{}
+	seclog.Setup(seclogtest.MockSecurityLogger(buf))
+	defer seclog.Setup(seclog.NewNopLogger())
+
+	s.state.Lock()
+	t := s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			Revision: snap.R(33),
+			SnapID:   "foo-id",
+		},
+		UserID: 2,
+	})
+	chg := s.state.NewChange("sample", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(t.Status(), Equals, state.DoneStatus)
+	c.Check(buf.String(), Equals, fmt.Sprintf("snap_installed User 2:<unknown>:<unknown> installed snap foo:33"+
+		" [user=seclog.SnapdUser{ID:2, StoreUserName:\"\", StoreUserEmail:\"\", Expiration:time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)}]"+
+		" [snap=seclog.Snap{Name:\"foo\", SnapID:\"foo-id\", Revision:\"33\"}]"+
+		" [change_id=\"%s\"]\n", chg.ID()))
+
+	// refresh to a new revision
+	buf.Reset()
+	t = s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			Revision: snap.R(34),
+			SnapID:   "foo-id",
+		},
+	})
+	chg = s.state.NewChange("sample", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	c.Assert(t.Status(), Equals, state.DoneStatus)
+	c.Check(buf.String(), testutil.Contains, "snap_refreshed User <unknown>:<unknown>:<unknown> refreshed snap foo:34 from revision 33")
+	c.Check(buf.String(), testutil.Contains, `[old_revision="33"]`)
+	c.Check(buf.String(), testutil.Contains, fmt.Sprintf(`[change_id="%s"]`, chg.ID()))
+
+	// revert to the previous revision
+	buf.Reset()
+	t = s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			Revision: snap.R(33),
+			SnapID:   "foo-id",
+		},
+		Flags: snapstate.Flags{Revert: true},
+	})
+	chg = s.state.NewChange("sample", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	c.Assert(t.Status(), Equals, state.DoneStatus)
+	c.Check(buf.String(), testutil.Contains, "snap_reverted User <unknown>:<unknown>:<unknown> reverted snap foo:33 from revision 34")
+	c.Check(buf.String(), testutil.Contains, `[old_revision="34"]`)
+	c.Check(buf.String(), Not(testutil.Contains), "snap_refreshed")
+}
+
+func (s *linkSnapSuite) TestDoUndoLinkSnapSecurityLog(c *C) {
+	buf := &bytes.Buffer{}
+	seclog.Setup(seclogtest.MockSecurityLogger(buf))
+	defer seclog.Setup(seclog.NewNopLogger())
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	linkWithUndo := func(rev int) *state.Change {
+		t := s.state.NewTask("link-snap", "test")
+		t.Set("snap-setup", &snapstate.SnapSetup{
+			SideInfo: &snap.SideInfo{
+				RealName: "foo",
+				Revision: snap.R(rev),
+				SnapID:   "foo-id",
+			},
+			UserID: 2,
+		})
+		chg := s.state.NewChange("sample", "...")
+		chg.AddTask(t)
+		terr := s.state.NewTask("error-trigger", "provoking total undo")
+		terr.WaitFor(t)
+		chg.AddTask(terr)
+
+		s.state.Unlock()
+		defer s.state.Lock()
+		for i := 0; i < 6; i++ {
+			s.se.Ensure()
+			s.se.Wait()
+		}
+		return chg
+	}
+
+	// the install is undone
+	chg := linkWithUndo(33)
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(buf.String(), Matches, `(?s)snap_installed User 2:<unknown>:<unknown> installed snap foo:33 .*\nsnap_removed User 2:<unknown>:<unknown> removed snap foo:33 .*`)
+
+	// the refresh is undone
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Sequence: snapstatetest.NewSequenceFromSnapSideInfos([]*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(33), SnapID: "foo-id"},
+		}),
+		Current: snap.R(33),
+		Active:  true,
+	})
+	buf.Reset()
+	chg = linkWithUndo(34)
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(buf.String(), Matches, `(?s)snap_refreshed User 2:<unknown>:<unknown> refreshed snap foo:34 from revision 33 .*\nsnap_reverted User 2:<unknown>:<unknown> reverted snap foo:33 from revision 34 .*`)
+	c.Check(buf.String(), testutil.Contains, fmt.Sprintf(`[change_id="%s"]`, chg.ID()))
+}
+
 func (s *linkSnapSuite) TestDoLinkSnapSuccessWithCohort(c *C) {
 	// we start without the auxiliary store info
 	c.Check(backend.AuxStoreInfoFilename("foo-id"), testutil.FileAbsent)
@@ -268,7 +268,7 @@ func (c *Change) Summary() string {
 // Set associates value with key for future consulting by managers.
 // The provided value must properly marshal and unmarshal with encoding/json.
 func (c *Change) Set(key string, value any) {
-	c.state.writing()
+	c.writing()
 	c.data.set(key, value)
 }
 
@@ -446,6 +446,7 @@ func shouldSkipChangeUpdateNotice(old, new Status) bool {
 }
 
 func (c *Change) notifyStatusChange(new Status) {
+	c.state.markDirty(EntriesChanges, c.id)
 	if c.lastObservedStatus != new {
 		c.state.notifyChangeStatusChangedHandlers(c, c.lastObservedStatus, new)
 		c.lastObservedStatus = new
@@ -459,9 +460,15 @@ func (c *Change) notifyStatusChange(new Status) {
 	}
 }
 
+// writing is State.writing, for modifying the change.
+func (c *Change) writing() {
+	c.state.writing()
+	c.state.markDirty(EntriesChanges, c.id)
+}
+
 // SetStatus sets the change status, overriding the default behavior (see Status method).
 func (c *Change) SetStatus(s Status) {
-	c.state.writing()
+	c.writing()
 	c.status = s
 	if s.Ready() {
 		c.markReady()
@@ -470,6 +477,7 @@ func (c *Change) SetStatus(s Status) {
 }
 
 func (c *Change) markReady() {
+	c.state.markDirty(EntriesChanges, c.id)
 	select {
 	case <-c.ready:
 	default:
@@ -552,6 +560,7 @@ func (c *Change) taskCleanChanged() {
 		}
 	}
 	c.clean = true
+	c.state.markDirty(EntriesChanges, c.id)
 }
 
 // SpawnTime returns the time when the change was created.
@@ -626,18 +635,19 @@ func (c *Change) State() *State {
 // AddTask registers a task as required for the state change to
 // be accomplished.
 func (c *Change) AddTask(t *Task) {
-	c.state.writing()
+	c.writing()
 	if t.change != "" {
 		panic(fmt.Sprintf("internal error: cannot add one %q task to multiple changes", t.Kind()))
 	}
 	t.change = c.id
 	c.taskIDs = addOnce(c.taskIDs, t.ID())
+	c.state.markDirty(EntriesTasks, t.id)
 }
 
 // AddAll registers all tasks in the set as required for the state
 // change to be accomplished.
 func (c *Change) AddAll(ts *TaskSet) {
-	c.state.writing()
+	c.writing()
 	for _, t := range ts.tasks {
 		c.AddTask(t)
 	}
@@ -676,7 +686,7 @@ func (c *Change) LaneTasks(lanes ...int) []*Task {
 // Abort flags the change for cancellation, whether in progress or not.
 // Cancellation will proceed at the next ensure pass.
 func (c *Change) Abort() {
-	c.state.writing()
+	c.writing()
 	tasks := make([]*Task, len(c.taskIDs))
 	for i, tid := range c.taskIDs {
 		tasks[i] = c.state.tasks[tid]
@@ -688,14 +698,14 @@ func (c *Change) Abort() {
 // except for tasks that are also in a healthy lane (not aborted, and not waiting
 // on aborted).
 func (c *Change) AbortLanes(lanes []int) {
-	c.state.writing()
+	c.writing()
 	c.abortLanes(lanes, make(map[int]bool), make(map[string]bool))
 }
 
 // AbortUnreadyLanes aborts the tasks from lanes that aren't fully ready, where
 // a ready lane is one in which all tasks are ready.
 func (c *Change) AbortUnreadyLanes() {
-	c.state.writing()
+	c.writing()
 	c.abortUnreadyLanes()
 }
 
@@ -113,12 +113,26 @@ func CopyState(srcStatePath, dstStatePath string, dataEntries []string) error {
 	}
 	defer f.Close()
 
-	// No need to lock/unlock the state here, srcState should not be
-	// in use at all.
 	srcState, err := ReadState(nil, f)
 	if err != nil {
 		return err
 	}
+	return CopyStateFrom(srcState, dstStatePath, dataEntries)
+}
+
+// CopyStateFrom is like CopyState but takes the state to copy from as
+// already read, e.g. from the state database. Note that srcState should
+// never be in use.
+func CopyStateFrom(srcState *State, dstStatePath string, dataEntries []string) error {
+	if osutil.FileExists(dstStatePath) {
+		return fmt.Errorf("cannot copy state: %q already exists", dstStatePath)
+	}
+	if len(dataEntries) == 0 {
+		return fmt.Errorf("cannot copy state: must provide at least one data entry to copy")
+	}
+
+	// No need to lock/unlock the state here, srcState should not be
+	// in use at all.
 
 	// copy relevant data
 	dstData := make(map[string]any)
@@ -20,6 +20,7 @@
 package state_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -111,6 +112,22 @@ func (ss *stateSuite) TestCopyStateIntegration(c *C) {
 	c.Check(string(dstContent), Equals, `{"data":{"auth":{"last-id":1,"users":[{"id":1,"email":"some@user.com","macaroon":"1234","store-macaroon":"5678","store-discharges":["9012345"]}]}}`+stateSuffix)
 }
 
+func (ss *stateSuite) TestCopyStateFrom(c *C) {
+	srcState, err := state.ReadState(nil, bytes.NewReader(srcStateContent))
+	c.Assert(err, IsNil)
+
+	dstStateFile := filepath.Join(c.MkDir(), "dst-state.json")
+	err = state.CopyStateFrom(srcState, dstStateFile, []string{"auth.users", "auth.last-id"})
+	c.Assert(err, IsNil)
+
+	dstContent, err := os.ReadFile(dstStateFile)
+	c.Assert(err, IsNil)
+	c.Check(string(dstContent), Equals, `{"data":{"auth":{"last-id":1,"users":[{"id":1,"email":"some@user.com","macaroon":"1234","store-macaroon":"5678","store-discharges":["9012345"]}]}}`+stateSuffix)
+
+	err = state.CopyStateFrom(srcState, dstStateFile, []string{"auth.users"})
+	c.Assert(err, ErrorMatches, `cannot copy state: "/.*/dst-state.json" already exists`)
+}
+
 var srcStateContent1 = []byte(`{
     "data": {
         "A": {"B": [{"C": 1}, {"D": 2}]},
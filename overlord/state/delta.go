@@ -0,0 +1,329 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+// Kinds of the entries the state is split in when checkpointing deltas.
+const (
+	// EntriesMeta holds, under MetaKey, the last ids and notice timestamp
+	EntriesMeta = "meta"
+	// EntriesData holds the top-level data keys
+	EntriesData    = "data"
+	EntriesChanges = "changes"
+	EntriesTasks   = "tasks"
+	// EntriesWarnings holds the warnings, by message
+	EntriesWarnings = "warnings"
+	// EntriesNotices holds the notices, by id
+	EntriesNotices = "notices"
+)
+
+// MetaKey is the only key of the EntriesMeta entries.
+const MetaKey = "state"
+
+// EntryKinds are all the kinds of entries the state is split in.
+var EntryKinds = []string{EntriesMeta, EntriesData, EntriesChanges, EntriesTasks, EntriesWarnings, EntriesNotices}
+
+// A Delta holds the serialized entries of the state that changed since
+// the last checkpoint, by kind and then key. Removed entries are nil.
+type Delta struct {
+	// Full is set when the delta holds the whole state, which then
+	// replaces anything checkpointed before.
+	Full    bool
+	Entries map[string]map[string][]byte
+}
+
+// A DeltaBackend is a Backend that can checkpoint only the entries of the
+// state that changed, instead of all of it. State checkpoints through
+// CheckpointDelta when its backend is a DeltaBackend.
+type DeltaBackend interface {
+	Backend
+	CheckpointDelta(delta *Delta) error
+}
+
+type entryHashes map[string]map[string][sha256.Size]byte
+
+type marshalledMeta struct {
+	LastChangeId int `json:"last-change-id"`
+	LastTaskId   int `json:"last-task-id"`
+	LastLaneId   int `json:"last-lane-id"`
+	LastNoticeId int `json:"last-notice-id"`
+
+	LastNoticeTimestamp json.RawMessage `json:"last-notice-timestamp,omitempty"`
+}
+
+func mustMarshal(what string, v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// this shouldn't happen, as for checkpointData
+		logger.Panicf("internal error: could not marshal %s for checkpointing: %v", what, err)
+	}
+	return data
+}
+
+func (s *State) metaEntry() []byte {
+	meta := marshalledMeta{
+		LastChangeId: s.lastChangeId,
+		LastTaskId:   s.lastTaskId,
+		LastLaneId:   s.lastLaneId,
+		LastNoticeId: s.lastNoticeId,
+	}
+	if ts := s.getLastNoticeTimestamp(); !ts.IsZero() {
+		meta.LastNoticeTimestamp = mustMarshal("last notice timestamp", ts)
+	}
+	return mustMarshal("state meta", meta)
+}
+
+// entries returns the state split in serialized entries, by kind and key.
+func (s *State) entries() map[string]map[string][]byte {
+	s.reading()
+	entries := make(map[string]map[string][]byte, len(EntryKinds))
+	for _, kind := range EntryKinds {
+		entries[kind] = make(map[string][]byte)
+	}
+	entries[EntriesMeta][MetaKey] = s.metaEntry()
+
+	for k, v := range s.data {
+		entries[EntriesData][k] = []byte(*v)
+	}
+	for id, chg := range s.changes {
+		entries[EntriesChanges][id] = mustMarshal("change", chg)
+	}
+	for id, t := range s.tasks {
+		entries[EntriesTasks][id] = mustMarshal("task", t)
+	}
+	for _, w := range s.flattenWarnings() {
+		entries[EntriesWarnings][w.message] = mustMarshal("warning", w)
+	}
+	for _, n := range s.flattenNotices() {
+		entries[EntriesNotices][n.id] = mustMarshal("notice", n)
+	}
+	return entries
+}
+
+// Entries returns the state split in serialized entries, by kind and key,
+// as checkpointed as a whole through a DeltaBackend.
+func (s *State) Entries() map[string]map[string][]byte {
+	return s.entries()
+}
+
+func hashEntries(entries map[string]map[string][]byte) entryHashes {
+	hashes := make(entryHashes, len(entries))
+	for kind, kindEntries := range entries {
+		hashes[kind] = make(map[string][sha256.Size]byte, len(kindEntries))
+		for k, v := range kindEntries {
+			hashes[kind][k] = sha256.Sum256(v)
+		}
+	}
+	return hashes
+}
+
+// markDirty records that the entry of the given kind and key may have
+// changed since the last checkpoint, so that it is part of the next delta.
+func (s *State) markDirty(kind, key string) {
+	if _, ok := s.backend.(DeltaBackend); !ok {
+		return
+	}
+	keys := s.dirty[kind]
+	if keys == nil {
+		keys = make(map[string]bool)
+		s.dirty[kind] = keys
+	}
+	keys[key] = true
+}
+
+// dirtyEntry returns the serialized entry of the given kind and key, nil if
+// it is gone. Expired warnings and notices are gone, as with entries.
+func (s *State) dirtyEntry(kind, key string, notices map[string]*Notice) []byte {
+	switch kind {
+	case EntriesData:
+		if v := s.data[key]; v != nil {
+			return []byte(*v)
+		}
+	case EntriesChanges:
+		if chg := s.changes[key]; chg != nil {
+			return mustMarshal("change", chg)
+		}
+	case EntriesTasks:
+		if t := s.tasks[key]; t != nil {
+			return mustMarshal("task", t)
+		}
+	case EntriesWarnings:
+		s.warningsMu.RLock()
+		w := s.warnings[key]
+		s.warningsMu.RUnlock()
+		if w != nil && !w.ExpiredBefore(time.Now()) {
+			return mustMarshal("warning", w)
+		}
+	case EntriesNotices:
+		if n := notices[key]; n != nil {
+			return mustMarshal("notice", n)
+		}
+	}
+	return nil
+}
+
+// checkpointDelta returns the delta of the state since the last checkpoint,
+// together with the hashes of the entries it sets. Only the meta entry and
+// the entries marked dirty are serialized, unless nothing was checkpointed
+// yet and the delta is the whole state.
+func (s *State) checkpointDelta() (*Delta, entryHashes) {
+	if !s.deltaCheckpointed {
+		entries := s.entries()
+		return &Delta{Full: true, Entries: entries}, hashEntries(entries)
+	}
+
+	delta := &Delta{Entries: make(map[string]map[string][]byte)}
+	hashes := make(entryHashes)
+	add := func(kind, key string, v []byte) {
+		old, ok := s.checkpointed[kind][key]
+		if v == nil {
+			if !ok {
+				return
+			}
+		} else {
+			h := sha256.Sum256(v)
+			if ok && h == old {
+				return
+			}
+			if hashes[kind] == nil {
+				hashes[kind] = make(map[string][sha256.Size]byte)
+			}
+			hashes[kind][key] = h
+		}
+		if delta.Entries[kind] == nil {
+			delta.Entries[kind] = make(map[string][]byte)
+		}
+		delta.Entries[kind][key] = v
+	}
+
+	add(EntriesMeta, MetaKey, s.metaEntry())
+	var notices map[string]*Notice
+	if len(s.dirty[EntriesNotices]) > 0 {
+		notices = make(map[string]*Notice)
+		for _, n := range s.flattenNotices() {
+			notices[n.id] = n
+		}
+	}
+	for kind, keys := range s.dirty {
+		for key := range keys {
+			add(kind, key, s.dirtyEntry(kind, key, notices))
+		}
+	}
+	return delta, hashes
+}
+
+// deltaCheckpointedAs records that the given delta, setting entries with
+// the given hashes, was checkpointed.
+func (s *State) deltaCheckpointedAs(delta *Delta, hashes entryHashes) {
+	if delta.Full {
+		s.checkpointed = hashes
+	} else {
+		for kind, entries := range delta.Entries {
+			if s.checkpointed[kind] == nil {
+				s.checkpointed[kind] = make(map[string][sha256.Size]byte)
+			}
+			for key := range entries {
+				if h, ok := hashes[kind][key]; ok {
+					s.checkpointed[kind][key] = h
+				} else {
+					delete(s.checkpointed[kind], key)
+				}
+			}
+		}
+	}
+	s.deltaCheckpointed = true
+	for kind := range s.dirty {
+		delete(s.dirty, kind)
+	}
+}
+
+// MarshalEntries returns the state made of the given entries, by kind and
+// key, serialized as a whole, as read by ReadState, without going through
+// State.
+func MarshalEntries(entries map[string]map[string][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"data":{`)
+	first := true
+	for k, v := range entries[EntriesData] {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(mustMarshal("data key", k))
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	for _, kind := range []string{EntriesChanges, EntriesTasks} {
+		fmt.Fprintf(&buf, `},%q:{`, kind)
+		first = true
+		for k, v := range entries[kind] {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.Write(mustMarshal("id", k))
+			buf.WriteByte(':')
+			buf.Write(v)
+		}
+	}
+	buf.WriteByte('}')
+	for _, kind := range []string{EntriesWarnings, EntriesNotices} {
+		fmt.Fprintf(&buf, `,%q:[`, kind)
+		first = true
+		for _, v := range entries[kind] {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.Write(v)
+		}
+		buf.WriteByte(']')
+	}
+	if meta := entries[EntriesMeta][MetaKey]; len(meta) > 2 {
+		// splice in the fields of the meta object
+		buf.WriteByte(',')
+		buf.Write(bytes.TrimSpace(meta)[1:])
+	} else {
+		buf.WriteByte('}')
+	}
+	return buf.Bytes()
+}
+
+// ReadStateEntries returns the state made of the given entries, by kind and
+// key, as checkpointed through a DeltaBackend. Later checkpoints through the
+// backend only carry what changed from them.
+func ReadStateEntries(backend Backend, entries map[string]map[string][]byte) (*State, error) {
+	s, err := ReadState(backend, bytes.NewReader(MarshalEntries(entries)))
+	if err != nil {
+		return nil, err
+	}
+	s.checkpointed = hashEntries(entries)
+	s.deltaCheckpointed = true
+	return s, nil
+}
@@ -0,0 +1,312 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state_test
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type deltaSuite struct{}
+
+var _ = Suite(&deltaSuite{})
+
+// fakeDeltaBackend applies the deltas it gets to its entries, as a keyed
+// store would.
+type fakeDeltaBackend struct {
+	deltas  []*state.Delta
+	entries map[string]map[string][]byte
+	error   func() error
+}
+
+func (b *fakeDeltaBackend) Checkpoint(data []byte) error {
+	panic("Checkpoint should not be called with a DeltaBackend")
+}
+
+func (b *fakeDeltaBackend) CheckpointDelta(delta *state.Delta) error {
+	b.deltas = append(b.deltas, delta)
+	if b.error != nil {
+		return b.error()
+	}
+	if delta.Full || b.entries == nil {
+		b.entries = make(map[string]map[string][]byte)
+	}
+	for kind, entries := range delta.Entries {
+		if b.entries[kind] == nil {
+			b.entries[kind] = make(map[string][]byte)
+		}
+		for k, v := range entries {
+			if v == nil {
+				delete(b.entries[kind], k)
+				continue
+			}
+			b.entries[kind][k] = v
+		}
+	}
+	return nil
+}
+
+func (b *fakeDeltaBackend) EnsureBefore(d time.Duration) {}
+
+func (b *fakeDeltaBackend) lastDeltaKeys() map[string][]string {
+	keys := make(map[string][]string)
+	for kind, entries := range b.deltas[len(b.deltas)-1].Entries {
+		for k := range entries {
+			keys[kind] = append(keys[kind], k)
+		}
+		sort.Strings(keys[kind])
+	}
+	return keys
+}
+
+func (ds *deltaSuite) TestFirstCheckpointIsFull(c *C) {
+	b := new(fakeDeltaBackend)
+	st := state.New(b)
+	st.Lock()
+	st.Set("a", 1)
+	chg := st.NewChange("install", "...")
+	chg.AddTask(st.NewTask("download", "..."))
+	st.Warnf("hello")
+	st.Unlock()
+
+	c.Assert(b.deltas, HasLen, 1)
+	c.Check(b.deltas[0].Full, Equals, true)
+	c.Check(b.lastDeltaKeys(), DeepEquals, map[string][]string{
+		state.EntriesMeta:     {state.MetaKey},
+		state.EntriesData:     {"a"},
+		state.EntriesChanges:  {"1"},
+		state.EntriesTasks:    {"1"},
+		state.EntriesWarnings: {"hello"},
+		// the change-update notice of the new change
+		state.EntriesNotices: {"1"},
+	})
+	c.Check(string(b.entries[state.EntriesData]["a"]), Equals, "1")
+}
+
+func (ds *deltaSuite) TestCheckpointOnlyWhatChanged(c *C) {
+	b := new(fakeDeltaBackend)
+	st := state.New(b)
+	st.Lock()
+	st.Set("a", 1)
+	st.Set("b", 2)
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "...")
+	t2 := st.NewTask("link", "...")
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+	st.Unlock()
+
+	st.Lock()
+	st.Set("b", 3)
+	t2.Set("foo", "bar")
+	st.Unlock()
+
+	c.Assert(b.deltas, HasLen, 2)
+	c.Check(b.deltas[1].Full, Equals, false)
+	c.Check(b.lastDeltaKeys(), DeepEquals, map[string][]string{
+		state.EntriesData:  {"b"},
+		state.EntriesTasks: {t2.ID()},
+	})
+
+	// removals are carried as nil entries
+	st.Lock()
+	st.Set("a", nil)
+	st.Unlock()
+
+	c.Check(b.lastDeltaKeys(), DeepEquals, map[string][]string{
+		state.EntriesData: {"a"},
+	})
+	c.Check(b.deltas[2].Entries[state.EntriesData]["a"], IsNil)
+	c.Check(b.entries[state.EntriesData], HasLen, 1)
+
+	// new changes and tasks bump the last ids
+	st.Lock()
+	t3 := st.NewTask("cleanup", "...")
+	chg.AddTask(t3)
+	st.Unlock()
+
+	c.Check(b.lastDeltaKeys(), DeepEquals, map[string][]string{
+		state.EntriesMeta:    {state.MetaKey},
+		state.EntriesChanges: {chg.ID()},
+		state.EntriesTasks:   {t3.ID()},
+	})
+}
+
+func (ds *deltaSuite) TestFailedCheckpointIsRetriedWhole(c *C) {
+	restore := state.MockCheckpointRetryDelay(time.Millisecond, time.Second)
+	defer restore()
+
+	b := new(fakeDeltaBackend)
+	st := state.New(b)
+	st.Lock()
+	st.Set("a", 1)
+	st.Unlock()
+
+	fail := 2
+	b.error = func() error {
+		if fail > 0 {
+			fail--
+			return errors.New("boom")
+		}
+		return nil
+	}
+	st.Lock()
+	st.Set("b", 2)
+	st.Unlock()
+	c.Assert(b.deltas, HasLen, 4)
+	c.Check(b.deltas[3], DeepEquals, b.deltas[1])
+
+	// the next delta is against what was eventually checkpointed
+	st.Lock()
+	st.Set("c", 3)
+	st.Unlock()
+	c.Check(b.lastDeltaKeys(), DeepEquals, map[string][]string{
+		state.EntriesData: {"c"},
+	})
+}
+
+func (ds *deltaSuite) TestCheckpointedEntriesMatchState(c *C) {
+	b := new(fakeDeltaBackend)
+	st := state.New(b)
+
+	check := func(comment string) {
+		st.Lock()
+		defer st.Unlock()
+		c.Check(b.entries, DeepEquals, st.Entries(), Commentf(comment))
+	}
+
+	st.Lock()
+	st.Set("a", 1)
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "...")
+	t2 := st.NewTask("link", "...")
+	chg.AddTask(t1)
+	st.Warnf("hello")
+	st.Unlock()
+	check("first checkpoint")
+
+	st.Lock()
+	t2.WaitFor(t1)
+	chg.AddTask(t2)
+	t1.SetProgress("downloading", 1, 10)
+	chg.Set("x", "y")
+	st.Unlock()
+	check("tasks and changes")
+
+	st.Lock()
+	t1.SetProgress("downloading", 5, 10)
+	st.Unlock()
+	st.Lock()
+	t1.Logf("hello")
+	t1.Clear("nope")
+	t1.SetStatus(state.DoneStatus)
+	t2.JoinLane(st.NewLane())
+	t2.At(time.Now().Add(time.Hour))
+	st.OkayWarnings(time.Now())
+	_, err := st.AddNotice(nil, state.ChangeUpdateNotice, "other", nil)
+	c.Assert(err, IsNil)
+	st.Unlock()
+	check("progress, status, lanes, warnings and notices")
+
+	st.Lock()
+	t2.SetStatus(state.DoneStatus)
+	c.Assert(chg.IsReady(), Equals, true)
+	st.RemoveWarning("hello")
+	st.DrainNotices(&state.NoticeFilter{Keys: []string{"other"}})
+	st.Unlock()
+	check("ready change")
+
+	st.Lock()
+	t1.SetClean()
+	t2.SetClean()
+	st.Unlock()
+	check("clean change")
+
+	st.Lock()
+	st.Prune(time.Now(), 0, time.Hour, 100)
+	c.Check(st.Changes(), HasLen, 0)
+	st.Set("a", nil)
+	st.Unlock()
+	check("pruned change")
+}
+
+func (ds *deltaSuite) TestReadStateEntries(c *C) {
+	b := new(fakeDeltaBackend)
+	st := state.New(b)
+	st.Lock()
+	st.Set("a", map[string]int{"x": 1})
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "...")
+	t2 := st.NewTask("link", "...")
+	t2.WaitFor(t1)
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+	st.Warnf("hello")
+	_, err := st.AddNotice(nil, state.ChangeUpdateNotice, chg.ID(), nil)
+	c.Assert(err, IsNil)
+	st.Unlock()
+
+	b2 := &fakeDeltaBackend{entries: b.entries}
+	st2, err := state.ReadStateEntries(b2, b.entries)
+	c.Assert(err, IsNil)
+
+	st2.Lock()
+	var a map[string]int
+	c.Check(st2.Get("a", &a), IsNil)
+	c.Check(a, DeepEquals, map[string]int{"x": 1})
+	chg2 := st2.Change(chg.ID())
+	c.Assert(chg2, NotNil)
+	c.Check(chg2.Tasks(), HasLen, 2)
+	c.Check(st2.Task(t2.ID()).WaitTasks(), HasLen, 1)
+	c.Check(st2.AllWarnings(), HasLen, 1)
+	c.Check(st2.Notices(nil), HasLen, 1)
+
+	// ids carry on from where they were
+	t3 := st2.NewTask("cleanup", "...")
+	c.Check(t3.ID(), Equals, "3")
+	chg2.AddTask(t3)
+	st2.Unlock()
+
+	c.Assert(b2.deltas, HasLen, 1)
+	c.Check(b2.deltas[0].Full, Equals, false)
+
+	// and only what changed is checkpointed
+	st2.Lock()
+	st2.Set("b", 2)
+	st2.Unlock()
+	c.Check(b2.lastDeltaKeys(), DeepEquals, map[string][]string{
+		state.EntriesData: {"b"},
+	})
+}
+
+func (ds *deltaSuite) TestReadStateEntriesEmpty(c *C) {
+	st, err := state.ReadStateEntries(nil, nil)
+	c.Assert(err, IsNil)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), HasLen, 0)
+	c.Check(st.NewChange("install", "...").ID(), Equals, "1")
+}
@@ -307,11 +307,16 @@ const (
 	// expired. The key for interfaces-requests-rule-update notices is the
 	// rule ID.
 	InterfacesRequestsRuleUpdateNotice NoticeType = "interfaces-requests-rule-update"
+
+	// Recorded whenever a quota group has been over its soft limits for the
+	// configured window and the soft limits action was taken. The key for
+	// quota-soft-limit notices is the quota group name.
+	QuotaSoftLimitNotice NoticeType = "quota-soft-limit"
 )
 
 func (t NoticeType) Valid() bool {
 	switch t {
-	case ChangeUpdateNotice, WarningNotice, RefreshInhibitNotice, SnapRunInhibitNotice, InterfacesRequestsPromptNotice, InterfacesRequestsRuleUpdateNotice:
+	case ChangeUpdateNotice, WarningNotice, RefreshInhibitNotice, SnapRunInhibitNotice, InterfacesRequestsPromptNotice, InterfacesRequestsRuleUpdateNotice, QuotaSoftLimitNotice:
 		return true
 	}
 	return false
@@ -410,6 +415,7 @@ func (s *State) AddNotice(userID *uint32, noticeType NoticeType, key string, opt
 		// Additional occurrence, update existing notice
 		newOrRepeated = notice.Reoccur(now, options.Data, options.RepeatAfter)
 	}
+	s.markDirty(EntriesNotices, notice.id)
 
 	if newOrRepeated {
 		s.noticeCond.Broadcast()
@@ -541,6 +547,7 @@ func (s *State) DrainNotices(filter *NoticeFilter) []*Notice {
 		notices = append(notices, n)
 	}
 	for _, k := range toRemove {
+		s.markDirty(EntriesNotices, s.notices[k].id)
 		delete(s.notices, k)
 	}
 	SortNotices(notices)
@@ -37,6 +37,7 @@ import (
 
 // A Backend is used by State to checkpoint on every unlock operation
 // and to mediate requests to ensure the state sooner or request restarts.
+// See also DeltaBackend.
 type Backend interface {
 	Checkpoint(data []byte) error
 	EnsureBefore(d time.Duration)
@@ -121,6 +122,13 @@ type State struct {
 	noticeCond *sync.Cond
 
 	modified bool
+	// checkpointed has the hashes of the entries last checkpointed
+	// through a DeltaBackend, if deltaCheckpointed
+	checkpointed      entryHashes
+	deltaCheckpointed bool
+	// dirty has the keys of the entries that may have changed since
+	// then, by kind
+	dirty map[string]map[string]bool
 
 	cache map[any]any
 
@@ -144,6 +152,8 @@ func New(backend Backend) *State {
 		warnings:            make(map[string]*Warning),
 		notices:             make(map[noticeKey]*Notice),
 		modified:            true,
+		checkpointed:        make(entryHashes),
+		dirty:               make(map[string]map[string]bool),
 		cache:               make(map[any]any),
 		pendingChangeByAttr: make(map[string]func(*Change) bool),
 		taskHandlers:        make(map[int]func(t *Task, old Status, new Status) bool),
@@ -228,6 +238,8 @@ func (s *State) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON makes State a json.Unmarshaller
 func (s *State) UnmarshalJSON(data []byte) error {
 	s.writing()
+	// all of it is to be checkpointed again
+	s.deltaCheckpointed = false
 	var unmarshalled marshalledState
 	err := json.Unmarshal(data, &unmarshalled)
 	if err != nil {
@@ -298,11 +310,26 @@ func (s *State) Unlock() {
 		return
 	}
 
-	data := s.checkpointData()
+	var checkpoint func() error
+	if db, ok := s.backend.(DeltaBackend); ok {
+		delta, hashes := s.checkpointDelta()
+		checkpoint = func() error {
+			if err := db.CheckpointDelta(delta); err != nil {
+				return err
+			}
+			s.deltaCheckpointedAs(delta, hashes)
+			return nil
+		}
+	} else {
+		data := s.checkpointData()
+		checkpoint = func() error {
+			return s.backend.Checkpoint(data)
+		}
+	}
 	var err error
 	start := time.Now()
 	for time.Since(start) <= unlockCheckpointRetryMaxTime {
-		if err = s.backend.Checkpoint(data); err == nil {
+		if err = checkpoint(); err == nil {
 			s.modified = false
 			return
 		}
@@ -362,6 +389,7 @@ func (s *State) Has(key string) bool {
 func (s *State) Set(key string, value any) {
 	s.writing()
 	s.data.set(key, value)
+	s.markDirty(EntriesData, key)
 }
 
 // Cached returns the cached value associated with the provided key.
@@ -389,6 +417,7 @@ func (s *State) NewChange(kind, summary string) *Change {
 	id := strconv.Itoa(s.lastChangeId)
 	chg := newChange(s, id, kind, summary)
 	s.changes[id] = chg
+	s.markDirty(EntriesChanges, id)
 	// Add change-update notice for newly spawned change
 	// NOTE: Implies State.writing()
 	if err := chg.addNotice(); err != nil {
@@ -429,6 +458,7 @@ func (s *State) NewTask(kind, summary string) *Task {
 	id := strconv.Itoa(s.lastTaskId)
 	t := newTask(s, id, kind, summary)
 	s.tasks[id] = t
+	s.markDirty(EntriesTasks, id)
 	return t
 }
 
@@ -539,6 +569,7 @@ NextChange:
 			if spawnTime.Before(pruneLimit) && len(chg.Tasks()) == 0 {
 				chg.Abort()
 				delete(s.changes, chg.ID())
+				s.markDirty(EntriesChanges, chg.ID())
 			} else if spawnTime.Before(abortLimit) {
 				for attr, pending := range s.pendingChangeByAttr {
 					if chg.Has(attr) && pending(chg) {
@@ -554,8 +585,10 @@ NextChange:
 			s.writing()
 			for _, t := range chg.Tasks() {
 				delete(s.tasks, t.ID())
+				s.markDirty(EntriesTasks, t.ID())
 			}
 			delete(s.changes, chg.ID())
+			s.markDirty(EntriesChanges, chg.ID())
 			readyChangesCount--
 		}
 	}
@@ -565,6 +598,7 @@ NextChange:
 		if t.Change() == nil && t.SpawnTime().Before(pruneLimit) {
 			s.writing()
 			delete(s.tasks, tid)
+			s.markDirty(EntriesTasks, tid)
 		}
 	}
 }
@@ -575,6 +609,7 @@ func (s *State) pruneWarnings(now time.Time) {
 	for k, w := range s.warnings {
 		if w.ExpiredBefore(now) {
 			delete(s.warnings, k)
+			s.markDirty(EntriesWarnings, k)
 		}
 	}
 }
@@ -585,6 +620,7 @@ func (s *State) pruneNotices(now time.Time) {
 	for k, n := range s.notices {
 		if n.Expired(now) {
 			delete(s.notices, k)
+			s.markDirty(EntriesNotices, n.id)
 		}
 	}
 }
@@ -673,6 +709,8 @@ func ReadState(backend Backend, r io.Reader) (*State, error) {
 	s.backend = backend
 	s.noticeCond = sync.NewCond(s.noticesMu.RLocker())
 	s.modified = false
+	s.checkpointed = make(entryHashes)
+	s.dirty = make(map[string]map[string]bool)
 	s.cache = make(map[any]any)
 	s.pendingChangeByAttr = make(map[string]func(*Change) bool)
 	s.changeHandlers = make(map[int]func(chg *Change, old Status, new Status))
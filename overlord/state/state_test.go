@@ -593,6 +593,8 @@ func (ss *stateSuite) TestEmptyStateDataAndCheckpointReadAndSet(c *C) {
 		"tasks",
 		"warnings",
 		"notices",
+		"checkpointed",
+		"dirty",
 		"cache",
 		"pendingChangeByAttr",
 		"taskHandlers",
@@ -0,0 +1,190 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package statedb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// The compat bucket keeps track of the state file written from the
+// database for a snapd that predates it, e.g. after a revert of snapd.
+var compatBucket = []byte("compat")
+
+var (
+	compatStateFileHashKey = []byte("state-file-sha256")
+	// compatStateFileStaleKey is set once the state changed since the
+	// state file was written
+	compatStateFileStaleKey = []byte("state-file-stale")
+)
+
+func hashStateFile(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func markStateFileStale(tx *bbolt.Tx) error {
+	bucket, err := tx.CreateBucketIfNotExists(compatBucket)
+	if err != nil {
+		return err
+	}
+	if bucket.Get(compatStateFileStaleKey) != nil {
+		return nil
+	}
+	return bucket.Put(compatStateFileStaleKey, []byte("true"))
+}
+
+// WriteStateFile writes the state kept in the database at path to
+// stateFile, see DB.WriteStateFile.
+func WriteStateFile(path, stateFile string) error {
+	return withDB(path, false, true, func(db *DB) error {
+		return db.WriteStateFile(stateFile)
+	})
+}
+
+// WriteStateFile writes the state kept in the database to stateFile, in the
+// format of a snapd that predates the state database, so that such a snapd
+// finds the current state when snapd is reverted to it. Nothing is written
+// if the state did not change since stateFile was last written. The
+// database records what was written, see StateFileChanged.
+func (db *DB) WriteStateFile(stateFile string) error {
+	var stale bool
+	var entries map[string]map[string][]byte
+	err := db.db.View(func(tx *bbolt.Tx) error {
+		if bucket := tx.Bucket(compatBucket); bucket != nil {
+			stale = bucket.Get(compatStateFileStaleKey) != nil
+		}
+		if !stale && osutil.FileExists(stateFile) {
+			return nil
+		}
+		var err error
+		entries, err = readEntries(tx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read state database: %v", err)
+	}
+	if entries == nil {
+		return nil
+	}
+	data := state.MarshalEntries(entries)
+	if err := osutil.AtomicWriteFile(stateFile, data, 0600, 0); err != nil {
+		return fmt.Errorf("cannot write state file: %v", err)
+	}
+	return db.recordStateFile(data)
+}
+
+func (db *DB) recordStateFile(data []byte) error {
+	err := db.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(compatBucket)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Delete(compatStateFileStaleKey); err != nil {
+			return err
+		}
+		return bucket.Put(compatStateFileHashKey, []byte(hashStateFile(data)))
+	})
+	if err != nil {
+		return fmt.Errorf("cannot write state database: %v", err)
+	}
+	return nil
+}
+
+// MigrateStateFile returns the state read from stateFile, see
+// DB.MigrateStateFile, after writing it into the database at path.
+func MigrateStateFile(backend state.Backend, path, stateFile string) (s *state.State, err error) {
+	err = withDB(path, false, false, func(db *DB) error {
+		s, err = db.MigrateStateFile(backend, stateFile)
+		return err
+	})
+	return s, err
+}
+
+// MigrateStateFile returns the state read from stateFile, as written by a
+// snapd that predates the state database, after writing all of it into the
+// database, replacing anything in it. The state file is left in place for
+// such a snapd, and recorded as if written by WriteStateFile.
+func (db *DB) MigrateStateFile(backend state.Backend, stateFile string) (*state.State, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the state file: %s", err)
+	}
+	s, err := state.ReadState(backend, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	entries := s.Entries()
+	s.Unlock()
+	if err := db.Write(&state.Delta{Full: true, Entries: entries}); err != nil {
+		return nil, err
+	}
+	if err := db.recordStateFile(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// StateFileChanged returns whether stateFile differs from what was last
+// written to it from the database at path, see DB.StateFileChanged.
+func StateFileChanged(path, stateFile string) (changed bool, err error) {
+	if !osutil.FileExists(stateFile) {
+		return false, nil
+	}
+	err = withDB(path, true, false, func(db *DB) error {
+		changed, err = db.StateFileChanged(stateFile)
+		return err
+	})
+	return changed, err
+}
+
+// StateFileChanged returns whether stateFile differs from what was last
+// written to it from the database by WriteStateFile, which means that a
+// snapd that predates the state database ran since and the database is
+// stale. A missing state file is not considered changed.
+func (db *DB) StateFileChanged(stateFile string) (bool, error) {
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot read the state file: %v", err)
+	}
+	var written string
+	err = db.db.View(func(tx *bbolt.Tx) error {
+		if bucket := tx.Bucket(compatBucket); bucket != nil {
+			written = string(bucket.Get(compatStateFileHashKey))
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("cannot read state database: %v", err)
+	}
+	return hashStateFile(data) != written, nil
+}
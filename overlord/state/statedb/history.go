@@ -0,0 +1,408 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package statedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// The history bucket keeps the checkpoints of the recent past, each recorded
+// as the entries it replaced, so that walking it back from the current
+// entries gives the state as it was at an earlier time. Each record is a
+// bucket keyed by sequence number, holding the times of the checkpoint
+// and a bucket per kind of the replaced entries, as raw values.
+var historyBucket = []byte("history")
+
+// historyTimesKey holds the times of a history record, see
+// encodeHistoryTimes.
+var historyTimesKey = []byte("times")
+
+var (
+	// historyAge is how long checkpoints are kept in the history.
+	historyAge = 24 * time.Hour
+	// historyInterval is the period over which consecutive checkpoints
+	// are coalesced in the history, so that the state can be walked back
+	// with that granularity only, and the previous value of entries
+	// changing at every checkpoint is recorded once per period.
+	historyInterval = time.Minute
+)
+
+// ErrNoHistory is returned when asking for the state at a time the state
+// history does not go back to.
+var ErrNoHistory = errors.New("no state history")
+
+// historyRecord is the in-memory copy of the last record of the history,
+// so that coalescing a checkpoint in it needs neither to read nor to
+// rewrite what it already holds.
+type historyRecord struct {
+	seq uint64
+	// start is when the first of the checkpoints coalesced in the record
+	// was made, and time when the last one was.
+	start time.Time
+	time  time.Time
+	// full is set for a checkpoint that replaced everything before it,
+	// past which the history does not go.
+	full bool
+	// recorded has the keys of the entries recorded, by kind.
+	recorded map[string]map[string]bool
+}
+
+func historyKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// encodeHistoryTimes encodes the start and time of a history record, in
+// nanoseconds since the epoch, followed by whether it is full.
+func encodeHistoryTimes(start, t time.Time, full bool) []byte {
+	v := make([]byte, 17)
+	binary.BigEndian.PutUint64(v, uint64(start.UnixNano()))
+	binary.BigEndian.PutUint64(v[8:], uint64(t.UnixNano()))
+	if full {
+		v[16] = 1
+	}
+	return v
+}
+
+func decodeHistoryTimes(v []byte) (start, t time.Time, full bool, err error) {
+	if len(v) != 17 {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("cannot decode state history: invalid record times")
+	}
+	start = time.Unix(0, int64(binary.BigEndian.Uint64(v))).UTC()
+	t = time.Unix(0, int64(binary.BigEndian.Uint64(v[8:]))).UTC()
+	return start, t, v[16] == 1, nil
+}
+
+// historyRecordBucket returns the bucket of the history record with the
+// given key, along with its times.
+func historyRecordBucket(bucket *bbolt.Bucket, key []byte) (rb *bbolt.Bucket, start, t time.Time, full bool, err error) {
+	rb = bucket.Bucket(key)
+	if rb == nil {
+		return nil, time.Time{}, time.Time{}, false, fmt.Errorf("cannot decode state history: invalid record")
+	}
+	start, t, full, err = decodeHistoryTimes(rb.Get(historyTimesKey))
+	return rb, start, t, full, err
+}
+
+// Replaced entries are recorded with a leading byte telling whether they
+// were present, to tell an empty value from an entry that was added.
+const (
+	historyAbsent  = 0
+	historyPresent = 1
+)
+
+func encodeHistoryValue(v []byte) []byte {
+	if v == nil {
+		return []byte{historyAbsent}
+	}
+	return append([]byte{historyPresent}, v...)
+}
+
+func decodeHistoryValue(v []byte) []byte {
+	if len(v) == 0 || v[0] != historyPresent {
+		return nil
+	}
+	return copyValue(v[1:])
+}
+
+func clearHistory(tx *bbolt.Tx) error {
+	if tx.Bucket(historyBucket) == nil {
+		return nil
+	}
+	return tx.DeleteBucket(historyBucket)
+}
+
+// loadLastHistoryRecord returns the last record of the history, if any.
+func loadLastHistoryRecord(tx *bbolt.Tx) (*historyRecord, error) {
+	bucket := tx.Bucket(historyBucket)
+	if bucket == nil {
+		return nil, nil
+	}
+	k, _ := bucket.Cursor().Last()
+	if k == nil {
+		return nil, nil
+	}
+	rb, start, t, full, err := historyRecordBucket(bucket, k)
+	if err != nil {
+		return nil, err
+	}
+	rec := &historyRecord{
+		seq:      binary.BigEndian.Uint64(k),
+		start:    start,
+		time:     t,
+		full:     full,
+		recorded: make(map[string]map[string]bool),
+	}
+	for _, kind := range state.EntryKinds {
+		kb := rb.Bucket([]byte(kind))
+		if kb == nil {
+			continue
+		}
+		rec.recorded[kind] = make(map[string]bool)
+		err := kb.ForEach(func(k, _ []byte) error {
+			rec.recorded[kind][string(k)] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
+
+// recordHistory records a checkpoint made at the given time, replacing the
+// given previous entries, by kind and key, nil for those it added. The
+// checkpoint is coalesced in the given last record of the history if
+// possible, otherwise appended as a new record. The last record of the
+// history is returned.
+func recordHistory(tx *bbolt.Tx, last *historyRecord, now time.Time, full bool, previous map[string]map[string][]byte) (*historyRecord, error) {
+	bucket, err := tx.CreateBucketIfNotExists(historyBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	var rb *bbolt.Bucket
+	rec := last
+	if rec != nil && !full && rec.coalesces(now) {
+		rb = bucket.Bucket(historyKey(rec.seq))
+		if rb == nil {
+			return nil, fmt.Errorf("internal error: cannot find state history record %d", rec.seq)
+		}
+	} else {
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return nil, err
+		}
+		rb, err = bucket.CreateBucket(historyKey(seq))
+		if err != nil {
+			return nil, err
+		}
+		rec = &historyRecord{
+			seq:      seq,
+			start:    now,
+			full:     full,
+			recorded: make(map[string]map[string]bool),
+		}
+	}
+	rec.time = now
+	if err := rb.Put(historyTimesKey, encodeHistoryTimes(rec.start, rec.time, rec.full)); err != nil {
+		return nil, err
+	}
+	for kind, kindPrevious := range previous {
+		kb, err := rb.CreateBucketIfNotExists([]byte(kind))
+		if err != nil {
+			return nil, err
+		}
+		if rec.recorded[kind] == nil {
+			rec.recorded[kind] = make(map[string]bool)
+		}
+		for k, v := range kindPrevious {
+			// the value from before the first checkpoint of the
+			// period is the one to go back to
+			if rec.recorded[kind][k] {
+				continue
+			}
+			if err := kb.Put([]byte(k), encodeHistoryValue(v)); err != nil {
+				return nil, err
+			}
+			rec.recorded[kind][k] = true
+		}
+	}
+	if err := expireHistory(bucket, now.Add(-historyAge)); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// coalesces returns whether a checkpoint made at the given time can be
+// coalesced in the record.
+func (rec *historyRecord) coalesces(t time.Time) bool {
+	return !rec.full && !t.Before(rec.time) && t.Sub(rec.start) < historyInterval
+}
+
+// expireHistory drops the records of the checkpoints made before the given
+// time.
+func expireHistory(bucket *bbolt.Bucket, before time.Time) error {
+	var expired [][]byte
+	c := bucket.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		_, _, t, _, err := historyRecordBucket(bucket, k)
+		if err != nil {
+			return err
+		}
+		if !t.Before(before) {
+			break
+		}
+		expired = append(expired, copyValue(k))
+	}
+	for _, k := range expired {
+		if err := bucket.DeleteBucket(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewindEntries rewinds in place the given current entries to the last
+// checkpoint at or before t.
+func rewindEntries(tx *bbolt.Tx, entries map[string]map[string][]byte, t time.Time) error {
+	bucket := tx.Bucket(historyBucket)
+	if bucket == nil {
+		return ErrNoHistory
+	}
+	var oldest time.Time
+	c := bucket.Cursor()
+	for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+		rb, _, recTime, full, err := historyRecordBucket(bucket, k)
+		if err != nil {
+			return err
+		}
+		if !recTime.After(t) {
+			return nil
+		}
+		oldest = recTime
+		if full {
+			break
+		}
+		for _, kind := range state.EntryKinds {
+			kb := rb.Bucket([]byte(kind))
+			if kb == nil {
+				continue
+			}
+			if entries[kind] == nil {
+				entries[kind] = make(map[string][]byte)
+			}
+			err := kb.ForEach(func(k, v []byte) error {
+				if previous := decodeHistoryValue(v); previous != nil {
+					entries[kind][string(k)] = previous
+				} else {
+					delete(entries[kind], string(k))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if oldest.IsZero() {
+		return ErrNoHistory
+	}
+	return fmt.Errorf("%w before %s", ErrNoHistory, oldest.Format(time.RFC3339))
+}
+
+// ReadEntriesAt returns the entries in the database at path as they were
+// checkpointed at the given time, by kind and key.
+func ReadEntriesAt(path string, t time.Time) (entries map[string]map[string][]byte, err error) {
+	err = withDB(path, true, true, func(db *DB) error {
+		entries, err = db.ReadEntriesAt(t)
+		return err
+	})
+	return entries, err
+}
+
+// ReadEntriesAt returns the entries in the database as they were
+// checkpointed at the given time, by kind and key.
+func (db *DB) ReadEntriesAt(t time.Time) (map[string]map[string][]byte, error) {
+	var entries map[string]map[string][]byte
+	err := db.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		entries, err = readEntries(tx)
+		if err != nil {
+			return err
+		}
+		return rewindEntries(tx, entries, t)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read state database at %s: %w", t.Format(time.RFC3339), err)
+	}
+	return entries, nil
+}
+
+// ReadStateAt returns the state kept in the database at path as it was
+// checkpointed at the given time.
+func ReadStateAt(backend state.Backend, path string, t time.Time) (*state.State, error) {
+	entries, err := ReadEntriesAt(path, t)
+	if err != nil {
+		return nil, err
+	}
+	return state.ReadStateEntries(backend, entries)
+}
+
+// Rollback restores the state in the database at path to what was
+// checkpointed at the given time. The rollback is itself recorded in the
+// state history, so it can be undone in turn. It must not be used while
+// snapd is running.
+func Rollback(path string, t time.Time) error {
+	return withDB(path, false, true, func(db *DB) error {
+		return db.rollback(t)
+	})
+}
+
+func (db *DB) rollback(t time.Time) error {
+	err := db.update(func(tx *bbolt.Tx, last *historyRecord) (*historyRecord, error) {
+		current, err := readEntries(tx)
+		if err != nil {
+			return nil, err
+		}
+		target, err := readEntries(tx)
+		if err != nil {
+			return nil, err
+		}
+		if err := rewindEntries(tx, target, t); err != nil {
+			return nil, err
+		}
+
+		delta := &state.Delta{Entries: make(map[string]map[string][]byte)}
+		for kind, targetEntries := range target {
+			changed := make(map[string][]byte)
+			for k, v := range targetEntries {
+				if old, ok := current[kind][k]; !ok || !bytes.Equal(old, v) {
+					changed[k] = v
+				}
+			}
+			for k := range current[kind] {
+				if _, ok := targetEntries[k]; !ok {
+					changed[k] = nil
+				}
+			}
+			if len(changed) > 0 {
+				delta.Entries[kind] = changed
+			}
+		}
+		if len(delta.Entries) == 0 {
+			return last, nil
+		}
+		return applyDelta(tx, last, delta, timeNow())
+	})
+	if err != nil {
+		return fmt.Errorf("cannot roll back state database to %s: %w", t.Format(time.RFC3339), err)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package statedb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type historySuite struct {
+	testutil.BaseTest
+
+	path string
+	now  time.Time
+}
+
+var _ = Suite(&historySuite{})
+
+var t0 = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func (s *historySuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.path = filepath.Join(c.MkDir(), "state.db")
+	s.now = t0
+	s.AddCleanup(statedb.MockTimeNow(func() time.Time { return s.now }))
+}
+
+// write writes the given data entries a minute after the last write.
+func (s *historySuite) write(c *C, full bool, data map[string][]byte) {
+	s.now = s.now.Add(time.Minute)
+	err := statedb.Write(s.path, &state.Delta{
+		Full:    full,
+		Entries: map[string]map[string][]byte{state.EntriesData: data},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *historySuite) dataAt(c *C, t time.Time) map[string][]byte {
+	entries, err := statedb.ReadEntriesAt(s.path, t)
+	c.Assert(err, IsNil)
+	return entries[state.EntriesData]
+}
+
+func (s *historySuite) TestReadEntriesAt(c *C) {
+	// 12:01
+	s.write(c, true, map[string][]byte{"a": []byte("1")})
+	// 12:02
+	s.write(c, false, map[string][]byte{"a": []byte("2"), "b": []byte("1")})
+	// 12:03
+	s.write(c, false, map[string][]byte{"a": nil})
+
+	c.Check(s.dataAt(c, t0.Add(time.Minute)), DeepEquals, map[string][]byte{
+		"a": []byte("1"),
+	})
+	c.Check(s.dataAt(c, t0.Add(150*time.Second)), DeepEquals, map[string][]byte{
+		"a": []byte("2"),
+		"b": []byte("1"),
+	})
+	c.Check(s.dataAt(c, t0.Add(time.Hour)), DeepEquals, map[string][]byte{
+		"b": []byte("1"),
+	})
+
+	// the history does not go past the full write
+	_, err := statedb.ReadEntriesAt(s.path, t0)
+	c.Check(err, ErrorMatches, `cannot read state database at 2026-10-01T12:00:00Z: no state history before 2026-10-01T12:01:00Z`)
+	c.Check(errors.Is(err, statedb.ErrNoHistory), Equals, true)
+
+	// a full write starts it over
+	s.write(c, true, map[string][]byte{"c": []byte("1")})
+	_, err = statedb.ReadEntriesAt(s.path, t0.Add(3*time.Minute))
+	c.Check(err, ErrorMatches, `.*no state history before 2026-10-01T12:04:00Z`)
+}
+
+func (s *historySuite) TestHistoryIsBounded(c *C) {
+	defer statedb.MockHistoryAge(150 * time.Second)()
+
+	s.write(c, true, map[string][]byte{"a": []byte("0")})
+	for _, v := range []string{"1", "2", "3", "4"} {
+		s.write(c, false, map[string][]byte{"a": []byte(v)})
+	}
+
+	// the checkpoints of the last 150s are kept, going back to the state
+	// before the oldest of them
+	c.Check(s.dataAt(c, t0.Add(3*time.Minute)), DeepEquals, map[string][]byte{
+		"a": []byte("2"),
+	})
+	_, err := statedb.ReadEntriesAt(s.path, t0.Add(150*time.Second))
+	c.Check(err, ErrorMatches, `.*no state history before 2026-10-01T12:03:00Z`)
+}
+
+func (s *historySuite) TestHistoryCoalesced(c *C) {
+	s.write(c, true, map[string][]byte{"a": []byte("0")})
+	// 12:02:00, 12:02:20 and 12:02:40 are coalesced
+	s.write(c, false, map[string][]byte{"a": []byte("1")})
+	for _, v := range []string{"2", "3"} {
+		s.now = s.now.Add(20 * time.Second)
+		err := statedb.Write(s.path, &state.Delta{
+			Entries: map[string]map[string][]byte{state.EntriesData: {"a": []byte(v), "b": []byte(v)}},
+		})
+		c.Assert(err, IsNil)
+	}
+	// 12:03:40
+	s.write(c, false, map[string][]byte{"b": nil})
+
+	// the coalesced checkpoints can only be walked back as a whole
+	c.Check(s.dataAt(c, t0.Add(90*time.Second)), DeepEquals, map[string][]byte{
+		"a": []byte("0"),
+	})
+	c.Check(s.dataAt(c, t0.Add(150*time.Second)), DeepEquals, map[string][]byte{
+		"a": []byte("0"),
+	})
+	c.Check(s.dataAt(c, t0.Add(3*time.Minute)), DeepEquals, map[string][]byte{
+		"a": []byte("3"),
+		"b": []byte("3"),
+	})
+	c.Check(s.dataAt(c, t0.Add(time.Hour)), DeepEquals, map[string][]byte{
+		"a": []byte("3"),
+	})
+}
+
+func (s *historySuite) TestHistoryCoalescedAcrossOpen(c *C) {
+	db, err := statedb.Open(s.path)
+	c.Assert(err, IsNil)
+	writeData := func(data map[string][]byte) {
+		s.now = s.now.Add(20 * time.Second)
+		err := db.Write(&state.Delta{
+			Entries: map[string]map[string][]byte{state.EntriesData: data},
+		})
+		c.Assert(err, IsNil)
+	}
+
+	// 12:01
+	s.write(c, true, map[string][]byte{"a": []byte("0")})
+	// 12:01:20 and 12:01:40, coalesced in memory
+	writeData(map[string][]byte{"a": []byte("1")})
+	writeData(map[string][]byte{"a": []byte("2"), "b": []byte("2")})
+	c.Assert(db.Close(), IsNil)
+
+	// 12:02:00, coalesced after reading the last record back
+	s.now = s.now.Add(20 * time.Second)
+	err = statedb.Write(s.path, &state.Delta{
+		Entries: map[string]map[string][]byte{state.EntriesData: {"b": []byte("3")}},
+	})
+	c.Assert(err, IsNil)
+	// 12:03:00, recorded apart
+	s.write(c, false, map[string][]byte{"a": nil})
+
+	c.Check(s.dataAt(c, t0.Add(110*time.Second)), DeepEquals, map[string][]byte{
+		"a": []byte("0"),
+	})
+	c.Check(s.dataAt(c, t0.Add(150*time.Second)), DeepEquals, map[string][]byte{
+		"a": []byte("2"),
+		"b": []byte("3"),
+	})
+	c.Check(s.dataAt(c, t0.Add(time.Hour)), DeepEquals, map[string][]byte{
+		"b": []byte("3"),
+	})
+}
+
+func (s *historySuite) TestReadEntriesAtMissing(c *C) {
+	_, err := statedb.ReadEntriesAt(s.path, t0)
+	c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+	c.Check(s.path, testutil.FileAbsent)
+}
+
+func (s *historySuite) TestRollback(c *C) {
+	s.write(c, true, map[string][]byte{"a": []byte("1")})
+	s.write(c, false, map[string][]byte{"a": []byte("2"), "b": []byte("1")})
+	s.write(c, false, map[string][]byte{"c": []byte("1")})
+
+	s.now = t0.Add(time.Hour)
+	err := statedb.Rollback(s.path, t0.Add(time.Minute))
+	c.Assert(err, IsNil)
+
+	entries, err := statedb.ReadEntries(s.path)
+	c.Assert(err, IsNil)
+	c.Check(entries[state.EntriesData], DeepEquals, map[string][]byte{
+		"a": []byte("1"),
+	})
+
+	// the rollback can be undone
+	s.now = t0.Add(2 * time.Hour)
+	err = statedb.Rollback(s.path, t0.Add(30*time.Minute))
+	c.Assert(err, IsNil)
+	entries, err = statedb.ReadEntries(s.path)
+	c.Assert(err, IsNil)
+	c.Check(entries[state.EntriesData], DeepEquals, map[string][]byte{
+		"a": []byte("2"),
+		"b": []byte("1"),
+		"c": []byte("1"),
+	})
+}
+
+func (s *historySuite) TestRollbackTooFarBack(c *C) {
+	s.write(c, true, map[string][]byte{"a": []byte("1")})
+	s.write(c, false, map[string][]byte{"a": []byte("2")})
+
+	err := statedb.Rollback(s.path, t0)
+	c.Check(err, ErrorMatches, `cannot roll back state database to 2026-10-01T12:00:00Z: no state history before 2026-10-01T12:01:00Z`)
+
+	// nothing changed
+	entries, err := statedb.ReadEntries(s.path)
+	c.Assert(err, IsNil)
+	c.Check(entries[state.EntriesData], DeepEquals, map[string][]byte{
+		"a": []byte("2"),
+	})
+}
+
+func (s *historySuite) TestReadStateAt(c *C) {
+	b := &dbBackend{path: s.path}
+	st := state.New(b)
+	st.Lock()
+	st.Set("a", 1)
+	st.Unlock()
+
+	s.now = s.now.Add(time.Minute)
+	st.Lock()
+	st.Set("a", 2)
+	chg := st.NewChange("install", "...")
+	chg.AddTask(st.NewTask("download", "..."))
+	st.Unlock()
+
+	st1, err := statedb.ReadStateAt(nil, s.path, t0)
+	c.Assert(err, IsNil)
+	st1.Lock()
+	defer st1.Unlock()
+	var a int
+	c.Check(st1.Get("a", &a), IsNil)
+	c.Check(a, Equals, 1)
+	c.Check(st1.Changes(), HasLen, 0)
+}
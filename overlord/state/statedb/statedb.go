@@ -0,0 +1,308 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package statedb implements the keyed store of the system state, a bbolt
+// database with a bucket per kind of state entry, so that checkpointing
+// only writes the entries that changed.
+package statedb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	// openTimeout is how long to wait for a database kept open by
+	// another process, e.g. a snapd stopping
+	openTimeout = 1 * time.Second
+	// keepOpenTimeout is the same for a database to keep open, which
+	// might be read by another process for a moment, e.g. with snap
+	// debug state
+	keepOpenTimeout = 30 * time.Second
+)
+
+var timeNow = time.Now
+
+// A DB is a state database kept open, as by snapd while it runs, so that
+// checkpointing does not need to open it every time. Other processes cannot
+// open the database meanwhile, but the functions of the package taking the
+// path of the database use the DB kept open by the process, if any.
+type DB struct {
+	path string
+	db   *bbolt.DB
+
+	// historyMu serializes the writes recording checkpoints in the
+	// state history, along with the in-memory copy of its last record.
+	historyMu         sync.Mutex
+	lastHistory       *historyRecord
+	lastHistoryLoaded bool
+}
+
+var (
+	openDBsMu sync.Mutex
+	openDBs   = make(map[string]*DB)
+)
+
+func open(path string, readOnly bool, timeout time.Duration) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{
+		Timeout:  timeout,
+		ReadOnly: readOnly,
+	})
+	if errors.Is(err, bbolt.ErrTimeout) {
+		return nil, fmt.Errorf("cannot open state database: it is in use, is snapd running?")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open state database: %w", err)
+	}
+	return db, nil
+}
+
+// Open opens the state database at path, creating it if needed, and keeps
+// it open until Close.
+func Open(path string) (*DB, error) {
+	path = filepath.Clean(path)
+	openDBsMu.Lock()
+	defer openDBsMu.Unlock()
+	if openDBs[path] != nil {
+		return nil, fmt.Errorf("internal error: state database %s is already open", path)
+	}
+	db, err := open(path, false, keepOpenTimeout)
+	if err != nil {
+		return nil, err
+	}
+	sdb := &DB{path: path, db: db}
+	openDBs[path] = sdb
+	return sdb, nil
+}
+
+// Close closes the database.
+func (db *DB) Close() error {
+	openDBsMu.Lock()
+	defer openDBsMu.Unlock()
+	if openDBs[db.path] == db {
+		delete(openDBs, db.path)
+	}
+	return db.db.Close()
+}
+
+// withDB calls f with the database at path, the DB kept open by the process
+// if any, or the database opened for the duration of the call. Unless
+// mustExist is false, a missing database is an error, rather than created.
+func withDB(path string, readOnly, mustExist bool, f func(db *DB) error) error {
+	path = filepath.Clean(path)
+	openDBsMu.Lock()
+	sdb := openDBs[path]
+	openDBsMu.Unlock()
+	if sdb != nil {
+		return f(sdb)
+	}
+	if mustExist {
+		// bbolt would otherwise create it
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("cannot open state database: %w", err)
+		}
+	}
+	db, err := open(path, readOnly, openTimeout)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return f(&DB{path: path, db: db})
+}
+
+// Write applies the given delta of the state to the database at path,
+// creating it if needed. See DB.Write.
+func Write(path string, delta *state.Delta) error {
+	if !delta.Full && len(delta.Entries) == 0 {
+		return nil
+	}
+	return withDB(path, false, false, func(db *DB) error {
+		return db.Write(delta)
+	})
+}
+
+// Write applies the given delta of the state to the database. The delta is
+// applied as a whole or not at all, and recorded in the state history. A
+// full delta also starts the history over.
+func (db *DB) Write(delta *state.Delta) error {
+	if !delta.Full && len(delta.Entries) == 0 {
+		return nil
+	}
+	err := db.update(func(tx *bbolt.Tx, last *historyRecord) (*historyRecord, error) {
+		return applyDelta(tx, last, delta, timeNow())
+	})
+	if err != nil {
+		return fmt.Errorf("cannot write state database: %v", err)
+	}
+	return nil
+}
+
+// update calls f in a read-write transaction of the database, with the last
+// record of the state history, which f returns as updated.
+func (db *DB) update(f func(tx *bbolt.Tx, last *historyRecord) (*historyRecord, error)) error {
+	db.historyMu.Lock()
+	defer db.historyMu.Unlock()
+	err := db.db.Update(func(tx *bbolt.Tx) error {
+		if !db.lastHistoryLoaded {
+			last, err := loadLastHistoryRecord(tx)
+			if err != nil {
+				return err
+			}
+			db.lastHistory = last
+			db.lastHistoryLoaded = true
+		}
+		last, err := f(tx, db.lastHistory)
+		if err != nil {
+			return err
+		}
+		db.lastHistory = last
+		return nil
+	})
+	if err != nil {
+		// the last record might have been updated in memory only
+		db.lastHistory = nil
+		db.lastHistoryLoaded = false
+	}
+	return err
+}
+
+func applyDelta(tx *bbolt.Tx, last *historyRecord, delta *state.Delta, now time.Time) (*historyRecord, error) {
+	if delta.Full {
+		// the history does not go past a full write
+		if err := clearHistory(tx); err != nil {
+			return nil, err
+		}
+		last = nil
+	}
+	previous := make(map[string]map[string][]byte)
+	for _, kind := range state.EntryKinds {
+		name := []byte(kind)
+		if delta.Full && tx.Bucket(name) != nil {
+			if err := tx.DeleteBucket(name); err != nil {
+				return nil, err
+			}
+		}
+		bucket, err := tx.CreateBucketIfNotExists(name)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range delta.Entries[kind] {
+			if !delta.Full {
+				if previous[kind] == nil {
+					previous[kind] = make(map[string][]byte)
+				}
+				// values are only valid during the transaction
+				previous[kind][k] = copyValue(bucket.Get([]byte(k)))
+			}
+			if v == nil {
+				err = bucket.Delete([]byte(k))
+			} else {
+				err = bucket.Put([]byte(k), v)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := markStateFileStale(tx); err != nil {
+		return nil, err
+	}
+	return recordHistory(tx, last, now, delta.Full, previous)
+}
+
+func copyValue(v []byte) []byte {
+	if v == nil {
+		return nil
+	}
+	return append([]byte{}, v...)
+}
+
+// ReadEntries returns all the entries in the database at path, by kind and
+// key.
+func ReadEntries(path string) (entries map[string]map[string][]byte, err error) {
+	err = withDB(path, true, true, func(db *DB) error {
+		entries, err = db.ReadEntries()
+		return err
+	})
+	return entries, err
+}
+
+// ReadEntries returns all the entries in the database, by kind and key.
+func (db *DB) ReadEntries() (map[string]map[string][]byte, error) {
+	var entries map[string]map[string][]byte
+	err := db.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		entries, err = readEntries(tx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read state database: %v", err)
+	}
+	return entries, nil
+}
+
+func readEntries(tx *bbolt.Tx) (map[string]map[string][]byte, error) {
+	entries := make(map[string]map[string][]byte, len(state.EntryKinds))
+	for _, kind := range state.EntryKinds {
+		kindEntries := make(map[string][]byte)
+		entries[kind] = kindEntries
+		bucket := tx.Bucket([]byte(kind))
+		if bucket == nil {
+			continue
+		}
+		err := bucket.ForEach(func(k, v []byte) error {
+			kindEntries[string(k)] = copyValue(v)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// ReadState returns the state kept in the database at path. Given a
+// state.DeltaBackend writing to the same database, the state only
+// checkpoints the entries that changed from it.
+func ReadState(backend state.Backend, path string) (*state.State, error) {
+	entries, err := ReadEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	return state.ReadStateEntries(backend, entries)
+}
+
+// ReadState returns the state kept in the database. Given a
+// state.DeltaBackend writing to it, the state only checkpoints the entries
+// that changed from it.
+func (db *DB) ReadState(backend state.Backend) (*state.State, error) {
+	entries, err := db.ReadEntries()
+	if err != nil {
+		return nil, err
+	}
+	return state.ReadStateEntries(backend, entries)
+}
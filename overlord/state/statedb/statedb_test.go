@@ -0,0 +1,280 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package statedb_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/overlord/state/statedb"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type statedbSuite struct {
+	path string
+}
+
+var _ = Suite(&statedbSuite{})
+
+func (s *statedbSuite) SetUpTest(c *C) {
+	s.path = filepath.Join(c.MkDir(), "state.db")
+}
+
+type dbBackend struct {
+	path   string
+	db     *statedb.DB
+	deltas []*state.Delta
+}
+
+func (b *dbBackend) Checkpoint(data []byte) error {
+	panic("Checkpoint should not be called with a DeltaBackend")
+}
+
+func (b *dbBackend) CheckpointDelta(delta *state.Delta) error {
+	b.deltas = append(b.deltas, delta)
+	if b.db != nil {
+		return b.db.Write(delta)
+	}
+	return statedb.Write(b.path, delta)
+}
+
+func (b *dbBackend) EnsureBefore(d time.Duration) {}
+
+func (s *statedbSuite) TestWriteAndReadEntries(c *C) {
+	err := statedb.Write(s.path, &state.Delta{
+		Full: true,
+		Entries: map[string]map[string][]byte{
+			state.EntriesData:  {"a": []byte("1"), "b": []byte("2")},
+			state.EntriesTasks: {"1": []byte(`{"id":"1"}`)},
+		},
+	})
+	c.Assert(err, IsNil)
+	st, err := os.Stat(s.path)
+	c.Assert(err, IsNil)
+	c.Check(st.Mode().Perm(), Equals, os.FileMode(0600))
+
+	err = statedb.Write(s.path, &state.Delta{
+		Entries: map[string]map[string][]byte{
+			state.EntriesData: {"a": nil, "c": []byte("3")},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	entries, err := statedb.ReadEntries(s.path)
+	c.Assert(err, IsNil)
+	c.Check(entries, DeepEquals, map[string]map[string][]byte{
+		state.EntriesMeta:     {},
+		state.EntriesData:     {"b": []byte("2"), "c": []byte("3")},
+		state.EntriesChanges:  {},
+		state.EntriesTasks:    {"1": []byte(`{"id":"1"}`)},
+		state.EntriesWarnings: {},
+		state.EntriesNotices:  {},
+	})
+
+	// a full delta replaces everything
+	err = statedb.Write(s.path, &state.Delta{
+		Full: true,
+		Entries: map[string]map[string][]byte{
+			state.EntriesData: {"d": []byte("4")},
+		},
+	})
+	c.Assert(err, IsNil)
+	entries, err = statedb.ReadEntries(s.path)
+	c.Assert(err, IsNil)
+	c.Check(entries[state.EntriesData], DeepEquals, map[string][]byte{"d": []byte("4")})
+	c.Check(entries[state.EntriesTasks], HasLen, 0)
+}
+
+func (s *statedbSuite) TestWriteEmptyDelta(c *C) {
+	err := statedb.Write(s.path, &state.Delta{})
+	c.Assert(err, IsNil)
+	// nothing to write, so not even created
+	c.Check(s.path, testutil.FileAbsent)
+}
+
+func (s *statedbSuite) TestReadEntriesMissing(c *C) {
+	_, err := statedb.ReadEntries(s.path)
+	c.Check(err, ErrorMatches, `cannot open state database: .*no such file or directory`)
+	c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+	c.Check(s.path, testutil.FileAbsent)
+}
+
+func (s *statedbSuite) TestStateRoundTrip(c *C) {
+	b := &dbBackend{path: s.path}
+	st := state.New(b)
+	st.Lock()
+	st.Set("a", 1)
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "...")
+	t1.Set("snap", "foo")
+	chg.AddTask(t1)
+	st.Warnf("hello")
+	st.Unlock()
+	c.Assert(b.deltas, HasLen, 1)
+	c.Check(b.deltas[0].Full, Equals, true)
+
+	b2 := &dbBackend{path: s.path}
+	st2, err := statedb.ReadState(b2, s.path)
+	c.Assert(err, IsNil)
+
+	st2.Lock()
+	var a int
+	c.Check(st2.Get("a", &a), IsNil)
+	c.Check(a, Equals, 1)
+	c.Assert(st2.Change(chg.ID()), NotNil)
+	var snap string
+	c.Check(st2.Task(t1.ID()).Get("snap", &snap), IsNil)
+	c.Check(snap, Equals, "foo")
+	c.Check(st2.AllWarnings(), HasLen, 1)
+
+	st2.Set("a", 2)
+	st2.Task(t1.ID()).SetStatus(state.DoneStatus)
+	st2.Unlock()
+
+	st3, err := statedb.ReadState(nil, s.path)
+	c.Assert(err, IsNil)
+	st3.Lock()
+	defer st3.Unlock()
+	c.Check(st3.Get("a", &a), IsNil)
+	c.Check(a, Equals, 2)
+	c.Check(st3.Task(t1.ID()).Status(), Equals, state.DoneStatus)
+	c.Check(st3.Change(chg.ID()).Status(), Equals, state.DoneStatus)
+}
+
+func (s *statedbSuite) TestWriteStateFile(c *C) {
+	err := statedb.Write(s.path, &state.Delta{
+		Full: true,
+		Entries: map[string]map[string][]byte{
+			state.EntriesData: {"a": []byte("1")},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	stateFile := filepath.Join(filepath.Dir(s.path), "state.json")
+	changed, err := statedb.StateFileChanged(s.path, stateFile)
+	c.Assert(err, IsNil)
+	c.Check(changed, Equals, false)
+
+	c.Assert(statedb.WriteStateFile(s.path, stateFile), IsNil)
+	st, err := os.Stat(stateFile)
+	c.Assert(err, IsNil)
+	c.Check(st.Mode().Perm(), Equals, os.FileMode(0600))
+
+	// the state file can be read by a snapd that predates the database
+	f, err := os.Open(stateFile)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	fromFile, err := state.ReadState(nil, f)
+	c.Assert(err, IsNil)
+	fromFile.Lock()
+	var a int
+	c.Check(fromFile.Get("a", &a), IsNil)
+	fromFile.Unlock()
+	c.Check(a, Equals, 1)
+
+	changed, err = statedb.StateFileChanged(s.path, stateFile)
+	c.Assert(err, IsNil)
+	c.Check(changed, Equals, false)
+
+	// recording the state file does not change the state
+	entries, err := statedb.ReadEntries(s.path)
+	c.Assert(err, IsNil)
+	c.Check(entries[state.EntriesData], DeepEquals, map[string][]byte{"a": []byte("1")})
+
+	// an older snapd wrote to it
+	c.Assert(os.WriteFile(stateFile, []byte(`{"data":{"a":2}}`), 0600), IsNil)
+	changed, err = statedb.StateFileChanged(s.path, stateFile)
+	c.Assert(err, IsNil)
+	c.Check(changed, Equals, true)
+}
+
+func (s *statedbSuite) TestWriteStateFileOnlyWhenStale(c *C) {
+	err := statedb.Write(s.path, &state.Delta{
+		Full: true,
+		Entries: map[string]map[string][]byte{
+			state.EntriesData: {"a": []byte("1")},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	stateFile := filepath.Join(filepath.Dir(s.path), "state.json")
+	c.Assert(statedb.WriteStateFile(s.path, stateFile), IsNil)
+	c.Check(stateFile, testutil.FileContains, `"a":1`)
+
+	// nothing changed since, so it is not written again
+	c.Assert(os.WriteFile(stateFile, []byte("canary"), 0600), IsNil)
+	c.Assert(statedb.WriteStateFile(s.path, stateFile), IsNil)
+	c.Check(stateFile, testutil.FileEquals, "canary")
+
+	err = statedb.Write(s.path, &state.Delta{
+		Entries: map[string]map[string][]byte{
+			state.EntriesData: {"a": []byte("2")},
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(statedb.WriteStateFile(s.path, stateFile), IsNil)
+	c.Check(stateFile, testutil.FileContains, `"a":2`)
+
+	// unless it is missing
+	c.Assert(os.Remove(stateFile), IsNil)
+	c.Assert(statedb.WriteStateFile(s.path, stateFile), IsNil)
+	c.Check(stateFile, testutil.FileContains, `"a":2`)
+}
+
+func (s *statedbSuite) TestOpen(c *C) {
+	db, err := statedb.Open(s.path)
+	c.Assert(err, IsNil)
+
+	b := &dbBackend{db: db}
+	st := state.New(b)
+	st.Lock()
+	st.Set("a", 1)
+	st.Unlock()
+
+	// it cannot be opened twice
+	_, err = statedb.Open(s.path)
+	c.Check(err, ErrorMatches, `internal error: state database .* is already open`)
+
+	// but is read through while kept open
+	entries, err := statedb.ReadEntries(s.path)
+	c.Assert(err, IsNil)
+	c.Check(entries[state.EntriesData], DeepEquals, map[string][]byte{"a": []byte("1")})
+
+	st2, err := db.ReadState(nil)
+	c.Assert(err, IsNil)
+	st2.Lock()
+	var a int
+	c.Check(st2.Get("a", &a), IsNil)
+	st2.Unlock()
+	c.Check(a, Equals, 1)
+
+	c.Assert(db.Close(), IsNil)
+	entries, err = statedb.ReadEntries(s.path)
+	c.Assert(err, IsNil)
+	c.Check(entries[state.EntriesData], DeepEquals, map[string][]byte{"a": []byte("1")})
+}
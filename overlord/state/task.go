@@ -258,13 +258,19 @@ func (t *Task) changeStatus(old, new Status) {
 	t.state.notifyTaskStatusChangedHandlers(t, old, new)
 }
 
+// writing is State.writing, for modifying the task.
+func (t *Task) writing() {
+	t.state.writing()
+	t.state.markDirty(EntriesTasks, t.id)
+}
+
 // SetStatus sets the task status, overriding the default behavior (see Status method).
 func (t *Task) SetStatus(new Status) {
 	if new == WaitStatus {
 		panic("Task.SetStatus() called with WaitStatus, which is not allowed. Use SetToWait() instead")
 	}
 
-	t.state.writing()
+	t.writing()
 	old := t.status
 	if new == DoneStatus && old == AbortStatus {
 		// if the task is in AbortStatus (because some other task ran
@@ -285,7 +291,7 @@ func (t *Task) SetToWait(resultStatus Status) {
 		panic("Task.SetToWait() cannot be invoked with either of DefaultStatus or WaitStatus")
 	}
 
-	t.state.writing()
+	t.writing()
 	old := t.status
 	if old == AbortStatus {
 		// if the task is in AbortStatus (because some other task ran
@@ -316,7 +322,7 @@ func (t *Task) IsClean() bool {
 //
 // Cleaning a task must only be done after the change is ready.
 func (t *Task) SetClean() {
-	t.state.writing()
+	t.writing()
 	if t.clean {
 		return
 	}
@@ -356,9 +362,11 @@ func (t *Task) Progress() (label string, done, total int) {
 func (t *Task) SetProgress(label string, done, total int) {
 	// Only mark state for checkpointing if progress is final.
 	if total > 0 && done == total {
-		t.state.writing()
+		t.writing()
 	} else {
 		t.state.reading()
+		// still checkpointed along with whatever else changes
+		t.state.markDirty(EntriesTasks, t.id)
 	}
 	if total <= 0 || done > total {
 		// Doing math wrong is easy. Be conservative.
@@ -387,12 +395,12 @@ func (t *Task) AtTime() time.Time {
 }
 
 func (t *Task) accumulateDoingTime(duration time.Duration) {
-	t.state.writing()
+	t.writing()
 	t.doingTime += duration
 }
 
 func (t *Task) accumulateUndoingTime(duration time.Duration) {
-	t.state.writing()
+	t.writing()
 	t.undoingTime += duration
 }
 
@@ -451,20 +459,20 @@ func (t *Task) Log() []string {
 
 // Logf logs information about the progress of the task.
 func (t *Task) Logf(format string, args ...any) {
-	t.state.writing()
+	t.writing()
 	t.addLog(LogInfo, format, args)
 }
 
 // Errorf logs error information about the progress of the task.
 func (t *Task) Errorf(format string, args ...any) {
-	t.state.writing()
+	t.writing()
 	t.addLog(LogError, format, args)
 }
 
 // Set associates value with key for future consulting by managers.
 // The provided value must properly marshal and unmarshal with encoding/json.
 func (t *Task) Set(key string, value any) {
-	t.state.writing()
+	t.writing()
 	t.data.set(key, value)
 }
 
@@ -483,7 +491,7 @@ func (t *Task) Has(key string) bool {
 
 // Clear disassociates the value from key.
 func (t *Task) Clear(key string) {
-	t.state.writing()
+	t.writing()
 	delete(t.data, key)
 }
 
@@ -498,9 +506,10 @@ func addOnce(set []string, s string) []string {
 
 // WaitFor registers another task as a requirement for t to make progress.
 func (t *Task) WaitFor(another *Task) {
-	t.state.writing()
+	t.writing()
 	t.waitTasks = addOnce(t.waitTasks, another.id)
 	another.haltTasks = addOnce(another.haltTasks, t.id)
+	t.state.markDirty(EntriesTasks, another.id)
 }
 
 // WaitAll registers all the tasks in the set as a requirement for t
@@ -540,13 +549,13 @@ func (t *Task) Lanes() []int {
 // JoinLane registers the task in the provided lane. Tasks in different lanes
 // abort independently on errors. See Change.AbortLane for details.
 func (t *Task) JoinLane(lane int) {
-	t.state.writing()
+	t.writing()
 	t.lanes = append(t.lanes, lane)
 }
 
 // At schedules the task, if it's not ready, to happen no earlier than when, if when is the zero time any previous special scheduling is suppressed.
 func (t *Task) At(when time.Time) {
-	t.state.writing()
+	t.writing()
 	iszero := when.IsZero()
 	if t.Status().Ready() && !iszero {
 		return
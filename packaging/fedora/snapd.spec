@@ -754,8 +754,9 @@ cat <<'EOF' > %{buildroot}%{_sysconfdir}/sysconfig/snapd
 #SNAP_REEXEC=0
 EOF
 
-# Create state.json and the README file to be ghosted
+# Create state.json, state.db and the README file to be ghosted
 touch %{buildroot}%{_sharedstatedir}/snapd/state.json
+touch %{buildroot}%{_sharedstatedir}/snapd/state.db
 touch %{buildroot}%{_sharedstatedir}/snapd/snap/README
 
 # When enabled, create a symlink for /snap to point to /var/lib/snapd/snap
@@ -894,6 +895,7 @@ make -C data -k check
 %dir %{_sharedstatedir}/snapd/snaps
 %dir %{_sharedstatedir}/snapd/snap
 %ghost %{_sharedstatedir}/snapd/state.json
+%ghost %{_sharedstatedir}/snapd/state.db
 %ghost %{_sharedstatedir}/snapd/system-key
 %ghost %{_sharedstatedir}/snapd/snap/bin
 %ghost %{_sharedstatedir}/snapd/snap/README
@@ -608,6 +608,7 @@ fi
 %ghost %{_localstatedir}/cache/snapd/sections
 %ghost %{_sharedstatedir}/snapd/seccomp/bpf/global.bin
 %ghost %{_sharedstatedir}/snapd/state.json
+%ghost %{_sharedstatedir}/snapd/state.db
 %ghost %{_sharedstatedir}/snapd/system-key
 %ghost %{snap_mount_dir}/README
 %ghost %{alt_snap_mount_dir}/README
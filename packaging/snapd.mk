@@ -264,6 +264,7 @@ install::
 # this way the package manager knows about them belonging to the package.
 install:: | $(DESTDIR)/$(sharedstatedir)/snapd
 	touch $|/state.json
+	touch $|/state.db
 	touch $|/system-key
 
 install:: | $(DESTDIR)$(localstatedir)/cache/snapd
@@ -92,6 +92,19 @@ func ProcPidPath(pid int) string {
 	return filepath.Join(dirs.GlobalRootDir, fmt.Sprintf("proc/%v/cgroup", pid))
 }
 
+// SlicePath returns the path of the given systemd slice unit in the unified
+// cgroup hierarchy. Dashes in the slice name denote nesting, so
+// "snap.foo-bar.slice" is found under "snap.foo.slice".
+func SlicePath(slice string) string {
+	parts := strings.Split(strings.TrimSuffix(slice, ".slice"), "-")
+	elems := make([]string, 0, len(parts)+2)
+	elems = append(elems, dirs.GlobalRootDir, cgroupMountPoint)
+	for i := range parts {
+		elems = append(elems, strings.Join(parts[:i+1], "-")+".slice")
+	}
+	return filepath.Join(elems...)
+}
+
 func probeCgroupVersion() (version int, err error) {
 	cgroupMount := filepath.Join(dirs.GlobalRootDir, cgroupMountPoint)
 	typ, err := fsTypeForPath(cgroupMount)
@@ -127,6 +127,13 @@ func (s *cgroupSuite) TestProcPidPath(c *C) {
 	c.Assert(cgroup.ProcPidPath(1234), Equals, filepath.Join(s.rootDir, "/proc/1234/cgroup"))
 }
 
+func (s *cgroupSuite) TestSlicePath(c *C) {
+	c.Check(cgroup.SlicePath("snap.foo.slice"), Equals,
+		filepath.Join(s.rootDir, "/sys/fs/cgroup/snap.foo.slice"))
+	c.Check(cgroup.SlicePath("snap.foo-bar-baz.slice"), Equals,
+		filepath.Join(s.rootDir, "/sys/fs/cgroup/snap.foo.slice/snap.foo-bar.slice/snap.foo-bar-baz.slice"))
+}
+
 var mockCgroup = []byte(`
 10:devices:/user.slice
 9:cpuset:/
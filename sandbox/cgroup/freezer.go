@@ -297,6 +297,55 @@ func thawSnapProcessesImplV2(snapName string) error {
 	return thawSnapProcessesV2(snapName, skipErrNotExist)
 }
 
+// FreezeSlice suspends execution of all the processes in the given systemd
+// slice and the slices nested in it. Processes remain frozen until ThawSlice
+// is called. As with FreezeSnapProcesses, the processes are thawed again if
+// freezing does not complete in time.
+//
+// Freezing a slice is only supported with cgroup v2.
+//
+// This operation can be mocked with MockSliceFreezing
+var FreezeSlice = func(ctx context.Context, slice string) error {
+	if !IsUnified() {
+		return fmt.Errorf("cannot freeze slice %q: not supported with cgroup v1", slice)
+	}
+	dir := SlicePath(slice)
+	if err := freezeOneV2(ctx, dir); err != nil {
+		// best-effort, do not leave the slice half frozen
+		thawOneV2(dir)
+		return err
+	}
+	return nil
+}
+
+// ThawSlice resumes execution of all the processes in the given systemd
+// slice. Thawing a slice which does not exist is not an error.
+//
+// This operation can be mocked with MockSliceFreezing
+var ThawSlice = func(slice string) error {
+	if !IsUnified() {
+		return fmt.Errorf("cannot thaw slice %q: not supported with cgroup v1", slice)
+	}
+	if err := thawOneV2(SlicePath(slice)); err != nil {
+		return fmt.Errorf("cannot thaw slice %q: %w", slice, err)
+	}
+	return nil
+}
+
+// MockSliceFreezing replaces the real implementation of slice freeze and thaw.
+func MockSliceFreezing(freeze func(ctx context.Context, slice string) error, thaw func(slice string) error) (restore func()) {
+	oldFreeze := FreezeSlice
+	oldThaw := ThawSlice
+
+	FreezeSlice = freeze
+	ThawSlice = thaw
+
+	return func() {
+		FreezeSlice = oldFreeze
+		ThawSlice = oldThaw
+	}
+}
+
 // MockFreezing replaces the real implementation of freeze and thaw.
 func MockFreezing(freeze func(ctx context.Context, snapName string) error, thaw func(snapName string) error) (restore func()) {
 	oldFreeze := FreezeSnapProcesses
@@ -478,3 +478,34 @@ func (s *freezerV2Suite) TestApplyToSnapCallbacks(c *C) {
 	c.Check(visited, DeepEquals, []string{filepath.Dir(g), filepath.Dir(gErr)})
 	c.Check(errors, DeepEquals, []string{"do not skip"})
 }
+
+func (s *freezerV2Suite) TestFreezeThawSlice(c *C) {
+	defer cgroup.MockVersion(cgroup.V2, nil)()
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	f := filepath.Join(dirs.GlobalRootDir, "/sys/fs/cgroup/snap.foo.slice/snap.foo-bar.slice/cgroup.freeze")
+
+	// a slice which is not active is left alone
+	c.Assert(cgroup.FreezeSlice(context.TODO(), "snap.foo-bar.slice"), IsNil)
+	c.Assert(cgroup.ThawSlice("snap.foo-bar.slice"), IsNil)
+	c.Check(f, testutil.FileAbsent)
+
+	c.Assert(os.MkdirAll(filepath.Dir(f), 0755), IsNil)
+	c.Assert(os.WriteFile(f, []byte("0"), 0644), IsNil)
+
+	c.Assert(cgroup.FreezeSlice(context.TODO(), "snap.foo-bar.slice"), IsNil)
+	c.Check(f, testutil.FileEquals, "1")
+
+	c.Assert(cgroup.ThawSlice("snap.foo-bar.slice"), IsNil)
+	c.Check(f, testutil.FileEquals, "0")
+}
+
+func (s *freezerV1Suite) TestFreezeThawSliceV1(c *C) {
+	defer cgroup.MockVersion(cgroup.V1, nil)()
+
+	err := cgroup.FreezeSlice(context.TODO(), "snap.foo.slice")
+	c.Check(err, ErrorMatches, `cannot freeze slice "snap.foo.slice": not supported with cgroup v1`)
+	err = cgroup.ThawSlice("snap.foo.slice")
+	c.Check(err, ErrorMatches, `cannot thaw slice "snap.foo.slice": not supported with cgroup v1`)
+}
@@ -19,3 +19,19 @@
 package ebpf
 
 var BpffsPinnedNameToSecurityTag = bpffsPinnedNameToSecurityTag
+
+type (
+	NetworkQuotaConfig = networkQuotaConfig
+	NetworkQuotaState  = networkQuotaState
+)
+
+const (
+	NetworkQuotaConfigSize = networkQuotaConfigSize
+	NetworkQuotaStateSize  = networkQuotaStateSize
+)
+
+var NetworkQuotaInstructions = networkQuotaInstructions
+
+func (c *NetworkQuotaConfig) SetRate(rate uint64) {
+	c.setRate(rate)
+}
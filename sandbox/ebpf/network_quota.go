@@ -0,0 +1,447 @@
+// -*- Mode: Go; indent-tabs-mode: t; tab-width: 4 -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+
+	"github.com/snapcore/snapd/arch"
+	"github.com/snapcore/snapd/dirs"
+)
+
+// ErrNoNetworkQuota is returned when no network quota is attached for a
+// quota group.
+var ErrNoNetworkQuota = errors.New("no network quota attached")
+
+// NetworkQuotaStats are the counters maintained by the network quota program
+// of a quota group.
+type NetworkQuotaStats struct {
+	// EgressBytes is the number of bytes sent by the group.
+	EgressBytes uint64
+	// EgressPackets is the number of packets sent by the group.
+	EgressPackets uint64
+	// DroppedBytes is the number of bytes dropped because the group
+	// exceeded its egress bandwidth.
+	DroppedBytes uint64
+}
+
+// networkQuotaConfig is the value of the single entry of the network quota
+// config map, set by snapd and read by the eBPF program.
+type networkQuotaConfig struct {
+	Rate  uint64 // bytes per second
+	Burst uint64 // maximum number of tokens, in bytes
+}
+
+// networkQuotaState is the value of the single entry of the network quota
+// state map, maintained by the eBPF program with atomic updates only. It is
+// kept apart from the config, such that snapd never writes it while the
+// program updates it.
+type networkQuotaState struct {
+	Tokens int64  // bytes which can currently be sent, negative when owed
+	Last   uint64 // time up to which tokens were refilled, in ns since boot
+	NetworkQuotaStats
+}
+
+// Offsets of the fields of networkQuotaConfig and networkQuotaState, as
+// accessed by the program.
+const (
+	nqRateOff  = 0
+	nqBurstOff = 8
+
+	networkQuotaConfigSize = 16
+
+	nqTokensOff        = 0
+	nqLastOff          = 8
+	nqEgressBytesOff   = 16
+	nqEgressPacketsOff = 24
+	nqDroppedBytesOff  = 32
+
+	networkQuotaStateSize = 40
+)
+
+// networkQuotaBurstMin is the smallest burst allowed, such that a full
+// segmentation offloaded packet can always go through eventually.
+const networkQuotaBurstMin = 64 * 1024
+
+// MarshalBinary encodes the config in the layout expected by the program.
+// Implements encoding.BinaryMarshaler.
+func (c *networkQuotaConfig) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, networkQuotaConfigSize)
+	// TODO:GOVERSION:use binary.NativeEndian
+	e := arch.Endian()
+	e.PutUint64(buf[nqRateOff:], c.Rate)
+	e.PutUint64(buf[nqBurstOff:], c.Burst)
+	return buf, nil
+}
+
+// UnmarshalBinary decodes the config from the map value.
+// Implements encoding.BinaryUnmarshaler.
+func (c *networkQuotaConfig) UnmarshalBinary(data []byte) error {
+	if l := len(data); l < networkQuotaConfigSize {
+		return fmt.Errorf("cannot unmarshal network quota config: unexpected size %v", l)
+	}
+	// TODO:GOVERSION:use binary.NativeEndian
+	e := arch.Endian()
+	c.Rate = e.Uint64(data[nqRateOff:])
+	c.Burst = e.Uint64(data[nqBurstOff:])
+	return nil
+}
+
+// setRate sets the rate and the matching burst of one second worth of
+// traffic. Tokens left above a lowered burst are used up before the bucket
+// is refilled again.
+func (c *networkQuotaConfig) setRate(rate uint64) {
+	c.Rate = rate
+	c.Burst = rate
+	if c.Burst < networkQuotaBurstMin {
+		c.Burst = networkQuotaBurstMin
+	}
+}
+
+// MarshalBinary encodes the state in the layout expected by the program.
+// Implements encoding.BinaryMarshaler.
+func (s *networkQuotaState) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, networkQuotaStateSize)
+	// TODO:GOVERSION:use binary.NativeEndian
+	e := arch.Endian()
+	e.PutUint64(buf[nqTokensOff:], uint64(s.Tokens))
+	e.PutUint64(buf[nqLastOff:], s.Last)
+	e.PutUint64(buf[nqEgressBytesOff:], s.EgressBytes)
+	e.PutUint64(buf[nqEgressPacketsOff:], s.EgressPackets)
+	e.PutUint64(buf[nqDroppedBytesOff:], s.DroppedBytes)
+	return buf, nil
+}
+
+// UnmarshalBinary decodes the state from the map value.
+// Implements encoding.BinaryUnmarshaler.
+func (s *networkQuotaState) UnmarshalBinary(data []byte) error {
+	if l := len(data); l < networkQuotaStateSize {
+		return fmt.Errorf("cannot unmarshal network quota state: unexpected size %v", l)
+	}
+	// TODO:GOVERSION:use binary.NativeEndian
+	e := arch.Endian()
+	s.Tokens = int64(e.Uint64(data[nqTokensOff:]))
+	s.Last = e.Uint64(data[nqLastOff:])
+	s.EgressBytes = e.Uint64(data[nqEgressBytesOff:])
+	s.EgressPackets = e.Uint64(data[nqEgressPacketsOff:])
+	s.DroppedBytes = e.Uint64(data[nqDroppedBytesOff:])
+	return nil
+}
+
+// NetworkQuotaPinPath returns the directory in the BPF filesystem under which
+// the network quota map and program link of a quota group are pinned.
+func NetworkQuotaPinPath(group string) string {
+	// quota group names cannot contain underscores, nor clash with the
+	// snap_ prefix of device cgroup maps
+	return filepath.Join(dirs.SnapBPFFSDir, "quota_"+group)
+}
+
+func networkQuotaConfigMapPath(group string) string {
+	return filepath.Join(NetworkQuotaPinPath(group), "config")
+}
+
+func networkQuotaMapPath(group string) string {
+	return filepath.Join(NetworkQuotaPinPath(group), "state")
+}
+
+// NetworkQuotaProgramPath returns the path of the pinned program enforcing
+// the network quota of a quota group, which systemd attaches to the slice of
+// the group whenever it starts, see BPFProgram= in
+// systemd.resource-control(5).
+func NetworkQuotaProgramPath(group string) string {
+	return filepath.Join(NetworkQuotaPinPath(group), "egress")
+}
+
+func networkQuotaLinkPath(group string) string {
+	return filepath.Join(NetworkQuotaPinPath(group), "egress-link")
+}
+
+// networkQuotaInstructions returns the cgroup skb egress program enforcing
+// the quota configured in the config map and kept in the state map with the
+// given file descriptors. The program implements a token bucket refilled at
+// the configured rate, and drops packets for which not enough tokens are
+// left.
+//
+// The state map entry is shared by all CPUs, so the tokens are only ever
+// changed with atomic additions. Packets sent concurrently may take the
+// tokens below zero, which is paid back by later refills. The time of the
+// last refill only advances by the time converted into whole tokens, such
+// that slow rates and closely spaced packets still refill the bucket
+// eventually. CPUs racing to refill for the same time push the time of the
+// last refill ahead by as much as they refilled, which withholds later
+// refills accordingly.
+func networkQuotaInstructions(configFD, stateFD int) asm.Instructions {
+	const nsPerSec = 1000000000
+	return asm.Instructions{
+		// r8 = skb->len
+		asm.LoadMem(asm.R8, asm.R1, 0, asm.Word),
+		// r6 = lookup(config, &0)
+		asm.StoreImm(asm.RFP, -4, 0, asm.Word),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.LoadMapPtr(asm.R1, configFD),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "allow"),
+		asm.Mov.Reg(asm.R6, asm.R0),
+		// r7 = lookup(state, &0)
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -4),
+		asm.LoadMapPtr(asm.R1, stateFD),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "allow"),
+		asm.Mov.Reg(asm.R7, asm.R0),
+		// r9 = now, r2 = now - last, which is negative when another CPU
+		// refilled ahead
+		asm.FnKtimeGetNs.Call(),
+		asm.Mov.Reg(asm.R9, asm.R0),
+		asm.LoadMem(asm.R1, asm.R7, nqLastOff, asm.DWord),
+		asm.Mov.Reg(asm.R2, asm.R9),
+		asm.Sub.Reg(asm.R2, asm.R1),
+		asm.JSLE.Imm(asm.R2, 0, "consume"),
+		asm.LoadMem(asm.R3, asm.R6, nqRateOff, asm.DWord),
+		asm.JEq.Imm(asm.R3, 0, "consume"),
+		asm.JGT.Imm(asm.R2, nsPerSec, "idle"),
+		// r4 = elapsed * rate / 1s, r2 = the time converted into those
+		// tokens, which leaves the remainder for the next refill
+		asm.Mov.Reg(asm.R4, asm.R2),
+		asm.Mul.Reg(asm.R4, asm.R3),
+		asm.Mov.Reg(asm.R0, asm.R4),
+		asm.Mod.Imm(asm.R0, nsPerSec),
+		asm.Div.Imm(asm.R4, nsPerSec),
+		asm.JEq.Imm(asm.R4, 0, "consume"),
+		asm.Div.Reg(asm.R0, asm.R3),
+		asm.Sub.Reg(asm.R2, asm.R0),
+		// last += r2
+		asm.Mov.Reg(asm.R1, asm.R7),
+		asm.Add.Imm(asm.R1, nqLastOff),
+		asm.StoreXAdd(asm.R1, asm.R2, asm.DWord),
+		asm.Ja.Label("refill"),
+		// after a second or more without traffic the bucket is full, avoid
+		// overflows by refilling a second worth of tokens, last = now
+		asm.StoreMem(asm.R7, nqLastOff, asm.R9, asm.DWord).WithSymbol("idle"),
+		asm.Mov.Reg(asm.R4, asm.R3),
+		// tokens += min(r4, burst - tokens)
+		asm.LoadMem(asm.R1, asm.R6, nqBurstOff, asm.DWord).WithSymbol("refill"),
+		asm.LoadMem(asm.R2, asm.R7, nqTokensOff, asm.DWord),
+		asm.Sub.Reg(asm.R1, asm.R2),
+		asm.JSLE.Imm(asm.R1, 0, "consume"),
+		asm.JLE.Reg(asm.R4, asm.R1, "add"),
+		asm.Mov.Reg(asm.R4, asm.R1),
+		asm.Mov.Reg(asm.R1, asm.R7).WithSymbol("add"),
+		asm.Add.Imm(asm.R1, nqTokensOff),
+		asm.StoreXAdd(asm.R1, asm.R4, asm.DWord),
+		// drop the packet if tokens < len
+		asm.LoadMem(asm.R1, asm.R7, nqTokensOff, asm.DWord).WithSymbol("consume"),
+		asm.JSLT.Reg(asm.R1, asm.R8, "drop"),
+		// tokens -= len, account the packet and let it through
+		asm.Mov.Imm(asm.R3, 0),
+		asm.Sub.Reg(asm.R3, asm.R8),
+		asm.Mov.Reg(asm.R2, asm.R7),
+		asm.Add.Imm(asm.R2, nqTokensOff),
+		asm.StoreXAdd(asm.R2, asm.R3, asm.DWord),
+		asm.Mov.Reg(asm.R2, asm.R7),
+		asm.Add.Imm(asm.R2, nqEgressBytesOff),
+		asm.StoreXAdd(asm.R2, asm.R8, asm.DWord),
+		asm.Mov.Imm(asm.R3, 1),
+		asm.Mov.Reg(asm.R2, asm.R7),
+		asm.Add.Imm(asm.R2, nqEgressPacketsOff),
+		asm.StoreXAdd(asm.R2, asm.R3, asm.DWord),
+		asm.Ja.Label("allow"),
+		// account the dropped packet
+		asm.Mov.Reg(asm.R2, asm.R7).WithSymbol("drop"),
+		asm.Add.Imm(asm.R2, nqDroppedBytesOff),
+		asm.StoreXAdd(asm.R2, asm.R8, asm.DWord),
+		asm.Mov.Imm(asm.R0, 0),
+		asm.Return(),
+		asm.Mov.Imm(asm.R0, 1).WithSymbol("allow"),
+		asm.Return(),
+	}
+}
+
+// loadOrCreateNetworkQuotaMap loads the map pinned at path, or creates and
+// pins a new one with the given value size. A pinned map of another size,
+// as left by an earlier layout, is replaced.
+func loadOrCreateNetworkQuotaMap(path string, valueSize uint32) (m *ebpf.Map, created bool, err error) {
+	m, err = ebpf.LoadPinnedMap(path, nil)
+	if err == nil {
+		if m.ValueSize() == valueSize {
+			return m, false, nil
+		}
+		m.Close()
+		if err := os.Remove(path); err != nil {
+			return nil, false, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, false, fmt.Errorf("cannot load network quota map at %s: %v", path, err)
+	}
+
+	m, err = ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.Array,
+		KeySize:    4,
+		ValueSize:  valueSize,
+		MaxEntries: 1,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot create network quota map: %v", err)
+	}
+	if err := m.Pin(path); err != nil {
+		m.Close()
+		return nil, false, fmt.Errorf("cannot pin network quota map at %s: %v", path, err)
+	}
+	return m, true, nil
+}
+
+// LoadNetworkQuota sets the egress bandwidth limit of a quota group to rate
+// bytes per second. The maps and the program enforcing the limit are pinned
+// under NetworkQuotaPinPath, and the program is loaded and pinned at
+// NetworkQuotaProgramPath unless it was already, which is reported. The
+// program is attached by systemd when the slice of the group starts, such
+// that a slice started before the program was loaded needs
+// AttachNetworkQuota. Updating the limit only changes the config of the
+// program, the counters of the group are kept.
+func LoadNetworkQuota(group string, rate uint64) (loaded bool, err error) {
+	if err := os.MkdirAll(NetworkQuotaPinPath(group), 0700); err != nil {
+		return false, err
+	}
+
+	configMap, configCreated, err := loadOrCreateNetworkQuotaMap(networkQuotaConfigMapPath(group), networkQuotaConfigSize)
+	if err != nil {
+		return false, err
+	}
+	defer configMap.Close()
+	var config networkQuotaConfig
+	config.setRate(rate)
+	if err := configMap.Update(uint32(0), &config, ebpf.UpdateAny); err != nil {
+		return false, fmt.Errorf("cannot update network quota config: %v", err)
+	}
+
+	stateMap, stateCreated, err := loadOrCreateNetworkQuotaMap(networkQuotaMapPath(group), networkQuotaStateSize)
+	if err != nil {
+		return false, err
+	}
+	defer stateMap.Close()
+
+	progPath := NetworkQuotaProgramPath(group)
+	if !configCreated && !stateCreated {
+		_, err := os.Stat(progPath)
+		if err == nil {
+			return false, nil
+		}
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:         "snap_net_quota",
+		Type:         ebpf.CGroupSKB,
+		AttachType:   ebpf.AttachCGroupInetEgress,
+		Instructions: networkQuotaInstructions(configMap.FD(), stateMap.FD()),
+		License:      "GPL",
+	})
+	if err != nil {
+		return false, fmt.Errorf("cannot load network quota program: %v", err)
+	}
+	defer prog.Close()
+
+	// a program using maps which were replaced is replaced as well
+	if err := os.Remove(progPath); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := prog.Pin(progPath); err != nil {
+		return false, fmt.Errorf("cannot pin network quota program: %v", err)
+	}
+	return true, nil
+}
+
+// AttachNetworkQuota attaches the program loaded by LoadNetworkQuota for a
+// quota group to the given cgroup, which must be the cgroup v2 path of the
+// slice of the group, for a slice which was already started when the
+// program was loaded. The program link is pinned under NetworkQuotaPinPath,
+// so the limit remains in place after snapd exits, until the slice stops.
+func AttachNetworkQuota(group, cgroupPath string) error {
+	progPath := NetworkQuotaProgramPath(group)
+	prog, err := ebpf.LoadPinnedProgram(progPath, nil)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNoNetworkQuota
+		}
+		return fmt.Errorf("cannot load network quota program at %s: %v", progPath, err)
+	}
+	defer prog.Close()
+
+	l, err := link.AttachCgroup(link.CgroupOptions{
+		Path:    cgroupPath,
+		Attach:  ebpf.AttachCGroupInetEgress,
+		Program: prog,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot attach network quota program to %s: %v", cgroupPath, err)
+	}
+	defer l.Close()
+
+	// replace the previous link, if any, once the new program is in place,
+	// removing the last reference to a link detaches it
+	linkPath := networkQuotaLinkPath(group)
+	if err := os.Remove(linkPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := l.Pin(linkPath); err != nil {
+		return fmt.Errorf("cannot pin network quota program: %v", err)
+	}
+	return nil
+}
+
+// DetachNetworkQuota removes the network quota of a quota group, if there
+// is any. The counters of the group are lost.
+func DetachNetworkQuota(group string) error {
+	if err := os.RemoveAll(NetworkQuotaPinPath(group)); err != nil {
+		return fmt.Errorf("cannot detach network quota of group %q: %v", group, err)
+	}
+	return nil
+}
+
+// ReadNetworkQuotaStats returns the counters of the network quota of a quota
+// group. ErrNoNetworkQuota is returned if the group has no network quota
+// attached.
+func ReadNetworkQuotaStats(group string) (*NetworkQuotaStats, error) {
+	path := networkQuotaMapPath(group)
+	m, err := ebpf.LoadPinnedMap(path, &ebpf.LoadPinOptions{ReadOnly: true})
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNoNetworkQuota
+		}
+		return nil, fmt.Errorf("cannot load network quota map at %s: %v", path, err)
+	}
+	defer m.Close()
+
+	var st networkQuotaState
+	if err := m.Lookup(uint32(0), &st); err != nil {
+		return nil, fmt.Errorf("cannot read network quota state: %v", err)
+	}
+	return &st.NetworkQuotaStats, nil
+}
@@ -0,0 +1,213 @@
+// -*- Mode: Go; indent-tabs-mode: t; tab-width: 4 -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ebpf_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	cilium "github.com/cilium/ebpf"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/sandbox/ebpf"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *ebpfSuite) TestNetworkQuotaPinPath(c *C) {
+	c.Check(ebpf.NetworkQuotaPinPath("foo-bar"), Equals,
+		filepath.Join(dirs.SnapBPFFSDir, "quota_foo-bar"))
+}
+
+func (s *ebpfSuite) TestNetworkQuotaConfigMarshalRoundTrip(c *C) {
+	config := ebpf.NetworkQuotaConfig{
+		Rate:  1000,
+		Burst: 2000,
+	}
+	data, err := config.MarshalBinary()
+	c.Assert(err, IsNil)
+	c.Assert(data, HasLen, ebpf.NetworkQuotaConfigSize)
+
+	var config2 ebpf.NetworkQuotaConfig
+	c.Assert(config2.UnmarshalBinary(data), IsNil)
+	c.Check(config2, Equals, config)
+
+	c.Check(config2.UnmarshalBinary(data[:8]), ErrorMatches,
+		"cannot unmarshal network quota config: unexpected size 8")
+}
+
+func (s *ebpfSuite) TestNetworkQuotaStateMarshalRoundTrip(c *C) {
+	st := ebpf.NetworkQuotaState{
+		Tokens: 3,
+		Last:   4,
+		NetworkQuotaStats: ebpf.NetworkQuotaStats{
+			EgressBytes:   5,
+			EgressPackets: 6,
+			DroppedBytes:  7,
+		},
+	}
+	data, err := st.MarshalBinary()
+	c.Assert(err, IsNil)
+	c.Assert(data, HasLen, ebpf.NetworkQuotaStateSize)
+
+	var st2 ebpf.NetworkQuotaState
+	c.Assert(st2.UnmarshalBinary(data), IsNil)
+	c.Check(st2, Equals, st)
+
+	c.Check(st2.UnmarshalBinary(data[:8]), ErrorMatches,
+		"cannot unmarshal network quota state: unexpected size 8")
+}
+
+func (s *ebpfSuite) TestNetworkQuotaConfigSetRate(c *C) {
+	var config ebpf.NetworkQuotaConfig
+
+	// the burst is one second worth of traffic
+	config.SetRate(1 << 20)
+	c.Check(config.Rate, Equals, uint64(1<<20))
+	c.Check(config.Burst, Equals, uint64(1<<20))
+
+	// but never less than the largest packet
+	config.SetRate(1024)
+	c.Check(config.Rate, Equals, uint64(1024))
+	c.Check(config.Burst, Equals, uint64(64*1024))
+}
+
+func (s *ebpfSuite) TestReadNetworkQuotaStatsNotAttached(c *C) {
+	_, err := ebpf.ReadNetworkQuotaStats("foo")
+	c.Check(err, Equals, ebpf.ErrNoNetworkQuota)
+}
+
+func (s *ebpfSuite) TestNetworkQuotaProgramPath(c *C) {
+	c.Check(ebpf.NetworkQuotaProgramPath("foo"), Equals,
+		filepath.Join(dirs.SnapBPFFSDir, "quota_foo", "egress"))
+}
+
+func (s *ebpfSuite) TestAttachNetworkQuotaNotLoaded(c *C) {
+	err := ebpf.AttachNetworkQuota("foo", "/sys/fs/cgroup/snap.foo.slice")
+	c.Check(err, Equals, ebpf.ErrNoNetworkQuota)
+}
+
+func (s *ebpfSuite) TestDetachNetworkQuota(c *C) {
+	pinDir := ebpf.NetworkQuotaPinPath("foo")
+	c.Assert(os.MkdirAll(pinDir, 0700), IsNil)
+	c.Assert(os.WriteFile(filepath.Join(pinDir, "state"), nil, 0600), IsNil)
+
+	c.Assert(ebpf.DetachNetworkQuota("foo"), IsNil)
+	c.Check(pinDir, testutil.FileAbsent)
+
+	// detaching again is fine
+	c.Assert(ebpf.DetachNetworkQuota("foo"), IsNil)
+}
+
+func newNetworkQuotaMap(c *C, valueSize uint32) *cilium.Map {
+	// this needs privileges to create BPF objects
+	m, err := cilium.NewMap(&cilium.MapSpec{
+		Type:       cilium.Array,
+		KeySize:    4,
+		ValueSize:  valueSize,
+		MaxEntries: 1,
+	})
+	if err != nil {
+		c.Skip("cannot create BPF map: " + err.Error())
+	}
+	return m
+}
+
+func loadNetworkQuotaProgram(c *C, rate uint64) (*cilium.Map, *cilium.Program) {
+	configMap := newNetworkQuotaMap(c, ebpf.NetworkQuotaConfigSize)
+	defer configMap.Close()
+	var config ebpf.NetworkQuotaConfig
+	config.SetRate(rate)
+	c.Assert(configMap.Update(uint32(0), &config, cilium.UpdateAny), IsNil)
+
+	m := newNetworkQuotaMap(c, ebpf.NetworkQuotaStateSize)
+	prog, err := cilium.NewProgram(&cilium.ProgramSpec{
+		Type:         cilium.CGroupSKB,
+		AttachType:   cilium.AttachCGroupInetEgress,
+		Instructions: ebpf.NetworkQuotaInstructions(configMap.FD(), m.FD()),
+		License:      "GPL",
+	})
+	c.Assert(err, IsNil)
+	return m, prog
+}
+
+func (s *ebpfSuite) TestNetworkQuotaProgram(c *C) {
+	m, prog := loadNetworkQuotaProgram(c, 100*1000)
+	defer m.Close()
+	defer prog.Close()
+
+	// send packets back to back, until the burst is used up
+	pkt := make([]byte, 1500)
+	allowed, dropped := 0, 0
+	for i := 0; i < 100; i++ {
+		ret, _, err := prog.Test(pkt)
+		if err != nil {
+			c.Skip("cannot test BPF program: " + err.Error())
+		}
+		if ret == 1 {
+			allowed++
+		} else {
+			dropped++
+		}
+	}
+	c.Check(allowed > 0, Equals, true)
+	c.Check(dropped > 0, Equals, true)
+
+	var st ebpf.NetworkQuotaState
+	c.Assert(m.Lookup(uint32(0), &st), IsNil)
+	c.Check(st.EgressPackets, Equals, uint64(allowed))
+	c.Check(st.DroppedBytes > 0, Equals, true)
+}
+
+func (s *ebpfSuite) TestNetworkQuotaProgramSlowRateRefills(c *C) {
+	// at 8KiB/s, packets sent microseconds apart each add less than a
+	// token worth of time, which must still add up to refill the bucket
+	m, prog := loadNetworkQuotaProgram(c, 8*1024)
+	defer m.Close()
+	defer prog.Close()
+
+	pkt := make([]byte, 1500)
+	for {
+		ret, _, err := prog.Test(pkt)
+		if err != nil {
+			c.Skip("cannot test BPF program: " + err.Error())
+		}
+		if ret == 0 {
+			break
+		}
+	}
+
+	allowed := 0
+	for start := time.Now(); time.Since(start) < 400*time.Millisecond; {
+		ret, _, err := prog.Test(pkt)
+		c.Assert(err, IsNil)
+		if ret == 1 {
+			allowed++
+		}
+	}
+	// 400ms refill a bit over 3200 bytes, that is 2 or 3 packets
+	// depending on what was left in the bucket
+	c.Check(allowed >= 2 && allowed <= 3, Equals, true, Commentf("allowed %d packets", allowed))
+
+	var st ebpf.NetworkQuotaState
+	c.Assert(m.Lookup(uint32(0), &st), IsNil)
+	c.Check(st.Tokens >= 0 && st.Tokens < 1500, Equals, true, Commentf("%d tokens left", st.Tokens))
+}
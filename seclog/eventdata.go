@@ -268,3 +268,81 @@ func (u SnapdUser) String() string {
 
 	return id + ":" + email + ":" + name
 }
+
+// Snap identifies a snap revision involved in a snap lifecycle event.
+type Snap struct {
+	// Name is the snap instance name.
+	Name string `json:"snap_name"`
+	// SnapID is empty for snaps not coming from the store.
+	SnapID   string `json:"snap_id"`
+	Revision string `json:"revision"`
+}
+
+// String returns a colon-separated representation in the form
+// "<Name>:<Revision>". Fields that are unset use [unknown] as a placeholder.
+func (s Snap) String() string {
+	name := unknown
+	if s.Name != "" {
+		name = s.Name
+	}
+
+	revision := unknown
+	if s.Revision != "" {
+		revision = s.Revision
+	}
+
+	return name + ":" + revision
+}
+
+// Connection describes an interface connection for interface events.
+type Connection struct {
+	Interface string `json:"interface"`
+	PlugSnap  string `json:"plug_snap"`
+	PlugName  string `json:"plug_name"`
+	SlotSnap  string `json:"slot_snap"`
+	SlotName  string `json:"slot_name"`
+	// Auto is true when snapd connected or disconnected the plug and slot
+	// on its own, as opposed to on request.
+	Auto bool `json:"auto"`
+	// SuperPrivileged is true for interfaces whose plugs or slots cannot
+	// be installed unless a snap declaration grants them.
+	SuperPrivileged bool `json:"super_privileged"`
+}
+
+// String returns a representation in the form
+// "<PlugSnap>:<PlugName> <SlotSnap>:<SlotName>", the way connections are
+// usually referred to.
+func (c Connection) String() string {
+	return c.PlugSnap + ":" + c.PlugName + " " + c.SlotSnap + ":" + c.SlotName
+}
+
+// ValidationSet identifies a validation set for validation set events.
+type ValidationSet struct {
+	AccountID string `json:"account_id"`
+	Name      string `json:"name"`
+	Sequence  int    `json:"sequence"`
+	// Pinned is true when the validation set is held at Sequence.
+	Pinned bool `json:"pinned"`
+}
+
+// String returns a representation in the form
+// "<AccountID>/<Name>=<Sequence>", as used to refer to validation sets.
+func (v ValidationSet) String() string {
+	return fmt.Sprintf("%s/%s=%d", v.AccountID, v.Name, v.Sequence)
+}
+
+// Assertion identifies an assertion for assertion events.
+type Assertion struct {
+	Type string `json:"type"`
+	// PrimaryKey holds the values of the primary key headers of the
+	// assertion, joined with "/".
+	PrimaryKey string `json:"primary_key"`
+	Revision   int    `json:"revision"`
+	SignKeyID  string `json:"sign_key_id"`
+}
+
+// String returns a colon-separated representation in the form
+// "<Type>:<PrimaryKey>".
+func (a Assertion) String() string {
+	return a.Type + ":" + a.PrimaryKey
+}
@@ -98,3 +98,23 @@ func (s *SecLogSuite) TestGrantReasonWithInterface(c *C) {
 	c.Check(seclog.GrantRootAuth.WithInterface("", true), Equals, seclog.GrantRootAuth)
 	c.Check(seclog.GrantRootAuth.WithInterface("", false), Equals, seclog.GrantRootAuth)
 }
+
+func (s *SecLogSuite) TestSnapString(c *C) {
+	c.Check(seclog.Snap{Name: "foo", SnapID: "foo-id", Revision: "3"}.String(), Equals, "foo:3")
+	c.Check(seclog.Snap{Name: "foo"}.String(), Equals, "foo:<unknown>")
+	c.Check(seclog.Snap{}.String(), Equals, "<unknown>:<unknown>")
+}
+
+func (s *SecLogSuite) TestConnectionString(c *C) {
+	c.Check(seclog.Connection{
+		Interface: "network", PlugSnap: "consumer", PlugName: "net", SlotSnap: "snapd", SlotName: "network",
+	}.String(), Equals, "consumer:net snapd:network")
+}
+
+func (s *SecLogSuite) TestValidationSetString(c *C) {
+	c.Check(seclog.ValidationSet{AccountID: "acc-id", Name: "base", Sequence: 3}.String(), Equals, "acc-id/base=3")
+}
+
+func (s *SecLogSuite) TestAssertionString(c *C) {
+	c.Check(seclog.Assertion{Type: "snap-declaration", PrimaryKey: "16/foo-id", Revision: 1}.String(), Equals, "snap-declaration:16/foo-id")
+}
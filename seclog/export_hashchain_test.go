@@ -0,0 +1,46 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seclog
+
+import (
+	"time"
+
+	"github.com/snapcore/snapd/testutil"
+)
+
+func MockCheckpointInterval(n uint64) (restore func()) {
+	return testutil.Mock(&checkpointInterval, n)
+}
+
+func MockCheckpointPeriod(d time.Duration) (restore func()) {
+	return testutil.Mock(&checkpointPeriod, d)
+}
+
+func MockHeadSaveInterval(n uint64) (restore func()) {
+	return testutil.Mock(&headSaveInterval, n)
+}
+
+func MockHeadSavePeriod(d time.Duration) (restore func()) {
+	return testutil.Mock(&headSavePeriod, d)
+}
+
+func MockHeadRecoveryTail(n int64) (restore func()) {
+	return testutil.Mock(&headRecoveryTail, n)
+}
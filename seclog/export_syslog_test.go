@@ -0,0 +1,57 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build go1.21 && !noslog
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seclog
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/snapcore/snapd/testutil"
+)
+
+var ReadFrames = readFrames
+
+func MockSyslogDial(f func(network, address string, tlsConfig *tls.Config) (net.Conn, error)) (restore func()) {
+	return testutil.Mock(&syslogDial, f)
+}
+
+func MockSyslogRetryInterval(d time.Duration) (restore func()) {
+	return testutil.Mock(&syslogRetryInterval, d)
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	return testutil.Mock(&timeNow, f)
+}
+
+// SyslogBuffer exposes the buffer of the syslog logger for testing.
+type SyslogBuffer struct {
+	b *syslogBuffer
+}
+
+func NewSyslogBuffer(path string, maxSize int64) *SyslogBuffer {
+	return &SyslogBuffer{b: newSyslogBuffer(path, maxSize)}
+}
+
+func (b *SyslogBuffer) Append(msgs ...[]byte) error { return b.b.append(msgs...) }
+func (b *SyslogBuffer) Replace(msgs [][]byte) error { return b.b.replace(msgs) }
+func (b *SyslogBuffer) Load() ([][]byte, error)     { return b.b.load() }
+func (b *SyslogBuffer) Empty() bool                 { return b.b.empty() }
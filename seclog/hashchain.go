@@ -0,0 +1,509 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seclog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// HashChainFile is the name of the file holding the state of the hash chain
+// of the security log of snapd, in the security logging directory.
+const HashChainFile = "hash-chain.json"
+
+// AuditLogFile is where auditd keeps the audit log, which the security log
+// records written to the audit subsystem end up in.
+const AuditLogFile = "/var/log/audit/audit.log"
+
+var (
+	// checkpointInterval is the maximum number of records between signed
+	// checkpoints.
+	checkpointInterval uint64 = 100
+	// checkpointPeriod is the maximum time between signed checkpoints.
+	checkpointPeriod = time.Hour
+
+	// headSaveInterval is the maximum number of records written before the
+	// head of the chain is saved.
+	headSaveInterval uint64 = 64
+	// headSavePeriod is the maximum time the saved head of the chain lags
+	// behind the records written.
+	headSavePeriod = time.Minute
+	// headRecoveryTail is how much of the end of the log is read to recover
+	// the records written after the head of the chain was last saved.
+	headRecoveryTail int64 = 1024 * 1024
+)
+
+// CheckpointSigner signs the hash of a checkpoint record of the security log
+// and returns the signature along with the ID of the signing key.
+type CheckpointSigner func(data []byte) (signature []byte, keyID string, err error)
+
+var (
+	checkpointSigner CheckpointSigner
+	signerLock       sync.Mutex
+)
+
+// SetupCheckpointSigner sets the signer of the checkpoints of the hash
+// chained security log, replacing any previously set signer. Passing nil
+// stops the signing of checkpoints.
+//
+// The signer is called while events are logged, so it must not wait for
+// anything that could itself be logging security events.
+func SetupCheckpointSigner(signer CheckpointSigner) {
+	signerLock.Lock()
+	defer signerLock.Unlock()
+
+	checkpointSigner = signer
+}
+
+func currentCheckpointSigner() CheckpointSigner {
+	signerLock.Lock()
+	defer signerLock.Unlock()
+
+	return checkpointSigner
+}
+
+// HashChainHead is the head of the hash chain of the security log, as
+// persisted by a [HashChainWriter].
+type HashChainHead struct {
+	// Seq is the sequence number of the last record.
+	Seq uint64 `json:"seq"`
+	// Hash is the hash of the last record.
+	Hash string `json:"hash"`
+}
+
+// ReadHashChainHead returns the head of the hash chain whose state is kept
+// in the file at statePath.
+func ReadHashChainHead(statePath string) (*HashChainHead, error) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, err
+	}
+	var head HashChainHead
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("cannot decode security log hash chain state: %v", err)
+	}
+	return &head, nil
+}
+
+// HashChainWriter implements [io.Writer]. It makes the JSON records written
+// by a slog based security logger tamper-evident: each record is extended
+// with its sequence number, the hash of the previous record and its own hash,
+// which covers the record, its sequence number and the previous hash. The
+// head of the chain is kept in a file so that the chain continues across
+// restarts. To avoid syncing the file for every record, it is saved at
+// checkpoints, once enough records or time went by, and when the writer is
+// closed. The records written after the head was last saved are recovered
+// from the end of the log.
+//
+// Periodically, if a [CheckpointSigner] is set up, a record also carries the
+// signature of its hash, which vouches for all the records up to it. See
+// [VerifyHashChain].
+//
+// It must be created via [NewHashChainWriter]; the zero value is not usable.
+type HashChainWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	statePath string
+	state     HashChainHead
+
+	// savedSeq is the sequence number of the last saved head.
+	savedSeq uint64
+	// savedTime is when the head was last saved.
+	savedTime time.Time
+
+	// checkpointSeq is the sequence number of the last signed record.
+	checkpointSeq uint64
+	// checkpointTime is when the last signed record was written.
+	checkpointTime time.Time
+}
+
+// NewHashChainWriter returns a [HashChainWriter] writing the chained records
+// to w and keeping the head of the chain in the file at statePath.
+//
+// logPath is the log that the records written to w end up in, if it can be
+// read. The chain continues from the last record found at its end if that
+// record follows the saved head. Otherwise, if the head cannot be read, a new
+// chain is started.
+func NewHashChainWriter(w io.Writer, statePath, logPath string) *HashChainWriter {
+	hw := &HashChainWriter{w: w, statePath: statePath, savedTime: time.Now()}
+	var lost error
+	data, err := os.ReadFile(statePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		lost = fmt.Errorf("cannot read security log hash chain state: %v", err)
+	default:
+		if err := json.Unmarshal(data, &hw.state); err != nil {
+			lost = fmt.Errorf("cannot decode security log hash chain state: %v", err)
+			hw.state = HashChainHead{}
+		}
+	}
+	hw.savedSeq = hw.state.Seq
+
+	if logPath != "" {
+		head, err := lastChainedRecord(logPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Noticef("cannot read the end of the security log: %v", err)
+		}
+		if head != nil && head.Seq > hw.state.Seq {
+			logger.Debugf("continuing the security log hash chain from record %d of %s", head.Seq, logPath)
+			hw.state = *head
+		}
+	}
+
+	if lost != nil {
+		if hw.state.Seq == 0 {
+			logger.Noticef("%v, starting a new chain", lost)
+		} else {
+			logger.Noticef("%v, continuing from the end of the log", lost)
+		}
+	}
+	return hw
+}
+
+// lastChainedRecord returns the sequence number and hash of the last chained
+// record found at the end of the log at logPath, if any.
+func lastChainedRecord(logPath string) (*HashChainHead, error) {
+	f, err := os.Open(logPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() > headRecoveryTail {
+		if _, err := f.Seek(fi.Size()-headRecoveryTail, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	var head *HashChainHead
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		rec := parseChainedRecord(scanner.Bytes())
+		if rec == nil || chainHash(rec.prevHash, rec.seq, rec.record) != rec.hash {
+			// the first line may be partial, and records which were
+			// altered are left for verification to report
+			continue
+		}
+		head = &HashChainHead{Seq: rec.seq, Hash: rec.hash}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// chainHash returns the hash of the record with the given sequence number
+// following the record with hash prevHash.
+func chainHash(prevHash string, seq uint64, record []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%d\n", prevHash, seq)
+	h.Write(record)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Write extends the JSON record in p, which must be a single JSON object
+// optionally followed by a newline, and writes it to the underlying writer.
+// The returned byte count reflects the original record length.
+func (hw *HashChainWriter) Write(p []byte) (int, error) {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+
+	record := bytes.TrimRight(p, "\n")
+	if len(record) < 2 || record[0] != '{' || record[len(record)-1] != '}' {
+		return 0, fmt.Errorf("cannot chain security log record: not a JSON object")
+	}
+
+	seq := hw.state.Seq + 1
+	hash := chainHash(hw.state.Hash, seq, record)
+
+	chained := make([]byte, 0, len(record)+256)
+	chained = append(chained, record[:len(record)-1]...)
+	chained = append(chained, `,"seq":`...)
+	chained = strconv.AppendUint(chained, seq, 10)
+	chained = append(chained, `,"prev_hash":"`...)
+	chained = append(chained, hw.state.Hash...)
+	chained = append(chained, `","hash":"`...)
+	chained = append(chained, hash...)
+	chained = append(chained, '"')
+	signed := false
+	if signer := currentCheckpointSigner(); signer != nil && hw.checkpointDue(seq) {
+		sig, keyID, err := signer([]byte(hash))
+		if err != nil {
+			logger.Noticef("cannot sign security log checkpoint: %v", err)
+		} else {
+			chained = append(chained, `,"signature":"`...)
+			chained = append(chained, base64.StdEncoding.EncodeToString(sig)...)
+			chained = append(chained, `","sign_key_id":`...)
+			chained = strconv.AppendQuote(chained, keyID)
+			signed = true
+		}
+	}
+	chained = append(chained, "}\n"...)
+
+	if _, err := hw.w.Write(chained); err != nil {
+		return 0, err
+	}
+
+	hw.state = HashChainHead{Seq: seq, Hash: hash}
+	if signed {
+		hw.checkpointSeq = seq
+		hw.checkpointTime = time.Now()
+	}
+	if signed || seq-hw.savedSeq >= headSaveInterval || time.Since(hw.savedTime) >= headSavePeriod {
+		if err := hw.saveState(); err != nil {
+			logger.Noticef("cannot save security log hash chain state: %v", err)
+		}
+	}
+	return len(p), nil
+}
+
+func (hw *HashChainWriter) checkpointDue(seq uint64) bool {
+	return hw.checkpointTime.IsZero() ||
+		seq-hw.checkpointSeq >= checkpointInterval ||
+		time.Since(hw.checkpointTime) >= checkpointPeriod
+}
+
+func (hw *HashChainWriter) saveState() error {
+	data, err := json.Marshal(hw.state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(hw.statePath), 0700); err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(hw.statePath, data, 0600, 0); err != nil {
+		return err
+	}
+	hw.savedSeq = hw.state.Seq
+	hw.savedTime = time.Now()
+	return nil
+}
+
+// Close saves the head of the chain and closes the underlying writer if it
+// implements [io.Closer].
+func (hw *HashChainWriter) Close() error {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+
+	if hw.state.Seq != hw.savedSeq {
+		if err := hw.saveState(); err != nil {
+			logger.Noticef("cannot save security log hash chain state: %v", err)
+		}
+	}
+	if closer, ok := hw.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// chainedRecordRx matches the fields added by [HashChainWriter] at the end
+// of a record.
+var chainedRecordRx = regexp.MustCompile(`,"seq":([0-9]+),"prev_hash":"([0-9a-f]*)","hash":"([0-9a-f]{64})"(?:,"signature":"([A-Za-z0-9+/=]+)","sign_key_id":"([^"\\]+)")?}$`)
+
+// chainedRecord is a record found in a line of the log.
+type chainedRecord struct {
+	seq      uint64
+	prevHash string
+	hash     string
+	// record is the record without the fields added by [HashChainWriter].
+	record []byte
+	// signature and keyID are set for checkpoint records.
+	signature string
+	keyID     string
+	// err is set if the sequence number is invalid.
+	err error
+}
+
+// parseChainedRecord returns the chained record found between the first '{'
+// and the last '}' of the line, or nil if there is none.
+func parseChainedRecord(text []byte) *chainedRecord {
+	start := bytes.IndexByte(text, '{')
+	end := bytes.LastIndexByte(text, '}')
+	if start < 0 || end < start {
+		return nil
+	}
+	chained := text[start : end+1]
+	m := chainedRecordRx.FindSubmatchIndex(chained)
+	if m == nil {
+		return nil
+	}
+	rec := &chainedRecord{
+		prevHash: string(chained[m[4]:m[5]]),
+		hash:     string(chained[m[6]:m[7]]),
+		record:   append(append([]byte(nil), chained[:m[0]]...), '}'),
+	}
+	rec.seq, rec.err = strconv.ParseUint(string(chained[m[2]:m[3]]), 10, 64)
+	if m[8] >= 0 {
+		rec.signature = string(chained[m[8]:m[9]])
+		rec.keyID = string(chained[m[10]:m[11]])
+	}
+	return rec
+}
+
+// HashChainReport describes the outcome of [VerifyHashChain].
+type HashChainReport struct {
+	// Records is the number of chained records found.
+	Records int
+	// FirstSeq and LastSeq are the sequence numbers of the first and last
+	// chained records found.
+	FirstSeq uint64
+	LastSeq  uint64
+	// Checkpoints is the number of records carrying a valid signature.
+	Checkpoints int
+	// LastCheckpointSeq is the sequence number of the last record carrying
+	// a valid signature, or 0 if there is none.
+	LastCheckpointSeq uint64
+	// Problems lists the gaps and modifications found, if any.
+	Problems []string
+}
+
+// VerifyHashChain checks the chained security log records read from r, one
+// per line, for gaps and modifications. Each record is looked for between
+// the first '{' and the last '}' of the line, so the records can be embedded
+// in lines of other logs, such as the audit log; lines without a chained
+// record are skipped.
+//
+// verify is called to check the signature of the checkpoint records with
+// the key of the given ID.
+//
+// If head is not nil, the last records of the log are checked against it, to
+// detect the removal of records at the end of the log. The head must be read
+// before the log, as records can be added meanwhile.
+//
+// A chain starting anew after the first record is reported as a problem,
+// unless it follows a valid checkpoint. The records following the last valid
+// checkpoint can still be altered without being noticed, if the head is not
+// checked.
+func VerifyHashChain(r io.Reader, head *HashChainHead, verify func(data, signature []byte, keyID string) error) (*HashChainReport, error) {
+	report := &HashChainReport{}
+	problemf := func(line int, format string, args ...any) {
+		report.Problems = append(report.Problems, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+	}
+
+	var prevSeq uint64
+	var prevHash string
+	// prevCheckpoint is set if the previous record carries a valid signature
+	var prevCheckpoint bool
+	// headHash is the hash of the last record with the sequence number of
+	// the head
+	var headHash string
+	scanner := bufio.NewScanner(r)
+	// records can be larger than the default maximum token size
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		rec := parseChainedRecord(scanner.Bytes())
+		if rec == nil {
+			continue
+		}
+		if rec.err != nil {
+			problemf(line, "invalid sequence number: %v", rec.err)
+			continue
+		}
+		seq, recPrevHash, hash := rec.seq, rec.prevHash, rec.hash
+
+		report.Records++
+		if report.Records == 1 {
+			report.FirstSeq = seq
+		}
+		report.LastSeq = seq
+
+		if chainHash(recPrevHash, seq, rec.record) != hash {
+			problemf(line, "record %d has been modified", seq)
+		}
+		switch {
+		case report.Records == 1:
+			// the log starts with a chain, or in the middle of one
+		case seq == 1 && recPrevHash == "":
+			// a new chain can only start legitimately once the
+			// previous records are vouched for
+			if !prevCheckpoint {
+				problemf(line, "the chain starts anew after record %d", prevSeq)
+			}
+		case seq <= prevSeq:
+			problemf(line, "record %d follows record %d", seq, prevSeq)
+		case seq == prevSeq+2:
+			problemf(line, "record %d is missing", prevSeq+1)
+		case seq != prevSeq+1:
+			problemf(line, "records %d to %d are missing", prevSeq+1, seq-1)
+		case recPrevHash != prevHash:
+			problemf(line, "record %d does not follow record %d", seq, prevSeq)
+		}
+		prevSeq, prevHash = seq, hash
+		if head != nil && seq == head.Seq {
+			headHash = hash
+		}
+
+		prevCheckpoint = false
+		if rec.signature == "" {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(rec.signature)
+		if err != nil {
+			problemf(line, "cannot decode signature of record %d: %v", seq, err)
+			continue
+		}
+		if err := verify([]byte(hash), sig, rec.keyID); err != nil {
+			problemf(line, "invalid signature of record %d: %v", seq, err)
+			continue
+		}
+		report.Checkpoints++
+		report.LastCheckpointSeq = seq
+		prevCheckpoint = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if head != nil && head.Seq > 0 {
+		switch {
+		case headHash == "" && report.LastSeq+1 == head.Seq:
+			report.Problems = append(report.Problems, fmt.Sprintf("record %d is missing at the end of the log", head.Seq))
+		case headHash == "" && report.LastSeq < head.Seq:
+			report.Problems = append(report.Problems, fmt.Sprintf("records %d to %d are missing at the end of the log", report.LastSeq+1, head.Seq))
+		case headHash == "":
+			report.Problems = append(report.Problems, fmt.Sprintf("record %d at the head of the chain is missing", head.Seq))
+		case headHash != head.Hash:
+			report.Problems = append(report.Problems, fmt.Sprintf("record %d does not match the head of the chain", head.Seq))
+		}
+	}
+	return report, nil
+}
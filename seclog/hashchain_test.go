@@ -0,0 +1,540 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seclog_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/seclog"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type HashChainSuite struct {
+	testutil.BaseTest
+
+	statePath string
+	logbuf    *bytes.Buffer
+
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+var _ = Suite(&HashChainSuite{})
+
+func (s *HashChainSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.statePath = filepath.Join(c.MkDir(), "seclog", seclog.HashChainFile)
+
+	logbuf, restore := logger.MockLogger()
+	s.logbuf = logbuf
+	s.AddCleanup(restore)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, IsNil)
+	s.pub, s.priv = pub, priv
+
+	s.AddCleanup(func() { seclog.SetupCheckpointSigner(nil) })
+}
+
+func (s *HashChainSuite) sign(data []byte) ([]byte, string, error) {
+	return ed25519.Sign(s.priv, data), "test-key", nil
+}
+
+func (s *HashChainSuite) verify(data, signature []byte, keyID string) error {
+	if keyID != "test-key" {
+		return fmt.Errorf("unknown key %q", keyID)
+	}
+	if !ed25519.Verify(s.pub, data, signature) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (s *HashChainSuite) writeRecords(c *C, hw *seclog.HashChainWriter, from, to int) {
+	for i := from; i <= to; i++ {
+		record := fmt.Sprintf(`{"description":"event %d","event":"test_event"}`+"\n", i)
+		n, err := hw.Write([]byte(record))
+		c.Assert(err, IsNil)
+		c.Check(n, Equals, len(record))
+	}
+}
+
+func (s *HashChainSuite) verifyLog(c *C, log string) *seclog.HashChainReport {
+	return s.verifyLogWithHead(c, log, nil)
+}
+
+func (s *HashChainSuite) verifyLogWithHead(c *C, log string, head *seclog.HashChainHead) *seclog.HashChainReport {
+	report, err := seclog.VerifyHashChain(strings.NewReader(log), head, s.verify)
+	c.Assert(err, IsNil)
+	return report
+}
+
+func (s *HashChainSuite) TestChainedRecords(c *C) {
+	buf := &bytes.Buffer{}
+	hw := seclog.NewHashChainWriter(buf, s.statePath, "")
+	s.writeRecords(c, hw, 1, 3)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	c.Assert(lines, HasLen, 3)
+
+	var prevHash string
+	for i, line := range lines {
+		var record struct {
+			Description string `json:"description"`
+			Seq         uint64 `json:"seq"`
+			PrevHash    string `json:"prev_hash"`
+			Hash        string `json:"hash"`
+		}
+		c.Assert(json.Unmarshal([]byte(line), &record), IsNil)
+		c.Check(record.Description, Equals, fmt.Sprintf("event %d", i+1))
+		c.Check(record.Seq, Equals, uint64(i+1))
+		c.Check(record.PrevHash, Equals, prevHash)
+		c.Check(record.Hash, HasLen, 64)
+		c.Check(line, Not(testutil.Contains), "signature")
+		prevHash = record.Hash
+	}
+
+	// the head is saved in batches, or once closed
+	c.Check(s.statePath, testutil.FileAbsent)
+	c.Assert(hw.Close(), IsNil)
+	c.Check(s.statePath, testutil.FileEquals, fmt.Sprintf(`{"seq":3,"hash":"%s"}`, prevHash))
+
+	report := s.verifyLog(c, buf.String())
+	c.Check(report, DeepEquals, &seclog.HashChainReport{
+		Records:  3,
+		FirstSeq: 1,
+		LastSeq:  3,
+	})
+}
+
+// writeRecordsAndClose writes records to a new writer, which is then closed.
+func (s *HashChainSuite) writeRecordsAndClose(c *C, w io.Writer, from, to int) {
+	hw := seclog.NewHashChainWriter(w, s.statePath, "")
+	s.writeRecords(c, hw, from, to)
+	c.Assert(hw.Close(), IsNil)
+}
+
+func (s *HashChainSuite) TestChainContinuesAcrossRestarts(c *C) {
+	buf := &bytes.Buffer{}
+	s.writeRecordsAndClose(c, buf, 1, 2)
+	s.writeRecordsAndClose(c, buf, 3, 4)
+
+	c.Check(buf.String(), testutil.Contains, `"description":"event 3","event":"test_event","seq":3,`)
+	report := s.verifyLog(c, buf.String())
+	c.Check(report.Records, Equals, 4)
+	c.Check(report.Problems, HasLen, 0)
+}
+
+func (s *HashChainSuite) TestHeadSavedInBatches(c *C) {
+	restore := seclog.MockHeadSaveInterval(3)
+	defer restore()
+
+	buf := &bytes.Buffer{}
+	hw := seclog.NewHashChainWriter(buf, s.statePath, "")
+	s.writeRecords(c, hw, 1, 2)
+	c.Check(s.statePath, testutil.FileAbsent)
+	s.writeRecords(c, hw, 3, 3)
+	head, err := seclog.ReadHashChainHead(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(head.Seq, Equals, uint64(3))
+	s.writeRecords(c, hw, 4, 5)
+	head, err = seclog.ReadHashChainHead(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(head.Seq, Equals, uint64(3))
+
+	// or once enough time went by
+	restore = seclog.MockHeadSavePeriod(0)
+	defer restore()
+	s.writeRecords(c, hw, 6, 6)
+	head, err = seclog.ReadHashChainHead(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(head.Seq, Equals, uint64(6))
+}
+
+func (s *HashChainSuite) TestHeadSavedAtCheckpoints(c *C) {
+	restore := seclog.MockCheckpointInterval(2)
+	defer restore()
+	seclog.SetupCheckpointSigner(s.sign)
+
+	buf := &bytes.Buffer{}
+	hw := seclog.NewHashChainWriter(buf, s.statePath, "")
+	// the first record is a checkpoint, the next one is at the third
+	s.writeRecords(c, hw, 1, 2)
+	head, err := seclog.ReadHashChainHead(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(head.Seq, Equals, uint64(1))
+	s.writeRecords(c, hw, 3, 3)
+	head, err = seclog.ReadHashChainHead(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(head.Seq, Equals, uint64(3))
+}
+
+func (s *HashChainSuite) TestChainRecoveredFromLog(c *C) {
+	logPath := filepath.Join(c.MkDir(), "audit.log")
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	hw := seclog.NewHashChainWriter(f, s.statePath, logPath)
+	s.writeRecords(c, hw, 1, 2)
+	c.Assert(hw.Close(), IsNil)
+
+	// records written after the head was saved, as before a crash
+	f, err = os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0600)
+	c.Assert(err, IsNil)
+	defer f.Close()
+	s.writeRecords(c, seclog.NewHashChainWriter(f, s.statePath, logPath), 3, 5)
+	// along with other lines in the log
+	_, err = f.WriteString("type=AVC msg=audit(1.2:3): unrelated\n")
+	c.Assert(err, IsNil)
+	head, err := seclog.ReadHashChainHead(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(head.Seq, Equals, uint64(2))
+
+	// only the end of the log is needed
+	restore := seclog.MockHeadRecoveryTail(512)
+	defer restore()
+	s.writeRecords(c, seclog.NewHashChainWriter(f, s.statePath, logPath), 6, 6)
+
+	data, err := os.ReadFile(logPath)
+	c.Assert(err, IsNil)
+	c.Check(string(data), testutil.Contains, `"description":"event 6","event":"test_event","seq":6,`)
+	report := s.verifyLog(c, string(data))
+	c.Check(report.Records, Equals, 6)
+	c.Check(report.Problems, HasLen, 0)
+}
+
+func (s *HashChainSuite) TestChainRecoveredFromLogWhenStateLost(c *C) {
+	logPath := filepath.Join(c.MkDir(), "audit.log")
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	s.writeRecords(c, seclog.NewHashChainWriter(f, s.statePath, logPath), 1, 2)
+	c.Assert(os.MkdirAll(filepath.Dir(s.statePath), 0700), IsNil)
+	c.Assert(os.WriteFile(s.statePath, []byte("garbage"), 0600), IsNil)
+	s.writeRecords(c, seclog.NewHashChainWriter(f, s.statePath, logPath), 3, 3)
+
+	c.Check(s.logbuf.String(), testutil.Contains, "cannot decode security log hash chain state: invalid character 'g' looking for beginning of value, continuing from the end of the log")
+	data, err := os.ReadFile(logPath)
+	c.Assert(err, IsNil)
+	report := s.verifyLog(c, string(data))
+	c.Check(report.Records, Equals, 3)
+	c.Check(report.Problems, HasLen, 0)
+}
+
+func (s *HashChainSuite) TestNewChainWhenStateLost(c *C) {
+	buf := &bytes.Buffer{}
+	s.writeRecordsAndClose(c, buf, 1, 2)
+
+	c.Assert(os.WriteFile(s.statePath, []byte("garbage"), 0600), IsNil)
+	s.writeRecords(c, seclog.NewHashChainWriter(buf, s.statePath, ""), 1, 1)
+
+	c.Check(s.logbuf.String(), testutil.Contains, "cannot decode security log hash chain state: invalid character 'g' looking for beginning of value, starting a new chain")
+	c.Check(buf.String(), testutil.Contains, `"description":"event 1","event":"test_event","seq":1,"prev_hash":"",`)
+	report := s.verifyLog(c, buf.String())
+	c.Check(report.Records, Equals, 3)
+	// the previous records could have been replaced
+	c.Check(report.Problems, DeepEquals, []string{"line 3: the chain starts anew after record 2"})
+}
+
+func (s *HashChainSuite) TestNewChainAfterCheckpoint(c *C) {
+	restore := seclog.MockCheckpointInterval(1)
+	defer restore()
+	seclog.SetupCheckpointSigner(s.sign)
+
+	buf := &bytes.Buffer{}
+	s.writeRecords(c, seclog.NewHashChainWriter(buf, s.statePath, ""), 1, 2)
+	c.Assert(os.Remove(s.statePath), IsNil)
+	s.writeRecords(c, seclog.NewHashChainWriter(buf, s.statePath, ""), 1, 1)
+
+	report := s.verifyLog(c, buf.String())
+	c.Check(report.Records, Equals, 3)
+	c.Check(report.Checkpoints, Equals, 3)
+	c.Check(report.Problems, HasLen, 0)
+}
+
+func (s *HashChainSuite) TestWriteNotJSONObject(c *C) {
+	buf := &bytes.Buffer{}
+	hw := seclog.NewHashChainWriter(buf, s.statePath, "")
+
+	_, err := hw.Write([]byte("not json\n"))
+	c.Check(err, ErrorMatches, "cannot chain security log record: not a JSON object")
+	c.Check(buf.Len(), Equals, 0)
+	c.Check(s.statePath, testutil.FileAbsent)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+func (s *HashChainSuite) TestWriteErrorDoesNotAdvance(c *C) {
+	hw := seclog.NewHashChainWriter(failingWriter{}, s.statePath, "")
+	_, err := hw.Write([]byte(`{"description":"lost"}`))
+	c.Check(err, ErrorMatches, "boom")
+	c.Check(s.statePath, testutil.FileAbsent)
+
+	buf := &bytes.Buffer{}
+	s.writeRecords(c, seclog.NewHashChainWriter(buf, s.statePath, ""), 1, 1)
+	c.Check(buf.String(), testutil.Contains, `"seq":1,"prev_hash":"",`)
+}
+
+func (s *HashChainSuite) TestCheckpoints(c *C) {
+	restore := seclog.MockCheckpointInterval(2)
+	defer restore()
+
+	buf := &bytes.Buffer{}
+	hw := seclog.NewHashChainWriter(buf, s.statePath, "")
+
+	// no signer yet
+	s.writeRecords(c, hw, 1, 1)
+	seclog.SetupCheckpointSigner(s.sign)
+	s.writeRecords(c, hw, 2, 6)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	c.Assert(lines, HasLen, 6)
+	var signed []int
+	for i, line := range lines {
+		if strings.Contains(line, `"sign_key_id":"test-key"}`) {
+			signed = append(signed, i+1)
+		}
+	}
+	// the first record once the signer is available is signed, then every
+	// other record
+	c.Check(signed, DeepEquals, []int{2, 4, 6})
+
+	report := s.verifyLog(c, buf.String())
+	c.Check(report, DeepEquals, &seclog.HashChainReport{
+		Records:           6,
+		FirstSeq:          1,
+		LastSeq:           6,
+		Checkpoints:       3,
+		LastCheckpointSeq: 6,
+	})
+}
+
+func (s *HashChainSuite) TestCheckpointsPeriod(c *C) {
+	restore := seclog.MockCheckpointPeriod(0)
+	defer restore()
+	seclog.SetupCheckpointSigner(s.sign)
+
+	buf := &bytes.Buffer{}
+	s.writeRecords(c, seclog.NewHashChainWriter(buf, s.statePath, ""), 1, 3)
+
+	report := s.verifyLog(c, buf.String())
+	c.Check(report.Checkpoints, Equals, 3)
+}
+
+func (s *HashChainSuite) TestCheckpointSignerError(c *C) {
+	seclog.SetupCheckpointSigner(func(data []byte) ([]byte, string, error) {
+		return nil, "", errors.New("no key")
+	})
+
+	buf := &bytes.Buffer{}
+	s.writeRecords(c, seclog.NewHashChainWriter(buf, s.statePath, ""), 1, 1)
+
+	c.Check(s.logbuf.String(), testutil.Contains, "cannot sign security log checkpoint: no key")
+	c.Check(buf.String(), Not(testutil.Contains), "signature")
+	report := s.verifyLog(c, buf.String())
+	c.Check(report.Records, Equals, 1)
+	c.Check(report.Checkpoints, Equals, 0)
+}
+
+// chainedLog returns the records of a new chain, with the descriptions of
+// events from to to.
+func (s *HashChainSuite) chainedLog(c *C, from, to int) []string {
+	seclog.SetupCheckpointSigner(s.sign)
+	buf := &bytes.Buffer{}
+	statePath := filepath.Join(c.MkDir(), seclog.HashChainFile)
+	s.writeRecords(c, seclog.NewHashChainWriter(buf, statePath, ""), from, to)
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func (s *HashChainSuite) TestVerifyModified(c *C) {
+	lines := s.chainedLog(c, 1, 3)
+	lines[1] = strings.Replace(lines[1], "event 2", "event X", 1)
+
+	report := s.verifyLog(c, strings.Join(lines, "\n"))
+	c.Check(report.Records, Equals, 3)
+	c.Check(report.Problems, DeepEquals, []string{"line 2: record 2 has been modified"})
+}
+
+func (s *HashChainSuite) TestVerifyMissing(c *C) {
+	lines := s.chainedLog(c, 1, 5)
+
+	report := s.verifyLog(c, strings.Join([]string{lines[0], lines[2], lines[4]}, "\n"))
+	c.Check(report.Problems, DeepEquals, []string{
+		"line 2: record 2 is missing",
+		"line 3: record 4 is missing",
+	})
+
+	report = s.verifyLog(c, strings.Join([]string{lines[0], lines[4]}, "\n"))
+	c.Check(report.Problems, DeepEquals, []string{
+		"line 2: records 2 to 4 are missing",
+	})
+}
+
+func (s *HashChainSuite) TestVerifyReordered(c *C) {
+	lines := s.chainedLog(c, 1, 3)
+
+	report := s.verifyLog(c, strings.Join([]string{lines[0], lines[2], lines[1]}, "\n"))
+	c.Check(report.Problems, DeepEquals, []string{
+		"line 2: record 2 is missing",
+		"line 3: record 2 follows record 3",
+	})
+}
+
+func (s *HashChainSuite) TestVerifyForgedSequence(c *C) {
+	lines := s.chainedLog(c, 1, 2)
+	other := s.chainedLog(c, 3, 4)
+
+	// a record of another chain, with a consistent hash and sequence number
+	report := s.verifyLog(c, strings.Join([]string{lines[0], other[1]}, "\n"))
+	c.Check(report.Problems, DeepEquals, []string{
+		"line 2: record 2 does not follow record 1",
+	})
+}
+
+func (s *HashChainSuite) TestVerifyInvalidSignature(c *C) {
+	lines := s.chainedLog(c, 1, 1)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, IsNil)
+	s.priv = otherPriv
+	lines = append(lines, s.chainedLog(c, 1, 1)...)
+
+	report := s.verifyLog(c, strings.Join(lines, "\n"))
+	c.Check(report.Checkpoints, Equals, 1)
+	c.Check(report.LastCheckpointSeq, Equals, uint64(1))
+	c.Check(report.Problems, DeepEquals, []string{
+		"line 2: invalid signature of record 1: signature mismatch",
+	})
+}
+
+func (s *HashChainSuite) TestVerifyEmbeddedRecords(c *C) {
+	lines := s.chainedLog(c, 1, 2)
+
+	log := fmt.Sprintf(`type=DAEMON_START msg=audit(1700000000.000:1): op=start
+type=TRUSTED_APP msg=audit(1700000000.100:2): %s
+type=USER_LOGIN msg=audit(1700000000.200:3): {"not":"chained"}
+type=TRUSTED_APP msg=audit(1700000000.300:4): %s
+`, lines[0], lines[1])
+	report := s.verifyLog(c, log)
+	c.Check(report, DeepEquals, &seclog.HashChainReport{
+		Records:           2,
+		FirstSeq:          1,
+		LastSeq:           2,
+		Checkpoints:       1,
+		LastCheckpointSeq: 1,
+	})
+}
+
+func (s *HashChainSuite) TestVerifyStartsMidChain(c *C) {
+	lines := s.chainedLog(c, 1, 3)
+
+	report := s.verifyLog(c, strings.Join(lines[1:], "\n"))
+	c.Check(report.Records, Equals, 2)
+	c.Check(report.FirstSeq, Equals, uint64(2))
+	c.Check(report.Problems, HasLen, 0)
+}
+
+func (s *HashChainSuite) TestReadHashChainHead(c *C) {
+	_, err := seclog.ReadHashChainHead(s.statePath)
+	c.Check(errors.Is(err, os.ErrNotExist), Equals, true)
+
+	buf := &bytes.Buffer{}
+	s.writeRecordsAndClose(c, buf, 1, 2)
+	head, err := seclog.ReadHashChainHead(s.statePath)
+	c.Assert(err, IsNil)
+	c.Check(head.Seq, Equals, uint64(2))
+	c.Check(buf.String(), testutil.Contains, fmt.Sprintf(`"seq":2,"prev_hash":"%s","hash":"%s"`, s.recordHash(c, buf, 1), head.Hash))
+
+	c.Assert(os.WriteFile(s.statePath, []byte("garbage"), 0600), IsNil)
+	_, err = seclog.ReadHashChainHead(s.statePath)
+	c.Check(err, ErrorMatches, "cannot decode security log hash chain state: .*")
+}
+
+// recordHash returns the hash of the record with the given index in buf.
+func (s *HashChainSuite) recordHash(c *C, buf *bytes.Buffer, i int) string {
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	var record struct {
+		Hash string `json:"hash"`
+	}
+	c.Assert(json.Unmarshal([]byte(lines[i-1]), &record), IsNil)
+	return record.Hash
+}
+
+func (s *HashChainSuite) TestVerifyHead(c *C) {
+	buf := &bytes.Buffer{}
+	s.writeRecordsAndClose(c, buf, 1, 4)
+	head, err := seclog.ReadHashChainHead(s.statePath)
+	c.Assert(err, IsNil)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	report := s.verifyLogWithHead(c, buf.String(), head)
+	c.Check(report.Problems, HasLen, 0)
+
+	// records added after the head was read
+	older := &seclog.HashChainHead{Seq: 3, Hash: s.recordHash(c, buf, 3)}
+	report = s.verifyLogWithHead(c, buf.String(), older)
+	c.Check(report.Problems, HasLen, 0)
+
+	// truncated log
+	report = s.verifyLogWithHead(c, strings.Join(lines[:3], "\n"), head)
+	c.Check(report.Problems, DeepEquals, []string{"record 4 is missing at the end of the log"})
+	report = s.verifyLogWithHead(c, strings.Join(lines[:2], "\n"), head)
+	c.Check(report.Problems, DeepEquals, []string{"records 3 to 4 are missing at the end of the log"})
+
+	// a tail of another chain
+	other := s.chainedLog(c, 5, 8)
+	report = s.verifyLogWithHead(c, strings.Join(append(lines[:3:3], other[3]), "\n"), head)
+	c.Check(report.Problems, DeepEquals, []string{
+		"line 4: record 4 does not follow record 3",
+		"record 4 does not match the head of the chain",
+	})
+
+	// the head record is gone
+	report = s.verifyLogWithHead(c, strings.Join([]string{lines[0], lines[1], lines[3]}, "\n"), older)
+	c.Check(report.Problems, DeepEquals, []string{
+		"line 3: record 3 is missing",
+		"record 3 at the head of the chain is missing",
+	})
+
+	// the last records are replaced by a new chain
+	report = s.verifyLogWithHead(c, strings.Join(append(lines[:2:2], other[0]), "\n"), head)
+	c.Check(report.Problems, DeepEquals, []string{
+		"line 3: the chain starts anew after record 2",
+		"records 2 to 4 are missing at the end of the log",
+	})
+}
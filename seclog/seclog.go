@@ -21,6 +21,7 @@ package seclog
 
 import (
 	"fmt"
+	"io"
 	"sync"
 
 	"github.com/snapcore/snapd/logger"
@@ -83,7 +84,9 @@ type SecurityLogger interface {
 
 var (
 	globalLogger SecurityLogger = NewNopLogger()
-	// lock guards globalLogger reads and writes.
+	// remoteLogger forwards events to a remote collector, if configured.
+	remoteLogger SecurityLogger
+	// lock guards globalLogger and remoteLogger reads and writes.
 	lock sync.Mutex
 )
 
@@ -98,6 +101,30 @@ func Setup(l SecurityLogger) {
 	globalLogger = l
 }
 
+// SetupRemote activates forwarding of security events to a remote
+// collector, in addition to the logger activated with [Setup]. It replaces
+// any previously configured remote logger, which is closed if it implements
+// [io.Closer]. Passing nil disables forwarding.
+func SetupRemote(l SecurityLogger) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if closer, ok := remoteLogger.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Noticef("cannot close remote security logger: %v", err)
+		}
+	}
+	remoteLogger = l
+}
+
+// logEvent passes the event to the active loggers. Callers must hold lock.
+func logEvent(event Event, description string, attrs ...Attr) {
+	globalLogger.LogEvent(event, description, attrs...)
+	if remoteLogger != nil {
+		remoteLogger.LogEvent(event, description, attrs...)
+	}
+}
+
 // LogLoggerEnabled logs that the security logger has been enabled.
 func LogLoggerEnabled() {
 	lock.Lock()
@@ -107,7 +134,7 @@ func LogLoggerEnabled() {
 		logger.Noticef("security logger enabled")
 	}
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "SYS", Name: "sys_logging_enabled", Level: LevelInfo},
 		"Security logging enabled",
 	)
@@ -122,7 +149,7 @@ func LogLoggerDisabled() {
 		logger.Noticef("security logger disabled")
 	}
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "SYS", Name: "sys_logging_disabled", Level: LevelCritical},
 		"Security logging disabled",
 	)
@@ -133,7 +160,7 @@ func LogLoginSuccess(user SnapdUser) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "AUTHN", Name: "authn_login_success", Level: LevelInfo},
 		fmt.Sprintf("User %s login success", user.String()),
 		Attr{Key: "user", Value: user},
@@ -145,7 +172,7 @@ func LogLoginFailure(user SnapdUser, reason Reason) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "AUTHN", Name: "authn_login_failure", Level: LevelWarn},
 		fmt.Sprintf("User %s login failure: %s", user.String(), reason.String()),
 		Attr{Key: "user", Value: user},
@@ -159,7 +186,7 @@ func LogAuthnTokenCreated(user SnapdUser, tokenID int) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "AUTHN", Name: "authn_token_created", Level: LevelInfo},
 		fmt.Sprintf("Token created for user %s", user.String()),
 		Attr{Key: "user", Value: user},
@@ -173,7 +200,7 @@ func LogAuthnTokenDeleted(user SnapdUser, tokenID int) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "AUTHN", Name: "authn_token_delete", Level: LevelInfo},
 		fmt.Sprintf("Token deleted for user %s", user.String()),
 		Attr{Key: "user", Value: user},
@@ -186,7 +213,7 @@ func LogUserCreated(user SnapdUser) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "USER", Name: "user_created", Level: LevelInfo},
 		fmt.Sprintf("Created user %s", user.String()),
 		Attr{Key: "user", Value: user},
@@ -198,7 +225,7 @@ func LogUserUpdated(user SnapdUser, changedFields []string) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "USER", Name: "user_updated", Level: LevelInfo},
 		fmt.Sprintf("Updated user %s", user.String()),
 		Attr{Key: "user", Value: user},
@@ -211,7 +238,7 @@ func LogUserRemoved(user SnapdUser) {
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "USER", Name: "user_removed", Level: LevelInfo},
 		fmt.Sprintf("Removed user %s", user.String()),
 		Attr{Key: "user", Value: user},
@@ -229,7 +256,7 @@ func LogAdminActivity(user SnapdUser, peer Peer, endpoint Endpoint, grantReason
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "AUTHZ", Name: "authz_admin", Level: LevelInfo},
 		fmt.Sprintf("User %s from %s granted access to %s (%s)",
 			user.String(), peer.String(), endpoint.String(), grantReason),
@@ -250,7 +277,7 @@ func LogUnauthorizedAccess(user SnapdUser, peer Peer, endpoint Endpoint, denialR
 	lock.Lock()
 	defer lock.Unlock()
 
-	globalLogger.LogEvent(
+	logEvent(
 		Event{Category: "AUTHZ", Name: "authz_fail", Level: LevelCritical},
 		fmt.Sprintf("User %s from %s denied access to %s (%s)",
 			user.String(), peer.String(), endpoint.String(), denialReason),
@@ -260,3 +287,140 @@ func LogUnauthorizedAccess(user SnapdUser, peer Peer, endpoint Endpoint, denialR
 		Attr{Key: "reason_denied", Value: denialReason},
 	)
 }
+
+// connectionLevel returns the level of interface events, which stand out
+// for super-privileged interfaces.
+func connectionLevel(conn Connection) Level {
+	if conn.SuperPrivileged {
+		return LevelWarn
+	}
+	return LevelInfo
+}
+
+// LogInterfaceConnected logs that a plug and slot were connected using the
+// global security logger. Connections of super-privileged interfaces are
+// logged at [LevelWarn].
+//
+// user is the user who requested the change, if any. changeID identifies
+// the change doing the connection.
+func LogInterfaceConnected(user SnapdUser, conn Connection, changeID string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	logEvent(
+		Event{Category: "IFACE", Name: "iface_connected", Level: connectionLevel(conn)},
+		fmt.Sprintf("User %s connected %s (%s)", user.String(), conn.String(), conn.Interface),
+		Attr{Key: "user", Value: user},
+		Attr{Key: "connection", Value: conn},
+		Attr{Key: "change_id", Value: changeID},
+	)
+}
+
+// LogInterfaceDisconnected logs that a plug and slot were disconnected using
+// the global security logger. Disconnections of super-privileged interfaces
+// are logged at [LevelWarn].
+//
+// user is the user who requested the change, if any. changeID identifies
+// the change doing the disconnection.
+func LogInterfaceDisconnected(user SnapdUser, conn Connection, changeID string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	logEvent(
+		Event{Category: "IFACE", Name: "iface_disconnected", Level: connectionLevel(conn)},
+		fmt.Sprintf("User %s disconnected %s (%s)", user.String(), conn.String(), conn.Interface),
+		Attr{Key: "user", Value: user},
+		Attr{Key: "connection", Value: conn},
+		Attr{Key: "change_id", Value: changeID},
+	)
+}
+
+// LogSnapInstalled logs that a snap was installed using the global security
+// logger.
+func LogSnapInstalled(user SnapdUser, snap Snap, changeID string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	logEvent(
+		Event{Category: "SNAP", Name: "snap_installed", Level: LevelInfo},
+		fmt.Sprintf("User %s installed snap %s", user.String(), snap.String()),
+		Attr{Key: "user", Value: user},
+		Attr{Key: "snap", Value: snap},
+		Attr{Key: "change_id", Value: changeID},
+	)
+}
+
+// LogSnapRefreshed logs that a snap was refreshed from oldRevision to the
+// revision of snap using the global security logger.
+func LogSnapRefreshed(user SnapdUser, snap Snap, oldRevision string, changeID string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	logEvent(
+		Event{Category: "SNAP", Name: "snap_refreshed", Level: LevelInfo},
+		fmt.Sprintf("User %s refreshed snap %s from revision %s", user.String(), snap.String(), oldRevision),
+		Attr{Key: "user", Value: user},
+		Attr{Key: "snap", Value: snap},
+		Attr{Key: "old_revision", Value: oldRevision},
+		Attr{Key: "change_id", Value: changeID},
+	)
+}
+
+// LogSnapReverted logs that a snap was reverted from oldRevision to the
+// revision of snap, either on request or as a refresh was undone, using the
+// global security logger.
+func LogSnapReverted(user SnapdUser, snap Snap, oldRevision string, changeID string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	logEvent(
+		Event{Category: "SNAP", Name: "snap_reverted", Level: LevelInfo},
+		fmt.Sprintf("User %s reverted snap %s from revision %s", user.String(), snap.String(), oldRevision),
+		Attr{Key: "user", Value: user},
+		Attr{Key: "snap", Value: snap},
+		Attr{Key: "old_revision", Value: oldRevision},
+		Attr{Key: "change_id", Value: changeID},
+	)
+}
+
+// LogSnapRemoved logs that the last revision of a snap was removed using
+// the global security logger.
+func LogSnapRemoved(user SnapdUser, snap Snap, changeID string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	logEvent(
+		Event{Category: "SNAP", Name: "snap_removed", Level: LevelInfo},
+		fmt.Sprintf("User %s removed snap %s", user.String(), snap.String()),
+		Attr{Key: "user", Value: user},
+		Attr{Key: "snap", Value: snap},
+		Attr{Key: "change_id", Value: changeID},
+	)
+}
+
+// LogValidationSetEnforced logs that a validation set is enforced using the
+// global security logger.
+func LogValidationSetEnforced(user SnapdUser, valset ValidationSet) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	logEvent(
+		Event{Category: "ASSERT", Name: "assert_validation_set_enforced", Level: LevelInfo},
+		fmt.Sprintf("User %s enforced validation set %s", user.String(), valset.String()),
+		Attr{Key: "user", Value: user},
+		Attr{Key: "validation_set", Value: valset},
+	)
+}
+
+// LogAssertionAdded logs that an assertion was added to the system
+// assertion database using the global security logger.
+func LogAssertionAdded(assertion Assertion) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	logEvent(
+		Event{Category: "ASSERT", Name: "assert_added", Level: LevelInfo},
+		fmt.Sprintf("Added assertion %s", assertion.String()),
+		Attr{Key: "assertion", Value: assertion},
+	)
+}
@@ -104,6 +104,46 @@ func (s *SecLogSuite) TestSetupReplacesExistingLogger(c *C) {
 	c.Check(s.buf.Len(), Equals, 0)
 }
 
+type closingLogger struct {
+	seclog.SecurityLogger
+	closed bool
+}
+
+func (l *closingLogger) Close() error {
+	l.closed = true
+	return nil
+}
+
+func (s *SecLogSuite) TestSetupRemote(c *C) {
+	remoteBuf := &bytes.Buffer{}
+	remote := &closingLogger{SecurityLogger: seclogtest.MockSecurityLogger(remoteBuf)}
+	seclog.SetupRemote(remote)
+	defer seclog.SetupRemote(nil)
+
+	// Events go to both loggers.
+	seclog.LogLoginSuccess(seclog.SnapdUser{ID: 1, StoreUserName: "first"})
+	c.Check(s.buf.String(), testutil.Contains, "authn_login_success")
+	c.Check(remoteBuf.String(), testutil.Contains, "authn_login_success")
+
+	// Replacing the remote logger closes the previous one.
+	secondBuf := &bytes.Buffer{}
+	seclog.SetupRemote(seclogtest.MockSecurityLogger(secondBuf))
+	c.Check(remote.closed, Equals, true)
+
+	remoteBuf.Reset()
+	seclog.LogLoginSuccess(seclog.SnapdUser{ID: 2, StoreUserName: "second"})
+	c.Check(secondBuf.String(), testutil.Contains, "authn_login_success")
+	c.Check(remoteBuf.Len(), Equals, 0)
+
+	// Forwarding can be disabled.
+	seclog.SetupRemote(nil)
+	secondBuf.Reset()
+	s.buf.Reset()
+	seclog.LogLoginSuccess(seclog.SnapdUser{ID: 3, StoreUserName: "third"})
+	c.Check(s.buf.String(), testutil.Contains, "authn_login_success")
+	c.Check(secondBuf.Len(), Equals, 0)
+}
+
 func (s *SecLogSuite) TestLogLoginSuccess(c *C) {
 	user := seclog.SnapdUser{
 		ID:             42,
@@ -298,3 +338,110 @@ func (s *SecLogSuite) TestLogUnauthorizedAccess(c *C) {
 	c.Check(s.buf.String(), testutil.Contains, "[reason_denied=\"user-auth-denied\"]")
 	c.Check(s.buf.String(), testutil.Contains, "[user=")
 }
+
+type levelLogger struct {
+	levels []seclog.Level
+}
+
+func (l *levelLogger) LogEvent(event seclog.Event, description string, attrs ...seclog.Attr) {
+	l.levels = append(l.levels, event.Level)
+}
+
+func (s *SecLogSuite) TestLogInterfaceConnected(c *C) {
+	user := seclog.SnapdUser{ID: 1, StoreUserEmail: "jdoe@test.com", StoreUserName: "jdoe"}
+	conn := seclog.Connection{
+		Interface: "network",
+		PlugSnap:  "consumer",
+		PlugName:  "network",
+		SlotSnap:  "snapd",
+		SlotName:  "network",
+	}
+	seclog.LogInterfaceConnected(user, conn, "42")
+
+	c.Check(s.buf.String(), testutil.Contains, "iface_connected User 1:jdoe@test.com:jdoe connected consumer:network snapd:network (network)")
+	c.Check(s.buf.String(), testutil.Contains, "[user=")
+	c.Check(s.buf.String(), testutil.Contains, "[connection=")
+	c.Check(s.buf.String(), testutil.Contains, `[change_id="42"]`)
+}
+
+func (s *SecLogSuite) TestLogInterfaceDisconnected(c *C) {
+	conn := seclog.Connection{
+		Interface: "network",
+		PlugSnap:  "consumer",
+		PlugName:  "network",
+		SlotSnap:  "snapd",
+		SlotName:  "network",
+		Auto:      true,
+	}
+	seclog.LogInterfaceDisconnected(seclog.SnapdUser{}, conn, "42")
+
+	c.Check(s.buf.String(), testutil.Contains, "iface_disconnected User <unknown>:<unknown>:<unknown> disconnected consumer:network snapd:network (network)")
+	c.Check(s.buf.String(), testutil.Contains, "Auto:true")
+	c.Check(s.buf.String(), testutil.Contains, `[change_id="42"]`)
+}
+
+func (s *SecLogSuite) TestLogInterfaceSuperPrivilegedLevel(c *C) {
+	l := &levelLogger{}
+	seclog.Setup(l)
+
+	conn := seclog.Connection{Interface: "network"}
+	seclog.LogInterfaceConnected(seclog.SnapdUser{}, conn, "1")
+	seclog.LogInterfaceDisconnected(seclog.SnapdUser{}, conn, "1")
+
+	conn = seclog.Connection{Interface: "docker-support", SuperPrivileged: true}
+	seclog.LogInterfaceConnected(seclog.SnapdUser{}, conn, "1")
+	seclog.LogInterfaceDisconnected(seclog.SnapdUser{}, conn, "1")
+
+	c.Check(l.levels, DeepEquals, []seclog.Level{
+		seclog.LevelInfo, seclog.LevelInfo,
+		seclog.LevelWarn, seclog.LevelWarn,
+	})
+}
+
+func (s *SecLogSuite) TestLogSnapInstalled(c *C) {
+	user := seclog.SnapdUser{ID: 1, StoreUserName: "jdoe"}
+	seclog.LogSnapInstalled(user, seclog.Snap{Name: "foo", SnapID: "foo-id", Revision: "3"}, "7")
+
+	c.Check(s.buf.String(), testutil.Contains, "snap_installed User 1:<unknown>:jdoe installed snap foo:3")
+	c.Check(s.buf.String(), testutil.Contains, `[snap=seclog.Snap{Name:"foo", SnapID:"foo-id", Revision:"3"}]`)
+	c.Check(s.buf.String(), testutil.Contains, `[change_id="7"]`)
+}
+
+func (s *SecLogSuite) TestLogSnapRefreshed(c *C) {
+	user := seclog.SnapdUser{ID: 1, StoreUserName: "jdoe"}
+	seclog.LogSnapRefreshed(user, seclog.Snap{Name: "foo", SnapID: "foo-id", Revision: "4"}, "3", "7")
+
+	c.Check(s.buf.String(), testutil.Contains, "snap_refreshed User 1:<unknown>:jdoe refreshed snap foo:4 from revision 3")
+	c.Check(s.buf.String(), testutil.Contains, `[old_revision="3"]`)
+	c.Check(s.buf.String(), testutil.Contains, `[change_id="7"]`)
+}
+
+func (s *SecLogSuite) TestLogSnapReverted(c *C) {
+	user := seclog.SnapdUser{ID: 1, StoreUserName: "jdoe"}
+	seclog.LogSnapReverted(user, seclog.Snap{Name: "foo", SnapID: "foo-id", Revision: "3"}, "4", "7")
+
+	c.Check(s.buf.String(), testutil.Contains, "snap_reverted User 1:<unknown>:jdoe reverted snap foo:3 from revision 4")
+	c.Check(s.buf.String(), testutil.Contains, `[old_revision="4"]`)
+	c.Check(s.buf.String(), testutil.Contains, `[change_id="7"]`)
+}
+
+func (s *SecLogSuite) TestLogSnapRemoved(c *C) {
+	seclog.LogSnapRemoved(seclog.SnapdUser{}, seclog.Snap{Name: "foo", Revision: "x1"}, "7")
+
+	c.Check(s.buf.String(), testutil.Contains, "snap_removed User <unknown>:<unknown>:<unknown> removed snap foo:x1")
+	c.Check(s.buf.String(), testutil.Contains, `[change_id="7"]`)
+}
+
+func (s *SecLogSuite) TestLogValidationSetEnforced(c *C) {
+	user := seclog.SnapdUser{ID: 1, StoreUserName: "jdoe"}
+	seclog.LogValidationSetEnforced(user, seclog.ValidationSet{AccountID: "acc-id", Name: "base", Sequence: 3, Pinned: true})
+
+	c.Check(s.buf.String(), testutil.Contains, "assert_validation_set_enforced User 1:<unknown>:jdoe enforced validation set acc-id/base=3")
+	c.Check(s.buf.String(), testutil.Contains, `[validation_set=seclog.ValidationSet{AccountID:"acc-id", Name:"base", Sequence:3, Pinned:true}]`)
+}
+
+func (s *SecLogSuite) TestLogAssertionAdded(c *C) {
+	seclog.LogAssertionAdded(seclog.Assertion{Type: "account", PrimaryKey: "acc-id", Revision: 2, SignKeyID: "key-id"})
+
+	c.Check(s.buf.String(), Equals, `assert_added Added assertion account:acc-id [assertion=seclog.Assertion{Type:"account", PrimaryKey:"acc-id", Revision:2, SignKeyID:"key-id"}]`+"\n")
+}
@@ -226,6 +226,52 @@ func (p Peer) LogValue() slog.Value {
 	)
 }
 
+// LogValue implements [slog.LogValuer], allowing [Snap] to be used
+// directly as a structured log attribute value.
+func (s Snap) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("snap_name", s.Name),
+		slog.String("snap_id", fieldOrUnknown(s.SnapID)),
+		slog.String("revision", fieldOrUnknown(s.Revision)),
+	)
+}
+
+// LogValue implements [slog.LogValuer], allowing [Connection] to be used
+// directly as a structured log attribute value.
+func (c Connection) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("interface", c.Interface),
+		slog.String("plug_snap", c.PlugSnap),
+		slog.String("plug_name", c.PlugName),
+		slog.String("slot_snap", c.SlotSnap),
+		slog.String("slot_name", c.SlotName),
+		slog.Bool("auto", c.Auto),
+		slog.Bool("super_privileged", c.SuperPrivileged),
+	)
+}
+
+// LogValue implements [slog.LogValuer], allowing [ValidationSet] to be
+// used directly as a structured log attribute value.
+func (v ValidationSet) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("account_id", v.AccountID),
+		slog.String("name", v.Name),
+		slog.Int("sequence", v.Sequence),
+		slog.Bool("pinned", v.Pinned),
+	)
+}
+
+// LogValue implements [slog.LogValuer], allowing [Assertion] to be used
+// directly as a structured log attribute value.
+func (a Assertion) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("type", a.Type),
+		slog.String("primary_key", a.PrimaryKey),
+		slog.Int("revision", a.Revision),
+		slog.String("sign_key_id", a.SignKeyID),
+	)
+}
+
 // fieldOrUnknown returns [unknown] when value is empty.
 func fieldOrUnknown(value string) string {
 	if value == "" {
@@ -454,6 +454,139 @@ func (s *SlogSuite) TestEndpointLogValue(c *C) {
 	}
 }
 
+func (s *SlogSuite) TestSnapLogValue(c *C) {
+	type snapRecord struct {
+		Snap struct {
+			Name     string `json:"snap_name"`
+			SnapID   string `json:"snap_id"`
+			Revision string `json:"revision"`
+		} `json:"snap"`
+	}
+
+	cases := []struct {
+		snap    seclog.Snap
+		wantID  string
+		wantRev string
+	}{
+		{
+			snap:    seclog.Snap{Name: "foo", SnapID: "foo-id", Revision: "3"},
+			wantID:  "foo-id",
+			wantRev: "3",
+		},
+		{
+			snap:    seclog.Snap{Name: "foo"},
+			wantID:  "<unknown>",
+			wantRev: "<unknown>",
+		},
+	}
+
+	for _, tc := range cases {
+		s.buf.Reset()
+		logger := s.newLogger(c)
+		logger.LogEvent(
+			seclog.Event{Category: "TEST", Name: "test_event", Level: seclog.LevelInfo},
+			"test",
+			seclog.Attr{Key: "snap", Value: tc.snap},
+		)
+
+		var obtained snapRecord
+		err := json.Unmarshal(s.buf.Bytes(), &obtained)
+		c.Assert(err, IsNil)
+		c.Check(obtained.Snap.Name, Equals, "foo")
+		c.Check(obtained.Snap.SnapID, Equals, tc.wantID)
+		c.Check(obtained.Snap.Revision, Equals, tc.wantRev)
+	}
+}
+
+func (s *SlogSuite) TestConnectionLogValue(c *C) {
+	type connectionRecord struct {
+		Connection map[string]any `json:"connection"`
+	}
+
+	logger := s.newLogger(c)
+	logger.LogEvent(
+		seclog.Event{Category: "TEST", Name: "test_event", Level: seclog.LevelInfo},
+		"test",
+		seclog.Attr{Key: "connection", Value: seclog.Connection{
+			Interface:       "docker-support",
+			PlugSnap:        "docker",
+			PlugName:        "docker-support",
+			SlotSnap:        "snapd",
+			SlotName:        "docker-support",
+			SuperPrivileged: true,
+		}},
+	)
+
+	var obtained connectionRecord
+	err := json.Unmarshal(s.buf.Bytes(), &obtained)
+	c.Assert(err, IsNil)
+	c.Check(obtained.Connection, DeepEquals, map[string]any{
+		"interface":        "docker-support",
+		"plug_snap":        "docker",
+		"plug_name":        "docker-support",
+		"slot_snap":        "snapd",
+		"slot_name":        "docker-support",
+		"auto":             false,
+		"super_privileged": true,
+	})
+}
+
+func (s *SlogSuite) TestValidationSetLogValue(c *C) {
+	type validationSetRecord struct {
+		ValidationSet map[string]any `json:"validation_set"`
+	}
+
+	logger := s.newLogger(c)
+	logger.LogEvent(
+		seclog.Event{Category: "TEST", Name: "test_event", Level: seclog.LevelInfo},
+		"test",
+		seclog.Attr{Key: "validation_set", Value: seclog.ValidationSet{
+			AccountID: "acc-id",
+			Name:      "base",
+			Sequence:  3,
+			Pinned:    true,
+		}},
+	)
+
+	var obtained validationSetRecord
+	err := json.Unmarshal(s.buf.Bytes(), &obtained)
+	c.Assert(err, IsNil)
+	c.Check(obtained.ValidationSet, DeepEquals, map[string]any{
+		"account_id": "acc-id",
+		"name":       "base",
+		"sequence":   float64(3),
+		"pinned":     true,
+	})
+}
+
+func (s *SlogSuite) TestAssertionLogValue(c *C) {
+	type assertionRecord struct {
+		Assertion map[string]any `json:"assertion"`
+	}
+
+	logger := s.newLogger(c)
+	logger.LogEvent(
+		seclog.Event{Category: "TEST", Name: "test_event", Level: seclog.LevelInfo},
+		"test",
+		seclog.Attr{Key: "assertion", Value: seclog.Assertion{
+			Type:       "snap-declaration",
+			PrimaryKey: "16/foo-id",
+			Revision:   2,
+			SignKeyID:  "key-id",
+		}},
+	)
+
+	var obtained assertionRecord
+	err := json.Unmarshal(s.buf.Bytes(), &obtained)
+	c.Assert(err, IsNil)
+	c.Check(obtained.Assertion, DeepEquals, map[string]any{
+		"type":        "snap-declaration",
+		"primary_key": "16/foo-id",
+		"revision":    float64(2),
+		"sign_key_id": "key-id",
+	})
+}
+
 func (s *SlogSuite) TestLevelFiltering(c *C) {
 	logger := seclog.NewSlogLogger(s.buf, s.appID, seclog.LevelWarn)
 	c.Assert(logger, NotNil)
@@ -0,0 +1,451 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+// The structured data of syslog messages is built from the log/slog
+// representation of event attributes, so that both backends agree on it.
+//go:build go1.21 && !noslog
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seclog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+const (
+	// syslogFacility is the facility of security events, LOG_AUTHPRIV.
+	syslogFacility = 10
+	// syslogEnterpriseID qualifies the names of the SD-elements of the
+	// messages, as required by RFC 5424 for names not registered with IANA.
+	// TODO: use the private enterprise number registered for snapd, 32473
+	// is reserved for documentation by RFC 5612.
+	syslogEnterpriseID = "32473"
+	// syslogQueueSize is the number of messages waiting to be sent after
+	// which further events are dropped.
+	syslogQueueSize = 256
+)
+
+var (
+	syslogDialTimeout   = 10 * time.Second
+	syslogWriteTimeout  = 10 * time.Second
+	syslogRetryInterval = 30 * time.Second
+
+	timeNow = time.Now
+)
+
+// NewSyslogLogger creates a new security logger sending events formatted
+// according to RFC 5424 to a syslog collector over TCP or TLS, using octet
+// counting framing (RFC 6587, RFC 5425). Events at or above minLevel are
+// sent; lower-level events are silently discarded.
+//
+// Events are sent in the background. While the collector cannot be reached
+// they are kept in a file of bounded size and sent once the connection is
+// restored, including across restarts. The returned logger implements
+// [io.Closer], closing it keeps the events that were not sent yet in the
+// file.
+func NewSyslogLogger(config SyslogConfig, appID string, minLevel Level) (SecurityLogger, error) {
+	switch config.Network {
+	case SyslogTCP, SyslogTLS:
+	default:
+		return nil, fmt.Errorf("cannot forward security events: unsupported network %q", config.Network)
+	}
+	if _, _, err := net.SplitHostPort(config.Address); err != nil {
+		return nil, fmt.Errorf("cannot forward security events: %v", err)
+	}
+	if config.BufferPath == "" || config.BufferSize <= 0 {
+		return nil, fmt.Errorf("cannot forward security events: no buffer")
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+
+	l := &syslogLogger{
+		config:   config,
+		appID:    appID,
+		minLevel: minLevel,
+		hostname: hostname,
+		pid:      os.Getpid(),
+		buffer:   newSyslogBuffer(config.BufferPath, config.BufferSize),
+		queue:    make(chan []byte, syslogQueueSize),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go l.run()
+
+	return l, nil
+}
+
+// Ensure [syslogLogger] implements [SecurityLogger].
+var _ SecurityLogger = (*syslogLogger)(nil)
+
+// syslogLogger implements [SecurityLogger] and is constructed by
+// [NewSyslogLogger]. Messages are formatted by LogEvent and sent by a
+// goroutine owning the connection and the buffer.
+type syslogLogger struct {
+	config   SyslogConfig
+	appID    string
+	minLevel Level
+	hostname string
+	pid      int
+
+	queue   chan []byte
+	dropped atomic.Int32
+	closed  atomic.Bool
+	stop    chan struct{}
+	stopped chan struct{}
+
+	// only accessed by run
+	buffer  *syslogBuffer
+	conn    net.Conn
+	retryAt time.Time
+}
+
+// LogEvent implements [SecurityLogger.LogEvent].
+func (l *syslogLogger) LogEvent(event Event, description string, attrs ...Attr) {
+	if event.Level < l.minLevel || l.closed.Load() {
+		return
+	}
+
+	msg := l.format(timeNow(), event, description, attrs)
+	select {
+	case l.queue <- msg:
+	default:
+		if n := l.dropped.Add(1); n == 1 {
+			logger.Noticef("WARNING: security log forwarding is not keeping up, dropping events")
+		}
+	}
+}
+
+// Close stops forwarding, the events that were not sent yet are kept in the
+// buffer.
+func (l *syslogLogger) Close() error {
+	if l.closed.Swap(true) {
+		return nil
+	}
+	close(l.stop)
+	<-l.stopped
+	return nil
+}
+
+func (l *syslogLogger) run() {
+	defer close(l.stopped)
+
+	// send what is left from before
+	l.deliver()
+
+	// retry is armed while there are buffered messages, it fires once the
+	// collector can be tried again
+	retry := time.NewTimer(syslogRetryInterval)
+	retry.Stop()
+	defer retry.Stop()
+	armed := false
+
+	for {
+		if !armed && !l.buffer.empty() {
+			retry.Reset(time.Until(l.retryAt))
+			armed = true
+		}
+
+		select {
+		case msg := <-l.queue:
+			l.deliver(msg)
+		case <-retry.C:
+			armed = false
+			l.deliver()
+		case <-l.stop:
+			var pending [][]byte
+			for len(l.queue) > 0 {
+				pending = append(pending, <-l.queue)
+			}
+			if err := l.buffer.append(pending...); err != nil {
+				logger.Noticef("WARNING: cannot buffer security events: %v", err)
+			}
+			l.disconnect()
+			return
+		}
+
+		if n := l.dropped.Swap(0); n > 0 {
+			logger.Noticef("WARNING: dropped %d security events", n)
+		}
+	}
+}
+
+// deliver sends the buffered messages followed by the given ones, keeping in
+// the buffer the ones that cannot be sent.
+func (l *syslogLogger) deliver(msgs ...[]byte) {
+	if l.conn == nil && time.Now().Before(l.retryAt) {
+		if err := l.buffer.append(msgs...); err != nil {
+			logger.Noticef("WARNING: cannot buffer security events: %v", err)
+		}
+		return
+	}
+
+	pending := msgs
+	if !l.buffer.empty() {
+		buffered, err := l.buffer.load()
+		if err != nil {
+			logger.Noticef("WARNING: cannot read buffered security events: %v", err)
+			// the buffer is tried again later rather than right away
+			l.retryAt = time.Now().Add(syslogRetryInterval)
+		}
+		pending = append(buffered, msgs...)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	sent := 0
+	// an established connection may have been closed by the collector in
+	// the meantime, in which case a new one is attempted right away
+	reconnect := l.conn != nil
+	for {
+		if err := l.connect(); err != nil {
+			logger.Noticef("WARNING: cannot connect to security log collector %s: %v", l.config.Address, err)
+			break
+		}
+		var err error
+		for sent < len(pending) {
+			if err = l.send(pending[sent]); err != nil {
+				break
+			}
+			sent++
+		}
+		if err == nil {
+			break
+		}
+		l.disconnect()
+		if !reconnect {
+			logger.Noticef("WARNING: cannot send security event to %s: %v", l.config.Address, err)
+			break
+		}
+		reconnect = false
+	}
+
+	if sent < len(pending) {
+		l.retryAt = time.Now().Add(syslogRetryInterval)
+	}
+	if sent == len(msgs) && sent == len(pending) {
+		// nothing was buffered
+		return
+	}
+	if err := l.buffer.replace(pending[sent:]); err != nil {
+		logger.Noticef("WARNING: cannot buffer security events: %v", err)
+	}
+}
+
+var syslogDial = func(network, address string, tlsConfig *tls.Config) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	if network == SyslogTLS {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		return tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	}
+	return dialer.Dial("tcp", address)
+}
+
+func (l *syslogLogger) connect() error {
+	if l.conn != nil {
+		return nil
+	}
+	conn, err := syslogDial(l.config.Network, l.config.Address, l.config.TLSConfig)
+	if err != nil {
+		return err
+	}
+	l.conn = conn
+	return nil
+}
+
+func (l *syslogLogger) disconnect() {
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+	}
+}
+
+func (l *syslogLogger) send(msg []byte) error {
+	if err := l.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err != nil {
+		return err
+	}
+	_, err := l.conn.Write(frame(msg))
+	return err
+}
+
+// syslogSeverity returns the syslog severity matching the level.
+func syslogSeverity(level Level) int {
+	switch {
+	case level >= LevelCritical:
+		return 2
+	case level >= LevelError:
+		return 3
+	case level >= LevelWarn:
+		return 4
+	case level >= LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// format returns the event as an RFC 5424 message:
+//
+//	<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+//
+// The event name is the MSGID. The first SD-element, "snapd", carries the
+// category, level and type of the event, along with the attributes holding
+// single values. Each attribute holding a group of values, like a user or a
+// peer, gets an SD-element of its own named after its key, nested groups
+// being flattened with dotted names. The description is the MSG.
+func (l *syslogLogger) format(t time.Time, event Event, description string, attrs []Attr) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s ",
+		syslogFacility*8+syslogSeverity(event.Level),
+		t.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(l.hostname, 255),
+		headerField(l.appID, 48),
+		l.pid,
+		headerField(event.Name, 32),
+	)
+
+	params := []sdParam{
+		{"category", event.Category},
+		{"level", event.Level.String()},
+		{"type", "security"},
+	}
+	var groups []sdElement
+	for _, a := range attrs {
+		value := slog.AnyValue(a.Value).Resolve()
+		if value.Kind() == slog.KindGroup {
+			groups = append(groups, sdElement{name: a.Key, params: flattenGroup("", value.Group())})
+			continue
+		}
+		params = append(params, sdParam{a.Key, valueString(value)})
+	}
+
+	writeSDElement(&buf, sdElement{name: "snapd", params: params})
+	for _, group := range groups {
+		writeSDElement(&buf, group)
+	}
+
+	if description != "" {
+		// the BOM marks the message as UTF-8
+		buf.WriteString(" \xef\xbb\xbf")
+		buf.WriteString(description)
+	}
+	return buf.Bytes()
+}
+
+type sdParam struct {
+	name  string
+	value string
+}
+
+type sdElement struct {
+	name   string
+	params []sdParam
+}
+
+func flattenGroup(prefix string, attrs []slog.Attr) []sdParam {
+	var params []sdParam
+	for _, a := range attrs {
+		value := a.Value.Resolve()
+		if value.Kind() == slog.KindGroup {
+			params = append(params, flattenGroup(prefix+a.Key+".", value.Group())...)
+			continue
+		}
+		params = append(params, sdParam{prefix + a.Key, valueString(value)})
+	}
+	return params
+}
+
+func valueString(value slog.Value) string {
+	switch value.Kind() {
+	case slog.KindTime:
+		return value.Time().UTC().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if data, err := json.Marshal(value.Any()); err == nil {
+			return string(data)
+		}
+	}
+	return value.String()
+}
+
+func writeSDElement(buf *bytes.Buffer, element sdElement) {
+	buf.WriteByte('[')
+	buf.WriteString(sdName(element.name, 32-len("@"+syslogEnterpriseID)))
+	buf.WriteString("@" + syslogEnterpriseID)
+	for _, param := range element.params {
+		buf.WriteByte(' ')
+		buf.WriteString(sdName(param.name, 32))
+		buf.WriteString(`="`)
+		for _, r := range param.value {
+			// escaping is mandatory for these, see RFC 5424 section 6.3.3
+			if r == '"' || r == '\\' || r == ']' {
+				buf.WriteByte('\\')
+			}
+			buf.WriteRune(r)
+		}
+		buf.WriteByte('"')
+	}
+	buf.WriteByte(']')
+}
+
+// sdName returns name as a valid SD-NAME: printable US-ASCII except '=',
+// ' ', ']', '"' and '@', of at most max characters.
+func sdName(name string, max int) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' || c == '@' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+// headerField returns value as a valid header field: printable US-ASCII of
+// at most max characters, or the NILVALUE if empty.
+func headerField(value string, max int) string {
+	if value == "" {
+		return "-"
+	}
+	b := []byte(value)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
@@ -0,0 +1,217 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seclog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// maxSyslogFrameSize bounds the size of a single buffered message, so that a
+// corrupted length cannot make reading the buffer allocate arbitrary amounts
+// of memory.
+const maxSyslogFrameSize = 64 * 1024
+
+// syslogBuffer holds the syslog messages that could not be delivered to the
+// collector in a file, framed with octet counting as described in RFC 6587,
+// which is also how they are sent. When the file would exceed its maximum
+// size the oldest messages are dropped, in chunks of a quarter of the
+// maximum size so that the file is not rewritten for every message while the
+// collector cannot be reached.
+//
+// A syslogBuffer is not safe for concurrent use.
+type syslogBuffer struct {
+	path    string
+	maxSize int64
+	// size is the current size of the file, or -1 if unknown.
+	size int64
+}
+
+func newSyslogBuffer(path string, maxSize int64) *syslogBuffer {
+	return &syslogBuffer{path: path, maxSize: maxSize, size: -1}
+}
+
+// frame returns msg framed with octet counting.
+func frame(msg []byte) []byte {
+	framed := make([]byte, 0, len(msg)+8)
+	framed = strconv.AppendInt(framed, int64(len(msg)), 10)
+	framed = append(framed, ' ')
+	return append(framed, msg...)
+}
+
+// readFrames returns the messages of the octet counted stream r. A truncated
+// or corrupted trailing frame is reported as an error along with the messages
+// read until then.
+func readFrames(r io.Reader) ([][]byte, error) {
+	br := bufio.NewReader(r)
+	var msgs [][]byte
+	for {
+		prefix, err := br.ReadString(' ')
+		if err == io.EOF && prefix == "" {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, fmt.Errorf("truncated message length")
+		}
+		n, err := strconv.Atoi(prefix[:len(prefix)-1])
+		if err != nil || n <= 0 || n > maxSyslogFrameSize {
+			return msgs, fmt.Errorf("invalid message length %q", prefix[:len(prefix)-1])
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(br, msg); err != nil {
+			return msgs, fmt.Errorf("truncated message")
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+// load returns the buffered messages.
+func (b *syslogBuffer) load() ([][]byte, error) {
+	f, err := os.Open(b.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			b.size = 0
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	msgs, err := readFrames(f)
+	if err != nil {
+		// keep what can be salvaged
+		logger.Noticef("WARNING: security log buffer %s is corrupted: %v", b.path, err)
+		if err := b.write(msgs, b.maxSize); err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
+
+// empty returns whether there are no buffered messages.
+func (b *syslogBuffer) empty() bool {
+	if b.size < 0 {
+		fi, err := os.Stat(b.path)
+		if err != nil {
+			// treat unreadable buffers as not empty so that delivery
+			// attempts report the problem
+			return errors.Is(err, os.ErrNotExist)
+		}
+		b.size = fi.Size()
+	}
+	return b.size == 0
+}
+
+// trimmedSize returns the size the buffer is trimmed to when appending to it
+// would make it grow larger than its maximum size.
+func (b *syslogBuffer) trimmedSize() int64 {
+	return b.maxSize - b.maxSize/4
+}
+
+// append adds the given messages to the buffer. If the buffer would grow
+// larger than its maximum size, the oldest messages are dropped until it is
+// trimmed down to leave room for further messages.
+func (b *syslogBuffer) append(msgs ...[]byte) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	var added bytes.Buffer
+	for _, msg := range msgs {
+		added.Write(frame(msg))
+	}
+
+	if b.empty() {
+		return b.write(msgs, b.maxSize)
+	}
+	if b.size+int64(added.Len()) > b.maxSize {
+		existing, err := b.load()
+		if err != nil {
+			return err
+		}
+		return b.write(append(existing, msgs...), b.trimmedSize())
+	}
+
+	f, err := os.OpenFile(b.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(added.Bytes()); err != nil {
+		b.size = -1
+		return err
+	}
+	b.size += int64(added.Len())
+	return nil
+}
+
+// replace sets the content of the buffer to the given messages, dropping the
+// oldest ones that do not fit.
+func (b *syslogBuffer) replace(msgs [][]byte) error {
+	return b.write(msgs, b.maxSize)
+}
+
+// write sets the content of the buffer to the given messages, dropping the
+// oldest ones so that the buffer is no larger than limit.
+func (b *syslogBuffer) write(msgs [][]byte, limit int64) error {
+	frames := make([][]byte, len(msgs))
+	var size int64
+	for i, msg := range msgs {
+		frames[i] = frame(msg)
+		size += int64(len(frames[i]))
+	}
+
+	dropped := 0
+	for size > limit {
+		size -= int64(len(frames[dropped]))
+		dropped++
+	}
+	if dropped > 0 {
+		logger.Noticef("WARNING: security log buffer is full, dropped %d oldest events", dropped)
+	}
+
+	if size == 0 {
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		b.size = 0
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(b.path, bytes.Join(frames[dropped:], nil), 0600, 0); err != nil {
+		b.size = -1
+		return err
+	}
+	b.size = size
+	return nil
+}
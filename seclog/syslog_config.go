@@ -0,0 +1,58 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seclog
+
+import (
+	"crypto/tls"
+)
+
+// Syslog transports supported by [NewSyslogLogger].
+const (
+	SyslogTCP = "tcp"
+	SyslogTLS = "tls"
+)
+
+// SyslogConfig configures the forwarding of security events to a syslog
+// collector.
+type SyslogConfig struct {
+	// Network is either [SyslogTCP] or [SyslogTLS].
+	Network string `json:"network"`
+	// Address is the host:port of the collector.
+	Address string `json:"address"`
+	// BufferSize is the maximum size in bytes of the messages kept while
+	// the collector cannot be reached.
+	BufferSize int64 `json:"buffer-size"`
+
+	// BufferPath is the file holding the messages kept while the collector
+	// cannot be reached.
+	BufferPath string `json:"-"`
+	// TLSConfig is used to connect to the collector over TLS. If nil, the
+	// certificate of the collector is verified against the system roots.
+	TLSConfig *tls.Config `json:"-"`
+}
+
+const (
+	// SyslogConfigFile is the name of the file holding the [SyslogConfig]
+	// of snapd, as JSON, in the security logging directory.
+	SyslogConfigFile = "syslog.json"
+	// SyslogBufferFile is the name of the file holding the events not yet
+	// forwarded by snapd, in the security logging directory.
+	SyslogBufferFile = "syslog-buffer"
+)
@@ -0,0 +1,460 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+//go:build go1.21 && !noslog
+
+/*
+ * Copyright (C) 2026 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seclog_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/seclog"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type SyslogSuite struct {
+	testutil.BaseTest
+
+	bufferPath string
+	logbuf     *bytes.Buffer
+}
+
+var _ = Suite(&SyslogSuite{})
+
+func (s *SyslogSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.bufferPath = filepath.Join(c.MkDir(), "seclog", "syslog-buffer")
+
+	logbuf, restore := logger.MockLogger()
+	s.logbuf = logbuf
+	s.AddCleanup(restore)
+}
+
+// collector is a syslog collector accepting octet counted messages.
+type collector struct {
+	ln   net.Listener
+	msgs chan string
+	wg   sync.WaitGroup
+}
+
+func newCollector(c *C, ln net.Listener) *collector {
+	col := &collector{ln: ln, msgs: make(chan string, 16)}
+	col.wg.Add(1)
+	go func() {
+		defer col.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			col.wg.Add(1)
+			go func() {
+				defer col.wg.Done()
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					prefix, err := r.ReadString(' ')
+					if err != nil {
+						return
+					}
+					n, err := strconv.Atoi(prefix[:len(prefix)-1])
+					c.Assert(err, IsNil)
+					msg := make([]byte, n)
+					if _, err := io.ReadFull(r, msg); err != nil {
+						return
+					}
+					col.msgs <- string(msg)
+				}
+			}()
+		}
+	}()
+	return col
+}
+
+func (col *collector) close() {
+	col.ln.Close()
+	col.wg.Wait()
+}
+
+func (col *collector) next(c *C) string {
+	select {
+	case msg := <-col.msgs:
+		return msg
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for syslog message")
+	}
+	return ""
+}
+
+func (col *collector) checkNothing(c *C) {
+	select {
+	case msg := <-col.msgs:
+		c.Fatalf("unexpected syslog message: %q", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (s *SyslogSuite) newTCPCollector(c *C) *collector {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	col := newCollector(c, ln)
+	s.AddCleanup(col.close)
+	return col
+}
+
+func (s *SyslogSuite) newLogger(c *C, network, address string) seclog.SecurityLogger {
+	l, err := seclog.NewSyslogLogger(seclog.SyslogConfig{
+		Network:    network,
+		Address:    address,
+		BufferSize: 4096,
+		BufferPath: s.bufferPath,
+	}, "canonical.snapd.snapd", seclog.LevelInfo)
+	c.Assert(err, IsNil)
+	return l
+}
+
+func closeLogger(c *C, l seclog.SecurityLogger) {
+	closer, ok := l.(io.Closer)
+	c.Assert(ok, Equals, true)
+	c.Assert(closer.Close(), IsNil)
+}
+
+func header(c *C, pri int, event string) string {
+	hostname, err := os.Hostname()
+	c.Assert(err, IsNil)
+	return fmt.Sprintf("<%d>1 2026-01-02T03:04:05.123456Z %s canonical.snapd.snapd %d %s ", pri, hostname, os.Getpid(), event)
+}
+
+func (s *SyslogSuite) TestFormat(c *C) {
+	s.AddCleanup(seclog.MockTimeNow(func() time.Time {
+		return time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	}))
+	col := s.newTCPCollector(c)
+
+	seclog.SetupRemote(s.newLogger(c, seclog.SyslogTCP, col.ln.Addr().String()))
+	defer seclog.SetupRemote(nil)
+
+	seclog.LogAdminActivity(
+		seclog.SnapdUser{ID: 42, StoreUserName: "jdoe", StoreUserEmail: "user@example.com"},
+		seclog.Peer{
+			Socket:         "/run/snapd.socket",
+			UID:            0,
+			PID:            1234,
+			Exe:            "/usr/bin/snap",
+			SecurityLabels: map[string]string{seclog.PeerSecurityLabelAppArmor: "unconfined"},
+		},
+		seclog.Endpoint{Method: "POST", Path: "/v2/snaps", Action: "install"},
+		seclog.GrantRootAuth,
+	)
+	c.Check(col.next(c), Equals, header(c, 86, "authz_admin")+
+		`[snapd@32473 category="AUTHZ" level="INFO" type="security" reason_granted="root-auth"]`+
+		`[user@32473 snapd_user_id="42" store_user_name="jdoe" store_user_email="user@example.com" expiration="never"]`+
+		`[peer@32473 socket="/run/snapd.socket" uid="0" pid="1234" exe="/usr/bin/snap" security_labels.AppArmor="unconfined" cgroup_label="<unknown>" snap="<unknown>" app="<unknown>"]`+
+		`[endpoint@32473 method="POST" path="/v2/snaps" action="install"]`+
+		" \xef\xbb\xbfUser 42:user@example.com:jdoe from /run/snapd.socket:0:1234 granted access to POST:/v2/snaps:install (root-auth)")
+
+	seclog.LogUserUpdated(seclog.SnapdUser{ID: 42}, []string{"email", "name"})
+	c.Check(col.next(c), Equals, header(c, 86, "user_updated")+
+		`[snapd@32473 category="USER" level="INFO" type="security" changed_fields="[\"email\",\"name\"\]"]`+
+		`[user@32473 snapd_user_id="42" store_user_name="" store_user_email="" expiration="never"]`+
+		" \xef\xbb\xbfUpdated user 42:<unknown>:<unknown>")
+
+	seclog.LogLoggerDisabled()
+	c.Check(col.next(c), Equals, header(c, 82, "sys_logging_disabled")+
+		`[snapd@32473 category="SYS" level="CRITICAL" type="security"]`+
+		" \xef\xbb\xbfSecurity logging disabled")
+}
+
+func (s *SyslogSuite) TestFormatSanitizes(c *C) {
+	s.AddCleanup(seclog.MockTimeNow(func() time.Time {
+		return time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	}))
+	col := s.newTCPCollector(c)
+
+	l := s.newLogger(c, seclog.SyslogTCP, col.ln.Addr().String())
+	defer closeLogger(c, l)
+
+	l.LogEvent(seclog.Event{Category: "SYS", Name: "an event name longer than thirty-two characters", Level: seclog.LevelWarn}, "",
+		seclog.Attr{Key: `odd key="x"]`, Value: `a "quoted" ] \ value`})
+	c.Check(col.next(c), Equals, header(c, 84, "an_event_name_longer_than_thirty")+
+		`[snapd@32473 category="SYS" level="WARN" type="security" odd_key__x__="a \"quoted\" \] \\ value"]`)
+}
+
+func (s *SyslogSuite) TestMinLevel(c *C) {
+	col := s.newTCPCollector(c)
+
+	l := s.newLogger(c, seclog.SyslogTCP, col.ln.Addr().String())
+	defer closeLogger(c, l)
+
+	l.LogEvent(seclog.Event{Category: "SYS", Name: "debug", Level: seclog.LevelDebug}, "ignored")
+	col.checkNothing(c)
+
+	l.LogEvent(seclog.Event{Category: "SYS", Name: "info", Level: seclog.LevelInfo}, "sent")
+	c.Check(col.next(c), Matches, `<86>1 .* info \[snapd@32473 .*\] \x{feff}sent`)
+}
+
+func (s *SyslogSuite) TestBufferWhileCollectorDown(c *C) {
+	s.AddCleanup(seclog.MockSyslogRetryInterval(time.Hour))
+	col := s.newTCPCollector(c)
+
+	var down bool
+	var mu sync.Mutex
+	s.AddCleanup(seclog.MockSyslogDial(func(network, address string, tlsConfig *tls.Config) (net.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return nil, errors.New("connection refused")
+		}
+		return net.Dial("tcp", address)
+	}))
+
+	mu.Lock()
+	down = true
+	mu.Unlock()
+
+	l := s.newLogger(c, seclog.SyslogTCP, col.ln.Addr().String())
+	for i := 1; i <= 3; i++ {
+		l.LogEvent(seclog.Event{Category: "SYS", Name: "test", Level: seclog.LevelInfo}, fmt.Sprintf("event %d", i))
+	}
+	closeLogger(c, l)
+	col.checkNothing(c)
+
+	// the events are kept across restarts
+	data, err := os.ReadFile(s.bufferPath)
+	c.Assert(err, IsNil)
+	msgs, err := seclog.ReadFrames(bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 3)
+	c.Check(string(msgs[2]), Matches, `.*\x{feff}event 3`)
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+
+	l = s.newLogger(c, seclog.SyslogTCP, col.ln.Addr().String())
+	defer closeLogger(c, l)
+	l.LogEvent(seclog.Event{Category: "SYS", Name: "test", Level: seclog.LevelInfo}, "event 4")
+	for i := 1; i <= 4; i++ {
+		c.Check(col.next(c), Matches, fmt.Sprintf(`.*\x{feff}event %d`, i))
+	}
+	c.Check(s.bufferPath, testutil.FileAbsent)
+}
+
+func (s *SyslogSuite) TestRetry(c *C) {
+	s.AddCleanup(seclog.MockSyslogRetryInterval(10 * time.Millisecond))
+	col := s.newTCPCollector(c)
+
+	attempts := 0
+	s.AddCleanup(seclog.MockSyslogDial(func(network, address string, tlsConfig *tls.Config) (net.Conn, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return net.Dial("tcp", address)
+	}))
+
+	l := s.newLogger(c, seclog.SyslogTCP, col.ln.Addr().String())
+	defer closeLogger(c, l)
+
+	// sent once the collector is back, without further events
+	l.LogEvent(seclog.Event{Category: "SYS", Name: "test", Level: seclog.LevelInfo}, "event")
+	c.Check(col.next(c), Matches, `.*\x{feff}event`)
+	c.Check(attempts, Equals, 2)
+	c.Check(s.logbuf.String(), testutil.Contains, "cannot connect to security log collector "+col.ln.Addr().String()+": connection refused")
+}
+
+func (s *SyslogSuite) TestUnreadableBufferBacksOff(c *C) {
+	s.AddCleanup(seclog.MockSyslogRetryInterval(time.Hour))
+	col := s.newTCPCollector(c)
+
+	var mu sync.Mutex
+	attempts := 0
+	s.AddCleanup(seclog.MockSyslogDial(func(network, address string, tlsConfig *tls.Config) (net.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		return nil, errors.New("connection refused")
+	}))
+
+	// the buffer cannot be stat'ed, yet it does not look empty
+	notDir := filepath.Join(c.MkDir(), "not-a-dir")
+	c.Assert(os.WriteFile(notDir, nil, 0644), IsNil)
+	s.bufferPath = filepath.Join(notDir, "syslog-buffer")
+
+	l := s.newLogger(c, seclog.SyslogTCP, col.ln.Addr().String())
+	// the buffer is only tried again once the retry interval elapsed
+	time.Sleep(100 * time.Millisecond)
+	closeLogger(c, l)
+
+	c.Check(strings.Count(s.logbuf.String(), "cannot read buffered security events"), Equals, 1)
+	mu.Lock()
+	defer mu.Unlock()
+	c.Check(attempts, Equals, 0)
+}
+
+func (s *SyslogSuite) TestReconnect(c *C) {
+	col := s.newTCPCollector(c)
+
+	var conns []net.Conn
+	s.AddCleanup(seclog.MockSyslogDial(func(network, address string, tlsConfig *tls.Config) (net.Conn, error) {
+		conn, err := net.Dial("tcp", address)
+		conns = append(conns, conn)
+		return conn, err
+	}))
+
+	l := s.newLogger(c, seclog.SyslogTCP, col.ln.Addr().String())
+	defer closeLogger(c, l)
+
+	l.LogEvent(seclog.Event{Category: "SYS", Name: "test", Level: seclog.LevelInfo}, "first")
+	c.Check(col.next(c), Matches, `.*\x{feff}first`)
+
+	// the connection breaks, the event is kept and sent over a new one
+	conns[0].Close()
+	l.LogEvent(seclog.Event{Category: "SYS", Name: "test", Level: seclog.LevelInfo}, "second")
+	c.Check(col.next(c), Matches, `.*\x{feff}second`)
+}
+
+func (s *SyslogSuite) TestTLS(c *C) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, IsNil)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: priv}},
+	})
+	c.Assert(err, IsNil)
+	col := newCollector(c, ln)
+	defer col.close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	l, err := seclog.NewSyslogLogger(seclog.SyslogConfig{
+		Network:    seclog.SyslogTLS,
+		Address:    ln.Addr().String(),
+		BufferSize: 4096,
+		BufferPath: s.bufferPath,
+		TLSConfig:  &tls.Config{RootCAs: roots},
+	}, "canonical.snapd.snapd", seclog.LevelInfo)
+	c.Assert(err, IsNil)
+	defer closeLogger(c, l)
+
+	l.LogEvent(seclog.Event{Category: "SYS", Name: "test", Level: seclog.LevelInfo}, "over tls")
+	c.Check(col.next(c), Matches, `.*\x{feff}over tls`)
+}
+
+func (s *SyslogSuite) TestNewSyslogLoggerErrors(c *C) {
+	for _, tc := range []struct {
+		config seclog.SyslogConfig
+		err    string
+	}{{
+		config: seclog.SyslogConfig{Network: "udp", Address: "localhost:514", BufferSize: 1, BufferPath: s.bufferPath},
+		err:    `cannot forward security events: unsupported network "udp"`,
+	}, {
+		config: seclog.SyslogConfig{Network: "tcp", Address: "localhost", BufferSize: 1, BufferPath: s.bufferPath},
+		err:    `cannot forward security events: address localhost: missing port in address`,
+	}, {
+		config: seclog.SyslogConfig{Network: "tls", Address: "localhost:6514"},
+		err:    `cannot forward security events: no buffer`,
+	}} {
+		_, err := seclog.NewSyslogLogger(tc.config, "app", seclog.LevelInfo)
+		c.Check(err, ErrorMatches, tc.err)
+	}
+}
+
+func (s *SyslogSuite) TestBufferBounded(c *C) {
+	// each message takes 2 bytes of framing
+	b := seclog.NewSyslogBuffer(s.bufferPath, 24)
+	c.Check(b.Empty(), Equals, true)
+
+	c.Assert(b.Append([]byte("aaaa"), []byte("bbbb")), IsNil)
+	c.Check(b.Empty(), Equals, false)
+	c.Check(s.bufferPath, testutil.FileEquals, "4 aaaa4 bbbb")
+
+	c.Assert(b.Append([]byte("cccc"), []byte("dddd")), IsNil)
+	c.Check(s.bufferPath, testutil.FileEquals, "4 aaaa4 bbbb4 cccc4 dddd")
+
+	// the buffer is full, the oldest messages are dropped until it is
+	// trimmed down to three quarters of its maximum size
+	c.Assert(b.Append([]byte("eeee")), IsNil)
+	c.Check(s.bufferPath, testutil.FileEquals, "4 cccc4 dddd4 eeee")
+	c.Check(s.logbuf.String(), testutil.Contains, "security log buffer is full, dropped 2 oldest events")
+	s.logbuf.Reset()
+
+	// which leaves room for further messages
+	c.Assert(b.Append([]byte("ffff")), IsNil)
+	c.Check(s.bufferPath, testutil.FileEquals, "4 cccc4 dddd4 eeee4 ffff")
+	c.Check(s.logbuf.String(), Equals, "")
+
+	msgs, err := b.Load()
+	c.Assert(err, IsNil)
+	c.Check(msgs, DeepEquals, [][]byte{[]byte("cccc"), []byte("dddd"), []byte("eeee"), []byte("ffff")})
+
+	// replacing the content only drops what does not fit
+	c.Assert(b.Replace([][]byte{[]byte("1111"), []byte("2222"), []byte("3333"), []byte("4444"), []byte("5555")}), IsNil)
+	c.Check(s.bufferPath, testutil.FileEquals, "4 22224 33334 44444 5555")
+	c.Check(s.logbuf.String(), testutil.Contains, "security log buffer is full, dropped 1 oldest events")
+
+	c.Assert(b.Replace(nil), IsNil)
+	c.Check(s.bufferPath, testutil.FileAbsent)
+	c.Check(b.Empty(), Equals, true)
+}
+
+func (s *SyslogSuite) TestBufferCorrupted(c *C) {
+	c.Assert(os.MkdirAll(filepath.Dir(s.bufferPath), 0700), IsNil)
+	c.Assert(os.WriteFile(s.bufferPath, []byte("4 aaaa9 bb"), 0600), IsNil)
+
+	b := seclog.NewSyslogBuffer(s.bufferPath, 100)
+	msgs, err := b.Load()
+	c.Assert(err, IsNil)
+	c.Check(msgs, DeepEquals, [][]byte{[]byte("aaaa")})
+	c.Check(s.logbuf.String(), testutil.Contains, "security log buffer "+s.bufferPath+" is corrupted: truncated message")
+
+	// what could be salvaged is kept when appending
+	c.Assert(b.Append([]byte("cc")), IsNil)
+	c.Check(s.bufferPath, testutil.FileEquals, "4 aaaa2 cc")
+}
@@ -23,6 +23,7 @@ package quota
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"runtime"
@@ -33,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/sandbox/ebpf"
 	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/systemd"
 )
@@ -83,6 +85,54 @@ type GroupQuotaJournal struct {
 	RatePeriod time.Duration `json:"rate-period,omitempty"`
 }
 
+// GroupQuotaIO contains the block IO limits of a quota group. The limits
+// apply to the block device backing the snap data directories.
+type GroupQuotaIO struct {
+	// ReadBandwidth is the maximum number of bytes per second the group
+	// may read. A value of 0 means no limit is present.
+	ReadBandwidth quantity.Size `json:"read-bandwidth,omitempty"`
+
+	// WriteBandwidth is the maximum number of bytes per second the group
+	// may write. A value of 0 means no limit is present.
+	WriteBandwidth quantity.Size `json:"write-bandwidth,omitempty"`
+
+	// Weight is the share of the IO bandwidth the group gets when
+	// competing with other groups, between 1 and 10000. A value of 0
+	// means the systemd default of 100 applies.
+	Weight int `json:"weight,omitempty"`
+}
+
+// GroupQuotaNetwork contains the network limits of a quota group.
+type GroupQuotaNetwork struct {
+	// EgressBandwidth is the maximum number of bytes per second the
+	// group may send, packets exceeding it are dropped.
+	EgressBandwidth quantity.Size `json:"egress-bandwidth,omitempty"`
+}
+
+// GroupQuotaSoftLimits contains the soft limits of a quota group, which snapd
+// acts upon when the usage of the group stays above them for a while.
+type GroupQuotaSoftLimits struct {
+	// Memory is the memory usage threshold. A value of 0 means no
+	// threshold is present.
+	Memory quantity.Size `json:"memory,omitempty"`
+
+	// CPUPercentage is the CPU usage threshold, as a percentage of a single
+	// CPU. A value of 0 means no threshold is present.
+	CPUPercentage int `json:"cpu-percentage,omitempty"`
+
+	// Threads is the threshold of the number of threads. A value of 0 means
+	// no threshold is present.
+	Threads int `json:"threads,omitempty"`
+
+	// Window is how long the usage must stay above any of the thresholds
+	// before the action is performed.
+	Window time.Duration `json:"window"`
+
+	// Action is what is done once the group was above the thresholds for
+	// the whole window.
+	Action SoftLimitAction `json:"action"`
+}
+
 // Group is a quota group of snaps, services or sub-groups that are all subject
 // to specific resource quotas. The only quota resource types currently
 // supported is memory, but this can be expanded in the future.
@@ -124,6 +174,20 @@ type Group struct {
 	// journald.
 	JournalLimit *GroupQuotaJournal `json:"journal-limit,omitempty"`
 
+	// IOLimit is the block IO limits of the group. Bandwidth limits of
+	// sub-groups must fit into those of the parent group, as for memory.
+	IOLimit *GroupQuotaIO `json:"io-limit,omitempty"`
+
+	// NetworkLimit is the network limits of the group. They are enforced by
+	// an eBPF program attached to the cgroup of the group, and the egress
+	// bandwidth of sub-groups must fit into that of the parent group.
+	NetworkLimit *GroupQuotaNetwork `json:"network-limit,omitempty"`
+
+	// SoftLimits are the usage thresholds of the group which are not
+	// enforced by the kernel, but acted upon by snapd when exceeded for
+	// a while.
+	SoftLimits *GroupQuotaSoftLimits `json:"soft-limits,omitempty"`
+
 	// ParentGroup is the the parent group that this group is a child of. If it
 	// is empty, then this is a "root" quota group.
 	ParentGroup string `json:"parent-group,omitempty"`
@@ -192,6 +256,29 @@ func (grp *Group) GetQuotaResources() Resources {
 			resourcesBuilder.WithJournalRate(grp.JournalLimit.RateCount, grp.JournalLimit.RatePeriod)
 		}
 	}
+	if grp.IOLimit != nil {
+		if grp.IOLimit.ReadBandwidth != 0 {
+			resourcesBuilder.WithIOReadBandwidth(grp.IOLimit.ReadBandwidth)
+		}
+		if grp.IOLimit.WriteBandwidth != 0 {
+			resourcesBuilder.WithIOWriteBandwidth(grp.IOLimit.WriteBandwidth)
+		}
+		if grp.IOLimit.Weight != 0 {
+			resourcesBuilder.WithIOWeight(grp.IOLimit.Weight)
+		}
+	}
+	if grp.NetworkLimit != nil {
+		resourcesBuilder.WithNetworkEgressBandwidth(grp.NetworkLimit.EgressBandwidth)
+	}
+	if grp.SoftLimits != nil {
+		resourcesBuilder.WithSoftLimits(ResourceSoftLimits{
+			Memory:        grp.SoftLimits.Memory,
+			CPUPercentage: grp.SoftLimits.CPUPercentage,
+			Threads:       grp.SoftLimits.Threads,
+			Window:        grp.SoftLimits.Window,
+			Action:        grp.SoftLimits.Action,
+		})
+	}
 	return resourcesBuilder.Build()
 }
 
@@ -244,6 +331,21 @@ func (grp *Group) CurrentTaskUsage() (int, error) {
 	return int(count), nil
 }
 
+// CurrentNetworkUsage returns the number of bytes sent by the quota group
+// since its network quota was attached. For quota groups without an attached
+// network quota (i.e. quota groups without a network limit or without any
+// snaps in them), the network usage is reported as 0.
+func (grp *Group) CurrentNetworkUsage() (quantity.Size, error) {
+	stats, err := ebpf.ReadNetworkQuotaStats(grp.Name)
+	if errors.Is(err, ebpf.ErrNoNetworkQuota) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return quantity.Size(stats.EgressBytes), nil
+}
+
 // SliceFileName returns the name of the slice file that should be used for this
 // quota group. This name will include all of the group's parents in the name.
 // For example, a group named "bar" that is a child of the "foo" group will have
@@ -371,6 +473,15 @@ type groupQuotaAllocations struct {
 
 	CPUSetLimit              []int
 	CPUSetReservedByChildren []int
+
+	IOReadBandwidthLimit              quantity.Size
+	IOReadBandwidthReservedByChildren quantity.Size
+
+	IOWriteBandwidthLimit              quantity.Size
+	IOWriteBandwidthReservedByChildren quantity.Size
+
+	NetworkEgressBandwidthLimit              quantity.Size
+	NetworkEgressBandwidthReservedByChildren quantity.Size
 }
 
 func max(a, b int) int {
@@ -455,6 +566,13 @@ func (grp *Group) getQuotaAllocations(allQuotas map[string]*groupQuotaAllocation
 		ThreadsLimit: grp.ThreadLimit,
 		CPUSetLimit:  grp.GetLocalCPUSetQuota(),
 	}
+	if grp.IOLimit != nil {
+		limits.IOReadBandwidthLimit = grp.IOLimit.ReadBandwidth
+		limits.IOWriteBandwidthLimit = grp.IOLimit.WriteBandwidth
+	}
+	if grp.NetworkLimit != nil {
+		limits.NetworkEgressBandwidthLimit = grp.NetworkLimit.EgressBandwidth
+	}
 
 	// sliceUniqueAndSort sorts an array of ints in ascending order and removes duplicates
 	sliceUniqueAndSort := func(input []int) []int {
@@ -482,6 +600,9 @@ func (grp *Group) getQuotaAllocations(allQuotas map[string]*groupQuotaAllocation
 		limits.MemoryReservedByChildren += maxq(subGroupLimits.MemoryLimit, subGroupLimits.MemoryReservedByChildren)
 		limits.CPUReservedByChildren += max(subGroupLimits.CPULimit, subGroupLimits.CPUReservedByChildren)
 		limits.ThreadsReservedByChildren += max(subGroupLimits.ThreadsLimit, subGroupLimits.ThreadsReservedByChildren)
+		limits.IOReadBandwidthReservedByChildren += maxq(subGroupLimits.IOReadBandwidthLimit, subGroupLimits.IOReadBandwidthReservedByChildren)
+		limits.IOWriteBandwidthReservedByChildren += maxq(subGroupLimits.IOWriteBandwidthLimit, subGroupLimits.IOWriteBandwidthReservedByChildren)
+		limits.NetworkEgressBandwidthReservedByChildren += maxq(subGroupLimits.NetworkEgressBandwidthLimit, subGroupLimits.NetworkEgressBandwidthReservedByChildren)
 
 		// We need to merge the allowed CPUs lists, but we need to make sure that the list is unique, since cpu cores
 		// can be reused between sub-groups.
@@ -720,6 +841,66 @@ func (grp *Group) validateThreadResourceFit(allQuotas map[string]*groupQuotaAllo
 	return nil
 }
 
+// bandwidthAllocation gives a bandwidth limit of a group and the bandwidth
+// reserved by its sub-groups.
+type bandwidthAllocation func(*groupQuotaAllocations) (limit, reservedByChildren quantity.Size)
+
+func ioReadBandwidthAllocation(a *groupQuotaAllocations) (quantity.Size, quantity.Size) {
+	return a.IOReadBandwidthLimit, a.IOReadBandwidthReservedByChildren
+}
+
+func ioWriteBandwidthAllocation(a *groupQuotaAllocations) (quantity.Size, quantity.Size) {
+	return a.IOWriteBandwidthLimit, a.IOWriteBandwidthReservedByChildren
+}
+
+func networkEgressBandwidthAllocation(a *groupQuotaAllocations) (quantity.Size, quantity.Size) {
+	return a.NetworkEgressBandwidthLimit, a.NetworkEgressBandwidthReservedByChildren
+}
+
+// validateBandwidthResourceFit verifies that the new bandwidth limit of the given kind (e.g. "io read")
+// doesn't conflict with the bandwidth reserved by the sub-groups of the group, and that it fits into the
+// remaining bandwidth of the nearest parent group with a limit of that kind, in the same way as
+// validateMemoryResourceFit does for memory.
+func (grp *Group) validateBandwidthResourceFit(allQuotas map[string]*groupQuotaAllocations, kind string, bandwidthLimit quantity.Size, allocation bandwidthAllocation) error {
+	// make sure current usage does not exceed the new limit, we can avoid any
+	// recursive descent as we already have counted up the usage of our children.
+	var bandwidthReserved quantity.Size
+	if currentLimits := allQuotas[grp.Name]; currentLimits != nil {
+		currentBandwidth, reservedByChildren := allocation(currentLimits)
+		if reservedByChildren > bandwidthLimit {
+			return fmt.Errorf("group %s bandwidth limit of %s/s is too small to fit current subgroup usage of %s/s",
+				kind, bandwidthLimit.IECString(), reservedByChildren.IECString())
+		}
+
+		// if we are reducing the limit, then we don't need to check upper parents,
+		// as we can assume it will fit by this point
+		if bandwidthLimit < currentBandwidth {
+			return nil
+		}
+
+		bandwidthReserved = maxq(currentBandwidth, reservedByChildren)
+	}
+
+	// now we check parents up the tree to make sure we also fit with any
+	// previous usage limits of our parents.
+	parent := grp.parentGroup
+	for parent != nil {
+		if limits := allQuotas[parent.Name]; limits != nil {
+			parentLimit, parentReserved := allocation(limits)
+			if parentLimit != 0 {
+				bandwidthAvailable := parentLimit - (parentReserved - bandwidthReserved)
+				if bandwidthLimit > bandwidthAvailable {
+					return fmt.Errorf("sub-group %s bandwidth limit of %s/s is too large to fit inside group %q remaining quota space %s/s",
+						kind, bandwidthLimit.IECString(), parent.Name, bandwidthAvailable.IECString())
+				}
+				break
+			}
+		}
+		parent = parent.parentGroup
+	}
+	return nil
+}
+
 // validateQuotasFit verifies that the given group's current limits fits correctly
 // into the group's parent group's limits. This is done in multiple steps, where the first
 // one is to get a statistics for the upper-most parent group, to get a combined overview
@@ -756,6 +937,21 @@ func (grp *Group) validateQuotasFit(resourceLimits Resources) error {
 			return err
 		}
 	}
+	if resourceLimits.IO != nil && resourceLimits.IO.ReadBandwidth != 0 {
+		if err := grp.validateBandwidthResourceFit(allQuotas, "io read", resourceLimits.IO.ReadBandwidth, ioReadBandwidthAllocation); err != nil {
+			return err
+		}
+	}
+	if resourceLimits.IO != nil && resourceLimits.IO.WriteBandwidth != 0 {
+		if err := grp.validateBandwidthResourceFit(allQuotas, "io write", resourceLimits.IO.WriteBandwidth, ioWriteBandwidthAllocation); err != nil {
+			return err
+		}
+	}
+	if resourceLimits.Network != nil && resourceLimits.Network.EgressBandwidth != 0 {
+		if err := grp.validateBandwidthResourceFit(allQuotas, "network egress", resourceLimits.Network.EgressBandwidth, networkEgressBandwidthAllocation); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -804,6 +1000,35 @@ func (grp *Group) UpdateQuotaLimits(resourceLimits Resources) error {
 			grp.JournalLimit.RatePeriod = resourceLimits.Journal.Rate.Period
 		}
 	}
+	if resourceLimits.IO != nil {
+		if grp.IOLimit == nil {
+			grp.IOLimit = &GroupQuotaIO{}
+		}
+		// only the io limits that are set are changed
+		if resourceLimits.IO.ReadBandwidth != 0 {
+			grp.IOLimit.ReadBandwidth = resourceLimits.IO.ReadBandwidth
+		}
+		if resourceLimits.IO.WriteBandwidth != 0 {
+			grp.IOLimit.WriteBandwidth = resourceLimits.IO.WriteBandwidth
+		}
+		if resourceLimits.IO.Weight != 0 {
+			grp.IOLimit.Weight = resourceLimits.IO.Weight
+		}
+	}
+	if resourceLimits.Network != nil {
+		grp.NetworkLimit = &GroupQuotaNetwork{
+			EgressBandwidth: resourceLimits.Network.EgressBandwidth,
+		}
+	}
+	if resourceLimits.SoftLimits != nil {
+		grp.SoftLimits = &GroupQuotaSoftLimits{
+			Memory:        resourceLimits.SoftLimits.Memory,
+			CPUPercentage: resourceLimits.SoftLimits.CPUPercentage,
+			Threads:       resourceLimits.SoftLimits.Threads,
+			Window:        resourceLimits.SoftLimits.Window,
+			Action:        resourceLimits.SoftLimits.Action,
+		}
+	}
 	return nil
 }
 
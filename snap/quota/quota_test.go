@@ -1371,6 +1371,123 @@ func (ts *quotaTestSuite) TestChangingParentThreadLimits(c *C) {
 	c.Check(err, ErrorMatches, `group thread limit of 16 is too small to fit current subgroup usage of 32`)
 }
 
+func (ts *quotaTestSuite) TestIOLimits(c *C) {
+	grp, err := quota.NewGroup("groot", quota.NewResourcesBuilder().WithIOReadBandwidth(10*quantity.SizeMiB).WithIOWeight(200).Build())
+	c.Assert(err, IsNil)
+	c.Check(grp.IOLimit, DeepEquals, &quota.GroupQuotaIO{
+		ReadBandwidth: 10 * quantity.SizeMiB,
+		Weight:        200,
+	})
+
+	// only the io limits given are changed
+	err = grp.UpdateQuotaLimits(quota.NewResourcesBuilder().WithIOWriteBandwidth(5 * quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	c.Check(grp.GetQuotaResources(), DeepEquals, quota.NewResourcesBuilder().
+		WithIOReadBandwidth(10*quantity.SizeMiB).
+		WithIOWriteBandwidth(5*quantity.SizeMiB).
+		WithIOWeight(200).
+		Build())
+}
+
+func (ts *quotaTestSuite) TestNestingOfIOBandwidthLimits(c *C) {
+	grp1, err := quota.NewGroup("groot", quota.NewResourcesBuilder().WithIOReadBandwidth(10*quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+
+	// the weight is relative, so it does not need to fit into anything
+	subgrp1, err := grp1.NewSubGroup("weight-sub", quota.NewResourcesBuilder().WithIOWeight(500).Build())
+	c.Assert(err, IsNil)
+
+	_, err = subgrp1.NewSubGroup("io-sub1", quota.NewResourcesBuilder().WithIOReadBandwidth(6*quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+
+	// together with its sibling, this would exceed the read bandwidth of the parent
+	_, err = grp1.NewSubGroup("io-sub2", quota.NewResourcesBuilder().WithIOReadBandwidth(6*quantity.SizeMiB).Build())
+	c.Check(err, ErrorMatches, `sub-group io read bandwidth limit of 6 MiB/s is too large to fit inside group "groot" remaining quota space 4 MiB/s`)
+
+	// the write bandwidth is accounted separately
+	_, err = grp1.NewSubGroup("io-sub2", quota.NewResourcesBuilder().WithIOWriteBandwidth(6*quantity.SizeMiB).Build())
+	c.Check(err, IsNil)
+}
+
+func (ts *quotaTestSuite) TestChangingParentIOBandwidthLimits(c *C) {
+	grp1, err := quota.NewGroup("groot", quota.NewResourcesBuilder().WithIOWriteBandwidth(10*quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+
+	subgrp1, err := grp1.NewSubGroup("cpu-sub", quota.NewResourcesBuilder().WithCPUCount(2).WithCPUPercentage(50).Build())
+	c.Assert(err, IsNil)
+
+	_, err = subgrp1.NewSubGroup("io-sub", quota.NewResourcesBuilder().WithIOWriteBandwidth(8*quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+
+	err = grp1.QuotaUpdateCheck(quota.NewResourcesBuilder().WithIOWriteBandwidth(4 * quantity.SizeMiB).Build())
+	c.Check(err, ErrorMatches, `group io write bandwidth limit of 4 MiB/s is too small to fit current subgroup usage of 8 MiB/s`)
+
+	err = grp1.QuotaUpdateCheck(quota.NewResourcesBuilder().WithIOWriteBandwidth(8 * quantity.SizeMiB).Build())
+	c.Check(err, IsNil)
+}
+
+func (ts *quotaTestSuite) TestNetworkLimits(c *C) {
+	grp, err := quota.NewGroup("groot", quota.NewResourcesBuilder().WithNetworkEgressBandwidth(10*quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	c.Check(grp.NetworkLimit, DeepEquals, &quota.GroupQuotaNetwork{
+		EgressBandwidth: 10 * quantity.SizeMiB,
+	})
+
+	_, err = grp.NewSubGroup("net-sub1", quota.NewResourcesBuilder().WithNetworkEgressBandwidth(6*quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+
+	// together with its sibling, this would exceed the bandwidth of the parent
+	_, err = grp.NewSubGroup("net-sub2", quota.NewResourcesBuilder().WithNetworkEgressBandwidth(6*quantity.SizeMiB).Build())
+	c.Check(err, ErrorMatches, `sub-group network egress bandwidth limit of 6 MiB/s is too large to fit inside group "groot" remaining quota space 4 MiB/s`)
+
+	err = grp.QuotaUpdateCheck(quota.NewResourcesBuilder().WithNetworkEgressBandwidth(4 * quantity.SizeMiB).Build())
+	c.Check(err, ErrorMatches, `group network egress bandwidth limit of 4 MiB/s is too small to fit current subgroup usage of 6 MiB/s`)
+
+	err = grp.UpdateQuotaLimits(quota.NewResourcesBuilder().WithNetworkEgressBandwidth(8 * quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+	c.Check(grp.GetQuotaResources(), DeepEquals, quota.NewResourcesBuilder().WithNetworkEgressBandwidth(8*quantity.SizeMiB).Build())
+}
+
+func (ts *quotaTestSuite) TestSoftLimits(c *C) {
+	grp, err := quota.NewGroup("groot", quota.NewResourcesBuilder().
+		WithMemoryLimit(quantity.SizeGiB).
+		WithSoftLimits(quota.ResourceSoftLimits{Memory: 800 * quantity.SizeMiB, Window: time.Hour}).
+		Build())
+	c.Assert(err, IsNil)
+	// without an action, the overuse is only notified
+	c.Check(grp.SoftLimits, DeepEquals, &quota.GroupQuotaSoftLimits{
+		Memory: 800 * quantity.SizeMiB,
+		Window: time.Hour,
+		Action: quota.SoftLimitActionNotify,
+	})
+
+	// the soft limits are replaced as a whole
+	err = grp.UpdateQuotaLimits(quota.NewResourcesBuilder().
+		WithSoftLimits(quota.ResourceSoftLimits{CPUPercentage: 150, Window: 10 * time.Minute, Action: quota.SoftLimitActionRestart}).
+		Build())
+	c.Assert(err, IsNil)
+	c.Check(grp.GetQuotaResources(), DeepEquals, quota.NewResourcesBuilder().
+		WithMemoryLimit(quantity.SizeGiB).
+		WithSoftLimits(quota.ResourceSoftLimits{CPUPercentage: 150, Window: 10 * time.Minute, Action: quota.SoftLimitActionRestart}).
+		Build())
+
+	// and must be below the hard limits
+	_, err = quota.NewGroup("groot", quota.NewResourcesBuilder().
+		WithMemoryLimit(quantity.SizeGiB).
+		WithSoftLimits(quota.ResourceSoftLimits{Memory: 2 * quantity.SizeGiB, Window: time.Hour}).
+		Build())
+	c.Check(err, ErrorMatches, `soft memory limit of 2 GiB must be smaller than the memory limit of 1 GiB`)
+}
+
+func (ts *quotaTestSuite) TestCurrentNetworkUsageNotAttached(c *C) {
+	grp, err := quota.NewGroup("groot", quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build())
+	c.Assert(err, IsNil)
+
+	sent, err := grp.CurrentNetworkUsage()
+	c.Assert(err, IsNil)
+	c.Check(sent, Equals, quantity.Size(0))
+}
+
 func (ts *quotaTestSuite) TestChangingMiddleParentLimits(c *C) {
 	// Catch any algorithmic mistakes made in regards to not catching parents
 	// that are also children of other parents.
@@ -80,6 +80,47 @@ type ResourceJournal struct {
 	Rate *ResourceJournalRate `json:"rate,omitempty"`
 }
 
+// ResourceIO represents the block IO quotas. The bandwidths are in bytes
+// per second, while the weight is relative to that of other groups. Zero
+// values are not set.
+type ResourceIO struct {
+	ReadBandwidth  quantity.Size `json:"read-bandwidth,omitempty"`
+	WriteBandwidth quantity.Size `json:"write-bandwidth,omitempty"`
+	Weight         int           `json:"weight,omitempty"`
+}
+
+// ResourceNetwork represents the network quotas. The egress bandwidth is in
+// bytes per second.
+type ResourceNetwork struct {
+	EgressBandwidth quantity.Size `json:"egress-bandwidth"`
+}
+
+// SoftLimitAction is what snapd does when the usage of a quota group stays
+// above its soft limits.
+type SoftLimitAction string
+
+const (
+	// SoftLimitActionNotify records a notice about the overuse.
+	SoftLimitActionNotify SoftLimitAction = "notify"
+	// SoftLimitActionRestart restarts the services in the group.
+	SoftLimitActionRestart SoftLimitAction = "restart"
+	// SoftLimitActionFreeze freezes the processes in the group.
+	SoftLimitActionFreeze SoftLimitAction = "freeze"
+)
+
+// ResourceSoftLimits are thresholds on the usage of a quota group which,
+// unlike the other limits, are not enforced by the kernel. Instead, when the
+// usage of the group stays above any of them for the whole window, snapd
+// performs the action. Zero thresholds are not set, the CPU threshold is a
+// percentage of a single CPU.
+type ResourceSoftLimits struct {
+	Memory        quantity.Size   `json:"memory,omitempty"`
+	CPUPercentage int             `json:"cpu-percentage,omitempty"`
+	Threads       int             `json:"threads,omitempty"`
+	Window        time.Duration   `json:"window"`
+	Action        SoftLimitAction `json:"action"`
+}
+
 // Resources are built up of multiple quota limits. Each quota limit is a pointer
 // value to indicate that their presence may be optional, and because we want to detect
 // whenever someone changes a limit to '0' explicitly.
@@ -89,6 +130,10 @@ type Resources struct {
 	CPUSet  *ResourceCPUSet  `json:"cpu-set,omitempty"`
 	Threads *ResourceThreads `json:"thread,omitempty"`
 	Journal *ResourceJournal `json:"journal,omitempty"`
+	IO      *ResourceIO      `json:"io,omitempty"`
+	Network *ResourceNetwork `json:"network,omitempty"`
+
+	SoftLimits *ResourceSoftLimits `json:"soft-limits,omitempty"`
 }
 
 const (
@@ -104,6 +149,19 @@ const (
 	// usage, but we have selected 64kB to protect against ridiculously small values.
 	journalLimitMin = 64 * quantity.SizeKiB
 	journalLimitMax = 4 * quantity.SizeGiB
+
+	// The range of IOWeight accepted by systemd.
+	ioWeightMin = 1
+	ioWeightMax = 10000
+
+	// The egress bandwidth is enforced by dropping packets once the group
+	// has sent more than its share, anything much lower than a few packets
+	// per second makes networking unusable.
+	networkEgressBandwidthMin = 8 * quantity.SizeKiB
+
+	// The usage of quota groups is only sampled every few minutes, a
+	// shorter window would be acted upon after a single sample.
+	softLimitsWindowMin = 5 * time.Minute
 )
 
 func (qr *Resources) validateMemoryQuota() error {
@@ -191,6 +249,57 @@ func (qr *Resources) validateJournalQuota() error {
 	return nil
 }
 
+func (qr *Resources) validateIOQuota() error {
+	if *qr.IO == (ResourceIO{}) {
+		return fmt.Errorf("io quota must have a limit set")
+	}
+	if qr.IO.Weight != 0 && (qr.IO.Weight < ioWeightMin || qr.IO.Weight > ioWeightMax) {
+		return fmt.Errorf("invalid io weight %d: must be between %d and %d", qr.IO.Weight, ioWeightMin, ioWeightMax)
+	}
+	return nil
+}
+
+func (qr *Resources) validateNetworkQuota() error {
+	if qr.Network.EgressBandwidth == 0 {
+		return fmt.Errorf("network quota must have an egress bandwidth limit set")
+	}
+	if qr.Network.EgressBandwidth < networkEgressBandwidthMin {
+		return fmt.Errorf("network egress bandwidth limit of %s/s is too small: limit must be at least %s/s",
+			qr.Network.EgressBandwidth.IECString(), networkEgressBandwidthMin.IECString())
+	}
+	return nil
+}
+
+func (qr *Resources) validateSoftLimits() error {
+	soft := qr.SoftLimits
+	if soft.Memory == 0 && soft.CPUPercentage == 0 && soft.Threads == 0 {
+		return fmt.Errorf("soft limits must have at least one threshold set")
+	}
+	if soft.CPUPercentage < 0 || soft.Threads < 0 {
+		return fmt.Errorf("soft limits thresholds must not be negative")
+	}
+	if soft.Window < softLimitsWindowMin {
+		return fmt.Errorf("soft limits window of %v is too short: window must be at least %v", soft.Window, softLimitsWindowMin)
+	}
+	switch soft.Action {
+	case SoftLimitActionNotify, SoftLimitActionRestart, SoftLimitActionFreeze:
+	default:
+		return fmt.Errorf("invalid soft limits action %q: must be one of %q, %q or %q",
+			soft.Action, SoftLimitActionNotify, SoftLimitActionRestart, SoftLimitActionFreeze)
+	}
+	// thresholds at or above the hard limits would never be exceeded for
+	// long, the kernel steps in first
+	if qr.Memory != nil && soft.Memory >= qr.Memory.Limit {
+		return fmt.Errorf("soft memory limit of %s must be smaller than the memory limit of %s",
+			soft.Memory.IECString(), qr.Memory.Limit.IECString())
+	}
+	if qr.Threads != nil && soft.Threads >= qr.Threads.Limit {
+		return fmt.Errorf("soft thread limit of %d must be smaller than the thread limit of %d",
+			soft.Threads, qr.Threads.Limit)
+	}
+	return nil
+}
+
 // CheckFeatureRequirements checks if the current system meets the
 // requirements for the given resource request.
 //
@@ -204,6 +313,35 @@ func (qr *Resources) CheckFeatureRequirements() error {
 			return fmt.Errorf("cannot use CPU set with cgroup version %d", cgroupVer)
 		}
 	}
+	// the io controller is only available with cgroup v2
+	if qr.IO != nil {
+		if cgroupVerErr != nil {
+			return cgroupVerErr
+		}
+		if cgroupVer < 2 {
+			return fmt.Errorf("cannot use IO quota with cgroup version %d", cgroupVer)
+		}
+	}
+	// the eBPF program enforcing network quotas is attached to the cgroup of
+	// the group, which is only possible with cgroup v2
+	if qr.Network != nil {
+		if cgroupVerErr != nil {
+			return cgroupVerErr
+		}
+		if cgroupVer < 2 {
+			return fmt.Errorf("cannot use network quota with cgroup version %d", cgroupVer)
+		}
+	}
+	// the freezer of the unified hierarchy is used to freeze the whole
+	// group at once
+	if qr.SoftLimits != nil && qr.SoftLimits.Action == SoftLimitActionFreeze {
+		if cgroupVerErr != nil {
+			return cgroupVerErr
+		}
+		if cgroupVer < 2 {
+			return fmt.Errorf("cannot use soft limits freeze action with cgroup version %d", cgroupVer)
+		}
+	}
 	if qr.Memory != nil {
 		cgroupCheckMemoryCgroupOnce.Do(setMemoryCgroupSupport)
 
@@ -260,6 +398,24 @@ func (qr *Resources) Validate() error {
 			return err
 		}
 	}
+
+	if qr.IO != nil {
+		if err := qr.validateIOQuota(); err != nil {
+			return err
+		}
+	}
+
+	if qr.Network != nil {
+		if err := qr.validateNetworkQuota(); err != nil {
+			return err
+		}
+	}
+
+	if qr.SoftLimits != nil {
+		if err := qr.validateSoftLimits(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -383,6 +539,17 @@ func (qr *Resources) clone() Resources {
 			resourcesCopy.Journal.Rate = &ResourceJournalRate{Count: qr.Journal.Rate.Count, Period: qr.Journal.Rate.Period}
 		}
 	}
+	if qr.IO != nil {
+		ioCopy := *qr.IO
+		resourcesCopy.IO = &ioCopy
+	}
+	if qr.Network != nil {
+		resourcesCopy.Network = &ResourceNetwork{EgressBandwidth: qr.Network.EgressBandwidth}
+	}
+	if qr.SoftLimits != nil {
+		softCopy := *qr.SoftLimits
+		resourcesCopy.SoftLimits = &softCopy
+	}
 	return resourcesCopy
 }
 
@@ -411,6 +578,34 @@ func (qr *Resources) changeInternal(newLimits Resources) {
 			qr.Journal.Rate = newLimits.Journal.Rate
 		}
 	}
+	if newLimits.IO != nil {
+		// only the io limits that are set are changed
+		io := ResourceIO{}
+		if qr.IO != nil {
+			io = *qr.IO
+		}
+		io.merge(newLimits.IO)
+		qr.IO = &io
+	}
+	if newLimits.Network != nil {
+		qr.Network = newLimits.Network
+	}
+	if newLimits.SoftLimits != nil {
+		qr.SoftLimits = newLimits.SoftLimits
+	}
+}
+
+// merge applies the non-zero limits of newLimits.
+func (io *ResourceIO) merge(newLimits *ResourceIO) {
+	if newLimits.ReadBandwidth != 0 {
+		io.ReadBandwidth = newLimits.ReadBandwidth
+	}
+	if newLimits.WriteBandwidth != 0 {
+		io.WriteBandwidth = newLimits.WriteBandwidth
+	}
+	if newLimits.Weight != 0 {
+		io.Weight = newLimits.Weight
+	}
 }
 
 // Change updates the current quota limits with the new limits. Additional verification
@@ -49,6 +49,21 @@ type ResourcesBuilder struct {
 	JournalRateCountLimit  int
 	JournalRatePeriodLimit time.Duration
 	JournalRateSet         bool
+
+	IOReadBandwidth    quantity.Size
+	IOReadBandwidthSet bool
+
+	IOWriteBandwidth    quantity.Size
+	IOWriteBandwidthSet bool
+
+	IOWeight    int
+	IOWeightSet bool
+
+	NetworkEgressBandwidth    quantity.Size
+	NetworkEgressBandwidthSet bool
+
+	SoftLimits    ResourceSoftLimits
+	SoftLimitsSet bool
 }
 
 func (rb *ResourcesBuilder) WithMemoryLimit(limit quantity.Size) *ResourcesBuilder {
@@ -99,6 +114,38 @@ func (rb *ResourcesBuilder) WithJournalRate(count int, period time.Duration) *Re
 	return rb
 }
 
+func (rb *ResourcesBuilder) WithIOReadBandwidth(limit quantity.Size) *ResourcesBuilder {
+	rb.IOReadBandwidth = limit
+	rb.IOReadBandwidthSet = true
+	return rb
+}
+
+func (rb *ResourcesBuilder) WithIOWriteBandwidth(limit quantity.Size) *ResourcesBuilder {
+	rb.IOWriteBandwidth = limit
+	rb.IOWriteBandwidthSet = true
+	return rb
+}
+
+func (rb *ResourcesBuilder) WithIOWeight(weight int) *ResourcesBuilder {
+	rb.IOWeight = weight
+	rb.IOWeightSet = true
+	return rb
+}
+
+func (rb *ResourcesBuilder) WithNetworkEgressBandwidth(limit quantity.Size) *ResourcesBuilder {
+	rb.NetworkEgressBandwidth = limit
+	rb.NetworkEgressBandwidthSet = true
+	return rb
+}
+
+// WithSoftLimits sets the soft limits of the group, without an action the
+// overuse is only notified.
+func (rb *ResourcesBuilder) WithSoftLimits(limits ResourceSoftLimits) *ResourcesBuilder {
+	rb.SoftLimits = limits
+	rb.SoftLimitsSet = true
+	return rb
+}
+
 func (rb *ResourcesBuilder) Build() Resources {
 	var quotaResources Resources
 	if rb.MemoryLimitSet {
@@ -136,6 +183,25 @@ func (rb *ResourcesBuilder) Build() Resources {
 			}
 		}
 	}
+	if rb.IOReadBandwidthSet || rb.IOWriteBandwidthSet || rb.IOWeightSet {
+		quotaResources.IO = &ResourceIO{
+			ReadBandwidth:  rb.IOReadBandwidth,
+			WriteBandwidth: rb.IOWriteBandwidth,
+			Weight:         rb.IOWeight,
+		}
+	}
+	if rb.NetworkEgressBandwidthSet {
+		quotaResources.Network = &ResourceNetwork{
+			EgressBandwidth: rb.NetworkEgressBandwidth,
+		}
+	}
+	if rb.SoftLimitsSet {
+		softLimits := rb.SoftLimits
+		if softLimits.Action == "" {
+			softLimits.Action = SoftLimitActionNotify
+		}
+		quotaResources.SoftLimits = &softLimits
+	}
 	return quotaResources
 }
 
@@ -50,6 +50,16 @@ func (s *resourcesTestSuite) TestQuotaValidationFails(c *C) {
 		{quota.NewResourcesBuilder().WithJournalRate(0, 1).Build(), `journal quota must have a period of at least 1 microsecond \(minimum resolution\)`},
 		{quota.NewResourcesBuilder().WithJournalRate(1, time.Nanosecond).Build(), `journal quota must have a period of at least 1 microsecond \(minimum resolution\)`},
 		{quota.NewResourcesBuilder().WithJournalSize(0).Build(), `journal size quota must have a limit set`},
+		{quota.NewResourcesBuilder().WithIOWeight(0).Build(), `io quota must have a limit set`},
+		{quota.NewResourcesBuilder().WithIOWeight(10001).Build(), `invalid io weight 10001: must be between 1 and 10000`},
+		{quota.NewResourcesBuilder().WithNetworkEgressBandwidth(0).Build(), `network quota must have an egress bandwidth limit set`},
+		{quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeKiB).Build(), `network egress bandwidth limit of 1 KiB/s is too small: limit must be at least 8 KiB/s`},
+		{quota.NewResourcesBuilder().WithSoftLimits(quota.ResourceSoftLimits{Window: time.Hour}).Build(), `soft limits must have at least one threshold set`},
+		{quota.NewResourcesBuilder().WithSoftLimits(quota.ResourceSoftLimits{Threads: -1, Window: time.Hour}).Build(), `soft limits thresholds must not be negative`},
+		{quota.NewResourcesBuilder().WithSoftLimits(quota.ResourceSoftLimits{Threads: 10, Window: time.Minute}).Build(), `soft limits window of 1m0s is too short: window must be at least 5m0s`},
+		{quota.NewResourcesBuilder().WithSoftLimits(quota.ResourceSoftLimits{Threads: 10, Window: time.Hour, Action: "reboot"}).Build(), `invalid soft limits action "reboot": must be one of "notify", "restart" or "freeze"`},
+		{quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).WithSoftLimits(quota.ResourceSoftLimits{Memory: quantity.SizeMiB, Window: time.Hour}).Build(), `soft memory limit of 1 MiB must be smaller than the memory limit of 1 MiB`},
+		{quota.NewResourcesBuilder().WithThreadLimit(16).WithSoftLimits(quota.ResourceSoftLimits{Threads: 32, Window: time.Hour}).Build(), `soft thread limit of 32 must be smaller than the thread limit of 16`},
 	}
 
 	for _, t := range tests {
@@ -69,6 +79,20 @@ func (s *resourcesTestSuite) TestResourceCheckFeatureRequirementsCgroupv1(c *C)
 	// cpu set with cgroup v1 is not supported
 	bad := quota.NewResourcesBuilder().WithCPUSet([]int{0, 1}).Build()
 	c.Check(bad.CheckFeatureRequirements(), ErrorMatches, "cannot use CPU set with cgroup version 1")
+
+	// and neither are io quotas
+	bad = quota.NewResourcesBuilder().WithIOWeight(100).Build()
+	c.Check(bad.CheckFeatureRequirements(), ErrorMatches, "cannot use IO quota with cgroup version 1")
+
+	// nor network quotas
+	bad = quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build()
+	c.Check(bad.CheckFeatureRequirements(), ErrorMatches, "cannot use network quota with cgroup version 1")
+
+	// soft limits are fine, unless the group is to be frozen
+	good = quota.NewResourcesBuilder().WithSoftLimits(quota.ResourceSoftLimits{Threads: 10, Window: time.Hour, Action: quota.SoftLimitActionRestart}).Build()
+	c.Check(good.CheckFeatureRequirements(), IsNil)
+	bad = quota.NewResourcesBuilder().WithSoftLimits(quota.ResourceSoftLimits{Threads: 10, Window: time.Hour, Action: quota.SoftLimitActionFreeze}).Build()
+	c.Check(bad.CheckFeatureRequirements(), ErrorMatches, "cannot use soft limits freeze action with cgroup version 1")
 }
 
 func (s *resourcesTestSuite) TestResourceCheckFeatureRequirementsCgroupv1Err(c *C) {
@@ -95,6 +119,12 @@ func (s *resourcesTestSuite) TestQuotaValidationPasses(c *C) {
 		{quota.NewResourcesBuilder().WithJournalSize(quantity.SizeMiB).Build()},
 		{quota.NewResourcesBuilder().WithJournalRate(1, time.Microsecond).Build()},
 		{quota.NewResourcesBuilder().WithJournalNamespace().Build()},
+		{quota.NewResourcesBuilder().WithIOReadBandwidth(quantity.SizeMiB).Build()},
+		{quota.NewResourcesBuilder().WithIOWriteBandwidth(quantity.SizeMiB).WithIOWeight(10000).Build()},
+		{quota.NewResourcesBuilder().WithNetworkEgressBandwidth(8 * quantity.SizeKiB).Build()},
+		{quota.NewResourcesBuilder().WithSoftLimits(quota.ResourceSoftLimits{CPUPercentage: 80, Window: 5 * time.Minute}).Build()},
+		{quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeGiB).WithSoftLimits(quota.ResourceSoftLimits{Memory: 800 * quantity.SizeMiB, Window: time.Hour, Action: quota.SoftLimitActionRestart}).Build()},
+		{quota.NewResourcesBuilder().WithThreadLimit(64).WithSoftLimits(quota.ResourceSoftLimits{Threads: 32, Window: time.Hour, Action: quota.SoftLimitActionFreeze}).Build()},
 	}
 
 	for _, t := range tests {
@@ -238,6 +268,18 @@ func (s *resourcesTestSuite) TestQuotaChangeValidationPasses(c *C) {
 			quota.NewResourcesBuilder().WithCPUSet([]int{0}).Build(),
 			quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).WithCPUCount(4).WithCPUPercentage(25).WithCPUSet([]int{0}).Build(),
 		},
+		{
+			// only the io limits given are changed
+			quota.NewResourcesBuilder().WithIOReadBandwidth(quantity.SizeMiB).WithIOWeight(100).Build(),
+			quota.NewResourcesBuilder().WithIOWriteBandwidth(quantity.SizeMiB).WithIOWeight(50).Build(),
+			quota.NewResourcesBuilder().WithIOReadBandwidth(quantity.SizeMiB).WithIOWriteBandwidth(quantity.SizeMiB).WithIOWeight(50).Build(),
+		},
+		{
+			// the network egress bandwidth can be decreased
+			quota.NewResourcesBuilder().WithNetworkEgressBandwidth(quantity.SizeMiB).Build(),
+			quota.NewResourcesBuilder().WithNetworkEgressBandwidth(512 * quantity.SizeKiB).Build(),
+			quota.NewResourcesBuilder().WithNetworkEgressBandwidth(512 * quantity.SizeKiB).Build(),
+		},
 		{
 			quota.NewResourcesBuilder().WithMemoryLimit(quantity.SizeMiB).WithCPUCount(4).WithCPUPercentage(25).WithCPUSet([]int{0}).Build(),
 			quota.NewResourcesBuilder().WithThreadLimit(128).Build(),
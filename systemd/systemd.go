@@ -499,6 +499,9 @@ type Systemd interface {
 	// threads if enabled, etc) part of the unit, which can be a service or a
 	// slice.
 	CurrentTasksCount(unit string) (uint64, error)
+	// CurrentCPUUsage returns the total CPU time consumed by the specified
+	// unit, which can be a service or a slice.
+	CurrentCPUUsage(unit string) (time.Duration, error)
 	// Run a command
 	Run(command []string, opts *RunOptions) ([]byte, error)
 	// Set log level for the system
@@ -1000,6 +1003,19 @@ func (s *systemd) CurrentMemoryUsage(unit string) (quantity.Size, error) {
 	return quantity.Size(memBytes), nil
 }
 
+func (s *systemd) CurrentCPUUsage(unit string) (time.Duration, error) {
+	cpuNsec, err := s.getPropertyUintValue(unit, "CPUUsageNSec")
+	if err != nil && err != errNotSet {
+		return 0, err
+	}
+
+	if err == errNotSet {
+		return 0, fmt.Errorf("cpu usage unavailable")
+	}
+
+	return time.Duration(cpuNsec), nil
+}
+
 func (s *systemd) InactiveEnterTimestamp(unit string) (time.Time, error) {
 	timeStr, err := s.getPropertyStringValue(unit, "InactiveEnterTimestamp")
 	if err != nil {
@@ -3193,15 +3193,19 @@ func (s *SystemdTestSuite) TestCurrentUsageFamilyReallyInvalid(c *C) {
 	s.outs = [][]byte{
 		[]byte(`gahstringsarehard`),
 		[]byte(`gahstringsarehard`),
+		[]byte(`gahstringsarehard`),
 	}
 	sysd := New(SystemMode, s.rep)
 	_, err := sysd.CurrentMemoryUsage("bar.service")
 	c.Assert(err, ErrorMatches, `invalid property format from systemd for MemoryCurrent \(got gahstringsarehard\)`)
 	_, err = sysd.CurrentTasksCount("bar.service")
 	c.Assert(err, ErrorMatches, `invalid property format from systemd for TasksCurrent \(got gahstringsarehard\)`)
+	_, err = sysd.CurrentCPUUsage("bar.service")
+	c.Assert(err, ErrorMatches, `invalid property format from systemd for CPUUsageNSec \(got gahstringsarehard\)`)
 	c.Check(s.argses, DeepEquals, [][]string{
 		{"show", "--property", "MemoryCurrent", "bar.service"},
 		{"show", "--property", "TasksCurrent", "bar.service"},
+		{"show", "--property", "CPUUsageNSec", "bar.service"},
 	})
 }
 
@@ -3209,15 +3213,19 @@ func (s *SystemdTestSuite) TestCurrentUsageFamilyInactive(c *C) {
 	s.outs = [][]byte{
 		[]byte(`MemoryCurrent=[not set]`),
 		[]byte(`TasksCurrent=[not set]`),
+		[]byte(`CPUUsageNSec=[not set]`),
 	}
 	sysd := New(SystemMode, s.rep)
 	_, err := sysd.CurrentMemoryUsage("bar.service")
 	c.Assert(err, ErrorMatches, "memory usage unavailable")
 	_, err = sysd.CurrentTasksCount("bar.service")
 	c.Assert(err, ErrorMatches, "tasks count unavailable")
+	_, err = sysd.CurrentCPUUsage("bar.service")
+	c.Assert(err, ErrorMatches, "cpu usage unavailable")
 	c.Check(s.argses, DeepEquals, [][]string{
 		{"show", "--property", "MemoryCurrent", "bar.service"},
 		{"show", "--property", "TasksCurrent", "bar.service"},
+		{"show", "--property", "CPUUsageNSec", "bar.service"},
 	})
 }
 
@@ -3225,15 +3233,19 @@ func (s *SystemdTestSuite) TestCurrentUsageFamilyInvalid(c *C) {
 	s.outs = [][]byte{
 		[]byte(`MemoryCurrent=blahhhhhhhhhhhhhh`),
 		[]byte(`TasksCurrent=blahhhhhhhhhhhhhh`),
+		[]byte(`CPUUsageNSec=blahhhhhhhhhhhhhh`),
 	}
 	sysd := New(SystemMode, s.rep)
 	_, err := sysd.CurrentMemoryUsage("bar.service")
 	c.Assert(err, ErrorMatches, `invalid property value from systemd for MemoryCurrent: cannot parse "blahhhhhhhhhhhhhh" as an integer`)
 	_, err = sysd.CurrentTasksCount("bar.service")
 	c.Assert(err, ErrorMatches, `invalid property value from systemd for TasksCurrent: cannot parse "blahhhhhhhhhhhhhh" as an integer`)
+	_, err = sysd.CurrentCPUUsage("bar.service")
+	c.Assert(err, ErrorMatches, `invalid property value from systemd for CPUUsageNSec: cannot parse "blahhhhhhhhhhhhhh" as an integer`)
 	c.Check(s.argses, DeepEquals, [][]string{
 		{"show", "--property", "MemoryCurrent", "bar.service"},
 		{"show", "--property", "TasksCurrent", "bar.service"},
+		{"show", "--property", "CPUUsageNSec", "bar.service"},
 	})
 }
 
@@ -3242,6 +3254,7 @@ func (s *SystemdTestSuite) TestCurrentUsageFamilyHappy(c *C) {
 		[]byte(`MemoryCurrent=1024`),
 		[]byte(`MemoryCurrent=18446744073709551615`), // special value from systemd bug
 		[]byte(`TasksCurrent=10`),
+		[]byte(`CPUUsageNSec=1500000000`),
 	}
 	sysd := New(SystemMode, s.rep)
 	memUsage, err := sysd.CurrentMemoryUsage("bar.service")
@@ -3254,10 +3267,14 @@ func (s *SystemdTestSuite) TestCurrentUsageFamilyHappy(c *C) {
 	tasksUsage, err := sysd.CurrentTasksCount("bar.service")
 	c.Assert(tasksUsage, Equals, uint64(10))
 	c.Assert(err, IsNil)
+	cpuUsage, err := sysd.CurrentCPUUsage("bar.service")
+	c.Assert(err, IsNil)
+	c.Assert(cpuUsage, Equals, 1500*time.Millisecond)
 	c.Check(s.argses, DeepEquals, [][]string{
 		{"show", "--property", "MemoryCurrent", "bar.service"},
 		{"show", "--property", "MemoryCurrent", "bar.service"},
 		{"show", "--property", "TasksCurrent", "bar.service"},
+		{"show", "--property", "CPUUsageNSec", "bar.service"},
 	})
 }
 
@@ -33,7 +33,7 @@ features_after_non_nested_task() {
     # but don't end with `}` and have "TRACE", remove their new lines to recompose the entry.
     # Then only grab TRACE-level entries.
     "$TESTSTOOLS"/journal-state get-log --no-pager | _extract_trace_entries > "$task_dir"/journal.txt
-    cp /var/lib/snapd/state.json "$task_dir" || true
+    snap debug state --json /var/lib/snapd/state.json > "$task_dir"/state.json || true
 }
 
 features_after_nested_task() {
@@ -53,8 +53,7 @@ features_after_nested_task() {
         gzip -dc "$task_dir"/install-mode.log.gz | _extract_trace_entries >> "$task_dir"/journal.txt || true
         rm -f "$task_dir"/install-mode.log.gz
     fi
-    "$TESTSTOOLS"/remote.exec "sudo chmod 777 /var/lib/snapd/state.json" || true
-    "$TESTSTOOLS"/remote.pull "/var/lib/snapd/state.json" "$task_dir" || true
+    "$TESTSTOOLS"/remote.exec "sudo snap debug state --json /var/lib/snapd/state.json" > "$task_dir"/state.json || true
 }
 
 locks(){
@@ -75,7 +74,7 @@ features_after_suite_prepare() {
         journalctl --sync || true
         journalctl --flush || true
         journalctl --list-boots -q | awk '{print $1}' | while read -r boot_id; do journalctl -b "$boot_id" --no-pager | _extract_trace_entries; done >> "$suite_dir"/journal.txt
-        cp /var/lib/snapd/state.json "$suite_dir" || true
+        snap debug state --json /var/lib/snapd/state.json > "$suite_dir"/state.json || true
 
         touch "$TESTSTMP/initial-coverage-collected-${SPREAD_SUITE//\//--}"
     fi
@@ -3,7 +3,7 @@
 clean_snapd_lib() {
     rm -rf /var/lib/snapd/assertions/*
     rm -rf /var/lib/snapd/device
-    rm -rf /var/lib/snapd/state.json
+    rm -rf /var/lib/snapd/state.json /var/lib/snapd/state.db
 }
 
 prepare_core_model(){
@@ -467,7 +467,7 @@ prepare_project() {
                 echo "# /var/lib/snapd"
                 ls -lR /var/lib/snapd || true
                 journalctl --no-pager || true
-                cat /var/lib/snapd/state.json || true
+                snap debug state --json /var/lib/snapd/state.json || true
                 snap debug state /var/lib/snapd/state.json || true
                 (
                     for chg in $(snap debug state /var/lib/snapd/state.json | tail -n +2 | awk '{print $1}'); do
@@ -555,7 +555,7 @@ prepare_classic() {
         # reset seeding data that is likely tainted with production keys
         systemctl stop snapd.service snapd.socket
         rm -rf /var/lib/snapd/assertions/*
-        rm -f /var/lib/snapd/state.json
+        rm -f /var/lib/snapd/state.json /var/lib/snapd/state.db
         "$TESTSTOOLS"/store-state setup-staging-store
     fi
 
@@ -1449,9 +1449,9 @@ setup_reflash_magic() {
     # Ensure we don't have snapd already installed, sometimes
     # on 20.04 purge seems to fail, catch that for further
     # debugging
-    if [ -e /var/lib/snapd/state.json ]; then
+    if [ -e /var/lib/snapd/state.json ] || [ -e /var/lib/snapd/state.db ]; then
         echo "reflash image not pristine, snaps already installed"
-        python3 -m json.tool < /var/lib/snapd/state.json
+        snap debug state --json /var/lib/snapd/state.json | python3 -m json.tool
         exit 1
     fi
 
@@ -24,6 +24,8 @@ import (
 	"fmt"
 	"runtime"
 
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/sandbox/ebpf"
 	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/strutil"
 )
@@ -90,6 +92,42 @@ TasksAccounting=true
 	return buf.String()
 }
 
+func formatIOGroupSlice(grp *quota.Group) string {
+	if grp.IOLimit == nil {
+		return ""
+	}
+	header := `
+# Always enable io accounting, so the following io quota options have an effect
+IOAccounting=true
+`
+	buf := bytes.NewBufferString(header)
+	if grp.IOLimit.Weight != 0 {
+		fmt.Fprintf(buf, "IOWeight=%d\n", grp.IOLimit.Weight)
+	}
+	// the bandwidth limits apply to the device backing the snap data, which
+	// systemd finds from the given path
+	if grp.IOLimit.ReadBandwidth != 0 {
+		fmt.Fprintf(buf, "IOReadBandwidthMax=%s %d\n", dirs.SnapDataDir, grp.IOLimit.ReadBandwidth)
+	}
+	if grp.IOLimit.WriteBandwidth != 0 {
+		fmt.Fprintf(buf, "IOWriteBandwidthMax=%s %d\n", dirs.SnapDataDir, grp.IOLimit.WriteBandwidth)
+	}
+	return buf.String()
+}
+
+func formatNetworkGroupSlice(grp *quota.Group) string {
+	if grp.NetworkLimit == nil {
+		return ""
+	}
+	// the program enforcing the limit is loaded by snapd, systemd attaches
+	// it whenever the slice starts, the BPFProgram setting is only
+	// available since systemd 249
+	return fmt.Sprintf(`
+# The egress bandwidth is limited by the following eBPF program
+BPFProgram=egress:%s
+`, ebpf.NetworkQuotaProgramPath(grp.Name))
+}
+
 // GenerateQuotaSliceUnitFile generates a systemd slice unit definition for the
 // specified quota group.
 func GenerateQuotaSliceUnitFile(grp *quota.Group) []byte {
@@ -98,6 +136,8 @@ func GenerateQuotaSliceUnitFile(grp *quota.Group) []byte {
 	cpuOptions := formatCpuGroupSlice(grp)
 	memoryOptions := formatMemoryGroupSlice(grp)
 	taskOptions := formatTaskGroupSlice(grp)
+	ioOptions := formatIOGroupSlice(grp)
+	networkOptions := formatNetworkGroupSlice(grp)
 	template := `[Unit]
 Description=Slice for snap quota group %[1]s
 Before=slices.target
@@ -107,6 +147,6 @@ X-Snappy=yes
 `
 
 	fmt.Fprintf(&buf, template, grp.Name)
-	fmt.Fprint(&buf, cpuOptions, memoryOptions, taskOptions)
+	fmt.Fprint(&buf, cpuOptions, memoryOptions, taskOptions, ioOptions, networkOptions)
 	return buf.Bytes()
 }